@@ -0,0 +1,137 @@
+// Package bip39 implements BIP39 mnemonic codes, backing up wallet seeds as 12 to 24 English words that can be
+// written down and turned back into the seed of a BIP32 HD wallet.
+// See https://github.com/bitcoin/bips/blob/master/bip-0039.mediawiki for full specification.
+package bip39
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/btcutils"
+
+	"crypto/sha256"
+	"crypto/sha512"
+	_ "embed"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	//Each word encodes 11 bits, an index into the 2048 word wordlist
+	bitsPerWord = 11
+	//PBKDF2 parameters for deriving the seed from a mnemonic
+	seedIterations = 2048
+	seedLength     = 64
+)
+
+//go:embed english.txt
+var englishWordlist string
+
+// wordlist is the BIP39 English wordlist, and wordIndexes maps each word back to its index.
+var wordlist = strings.Fields(englishWordlist)
+var wordIndexes = func() map[string]int {
+	indexes := make(map[string]int, len(wordlist))
+	for i, word := range wordlist {
+		indexes[word] = i
+	}
+	return indexes
+}()
+
+// GenerateMnemonic generates a mnemonic of fresh entropy of the given number of bits, 128, 160, 192, 224 or 256,
+// giving 12, 15, 18, 21 or 24 words. Cryptographically secure to the limits of crypto/rand package.
+func GenerateMnemonic(bits int) (string, error) {
+	err := checkEntropyBits(bits)
+	if err != nil {
+		return "", err
+	}
+	entropy, err := btcutils.NewRandomBytes(bits / 8)
+	if err != nil {
+		//Never fall back to a weaker source, the seed is only as strong as its entropy.
+		return "", err
+	}
+	return entropyToMnemonic(entropy)
+}
+
+// MnemonicToSeed derives the 64 byte seed of a mnemonic with PBKDF2-SHA512, using the mnemonic as password and
+// "mnemonic" followed by the optional passphrase as salt. Each passphrase gives a different seed.
+// The passphrase is used exactly as given, so a passphrase with non-ASCII characters must already be in Unicode
+// NFKD form as BIP39 requires. Returns an error if the mnemonic is invalid.
+func MnemonicToSeed(mnemonic string, passphrase string) ([]byte, error) {
+	err := ValidateMnemonic(mnemonic)
+	if err != nil {
+		return nil, err
+	}
+	//Words are separated by single spaces however the mnemonic was typed in
+	normalized := strings.Join(strings.Fields(mnemonic), " ")
+	return pbkdf2.Key([]byte(normalized), []byte("mnemonic"+passphrase), seedIterations, seedLength, sha512.New), nil
+}
+
+// ValidateMnemonic makes sure every word of a mnemonic is in the wordlist, that it has a valid number of words and
+// that its checksum matches, catching most mistakes made writing down or typing in the words.
+// Returns an error with a helpful message or nil if the mnemonic is valid.
+func ValidateMnemonic(mnemonic string) error {
+	_, err := mnemonicToEntropy(mnemonic)
+	return err
+}
+
+// checkEntropyBits makes sure the number of bits of entropy is one BIP39 allows.
+func checkEntropyBits(bits int) error {
+	if bits < 128 || bits > 256 || bits%32 != 0 {
+		return fmt.Errorf("Entropy should be 128, 160, 192, 224 or 256 bits. Provided entropy is %d bits.", bits)
+	}
+	return nil
+}
+
+// entropyToMnemonic encodes entropy followed by the first len(entropy)*8/32 bits of its SHA256 hash as checksum,
+// 11 bits per word.
+func entropyToMnemonic(entropy []byte) (string, error) {
+	entropyBits := len(entropy) * 8
+	err := checkEntropyBits(entropyBits)
+	if err != nil {
+		return "", err
+	}
+	checksumBits := uint(entropyBits / 32)
+	hash := sha256.Sum256(entropy)
+	data := new(big.Int).SetBytes(entropy)
+	data.Lsh(data, checksumBits)
+	data.Or(data, big.NewInt(int64(hash[0]>>(8-checksumBits))))
+
+	//Words are taken from the most significant bits first
+	wordCount := (entropyBits + int(checksumBits)) / bitsPerWord
+	words := make([]string, wordCount)
+	mask := big.NewInt(1<<bitsPerWord - 1)
+	for i := wordCount - 1; i >= 0; i-- {
+		index := new(big.Int).And(data, mask)
+		words[i] = wordlist[index.Int64()]
+		data.Rsh(data, bitsPerWord)
+	}
+	return strings.Join(words, " "), nil
+}
+
+// mnemonicToEntropy decodes a mnemonic back to its entropy, checking its checksum.
+func mnemonicToEntropy(mnemonic string) ([]byte, error) {
+	words := strings.Fields(mnemonic)
+	//Each 3 words encode 32 bits of entropy and 1 bit of checksum
+	if len(words) < 12 || len(words) > 24 || len(words)%3 != 0 {
+		return nil, fmt.Errorf("Mnemonic should be 12, 15, 18, 21 or 24 words long. Provided mnemonic is %d words long.", len(words))
+	}
+	data := new(big.Int)
+	for i, word := range words {
+		index, ok := wordIndexes[word]
+		if !ok {
+			return nil, fmt.Errorf("Mnemonic word %d is not in the BIP39 English wordlist.", i+1)
+		}
+		data.Lsh(data, bitsPerWord)
+		data.Or(data, big.NewInt(int64(index)))
+	}
+	checksumBits := uint(len(words) / 3)
+	checksum := new(big.Int).And(data, big.NewInt(1<<checksumBits-1))
+	data.Rsh(data, checksumBits)
+	entropy := data.FillBytes(make([]byte, len(words)/3*4))
+	hash := sha256.Sum256(entropy)
+	if checksum.Int64() != int64(hash[0]>>(8-checksumBits)) {
+		return nil, errors.New("Mnemonic checksum does not match. Check the words were written down and typed in correctly.")
+	}
+	return entropy, nil
+}
@@ -0,0 +1,129 @@
+package bip39
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/hdwallet"
+	"github.com/CryptoProcessing/go-bitcoin-multisig/testutils"
+
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// testVectors are from the BIP39 reference implementation, all with the passphrase "TREZOR".
+var testVectors = []struct {
+	entropy  string
+	mnemonic string
+	seed     string
+}{
+	{"00000000000000000000000000000000", "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about", "c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04"},
+	{"7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f", "legal winner thank year wave sausage worth useful legal winner thank yellow", "2e8905819b8723fe2c1d161860e5ee1830318dbf49a83bd451cfb8440c28bd6fa457fe1296106559a3c80937a1c1069be3a3a5bd381ee6260e8d9739fce1f607"},
+	{"80808080808080808080808080808080", "letter advice cage absurd amount doctor acoustic avoid letter advice cage above", "d71de856f81a8acc65e6fc851a38d4d7ec216fd0796d0a6827a3ad6ed5511a30fa280f12eb2e47ed2ac03b5c462a0358d18d69fe4f985ec81778c1b370b652a8"},
+	{"ffffffffffffffffffffffffffffffff", "zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo wrong", "ac27495480225222079d7be181583751e86f571027b0497b5b5d11218e0a8a13332572917f0f8e5a589620c6f15b11c61dee327651a14c34e18231052e48c069"},
+	{"000000000000000000000000000000000000000000000000", "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon agent", "035895f2f481b1b0f01fcf8c289c794660b289981a78f8106447707fdd9666ca06da5a9a565181599b79f53b844d8a71dd9f439c52a3d7b3e8a79c906ac845fa"},
+	{"0000000000000000000000000000000000000000000000000000000000000000", "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon art", "bda85446c68413707090a52022edd26a1c9462295029f2e60cd7c4f2bbd3097170af7a4d73245cafa9c3cca8d561a7c3de6f5d4a10be8ed2a5e608d68f92fcc8"},
+	{"9e885d952ad362caeb4efe34a8e91bd2", "ozone drill grab fiber curtain grace pudding thank cruise elder eight picnic", "274ddc525802f7c828d8ef7ddbcdc5304e87ac3535913611fbbfa986d0c9e5476c91689f9c8a54fd55bd38606aa6a8595ad213d4c9c9f9aca3fb217069a41028"},
+	{"c0ba5a8e914111210f2bd131f3d5e08d", "scheme spot photo card baby mountain device kick cradle pact join borrow", "ea725895aaae8d4c1cf682c1bfd2d358d52ed9f0f0591131b559e2724bb234fca05aa9c02c57407e04ee9dc3b454aa63fbff483a8b11de949624b9f1831a9612"},
+	{"f585c11aec520db57dd353c69554b21a89b20fb0650966fa0a9d6f74fd989d8f", "void come effort suffer camp survey warrior heavy shoot primary clutch crush open amazing screen patrol group space point ten exist slush involve unfold", "01f5bced59dec48e362f2c45b5de68b9fd6c92c6634f44d6d40aab69056506f0e35524a518034ddc1192e1dacd32c1ed3eaa3c3b131c88ed8e7e54c49a5d0998"},
+}
+
+func TestWordlist(t *testing.T) {
+	//SHA256 of english.txt in the BIP39 repository
+	testWordlistHash := "2f5eed53a4727b4bf8880d8f3f199efc90e58503646d9ff8eff3a2ed3b24dbda"
+	if len(wordlist) != 2048 {
+		t.Fatalf("Wordlist has %d words, expected 2048.", len(wordlist))
+	}
+	hash := sha256.Sum256([]byte(strings.Join(wordlist, "\n") + "\n"))
+	wordlistHash := hex.EncodeToString(hash[:])
+	if wordlistHash != testWordlistHash {
+		testutils.CompareError(t, "Wordlist hash different from expected hash.", testWordlistHash, wordlistHash)
+	}
+}
+
+func TestBIP39Vectors(t *testing.T) {
+	for _, testVector := range testVectors {
+		testEntropy, _ := hex.DecodeString(testVector.entropy)
+		mnemonic, err := entropyToMnemonic(testEntropy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mnemonic != testVector.mnemonic {
+			testutils.CompareError(t, "Mnemonic different from expected mnemonic.", testVector.mnemonic, mnemonic)
+		}
+		entropy, err := mnemonicToEntropy(testVector.mnemonic)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entropyHex := hex.EncodeToString(entropy)
+		if entropyHex != testVector.entropy {
+			testutils.CompareError(t, "Mnemonic entropy different from expected entropy.", testVector.entropy, entropyHex)
+		}
+		seed, err := MnemonicToSeed(testVector.mnemonic, "TREZOR")
+		if err != nil {
+			t.Fatal(err)
+		}
+		seedHex := hex.EncodeToString(seed)
+		if seedHex != testVector.seed {
+			testutils.CompareError(t, "Seed different from expected seed.", testVector.seed, seedHex)
+		}
+	}
+}
+
+func TestMnemonicToMasterKey(t *testing.T) {
+	//BIP32 master key of the first test vector seed
+	testXPRV := "xprv9s21ZrQH143K3h3fDYiay8mocZ3afhfULfb5GX8kCBdno77K4HiA15Tg23wpbeF1pLfs1c5SPmYHrEpTuuRhxMwvKDwqdKiGJS9XFKzUsAF"
+	//Extra whitespace between words doesn't change the seed
+	seed, err := MnemonicToSeed("  abandon abandon abandon abandon abandon abandon\nabandon abandon abandon abandon abandon  about ", "TREZOR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	master, err := hdwallet.NewMasterKey(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if master.String() != testXPRV {
+		testutils.CompareError(t, "Master key of mnemonic seed different from expected key.", testXPRV, master.String())
+	}
+}
+
+func TestGenerateMnemonic(t *testing.T) {
+	for bits, wordCount := range map[int]int{128: 12, 160: 15, 192: 18, 224: 21, 256: 24} {
+		mnemonic, err := GenerateMnemonic(bits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(strings.Fields(mnemonic)) != wordCount {
+			testutils.CompareError(t, "Generated mnemonic word count different from expected count.", wordCount, len(strings.Fields(mnemonic)))
+		}
+		if err := ValidateMnemonic(mnemonic); err != nil {
+			t.Error(err)
+		}
+	}
+	for _, bits := range []int{0, 96, 129, 288} {
+		if _, err := GenerateMnemonic(bits); err == nil {
+			t.Errorf("GenerateMnemonic accepting %d bits of entropy.", bits)
+		}
+	}
+}
+
+func TestValidateMnemonic(t *testing.T) {
+	invalidMnemonics := []string{
+		"",
+		//Wrong checksum word
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon",
+		//Word not in wordlist
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon aboot",
+		//11 words
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+		//Words must be lower case
+		"Legal winner thank year wave sausage worth useful legal winner thank yellow",
+	}
+	for _, invalidMnemonic := range invalidMnemonics {
+		if err := ValidateMnemonic(invalidMnemonic); err == nil {
+			t.Errorf("ValidateMnemonic accepting invalid mnemonic %q.", invalidMnemonic)
+		}
+		if _, err := MnemonicToSeed(invalidMnemonic, ""); err == nil {
+			t.Errorf("MnemonicToSeed accepting invalid mnemonic %q.", invalidMnemonic)
+		}
+	}
+}
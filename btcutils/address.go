@@ -0,0 +1,228 @@
+// Base58Check encoding, and decoding of Base58Check and Bech32 encoded Bitcoin addresses, so the correct
+// scriptPubKey template can be chosen from the address version byte or witness version, and encoding of the P2PKH,
+// P2SH and P2WSH addresses of a key or script.
+package btcutils
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// base58Decode decodes a Base58 string into raw bytes, preserving leading zero bytes encoded as '1'.
+func base58Decode(encoded string) ([]byte, error) {
+	value := new(big.Int)
+	base := big.NewInt(58)
+	for _, char := range encoded {
+		digit := bytes.IndexRune([]byte(base58Alphabet), char)
+		if digit < 0 {
+			return nil, fmt.Errorf("Invalid Base58 character %q.", char)
+		}
+		value.Mul(value, base)
+		value.Add(value, big.NewInt(int64(digit)))
+	}
+	leadingZeros := 0
+	for leadingZeros < len(encoded) && encoded[leadingZeros] == base58Alphabet[0] {
+		leadingZeros++
+	}
+	return append(make([]byte, leadingZeros), value.Bytes()...), nil
+}
+
+// Base58CheckEncode encodes data, starting with its version bytes, as a Base58Check string with the first four
+// bytes of SHA256(SHA256(data)) appended as checksum, preserving leading zero bytes as '1'.
+func Base58CheckEncode(data []byte) string {
+	data = append(append([]byte{}, data...), DoubleSHA256(data)[:4]...)
+	value := new(big.Int).SetBytes(data)
+	base := big.NewInt(58)
+	remainder := new(big.Int)
+	var encoded []byte
+	for value.Sign() > 0 {
+		value.DivMod(value, base, remainder)
+		encoded = append(encoded, base58Alphabet[remainder.Int64()])
+	}
+	for i := 0; i < len(data) && data[i] == 0; i++ {
+		encoded = append(encoded, base58Alphabet[0])
+	}
+	//Digits were added least significant first
+	for i, j := 0, len(encoded)-1; i < j; i, j = i+1, j-1 {
+		encoded[i], encoded[j] = encoded[j], encoded[i]
+	}
+	return string(encoded)
+}
+
+// Base58CheckDecode decodes a Base58Check string, returning the data including its version bytes without the
+// checksum. A mistyped character almost certainly changes the checksum, so an error is returned rather than
+// corrupted data if the checksum does not match. Errors don't include the string, which may be a private key.
+func Base58CheckDecode(encoded string) ([]byte, error) {
+	decoded, err := base58Decode(encoded)
+	if err != nil {
+		return nil, err
+	}
+	//At least 1 version byte + 4 byte checksum
+	if len(decoded) < 5 {
+		return nil, fmt.Errorf("Base58Check string should decode to at least 5 bytes. Decoded string is %d bytes long.", len(decoded))
+	}
+	data := decoded[:len(decoded)-4]
+	checksum := decoded[len(decoded)-4:]
+	//Checksum is first four bytes of SHA256(SHA256(data))
+	if !bytes.Equal(DoubleSHA256(data)[:4], checksum) {
+		return nil, errors.New("Base58Check checksum does not match, the string is mistyped or truncated.")
+	}
+	return data, nil
+}
+
+// base58CheckEncode encodes a version byte and payload as a Base58Check string.
+func base58CheckEncode(version byte, payload []byte) string {
+	return Base58CheckEncode(append([]byte{version}, payload...))
+}
+
+// DecodeAddress decodes a Base58Check encoded address, returning its version byte and payload.
+// Returns an error if the address is malformed or its checksum does not match.
+func DecodeAddress(address string) (byte, []byte, error) {
+	decoded, err := Base58CheckDecode(address)
+	if err != nil {
+		return 0, nil, fmt.Errorf("Address %s is not valid. %s", address, err)
+	}
+	//1 version byte + at least 1 byte payload
+	if len(decoded) < 2 {
+		return 0, nil, fmt.Errorf("Address %s is too short.", address)
+	}
+	return decoded[0], decoded[1:], nil
+}
+
+// NewScriptPubKeyFromAddress creates the scriptPubKey paying to a Base58Check encoded address, using the
+// P2PKH or P2SH template depending on the address version byte, or to a Bech32 or Bech32m encoded SegWit address.
+// Taproot (version 1) addresses pay to OP_1 <32 byte x-only public key>.
+// Returns an error if the address is not encoded for the network given by params, or is a version 1 address whose
+// witness program is not 32 bytes long.
+func NewScriptPubKeyFromAddress(address string, params *NetworkParams) ([]byte, error) {
+	lowerAddress := strings.ToLower(address)
+	isSegWit := NetworkNames(func(network *NetworkParams) bool {
+		return strings.HasPrefix(lowerAddress, network.Bech32HRP+"1")
+	}) != ""
+	if isSegWit {
+		hrp, version, program, err := Bech32Decode(address)
+		if err != nil {
+			return nil, err
+		}
+		if hrp != params.Bech32HRP {
+			names := NetworkNames(func(network *NetworkParams) bool { return network.Bech32HRP == hrp })
+			return nil, fmt.Errorf("Address %s is a %s address, but the selected network is %s.", address, names, params.Name)
+		}
+		//Version 1 programs of other lengths are valid addresses but not Taproot outputs, and are unspendable for now
+		if version == 1 && len(program) != 32 {
+			return nil, fmt.Errorf("Version 1 address %s should encode a 32 byte Taproot output key. Encoded program is %d bytes long.", address, len(program))
+		}
+		return NewWitnessScriptPubKey(version, program)
+	}
+	version, hash, err := DecodeAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	if len(hash) != 20 {
+		return nil, fmt.Errorf("Address %s should encode a 20 byte hash. Encoded hash is %d bytes long.", address, len(hash))
+	}
+	switch version {
+	case params.P2PKHVersion:
+		return NewP2PKHScriptPubKey(hash)
+	case params.P2SHVersion:
+		return NewP2SHScriptPubKey(hash)
+	}
+	names := NetworkNames(func(network *NetworkParams) bool {
+		return network.P2PKHVersion == version || network.P2SHVersion == version
+	})
+	if names != "" {
+		return nil, fmt.Errorf("Address %s is a %s address, but the selected network is %s.", address, names, params.Name)
+	}
+	return nil, errors.New(fmt.Sprintf("Address %s has unknown version byte 0x%02x.", address, version))
+}
+
+// ScriptPubKeyToAddress returns the address on the network given by params that scriptPubKey pays to, the reverse of
+// NewScriptPubKeyFromAddress. P2PKH and P2SH scriptPubKeys give Base58Check addresses and witness programs give
+// Bech32 or Bech32m addresses. Returns an error for scriptPubKeys without an address, such as bare multisig and
+// OP_RETURN outputs.
+func ScriptPubKeyToAddress(scriptPubKey []byte, params *NetworkParams) (string, error) {
+	switch {
+	case len(scriptPubKey) == 25 && scriptPubKey[0] == OP_DUP && scriptPubKey[1] == OP_HASH160 && scriptPubKey[2] == 20 &&
+		scriptPubKey[23] == OP_EQUALVERIFY && scriptPubKey[24] == OP_CHECKSIG:
+		return base58CheckEncode(params.P2PKHVersion, scriptPubKey[3:23]), nil
+	case len(scriptPubKey) == 23 && scriptPubKey[0] == OP_HASH160 && scriptPubKey[1] == 20 && scriptPubKey[22] == OP_EQUAL:
+		return base58CheckEncode(params.P2SHVersion, scriptPubKey[2:22]), nil
+	case IsWitnessScriptPubKey(scriptPubKey):
+		version := scriptPubKey[0]
+		if version != OP_0 {
+			version -= OP_1 - 1
+		}
+		return Bech32Encode(params.Bech32HRP, version, scriptPubKey[2:])
+	}
+	return "", fmt.Errorf("scriptPubKey %x does not pay to an address.", scriptPubKey)
+}
+
+// PublicKeyToP2PKHAddress returns the P2PKH address of publicKey on the network given by params, the Base58Check
+// encoding of HASH160 of the public key with the network P2PKH version byte, eg. '1' addresses on mainnet and 'm' or
+// 'n' addresses on test networks. Compressed and uncompressed forms of a public key have different addresses.
+func PublicKeyToP2PKHAddress(publicKey []byte, params *NetworkParams) (string, error) {
+	publicKeyHash, err := Hash160(publicKey)
+	if err != nil {
+		return "", err
+	}
+	return base58CheckEncode(params.P2PKHVersion, publicKeyHash), nil
+}
+
+// PublicKeyToP2SHP2WPKHAddress returns the nested SegWit P2SH-P2WPKH address of the 33 byte compressed publicKey on
+// the network given by params, the P2SH address of the redeem script given by NewP2SHP2WPKHRedeemScript, eg. a '3'
+// address on mainnet. This is the address BIP49 wallets receive to, for senders that cannot pay to Bech32 addresses.
+func PublicKeyToP2SHP2WPKHAddress(publicKey []byte, params *NetworkParams) (string, error) {
+	redeemScript, err := NewP2SHP2WPKHRedeemScript(publicKey)
+	if err != nil {
+		return "", err
+	}
+	return RedeemScriptToP2SHAddress(redeemScript, params)
+}
+
+// RedeemScriptToP2SHAddress returns the P2SH address paying to redeemScript on the network given by params, the
+// Base58Check encoding of HASH160 of the script with the network P2SH version byte, eg. '3' addresses on mainnet.
+// Returns an error if the script is empty or longer than the 520 byte P2SH redeem script limit.
+func RedeemScriptToP2SHAddress(redeemScript []byte, params *NetworkParams) (string, error) {
+	if len(redeemScript) == 0 || len(redeemScript) > MaxRedeemScriptSize {
+		return "", fmt.Errorf("Redeem script must be between 1 and %d bytes long. Provided script is %d bytes long.", MaxRedeemScriptSize, len(redeemScript))
+	}
+	redeemScriptHash, err := Hash160(redeemScript)
+	if err != nil {
+		return "", err
+	}
+	return base58CheckEncode(params.P2SHVersion, redeemScriptHash), nil
+}
+
+// RedeemScriptToP2WSHAddress returns the native SegWit P2WSH address paying to witnessScript on the network given by
+// params, the Bech32 encoding of SHA256 of the script as a version 0 witness program, eg. 'bc1q' addresses on mainnet.
+// Unlike P2SH, the script is hashed with a single SHA256 rather than HASH160. Returns an error if the script is empty
+// or longer than the 3600 byte P2WSH witness script limit.
+func RedeemScriptToP2WSHAddress(witnessScript []byte, params *NetworkParams) (string, error) {
+	if len(witnessScript) == 0 || len(witnessScript) > MaxWitnessScriptSize {
+		return "", fmt.Errorf("Witness script must be between 1 and %d bytes long. Provided script is %d bytes long.", MaxWitnessScriptSize, len(witnessScript))
+	}
+	witnessScriptHash := sha256.Sum256(witnessScript)
+	return Bech32Encode(params.Bech32HRP, 0, witnessScriptHash[:])
+}
+
+// RedeemScriptToP2SHP2WSHAddress returns the nested SegWit P2SH-P2WSH address paying to witnessScript on the network
+// given by params, for senders that cannot pay to Bech32 addresses. The P2SH redeem script is the P2WSH scriptPubKey
+// OP_0 <SHA256 of witnessScript> given by NewP2WSHScriptPubKey, and the address is the P2SH address of that script,
+// eg. a '3' address on mainnet. Returns an error if the script is empty or longer than the 3600 byte P2WSH witness
+// script limit.
+func RedeemScriptToP2SHP2WSHAddress(witnessScript []byte, params *NetworkParams) (string, error) {
+	if len(witnessScript) == 0 || len(witnessScript) > MaxWitnessScriptSize {
+		return "", fmt.Errorf("Witness script must be between 1 and %d bytes long. Provided script is %d bytes long.", MaxWitnessScriptSize, len(witnessScript))
+	}
+	redeemScript, err := NewP2WSHScriptPubKey(witnessScript)
+	if err != nil {
+		return "", err
+	}
+	return RedeemScriptToP2SHAddress(redeemScript, params)
+}
@@ -0,0 +1,384 @@
+package btcutils
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/testutils"
+
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestDecodeAddress(t *testing.T) {
+	testAddress := "13LSqJeZBpqLHzmLkJ5mvRHiM11waShFUP"
+	testVersion := MainNetParams.P2PKHVersion
+	testHashHex := "199db810a3c8ae5e55c0432d2b72e55b0634f790"
+
+	version, hash, err := DecodeAddress(testAddress)
+	if err != nil {
+		t.Error(err)
+	}
+	if version != testVersion {
+		testutils.CompareError(t, "Decoded address version different from expected version.", testVersion, version)
+	}
+	hashHex := hex.EncodeToString(hash)
+	if hashHex != testHashHex {
+		testutils.CompareError(t, "Decoded address hash different from expected hash.", testHashHex, hashHex)
+	}
+
+	invalidAddresses := []string{
+		"",                                   //empty address
+		"13LSqJeZBpqLHzmLkJ5mvRHiM11waShFUQ", //bad checksum
+		"13LSqJeZBpqLHzmLkJ5mvRHiM11waShFU0", //invalid Base58 character
+	}
+	for _, invalidAddress := range invalidAddresses {
+		if _, _, err := DecodeAddress(invalidAddress); err == nil {
+			t.Errorf("DecodeAddress accepting invalid address %s as valid.", invalidAddress)
+		}
+	}
+}
+
+func TestBase58Check(t *testing.T) {
+	//Version byte followed by a public key hash, and a 4 byte BIP32 version followed by part of an extended key
+	testCases := []struct {
+		dataHex string
+		encoded string
+	}{
+		{"00199db810a3c8ae5e55c0432d2b72e55b0634f790", "13LSqJeZBpqLHzmLkJ5mvRHiM11waShFUP"},
+		{"6f751e76e8199196d454941c45d1b3a323f1433bd6", "mrCDrCybB6J1vRfbwM5hemdJz73FwDBC8r"},
+	}
+	for _, testCase := range testCases {
+		testData, _ := hex.DecodeString(testCase.dataHex)
+		encoded := Base58CheckEncode(testData)
+		if encoded != testCase.encoded {
+			testutils.CompareError(t, "Base58Check encoding different from expected encoding.", testCase.encoded, encoded)
+		}
+		data, err := Base58CheckDecode(encoded)
+		if err != nil {
+			t.Error(err)
+		}
+		dataHex := hex.EncodeToString(data)
+		if dataHex != testCase.dataHex {
+			testutils.CompareError(t, "Base58Check decoded data different from expected data.", testCase.dataHex, dataHex)
+		}
+	}
+
+	//Any single mistyped character must be caught by the checksum rather than decoding to other bytes
+	testEncoded := testCases[0].encoded
+	for i := range testEncoded {
+		for _, char := range base58Alphabet {
+			if byte(char) == testEncoded[i] {
+				continue
+			}
+			mistyped := testEncoded[:i] + string(char) + testEncoded[i+1:]
+			if _, err := Base58CheckDecode(mistyped); err == nil {
+				t.Errorf("Base58CheckDecode accepting %s with character %d mistyped.", mistyped, i)
+			}
+		}
+	}
+	invalidEncodings := []string{
+		"",                                   //empty
+		"1111",                               //too short for a checksum
+		testEncoded[:len(testEncoded)-1],     //truncated
+		testEncoded + "1",                    //extended
+		"13LSqJeZBpqLHzmLkJ5mvRHiM11waShFU0", //invalid Base58 character
+	}
+	for _, invalidEncoding := range invalidEncodings {
+		if _, err := Base58CheckDecode(invalidEncoding); err == nil {
+			t.Errorf("Base58CheckDecode accepting invalid encoding %s.", invalidEncoding)
+		}
+	}
+}
+
+func TestNewScriptPubKeyFromAddress(t *testing.T) {
+	testCases := []struct {
+		address         string
+		params          *NetworkParams
+		scriptPubKeyHex string
+	}{
+		{"13LSqJeZBpqLHzmLkJ5mvRHiM11waShFUP", &MainNetParams, "76a914199db810a3c8ae5e55c0432d2b72e55b0634f79088ac"},                                                //P2PKH
+		{"347N1Thc213QqfYCz3PZkjoJpNv5b14kBd", &MainNetParams, "a9141a8b0026343166625c7475f01e48b5ede8c0252e87"},                                                    //P2SH
+		{"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4", &MainNetParams, "0014751e76e8199196d454941c45d1b3a323f1433bd6"},                                              //P2WPKH
+		{"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0", &MainNetParams, "512079be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"},  //Witness version 1
+		{"mhrQ8MjXzrGb57ExTs49kLW3CzceTFTacX", &TestNet3Params, "76a914199db810a3c8ae5e55c0432d2b72e55b0634f79088ac"},                                               //Testnet P2PKH
+		{"2Mufa5CdddTYm3TAkfB1SNgna2j8FM6W9sq", &TestNet3Params, "a9141a8b0026343166625c7475f01e48b5ede8c0252e87"},                                                  //Testnet P2SH
+		{"tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sl5k7", &TestNet3Params, "00201863143c14c5166804bd19203356da136c985678cd4d27a1b8c6329604903262"}, //Testnet P2WSH
+		{"tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sl5k7", &SigNetParams, "00201863143c14c5166804bd19203356da136c985678cd4d27a1b8c6329604903262"},   //Signet P2WSH
+		{"mhrQ8MjXzrGb57ExTs49kLW3CzceTFTacX", &RegTestParams, "76a914199db810a3c8ae5e55c0432d2b72e55b0634f79088ac"},                                                //Regtest P2PKH
+		{"bcrt1qw508d6qejxtdg4y5r3zarvary0c5xw7kygt080", &RegTestParams, "0014751e76e8199196d454941c45d1b3a323f1433bd6"},                                            //Regtest P2WPKH
+		{"tb1pqqqqp399et2xygdj5xreqhjjvcmzhxw4aywxecjdzew6hylgvsesf3hn0c", &TestNet3Params, "5120000000c4a5cad46221b2a187905e5266362b99d5e91c6ce24d165dab93e86433"}, //Testnet P2TR
+	}
+	for _, testCase := range testCases {
+		scriptPubKey, err := NewScriptPubKeyFromAddress(testCase.address, testCase.params)
+		if err != nil {
+			t.Error(err)
+		}
+		scriptPubKeyHex := hex.EncodeToString(scriptPubKey)
+		if scriptPubKeyHex != testCase.scriptPubKeyHex {
+			testutils.CompareError(t, "scriptPubKey for address different from expected script.", testCase.scriptPubKeyHex, scriptPubKeyHex)
+		}
+	}
+	//Private key WIF is not a valid address
+	if _, err := NewScriptPubKeyFromAddress("5JJyqG4bb15zqi7fTA4b227aUxQhBo1Ux6qX69ngeXYLr7fk2hs", &MainNetParams); err == nil {
+		t.Error("NewScriptPubKeyFromAddress accepting address with unknown version byte.")
+	}
+	//Addresses for a different network than selected
+	wrongNetworkCases := []struct {
+		address string
+		params  *NetworkParams
+	}{
+		{"tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sl5k7", &MainNetParams},
+		{"mhrQ8MjXzrGb57ExTs49kLW3CzceTFTacX", &MainNetParams},
+		{"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4", &TestNet3Params},
+		{"13LSqJeZBpqLHzmLkJ5mvRHiM11waShFUP", &TestNet3Params},
+		{"347N1Thc213QqfYCz3PZkjoJpNv5b14kBd", &TestNet3Params},
+		{"tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sl5k7", &RegTestParams},
+		{"bcrt1qw508d6qejxtdg4y5r3zarvary0c5xw7kygt080", &TestNet3Params},
+		{"bcrt1qw508d6qejxtdg4y5r3zarvary0c5xw7kygt080", &MainNetParams},
+	}
+	for _, testCase := range wrongNetworkCases {
+		_, err := NewScriptPubKeyFromAddress(testCase.address, testCase.params)
+		if err == nil {
+			t.Errorf("NewScriptPubKeyFromAddress accepting address %s on %s.", testCase.address, testCase.params.Name)
+		} else if !strings.Contains(err.Error(), "but the selected network is "+testCase.params.Name) {
+			testutils.CompareError(t, "Error for address on wrong network different from expected error.", "... but the selected network is "+testCase.params.Name+".", err.Error())
+		}
+	}
+	//BIP350 vectors: Bech32 checksum on witness version 1 addresses, Bech32m checksum on version 0 addresses, and a
+	//valid version 1 address with a 40 byte program that is not a Taproot output
+	for _, invalidAddress := range []string{
+		"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqh2y7hd",
+		"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kemeawh",
+		"tb1q0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vq24jc47",
+		"bc1pw508d6qejxtdg4y5r3zarvary0c5xw7kw508d6qejxtdg4y5r3zarvary0c5xw7kt5nd6y",
+	} {
+		params := &MainNetParams
+		if strings.HasPrefix(invalidAddress, "tb1") {
+			params = &TestNet3Params
+		}
+		if _, err := NewScriptPubKeyFromAddress(invalidAddress, params); err == nil {
+			t.Errorf("NewScriptPubKeyFromAddress accepting invalid SegWit address %s.", invalidAddress)
+		}
+	}
+}
+
+func TestScriptPubKeyToAddress(t *testing.T) {
+	testCases := []struct {
+		scriptPubKeyHex string
+		params          *NetworkParams
+		address         string
+	}{
+		{"76a914199db810a3c8ae5e55c0432d2b72e55b0634f79088ac", &MainNetParams, "13LSqJeZBpqLHzmLkJ5mvRHiM11waShFUP"},
+		{"a9141a8b0026343166625c7475f01e48b5ede8c0252e87", &MainNetParams, "347N1Thc213QqfYCz3PZkjoJpNv5b14kBd"},
+		{"0014751e76e8199196d454941c45d1b3a323f1433bd6", &MainNetParams, "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4"},
+		{"512079be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798", &MainNetParams, "bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0"},
+		{"a9141a8b0026343166625c7475f01e48b5ede8c0252e87", &TestNet3Params, "2Mufa5CdddTYm3TAkfB1SNgna2j8FM6W9sq"},
+		{"00201863143c14c5166804bd19203356da136c985678cd4d27a1b8c6329604903262", &TestNet3Params, "tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sl5k7"},
+		{"0014751e76e8199196d454941c45d1b3a323f1433bd6", &RegTestParams, "bcrt1qw508d6qejxtdg4y5r3zarvary0c5xw7kygt080"},
+	}
+	for _, testCase := range testCases {
+		scriptPubKey, _ := hex.DecodeString(testCase.scriptPubKeyHex)
+		address, err := ScriptPubKeyToAddress(scriptPubKey, testCase.params)
+		if err != nil {
+			t.Error(err)
+		}
+		if address != testCase.address {
+			testutils.CompareError(t, "Address of scriptPubKey different from expected address.", testCase.address, address)
+		}
+	}
+	//P2PK, bare 1-of-1 multisig and OP_RETURN outputs have no address
+	for _, scriptPubKeyHex := range []string{
+		"2103c9f4836b9a4f77fc0d81f7bcb01b7f1b35916864b9476c241ce9fc198bd25432ac",
+		"512103c9f4836b9a4f77fc0d81f7bcb01b7f1b35916864b9476c241ce9fc198bd2543251ae",
+		"6a0568656c6c6f",
+	} {
+		scriptPubKey, _ := hex.DecodeString(scriptPubKeyHex)
+		if _, err := ScriptPubKeyToAddress(scriptPubKey, &MainNetParams); err == nil {
+			t.Errorf("ScriptPubKeyToAddress giving an address for scriptPubKey %s.", scriptPubKeyHex)
+		}
+	}
+}
+
+func TestRedeemScriptToP2SHAddress(t *testing.T) {
+	testCases := []struct {
+		redeemScriptHex string
+		params          *NetworkParams
+		address         string
+	}{
+		//2-of-3 multisig of the README address example
+		{"524104a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575fa349b5694ed3155b136f09e63975a1700c9f4d4df849323dac06cf3bd6458cd41046ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640de68c2fe913d363a51154a0c62d7adea1b822d05035077418267b1a1379790187410411ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef992f63280567f52f5ba870678b4ab4ff6c8ea600bd217870a8b4f1f09f3a8e8353ae", &MainNetParams, "347N1Thc213QqfYCz3PZkjoJpNv5b14kBd"},
+		//BIP67 test vector 2
+		{"522102632b12f4ac5b1d1b72b2a3b508c19172de44f6f46bcee50ba33f3f9291e47ed021027735a29bae7780a9755fae7a1c4374c656ac6a69ea9f3697fda61bb99a4f3e772102e2cc6bd5f45edd43bebe7cb9b675f0ce9ed3efe613b177588290ad188d11b40453ae", &MainNetParams, "3CKHTjBKxCARLzwABMu9yD85kvtm7WnMfH"},
+	}
+	for _, testCase := range testCases {
+		redeemScript, _ := hex.DecodeString(testCase.redeemScriptHex)
+		address, err := RedeemScriptToP2SHAddress(redeemScript, testCase.params)
+		if err != nil {
+			t.Error(err)
+		}
+		if address != testCase.address {
+			testutils.CompareError(t, "P2SH address different from expected address.", testCase.address, address)
+		}
+		//The address decodes back to the P2SH scriptPubKey of the script
+		scriptPubKey, err := NewScriptPubKeyFromAddress(address, testCase.params)
+		if err != nil {
+			t.Error(err)
+		}
+		redeemScriptHash, _ := Hash160(redeemScript)
+		testScriptPubKey, _ := NewP2SHScriptPubKey(redeemScriptHash)
+		if hex.EncodeToString(scriptPubKey) != hex.EncodeToString(testScriptPubKey) {
+			testutils.CompareError(t, "P2SH address scriptPubKey different from expected scriptPubKey.", hex.EncodeToString(testScriptPubKey), hex.EncodeToString(scriptPubKey))
+		}
+	}
+	//Testnet addresses have the 0xc4 version byte and start with '2'
+	redeemScript, _ := hex.DecodeString(testCases[1].redeemScriptHex)
+	address, err := RedeemScriptToP2SHAddress(redeemScript, &TestNet3Params)
+	if err != nil {
+		t.Error(err)
+	}
+	version, _, err := DecodeAddress(address)
+	if err != nil || version != 0xc4 || !strings.HasPrefix(address, "2") {
+		t.Errorf("Testnet P2SH address %s has version byte 0x%02x.", address, version)
+	}
+
+	if _, err := RedeemScriptToP2SHAddress(nil, &MainNetParams); err == nil {
+		t.Error("RedeemScriptToP2SHAddress accepting empty redeem script.")
+	}
+	if _, err := RedeemScriptToP2SHAddress(make([]byte, MaxRedeemScriptSize+1), &MainNetParams); err == nil {
+		t.Error("RedeemScriptToP2SHAddress accepting redeem script longer than the P2SH limit.")
+	}
+}
+
+func TestRedeemScriptToP2WSHAddress(t *testing.T) {
+	//BIP173 test vectors, paying to <pubkey> OP_CHECKSIG
+	testWitnessScript, _ := hex.DecodeString("210279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798ac")
+	testCases := []struct {
+		params  *NetworkParams
+		address string
+	}{
+		{&MainNetParams, "bc1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3qccfmv3"},
+		{&TestNet3Params, "tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sl5k7"},
+	}
+	for _, testCase := range testCases {
+		address, err := RedeemScriptToP2WSHAddress(testWitnessScript, testCase.params)
+		if err != nil {
+			t.Error(err)
+		}
+		if address != testCase.address {
+			testutils.CompareError(t, "P2WSH address different from expected address.", testCase.address, address)
+		}
+	}
+	if _, err := RedeemScriptToP2WSHAddress(nil, &MainNetParams); err == nil {
+		t.Error("RedeemScriptToP2WSHAddress accepting empty witness script.")
+	}
+	if _, err := RedeemScriptToP2WSHAddress(make([]byte, MaxWitnessScriptSize), &MainNetParams); err != nil {
+		t.Error(err)
+	}
+	if _, err := RedeemScriptToP2WSHAddress(make([]byte, MaxWitnessScriptSize+1), &MainNetParams); err == nil {
+		t.Error("RedeemScriptToP2WSHAddress accepting witness script longer than the P2WSH limit.")
+	}
+}
+
+func TestRedeemScriptToP2SHP2WSHAddress(t *testing.T) {
+	//BIP143 P2SH-P2WSH 6-of-6 multisig example, with redeemScript 0020a16b5755f7f6f96dbd65f5f0d6ab9418b89af4b1f14a1bb8a09062c35f0dcb54
+	testWitnessScript, _ := hex.DecodeString("56210307b8ae49ac90a048e9b53357a2354b3334e9c8bee813ecb98e99a7e07e8c3ba32103b28f0c28bfab54554ae8c658ac5c3e0ce6e79ad336331f78c428dd43eea8449b21034b8113d703413d57761b8b9781957b8c0ac1dfe69f492580ca4195f50376ba4a21033400f6afecb833092a9a21cfdf1ed1376e58c5d1f47de74683123987e967a8f42103a6d48b1131e94ba04d9737d61acdaa1322008af9602b3b14862c07a1789aac162102d8b661b0b3302ee2f162b09e07a55ad5dfbe673a9f01d9f0c19617681024306b56ae")
+	testAddress := "3Fh4BBqrshHn1qc7pFf294rFGbNsXNDcDa"
+	testScriptPubKeyHex := "a9149993a429037b5d912407a71c252019287b8d27a587"
+
+	address, err := RedeemScriptToP2SHP2WSHAddress(testWitnessScript, &MainNetParams)
+	if err != nil {
+		t.Error(err)
+	}
+	if address != testAddress {
+		testutils.CompareError(t, "P2SH-P2WSH address different from expected address.", testAddress, address)
+	}
+	scriptPubKey, err := NewScriptPubKeyFromAddress(address, &MainNetParams)
+	if err != nil {
+		t.Error(err)
+	}
+	if hex.EncodeToString(scriptPubKey) != testScriptPubKeyHex {
+		testutils.CompareError(t, "P2SH-P2WSH scriptPubKey different from expected scriptPubKey.", testScriptPubKeyHex, hex.EncodeToString(scriptPubKey))
+	}
+	if _, err := RedeemScriptToP2SHP2WSHAddress(nil, &MainNetParams); err == nil {
+		t.Error("RedeemScriptToP2SHP2WSHAddress accepting empty witness script.")
+	}
+	if _, err := RedeemScriptToP2SHP2WSHAddress(make([]byte, MaxWitnessScriptSize+1), &MainNetParams); err == nil {
+		t.Error("RedeemScriptToP2SHP2WSHAddress accepting witness script longer than the P2WSH limit.")
+	}
+}
+
+func TestPublicKeyToP2SHP2WPKHAddress(t *testing.T) {
+	testCases := []struct {
+		publicKeyHex string
+		params       *NetworkParams
+		address      string
+	}{
+		//BIP143 P2SH-P2WPKH example
+		{"03ad1d8e89212f0b92c74d23bb710c00662ad1470198ac48c43f7d6f93a2a26873", &MainNetParams, "38BW8nqpHSWpkf5sXrQd2xYwvnPJwP59ic"},
+		//BIP49 test vector, first receiving address of account 0
+		{"03a1af804ac108a8a51782198c2d034b28bf90c8803f5a53f76276fa69a4eae77f", &TestNet3Params, "2Mww8dCYPUpKHofjgcXcBCEGmniw9CoaiD2"},
+	}
+	for _, testCase := range testCases {
+		publicKey, _ := hex.DecodeString(testCase.publicKeyHex)
+		address, err := PublicKeyToP2SHP2WPKHAddress(publicKey, testCase.params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if address != testCase.address {
+			testutils.CompareError(t, "P2SH-P2WPKH address different from expected address.", testCase.address, address)
+		}
+		//The address pays to the same scriptPubKey CreateP2SHP2WPKHScriptPubKey gives
+		scriptPubKey, err := NewScriptPubKeyFromAddress(address, testCase.params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectedScriptPubKey, _ := CreateP2SHP2WPKHScriptPubKey(publicKey)
+		if !bytes.Equal(scriptPubKey, expectedScriptPubKey) {
+			testutils.CompareError(t, "P2SH-P2WPKH address scriptPubKey different from expected scriptPubKey.", hex.EncodeToString(expectedScriptPubKey), hex.EncodeToString(scriptPubKey))
+		}
+	}
+}
+
+func TestPublicKeyToP2PKHAddress(t *testing.T) {
+	//Addresses of private key 1, as given by Bitcoin Core for each network
+	testPrivateKey, _ := hex.DecodeString("0000000000000000000000000000000000000000000000000000000000000001")
+	testCases := []struct {
+		compressed bool
+		params     *NetworkParams
+		address    string
+	}{
+		{true, &MainNetParams, "1BgGZ9tcN4rm9KBzDn7KprQz87SZ26SAMH"},
+		{false, &MainNetParams, "1EHNa6Q4Jz2uvNExL497mE43ikXhwF6kZm"},
+		{true, &TestNet3Params, "mrCDrCybB6J1vRfbwM5hemdJz73FwDBC8r"},
+		{false, &TestNet3Params, "mtoKs9V381UAhUia3d7Vb9GNak8Qvmcsme"},
+		{true, &SigNetParams, "mrCDrCybB6J1vRfbwM5hemdJz73FwDBC8r"},
+		{true, &RegTestParams, "mrCDrCybB6J1vRfbwM5hemdJz73FwDBC8r"},
+	}
+	for _, testCase := range testCases {
+		var publicKey []byte
+		var err error
+		if testCase.compressed {
+			publicKey, err = NewCompressedPublicKey(testPrivateKey)
+		} else {
+			publicKey, err = NewPublicKey(testPrivateKey)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		address, err := PublicKeyToP2PKHAddress(publicKey, testCase.params)
+		if err != nil {
+			t.Error(err)
+		}
+		if address != testCase.address {
+			testutils.CompareError(t, "P2PKH address different from expected address.", testCase.address, address)
+		}
+		//The address must decode back to the public key hash on its own network only
+		if _, err := NewScriptPubKeyFromAddress(address, testCase.params); err != nil {
+			t.Error(err)
+		}
+	}
+
+	if _, err := PublicKeyToP2PKHAddress(nil, &TestNet3Params); err == nil {
+		t.Error("PublicKeyToP2PKHAddress accepting empty public key.")
+	}
+}
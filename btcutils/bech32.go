@@ -0,0 +1,209 @@
+// Bech32 (BIP173) and Bech32m (BIP350) encoding of segregated witness addresses.
+// Witness version 0 addresses use Bech32, version 1 and above use Bech32m.
+package btcutils
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+// bech32Encoding is the checksum constant distinguishing Bech32 from Bech32m.
+type bech32Encoding uint32
+
+const (
+	bech32Const  bech32Encoding = 1
+	bech32mConst bech32Encoding = 0x2bc830a3
+)
+
+// Limits on the length of a Bech32 string and its checksum.
+const (
+	bech32MaxLength      = 90
+	bech32ChecksumLength = 6
+)
+
+// bech32Polymod computes the BCH checksum of the 5 bit values, as defined in BIP173.
+func bech32Polymod(values []byte) uint32 {
+	generator := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	checksum := uint32(1)
+	for _, value := range values {
+		top := checksum >> 25
+		checksum = (checksum&0x1ffffff)<<5 ^ uint32(value)
+		for i := 0; i < 5; i++ {
+			if (top>>uint(i))&1 == 1 {
+				checksum ^= generator[i]
+			}
+		}
+	}
+	return checksum
+}
+
+// bech32HRPExpand expands the human readable part into 5 bit values for checksum computation.
+func bech32HRPExpand(hrp string) []byte {
+	expanded := make([]byte, 0, len(hrp)*2+1)
+	for i := 0; i < len(hrp); i++ {
+		expanded = append(expanded, hrp[i]>>5)
+	}
+	expanded = append(expanded, 0)
+	for i := 0; i < len(hrp); i++ {
+		expanded = append(expanded, hrp[i]&31)
+	}
+	return expanded
+}
+
+// bech32Checksum computes the 6 checksum values for the human readable part and 5 bit data.
+func bech32Checksum(hrp string, data []byte, encoding bech32Encoding) []byte {
+	values := append(bech32HRPExpand(hrp), data...)
+	values = append(values, make([]byte, bech32ChecksumLength)...)
+	polymod := bech32Polymod(values) ^ uint32(encoding)
+	checksum := make([]byte, bech32ChecksumLength)
+	for i := range checksum {
+		checksum[i] = byte(polymod>>uint(5*(5-i))) & 31
+	}
+	return checksum
+}
+
+// bech32EncodeString encodes the human readable part and 5 bit data, appending the checksum.
+func bech32EncodeString(hrp string, data []byte, encoding bech32Encoding) string {
+	var encoded strings.Builder
+	encoded.WriteString(hrp)
+	encoded.WriteByte('1') //separator
+	for _, value := range append(data, bech32Checksum(hrp, data, encoding)...) {
+		encoded.WriteByte(bech32Charset[value])
+	}
+	return encoded.String()
+}
+
+// bech32DecodeString splits a Bech32 or Bech32m string into its lowercase human readable part and 5 bit data,
+// returning which checksum constant it was encoded with. The checksum is stripped from the returned data.
+func bech32DecodeString(encoded string) (string, []byte, bech32Encoding, error) {
+	if len(encoded) > bech32MaxLength {
+		return "", nil, 0, fmt.Errorf("Bech32 string %s is longer than %d characters.", encoded, bech32MaxLength)
+	}
+	lower := strings.ToLower(encoded)
+	if lower != encoded && strings.ToUpper(encoded) != encoded {
+		return "", nil, 0, fmt.Errorf("Bech32 string %s mixes upper and lower case.", encoded)
+	}
+	for i := 0; i < len(lower); i++ {
+		if lower[i] < 33 || lower[i] > 126 {
+			return "", nil, 0, fmt.Errorf("Bech32 string %s contains invalid character 0x%02x.", encoded, lower[i])
+		}
+	}
+	separator := strings.LastIndexByte(lower, '1')
+	if separator < 1 || separator+1+bech32ChecksumLength > len(lower) {
+		return "", nil, 0, fmt.Errorf("Bech32 string %s has an invalid separator position.", encoded)
+	}
+	hrp := lower[:separator]
+	data := make([]byte, 0, len(lower)-separator-1)
+	for i := separator + 1; i < len(lower); i++ {
+		value := strings.IndexByte(bech32Charset, lower[i])
+		if value < 0 {
+			return "", nil, 0, fmt.Errorf("Bech32 string %s contains invalid character %q.", encoded, lower[i])
+		}
+		data = append(data, byte(value))
+	}
+	encoding := bech32Encoding(bech32Polymod(append(bech32HRPExpand(hrp), data...)))
+	if encoding != bech32Const && encoding != bech32mConst {
+		return "", nil, 0, fmt.Errorf("Bech32 string %s has an invalid checksum.", encoded)
+	}
+	return hrp, data[:len(data)-bech32ChecksumLength], encoding, nil
+}
+
+// convertBits regroups data from fromBits to toBits sized groups. With pad, incomplete trailing groups are
+// zero padded, otherwise any padding left over must be fewer than fromBits zero bits.
+func convertBits(data []byte, fromBits uint, toBits uint, pad bool) ([]byte, error) {
+	var accumulator uint32
+	var bits uint
+	maxValue := uint32(1)<<toBits - 1
+	converted := make([]byte, 0, len(data)*int(fromBits)/int(toBits)+1)
+	for _, value := range data {
+		if uint32(value)>>fromBits != 0 {
+			return nil, fmt.Errorf("Value 0x%02x does not fit in %d bits.", value, fromBits)
+		}
+		accumulator = accumulator<<fromBits | uint32(value)
+		bits += fromBits
+		for bits >= toBits {
+			bits -= toBits
+			converted = append(converted, byte(accumulator>>bits&maxValue))
+		}
+	}
+	if pad {
+		if bits > 0 {
+			converted = append(converted, byte(accumulator<<(toBits-bits)&maxValue))
+		}
+	} else if bits >= fromBits || accumulator<<(toBits-bits)&maxValue != 0 {
+		return nil, errors.New("Invalid padding in Bech32 data.")
+	}
+	return converted, nil
+}
+
+// checkWitnessProgram validates the witness version and program length as per BIP141.
+func checkWitnessProgram(version byte, program []byte) error {
+	if version > 16 {
+		return fmt.Errorf("Witness version should be between 0 and 16. Provided version is %d.", version)
+	}
+	if len(program) < 2 || len(program) > 40 {
+		return fmt.Errorf("Witness program should be between 2 and 40 bytes long. Provided program is %d bytes long.", len(program))
+	}
+	if version == 0 && len(program) != 20 && len(program) != 32 {
+		return fmt.Errorf("Version 0 witness program should be 20 or 32 bytes long. Provided program is %d bytes long.", len(program))
+	}
+	return nil
+}
+
+// Bech32Encode encodes a witness program as a SegWit address with the human readable part hrp, eg. "bc" for
+// mainnet. Version 0 programs are encoded with Bech32, version 1 and above with Bech32m.
+func Bech32Encode(hrp string, version byte, program []byte) (string, error) {
+	err := checkWitnessProgram(version, program)
+	if err != nil {
+		return "", err
+	}
+	if hrp == "" || strings.ToLower(hrp) != hrp {
+		return "", fmt.Errorf("Human readable part %q should be non-empty and lowercase.", hrp)
+	}
+	converted, err := convertBits(program, 8, 5, true)
+	if err != nil {
+		return "", err
+	}
+	encoding := bech32Const
+	if version > 0 {
+		encoding = bech32mConst
+	}
+	address := bech32EncodeString(hrp, append([]byte{version}, converted...), encoding)
+	if len(address) > bech32MaxLength {
+		return "", fmt.Errorf("Address is longer than %d characters.", bech32MaxLength)
+	}
+	return address, nil
+}
+
+// Bech32Decode decodes a SegWit address into its human readable part, witness version and witness program.
+// Returns an error if the checksum is invalid or does not match the witness version, the address mixes case,
+// or the witness program length is invalid for its version. Callers should check the returned human readable
+// part matches the expected network.
+func Bech32Decode(address string) (hrp string, version byte, program []byte, err error) {
+	hrp, data, encoding, err := bech32DecodeString(address)
+	if err != nil {
+		return "", 0, nil, err
+	}
+	if len(data) == 0 {
+		return "", 0, nil, fmt.Errorf("Address %s has no witness version.", address)
+	}
+	version = data[0]
+	program, err = convertBits(data[1:], 5, 8, false)
+	if err != nil {
+		return "", 0, nil, err
+	}
+	err = checkWitnessProgram(version, program)
+	if err != nil {
+		return "", 0, nil, err
+	}
+	if version == 0 && encoding != bech32Const {
+		return "", 0, nil, fmt.Errorf("Version 0 address %s should use a Bech32 checksum.", address)
+	}
+	if version > 0 && encoding != bech32mConst {
+		return "", 0, nil, fmt.Errorf("Version %d address %s should use a Bech32m checksum.", version, address)
+	}
+	return hrp, version, program, nil
+}
@@ -0,0 +1,187 @@
+package btcutils
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/testutils"
+
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestBech32DecodeString(t *testing.T) {
+	//BIP173 and BIP350 valid checksums
+	testCases := []struct {
+		encoded  string
+		encoding bech32Encoding
+	}{
+		{"A12UEL5L", bech32Const},
+		{"a12uel5l", bech32Const},
+		{"an83characterlonghumanreadablepartthatcontainsthenumber1andtheexcludedcharactersbio1tt5tgs", bech32Const},
+		{"abcdef1qpzry9x8gf2tvdw0s3jn54khce6mua7lmqqqxw", bech32Const},
+		{"split1checkupstagehandshakeupstreamerranterredcaperred2y9e3w", bech32Const},
+		{"?1ezyfcl", bech32Const},
+		{"A1LQFN3A", bech32mConst},
+		{"a1lqfn3a", bech32mConst},
+		{"an83characterlonghumanreadablepartthatcontainsthetheexcludedcharactersbioandnumber11sg7hg6", bech32mConst},
+		{"abcdef1l7aum6echk45nj3s0wdvt2fg8x9yrzpqzd3ryx", bech32mConst},
+		{"split1checkupstagehandshakeupstreamerranterredcaperredlc445v", bech32mConst},
+		{"?1v759aa", bech32mConst},
+	}
+	for _, testCase := range testCases {
+		hrp, data, encoding, err := bech32DecodeString(testCase.encoded)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if encoding != testCase.encoding {
+			testutils.CompareError(t, "Bech32 checksum constant different from expected constant.", testCase.encoding, encoding)
+		}
+		//Re-encoding gives the lowercase form of the original string
+		reencoded := bech32EncodeString(hrp, data, encoding)
+		if reencoded != strings.ToLower(testCase.encoded) {
+			testutils.CompareError(t, "Re-encoded Bech32 string different from expected string.", strings.ToLower(testCase.encoded), reencoded)
+		}
+	}
+}
+
+func TestBech32DecodeStringInvalid(t *testing.T) {
+	//BIP173 and BIP350 invalid checksums
+	invalidStrings := []string{
+		"\x201nwldj5", //human readable part character out of range
+		"\x7f1axkwrx", //human readable part character out of range
+		"\x801eym55h", //human readable part character out of range
+		"an84characterslonghumanreadablepartthatcontainsthenumber1andtheexcludedcharactersbio1569pvx", //overall max length exceeded
+		"pzry9x0s0muk",  //no separator character
+		"1pzry9x0s0muk", //empty human readable part
+		"x1b4n0q5v",     //invalid data character
+		"li1dgmt3",      //too short checksum
+		"de1lg7wt\xff",  //invalid character in checksum
+		"A1G7SGD8",      //checksum calculated with uppercase form of human readable part
+		"10a06t8",       //empty human readable part
+		"1qzzfhee",      //empty human readable part
+		"\x201xj0phk",   //human readable part character out of range
+		"\x7f1g6xzxy",   //human readable part character out of range
+		"\x801vctc34",   //human readable part character out of range
+		"an84characterslonghumanreadablepartthatcontainsthetheexcludedcharactersbioandnumber11d6pts4", //overall max length exceeded
+		"qyrz8wqd2c9m",  //no separator character
+		"1qyrz8wqd2c9m", //empty human readable part
+		"y1b0jsk6g",     //invalid data character
+		"lt1igcx5c0",    //invalid data character
+		"in1muywd",      //too short checksum
+		"mm1crxm3i",     //invalid character in checksum
+		"au1s5cgom",     //invalid character in checksum
+		"M1VUXWEZ",      //checksum calculated with uppercase form of human readable part
+		"16plkw9",       //empty human readable part
+		"1p2gdwpf",      //empty human readable part
+	}
+	for _, invalidString := range invalidStrings {
+		if _, _, _, err := bech32DecodeString(invalidString); err == nil {
+			t.Errorf("bech32DecodeString accepting invalid string %q.", invalidString)
+		}
+	}
+}
+
+func TestBech32Decode(t *testing.T) {
+	//BIP350 valid SegWit addresses
+	testCases := []struct {
+		address         string
+		hrp             string
+		scriptPubKeyHex string
+	}{
+		{"BC1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KV8F3T4", "bc", "0014751e76e8199196d454941c45d1b3a323f1433bd6"},
+		{"tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sl5k7", "tb", "00201863143c14c5166804bd19203356da136c985678cd4d27a1b8c6329604903262"},
+		{"bc1pw508d6qejxtdg4y5r3zarvary0c5xw7kw508d6qejxtdg4y5r3zarvary0c5xw7kt5nd6y", "bc", "5128751e76e8199196d454941c45d1b3a323f1433bd6751e76e8199196d454941c45d1b3a323f1433bd6"},
+		{"BC1SW50QGDZ25J", "bc", "6002751e"},
+		{"bc1zw508d6qejxtdg4y5r3zarvaryvaxxpcs", "bc", "5210751e76e8199196d454941c45d1b3a323"},
+		{"tb1qqqqqp399et2xygdj5xreqhjjvcmzhxw4aywxecjdzew6hylgvsesrxh6hy", "tb", "0020000000c4a5cad46221b2a187905e5266362b99d5e91c6ce24d165dab93e86433"},
+		{"tb1pqqqqp399et2xygdj5xreqhjjvcmzhxw4aywxecjdzew6hylgvsesf3hn0c", "tb", "5120000000c4a5cad46221b2a187905e5266362b99d5e91c6ce24d165dab93e86433"},
+		{"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0", "bc", "512079be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"},
+	}
+	for _, testCase := range testCases {
+		hrp, version, program, err := Bech32Decode(testCase.address)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if hrp != testCase.hrp {
+			testutils.CompareError(t, "Decoded human readable part different from expected part.", testCase.hrp, hrp)
+		}
+		scriptPubKey, err := NewWitnessScriptPubKey(version, program)
+		if err != nil {
+			t.Error(err)
+		}
+		scriptPubKeyHex := hex.EncodeToString(scriptPubKey)
+		if scriptPubKeyHex != testCase.scriptPubKeyHex {
+			testutils.CompareError(t, "scriptPubKey for SegWit address different from expected script.", testCase.scriptPubKeyHex, scriptPubKeyHex)
+		}
+		//Encoding the decoded program gives back the lowercase address
+		address, err := Bech32Encode(hrp, version, program)
+		if err != nil {
+			t.Error(err)
+		}
+		if address != strings.ToLower(testCase.address) {
+			testutils.CompareError(t, "Encoded SegWit address different from expected address.", strings.ToLower(testCase.address), address)
+		}
+	}
+
+	//BIP173 and BIP350 invalid SegWit addresses, which fail to decode or have neither the mainnet nor testnet human
+	//readable part
+	invalidAddresses := []string{
+		"tc1qw508d6qejxtdg4y5r3zarvary0c5xw7kg3g4ty", //invalid human readable part
+		"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t5", //invalid checksum
+		"BC13W508D6QEJXTDG4Y5R3ZARVARY0C5XW7KN40WF2", //invalid witness version
+		"bc1rw5uspcuh", //program too short
+		"bc10w508d6qejxtdg4y5r3zarvary0c5xw7kw508d6qejxtdg4y5r3zarvary0c5xw7kw5rljs90", //program too long
+		"tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sL5k7",               //mixed case
+		"bc1zw508d6qejxtdg4y5r3zarvaryvqyzf3du",                                        //zero padding of more than 4 bits
+		"tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3pjxtptv",               //non-zero padding
+		"tc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vq5zuyut",               //invalid human readable part
+		"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqh2y7hd",               //Bech32 instead of Bech32m
+		"tb1z0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqglt7rf",               //Bech32 instead of Bech32m
+		"BC1S0XLXVLHEMJA6C4DQV22UAPCTQUPFHLXM9H8Z3K2E72Q4K9HCZ7VQ54WELL",               //Bech32 instead of Bech32m
+		"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kemeawh",                                   //Bech32m instead of Bech32
+		"tb1q0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vq24jc47",               //Bech32m instead of Bech32
+		"bc1p38j9r5y49hruaue7wxjce0updqjuyyx0kh56v8s25huc6995vvpql3jow4",               //invalid character
+		"BC130XLXVLHEMJA6C4DQV22UAPCTQUPFHLXM9H8Z3K2E72Q4K9HCZ7VQ7ZWS8R",               //invalid witness version
+		"bc1pw5dgrnzv", //program too short
+		"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7v8n0nx0muaewav253zgeav", //program too long
+		"BC1QR508D6QEJXTDG4Y5R3ZARVARYV98GJ9P",                                         //invalid version 0 program length
+		"tb1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vq47Zagq",               //mixed case
+		"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7v07qwwzcrf",             //more than 4 padding bits
+		"tb1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vpggkg4j",               //non-zero padding
+		"bc1gmk9yu", //empty data
+		"an84characterslonghumanreadablepartthatcontainsthenumber1andtheexcludedcharactersbio1569pvx", //too long
+	}
+	for _, invalidAddress := range invalidAddresses {
+		hrp, _, _, err := Bech32Decode(invalidAddress)
+		if err == nil && (hrp == "bc" || hrp == "tb") {
+			t.Errorf("Bech32Decode accepting invalid address %s as valid.", invalidAddress)
+		}
+	}
+}
+
+func TestBech32Encode(t *testing.T) {
+	testProgram, _ := hex.DecodeString("751e76e8199196d454941c45d1b3a323f1433bd6")
+	testAddress := "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4"
+
+	address, err := Bech32Encode("bc", 0, testProgram)
+	if err != nil {
+		t.Error(err)
+	}
+	if address != testAddress {
+		testutils.CompareError(t, "Encoded SegWit address different from expected address.", testAddress, address)
+	}
+
+	//Version 0 programs must be 20 or 32 bytes
+	if _, err := Bech32Encode("bc", 0, testProgram[:19]); err == nil {
+		t.Error("Bech32Encode accepting 19 byte version 0 program.")
+	}
+	//Witness versions only go up to 16
+	if _, err := Bech32Encode("bc", 17, testProgram); err == nil {
+		t.Error("Bech32Encode accepting witness version 17.")
+	}
+	//Human readable part must be lowercase
+	if _, err := Bech32Encode("BC", 0, testProgram); err == nil {
+		t.Error("Bech32Encode accepting uppercase human readable part.")
+	}
+}
@@ -10,43 +10,47 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
-	"log"
-	"math"
-	mathrand "math/rand"
-	"time"
+	"sort"
 
-	"golang.org/x/crypto/ripemd160"
+	"github.com/CryptoProcessing/go-bitcoin-multisig/txscript"
 	secp256k1 "github.com/toxeus/go-secp256k1"
+	"golang.org/x/crypto/ripemd160"
 )
 
 // setFixedNonce is used for testing and debugging. It is by default false, but if set to true, then newNonce()
-// will always return a zero-valued [32]byte{}. Allows repeatable ECDSA signatures for testing
+// will always return FIXED_NONCE instead of the RFC 6979 nonce. Allows ECDSA signatures to be checked against
+// signatures made before deterministic nonces were used. SchnorrSign uses all zero auxiliary randomness instead.
 // **Should never be turned on in production. Limit to use in tests only.**
 var SetFixedNonce bool
 
-//Fixed nonce value for repeatable testing.
-//We declare var and not const because Go slices are mutable and cannot be const, but we use fixedNonce like a constant.
+// Fixed nonce value for repeatable testing.
+// We declare var and not const because Go slices are mutable and cannot be const, but we use fixedNonce like a constant.
 var FIXED_NONCE = [...]byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 21, 22, 23, 24, 25, 26, 27, 28, 29, 30, 31}
 
-func randInt(min int, max int) uint8 {
-	//This function is *not* cryptographically secure.
-	//Used for nonce which does not need to be cryptographically secure, just changing with each signature
-	mathrand.Seed(time.Now().UTC().UnixNano())
-	return uint8(min + mathrand.Intn(max-min))
-}
+// DisableLowR is used for debugging. It is by default false, so SignHash grinds nonces until the signature R value
+// is below 2^255 and every signature is 71 bytes long, but if set to true the first RFC 6979 nonce is always used.
+var DisableLowR bool
 
-func newNonce() [32]byte {
-	//This function is *not* cryptographically secure.
-	//Used for nonce which does not need to be cryptographically secure, just changing with each signature
-	var bytes [32]byte
+// maxSignAttempts bounds the nonces tried to find a strict DER, low R signature. Each nonce gives a low R with
+// probability 1/2.
+const maxSignAttempts = 256
+
+func newNonce(privateKey []byte, hash []byte, counter uint32) [32]byte {
+	//Nonce is derived deterministically from the private key and hash as per RFC 6979, so it never depends on
+	//a random number generator and is never reused for a different hash
+	var nonce [32]byte
 	if !SetFixedNonce {
-		for i := 0; i < 32; i++ {
-			bytes[i] = byte(randInt(0, math.MaxUint8))
+		//Retries add the counter as 32 byte little-endian extra data, the same nonces Bitcoin Core grinds through
+		var extraData []byte
+		if counter > 0 {
+			extraData = make([]byte, 32)
+			binary.LittleEndian.PutUint32(extraData, counter)
 		}
+		copy(nonce[:], rfc6979Nonce(secp256k1N, privateKey, hash, extraData))
 	} else {
-		bytes = FIXED_NONCE
+		nonce = FIXED_NONCE
 	}
-	return bytes
+	return nonce
 }
 
 // NewRandomBytes generates pseudorandom bytes of length size.
@@ -62,13 +66,28 @@ func NewRandomBytes(size int) ([]byte, error) {
 
 // NewPrivateKey generates a pseudorandom private key compatible with ECDSA.
 // Cryptographically secure to the limits of crypto/rand package.
-func NewPrivateKey() []byte {
+func NewPrivateKey() ([]byte, error) {
 	bytes, err := NewRandomBytes(32)
 	if err != nil {
-		log.Fatal(err)
-		//Throw an error instead of just returning one quietly since cryptographically secure pseudorandomness is crucial to private key.
+		//Never fall back to a weaker source, cryptographically secure pseudorandomness is crucial to private key.
+		return nil, err
 	}
-	return bytes
+	return bytes, nil
+}
+
+// checkPrivateKeyLength makes sure a private key is the 32 bytes expected by secp256k1.
+func checkPrivateKeyLength(privateKey []byte) error {
+	if len(privateKey) != 32 {
+		return fmt.Errorf("Private key should be 32 bytes long. Provided private key is %d bytes long.", len(privateKey))
+	}
+	return nil
+}
+
+// CheckPrivateKeyIsValid makes sure a private key is 32 bytes long and in the range [1, N-1] of valid secp256k1
+// private keys. Returns an error with a helpful message or nil if key is valid.
+func CheckPrivateKeyIsValid(privateKey []byte) error {
+	_, err := parsePrivateKeyScalar(privateKey)
+	return err
 }
 
 // NewPublicKey generates the public key from the private key.
@@ -76,12 +95,26 @@ func NewPrivateKey() []byte {
 // secp256k1 curve as this is fairly specific to Bitcoin.
 // Using toxeus/go-secp256k1 which wraps the official bitcoin/c-secp256k1 with cgo.
 func NewPublicKey(privateKey []byte) ([]byte, error) {
+	return newPublicKey(privateKey, false)
+}
+
+// NewCompressedPublicKey generates the 33 byte compressed form of the public key from the private key.
+// Segregated witness outputs such as P2WPKH require compressed public keys.
+func NewCompressedPublicKey(privateKey []byte) ([]byte, error) {
+	return newPublicKey(privateKey, true)
+}
+
+func newPublicKey(privateKey []byte, compressed bool) ([]byte, error) {
+	err := checkPrivateKeyLength(privateKey)
+	if err != nil {
+		return nil, err
+	}
 	var privateKey32 [32]byte
 	for i := 0; i < 32; i++ {
 		privateKey32[i] = privateKey[i]
 	}
 	secp256k1.Start()
-	publicKey, success := secp256k1.Pubkey_create(privateKey32, false)
+	publicKey, success := secp256k1.Pubkey_create(privateKey32, compressed)
 	if !success {
 		return nil, errors.New("Failed to create public key from provided private key.")
 	}
@@ -90,7 +123,7 @@ func NewPublicKey(privateKey []byte) ([]byte, error) {
 }
 
 // Hash160 performs the same operations as OP_HASH160 in Bitcoin Script
-// It hashes the given data first with SHA256, then RIPEMD160
+// It hashes the given data first with SHA256, then RIPEMD160, giving a 20 byte hash
 func Hash160(data []byte) ([]byte, error) {
 	//Does identical function to Script OP_HASH160. Hash once with SHA-256, then RIPEMD-160
 	if data == nil {
@@ -106,49 +139,129 @@ func Hash160(data []byte) ([]byte, error) {
 	return hash, nil
 }
 
+const (
+	//MaxMultiSigKeys is the most public keys OP_CHECKMULTISIG accepts
+	MaxMultiSigKeys = 20
+	//MaxRedeemScriptSize is the largest P2SH redeem script, as no larger element can be pushed on the stack
+	MaxRedeemScriptSize = 520
+	//MaxWitnessScriptSize is the largest P2WSH witness script nodes relay, as per Bitcoin Core standardness rules
+	MaxWitnessScriptSize = 3600
+)
+
 // NewMOfNRedeemScript creates a M-of-N Multisig redeem script given m, n and n public keys
 func NewMOfNRedeemScript(m int, n int, publicKeys [][]byte) ([]byte, error) {
-	//Check we have valid numbers for M and N
-	if n < 1 || n > 7 {
-		return nil, errors.New("N must be between 1 and 7 (inclusive) for valid, standard P2SH multisig transaction as per Bitcoin protocol.")
-	}
-	if m < 1 || m > n {
-		return nil, errors.New("M must be between 1 and N (inclusive).")
-	}
 	//Check we have N public keys as necessary.
 	if len(publicKeys) != n {
 		return nil, errors.New(fmt.Sprintf("Need exactly %d public keys to create P2SH address for %d-of-%d multisig transaction. Only %d keys provided.", n, m, n, len(publicKeys)))
 	}
-	//Get OP Code for m and n.
-	//81 is OP_1, 82 is OP_2 etc.
-	//80 is not a valid OP_Code, so we floor at 81
-	mOPCode := OP_1 + (m - 1)
-	nOPCode := OP_1 + (n - 1)
+	return CreateMultiSigRedeemScript(m, publicKeys)
+}
+
+// NewMOfNWitnessScript creates a M-of-N Multisig P2WSH witness script given m, n and n compressed public keys
+func NewMOfNWitnessScript(m int, n int, publicKeys [][]byte) ([]byte, error) {
+	if len(publicKeys) != n {
+		return nil, fmt.Errorf("Need exactly %d public keys to create P2WSH address for %d-of-%d multisig transaction. Only %d keys provided.", n, m, n, len(publicKeys))
+	}
+	return CreateMultiSigWitnessScript(m, publicKeys)
+}
+
+// CreateMultiSigRedeemScript creates a M-of-N Multisig redeem script requiring m signatures from the N public keys
+// given, in the order given. M and N must be between 1 and 20 with M at most N, as OP_CHECKMULTISIG allows, and the
+// script must fit the 520 byte P2SH redeem script limit. This allows up to 15 compressed or 7 uncompressed public keys.
+func CreateMultiSigRedeemScript(m int, publicKeys [][]byte) ([]byte, error) {
+	redeemScript, err := createMultiSigScript(m, publicKeys)
+	if err != nil {
+		return nil, err
+	}
+	if len(redeemScript) > MaxRedeemScriptSize {
+		return nil, fmt.Errorf("%d-of-%d redeem script is %d bytes long, longer than the %d byte P2SH limit. Use fewer or compressed public keys, or a P2WSH address.", m, len(publicKeys), len(redeemScript), MaxRedeemScriptSize)
+	}
+	return redeemScript, nil
+}
+
+// CreateMultiSigWitnessScript creates a M-of-N Multisig witness script for a P2WSH address, like
+// CreateMultiSigRedeemScript but with the witness limits instead of the P2SH ones. All 20 public keys
+// OP_CHECKMULTISIG accepts fit, as the script may be up to 3600 bytes long, but public keys must be compressed
+// for the spending transaction to be relayed.
+func CreateMultiSigWitnessScript(m int, publicKeys [][]byte) ([]byte, error) {
+	for _, publicKey := range publicKeys {
+		if len(publicKey) != 33 {
+			return nil, fmt.Errorf("P2WSH requires compressed public keys. Public key %x is %d bytes long.", publicKey, len(publicKey))
+		}
+	}
+	witnessScript, err := createMultiSigScript(m, publicKeys)
+	if err != nil {
+		return nil, err
+	}
+	if len(witnessScript) > MaxWitnessScriptSize {
+		return nil, fmt.Errorf("%d-of-%d witness script is %d bytes long, longer than the %d byte P2WSH limit.", m, len(publicKeys), len(witnessScript), MaxWitnessScriptSize)
+	}
+	return witnessScript, nil
+}
+
+// createMultiSigScript creates a M-of-N Multisig script requiring m signatures from the N public keys given, in the
+// order given, without any limit on its size.
+func createMultiSigScript(m int, publicKeys [][]byte) ([]byte, error) {
+	n := len(publicKeys)
+	//Check we have valid numbers for M and N
+	if n < 1 || n > MaxMultiSigKeys {
+		return nil, fmt.Errorf("N must be between 1 and %d (inclusive). %d public keys provided.", MaxMultiSigKeys, n)
+	}
+	if m < 1 || m > n {
+		return nil, fmt.Errorf("M must be between 1 and N (inclusive). M is %d and N is %d.", m, n)
+	}
 	//Multisig redeemScript format:
 	//<OP_m> <A pubkey> <B pubkey> <C pubkey>... <OP_n> OP_CHECKMULTISIG
-	var redeemScript bytes.Buffer
-	redeemScript.WriteByte(byte(mOPCode)) //m
+	//81 is OP_1, 82 is OP_2 etc. up to OP_16, larger numbers are pushed as script numbers
+	builder := txscript.NewBuilder().AddInt64(int64(m)) //m
 	for _, publicKey := range publicKeys {
 		err := CheckPublicKeyIsValid(publicKey)
 		if err != nil {
 			return nil, err
 		}
-		redeemScript.WriteByte(byte(len(publicKey))) //PUSH
-		redeemScript.Write(publicKey)                //<pubkey>
+		builder.AddData(publicKey) //<pubkey>
+	}
+	builder.AddInt64(int64(n)) //n
+	builder.AddOp(OP_CHECKMULTISIG)
+	return builder.Script()
+}
+
+// CreateSortedMultiSigRedeemScript creates a M-of-N Multisig redeem script like CreateMultiSigRedeemScript, with the
+// public keys sorted as BIP67 specifies, so every permutation of the same keys gives the same script and address.
+// BIP67 only applies to compressed public keys, so an error is returned if any key is uncompressed.
+func CreateSortedMultiSigRedeemScript(m int, publicKeys [][]byte) ([]byte, error) {
+	for _, publicKey := range publicKeys {
+		if len(publicKey) != 33 {
+			return nil, fmt.Errorf("BIP67 sorting requires compressed public keys. Public key %x is %d bytes long.", publicKey, len(publicKey))
+		}
 	}
-	redeemScript.WriteByte(byte(nOPCode)) //n
-	redeemScript.WriteByte(byte(OP_CHECKMULTISIG))
-	return redeemScript.Bytes(), nil
+	return CreateMultiSigRedeemScript(m, SortPublicKeys(publicKeys))
+}
+
+// SortPublicKeys returns a copy of publicKeys sorted in the lexicographic order of their bytes, as BIP67 specifies for
+// the public keys of multisig redeem scripts. publicKeys itself is left unchanged.
+func SortPublicKeys(publicKeys [][]byte) [][]byte {
+	sorted := make([][]byte, len(publicKeys))
+	copy(sorted, publicKeys)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i], sorted[j]) < 0
+	})
+	return sorted
 }
 
 // CheckPublicKeyIsValid runs a couple of checks to make sure a public key looks valid.
+// Both 65 byte uncompressed and 33 byte compressed public keys are accepted.
 // Returns an error with a helpful message or nil if key is valid.
 func CheckPublicKeyIsValid(publicKey []byte) error {
 	errMessage := ""
 	if publicKey == nil {
 		errMessage += "Public key cannot be empty.\n"
+	} else if len(publicKey) == 33 {
+		if publicKey[0] != byte(2) && publicKey[0] != byte(3) {
+			errMessage += fmt.Sprintf("Compressed public key first byte should be 0x02 or 0x03. Provided public key first byte is 0x%v.", hex.EncodeToString([]byte{publicKey[0]}))
+		}
 	} else if len(publicKey) != 65 {
-		errMessage += fmt.Sprintf("Public key should be 65 bytes long. Provided public key is %d bytes long.", len(publicKey))
+		errMessage += fmt.Sprintf("Public key should be 65 bytes long, or 33 bytes long if compressed. Provided public key is %d bytes long.", len(publicKey))
 	} else if publicKey[0] != byte(4) {
 		errMessage += fmt.Sprintf("Public key first byte should be 0x04. Provided public key first byte is 0x%v.", hex.EncodeToString([]byte{publicKey[0]}))
 	}
@@ -160,6 +273,37 @@ func CheckPublicKeyIsValid(publicKey []byte) error {
 	return nil
 }
 
+// IsValidPublicKey reports whether publicKey is a 33 byte compressed or 65 byte uncompressed public key of a point on
+// the secp256k1 curve. Unlike CheckPublicKeyIsValid, which only checks the length and first byte, keys that are not
+// points on the curve are refused.
+func IsValidPublicKey(publicKey []byte) bool {
+	_, err := parsePublicKeyPoint(publicKey)
+	return err == nil
+}
+
+// CompressPublicKey converts a 65 byte uncompressed public key to its 33 byte compressed form, 0x02 or 0x03 for an
+// even or odd y coordinate followed by the x coordinate. Compressed public keys are returned as they are. Returns an
+// error if publicKey is not a point on the secp256k1 curve.
+func CompressPublicKey(publicKey []byte) ([]byte, error) {
+	point, err := parsePublicKeyPoint(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	return compressPoint(point), nil
+}
+
+// PublicKeyToHash160 returns the HASH160 of the compressed form of publicKey, the hash P2WPKH and P2SH-P2WPKH
+// outputs of the key pay to, as SegWit only allows compressed keys. P2PKH outputs of an uncompressed key pay to the
+// Hash160 of the uncompressed key instead. Returns nil if publicKey is not valid.
+func PublicKeyToHash160(publicKey []byte) []byte {
+	compressedPublicKey, err := CompressPublicKey(publicKey)
+	if err != nil {
+		return nil
+	}
+	publicKeyHash, _ := Hash160(compressedPublicKey)
+	return publicKeyHash
+}
+
 // NewP2SHScriptPubKey creates a scriptPubKey for a P2SH transaction given the redeemScript hash
 func NewP2SHScriptPubKey(redeemScriptHash []byte) ([]byte, error) {
 	if redeemScriptHash == nil {
@@ -167,12 +311,7 @@ func NewP2SHScriptPubKey(redeemScriptHash []byte) ([]byte, error) {
 	}
 	//P2SH scriptSig format:
 	//<OP_HASH160> <Hash160(redeemScript)> <OP_EQUAL>
-	var scriptPubKey bytes.Buffer
-	scriptPubKey.WriteByte(byte(OP_HASH160))
-	scriptPubKey.WriteByte(byte(len(redeemScriptHash))) //PUSH
-	scriptPubKey.Write(redeemScriptHash)
-	scriptPubKey.WriteByte(byte(OP_EQUAL))
-	return scriptPubKey.Bytes(), nil
+	return txscript.NewBuilder().AddOp(OP_HASH160).AddData(redeemScriptHash).AddOp(OP_EQUAL).Script()
 }
 
 // NewP2PKHScriptPubKey creates a scriptPubKey for a P2PKH transaction given the destination public key hash
@@ -182,117 +321,184 @@ func NewP2PKHScriptPubKey(publicKeyHash []byte) ([]byte, error) {
 	}
 	//P2PKH scriptSig format:
 	//<OP_DUP> <OP_HASH160> <pubKeyHash> <OP_EQUALVERIFY> <OP_CHECKSIG>
-	var scriptPubKey bytes.Buffer
-	scriptPubKey.WriteByte(byte(OP_DUP))
-	scriptPubKey.WriteByte(byte(OP_HASH160))
-	scriptPubKey.WriteByte(byte(len(publicKeyHash))) //PUSH
-	scriptPubKey.Write(publicKeyHash)
-	scriptPubKey.WriteByte(byte(OP_EQUALVERIFY))
-	scriptPubKey.WriteByte(byte(OP_CHECKSIG))
-	return scriptPubKey.Bytes(), nil
+	return txscript.NewBuilder().AddOp(OP_DUP).AddOp(OP_HASH160).AddData(publicKeyHash).AddOp(OP_EQUALVERIFY).AddOp(OP_CHECKSIG).Script()
 }
 
-// NewRawTransaction creates a Bitcoin transaction given inputs, output satoshi amount, scriptSig and scriptPubKey
-func NewRawTransaction(inputTxHash string, satoshis int, scriptSig []byte, scriptPubKey []byte) ([]byte, error) {
-	//Version field
-	version, err := hex.DecodeString("01000000")
-	if err != nil {
-		return nil, err
-	}
-	//# of inputs (always 1 in our case)
-	inputs, err := hex.DecodeString("01")
-	if err != nil {
-		return nil, err
-	}
-	//Input transaction hash
-	inputTxBytes, err := hex.DecodeString(inputTxHash)
-	if err != nil {
-		return nil, err
+// MaxOpReturnDataSize is the most data nodes relay in an OP_RETURN output by default, giving an 83 byte scriptPubKey.
+const MaxOpReturnDataSize = 80
+
+// CreateOPReturnScriptPubKey creates a scriptPubKey for a provably unspendable OP_RETURN output carrying data, which
+// may be empty. Returns an error if data is longer than MaxOpReturnDataSize, as the output would not be relayed.
+func CreateOPReturnScriptPubKey(data []byte) ([]byte, error) {
+	if len(data) > MaxOpReturnDataSize {
+		return nil, fmt.Errorf("OP_RETURN data must be at most %d bytes long. Provided data is %d bytes long.", MaxOpReturnDataSize, len(data))
 	}
-	//Convert input transaction hash to little-endian form
-	inputTxBytesReversed := make([]byte, len(inputTxBytes))
-	for i := 0; i < len(inputTxBytes); i++ {
-		inputTxBytesReversed[i] = inputTxBytes[len(inputTxBytes)-i-1]
+	//OP_RETURN scriptPubKey format:
+	//<OP_RETURN> <data>, with the smallest push of data
+	var scriptPubKey bytes.Buffer
+	scriptPubKey.WriteByte(byte(OP_RETURN))
+	if len(data) == 0 {
+		return scriptPubKey.Bytes(), nil
 	}
-	//Ouput index of input transaction
-	outputIndex, err := hex.DecodeString("00000000")
-	if err != nil {
-		return nil, err
+	if len(data) >= OP_PUSHDATA1 {
+		scriptPubKey.WriteByte(byte(OP_PUSHDATA1))
 	}
-	//scriptSig length. To allow scriptSig > 255 bytes, we use variable length integer syntax from protocol spec
-	var scriptSigLengthBytes []byte
-	if len(scriptSig) < 253 {
-		scriptSigLengthBytes = []byte{byte(len(scriptSig))}
-	} else {
-		scriptSigLengthBytes = make([]byte, 3)
-		binary.LittleEndian.PutUint16(scriptSigLengthBytes, uint16(len(scriptSig)))
-		copy(scriptSigLengthBytes[1:3], scriptSigLengthBytes[0:2])
-		scriptSigLengthBytes[0] = 253 //Signifies that next two bytes are 2-byte representation of scriptSig length
+	scriptPubKey.WriteByte(byte(len(data))) //PUSH
+	scriptPubKey.Write(data)
+	return scriptPubKey.Bytes(), nil
+}
 
+// IsOpReturnScriptPubKey reports whether scriptPubKey is an unspendable OP_RETURN output script.
+func IsOpReturnScriptPubKey(scriptPubKey []byte) bool {
+	return len(scriptPubKey) > 0 && scriptPubKey[0] == OP_RETURN
+}
+
+// SequenceFinal is the default sequence number for a transaction input, marking the input as final.
+const SequenceFinal uint32 = 0xffffffff
+
+// Input is a single transaction input, spending output OutputIndex of transaction TxHash.
+// TxHash is given in the usual big-endian hex form displayed by block explorers and Bitcoin Core.
+// Witness holds the witness stack items for inputs spending segregated witness outputs.
+type Input struct {
+	TxHash      string
+	OutputIndex uint32
+	ScriptSig   []byte
+	Sequence    uint32
+	Witness     [][]byte
+}
+
+// Output is a single transaction output, paying Satoshis to ScriptPubKey.
+type Output struct {
+	Satoshis     uint64
+	ScriptPubKey []byte
+}
+
+// MaxMoney is the number of satoshis in 21,000,000 BTC, more than any output or all outputs together can hold.
+const MaxMoney = 21000000 * 100000000
+
+// CheckOutputs validates the amounts of outputs. Every output must hold more than zero satoshis, except unspendable
+// OP_RETURN outputs which may hold nothing, and neither any output nor the outputs together may exceed MaxMoney.
+// Amounts that do not fit in an int64, such as negative amounts cast to uint64, are refused as well.
+// Returns the total of the outputs in satoshis.
+func CheckOutputs(outputs []Output) (uint64, error) {
+	var total uint64
+	for i, output := range outputs {
+		if int64(output.Satoshis) < 0 {
+			return 0, fmt.Errorf("Output %d amount of %d satoshis is negative.", i, int64(output.Satoshis))
+		}
+		if output.Satoshis == 0 && !IsOpReturnScriptPubKey(output.ScriptPubKey) {
+			return 0, fmt.Errorf("Output %d amount must be more than zero satoshis.", i)
+		}
+		if output.Satoshis > MaxMoney {
+			return 0, fmt.Errorf("Output %d amount of %d satoshis exceeds the maximum of %d satoshis (21,000,000 BTC).", i, output.Satoshis, uint64(MaxMoney))
+		}
+		total += output.Satoshis
+		if total > MaxMoney {
+			return 0, fmt.Errorf("Outputs total more than the maximum of %d satoshis (21,000,000 BTC).", uint64(MaxMoney))
+		}
 	}
-	//sequence_no. Normally 0xFFFFFFFF. Always in this case.
-	sequence, err := hex.DecodeString("ffffffff")
+	return total, nil
+}
+
+// CheckFee validates that outputs spend no more than inputAmount satoshis, the total of the inputs, as well as
+// validating the amounts of outputs with CheckOutputs. Returns the implied fee, what the inputs hold beyond the
+// outputs, in satoshis.
+func CheckFee(inputAmount uint64, outputs []Output) (uint64, error) {
+	total, err := CheckOutputs(outputs)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
-	//Numbers of outputs for the transaction being created. Always one in this example.
-	numOutputs, err := hex.DecodeString("01")
-	if err != nil {
-		return nil, err
+	if total > inputAmount {
+		return 0, fmt.Errorf("Outputs total %d satoshis, %d satoshis more than the inputs hold (%d).", total, total-inputAmount, inputAmount)
 	}
-	//Satoshis to send.
-	satoshiBytes := make([]byte, 8)
-	binary.LittleEndian.PutUint64(satoshiBytes, uint64(satoshis))
-	//Lock time field
-	lockTimeField, err := hex.DecodeString("00000000")
+	return inputAmount - total, nil
+}
+
+// NewRawTransaction creates a Bitcoin transaction given inputs, output satoshi amount and scriptPubKey.
+// Convenience wrapper around NewRawTransactionWithOutputs for the common single output case.
+func NewRawTransaction(inputs []Input, satoshis int, scriptPubKey []byte) ([]byte, error) {
+	if satoshis < 0 {
+		return nil, fmt.Errorf("Output amount of %d satoshis is negative.", satoshis)
+	}
+	return NewRawTransactionWithOutputs(inputs, []Output{{Satoshis: uint64(satoshis), ScriptPubKey: scriptPubKey}})
+}
+
+// NewRawTransactionWithOutputs creates a version 2 Bitcoin transaction given inputs and outputs, so BIP68 relative lock
+// times set by the input sequences are enforced. Inputs and outputs are serialized in the order given. Output amounts
+// are validated with CheckOutputs.
+func NewRawTransactionWithOutputs(inputs []Input, outputs []Output) ([]byte, error) {
+	_, err := CheckOutputs(outputs)
 	if err != nil {
 		return nil, err
 	}
-
-	var buffer bytes.Buffer
-	buffer.Write(version)
-	buffer.Write(inputs)
-	buffer.Write(inputTxBytesReversed)
-	buffer.Write(outputIndex)
-	buffer.Write(scriptSigLengthBytes)
-	buffer.Write(scriptSig)
-	buffer.Write(sequence)
-	buffer.Write(numOutputs)
-	buffer.Write(satoshiBytes)
-	buffer.WriteByte(byte(len(scriptPubKey)))
-	buffer.Write(scriptPubKey)
-	buffer.Write(lockTimeField)
-
-	return buffer.Bytes(), nil
+	tx := &Transaction{Version: 2, Inputs: inputs, Outputs: outputs}
+	return tx.Serialize()
 }
 
 // NewSignature generates a ECDSA signature given the raw transaction and privateKey to sign with
 func NewSignature(rawTransaction []byte, privateKey []byte) ([]byte, error) {
+	//Hash the raw transaction twice with SHA256 before the signing
+	return SignHash(DoubleSHA256(rawTransaction), privateKey)
+}
+
+// SignHash generates a ECDSA signature of an already computed 32 byte signature hash, such as the
+// BIP143 hash returned by CalcWitnessSigHash, given the privateKey to sign with
+func SignHash(hash []byte, privateKey []byte) ([]byte, error) {
+	err := checkPrivateKeyLength(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(hash) != 32 {
+		return nil, fmt.Errorf("Signature hash should be 32 bytes long. Provided hash is %d bytes long.", len(hash))
+	}
 	//Start secp256k1
 	secp256k1.Start()
 	var privateKey32 [32]byte
 	for i := 0; i < 32; i++ {
 		privateKey32[i] = privateKey[i]
 	}
-	//Get the raw public key
-	publicKey, success := secp256k1.Pubkey_create(privateKey32, false)
+	//Get the compressed public key to verify the signature with
+	publicKey, success := secp256k1.Pubkey_create(privateKey32, true)
 	if !success {
 		return nil, errors.New("Failed to create public key from provided private key.")
 	}
-	//Hash the raw transaction twice with SHA256 before the signing
-	shaHash := sha256.New()
-	shaHash.Write(rawTransaction)
-	var hash []byte = shaHash.Sum(nil)
-	shaHash2 := sha256.New()
-	shaHash2.Write(hash)
-	rawTransactionHashed := shaHash2.Sum(nil)
-	//Sign the raw transaction
-	signedTransaction, success := secp256k1.Sign(rawTransactionHashed, privateKey32, newNonce())
-	if !success {
-		return nil, errors.New("Failed to sign transaction")
+	var signedTransaction []byte
+	for counter := uint32(0); ; counter++ {
+		if counter == maxSignAttempts {
+			return nil, fmt.Errorf("Failed to sign transaction. No valid low R signature in %d attempts.", maxSignAttempts)
+		}
+		//Sign the hash
+		rawSignature, success := secp256k1.Sign(hash, privateKey32, newNonce(privateKey, hash, counter))
+		if !success {
+			return nil, errors.New("Failed to sign transaction")
+		}
+		//Don't trust the raw secp256k1 output to be strict DER (BIP66), re-sign with the next nonce if it isn't
+		r, s, err := DecodeDER(rawSignature)
+		if err != nil {
+			continue
+		}
+		//Low R saves a byte per signature. A fixed nonce always gives the same R, so there is nothing to grind
+		if !DisableLowR && !SetFixedNonce && !isLowR(r) {
+			continue
+		}
+		//Nodes only relay signatures with S in the lower half of the curve order (BIP62 low-S rule)
+		signature, err := EncodeDER(r, normalizeS(s))
+		if err != nil {
+			continue
+		}
+		//Check the signature we hand out is strict DER too, so it never fails CheckSignatureEncoding on relay
+		if _, _, err = DecodeDER(signature); err != nil {
+			continue
+		}
+		signedTransaction = signature
+		break
+	}
+	err = CheckSignatureIsLowS(signedTransaction)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to sign transaction. %s", err)
 	}
 	//Verify that it worked.
-	verified := secp256k1.Verify(rawTransactionHashed, signedTransaction, publicKey)
+	verified := secp256k1.Verify(hash, signedTransaction, publicKey)
 	if !verified {
 		return nil, errors.New("Failed to verify signed transaction")
 	}
@@ -300,3 +506,25 @@ func NewSignature(rawTransaction []byte, privateKey []byte) ([]byte, error) {
 	secp256k1.Stop()
 	return signedTransaction, nil
 }
+
+// VerifySignature reports whether signature, DER encoded without a hash type, is a valid ECDSA signature of the 32 byte
+// hash by publicKey, compressed or uncompressed.
+func VerifySignature(hash []byte, signature []byte, publicKey []byte) bool {
+	if len(hash) != 32 || CheckPublicKeyIsValid(publicKey) != nil {
+		return false
+	}
+	if _, _, err := DecodeDER(signature); err != nil {
+		return false
+	}
+	secp256k1.Start()
+	defer secp256k1.Stop()
+	return secp256k1.Verify(hash, signature, publicKey)
+}
+
+// DoubleSHA256 hashes data twice with SHA256, as used for transaction hashes, signature hashes and Base58Check
+// checksums. The result is always 32 bytes.
+func DoubleSHA256(data []byte) []byte {
+	firstHash := sha256.Sum256(data)
+	secondHash := sha256.Sum256(firstHash[:])
+	return secondHash[:]
+}
@@ -4,7 +4,9 @@ import (
 	"github.com/prettymuchbryce/hellobitcoin/base58check"
 	"github.com/CryptoProcessing/go-bitcoin-multisig/testutils"
 
+	"bytes"
 	"encoding/hex"
+	"fmt"
 	"reflect"
 	"testing"
 )
@@ -22,6 +24,17 @@ func TestNewPublicKey(t *testing.T) {
 	}
 }
 
+func TestNewPublicKeyWrongLength(t *testing.T) {
+	for _, testPrivateKey := range [][]byte{nil, make([]byte, 31), make([]byte, 33)} {
+		if _, err := NewPublicKey(testPrivateKey); err == nil {
+			t.Errorf("NewPublicKey accepting %d byte private key.", len(testPrivateKey))
+		}
+		if _, err := NewSignature([]byte{1, 0, 0, 0}, testPrivateKey); err == nil {
+			t.Errorf("NewSignature accepting %d byte private key.", len(testPrivateKey))
+		}
+	}
+}
+
 func TestHash160(t *testing.T) {
 	testHashHex := "51d9ac622c2133ca4aaf58d4a4239526eb42c348"
 
@@ -35,6 +48,38 @@ func TestHash160(t *testing.T) {
 	}
 }
 
+func TestDoubleSHA256(t *testing.T) {
+	testHashHex := "9595c9df90075148eb06860365df33584b75bff782a510c6cd4883a419833d50"
+
+	hashHex := hex.EncodeToString(DoubleSHA256([]byte("hello")))
+	if hashHex != testHashHex {
+		testutils.CompareError(t, "Deterministic hash SHA256(SHA256(data)) different from expected hash.", testHashHex, hashHex)
+	}
+}
+
+func TestHashLengths(t *testing.T) {
+	//Hashes have a fixed length whatever the length and content of the data hashed
+	for size := 1; size <= 1024; size += 37 {
+		data, err := NewRandomBytes(size)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hash, err := Hash160(data)
+		if err != nil {
+			t.Error(err)
+		}
+		if len(hash) != 20 {
+			testutils.CompareError(t, "Hash160 of random data different from expected length.", 20, len(hash))
+		}
+		if hash := DoubleSHA256(data); len(hash) != 32 {
+			testutils.CompareError(t, "DoubleSHA256 of random data different from expected length.", 32, len(hash))
+		}
+	}
+	if hash := DoubleSHA256(nil); len(hash) != 32 {
+		testutils.CompareError(t, "DoubleSHA256 of empty data different from expected length.", 32, len(hash))
+	}
+}
+
 func TestNewMOfNRedeemScript(t *testing.T) {
 	testPublicKeyStrings := []string{
 		"0446f1c8de232a065da428bf76e44b41f59a46620dec0aedfc9b5ab651e91f2051d610fddc78b8eba38a634bfe9a74bb015a88c52b9b844c74997035e08a695ce9",
@@ -59,11 +104,150 @@ func TestNewMOfNRedeemScript(t *testing.T) {
 	}
 }
 
+func TestCreateMultiSigRedeemScript(t *testing.T) {
+	//Compressed public keys of private keys 1 to 21
+	publicKeys := make([][]byte, 21)
+	for i := range publicKeys {
+		privateKey := make([]byte, 32)
+		privateKey[31] = byte(i + 1)
+		publicKeys[i], _ = NewCompressedPublicKey(privateKey)
+	}
+	testCases := []struct {
+		m int
+		n int
+	}{
+		{1, 1},
+		{2, 3},
+		{3, 5},
+		{15, 15},
+	}
+	for _, testCase := range testCases {
+		redeemScript, err := CreateMultiSigRedeemScript(testCase.m, publicKeys[:testCase.n])
+		if err != nil {
+			t.Fatal(err)
+		}
+		//<OP_m> <33 byte pubkey>... <OP_n> OP_CHECKMULTISIG
+		var testRedeemScript bytes.Buffer
+		testRedeemScript.WriteByte(byte(OP_1 + testCase.m - 1))
+		for _, publicKey := range publicKeys[:testCase.n] {
+			testRedeemScript.WriteByte(33)
+			testRedeemScript.Write(publicKey)
+		}
+		testRedeemScript.WriteByte(byte(OP_1 + testCase.n - 1))
+		testRedeemScript.WriteByte(OP_CHECKMULTISIG)
+		if !bytes.Equal(redeemScript, testRedeemScript.Bytes()) {
+			testutils.CompareError(t, fmt.Sprintf("%d-of-%d redeem script different from expected script.", testCase.m, testCase.n), hex.EncodeToString(testRedeemScript.Bytes()), hex.EncodeToString(redeemScript))
+		}
+	}
+
+	//Uncompressed public keys, of which 7 fit in a redeem script
+	uncompressedPublicKeys := make([][]byte, 8)
+	for i := range uncompressedPublicKeys {
+		privateKey := make([]byte, 32)
+		privateKey[31] = byte(i + 1)
+		uncompressedPublicKeys[i], _ = NewPublicKey(privateKey)
+	}
+	if _, err := CreateMultiSigRedeemScript(7, uncompressedPublicKeys[:7]); err != nil {
+		t.Error(err)
+	}
+
+	invalidTestCases := []struct {
+		name       string
+		m          int
+		publicKeys [][]byte
+	}{
+		{"m=0", 0, publicKeys[:3]},
+		{"m>n", 4, publicKeys[:3]},
+		{"n=0", 0, nil},
+		{"n=21", 1, publicKeys},
+		//Compressed 16-of-16 and uncompressed 8-of-8 redeem scripts are over 520 bytes
+		{"16 compressed keys", 1, publicKeys[:16]},
+		{"8 uncompressed keys", 1, uncompressedPublicKeys},
+		{"invalid public key", 1, [][]byte{publicKeys[0][1:]}},
+	}
+	for _, invalidTestCase := range invalidTestCases {
+		if _, err := CreateMultiSigRedeemScript(invalidTestCase.m, invalidTestCase.publicKeys); err == nil {
+			t.Errorf("CreateMultiSigRedeemScript accepting invalid redeem script with %s.", invalidTestCase.name)
+		}
+	}
+}
+
+func TestCreateMultiSigWitnessScript(t *testing.T) {
+	//Compressed public keys of private keys 1 to 21
+	publicKeys := make([][]byte, 21)
+	for i := range publicKeys {
+		privateKey := make([]byte, 32)
+		privateKey[31] = byte(i + 1)
+		publicKeys[i], _ = NewCompressedPublicKey(privateKey)
+	}
+	testCases := []struct {
+		m        int
+		n        int
+		mPushHex string
+		nPushHex string
+	}{
+		{2, 3, "52", "53"},
+		{15, 15, "5f", "5f"},
+		{16, 16, "60", "60"},
+		//Numbers above 16 have no OP code of their own and are pushed as a single byte
+		{16, 17, "60", "0111"},
+		{17, 20, "0111", "0114"},
+		{20, 20, "0114", "0114"},
+	}
+	for _, testCase := range testCases {
+		witnessScript, err := CreateMultiSigWitnessScript(testCase.m, publicKeys[:testCase.n])
+		if err != nil {
+			t.Fatal(err)
+		}
+		//<m> <33 byte pubkey>... <n> OP_CHECKMULTISIG
+		testWitnessScriptHex := testCase.mPushHex
+		for _, publicKey := range publicKeys[:testCase.n] {
+			testWitnessScriptHex += "21" + hex.EncodeToString(publicKey)
+		}
+		testWitnessScriptHex += testCase.nPushHex + "ae"
+		if hex.EncodeToString(witnessScript) != testWitnessScriptHex {
+			testutils.CompareError(t, fmt.Sprintf("%d-of-%d witness script different from expected script.", testCase.m, testCase.n), testWitnessScriptHex, hex.EncodeToString(witnessScript))
+		}
+		if len(witnessScript) > MaxRedeemScriptSize {
+			//Too long for P2SH, but not for P2WSH
+			if _, err := CreateMultiSigRedeemScript(testCase.m, publicKeys[:testCase.n]); err == nil {
+				t.Errorf("CreateMultiSigRedeemScript accepting %d-of-%d redeem script over 520 bytes.", testCase.m, testCase.n)
+			}
+		}
+	}
+	if _, err := NewMOfNWitnessScript(2, 3, publicKeys[:3]); err != nil {
+		t.Error(err)
+	}
+
+	uncompressedPrivateKey := make([]byte, 32)
+	uncompressedPrivateKey[31] = 2
+	uncompressedPublicKey, _ := NewPublicKey(uncompressedPrivateKey)
+	invalidTestCases := []struct {
+		name       string
+		m          int
+		publicKeys [][]byte
+	}{
+		{"m=0", 0, publicKeys[:3]},
+		{"m>n", 4, publicKeys[:3]},
+		{"n=21", 1, publicKeys},
+		{"uncompressed public key", 1, [][]byte{publicKeys[0], uncompressedPublicKey}},
+	}
+	for _, invalidTestCase := range invalidTestCases {
+		if _, err := CreateMultiSigWitnessScript(invalidTestCase.m, invalidTestCase.publicKeys); err == nil {
+			t.Errorf("CreateMultiSigWitnessScript accepting invalid witness script with %s.", invalidTestCase.name)
+		}
+	}
+	if _, err := NewMOfNWitnessScript(2, 4, publicKeys[:3]); err == nil {
+		t.Error("NewMOfNWitnessScript accepting fewer public keys than N.")
+	}
+}
+
 func TestCheckPublicKeyIsValid(t *testing.T) {
 	invalidPublicKeyStrings := []string{
 		"", //empty key
 		"0446f1c8de232a065da428bf76e44b41f59a46620dec0aedfc9b5ab651e91f2051d610fddc78b8eba38a634bfe9a74bb015a88c52b9b844c74997035e08a695c",   //wrong length key
 		"0346f1c8de232a065da428bf76e44b41f59a46620dec0aedfc9b5ab651e91f2051d610fddc78b8eba38a634bfe9a74bb015a88c52b9b844c74997035e08a695ce9", //wrong prefix key
+		"045476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee6357",                                                                 //wrong prefix compressed key
 	}
 	for _, publicKeyString := range invalidPublicKeyStrings {
 		publicKey, _ := hex.DecodeString(publicKeyString)
@@ -71,6 +255,83 @@ func TestCheckPublicKeyIsValid(t *testing.T) {
 			t.Error("CheckPublicKeyIsValid accepting invalids public keys as valid.")
 		}
 	}
+	validPublicKeyStrings := []string{
+		"0446f1c8de232a065da428bf76e44b41f59a46620dec0aedfc9b5ab651e91f2051d610fddc78b8eba38a634bfe9a74bb015a88c52b9b844c74997035e08a695ce9", //uncompressed key
+		"025476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee6357",                                                                 //compressed key
+	}
+	for _, publicKeyString := range validPublicKeyStrings {
+		publicKey, _ := hex.DecodeString(publicKeyString)
+		if err := CheckPublicKeyIsValid(publicKey); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestCompressPublicKey(t *testing.T) {
+	testCases := []struct {
+		privateKey       string
+		compressedKey    string
+		publicKeyHash160 string
+	}{
+		//BIP143 native P2WPKH example key, even y
+		{"619c335025c7f4012e556c2a58b2506e30b8511b53ade95ea316fd8c3286feb9", "025476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee6357", "1d0f172a0ecb48aee1be1f2687d2963ae33f71a1"},
+		//Generator point, private key 1, even y
+		{"0000000000000000000000000000000000000000000000000000000000000001", "0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798", "751e76e8199196d454941c45d1b3a323f1433bd6"},
+		//Private key 6, odd y
+		{"0000000000000000000000000000000000000000000000000000000000000006", "03fff97bd5755eeea420453a14355235d382f6472f8568a18b2f057a1460297556", ""},
+	}
+	for _, testCase := range testCases {
+		testPrivateKey, _ := hex.DecodeString(testCase.privateKey)
+		uncompressedPublicKey, err := NewPublicKey(testPrivateKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		compressedPublicKey, err := NewCompressedPublicKey(testPrivateKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		//Both forms compress to the same key
+		for _, publicKey := range [][]byte{uncompressedPublicKey, compressedPublicKey} {
+			if !IsValidPublicKey(publicKey) {
+				t.Errorf("IsValidPublicKey refusing valid public key %x.", publicKey)
+			}
+			compressedKey, err := CompressPublicKey(publicKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			compressedKeyHex := hex.EncodeToString(compressedKey)
+			if compressedKeyHex != testCase.compressedKey {
+				testutils.CompareError(t, "Compressed public key different from expected key.", testCase.compressedKey, compressedKeyHex)
+			}
+			if testCase.publicKeyHash160 != "" {
+				publicKeyHash160Hex := hex.EncodeToString(PublicKeyToHash160(publicKey))
+				if publicKeyHash160Hex != testCase.publicKeyHash160 {
+					testutils.CompareError(t, "Public key HASH160 different from expected hash.", testCase.publicKeyHash160, publicKeyHash160Hex)
+				}
+			}
+		}
+	}
+
+	invalidPublicKeyStrings := []string{
+		"", //empty key
+		"0446f1c8de232a065da428bf76e44b41f59a46620dec0aedfc9b5ab651e91f2051d610fddc78b8eba38a634bfe9a74bb015a88c52b9b844c74997035e08a695ce8", //uncompressed key off the curve
+		"020000000000000000000000000000000000000000000000000000000000000005",                                                                 //compressed key with x not on the curve
+		"02fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2f",                                                                 //compressed key with x not in the field
+		"045476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee6357",                                                                 //wrong prefix compressed key
+		"5476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee6357",                                                                   //x-only key
+	}
+	for _, publicKeyString := range invalidPublicKeyStrings {
+		publicKey, _ := hex.DecodeString(publicKeyString)
+		if IsValidPublicKey(publicKey) {
+			t.Errorf("IsValidPublicKey accepting invalid public key %s.", publicKeyString)
+		}
+		if _, err := CompressPublicKey(publicKey); err == nil {
+			t.Errorf("CompressPublicKey accepting invalid public key %s.", publicKeyString)
+		}
+		if PublicKeyToHash160(publicKey) != nil {
+			t.Errorf("PublicKeyToHash160 hashing invalid public key %s.", publicKeyString)
+		}
+	}
 }
 
 func TestNewP2SHScriptPubKey(t *testing.T) {
@@ -88,6 +349,39 @@ func TestNewP2SHScriptPubKey(t *testing.T) {
 	}
 }
 
+func TestCreateOPReturnScriptPubKey(t *testing.T) {
+	testCases := []struct {
+		dataLength         int
+		scriptPubKeyPrefix string
+	}{
+		{0, "6a"},
+		{20, "6a14"},
+		{32, "6a20"},
+		{40, "6a28"},
+		{75, "6a4b"},
+		//Pushes of 76 bytes and more need OP_PUSHDATA1
+		{76, "6a4c4c"},
+		{MaxOpReturnDataSize, "6a4c50"},
+	}
+	for _, testCase := range testCases {
+		data := bytes.Repeat([]byte{0xab}, testCase.dataLength)
+		scriptPubKey, err := CreateOPReturnScriptPubKey(data)
+		if err != nil {
+			t.Error(err)
+		}
+		testScriptPubKey := testCase.scriptPubKeyPrefix + hex.EncodeToString(data)
+		if hex.EncodeToString(scriptPubKey) != testScriptPubKey {
+			testutils.CompareError(t, "OP_RETURN scriptPubKey different from expected scriptPubKey.", testScriptPubKey, hex.EncodeToString(scriptPubKey))
+		}
+		if !IsOpReturnScriptPubKey(scriptPubKey) {
+			t.Error("IsOpReturnScriptPubKey not recognizing OP_RETURN scriptPubKey.")
+		}
+	}
+	if _, err := CreateOPReturnScriptPubKey(make([]byte, MaxOpReturnDataSize+1)); err == nil {
+		t.Error("CreateOPReturnScriptPubKey accepting data longer than the standard limit.")
+	}
+}
+
 func TestNewP2PKHScriptPubKey(t *testing.T) {
 	testPublicAddressString := "13LSqJeZBpqLHzmLkJ5mvRHiM11waShFUP"
 	testPublicKeyHash := base58check.Decode(testPublicAddressString)
@@ -108,15 +402,170 @@ func TestNewRawTransaction(t *testing.T) {
 	testAmount := 65600
 	testScriptSig := []byte{118, 169, 20, 146, 3, 228, 122, 22, 247, 153, 222, 208, 53, 50, 227, 228, 82, 96, 111, 220, 82, 0, 126, 136, 172}
 	testScriptPubKey := []byte{169, 20, 26, 139, 0, 38, 52, 49, 102, 98, 92, 116, 117, 240, 30, 72, 181, 237, 232, 192, 37, 46, 135}
-	testRawTx := []byte{1, 0, 0, 0, 1, 172, 198, 251, 158, 194, 195, 136, 77, 58, 18, 168, 158, 112, 120, 200, 56, 83, 217, 183, 145, 34, 129, 206, 251, 20, 186, 192, 10, 39, 55, 211, 58, 0, 0, 0, 0, 25, 118, 169, 20, 146, 3, 228, 122, 22, 247, 153, 222, 208, 53, 50, 227, 228, 82, 96, 111, 220, 82, 0, 126, 136, 172, 255, 255, 255, 255, 1, 64, 0, 1, 0, 0, 0, 0, 0, 23, 169, 20, 26, 139, 0, 38, 52, 49, 102, 98, 92, 116, 117, 240, 30, 72, 181, 237, 232, 192, 37, 46, 135, 0, 0, 0, 0}
+	testRawTx := []byte{2, 0, 0, 0, 1, 172, 198, 251, 158, 194, 195, 136, 77, 58, 18, 168, 158, 112, 120, 200, 56, 83, 217, 183, 145, 34, 129, 206, 251, 20, 186, 192, 10, 39, 55, 211, 58, 0, 0, 0, 0, 25, 118, 169, 20, 146, 3, 228, 122, 22, 247, 153, 222, 208, 53, 50, 227, 228, 82, 96, 111, 220, 82, 0, 126, 136, 172, 255, 255, 255, 255, 1, 64, 0, 1, 0, 0, 0, 0, 0, 23, 169, 20, 26, 139, 0, 38, 52, 49, 102, 98, 92, 116, 117, 240, 30, 72, 181, 237, 232, 192, 37, 46, 135, 0, 0, 0, 0}
 
-	rawTx, err := NewRawTransaction(testInputTx, testAmount, testScriptSig, testScriptPubKey)
+	testInputs := []Input{{TxHash: testInputTx, ScriptSig: testScriptSig, Sequence: SequenceFinal}}
+	rawTx, err := NewRawTransaction(testInputs, testAmount, testScriptPubKey)
 	if err != nil {
 		t.Error(err)
 	}
 	if !reflect.DeepEqual(rawTx, testRawTx) {
 		testutils.CompareError(t, "Raw transaction different from expected transaction.", testRawTx, rawTx)
 	}
+	//Negative amounts should be rejected rather than wrapping around
+	if _, err := NewRawTransaction(testInputs, -1, testScriptPubKey); err == nil {
+		t.Error("NewRawTransaction accepting negative amount.")
+	}
+}
+
+func TestNewRawTransactionMultipleInputs(t *testing.T) {
+	testInputs := []Input{
+		{
+			TxHash:    "3ad337270ac0ba14fbce812291b7d95338c878709ea8123a4d88c3c29efbc6ac",
+			ScriptSig: []byte{118, 169, 20, 146, 3, 228, 122, 22, 247, 153, 222, 208, 53, 50, 227, 228, 82, 96, 111, 220, 82, 0, 126, 136, 172},
+			Sequence:  SequenceFinal,
+		},
+		{
+			TxHash:      "2648867c648fa66f3c81f0d75c10577250e2568490741da6928e2fb8bad9479f",
+			OutputIndex: 1,
+			Sequence:    0xfffffffe,
+		},
+	}
+	testAmount := 65600
+	testScriptPubKey := []byte{169, 20, 26, 139, 0, 38, 52, 49, 102, 98, 92, 116, 117, 240, 30, 72, 181, 237, 232, 192, 37, 46, 135}
+	testRawTxHex := "0200000002acc6fb9ec2c3884d3a12a89e7078c83853d9b7912281cefb14bac00a2737d33a000000001976a9149203e47a16f799ded03532e3e452606fdc52007e88acffffffff9f47d9bab82f8e92a61d74908456e2507257105cd7f0813c6fa68f647c8648260100000000feffffff01400001000000000017a9141a8b0026343166625c7475f01e48b5ede8c0252e8700000000"
+
+	rawTx, err := NewRawTransaction(testInputs, testAmount, testScriptPubKey)
+	if err != nil {
+		t.Error(err)
+	}
+	rawTxHex := hex.EncodeToString(rawTx)
+	if rawTxHex != testRawTxHex {
+		testutils.CompareError(t, "Raw transaction with multiple inputs different from expected transaction.", testRawTxHex, rawTxHex)
+	}
+	//No inputs should be rejected
+	if _, err := NewRawTransaction(nil, testAmount, testScriptPubKey); err == nil {
+		t.Error("NewRawTransaction accepting transaction with no inputs.")
+	}
+}
+
+func TestNewRawTransactionWithOutputs(t *testing.T) {
+	testInputs := []Input{{
+		TxHash:    "3ad337270ac0ba14fbce812291b7d95338c878709ea8123a4d88c3c29efbc6ac",
+		ScriptSig: []byte{118, 169, 20, 146, 3, 228, 122, 22, 247, 153, 222, 208, 53, 50, 227, 228, 82, 96, 111, 220, 82, 0, 126, 136, 172},
+		Sequence:  SequenceFinal,
+	}}
+	testOutputs := []Output{
+		{Satoshis: 65600, ScriptPubKey: []byte{169, 20, 26, 139, 0, 38, 52, 49, 102, 98, 92, 116, 117, 240, 30, 72, 181, 237, 232, 192, 37, 46, 135}},
+		{Satoshis: 10000, ScriptPubKey: []byte{118, 169, 20, 146, 3, 228, 122, 22, 247, 153, 222, 208, 53, 50, 227, 228, 82, 96, 111, 220, 82, 0, 126, 136, 172}},
+	}
+	//version + input count + (hash + index + scriptSig length + scriptSig + sequence) + output count
+	//+ each (satoshis + scriptPubKey length + scriptPubKey) + locktime
+	testLength := 4 + 1 + (32 + 4 + 1 + 25 + 4) + 1 + (8 + 1 + 23) + (8 + 1 + 25) + 4
+	testOutputsHex := "02" + "4000010000000000" + "17a9141a8b0026343166625c7475f01e48b5ede8c0252e87" + "1027000000000000" + "1976a9149203e47a16f799ded03532e3e452606fdc52007e88ac"
+
+	rawTx, err := NewRawTransactionWithOutputs(testInputs, testOutputs)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(rawTx) != testLength {
+		testutils.CompareError(t, "Raw transaction with multiple outputs different length from expected transaction.", testLength, len(rawTx))
+	}
+	outputsHex := hex.EncodeToString(rawTx[4+1+(32+4+1+25+4) : len(rawTx)-4])
+	if outputsHex != testOutputsHex {
+		testutils.CompareError(t, "Raw transaction outputs different from expected outputs.", testOutputsHex, outputsHex)
+	}
+	//No outputs should be rejected
+	if _, err := NewRawTransactionWithOutputs(testInputs, nil); err == nil {
+		t.Error("NewRawTransactionWithOutputs accepting transaction with no outputs.")
+	}
+	//Zero amount outputs should be rejected
+	if _, err := NewRawTransactionWithOutputs(testInputs, []Output{{Satoshis: 0, ScriptPubKey: testOutputs[0].ScriptPubKey}}); err == nil {
+		t.Error("NewRawTransactionWithOutputs accepting zero amount output.")
+	}
+}
+
+func TestCheckOutputs(t *testing.T) {
+	testScriptPubKey, _ := hex.DecodeString("a9141a8b0026343166625c7475f01e48b5ede8c0252e87")
+	testOpReturnScriptPubKey, _ := hex.DecodeString("6a0b68656c6c6f20776f726c64")
+
+	total, err := CheckOutputs([]Output{{Satoshis: 65600, ScriptPubKey: testScriptPubKey}, {Satoshis: 0, ScriptPubKey: testOpReturnScriptPubKey}, {Satoshis: 10000, ScriptPubKey: testScriptPubKey}})
+	if err != nil {
+		t.Error(err)
+	}
+	if total != 75600 {
+		testutils.CompareError(t, "Outputs total different from expected total.", 75600, total)
+	}
+	if _, err := CheckOutputs([]Output{{Satoshis: MaxMoney, ScriptPubKey: testScriptPubKey}}); err != nil {
+		t.Error(err)
+	}
+
+	invalidOutputs := map[string][]Output{
+		"zero amount":           {{Satoshis: 0, ScriptPubKey: testScriptPubKey}},
+		"negative amount":       {{Satoshis: uint64(0xffffffffffffffff), ScriptPubKey: testScriptPubKey}},
+		"amount above MaxMoney": {{Satoshis: MaxMoney + 1, ScriptPubKey: testScriptPubKey}},
+		"total above MaxMoney":  {{Satoshis: MaxMoney, ScriptPubKey: testScriptPubKey}, {Satoshis: 1, ScriptPubKey: testScriptPubKey}},
+		"amount beyond int64":   {{Satoshis: 1 << 63, ScriptPubKey: testScriptPubKey}},
+	}
+	for description, outputs := range invalidOutputs {
+		if _, err := CheckOutputs(outputs); err == nil {
+			t.Errorf("CheckOutputs accepting %s.", description)
+		}
+	}
+}
+
+func TestCheckFee(t *testing.T) {
+	testScriptPubKey, _ := hex.DecodeString("a9141a8b0026343166625c7475f01e48b5ede8c0252e87")
+	testOutputs := []Output{{Satoshis: 65600, ScriptPubKey: testScriptPubKey}, {Satoshis: 24400, ScriptPubKey: testScriptPubKey}}
+
+	fee, err := CheckFee(100000, testOutputs)
+	if err != nil {
+		t.Error(err)
+	}
+	if fee != 10000 {
+		testutils.CompareError(t, "Implied fee different from expected fee.", 10000, fee)
+	}
+	if fee, err := CheckFee(90000, testOutputs); err != nil || fee != 0 {
+		t.Errorf("CheckFee rejecting outputs spending the whole input. %v", err)
+	}
+	if _, err := CheckFee(89999, testOutputs); err == nil {
+		t.Error("CheckFee accepting outputs exceeding the input amount.")
+	}
+}
+
+func TestNewRawTransactionLongScriptSig(t *testing.T) {
+	//A 3-of-5 multisig scriptSig with uncompressed keys and the embedded redeem script is around 560 bytes,
+	//so its length needs the 0xfd CompactSize prefix rather than a single byte.
+	testScriptSig := bytes.Repeat([]byte{0xab}, 560)
+	testInputs := []Input{{
+		TxHash:    "3ad337270ac0ba14fbce812291b7d95338c878709ea8123a4d88c3c29efbc6ac",
+		ScriptSig: testScriptSig,
+		Sequence:  SequenceFinal,
+	}}
+	testScriptPubKey := []byte{169, 20, 26, 139, 0, 38, 52, 49, 102, 98, 92, 116, 117, 240, 30, 72, 181, 237, 232, 192, 37, 46, 135}
+	testScriptSigLengthHex := "fd3002"
+
+	rawTx, err := NewRawTransaction(testInputs, 65600, testScriptPubKey)
+	if err != nil {
+		t.Error(err)
+	}
+	//version + input count + hash + index
+	scriptSigOffset := 4 + 1 + 32 + 4
+	scriptSigLengthHex := hex.EncodeToString(rawTx[scriptSigOffset : scriptSigOffset+3])
+	if scriptSigLengthHex != testScriptSigLengthHex {
+		testutils.CompareError(t, "Raw transaction scriptSig length different from expected length.", testScriptSigLengthHex, scriptSigLengthHex)
+	}
+	//Round trip the scriptSig back out of the serialized transaction
+	reader := bytes.NewReader(rawTx[scriptSigOffset:])
+	scriptSigLength, err := ReadVarInt(reader)
+	if err != nil {
+		t.Error(err)
+	}
+	scriptSig := make([]byte, scriptSigLength)
+	reader.Read(scriptSig)
+	if !bytes.Equal(scriptSig, testScriptSig) {
+		t.Error("scriptSig read back from raw transaction different from original scriptSig.")
+	}
 }
 
 func TestNewSignature(t *testing.T) {
@@ -133,3 +582,44 @@ func TestNewSignature(t *testing.T) {
 		testutils.CompareError(t, "ECDSA signature different from expected signature.", testSignature, signature)
 	}
 }
+
+func TestSortPublicKeys(t *testing.T) {
+	//BIP67 test vector 2, a 2-of-3 multisig with address 3CKHTjBKxCARLzwABMu9yD85kvtm7WnMfH
+	testSortedPublicKeys := []string{
+		"02632b12f4ac5b1d1b72b2a3b508c19172de44f6f46bcee50ba33f3f9291e47ed0",
+		"027735a29bae7780a9755fae7a1c4374c656ac6a69ea9f3697fda61bb99a4f3e77",
+		"02e2cc6bd5f45edd43bebe7cb9b675f0ce9ed3efe613b177588290ad188d11b404",
+	}
+	testRedeemScriptHex := "522102632b12f4ac5b1d1b72b2a3b508c19172de44f6f46bcee50ba33f3f9291e47ed021027735a29bae7780a9755fae7a1c4374c656ac6a69ea9f3697fda61bb99a4f3e772102e2cc6bd5f45edd43bebe7cb9b675f0ce9ed3efe613b177588290ad188d11b40453ae"
+	permutations := [][]int{{0, 1, 2}, {0, 2, 1}, {1, 0, 2}, {1, 2, 0}, {2, 0, 1}, {2, 1, 0}}
+	for _, permutation := range permutations {
+		publicKeys := make([][]byte, len(permutation))
+		for i, index := range permutation {
+			publicKeys[i], _ = hex.DecodeString(testSortedPublicKeys[index])
+		}
+		firstPublicKey := publicKeys[0]
+		sortedPublicKeys := SortPublicKeys(publicKeys)
+		for i, publicKey := range sortedPublicKeys {
+			if hex.EncodeToString(publicKey) != testSortedPublicKeys[i] {
+				testutils.CompareError(t, fmt.Sprintf("Public key %d of permutation %v different from expected key.", i, permutation), testSortedPublicKeys[i], hex.EncodeToString(publicKey))
+			}
+		}
+		if !bytes.Equal(publicKeys[0], firstPublicKey) {
+			t.Error("SortPublicKeys modifying the public keys given.")
+		}
+		redeemScript, err := CreateSortedMultiSigRedeemScript(2, publicKeys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hex.EncodeToString(redeemScript) != testRedeemScriptHex {
+			testutils.CompareError(t, fmt.Sprintf("Sorted redeem script of permutation %v different from expected script.", permutation), testRedeemScriptHex, hex.EncodeToString(redeemScript))
+		}
+	}
+
+	privateKey := make([]byte, 32)
+	privateKey[31] = 1
+	uncompressedPublicKey, _ := NewPublicKey(privateKey)
+	if _, err := CreateSortedMultiSigRedeemScript(1, [][]byte{uncompressedPublicKey}); err == nil {
+		t.Error("CreateSortedMultiSigRedeemScript accepting uncompressed public key.")
+	}
+}
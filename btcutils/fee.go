@@ -0,0 +1,250 @@
+// Transaction size estimation, so fees can be calculated from a fee rate before a transaction is signed.
+package btcutils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// Sizes in bytes used to estimate the size of a signed scriptSig.
+const (
+	maxSignatureSize  = 72 //Maximum size of a DER encoded ECDSA signature
+	lowRSignatureSize = 71 //Maximum size of a DER encoded ECDSA signature with low R, as SignHash grinds for
+	sigHashTypeSize   = 1  //Hash type byte appended to each signature
+)
+
+// signatureSize returns the largest signature SignHash can give, which is smaller unless low R grinding is disabled.
+func signatureSize() int {
+	if DisableLowR {
+		return maxSignatureSize
+	}
+	return lowRSignatureSize
+}
+
+// MaxFeeRate is the highest fee rate in satoshis per virtual byte considered sane.
+// Higher rates are almost certainly a mistake and should only be used if explicitly forced.
+const MaxFeeRate = 10000
+
+// CheckFeeRate validates a fee rate in satoshis per virtual byte. Negative rates are always rejected,
+// rates above MaxFeeRate are rejected unless force is true.
+func CheckFeeRate(feeRate int, force bool) error {
+	if feeRate < 0 {
+		return fmt.Errorf("Fee rate cannot be negative. Provided fee rate is %d sat/vB.", feeRate)
+	}
+	if feeRate > MaxFeeRate && !force {
+		return fmt.Errorf("Fee rate of %d sat/vB is above the maximum sane fee rate of %d sat/vB. Force to use it anyway.", feeRate, MaxFeeRate)
+	}
+	return nil
+}
+
+// DefaultMaxFee is the highest fee in satoshis, 0.01 BTC, CheckMaxFee allows unless given another ceiling.
+const DefaultMaxFee = 1000000
+
+// MaxFeePercent is the highest fee CheckMaxFee allows as a percentage of the amount sent.
+const MaxFeePercent = 10
+
+// HighFeeError is returned by CheckMaxFee for a fee above one of its ceilings.
+type HighFeeError struct {
+	Fee      int64 //Fee in satoshis
+	Ceiling  int64 //Highest fee in satoshis allowed by the ceiling hit
+	Sent     int64 //Satoshis sent, if Relative is set
+	Relative bool  //Whether the ceiling hit is MaxFeePercent of Sent rather than the absolute maximum fee
+}
+
+func (e *HighFeeError) Error() string {
+	if e.Relative {
+		return fmt.Sprintf("Fee of %d satoshis is above %d%% of the %d satoshis sent, a ceiling of %d satoshis.", e.Fee, MaxFeePercent, e.Sent, e.Ceiling)
+	}
+	return fmt.Sprintf("Fee of %d satoshis is above the maximum fee of %d satoshis.", e.Fee, e.Ceiling)
+}
+
+// CheckMaxFee guards against mistyped amounts turning into fees, refusing a fee above maxFee satoshis, or above
+// MaxFeePercent of sent, the satoshis sent to outputs other than change. Fees above either ceiling are refused with
+// a *HighFeeError, which callers should let users override explicitly.
+func CheckMaxFee(fee int64, sent int64, maxFee int64) error {
+	if maxFee < 0 {
+		return fmt.Errorf("Maximum fee cannot be negative. Provided maximum is %d satoshis.", maxFee)
+	}
+	if fee > maxFee {
+		return &HighFeeError{Fee: fee, Ceiling: maxFee}
+	}
+	//Compared as fee*100 > sent*MaxFeePercent, which can't overflow for amounts up to MaxMoney
+	if fee*100 > sent*MaxFeePercent {
+		return &HighFeeError{Fee: fee, Ceiling: sent * MaxFeePercent / 100, Sent: sent, Relative: true}
+	}
+	return nil
+}
+
+// DefaultDustRelayFeeRate is the dust relay fee rate in satoshis per kilo virtual byte used by Bitcoin Core nodes
+// unless configured otherwise.
+const DefaultDustRelayFeeRate = 3000
+
+// Sizes in bytes of the smallest typical input spending an output, used to calculate dust thresholds.
+const (
+	spendInputSize        = 32 + 4 + 1 + 107 + 4   //outpoint + scriptSig length + P2PKH scriptSig + sequence
+	witnessSpendInputSize = 32 + 4 + 1 + 107/4 + 4 //as above, with the signature and public key in the discounted witness
+)
+
+// DustThreshold returns the smallest amount in satoshis an output with scriptPubKey can hold without being dust,
+// as calculated by Bitcoin Core: the fee at dustRelayFeeRate satoshis per kilo virtual byte to create and later
+// spend the output. Unspendable OP_RETURN outputs have no dust threshold.
+func DustThreshold(scriptPubKey []byte, dustRelayFeeRate int) int {
+	if IsOpReturnScriptPubKey(scriptPubKey) {
+		return 0
+	}
+	var varInt bytes.Buffer
+	WriteVarInt(&varInt, uint64(len(scriptPubKey)))
+	size := 8 + varInt.Len() + len(scriptPubKey) //satoshis + scriptPubKey
+	if IsWitnessScriptPubKey(scriptPubKey) {
+		size += witnessSpendInputSize
+	} else {
+		size += spendInputSize
+	}
+	return size * dustRelayFeeRate / 1000
+}
+
+// IsDust reports whether an output of satoshis paying to scriptPubKey is dust at feeRate satoshis per virtual byte,
+// worth less than the fee to create and later spend it, so nodes will not relay transactions creating it. At the
+// default rate of 3 sat/vB outputs below 546 satoshis are dust for P2PKH, and below 294 satoshis for P2WPKH.
+func IsDust(scriptPubKey []byte, satoshis int64, feeRate int64) bool {
+	return satoshis < int64(DustThreshold(scriptPubKey, int(feeRate*1000)))
+}
+
+// CheckDust validates that output holds at least the dust threshold of its scriptPubKey at dustRelayFeeRate
+// satoshis per kilo virtual byte, since nodes will not relay transactions with dust outputs.
+func CheckDust(output Output, dustRelayFeeRate int) error {
+	if dustRelayFeeRate < 0 {
+		return fmt.Errorf("Dust relay fee rate cannot be negative. Provided fee rate is %d sat/kvB.", dustRelayFeeRate)
+	}
+	threshold := DustThreshold(output.ScriptPubKey, dustRelayFeeRate)
+	if int64(output.Satoshis) < int64(threshold) {
+		return fmt.Errorf("Output of %d satoshis is below the dust threshold of %d satoshis for its script at a dust relay fee rate of %d sat/kvB.", output.Satoshis, threshold, dustRelayFeeRate)
+	}
+	return nil
+}
+
+// pushDataSize returns the size of the opcodes needed to push dataLength bytes onto the stack.
+func pushDataSize(dataLength int) int {
+	switch {
+	case dataLength < OP_PUSHDATA1:
+		return 1
+	case dataLength <= 0xff:
+		return 2 //OP_PUSHDATA1 <1 byte length>
+	default:
+		return 3 //OP_PUSHDATA2 <2 byte length>
+	}
+}
+
+// EstimateP2PKHScriptSigSize estimates the size of a signed P2PKH scriptSig given the length of the
+// public key, assuming the largest possible signature.
+func EstimateP2PKHScriptSigSize(publicKeyLength int) int {
+	//<sig> <pubkey>
+	return 1 + signatureSize() + sigHashTypeSize + pushDataSize(publicKeyLength) + publicKeyLength
+}
+
+// EstimateMultisigScriptSigSize estimates the size of a signed P2SH multisig scriptSig given the number
+// of signatures m and the length of the redeem script, assuming the largest possible signatures.
+func EstimateMultisigScriptSigSize(m int, redeemScriptLength int) int {
+	//OP_0 <sig1> ... <sigm> <redeemScript>
+	return 1 + m*(1+signatureSize()+sigHashTypeSize) + pushDataSize(redeemScriptLength) + redeemScriptLength
+}
+
+// EstimateSize estimates the size in bytes of a signed transaction, given the estimated size of the scriptSig
+// for each input and the outputs. For transactions without witness data the virtual size is equal to the size.
+func EstimateSize(scriptSigSizes []int, outputs []Output) int {
+	var varInt bytes.Buffer
+	WriteVarInt(&varInt, uint64(len(scriptSigSizes)))
+	WriteVarInt(&varInt, uint64(len(outputs)))
+	size := 4 + varInt.Len() + 4 //version + input and output counts + lock time
+	for _, scriptSigSize := range scriptSigSizes {
+		varInt.Reset()
+		WriteVarInt(&varInt, uint64(scriptSigSize))
+		size += 32 + 4 + varInt.Len() + scriptSigSize + 4 //hash + index + scriptSig + sequence
+	}
+	for _, output := range outputs {
+		varInt.Reset()
+		WriteVarInt(&varInt, uint64(len(output.ScriptPubKey)))
+		size += 8 + varInt.Len() + len(output.ScriptPubKey) //satoshis + scriptPubKey
+	}
+	return size
+}
+
+// TransactionWeight returns the BIP141 weight of tx in weight units. Bytes of the serialization without witness
+// data count 4 times and witness bytes, including the marker and flag, once, so the weight of a transaction without
+// witness data is 4 times its size.
+func TransactionWeight(tx *Transaction) (int, error) {
+	stripped, err := tx.serialize(false)
+	if err != nil {
+		return 0, err
+	}
+	full, err := tx.serialize(tx.HasWitness())
+	if err != nil {
+		return 0, err
+	}
+	return 3*len(stripped) + len(full), nil
+}
+
+// VirtualSize returns the BIP141 virtual size of tx in virtual bytes, its weight divided by 4 and rounded up, which
+// fee rates are given in.
+func VirtualSize(tx *Transaction) (int, error) {
+	weight, err := TransactionWeight(tx)
+	if err != nil {
+		return 0, err
+	}
+	return (weight + 3) / 4, nil
+}
+
+// EstimateFee estimates the fee in satoshis of tx at feeRate satoshis per virtual byte, from the virtual size tx
+// will have once signed. Signed inputs are counted as they are. Unsigned inputs have no witness and the script of
+// the output they spend as their scriptSig, as when calculating a legacy signature hash: a P2PKH scriptPubKey,
+// estimated as signed with a compressed public key (about 148 virtual bytes), a P2WPKH scriptPubKey (about 68
+// virtual bytes), or an M-of-N multisig redeem script spent from P2SH, estimated with M signatures.
+// Returns an error if feeRate is negative or an input has neither a scriptSig nor a witness.
+func EstimateFee(tx *Transaction, feeRate int64) (int64, error) {
+	if feeRate < 0 {
+		return 0, fmt.Errorf("Fee rate cannot be negative. Provided fee rate is %d sat/vB.", feeRate)
+	}
+	estimated := *tx
+	estimated.Inputs = make([]Input, len(tx.Inputs))
+	for i, input := range tx.Inputs {
+		signed, err := estimateSignedInput(input)
+		if err != nil {
+			return 0, fmt.Errorf("Cannot estimate the size of input %d. %s", i, err)
+		}
+		estimated.Inputs[i] = signed
+	}
+	virtualSize, err := VirtualSize(&estimated)
+	if err != nil {
+		return 0, err
+	}
+	return int64(virtualSize) * feeRate, nil
+}
+
+// estimateSignedInput returns input with its scriptSig and witness replaced by placeholders of the size they will
+// have once signed, if input is unsigned.
+func estimateSignedInput(input Input) (Input, error) {
+	if len(input.Witness) > 0 {
+		return input, nil
+	}
+	script := input.ScriptSig
+	//Placeholder signature with its hash type byte
+	signature := make([]byte, signatureSize()+sigHashTypeSize)
+	switch {
+	case len(script) == 0:
+		return input, errors.New("Unsigned inputs need the script they spend as their scriptSig.")
+	case len(script) == 25 && script[0] == OP_DUP && script[1] == OP_HASH160 && script[2] == 20 &&
+		script[23] == OP_EQUALVERIFY && script[24] == OP_CHECKSIG:
+		//P2PKH: <sig> <compressed pubkey>
+		input.ScriptSig = make([]byte, EstimateP2PKHScriptSigSize(33))
+	case len(script) == 22 && script[0] == OP_0 && script[1] == 20:
+		//P2WPKH: empty scriptSig, <sig> <compressed pubkey> in the witness
+		input.ScriptSig = nil
+		input.Witness = [][]byte{signature, make([]byte, 33)}
+	case len(script) > 3 && script[0] >= OP_1 && script[0] <= OP_16 && script[len(script)-1] == OP_CHECKMULTISIG:
+		//P2SH multisig: OP_0 <sig1> ... <sigm> <redeemScript>
+		m := int(script[0]) - OP_1 + 1
+		input.ScriptSig = make([]byte, EstimateMultisigScriptSigSize(m, len(script)))
+	}
+	return input, nil
+}
@@ -0,0 +1,395 @@
+package btcutils
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/testutils"
+	"github.com/CryptoProcessing/go-bitcoin-multisig/txscript"
+
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestEstimateSize(t *testing.T) {
+	testScriptPubKey, _ := hex.DecodeString("a9141a8b0026343166625c7475f01e48b5ede8c0252e87")
+	testOutputs := []Output{{Satoshis: 65600, ScriptPubKey: testScriptPubKey}}
+	//Signed funding transaction from TestGenerateFund is 221 bytes with a 70 byte signature, estimate assumes 71 bytes
+	testScriptSigSize := EstimateP2PKHScriptSigSize(65)
+	testSize := 222
+
+	size := EstimateSize([]int{testScriptSigSize}, testOutputs)
+	if size != testSize {
+		testutils.CompareError(t, "Estimated transaction size different from expected size.", testSize, size)
+	}
+
+	//Without low R grinding, signatures can be 72 bytes
+	DisableLowR = true
+	defer func() { DisableLowR = false }()
+	testSize = 223
+	size = EstimateSize([]int{EstimateP2PKHScriptSigSize(65)}, testOutputs)
+	if size != testSize {
+		testutils.CompareError(t, "Estimated transaction size without low R different from expected size.", testSize, size)
+	}
+}
+
+func TestEstimateMultisigScriptSigSize(t *testing.T) {
+	//2-of-3 with uncompressed keys has a 201 byte redeem script, pushed with OP_PUSHDATA1
+	testSize := 1 + 2*73 + 2 + 201
+
+	size := EstimateMultisigScriptSigSize(2, 201)
+	if size != testSize {
+		testutils.CompareError(t, "Estimated multisig scriptSig size different from expected size.", testSize, size)
+	}
+
+	//Without low R grinding, signatures can be 72 bytes
+	DisableLowR = true
+	defer func() { DisableLowR = false }()
+	testSize = 1 + 2*74 + 2 + 201
+	size = EstimateMultisigScriptSigSize(2, 201)
+	if size != testSize {
+		testutils.CompareError(t, "Estimated multisig scriptSig size without low R different from expected size.", testSize, size)
+	}
+}
+
+func TestPushDataSize(t *testing.T) {
+	//Each push the estimates count is as long as the push txscript.Builder writes
+	for length := 0; length <= txscript.MaxElementSize; length++ {
+		script, err := txscript.NewBuilder().AddData(make([]byte, length)).Script()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if size := pushDataSize(length) + length; size != len(script) {
+			testutils.CompareError(t, "Push size of "+strconv.Itoa(length)+" bytes different from the pushed size.", len(script), size)
+		}
+	}
+}
+
+func TestCheckFeeRate(t *testing.T) {
+	for _, validFeeRate := range []int{0, 1, MaxFeeRate} {
+		if err := CheckFeeRate(validFeeRate, false); err != nil {
+			t.Error(err)
+		}
+	}
+	if err := CheckFeeRate(-1, true); err == nil {
+		t.Error("CheckFeeRate accepting negative fee rate.")
+	}
+	if err := CheckFeeRate(MaxFeeRate+1, false); err == nil {
+		t.Error("CheckFeeRate accepting absurd fee rate without force.")
+	}
+	if err := CheckFeeRate(MaxFeeRate+1, true); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCheckMaxFee(t *testing.T) {
+	testCases := []struct {
+		fee      int64
+		sent     int64
+		maxFee   int64
+		ceiling  int64 //Ceiling hit, 0 if the fee is allowed
+		relative bool
+	}{
+		{1000, 100000, DefaultMaxFee, 0, false},
+		{10000, 100000, DefaultMaxFee, 0, false},            //Exactly MaxFeePercent of the amount sent
+		{10001, 100000, DefaultMaxFee, 10000, true},         //Above MaxFeePercent of the amount sent
+		{1000001, 100000000, DefaultMaxFee, 1000000, false}, //Above the absolute maximum
+		{20000, 1000000, 10000, 10000, false},               //Above a lowered absolute maximum
+		{0, 0, 0, 0, false},
+	}
+	for _, testCase := range testCases {
+		err := CheckMaxFee(testCase.fee, testCase.sent, testCase.maxFee)
+		if testCase.ceiling == 0 {
+			if err != nil {
+				t.Error(err)
+			}
+			continue
+		}
+		highFeeError, ok := err.(*HighFeeError)
+		if !ok {
+			t.Errorf("CheckMaxFee returned %v for a fee of %d satoshis sending %d, expected a HighFeeError.", err, testCase.fee, testCase.sent)
+			continue
+		}
+		if highFeeError.Fee != testCase.fee || highFeeError.Ceiling != testCase.ceiling || highFeeError.Relative != testCase.relative {
+			t.Errorf("CheckMaxFee returned %+v for a fee of %d satoshis sending %d, expected ceiling %d, relative %v.", *highFeeError, testCase.fee, testCase.sent, testCase.ceiling, testCase.relative)
+		}
+	}
+	if err := CheckMaxFee(0, 100000, -1); err == nil {
+		t.Error("CheckMaxFee accepting negative maximum fee.")
+	}
+}
+
+func TestDustThreshold(t *testing.T) {
+	//Thresholds at the default dust relay fee rate match those of Bitcoin Core
+	testCases := []struct {
+		scriptPubKey string
+		threshold    int
+	}{
+		{"76a914199db810a3c8ae5e55c0432d2b72e55b0634f79088ac", 546},                   //P2PKH
+		{"a9141a8b0026343166625c7475f01e48b5ede8c0252e87", 540},                       //P2SH
+		{"0014751e76e8199196d454941c45d1b3a323f1433bd6", 294},                         //P2WPKH
+		{"00201863143c14c5166804bd19203356da136c985678cd4d27a1b8c6329604903262", 330}, //P2WSH
+		{"51200f0c8db753acbd17343a39c2f3f4e35e4be6da749f9e35137ab220e7b238a667", 330}, //P2TR
+		{"6a20e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", 0},   //OP_RETURN
+	}
+	for _, testCase := range testCases {
+		testScriptPubKey, _ := hex.DecodeString(testCase.scriptPubKey)
+		threshold := DustThreshold(testScriptPubKey, DefaultDustRelayFeeRate)
+		if threshold != testCase.threshold {
+			testutils.CompareError(t, "Dust threshold different from expected threshold.", testCase.threshold, threshold)
+		}
+	}
+
+	//Thresholds scale with the dust relay fee rate
+	testScriptPubKey, _ := hex.DecodeString(testCases[0].scriptPubKey)
+	if threshold := DustThreshold(testScriptPubKey, 1000); threshold != 182 {
+		testutils.CompareError(t, "Dust threshold at 1000 sat/kvB different from expected threshold.", 182, threshold)
+	}
+}
+
+func TestIsDust(t *testing.T) {
+	//Dust thresholds of Bitcoin Core at its default dust relay fee rate of 3 sat/vB
+	testCases := []struct {
+		scriptPubKey string
+		threshold    int64
+	}{
+		{"76a914199db810a3c8ae5e55c0432d2b72e55b0634f79088ac", 546},                   //P2PKH
+		{"a9141a8b0026343166625c7475f01e48b5ede8c0252e87", 540},                       //P2SH
+		{"0014751e76e8199196d454941c45d1b3a323f1433bd6", 294},                         //P2WPKH
+		{"00201863143c14c5166804bd19203356da136c985678cd4d27a1b8c6329604903262", 330}, //P2WSH
+		{"51200f0c8db753acbd17343a39c2f3f4e35e4be6da749f9e35137ab220e7b238a667", 330}, //P2TR
+	}
+	for _, testCase := range testCases {
+		testScriptPubKey, _ := hex.DecodeString(testCase.scriptPubKey)
+		if IsDust(testScriptPubKey, testCase.threshold, 3) {
+			t.Errorf("IsDust reporting %d satoshis as dust for scriptPubKey %s.", testCase.threshold, testCase.scriptPubKey)
+		}
+		if !IsDust(testScriptPubKey, testCase.threshold-1, 3) {
+			t.Errorf("IsDust not reporting %d satoshis as dust for scriptPubKey %s.", testCase.threshold-1, testCase.scriptPubKey)
+		}
+	}
+
+	//OP_RETURN outputs are unspendable, so never dust
+	testOpReturnScriptPubKey, _ := hex.DecodeString("6a20e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855")
+	if IsDust(testOpReturnScriptPubKey, 0, 3) {
+		t.Error("IsDust reporting zero value OP_RETURN output as dust.")
+	}
+	//Thresholds scale with the fee rate, 182 virtual bytes for P2PKH
+	testScriptPubKey, _ := hex.DecodeString(testCases[0].scriptPubKey)
+	if IsDust(testScriptPubKey, 1820, 10) || !IsDust(testScriptPubKey, 1819, 10) {
+		t.Error("IsDust threshold for P2PKH at 10 sat/vB different from 1820 satoshis.")
+	}
+}
+
+func TestCheckDust(t *testing.T) {
+	testScriptPubKey, _ := hex.DecodeString("a9141a8b0026343166625c7475f01e48b5ede8c0252e87")
+
+	if err := CheckDust(Output{Satoshis: 540, ScriptPubKey: testScriptPubKey}, DefaultDustRelayFeeRate); err != nil {
+		t.Error(err)
+	}
+	if err := CheckDust(Output{Satoshis: 539, ScriptPubKey: testScriptPubKey}, DefaultDustRelayFeeRate); err == nil {
+		t.Error("CheckDust accepting output below the dust threshold.")
+	}
+	if err := CheckDust(Output{Satoshis: 0, ScriptPubKey: testScriptPubKey}, 0); err != nil {
+		t.Error(err)
+	}
+	if err := CheckDust(Output{Satoshis: 540, ScriptPubKey: testScriptPubKey}, -1); err == nil {
+		t.Error("CheckDust accepting negative dust relay fee rate.")
+	}
+}
+
+func TestVirtualSize(t *testing.T) {
+	//Signed BIP143 native P2WPKH example is 343 bytes, 233 of them without witness data, a weight of 1042
+	testTransaction, _ := hex.DecodeString("01000000000102fff7f7881a8099afa6940d42d1e7f6362bec38171ea3edf433541db4e4ad969f00000000494830450221008b9d1dc26ba6a9cb62127b02742fa9d754cd3bebf337f7a55d114c8e5cdd30be022040529b194ba3f9281a99f2b1c0a19c0489bc22ede944ccf4ecbab4cc618ef3ed01eeffffffef51e1b804cc89d182d279655c3aa89e815b1b309fe287d9b2b55d57b90ec68a0100000000ffffffff02202cb206000000001976a9148280b37df378db99f66f85c95a783a76ac7a6d5988ac9093510d000000001976a9143bde42dbee7e4dbe6a21b2d50ce2f0167faa815988ac000247304402203609e17b84f6a7d30c80bfa610b5b4542f32a8a0d5447a12fb1366d7f01cc44a0220573a954c4518331561406f90300e8f3358f51928d43c212a8caed02de67eebee0121025476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee635711000000")
+	testVirtualSize := 261
+	tx, err := DeserializeTransaction(testTransaction)
+	if err != nil {
+		t.Fatal(err)
+	}
+	virtualSize, err := VirtualSize(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if virtualSize != testVirtualSize {
+		testutils.CompareError(t, "Virtual size different from expected size.", testVirtualSize, virtualSize)
+	}
+
+	//Without witness data the virtual size is the size
+	tx.Inputs[1].Witness = nil
+	virtualSize, err = VirtualSize(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if virtualSize != 233 {
+		testutils.CompareError(t, "Virtual size without witness data different from expected size.", 233, virtualSize)
+	}
+}
+
+// testWeightTransactions are signed transactions of each input type with their weight and virtual size, as given by
+// Bitcoin Core's decoderawtransaction.
+var testWeightTransactions = []struct {
+	name        string
+	hex         string
+	weight      int
+	virtualSize int
+}{
+	{"P2PKH", "0100000001acc6fb9ec2c3884d3a12a89e7078c83853d9b7912281cefb14bac00a2737d33a000000008a47304402206d6caac248af96f6afa7f904f550253a0f3ef3f5aa2fe6838a95b216691468e202207d1c7fb129adec15700c378e142c506b5bbadafdedbb62f614dd0bb128faeecd01410431393af9984375830971ab5d3094c6a7d02db3568b2b06212a7090094549701bbb9e84d9477451acc42638963635899ce91bacb451a1bb6da73ddfbcf596bddfffffffff01400001000000000017a9141a8b0026343166625c7475f01e48b5ede8c0252e8700000000", 884, 221},
+	{"P2WPKH", "01000000000101ef51e1b804cc89d182d279655c3aa89e815b1b309fe287d9b2b55d57b90ec68a0100000000ffffffff01f01ec3230000000017a9141a8b0026343166625c7475f01e48b5ede8c0252e870247304402206d6caac248af96f6afa7f904f550253a0f3ef3f5aa2fe6838a95b216691468e202203c8ad85f3239a1cd07740523f3b16456f53a0572f7d72ab907a597a9179e39b30121025476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee635700000000", 441, 111},
+	{"P2SH-P2WPKH", "01000000000101db6b1b20aa0fd7b23880be2ecbd4a98130974cf4748fb66092ac4d3ceb1a5477010000001716001479091972186c449eb1ded22b78e40d009bdf0089feffffff01f0a29a3b000000001976a914a457b684d7f0d539a46a45bbc043f35b59d0d96388ac0247304402206d6caac248af96f6afa7f904f550253a0f3ef3f5aa2fe6838a95b216691468e202202eaf1481f7e4a6eb33dc90f11d1e38ddd3b04c9e646fa44b523f4c7400ded57f012103ad1d8e89212f0b92c74d23bb710c00662ad1470198ac48c43f7d6f93a2a2687392040000", 541, 136},
+	{"P2TR", "01000000000101acc6fb9ec2c3884d3a12a89e7078c83853d9b7912281cefb14bac00a2737d33a0100000000ffffffff01905f01000000000022512053a1f6e454df1aa2776a2814a721372d6258050de330b3c6d10ee8f4e0dda3430140f713faa59c59561c72fab211c442c9966827edb7690375645e0fb6c4a6b60da607aca3a7d480223a9fc92bc9b0d7e91231f97b24589100eca7b04d88926fe3e200000000", 444, 111},
+	{"P2PK and P2WPKH", "01000000000102fff7f7881a8099afa6940d42d1e7f6362bec38171ea3edf433541db4e4ad969f00000000494830450221008b9d1dc26ba6a9cb62127b02742fa9d754cd3bebf337f7a55d114c8e5cdd30be022040529b194ba3f9281a99f2b1c0a19c0489bc22ede944ccf4ecbab4cc618ef3ed01eeffffffef51e1b804cc89d182d279655c3aa89e815b1b309fe287d9b2b55d57b90ec68a0100000000ffffffff02202cb206000000001976a9148280b37df378db99f66f85c95a783a76ac7a6d5988ac9093510d000000001976a9143bde42dbee7e4dbe6a21b2d50ce2f0167faa815988ac000247304402203609e17b84f6a7d30c80bfa610b5b4542f32a8a0d5447a12fb1366d7f01cc44a0220573a954c4518331561406f90300e8f3358f51928d43c212a8caed02de67eebee0121025476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee635711000000", 1042, 261},
+}
+
+func TestTransactionWeight(t *testing.T) {
+	for _, test := range testWeightTransactions {
+		rawTx, _ := hex.DecodeString(test.hex)
+		tx, err := DeserializeTransaction(rawTx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		weight, err := TransactionWeight(tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if weight != test.weight {
+			testutils.CompareError(t, "Weight of "+test.name+" transaction different from expected weight.", test.weight, weight)
+		}
+		virtualSize, err := VirtualSize(tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if virtualSize != test.virtualSize {
+			testutils.CompareError(t, "Virtual size of "+test.name+" transaction different from expected size.", test.virtualSize, virtualSize)
+		}
+		//Without witness data the weight is 4 times the size
+		if !tx.HasWitness() && weight != 4*len(rawTx) {
+			testutils.CompareError(t, "Weight of "+test.name+" transaction different from 4 times its size.", 4*len(rawTx), weight)
+		}
+	}
+
+	//One P2WPKH input spent to one P2WPKH output, signed with a 72 byte signature: 82 bytes without witness data
+	//(version, counts and lock time 10, input 41, output 31) and 110 witness bytes (marker and flag 2, item count 1,
+	//signature 1+72, public key 1+33), a weight of 4*82+110 = 438 and a virtual size of 110
+	testScriptPubKey, _ := hex.DecodeString("0014ffe9c0061097cc3b636f2cb0460fa4fc427d2b45")
+	tx := &Transaction{
+		Version: 2,
+		Inputs:  []Input{{TxHash: strings.Repeat("11", 32), Sequence: SequenceFinal, Witness: [][]byte{make([]byte, 72), make([]byte, 33)}}},
+		Outputs: []Output{{Satoshis: 90000, ScriptPubKey: testScriptPubKey}},
+	}
+	weight, err := TransactionWeight(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if weight != 438 {
+		testutils.CompareError(t, "Weight of P2WPKH transaction different from expected weight.", 438, weight)
+	}
+	virtualSize, err := VirtualSize(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if virtualSize != 110 {
+		testutils.CompareError(t, "Virtual size of P2WPKH transaction different from expected size.", 110, virtualSize)
+	}
+}
+
+func BenchmarkTransactionWeight(b *testing.B) {
+	var txs []*Transaction
+	for _, test := range testWeightTransactions {
+		rawTx, _ := hex.DecodeString(test.hex)
+		tx, err := DeserializeTransaction(rawTx)
+		if err != nil {
+			b.Fatal(err)
+		}
+		txs = append(txs, tx)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, tx := range txs {
+			if _, err := TransactionWeight(tx); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestEstimateFee(t *testing.T) {
+	testPrivateKey, _ := hex.DecodeString("619c335025c7f4012e556c2a58b2506e30b8511b53ade95ea316fd8c3286feb9")
+	testPublicKey, _ := NewCompressedPublicKey(testPrivateKey)
+	testPublicKeyHash, _ := Hash160(testPublicKey)
+	testP2PKHScriptPubKey, _ := NewP2PKHScriptPubKey(testPublicKeyHash)
+	testP2WPKHScriptPubKey, _ := NewP2WPKHScriptPubKey(testPublicKeyHash)
+	testRedeemScript, _ := CreateMultiSigRedeemScript(2, [][]byte{testPublicKey, testPublicKey, testPublicKey})
+	testFeeRate := int64(10)
+
+	testCases := []struct {
+		name        string
+		scriptSig   []byte
+		virtualSize int64
+	}{
+		//10 bytes of version, counts and lock time, 34 byte P2PKH output
+		{"P2PKH", testP2PKHScriptPubKey, 10 + 148 + 34},
+		//Marker, flag and witness of 108 bytes add 27.5 virtual bytes to the 41 byte input
+		{"P2WPKH", testP2WPKHScriptPubKey, 10 + 69 + 34},
+		//2-of-3 with compressed keys has a 105 byte redeem script and a 254 byte scriptSig
+		{"P2SH multisig", testRedeemScript, 10 + 32 + 4 + 3 + 254 + 4 + 34},
+	}
+	for _, testCase := range testCases {
+		tx := &Transaction{
+			Version:  1,
+			Inputs:   []Input{{TxHash: "8ac60eb9575db5b2d987e29f301b5b819ea83a5c6579d282d189cc04b8e151ef", OutputIndex: 1, ScriptSig: testCase.scriptSig, Sequence: SequenceFinal}},
+			Outputs:  []Output{{Satoshis: 100000, ScriptPubKey: testP2PKHScriptPubKey}},
+			LockTime: 0,
+		}
+		fee, err := EstimateFee(tx, testFeeRate)
+		if err != nil {
+			t.Error(err)
+		}
+		if fee != testCase.virtualSize*testFeeRate {
+			testutils.CompareError(t, "Estimated fee of "+testCase.name+" input different from expected fee.", testCase.virtualSize*testFeeRate, fee)
+		}
+	}
+
+	//Estimated P2WPKH fee is within 2 virtual bytes of the signed transaction
+	tx := &Transaction{
+		Version:  1,
+		Inputs:   []Input{{TxHash: "8ac60eb9575db5b2d987e29f301b5b819ea83a5c6579d282d189cc04b8e151ef", OutputIndex: 1, ScriptSig: testP2WPKHScriptPubKey, Sequence: SequenceFinal}},
+		Outputs:  []Output{{Satoshis: 100000, ScriptPubKey: testP2WPKHScriptPubKey}},
+		LockTime: 0,
+	}
+	fee, err := EstimateFee(tx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx.Inputs[0].ScriptSig = nil
+	sigHash, err := CalcWitnessSigHash(tx, 0, testP2PKHScriptPubKey, 600000000, SigHashAll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature, err := SignHash(sigHash, testPrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx.Inputs[0].Witness = [][]byte{append(signature, byte(SigHashAll)), testPublicKey}
+	virtualSize, err := VirtualSize(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fee < int64(virtualSize) || fee > int64(virtualSize)+2 {
+		testutils.CompareError(t, "Estimated P2WPKH virtual size not within 2 virtual bytes of the signed transaction.", virtualSize, fee)
+	}
+	//Signed inputs are counted as they are
+	signedFee, err := EstimateFee(tx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signedFee != int64(virtualSize) {
+		testutils.CompareError(t, "Estimated fee of signed transaction different from its virtual size.", virtualSize, signedFee)
+	}
+
+	if _, err := EstimateFee(tx, -1); err == nil {
+		t.Error("EstimateFee accepting negative fee rate.")
+	}
+	tx.Inputs[0].Witness = nil
+	if _, err := EstimateFee(tx, 1); err == nil {
+		t.Error("EstimateFee accepting input without scriptSig or witness.")
+	}
+}
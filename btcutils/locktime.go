@@ -0,0 +1,334 @@
+// Transaction lock times, delaying a transaction until a block height or time, and BIP68 relative lock times,
+// delaying it until the output it spends is a number of blocks or seconds old. BIP65 OP_CHECKLOCKTIMEVERIFY scripts
+// lock outputs in the same way until a block height or time.
+// See https://en.bitcoin.it/wiki/NLockTime and https://github.com/bitcoin/bips/blob/master/bip-0068.mediawiki
+// for full specification.
+package btcutils
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/txscript"
+
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+)
+
+const (
+	//Lock times below LockTimeThreshold are block heights, others are Unix timestamps
+	LockTimeThreshold = 500000000
+	//Heights above maxLockTimeHeight are nearly 200 years of blocks away, and more likely a mistyped timestamp
+	maxLockTimeHeight = 10000000
+	//Timestamp of the genesis block, no earlier time lock makes sense
+	genesisTimestamp = 1231006505
+)
+
+// SequenceMaxNonFinal is the largest sequence number that is not final. A transaction's lock time is only
+// enforced if at least one of its inputs has a sequence number below SequenceFinal.
+const SequenceMaxNonFinal uint32 = 0xfffffffe
+
+// SequenceRBF is the largest sequence number signalling that a transaction can be replaced by one paying a higher
+// fee, as per BIP125. See https://github.com/bitcoin/bips/blob/master/bip-0125.mediawiki for full specification.
+const SequenceRBF uint32 = 0xfffffffd
+
+// IsRBFSignaled reports whether tx signals that it can be replaced by a higher fee transaction, which it does
+// as per BIP125 if any of its inputs has a sequence number below SequenceMaxNonFinal.
+func IsRBFSignaled(tx *Transaction) bool {
+	for _, input := range tx.Inputs {
+		if input.Sequence < SequenceMaxNonFinal {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckLockTime makes sure lockTime fits the 4 byte lock time field and is a sensible block height, below
+// LockTimeThreshold, or Unix timestamp, from LockTimeThreshold, catching timestamps given in milliseconds and
+// timestamps too small to be told apart from block heights. Zero disables the lock time.
+// Returns an error with a helpful message or nil if the lock time is valid.
+func CheckLockTime(lockTime int64) error {
+	switch {
+	case lockTime < 0 || lockTime > math.MaxUint32:
+		return fmt.Errorf("Lock time must be between 0 and %d. Provided lock time is %d.", uint32(math.MaxUint32), lockTime)
+	case lockTime < LockTimeThreshold && lockTime > maxLockTimeHeight:
+		return fmt.Errorf("Lock time %d is a block height, as it is below %d, but too far in the future. Unix timestamps must be at least %d.", lockTime, LockTimeThreshold, LockTimeThreshold)
+	case lockTime >= LockTimeThreshold && lockTime < genesisTimestamp:
+		return fmt.Errorf("Lock time %d is a Unix timestamp before the genesis block. Block heights must be below %d.", lockTime, LockTimeThreshold)
+	}
+	return nil
+}
+
+// Relative lock times are encoded in input sequence numbers as per BIP68, and only apply to transactions of
+// version RelativeLockTimeVersion or above.
+const (
+	RelativeLockTimeVersion = 2
+	//Sequence numbers with SequenceLockTimeDisabled set have no relative lock time, as do all final sequences
+	SequenceLockTimeDisabled uint32 = 1 << 31
+	//Relative lock times with SequenceLockTimeIsSeconds set are in units of 512 seconds, otherwise in blocks
+	SequenceLockTimeIsSeconds uint32 = 1 << 22
+	//Only the lowest 16 bits hold the relative lock time
+	SequenceLockTimeMask uint32 = 0x0000ffff
+	//Relative lock times in seconds are counted in units of 2^9 = 512 seconds
+	SequenceLockTimeGranularity = 9
+)
+
+// CheckLockTimeVerify checks that input inputIndex of tx satisfies an OP_CHECKLOCKTIMEVERIFY of lockTime, the number
+// on top of the stack, as per BIP65. The transaction lock time must be of the same type, block height or Unix
+// timestamp, and at least lockTime, and the input must not be final, as lock times are ignored for final inputs.
+// Returns an error with a helpful message or nil if the transaction satisfies the lock time.
+func CheckLockTimeVerify(tx *Transaction, inputIndex int, lockTime int64) error {
+	if inputIndex < 0 || inputIndex >= len(tx.Inputs) {
+		return fmt.Errorf("Input index %d out of range for a transaction with %d inputs.", inputIndex, len(tx.Inputs))
+	}
+	if lockTime < 0 {
+		return fmt.Errorf("OP_CHECKLOCKTIMEVERIFY of negative lock time %d always fails.", lockTime)
+	}
+	if (lockTime < LockTimeThreshold) != (tx.LockTime < LockTimeThreshold) {
+		return fmt.Errorf("OP_CHECKLOCKTIMEVERIFY requires a lock time that is a %s, but transaction lock time %d is a %s.", lockTimeUnit(lockTime), tx.LockTime, lockTimeUnit(int64(tx.LockTime)))
+	}
+	if int64(tx.LockTime) < lockTime {
+		return fmt.Errorf("OP_CHECKLOCKTIMEVERIFY requires a lock time of at least %d, but transaction lock time is %d.", lockTime, tx.LockTime)
+	}
+	if tx.Inputs[inputIndex].Sequence == SequenceFinal {
+		return fmt.Errorf("OP_CHECKLOCKTIMEVERIFY requires a non-final input, but input %d has the final sequence 0x%08x.", inputIndex, SequenceFinal)
+	}
+	return nil
+}
+
+// lockTimeUnit names the type of lockTime.
+func lockTimeUnit(lockTime int64) string {
+	if lockTime < LockTimeThreshold {
+		return "block height"
+	}
+	return "Unix timestamp"
+}
+
+// CreateCLTVScript creates a P2PKH script that cannot be spent until lockTime, a block height or Unix timestamp, as
+// per BIP65. The script is <lockTime> OP_CHECKLOCKTIMEVERIFY OP_DROP OP_DUP OP_HASH160 <pubKeyHash> OP_EQUALVERIFY
+// OP_CHECKSIG, and can be paid to with a P2SH or P2WSH address. Spending transactions need a lock time of the same
+// type and at least lockTime, and a non-final input sequence.
+func CreateCLTVScript(lockTime uint32, pubKeyHash []byte) ([]byte, error) {
+	if len(pubKeyHash) != 20 {
+		return nil, fmt.Errorf("Public key hash must be 20 bytes long. Provided public key hash is %d bytes long.", len(pubKeyHash))
+	}
+	script, err := newCheckLockTimeVerifyPrefix(lockTime)
+	if err != nil {
+		return nil, err
+	}
+	p2pkhScript, err := NewP2PKHScriptPubKey(pubKeyHash)
+	if err != nil {
+		return nil, err
+	}
+	return append(script, p2pkhScript...), nil
+}
+
+// CreateCLTVMultisigRedeemScript creates a M-of-N Multisig redeem script like CreateMultiSigRedeemScript that cannot
+// be spent until lockTime, a block height or Unix timestamp, as per BIP65. The script is <lockTime>
+// OP_CHECKLOCKTIMEVERIFY OP_DROP followed by the multisig script, and must fit the 520 byte P2SH redeem script limit.
+func CreateCLTVMultisigRedeemScript(lockTime uint32, m int, pubKeys [][]byte) ([]byte, error) {
+	script, err := newCheckLockTimeVerifyPrefix(lockTime)
+	if err != nil {
+		return nil, err
+	}
+	multisigScript, err := createMultiSigScript(m, pubKeys)
+	if err != nil {
+		return nil, err
+	}
+	redeemScript := append(script, multisigScript...)
+	if len(redeemScript) > MaxRedeemScriptSize {
+		return nil, fmt.Errorf("%d-of-%d lock time redeem script is %d bytes long, longer than the %d byte P2SH limit. Use fewer or compressed public keys.", m, len(pubKeys), len(redeemScript), MaxRedeemScriptSize)
+	}
+	return redeemScript, nil
+}
+
+// newCheckLockTimeVerifyPrefix returns <lockTime> OP_CHECKLOCKTIMEVERIFY OP_DROP, after checking lockTime with
+// CheckLockTime. A lock time of 0 is refused, as it would not lock anything.
+func newCheckLockTimeVerifyPrefix(lockTime uint32) ([]byte, error) {
+	if lockTime == 0 {
+		return nil, errors.New("OP_CHECKLOCKTIMEVERIFY lock time must not be 0.")
+	}
+	err := CheckLockTime(int64(lockTime))
+	if err != nil {
+		return nil, err
+	}
+	//OP_CHECKLOCKTIMEVERIFY accepts numbers of up to 5 bytes, enough for every 4 byte lock time with its sign bit
+	if len(txscript.Number(int64(lockTime))) > 5 {
+		return nil, fmt.Errorf("Lock time %d does not fit in a 5 byte script number.", lockTime)
+	}
+	var script bytes.Buffer
+	writeScriptNumber(&script, int64(lockTime))
+	script.WriteByte(OP_CHECKLOCKTIMEVERIFY)
+	script.WriteByte(OP_DROP)
+	return script.Bytes(), nil
+}
+
+// NewRelativeLockTimeSequence returns the input sequence number locking a transaction until the output it spends is
+// value blocks old, or value seconds old if seconds is set. Seconds are rounded up to the next multiple of 512, so
+// the lock is never shorter than asked. The sequence also signals replaceability as per BIP125.
+// Returns an error if value does not fit the 16 bits of a relative lock time.
+func NewRelativeLockTimeSequence(value int64, seconds bool) (uint32, error) {
+	if value < 0 {
+		return 0, fmt.Errorf("Relative lock time cannot be negative. Provided relative lock time is %d.", value)
+	}
+	if !seconds {
+		if value > int64(SequenceLockTimeMask) {
+			return 0, fmt.Errorf("Relative lock time must be at most %d blocks. Provided relative lock time is %d blocks.", SequenceLockTimeMask, value)
+		}
+		return uint32(value), nil
+	}
+	units := (value + 1<<SequenceLockTimeGranularity - 1) >> SequenceLockTimeGranularity
+	if units > int64(SequenceLockTimeMask) {
+		return 0, fmt.Errorf("Relative lock time must be at most %d seconds. Provided relative lock time is %d seconds.", int64(SequenceLockTimeMask)<<SequenceLockTimeGranularity, value)
+	}
+	return SequenceLockTimeIsSeconds | uint32(units), nil
+}
+
+// RelativeLockTime decodes the relative lock time of an input with the given sequence number in a transaction of the
+// given version, returning the number of blocks, or seconds if the second return value is set, the output spent must
+// be old. Returns false as the third value if the sequence has no relative lock time.
+func RelativeLockTime(version uint32, sequence uint32) (int64, bool, bool) {
+	if version < RelativeLockTimeVersion || sequence&SequenceLockTimeDisabled != 0 {
+		return 0, false, false
+	}
+	if sequence&SequenceLockTimeIsSeconds != 0 {
+		return int64(sequence&SequenceLockTimeMask) << SequenceLockTimeGranularity, true, true
+	}
+	return int64(sequence & SequenceLockTimeMask), false, true
+}
+
+// CheckSequenceVerify checks that input inputIndex of tx satisfies an OP_CHECKSEQUENCEVERIFY of relativeLockTime, the
+// number on top of the stack, as per BIP112. relativeLockTime is encoded like a sequence number, so
+// NewRelativeLockTimeSequence gives the value to use in a script. The input must have a relative lock time of the same
+// type, blocks or seconds, and at least as long.
+// Returns an error with a helpful message or nil if the input satisfies the relative lock time.
+func CheckSequenceVerify(tx *Transaction, inputIndex int, relativeLockTime int64) error {
+	if inputIndex < 0 || inputIndex >= len(tx.Inputs) {
+		return fmt.Errorf("Input index %d out of range for a transaction with %d inputs.", inputIndex, len(tx.Inputs))
+	}
+	if relativeLockTime < 0 {
+		return fmt.Errorf("OP_CHECKSEQUENCEVERIFY of negative relative lock time %d always fails.", relativeLockTime)
+	}
+	//OP_CHECKSEQUENCEVERIFY is a no-op if the disable flag is set, leaving it free for future soft forks
+	if uint32(relativeLockTime)&SequenceLockTimeDisabled != 0 {
+		return nil
+	}
+	if tx.Version < RelativeLockTimeVersion {
+		return fmt.Errorf("OP_CHECKSEQUENCEVERIFY requires a transaction version of at least %d. Transaction version is %d.", RelativeLockTimeVersion, tx.Version)
+	}
+	sequence := tx.Inputs[inputIndex].Sequence
+	if sequence&SequenceLockTimeDisabled != 0 {
+		return fmt.Errorf("OP_CHECKSEQUENCEVERIFY requires a relative lock time, but input %d has sequence 0x%08x without one.", inputIndex, sequence)
+	}
+	required := uint32(relativeLockTime) & (SequenceLockTimeIsSeconds | SequenceLockTimeMask)
+	if required&SequenceLockTimeIsSeconds != sequence&SequenceLockTimeIsSeconds {
+		return fmt.Errorf("OP_CHECKSEQUENCEVERIFY requires a relative lock time in %s, but input %d has sequence 0x%08x with one in %s.", relativeLockTimeUnit(required), inputIndex, sequence, relativeLockTimeUnit(sequence))
+	}
+	if sequence&SequenceLockTimeMask < required&SequenceLockTimeMask {
+		requiredValue, _, _ := RelativeLockTime(RelativeLockTimeVersion, required)
+		value, _, _ := RelativeLockTime(RelativeLockTimeVersion, sequence)
+		return fmt.Errorf("OP_CHECKSEQUENCEVERIFY requires a relative lock time of %d %s, but input %d only has %d %s.", requiredValue, relativeLockTimeUnit(required), inputIndex, value, relativeLockTimeUnit(sequence))
+	}
+	return nil
+}
+
+// CreateCSVScript creates a P2PKH script that cannot be spent until the output is a number of blocks or seconds old,
+// as per BIP112. sequence is the BIP68 relative lock time given by NewRelativeLockTimeSequence, in blocks or, with
+// SequenceLockTimeIsSeconds set, in units of 512 seconds. The script is <sequence> OP_CHECKSEQUENCEVERIFY OP_DROP
+// OP_DUP OP_HASH160 <pubKeyHash> OP_EQUALVERIFY OP_CHECKSIG, and can be paid to with a P2SH or P2WSH address.
+// Spending transactions need a version of at least 2 and an input sequence with a relative lock time at least as long.
+func CreateCSVScript(sequence uint32, pubKeyHash []byte) ([]byte, error) {
+	if len(pubKeyHash) != 20 {
+		return nil, fmt.Errorf("Public key hash must be 20 bytes long. Provided public key hash is %d bytes long.", len(pubKeyHash))
+	}
+	script, err := newCheckSequenceVerifyPrefix(sequence)
+	if err != nil {
+		return nil, err
+	}
+	p2pkhScript, err := NewP2PKHScriptPubKey(pubKeyHash)
+	if err != nil {
+		return nil, err
+	}
+	return append(script, p2pkhScript...), nil
+}
+
+// CreateCSVMultisigRedeemScript creates a M-of-N Multisig redeem script like CreateMultiSigRedeemScript that cannot be
+// spent until the output is a number of blocks or seconds old, as per BIP112, such as the 2-of-2 multisig of payment
+// channel outputs. The script is <sequence> OP_CHECKSEQUENCEVERIFY OP_DROP followed by the multisig script, with
+// sequence as for CreateCSVScript, and must fit the 520 byte P2SH redeem script limit.
+func CreateCSVMultisigRedeemScript(sequence uint32, m int, pubKeys [][]byte) ([]byte, error) {
+	script, err := newCheckSequenceVerifyPrefix(sequence)
+	if err != nil {
+		return nil, err
+	}
+	multisigScript, err := createMultiSigScript(m, pubKeys)
+	if err != nil {
+		return nil, err
+	}
+	redeemScript := append(script, multisigScript...)
+	if len(redeemScript) > MaxRedeemScriptSize {
+		return nil, fmt.Errorf("%d-of-%d relative lock time redeem script is %d bytes long, longer than the %d byte P2SH limit. Use fewer or compressed public keys.", m, len(pubKeys), len(redeemScript), MaxRedeemScriptSize)
+	}
+	return redeemScript, nil
+}
+
+// newCheckSequenceVerifyPrefix returns <sequence> OP_CHECKSEQUENCEVERIFY OP_DROP. The sequence must hold a non-zero
+// BIP68 relative lock time, without the disable flag, which would make OP_CHECKSEQUENCEVERIFY a no-op, or any bits
+// other than the type flag and the 16 bit value, which are most likely a mistake.
+func newCheckSequenceVerifyPrefix(sequence uint32) ([]byte, error) {
+	if sequence&SequenceLockTimeDisabled != 0 {
+		return nil, fmt.Errorf("Sequence 0x%08x has the disable flag set, so OP_CHECKSEQUENCEVERIFY would not lock anything.", sequence)
+	}
+	if sequence&^(SequenceLockTimeIsSeconds|SequenceLockTimeMask) != 0 {
+		return nil, fmt.Errorf("Sequence 0x%08x has bits set other than the BIP68 type flag and relative lock time.", sequence)
+	}
+	if sequence&SequenceLockTimeMask == 0 {
+		return nil, errors.New("OP_CHECKSEQUENCEVERIFY relative lock time must not be 0.")
+	}
+	var script bytes.Buffer
+	writeScriptNumber(&script, int64(sequence))
+	script.WriteByte(OP_CHECKSEQUENCEVERIFY)
+	script.WriteByte(OP_DROP)
+	return script.Bytes(), nil
+}
+
+// relativeLockTimeUnit names the unit of the relative lock time encoded in sequence.
+func relativeLockTimeUnit(sequence uint32) string {
+	if sequence&SequenceLockTimeIsSeconds != 0 {
+		return "seconds"
+	}
+	return "blocks"
+}
+
+// FindCheckSequenceVerify returns the relative lock time checked by each OP_CHECKSEQUENCEVERIFY in script, which is the
+// number pushed directly before it as in <relative lock time> OP_CHECKSEQUENCEVERIFY OP_DROP. Returns an error if the
+// script cannot be parsed, or an OP_CHECKSEQUENCEVERIFY does not directly follow a minimally encoded number of up to
+// 5 bytes.
+func FindCheckSequenceVerify(script []byte) ([]int64, error) {
+	opcodes, err := txscript.ParseScript(script)
+	if err != nil {
+		return nil, err
+	}
+	var relativeLockTimes []int64
+	for i, opcode := range opcodes {
+		if opcode.Opcode != OP_CHECKSEQUENCEVERIFY {
+			continue
+		}
+		if i == 0 || opcodes[i-1].Data == nil {
+			return nil, errors.New("OP_CHECKSEQUENCEVERIFY does not follow a relative lock time.")
+		}
+		//OP_CHECKSEQUENCEVERIFY accepts numbers of up to 5 bytes
+		relativeLockTime, err := txscript.ParseNumber(opcodes[i-1].Data, 5)
+		if err != nil {
+			return nil, err
+		}
+		relativeLockTimes = append(relativeLockTimes, relativeLockTime)
+	}
+	return relativeLockTimes, nil
+}
+
+// writeScriptNumber writes the smallest push of number to w, OP_0, OP_1NEGATE or OP_1 through OP_16 if possible.
+func writeScriptNumber(w *bytes.Buffer, number int64) {
+	//Pushes of numbers are at most 9 bytes long, well within the element size limit
+	data, _ := txscript.NewBuilder().AddInt64(number).Script()
+	w.Write(data)
+}
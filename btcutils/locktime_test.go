@@ -0,0 +1,411 @@
+package btcutils
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/testutils"
+	"github.com/CryptoProcessing/go-bitcoin-multisig/txscript"
+
+	"encoding/binary"
+	"encoding/hex"
+	"reflect"
+	"testing"
+)
+
+func TestCheckLockTime(t *testing.T) {
+	//No lock time, block heights and Unix timestamps
+	for _, lockTime := range []int64{0, 1, 840000, maxLockTimeHeight, genesisTimestamp, 1735689600, 0xffffffff} {
+		if err := CheckLockTime(lockTime); err != nil {
+			t.Error(err)
+		}
+	}
+	invalidLockTimes := []int64{
+		-1,
+		0x100000000,
+		//Timestamp in milliseconds
+		1735689600000,
+		//Too far in the future to be a block height, likely a timestamp with a digit missing
+		maxLockTimeHeight + 1,
+		LockTimeThreshold - 1,
+		//Timestamps before the genesis block
+		LockTimeThreshold,
+		genesisTimestamp - 1,
+	}
+	for _, invalidLockTime := range invalidLockTimes {
+		if err := CheckLockTime(invalidLockTime); err == nil {
+			t.Errorf("CheckLockTime accepting invalid lock time %d.", invalidLockTime)
+		}
+	}
+}
+
+func TestIsRBFSignaled(t *testing.T) {
+	testScriptPubKey, _ := hex.DecodeString("76a914199db810a3c8ae5e55c0432d2b72e55b0634f79088ac")
+	testCases := []struct {
+		sequences []uint32
+		rbf       bool
+	}{
+		{[]uint32{SequenceRBF}, true},
+		{[]uint32{SequenceFinal}, false},
+		{[]uint32{SequenceMaxNonFinal}, false},                //Enables the lock time only
+		{[]uint32{0}, true},                                   //Lowest sequence
+		{[]uint32{SequenceFinal, SequenceRBF}, true},          //A single signalling input is enough
+		{[]uint32{SequenceFinal, SequenceMaxNonFinal}, false}, //No input below SequenceMaxNonFinal
+	}
+	for _, testCase := range testCases {
+		var inputs []Input
+		for i, sequence := range testCase.sequences {
+			inputs = append(inputs, Input{TxHash: "3ad337270ac0ba14fbce812291b7d95338c878709ea8123a4d88c3c29efbc6ac", OutputIndex: uint32(i), Sequence: sequence})
+		}
+		rawTransaction, err := NewRawTransaction(inputs, 65600, testScriptPubKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		//Sequence of the first input follows version (4 bytes), input count (1), outpoint (36) and empty scriptSig (1)
+		sequenceHex := hex.EncodeToString(rawTransaction[42:46])
+		expectedSequence := make([]byte, 4)
+		binary.LittleEndian.PutUint32(expectedSequence, testCase.sequences[0])
+		expectedSequenceHex := hex.EncodeToString(expectedSequence)
+		if sequenceHex != expectedSequenceHex {
+			testutils.CompareError(t, "Serialized input sequence different from expected sequence.", expectedSequenceHex, sequenceHex)
+		}
+		tx, err := DeserializeTransaction(rawTransaction)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if IsRBFSignaled(tx) != testCase.rbf {
+			testutils.CompareError(t, "IsRBFSignaled different from expected.", testCase.rbf, IsRBFSignaled(tx))
+		}
+	}
+}
+
+func TestCreateCLTVScript(t *testing.T) {
+	testPubKeyHash, _ := hex.DecodeString("199db810a3c8ae5e55c0432d2b72e55b0634f790")
+	testP2PKHScriptHex := "76a914199db810a3c8ae5e55c0432d2b72e55b0634f79088ac"
+	testCases := []struct {
+		lockTime       uint32
+		lockTimePrefix string
+	}{
+		{16, "60"},
+		{128, "028000"}, //Extra byte for the sign bit
+		{500, "02f401"},
+		{840000, "0340d10c"},
+		{1735689600, "0480857467"},
+		{0xffffffff, "05ffffffff00"}, //Largest lock time needs all 5 bytes
+	}
+	for _, testCase := range testCases {
+		script, err := CreateCLTVScript(testCase.lockTime, testPubKeyHash)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		//<lockTime> OP_CHECKLOCKTIMEVERIFY OP_DROP OP_DUP OP_HASH160 <pubKeyHash> OP_EQUALVERIFY OP_CHECKSIG
+		testScriptHex := testCase.lockTimePrefix + "b175" + testP2PKHScriptHex
+		if hex.EncodeToString(script) != testScriptHex {
+			testutils.CompareError(t, "CLTV script different from expected script.", testScriptHex, hex.EncodeToString(script))
+		}
+	}
+
+	invalidTestCases := []struct {
+		lockTime   uint32
+		pubKeyHash []byte
+	}{
+		{0, testPubKeyHash},
+		{173568960, testPubKeyHash}, //Timestamp with a digit missing
+		{840000, testPubKeyHash[1:]},
+	}
+	for _, invalidTestCase := range invalidTestCases {
+		if _, err := CreateCLTVScript(invalidTestCase.lockTime, invalidTestCase.pubKeyHash); err == nil {
+			t.Errorf("CreateCLTVScript accepting lock time %d with %d byte public key hash.", invalidTestCase.lockTime, len(invalidTestCase.pubKeyHash))
+		}
+	}
+}
+
+func TestCreateCLTVMultisigRedeemScript(t *testing.T) {
+	//Compressed public keys of private keys 1 to 15
+	publicKeys := make([][]byte, 15)
+	for i := range publicKeys {
+		privateKey := make([]byte, 32)
+		privateKey[31] = byte(i + 1)
+		publicKeys[i], _ = NewCompressedPublicKey(privateKey)
+	}
+	redeemScript, err := CreateCLTVMultisigRedeemScript(840000, 2, publicKeys[:3])
+	if err != nil {
+		t.Fatal(err)
+	}
+	multisigScript, _ := CreateMultiSigRedeemScript(2, publicKeys[:3])
+	testRedeemScriptHex := "0340d10cb175" + hex.EncodeToString(multisigScript)
+	if hex.EncodeToString(redeemScript) != testRedeemScriptHex {
+		testutils.CompareError(t, "CLTV multisig redeem script different from expected script.", testRedeemScriptHex, hex.EncodeToString(redeemScript))
+	}
+
+	//15 compressed keys leave room for a lock time of up to 4 bytes within the 520 byte P2SH limit, but not 5
+	if _, err := CreateCLTVMultisigRedeemScript(1735689600, 15, publicKeys); err != nil {
+		t.Error(err)
+	}
+	if _, err := CreateCLTVMultisigRedeemScript(0xffffffff, 15, publicKeys); err == nil {
+		t.Error("CreateCLTVMultisigRedeemScript accepting redeem script over 520 bytes.")
+	}
+	if _, err := CreateCLTVMultisigRedeemScript(0, 2, publicKeys[:3]); err == nil {
+		t.Error("CreateCLTVMultisigRedeemScript accepting lock time 0.")
+	}
+	if _, err := CreateCLTVMultisigRedeemScript(840000, 4, publicKeys[:3]); err == nil {
+		t.Error("CreateCLTVMultisigRedeemScript accepting m>n.")
+	}
+}
+
+func TestCheckLockTimeVerify(t *testing.T) {
+	testPubKeyHash, _ := hex.DecodeString("199db810a3c8ae5e55c0432d2b72e55b0634f790")
+	testCases := []struct {
+		scriptLockTime uint32
+		txLockTime     uint32
+		sequence       uint32
+		valid          bool
+	}{
+		{840000, 840000, SequenceMaxNonFinal, true},
+		{840000, 840001, SequenceRBF, true},
+		{1735689600, 1735689600, SequenceMaxNonFinal, true},
+		//Lock time not yet reached
+		{840000, 839999, SequenceMaxNonFinal, false},
+		{1735689600, 1735689599, SequenceMaxNonFinal, false},
+		//Lock times are ignored for final inputs
+		{840000, 840000, SequenceFinal, false},
+		//Block heights and timestamps cannot satisfy each other
+		{840000, 1735689600, SequenceMaxNonFinal, false},
+		{1735689600, 840000, SequenceMaxNonFinal, false},
+	}
+	for _, testCase := range testCases {
+		//Lock time checked by the spending path of the script, pushed before OP_CHECKLOCKTIMEVERIFY
+		script, err := CreateCLTVScript(testCase.scriptLockTime, testPubKeyHash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lockTime, err := txscript.ParseNumber(script[1:1+script[0]], 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tx := &Transaction{Version: 1, Inputs: []Input{{Sequence: testCase.sequence}}, LockTime: testCase.txLockTime}
+		err = CheckLockTimeVerify(tx, 0, lockTime)
+		if testCase.valid && err != nil {
+			t.Error(err)
+		}
+		if !testCase.valid && err == nil {
+			t.Errorf("CheckLockTimeVerify accepting transaction lock time %d with sequence 0x%08x for lock time %d.", testCase.txLockTime, testCase.sequence, lockTime)
+		}
+	}
+	tx := &Transaction{Version: 1, Inputs: []Input{{Sequence: SequenceMaxNonFinal}}, LockTime: 840000}
+	if err := CheckLockTimeVerify(tx, 0, -1); err == nil {
+		t.Error("CheckLockTimeVerify accepting negative lock time.")
+	}
+	if err := CheckLockTimeVerify(tx, 1, 840000); err == nil {
+		t.Error("CheckLockTimeVerify accepting out of range input index.")
+	}
+}
+
+func TestNewRelativeLockTimeSequence(t *testing.T) {
+	testCases := []struct {
+		value    int64
+		seconds  bool
+		sequence uint32
+	}{
+		{0, false, 0},
+		{144, false, 144},
+		{0xffff, false, 0xffff},
+		{512, true, 0x00400001},
+		//Seconds are rounded up to 512 second units
+		{513, true, 0x00400002},
+		{86400, true, 0x004000a9},
+		{0xffff << 9, true, 0x0040ffff},
+	}
+	for _, testCase := range testCases {
+		sequence, err := NewRelativeLockTimeSequence(testCase.value, testCase.seconds)
+		if err != nil {
+			t.Error(err)
+		}
+		if sequence != testCase.sequence {
+			testutils.CompareError(t, "Relative lock time sequence different from expected sequence.", testCase.sequence, sequence)
+		}
+		value, seconds, ok := RelativeLockTime(RelativeLockTimeVersion, sequence)
+		if !ok || seconds != testCase.seconds || value < testCase.value {
+			t.Errorf("RelativeLockTime decoding sequence 0x%08x as %d, seconds %v, ok %v.", sequence, value, seconds, ok)
+		}
+	}
+	invalidTestCases := []struct {
+		value   int64
+		seconds bool
+	}{
+		{-1, false},
+		{0x10000, false},
+		{0xffff<<9 + 1, true},
+	}
+	for _, testCase := range invalidTestCases {
+		if _, err := NewRelativeLockTimeSequence(testCase.value, testCase.seconds); err == nil {
+			t.Errorf("NewRelativeLockTimeSequence accepting relative lock time %d, seconds %v.", testCase.value, testCase.seconds)
+		}
+	}
+	//No relative lock time in version 1 transactions, or with the disable flag
+	for _, sequence := range []uint32{SequenceFinal, SequenceRBF, SequenceLockTimeDisabled | 144} {
+		if _, _, ok := RelativeLockTime(RelativeLockTimeVersion, sequence); ok {
+			t.Errorf("RelativeLockTime decoding a relative lock time from sequence 0x%08x.", sequence)
+		}
+	}
+	if _, _, ok := RelativeLockTime(1, 144); ok {
+		t.Error("RelativeLockTime decoding a relative lock time in a version 1 transaction.")
+	}
+}
+
+func TestCheckSequenceVerify(t *testing.T) {
+	testCases := []struct {
+		version          uint32
+		sequence         uint32
+		relativeLockTime int64
+		valid            bool
+	}{
+		{2, 144, 144, true},
+		{2, 1000, 144, true},
+		{2, 143, 144, false},
+		{1, 144, 144, false},
+		{2, SequenceRBF, 144, false},
+		//Blocks and seconds cannot satisfy each other
+		{2, 0x004000a9, 144, false},
+		{2, 0x004000a9, 0x004000a9, true},
+		{2, 144, 0x00400001, false},
+		//The disable flag makes OP_CHECKSEQUENCEVERIFY a no-op
+		{1, SequenceFinal, int64(SequenceLockTimeDisabled), true},
+		{2, 144, -1, false},
+	}
+	for _, testCase := range testCases {
+		tx := &Transaction{Version: testCase.version, Inputs: []Input{{Sequence: testCase.sequence}}}
+		err := CheckSequenceVerify(tx, 0, testCase.relativeLockTime)
+		if testCase.valid && err != nil {
+			t.Error(err)
+		}
+		if !testCase.valid && err == nil {
+			t.Errorf("CheckSequenceVerify accepting version %d transaction with sequence 0x%08x for relative lock time %d.", testCase.version, testCase.sequence, testCase.relativeLockTime)
+		}
+	}
+}
+
+func TestCreateCSVScript(t *testing.T) {
+	testPubKeyHash, _ := hex.DecodeString("199db810a3c8ae5e55c0432d2b72e55b0634f790")
+	testP2PKHScriptHex := "76a914199db810a3c8ae5e55c0432d2b72e55b0634f79088ac"
+	testCases := []struct {
+		sequence       uint32
+		sequencePrefix string
+	}{
+		{1, "51"},
+		{16, "60"},
+		//144 blocks, about a day, needs a second byte as 0x90 has the sign bit set
+		{144, "029000"},
+		{0xffff, "03ffff00"},
+		//169 units of 512 seconds, about a day, with the BIP68 type flag in bit 22
+		{SequenceLockTimeIsSeconds | 169, "03a90040"},
+	}
+	for _, testCase := range testCases {
+		script, err := CreateCSVScript(testCase.sequence, testPubKeyHash)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		//<sequence> OP_CHECKSEQUENCEVERIFY OP_DROP OP_DUP OP_HASH160 <pubKeyHash> OP_EQUALVERIFY OP_CHECKSIG
+		testScriptHex := testCase.sequencePrefix + "b275" + testP2PKHScriptHex
+		if hex.EncodeToString(script) != testScriptHex {
+			testutils.CompareError(t, "CSV script different from expected script.", testScriptHex, hex.EncodeToString(script))
+		}
+		//The spending path finds the same relative lock time in the script, and is satisfied by an input with it
+		relativeLockTimes, err := FindCheckSequenceVerify(script)
+		if err != nil {
+			t.Error(err)
+		}
+		if !reflect.DeepEqual(relativeLockTimes, []int64{int64(testCase.sequence)}) {
+			testutils.CompareError(t, "Relative lock times found different from expected relative lock times.", []int64{int64(testCase.sequence)}, relativeLockTimes)
+		}
+		tx := &Transaction{Version: RelativeLockTimeVersion, Inputs: []Input{{Sequence: testCase.sequence}}}
+		if err := CheckSequenceVerify(tx, 0, int64(testCase.sequence)); err != nil {
+			t.Error(err)
+		}
+		tx.Inputs[0].Sequence--
+		if err := CheckSequenceVerify(tx, 0, int64(testCase.sequence)); err == nil {
+			t.Errorf("CheckSequenceVerify accepting sequence 0x%08x for relative lock time 0x%08x.", tx.Inputs[0].Sequence, testCase.sequence)
+		}
+	}
+
+	invalidTestCases := []struct {
+		sequence   uint32
+		pubKeyHash []byte
+	}{
+		{0, testPubKeyHash},
+		{SequenceLockTimeIsSeconds, testPubKeyHash},
+		{SequenceLockTimeDisabled | 144, testPubKeyHash},
+		{1<<23 | 144, testPubKeyHash}, //Bit outside the BIP68 fields
+		{144, testPubKeyHash[1:]},
+	}
+	for _, invalidTestCase := range invalidTestCases {
+		if _, err := CreateCSVScript(invalidTestCase.sequence, invalidTestCase.pubKeyHash); err == nil {
+			t.Errorf("CreateCSVScript accepting sequence 0x%08x with %d byte public key hash.", invalidTestCase.sequence, len(invalidTestCase.pubKeyHash))
+		}
+	}
+}
+
+func TestCreateCSVMultisigRedeemScript(t *testing.T) {
+	//Compressed public keys of private keys 1 to 16
+	publicKeys := make([][]byte, 16)
+	for i := range publicKeys {
+		privateKey := make([]byte, 32)
+		privateKey[31] = byte(i + 1)
+		publicKeys[i], _ = NewCompressedPublicKey(privateKey)
+	}
+	//2-of-2 delayed by 144 blocks
+	redeemScript, err := CreateCSVMultisigRedeemScript(144, 2, publicKeys[:2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	multisigScript, _ := CreateMultiSigRedeemScript(2, publicKeys[:2])
+	testRedeemScriptHex := "029000b275" + hex.EncodeToString(multisigScript)
+	if hex.EncodeToString(redeemScript) != testRedeemScriptHex {
+		testutils.CompareError(t, "CSV multisig redeem script different from expected script.", testRedeemScriptHex, hex.EncodeToString(redeemScript))
+	}
+
+	//15 compressed keys fit within the 520 byte P2SH limit with any relative lock time, 16 do not
+	if _, err := CreateCSVMultisigRedeemScript(SequenceLockTimeIsSeconds|SequenceLockTimeMask, 15, publicKeys[:15]); err != nil {
+		t.Error(err)
+	}
+	if _, err := CreateCSVMultisigRedeemScript(144, 16, publicKeys); err == nil {
+		t.Error("CreateCSVMultisigRedeemScript accepting redeem script over 520 bytes.")
+	}
+	if _, err := CreateCSVMultisigRedeemScript(0, 2, publicKeys[:2]); err == nil {
+		t.Error("CreateCSVMultisigRedeemScript accepting relative lock time 0.")
+	}
+	if _, err := CreateCSVMultisigRedeemScript(144, 3, publicKeys[:2]); err == nil {
+		t.Error("CreateCSVMultisigRedeemScript accepting m>n.")
+	}
+}
+
+func TestFindCheckSequenceVerify(t *testing.T) {
+	testCases := []struct {
+		scriptHex         string
+		relativeLockTimes []int64
+	}{
+		//<144> OP_CHECKSEQUENCEVERIFY OP_DROP OP_1 <pubkey> OP_1 OP_CHECKMULTISIG, 144 needing 2 bytes for its sign bit
+		{"029000b27551210279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f8179851ae", []int64{144}},
+		//OP_16 OP_CHECKSEQUENCEVERIFY and <0x4000a9> OP_CHECKSEQUENCEVERIFY
+		{"60b27503a90040b275", []int64{16, 0x004000a9}},
+		//No OP_CHECKSEQUENCEVERIFY
+		{"51210279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f8179851ae", nil},
+		//Negative relative lock time
+		{"0190b275", []int64{-16}},
+	}
+	for _, testCase := range testCases {
+		script, _ := hex.DecodeString(testCase.scriptHex)
+		relativeLockTimes, err := FindCheckSequenceVerify(script)
+		if err != nil {
+			t.Error(err)
+		}
+		if !reflect.DeepEqual(relativeLockTimes, testCase.relativeLockTimes) {
+			testutils.CompareError(t, "Relative lock times found different from expected relative lock times.", testCase.relativeLockTimes, relativeLockTimes)
+		}
+	}
+	for _, invalidScriptHex := range []string{"b2", "75b2", "0290", "4c", "06010203040506b2", "04a9004000b2", "029000b2b2"} {
+		script, _ := hex.DecodeString(invalidScriptHex)
+		if _, err := FindCheckSequenceVerify(script); err == nil {
+			t.Errorf("FindCheckSequenceVerify accepting invalid script %s.", invalidScriptHex)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+// MuSig2 (BIP327) key aggregation, combining the public keys of an N-of-N multisig into a single public key.
+// Only key aggregation is implemented. Spending needs the interactive MuSig2 signing protocol, which is not.
+// See https://github.com/bitcoin/bips/blob/master/bip-0327.mediawiki for full specification.
+package btcutils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// MuSig2KeyAggContext holds the result of MuSig2 key aggregation, the aggregate public key and the accumulated sign
+// and tweak the MuSig2 signing protocol needs to sign for it, as the BIP327 KeyAgg Context.
+type MuSig2KeyAggContext struct {
+	PublicKeys [][]byte //Compressed public keys in the order they were aggregated in
+	point      *ecPoint
+	gacc       *big.Int
+	tacc       *big.Int
+}
+
+// MuSig2KeySort returns a copy of the 33 byte compressed publicKeys sorted in lexicographic order, as BIP327 KeySort
+// specifies. Aggregating sorted keys gives every cosigner the same aggregate key whatever order they list the keys in.
+func MuSig2KeySort(publicKeys [][]byte) [][]byte {
+	return SortPublicKeys(publicKeys)
+}
+
+// MuSig2KeyAgg aggregates the 33 byte compressed publicKeys of an N-of-N multisig into a single public key, as per
+// BIP327 KeyAgg. The aggregate key depends on the order of publicKeys, unless sortKeys is set, in which case they are
+// sorted with MuSig2KeySort first. Keys may be repeated. Returns an error if a key is invalid or, which only happens
+// if keys are chosen to cancel out, the aggregate key is the point at infinity.
+func MuSig2KeyAgg(publicKeys [][]byte, sortKeys bool) (*MuSig2KeyAggContext, error) {
+	if len(publicKeys) == 0 {
+		return nil, errors.New("MuSig2 key aggregation needs at least one public key.")
+	}
+	if sortKeys {
+		publicKeys = MuSig2KeySort(publicKeys)
+	}
+	keyAggList := taggedHash("KeyAgg list", publicKeys...)
+	secondKey := muSig2SecondKey(publicKeys)
+	var aggregate *ecPoint
+	for i, publicKey := range publicKeys {
+		if len(publicKey) != 33 {
+			return nil, fmt.Errorf("MuSig2 public keys should be 33 bytes long and compressed. Public key %d is %d bytes long.", i+1, len(publicKey))
+		}
+		point, err := parsePublicKeyPoint(publicKey)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid public key %d. %s", i+1, err)
+		}
+		coefficient := muSig2KeyAggCoefficient(keyAggList, secondKey, publicKey)
+		aggregate = ecAdd(aggregate, ecMul(coefficient, point))
+	}
+	if aggregate == nil {
+		return nil, errors.New("MuSig2 aggregate public key is the point at infinity.")
+	}
+	return &MuSig2KeyAggContext{
+		PublicKeys: publicKeys,
+		point:      aggregate,
+		gacc:       big.NewInt(1),
+		tacc:       big.NewInt(0),
+	}, nil
+}
+
+// MuSig2KeyAggCoefficient returns the 32 byte BIP327 KeyAgg coefficient publicKey is multiplied by before being
+// added to the aggregate key of publicKeys, in the order given. Returns an error if publicKey is not one of them.
+func MuSig2KeyAggCoefficient(publicKeys [][]byte, publicKey []byte) ([]byte, error) {
+	for _, key := range publicKeys {
+		if bytes.Equal(key, publicKey) {
+			keyAggList := taggedHash("KeyAgg list", publicKeys...)
+			return int2octets(muSig2KeyAggCoefficient(keyAggList, muSig2SecondKey(publicKeys), publicKey), 32), nil
+		}
+	}
+	return nil, errors.New("Public key is not one of the aggregated public keys.")
+}
+
+// muSig2SecondKey returns the first of publicKeys that differs from the first one, or 33 zero bytes if all are the
+// same, as per BIP327 GetSecondKey.
+func muSig2SecondKey(publicKeys [][]byte) []byte {
+	for _, key := range publicKeys {
+		if !bytes.Equal(key, publicKeys[0]) {
+			return key
+		}
+	}
+	return make([]byte, 33)
+}
+
+// muSig2KeyAggCoefficient computes the KeyAgg coefficient of publicKey, H_KeyAgg coefficient(keyAggList || publicKey)
+// mod N with keyAggList the hash of all the public keys. The second distinct key secondKey has coefficient 1, which
+// saves a multiplication.
+func muSig2KeyAggCoefficient(keyAggList []byte, secondKey []byte, publicKey []byte) *big.Int {
+	if bytes.Equal(publicKey, secondKey) {
+		return big.NewInt(1)
+	}
+	coefficient := new(big.Int).SetBytes(taggedHash("KeyAgg coefficient", keyAggList, publicKey))
+	return coefficient.Mod(coefficient, secp256k1N)
+}
+
+// PublicKey returns the aggregate public key as a 33 byte compressed public key.
+func (ctx *MuSig2KeyAggContext) PublicKey() []byte {
+	return compressPoint(ctx.point)
+}
+
+// XOnlyPublicKey returns the aggregate public key as a 32 byte BIP340 x-only public key.
+func (ctx *MuSig2KeyAggContext) XOnlyPublicKey() []byte {
+	return int2octets(ctx.point.x, 32)
+}
+
+// ApplyTweak returns a new context for the aggregate key plus tweak times G, as per BIP327 ApplyTweak. With xOnly
+// the aggregate key is first negated if needed for it to have an even y, as for BIP341 Taproot tweaks, otherwise it
+// is tweaked as a plain public key, as for BIP32 derivation. Returns an error if the 32 byte tweak is not less than
+// N or the tweaked key is the point at infinity.
+func (ctx *MuSig2KeyAggContext) ApplyTweak(tweak []byte, xOnly bool) (*MuSig2KeyAggContext, error) {
+	t, err := parseTweak(tweak)
+	if err != nil {
+		return nil, err
+	}
+	g := big.NewInt(1)
+	if xOnly && ctx.point.y.Bit(0) == 1 {
+		g.Sub(secp256k1N, g)
+	}
+	point := ecAdd(ecMul(g, ctx.point), ecMul(t, secp256k1G))
+	if point == nil {
+		return nil, errors.New("Tweaked MuSig2 aggregate public key is the point at infinity.")
+	}
+	gacc := new(big.Int).Mul(g, ctx.gacc)
+	tacc := new(big.Int).Mul(g, ctx.tacc)
+	tacc.Add(tacc, t)
+	return &MuSig2KeyAggContext{
+		PublicKeys: ctx.PublicKeys,
+		point:      point,
+		gacc:       gacc.Mod(gacc, secp256k1N),
+		tacc:       tacc.Mod(tacc, secp256k1N),
+	}, nil
+}
+
+// TaprootTweak returns a new context for the Taproot output key of the aggregate key used as the internal key with
+// the merkleRoot of the script tree, empty for key path only outputs. Its x-only public key is the same output key
+// TweakPublicKey returns for the x-only aggregate key.
+func (ctx *MuSig2KeyAggContext) TaprootTweak(merkleRoot []byte) (*MuSig2KeyAggContext, error) {
+	tweak, err := tapTweak(ctx.XOnlyPublicKey(), merkleRoot)
+	if err != nil {
+		return nil, err
+	}
+	return ctx.ApplyTweak(int2octets(tweak, 32), true)
+}
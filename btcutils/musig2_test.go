@@ -0,0 +1,182 @@
+package btcutils
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/testutils"
+
+	"bytes"
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+// BIP327 key_agg_vectors.json public keys, the last three invalid
+var testMuSig2PublicKeyHexes = []string{
+	"02f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9",
+	"03dff1d77f2a671c5f36183726db2341be58feae1da2deced843240f7b502ba659",
+	"023590a94e768f8e1815c2f24b4d80a8e3149316c3518ce7b7ad338368d038ca66",
+	"020000000000000000000000000000000000000000000000000000000000000005",
+	"02fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc30",
+	"04f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9",
+}
+
+func testMuSig2PublicKeys(indexes ...int) [][]byte {
+	publicKeys := make([][]byte, len(indexes))
+	for i, index := range indexes {
+		publicKeys[i], _ = hex.DecodeString(testMuSig2PublicKeyHexes[index])
+	}
+	return publicKeys
+}
+
+func TestMuSig2KeyAgg(t *testing.T) {
+	testCases := []struct {
+		indexes          []int
+		xOnlyAggregate   string
+		taprootOutputKey string
+	}{
+		{[]int{0, 1, 2}, "90539eede565f5d054f32cc0c220126889ed1e5d193baf15aef344fe59d4610c", "f79d14149ecd4bb74921865906a8e4f1333439a91b96610d72caa7495dcf2376"},
+		{[]int{2, 1, 0}, "6204de8b083426dc6eaf9502d27024d53fc826bf7d2012148a0575435df54b2b", "d61d333ab8c53c330290c144f406ce0c0dc3564b8e3dee6d1daa6288609bfc75"},
+		{[]int{0, 0, 0}, "b436e3bad62b8cd409969a224731c193d051162d8c5ae8b109306127da3aa935", "d0e703552cc5412c53ad0ad905b25289145dd9a0065fd91a9e72e84d70f9348a"},
+		{[]int{0, 0, 1, 1}, "69bc22bfa5d106306e48a20679de1d7389386124d07571d0d872686028c26a3e", "ce153062517d5411263a0f76c4329b59cb3bde98b2c6d207752f1b4d63007ee3"},
+	}
+	for _, testCase := range testCases {
+		ctx, err := MuSig2KeyAgg(testMuSig2PublicKeys(testCase.indexes...), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		xOnlyAggregate := hex.EncodeToString(ctx.XOnlyPublicKey())
+		if xOnlyAggregate != testCase.xOnlyAggregate {
+			testutils.CompareError(t, "MuSig2 aggregate public key different from expected key.", testCase.xOnlyAggregate, xOnlyAggregate)
+		}
+		if !bytes.Equal(ctx.PublicKey()[1:], ctx.XOnlyPublicKey()) {
+			t.Error("Compressed and x-only MuSig2 aggregate public keys have different x coordinates.")
+		}
+		//The Taproot tweak of the aggregate key gives the same output key as tweaking the x-only key directly
+		tweaked, err := ctx.TaprootTweak(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		taprootOutputKey := hex.EncodeToString(tweaked.XOnlyPublicKey())
+		if taprootOutputKey != testCase.taprootOutputKey {
+			testutils.CompareError(t, "MuSig2 Taproot output key different from expected key.", testCase.taprootOutputKey, taprootOutputKey)
+		}
+		outputKey, err := TweakPublicKey(ctx.XOnlyPublicKey(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(outputKey, tweaked.XOnlyPublicKey()) {
+			testutils.CompareError(t, "MuSig2 Taproot output key different from TweakPublicKey output key.", hex.EncodeToString(outputKey), taprootOutputKey)
+		}
+	}
+
+	//Sorting makes the aggregate key independent of the order of the keys
+	sorted, err := MuSig2KeyAgg(testMuSig2PublicKeys(0, 1, 2), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reverseSorted, err := MuSig2KeyAgg(testMuSig2PublicKeys(2, 1, 0), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(sorted.PublicKey(), reverseSorted.PublicKey()) {
+		testutils.CompareError(t, "MuSig2 aggregate public keys of sorted keys different.", hex.EncodeToString(sorted.PublicKey()), hex.EncodeToString(reverseSorted.PublicKey()))
+	}
+
+	for _, invalidIndex := range []int{3, 4, 5} {
+		if _, err := MuSig2KeyAgg(testMuSig2PublicKeys(0, invalidIndex), false); err == nil {
+			t.Errorf("MuSig2KeyAgg accepting invalid public key %s.", testMuSig2PublicKeyHexes[invalidIndex])
+		}
+	}
+	uncompressed, _ := hex.DecodeString("0479be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8")
+	if _, err := MuSig2KeyAgg([][]byte{uncompressed}, false); err == nil {
+		t.Error("MuSig2KeyAgg accepting uncompressed public key.")
+	}
+	if _, err := MuSig2KeyAgg(nil, false); err == nil {
+		t.Error("MuSig2KeyAgg accepting no public keys.")
+	}
+}
+
+func TestMuSig2KeySort(t *testing.T) {
+	//BIP327 key_sort_vectors.json
+	testPublicKeyHexes := []string{
+		"02dd308afec5777e13121fa72b9cc1b7cc0139715309b086c960e18fd969774eb8",
+		"02f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9",
+		"03dff1d77f2a671c5f36183726db2341be58feae1da2deced843240f7b502ba659",
+		"023590a94e768f8e1815c2f24b4d80a8e3149316c3518ce7b7ad338368d038ca66",
+		"02dd308afec5777e13121fa72b9cc1b7cc0139715309b086c960e18fd969774eff",
+		"02dd308afec5777e13121fa72b9cc1b7cc0139715309b086c960e18fd969774eb8",
+	}
+	testSortedHexes := []string{
+		"023590a94e768f8e1815c2f24b4d80a8e3149316c3518ce7b7ad338368d038ca66",
+		"02dd308afec5777e13121fa72b9cc1b7cc0139715309b086c960e18fd969774eb8",
+		"02dd308afec5777e13121fa72b9cc1b7cc0139715309b086c960e18fd969774eb8",
+		"02dd308afec5777e13121fa72b9cc1b7cc0139715309b086c960e18fd969774eff",
+		"02f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9",
+		"03dff1d77f2a671c5f36183726db2341be58feae1da2deced843240f7b502ba659",
+	}
+	testPublicKeys := make([][]byte, len(testPublicKeyHexes))
+	for i, testPublicKeyHex := range testPublicKeyHexes {
+		testPublicKeys[i], _ = hex.DecodeString(testPublicKeyHex)
+	}
+	sorted := MuSig2KeySort(testPublicKeys)
+	sortedHexes := make([]string, len(sorted))
+	for i, publicKey := range sorted {
+		sortedHexes[i] = hex.EncodeToString(publicKey)
+	}
+	if strings.Join(sortedHexes, ",") != strings.Join(testSortedHexes, ",") {
+		testutils.CompareError(t, "MuSig2 sorted public keys different from expected order.", strings.Join(testSortedHexes, ","), strings.Join(sortedHexes, ","))
+	}
+}
+
+func TestMuSig2KeyAggCoefficient(t *testing.T) {
+	testPublicKeys := testMuSig2PublicKeys(0, 1, 2)
+	ctx, err := MuSig2KeyAgg(testPublicKeys, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//The aggregate key is the sum of each public key times its coefficient, and the second key's coefficient is 1
+	var aggregate *ecPoint
+	for i, publicKey := range testPublicKeys {
+		coefficient, err := MuSig2KeyAggCoefficient(testPublicKeys, publicKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i == 1 && hex.EncodeToString(coefficient) != strings.Repeat("00", 31)+"01" {
+			testutils.CompareError(t, "MuSig2 coefficient of the second key is not 1.", strings.Repeat("00", 31)+"01", hex.EncodeToString(coefficient))
+		}
+		point, _ := parsePublicKeyPoint(publicKey)
+		aggregate = ecAdd(aggregate, ecMul(new(big.Int).SetBytes(coefficient), point))
+	}
+	if !bytes.Equal(compressPoint(aggregate), ctx.PublicKey()) {
+		testutils.CompareError(t, "Sum of public keys times coefficients different from MuSig2 aggregate public key.", hex.EncodeToString(ctx.PublicKey()), hex.EncodeToString(compressPoint(aggregate)))
+	}
+	if _, err := MuSig2KeyAggCoefficient(testPublicKeys, testMuSig2PublicKeys(3)[0]); err == nil {
+		t.Error("MuSig2KeyAggCoefficient accepting public key that is not aggregated.")
+	}
+}
+
+func TestMuSig2ApplyTweak(t *testing.T) {
+	ctx, err := MuSig2KeyAgg(testMuSig2PublicKeys(0, 1, 2), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//A plain tweak matches TweakAddPublicKey on the aggregate key
+	testTweak, _ := hex.DecodeString("e8f791ff9225a2af0102afff4a9a723d9612a682a25ebe79802b263cdfcd83bb")
+	tweaked, err := ctx.ApplyTweak(testTweak, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := TweakAddPublicKey(ctx.PublicKey(), testTweak)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(tweaked.PublicKey(), expected) {
+		testutils.CompareError(t, "Plain tweaked MuSig2 aggregate public key different from expected key.", hex.EncodeToString(expected), hex.EncodeToString(tweaked.PublicKey()))
+	}
+	if _, err := ctx.ApplyTweak(secp256k1N.Bytes(), true); err == nil {
+		t.Error("ApplyTweak accepting tweak equal to the curve order.")
+	}
+	if _, err := ctx.ApplyTweak(testTweak[1:], true); err == nil {
+		t.Error("ApplyTweak accepting 31 byte tweak.")
+	}
+}
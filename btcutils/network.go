@@ -0,0 +1,90 @@
+// Network parameters distinguishing addresses and private keys of the Bitcoin networks, so addresses for one
+// network are never mistaken for another.
+package btcutils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NetworkParams holds the version bytes and Bech32 human readable part used to encode addresses and private keys
+// for a Bitcoin network. BIP32 extended keys have their own 4 byte versions, giving the xprv/xpub prefixes.
+type NetworkParams struct {
+	Name                string
+	P2PKHVersion        byte
+	P2SHVersion         byte
+	WIFVersion          byte
+	Bech32HRP           string
+	HDPrivateKeyVersion uint32
+	HDPublicKeyVersion  uint32
+}
+
+// MainNetParams are the parameters of the main Bitcoin network.
+var MainNetParams = NetworkParams{
+	Name:                "mainnet",
+	P2PKHVersion:        0x00,
+	P2SHVersion:         0x05,
+	WIFVersion:          0x80,
+	Bech32HRP:           "bc",
+	HDPrivateKeyVersion: 0x0488ade4,
+	HDPublicKeyVersion:  0x0488b21e,
+}
+
+// TestNet3Params are the parameters of the version 3 Bitcoin test network.
+var TestNet3Params = NetworkParams{
+	Name:                "testnet3",
+	P2PKHVersion:        0x6f,
+	P2SHVersion:         0xc4,
+	WIFVersion:          0xef,
+	Bech32HRP:           "tb",
+	HDPrivateKeyVersion: 0x04358394,
+	HDPublicKeyVersion:  0x043587cf,
+}
+
+// SigNetParams are the parameters of the default signet test network, which shares testnet3 address encodings.
+var SigNetParams = NetworkParams{
+	Name:                "signet",
+	P2PKHVersion:        0x6f,
+	P2SHVersion:         0xc4,
+	WIFVersion:          0xef,
+	Bech32HRP:           "tb",
+	HDPrivateKeyVersion: 0x04358394,
+	HDPublicKeyVersion:  0x043587cf,
+}
+
+// RegTestParams are the parameters of the local regression test network.
+var RegTestParams = NetworkParams{
+	Name:                "regtest",
+	P2PKHVersion:        0x6f,
+	P2SHVersion:         0xc4,
+	WIFVersion:          0xef,
+	Bech32HRP:           "bcrt",
+	HDPrivateKeyVersion: 0x04358394,
+	HDPublicKeyVersion:  0x043587cf,
+}
+
+// networks lists all known networks, used to look up networks by name and to name the network a mismatched
+// address belongs to.
+var networks = []*NetworkParams{&MainNetParams, &TestNet3Params, &SigNetParams, &RegTestParams}
+
+// NetworkParamsByName returns the parameters of the known network called name, eg. "mainnet" or "regtest".
+func NetworkParamsByName(name string) (*NetworkParams, error) {
+	for _, network := range networks {
+		if network.Name == name {
+			return network, nil
+		}
+	}
+	return nil, fmt.Errorf("Unknown network %s. Known networks are %s.", name, NetworkNames(func(*NetworkParams) bool { return true }))
+}
+
+// NetworkNames returns the names of known networks for which belongsTo is true, separated by '/', eg. to name the
+// network of an address encoded for a different network than the one selected. Networks may share version bytes.
+func NetworkNames(belongsTo func(*NetworkParams) bool) string {
+	names := []string{}
+	for _, network := range networks {
+		if belongsTo(network) {
+			names = append(names, network.Name)
+		}
+	}
+	return strings.Join(names, "/")
+}
@@ -0,0 +1,42 @@
+package btcutils
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/testutils"
+
+	"testing"
+)
+
+func TestNetworkParamsByName(t *testing.T) {
+	for _, network := range []*NetworkParams{&MainNetParams, &TestNet3Params, &SigNetParams, &RegTestParams} {
+		params, err := NetworkParamsByName(network.Name)
+		if err != nil {
+			t.Error(err)
+		}
+		if params != network {
+			testutils.CompareError(t, "Network parameters different from expected parameters.", network.Name, params.Name)
+		}
+	}
+	if _, err := NetworkParamsByName("testnet"); err == nil {
+		t.Error("NetworkParamsByName accepting unknown network.")
+	}
+}
+
+func TestNetworkNames(t *testing.T) {
+	//Test networks share Base58 version bytes, and signet shares the testnet3 Bech32 human readable part
+	testCases := []struct {
+		belongsTo func(*NetworkParams) bool
+		names     string
+	}{
+		{func(network *NetworkParams) bool { return network.P2PKHVersion == 0x6f }, "testnet3/signet/regtest"},
+		{func(network *NetworkParams) bool { return network.Bech32HRP == "tb" }, "testnet3/signet"},
+		{func(network *NetworkParams) bool { return network.Bech32HRP == "bcrt" }, "regtest"},
+		{func(network *NetworkParams) bool { return network.WIFVersion == 0x80 }, "mainnet"},
+		{func(network *NetworkParams) bool { return false }, ""},
+	}
+	for _, testCase := range testCases {
+		names := NetworkNames(testCase.belongsTo)
+		if names != testCase.names {
+			testutils.CompareError(t, "Network names different from expected names.", testCase.names, names)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+// Pay-to-public-key (P2PK) scripts, the oldest output type, paying to a public key itself rather than its hash, as
+// early coinbase transactions did.
+package btcutils
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/txscript"
+
+	"errors"
+)
+
+// CreateP2PKScriptPubKey creates the scriptPubKey of a P2PK output given the 33 byte compressed or 65 byte
+// uncompressed public key it pays to.
+func CreateP2PKScriptPubKey(pubKey []byte) ([]byte, error) {
+	err := CheckPublicKeyIsValid(pubKey)
+	if err != nil {
+		return nil, err
+	}
+	//P2PK scriptPubKey format:
+	//<pubKey> <OP_CHECKSIG>
+	return txscript.NewBuilder().AddData(pubKey).AddOp(OP_CHECKSIG).Script()
+}
+
+// CreateP2PKScriptSig creates the scriptSig spending a P2PK output given sig, a DER signature followed by its hash
+// type byte. The public key is in the scriptPubKey, so unlike P2PKH the scriptSig only pushes the signature.
+func CreateP2PKScriptSig(sig []byte) ([]byte, error) {
+	if len(sig) == 0 {
+		return nil, errors.New("Signature cannot be empty.")
+	}
+	if _, _, _, err := ParseDERSignature(sig); err != nil {
+		return nil, err
+	}
+	//P2PK scriptSig format:
+	//<sig>
+	return txscript.NewBuilder().AddData(sig).Script()
+}
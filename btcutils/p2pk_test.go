@@ -0,0 +1,60 @@
+package btcutils
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/testutils"
+
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestCreateP2PKScripts(t *testing.T) {
+	//First transaction between two people, in mainnet block 170, spending the P2PK coinbase output of block 9
+	rawTx, _ := hex.DecodeString("0100000001c997a5e56e104102fa209c6a852dd90660a20b2d9c352423edce25857fcd3704000000004847304402204e45e16932b8af514961a1d3a1a25fdf3f4f7732e9d624c6c61548ab5fb8cd410220181522ec8eca07de4860a4acdd12909d831cc56cbbac4622082221a8768d1d0901ffffffff0200ca9a3b00000000434104ae1a62fe09c5f51b13905f07f06b99a2f7159b2225f374cd378d71302fa28414e7aab37397f554a7df5f142c21c1b7303b8a0626f1baded5c72a704f7e6cd84cac00286bee0000000043410411db93e1dcdb8a016b49840f8c53bc1eb68a382e97b1482ecad7b148a6909a5cb2e0eaddfb84ccf9744464f82e160bfa9b8b64f9d4c03f999b8643f656b412a3ac00000000")
+	tx, err := DeserializeTransaction(rawTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey, _ := hex.DecodeString("0411db93e1dcdb8a016b49840f8c53bc1eb68a382e97b1482ecad7b148a6909a5cb2e0eaddfb84ccf9744464f82e160bfa9b8b64f9d4c03f999b8643f656b412a3")
+	signature, _ := hex.DecodeString("304402204e45e16932b8af514961a1d3a1a25fdf3f4f7732e9d624c6c61548ab5fb8cd410220181522ec8eca07de4860a4acdd12909d831cc56cbbac4622082221a8768d1d0901")
+
+	scriptPubKey, err := CreateP2PKScriptPubKey(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//The change output of the transaction pays back to the same key
+	if !bytes.Equal(scriptPubKey, tx.Outputs[1].ScriptPubKey) {
+		testutils.CompareError(t, "P2PK scriptPubKey different from expected scriptPubKey.", hex.EncodeToString(tx.Outputs[1].ScriptPubKey), hex.EncodeToString(scriptPubKey))
+	}
+	scriptSig, err := CreateP2PKScriptSig(signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(scriptSig, tx.Inputs[0].ScriptSig) {
+		testutils.CompareError(t, "P2PK scriptSig different from expected scriptSig.", hex.EncodeToString(tx.Inputs[0].ScriptSig), hex.EncodeToString(scriptSig))
+	}
+	tx.Inputs[0].ScriptSig = scriptSig
+	if err := VerifyInputScript(tx, 0, scriptPubKey, 0); err != nil {
+		t.Error(err)
+	}
+
+	compressedPublicKey, _ := CompressPublicKey(publicKey)
+	scriptPubKey, err = CreateP2PKScriptPubKey(compressedPublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scriptPubKey) != 35 || !bytes.Equal(scriptPubKey[1:34], compressedPublicKey) {
+		t.Errorf("P2PK scriptPubKey %x of compressed public key is not <pubKey> OP_CHECKSIG.", scriptPubKey)
+	}
+
+	for _, invalidPublicKey := range [][]byte{nil, publicKey[:33], publicKey[1:]} {
+		if _, err := CreateP2PKScriptPubKey(invalidPublicKey); err == nil {
+			t.Errorf("CreateP2PKScriptPubKey accepting invalid public key %x.", invalidPublicKey)
+		}
+	}
+	for _, invalidSignature := range [][]byte{nil, signature[:len(signature)-2], append([]byte{0x31}, signature[1:]...)} {
+		if _, err := CreateP2PKScriptSig(invalidSignature); err == nil {
+			t.Errorf("CreateP2PKScriptSig accepting invalid signature %x.", invalidSignature)
+		}
+	}
+}
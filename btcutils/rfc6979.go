@@ -0,0 +1,88 @@
+// Deterministic ECDSA nonce generation as specified in RFC 6979, using HMAC-SHA256.
+// See https://tools.ietf.org/html/rfc6979#section-3.2 for full specification.
+package btcutils
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"math/big"
+)
+
+// secp256k1N is the order of the secp256k1 base point.
+var secp256k1N, _ = new(big.Int).SetString("fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141", 16)
+
+// GenerateRFC6979Nonce deterministically generates the 32 byte secp256k1 ECDSA nonce k for signing
+// messageHash with privateKey, as per RFC 6979 section 3.2 with HMAC-SHA256. The same key and hash
+// always give the same nonce, so nonces can't be reused across different messages.
+func GenerateRFC6979Nonce(privateKey []byte, messageHash []byte) []byte {
+	return rfc6979Nonce(secp256k1N, privateKey, messageHash, nil)
+}
+
+// rfc6979Nonce generates the nonce k for the curve of order q, with HMAC-SHA256 as the HMAC-DRBG hash function.
+// Optional extraData is appended to the HMAC input as per RFC 6979 section 3.6, giving a different nonce for the
+// same key and hash.
+func rfc6979Nonce(q *big.Int, privateKey []byte, messageHash []byte, extraData []byte) []byte {
+	qlen := q.BitLen()
+	rlen := (qlen + 7) / 8
+	//Step a is hashing the message, which callers have already done
+	//Input to HMAC is int2octets(x) || bits2octets(h1) || extraData
+	x := int2octets(new(big.Int).SetBytes(privateKey), rlen)
+	h1 := bits2int(messageHash, qlen)
+	if h1.Cmp(q) >= 0 {
+		h1.Sub(h1, q)
+	}
+	seed := append(x, int2octets(h1, rlen)...)
+	seed = append(seed, extraData...)
+	//Steps b and c
+	v := bytes.Repeat([]byte{0x01}, sha256.Size)
+	k := make([]byte, sha256.Size)
+	//Steps d through g
+	k = hmacSHA256(k, v, []byte{0x00}, seed)
+	v = hmacSHA256(k, v)
+	k = hmacSHA256(k, v, []byte{0x01}, seed)
+	v = hmacSHA256(k, v)
+	//Step h, generating candidates until one is in the range [1, q-1]
+	for {
+		var t []byte
+		for len(t)*8 < qlen {
+			v = hmacSHA256(k, v)
+			t = append(t, v...)
+		}
+		nonce := bits2int(t, qlen)
+		if nonce.Sign() > 0 && nonce.Cmp(q) < 0 {
+			return int2octets(nonce, rlen)
+		}
+		k = hmacSHA256(k, v, []byte{0x00})
+		v = hmacSHA256(k, v)
+	}
+}
+
+// bits2int converts data to an integer, keeping only its leftmost qlen bits as per RFC 6979 section 2.3.2.
+func bits2int(data []byte, qlen int) *big.Int {
+	n := new(big.Int).SetBytes(data)
+	if len(data)*8 > qlen {
+		n.Rsh(n, uint(len(data)*8-qlen))
+	}
+	return n
+}
+
+// int2octets converts n to a big-endian byte slice of length rlen as per RFC 6979 section 2.3.3.
+func int2octets(n *big.Int, rlen int) []byte {
+	nBytes := n.Bytes()
+	if len(nBytes) >= rlen {
+		return nBytes[len(nBytes)-rlen:]
+	}
+	octets := make([]byte, rlen)
+	copy(octets[rlen-len(nBytes):], nBytes)
+	return octets
+}
+
+// hmacSHA256 computes the HMAC-SHA256 of the concatenated data with key.
+func hmacSHA256(key []byte, data ...[]byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	for _, d := range data {
+		mac.Write(d)
+	}
+	return mac.Sum(nil)
+}
@@ -0,0 +1,79 @@
+package btcutils
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/testutils"
+
+	"crypto/sha256"
+	"encoding/hex"
+	"math/big"
+	"testing"
+)
+
+func TestRFC6979NonceP256(t *testing.T) {
+	//RFC 6979 appendix A.2.5, ECDSA with the 256 bit NIST curve P-256 and SHA-256
+	testQ, _ := new(big.Int).SetString("ffffffff00000000ffffffffffffffffbce6faada7179e84f3b9cac2fc632551", 16)
+	testPrivateKey, _ := hex.DecodeString("c9afa9d845ba75166b5c215767b1d6934e50c3db36e89b127b8a622b120f6721")
+	testCases := []struct {
+		message string
+		nonce   string
+	}{
+		{"sample", "a6e3c57dd01abe90086538398355dd4c3b17aa873382b0f24d6129493d8aad60"},
+		{"test", "d16b6ae827f17175e040871a1c7ec3500192c4c92677336ec2537acaee0008e0"},
+	}
+	for _, testCase := range testCases {
+		messageHash := sha256.Sum256([]byte(testCase.message))
+		nonce := hex.EncodeToString(rfc6979Nonce(testQ, testPrivateKey, messageHash[:], nil))
+		if nonce != testCase.nonce {
+			testutils.CompareError(t, "Generated RFC 6979 nonce different from expected nonce.", testCase.nonce, nonce)
+		}
+	}
+}
+
+func TestGenerateRFC6979Nonce(t *testing.T) {
+	testCases := []struct {
+		privateKey string
+		message    string
+		nonce      string
+	}{
+		{"0000000000000000000000000000000000000000000000000000000000000001", "Satoshi Nakamoto", "8f8a276c19f4149656b280621e358cce24f5f52542772691ee69063b74f15d15"},
+		{"0000000000000000000000000000000000000000000000000000000000000001", "All those moments will be lost in time, like tears in rain. Time to die...", "38aa22d72376b4dbc472e06c3ba403ee0a394da63fc58d88686c611aba98d6b3"},
+		{"fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364140", "Satoshi Nakamoto", "33a19b60e25fb6f4435af53a3d42d493644827367e6453928554f43e49aa6f90"},
+		{"f8b8af8ce3c7cca5e300d33939540c10d45ce001b8f252bfbc57ba0342904181", "Alan Turing", "525a82b70e67874398067543fd84c83d30c175fdc45fdeee082fe13b1d7cfdf1"},
+	}
+	for _, testCase := range testCases {
+		privateKey, _ := hex.DecodeString(testCase.privateKey)
+		messageHash := sha256.Sum256([]byte(testCase.message))
+		nonce := hex.EncodeToString(GenerateRFC6979Nonce(privateKey, messageHash[:]))
+		if nonce != testCase.nonce {
+			testutils.CompareError(t, "Generated RFC 6979 nonce different from expected nonce.", testCase.nonce, nonce)
+		}
+	}
+}
+
+func TestSignHashRFC6979(t *testing.T) {
+	//Published signatures use the first RFC 6979 nonce, without grinding for low R
+	fixedNonce := SetFixedNonce
+	SetFixedNonce = false
+	DisableLowR = true
+	defer func() { SetFixedNonce, DisableLowR = fixedNonce, false }()
+	testCases := []struct {
+		privateKey string
+		message    string
+		signature  string
+	}{
+		{"0000000000000000000000000000000000000000000000000000000000000001", "Satoshi Nakamoto", "3045022100934b1ea10a4b3c1757e2b0c017d0b6143ce3c9a7e6a4a49860d7a6ab210ee3d802202442ce9d2b916064108014783e923ec36b49743e2ffa1c4496f01a512aafd9e5"},
+		{"fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364140", "Satoshi Nakamoto", "3045022100fd567d121db66e382991534ada77a6bd3106f0a1098c231e47993447cd6af2d002206b39cd0eb1bc8603e159ef5c20a5c8ad685a45b06ce9bebed3f153d10d93bed5"},
+	}
+	for _, testCase := range testCases {
+		privateKey, _ := hex.DecodeString(testCase.privateKey)
+		messageHash := sha256.Sum256([]byte(testCase.message))
+		signature, err := SignHash(messageHash[:], privateKey)
+		if err != nil {
+			t.Error(err)
+		}
+		signatureHex := hex.EncodeToString(signature)
+		if signatureHex != testCase.signature {
+			testutils.CompareError(t, "Signature with RFC 6979 nonce different from expected signature.", testCase.signature, signatureHex)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+// BIP340 Schnorr signatures over secp256k1, as used by Taproot key path spends. Curve arithmetic is done here with
+// math/big since the secp256k1 library only provides ECDSA.
+// See https://github.com/bitcoin/bips/blob/master/bip-0340.mediawiki for full specification.
+package btcutils
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// secp256k1P is the prime order of the field secp256k1 is defined over.
+var secp256k1P, _ = new(big.Int).SetString("fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2f", 16)
+
+// secp256k1G is the secp256k1 base point.
+var secp256k1G = &ecPoint{
+	x: fromHex("79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"),
+	y: fromHex("483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8"),
+}
+
+// ecPoint is an affine point on the secp256k1 curve. The point at infinity is represented by a nil *ecPoint.
+type ecPoint struct {
+	x *big.Int
+	y *big.Int
+}
+
+// fromHex converts a hex string constant to an integer.
+func fromHex(s string) *big.Int {
+	n, _ := new(big.Int).SetString(s, 16)
+	return n
+}
+
+// ecAdd returns the sum of points a and b.
+func ecAdd(a *ecPoint, b *ecPoint) *ecPoint {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	var numerator, denominator *big.Int
+	if a.x.Cmp(b.x) == 0 {
+		//a + (-a) is the point at infinity
+		sum := new(big.Int).Add(a.y, b.y)
+		if sum.Mod(sum, secp256k1P).Sign() == 0 {
+			return nil
+		}
+		//Point doubling, the slope of the tangent is 3x^2 / 2y
+		numerator = new(big.Int).Mul(big.NewInt(3), new(big.Int).Mul(a.x, a.x))
+		denominator = new(big.Int).Lsh(a.y, 1)
+	} else {
+		numerator = new(big.Int).Sub(b.y, a.y)
+		denominator = new(big.Int).Sub(b.x, a.x)
+	}
+	denominator.Mod(denominator, secp256k1P)
+	slope := numerator.Mul(numerator, denominator.ModInverse(denominator, secp256k1P))
+	slope.Mod(slope, secp256k1P)
+	x := new(big.Int).Mul(slope, slope)
+	x.Sub(x, a.x).Sub(x, b.x).Mod(x, secp256k1P)
+	y := new(big.Int).Sub(a.x, x)
+	y.Mul(y, slope).Sub(y, a.y).Mod(y, secp256k1P)
+	return &ecPoint{x: x, y: y}
+}
+
+// ecMul returns the point p multiplied by the scalar k, by double-and-add.
+func ecMul(k *big.Int, p *ecPoint) *ecPoint {
+	var result *ecPoint
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		result = ecAdd(result, result)
+		if k.Bit(i) == 1 {
+			result = ecAdd(result, p)
+		}
+	}
+	return result
+}
+
+// liftX returns the point with x coordinate x and an even y coordinate, as BIP340 x-only public keys imply.
+func liftX(x *big.Int) (*ecPoint, error) {
+	if x.Cmp(secp256k1P) >= 0 {
+		return nil, errors.New("Public key x coordinate is not in the field.")
+	}
+	//y^2 = x^3 + 7, and since P = 3 mod 4 the square root is c^((P+1)/4)
+	c := new(big.Int).Exp(x, big.NewInt(3), secp256k1P)
+	c.Add(c, big.NewInt(7)).Mod(c, secp256k1P)
+	y := new(big.Int).Exp(c, new(big.Int).Rsh(new(big.Int).Add(secp256k1P, big.NewInt(1)), 2), secp256k1P)
+	if new(big.Int).Exp(y, big.NewInt(2), secp256k1P).Cmp(c) != 0 {
+		return nil, errors.New("Public key x coordinate is not on the secp256k1 curve.")
+	}
+	if y.Bit(0) == 1 {
+		y.Sub(secp256k1P, y)
+	}
+	return &ecPoint{x: x, y: y}, nil
+}
+
+// taggedHash computes the BIP340 tagged hash SHA256(SHA256(tag) || SHA256(tag) || data), so hashes for different
+// purposes can never collide.
+func taggedHash(tag string, data ...[]byte) []byte {
+	tagHash := sha256.Sum256([]byte(tag))
+	hash := sha256.New()
+	hash.Write(tagHash[:])
+	hash.Write(tagHash[:])
+	for _, d := range data {
+		hash.Write(d)
+	}
+	return hash.Sum(nil)
+}
+
+// parsePrivateKeyScalar converts a 32 byte private key to an integer, which must be in the range [1, N-1].
+func parsePrivateKeyScalar(privateKey []byte) (*big.Int, error) {
+	err := checkPrivateKeyLength(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	d := new(big.Int).SetBytes(privateKey)
+	if d.Sign() == 0 || d.Cmp(secp256k1N) >= 0 {
+		return nil, errors.New("Private key is not in the range of valid secp256k1 private keys.")
+	}
+	return d, nil
+}
+
+// SchnorrSign generates a 64 byte BIP340 Schnorr signature of the 32 byte message msg, usually a signature hash,
+// given the privateKey to sign with. Fresh auxiliary randomness is mixed into the nonce as BIP340 recommends,
+// unless SetFixedNonce is set, in which case it is all zero and signatures are repeatable.
+func SchnorrSign(privateKey []byte, msg []byte) ([]byte, error) {
+	auxRand := make([]byte, 32)
+	if !SetFixedNonce {
+		var err error
+		auxRand, err = NewRandomBytes(32)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return schnorrSign(privateKey, msg, auxRand)
+}
+
+// schnorrSign generates a BIP340 Schnorr signature of msg with privateKey and the 32 bytes of auxiliary data auxRand,
+// as per BIP340 section "Default Signing".
+func schnorrSign(privateKey []byte, msg []byte, auxRand []byte) ([]byte, error) {
+	if len(msg) != 32 {
+		return nil, fmt.Errorf("Message to sign should be 32 bytes long. Provided message is %d bytes long.", len(msg))
+	}
+	d, err := parsePrivateKeyScalar(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	//The public key is x-only, so negate the private key if needed for its public key to have an even y
+	publicKeyPoint := ecMul(d, secp256k1G)
+	if publicKeyPoint.y.Bit(0) == 1 {
+		d.Sub(secp256k1N, d)
+	}
+	publicKey := int2octets(publicKeyPoint.x, 32)
+	//Nonce is derived from the private key masked with the auxiliary data, the public key and message
+	t := int2octets(d, 32)
+	auxHash := taggedHash("BIP0340/aux", auxRand)
+	for i := range t {
+		t[i] ^= auxHash[i]
+	}
+	k := new(big.Int).SetBytes(taggedHash("BIP0340/nonce", t, publicKey, msg))
+	k.Mod(k, secp256k1N)
+	if k.Sign() == 0 {
+		return nil, errors.New("Failed to sign message, nonce is zero.")
+	}
+	noncePoint := ecMul(k, secp256k1G)
+	if noncePoint.y.Bit(0) == 1 {
+		k.Sub(secp256k1N, k)
+	}
+	r := int2octets(noncePoint.x, 32)
+	e := new(big.Int).SetBytes(taggedHash("BIP0340/challenge", r, publicKey, msg))
+	e.Mod(e, secp256k1N)
+	//s = k + e*d mod N
+	s := e.Mul(e, d)
+	s.Add(s, k).Mod(s, secp256k1N)
+	signature := append(r, int2octets(s, 32)...)
+	//Verify that it worked.
+	if SchnorrVerify(publicKey, msg, signature) != nil {
+		return nil, errors.New("Failed to verify Schnorr signature.")
+	}
+	return signature, nil
+}
+
+// Errors returned by SchnorrVerify, telling which BIP340 verification check a signature failed.
+var (
+	ErrSchnorrPublicKeyLength = errors.New("Schnorr public key should be 32 bytes long.")
+	ErrSchnorrMessageLength   = errors.New("Schnorr signed message should be 32 bytes long.")
+	ErrSchnorrSignatureLength = errors.New("Schnorr signature should be 64 bytes long.")
+	ErrSchnorrPublicKey       = errors.New("Schnorr public key is not the x coordinate of a point on the secp256k1 curve.")
+	ErrSchnorrR               = errors.New("Schnorr signature R is not less than the field size.")
+	ErrSchnorrS               = errors.New("Schnorr signature s is not less than the curve order.")
+	ErrSchnorrChallenge       = errors.New("Schnorr signature does not match the challenge of the public key and message.")
+)
+
+// SchnorrVerify checks that signature is a 64 byte BIP340 Schnorr signature of the 32 byte message msg by the 32 byte
+// x-only publicKey, as returned by XOnlyPublicKey, as per BIP340 section "Verification": with the challenge
+// e = H_challenge(R.x || P || msg), the point R = s*G - e*P must have an even y and x coordinate R.x.
+// Returns nil if the signature is valid, otherwise one of the ErrSchnorr errors for the check that failed.
+func SchnorrVerify(publicKey []byte, msg []byte, signature []byte) error {
+	if len(publicKey) != 32 {
+		return ErrSchnorrPublicKeyLength
+	}
+	if len(msg) != 32 {
+		return ErrSchnorrMessageLength
+	}
+	if len(signature) != 64 {
+		return ErrSchnorrSignatureLength
+	}
+	publicKeyPoint, err := liftX(new(big.Int).SetBytes(publicKey))
+	if err != nil {
+		return ErrSchnorrPublicKey
+	}
+	r := new(big.Int).SetBytes(signature[:32])
+	if r.Cmp(secp256k1P) >= 0 {
+		return ErrSchnorrR
+	}
+	s := new(big.Int).SetBytes(signature[32:])
+	if s.Cmp(secp256k1N) >= 0 {
+		return ErrSchnorrS
+	}
+	e := new(big.Int).SetBytes(taggedHash("BIP0340/challenge", signature[:32], publicKey, msg))
+	e.Mod(e, secp256k1N)
+	noncePoint := ecAdd(ecMul(s, secp256k1G), ecMul(e.Sub(secp256k1N, e), publicKeyPoint))
+	if noncePoint == nil || noncePoint.y.Bit(0) == 1 || !bytes.Equal(int2octets(noncePoint.x, 32), signature[:32]) {
+		return ErrSchnorrChallenge
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package btcutils
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/testutils"
+
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestSchnorrSign(t *testing.T) {
+	//BIP340 test vectors 0 to 3
+	testCases := []struct {
+		privateKey string
+		publicKey  string
+		auxRand    string
+		msg        string
+		signature  string
+	}{
+		{"0000000000000000000000000000000000000000000000000000000000000003", "F9308A019258C31049344F85F89D5229B531C845836F99B08601F113BCE036F9", "0000000000000000000000000000000000000000000000000000000000000000", "0000000000000000000000000000000000000000000000000000000000000000", "E907831F80848D1069A5371B402410364BDF1C5F8307B0084C55F1CE2DCA821525F66A4A85EA8B71E482A74F382D2CE5EBEEE8FDB2172F477DF4900D310536C0"},
+		{"B7E151628AED2A6ABF7158809CF4F3C762E7160F38B4DA56A784D9045190CFEF", "DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659", "0000000000000000000000000000000000000000000000000000000000000001", "243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89", "6896BD60EEAE296DB48A229FF71DFE071BDE413E6D43F917DC8DCF8C78DE33418906D11AC976ABCCB20B091292BFF4EA897EFCB639EA871CFA95F6DE339E4B0A"},
+		{"C90FDAA22168C234C4C6628B80DC1CD129024E088A67CC74020BBEA63B14E5C9", "DD308AFEC5777E13121FA72B9CC1B7CC0139715309B086C960E18FD969774EB8", "C87AA53824B4D7AE2EB035A2B5BBBCCC080E76CDC6D1692C4B0B62D798E6D906", "7E2D58D8B3BCDF1ABADEC7829054F90DDA9805AAB56C77333024B9D0A508B75C", "5831AAEED7B44BB74E5EAB94BA9D4294C49BCF2A60728D8B4C200F50DD313C1BAB745879A5AD954A72C45A91C3A51D3C7ADEA98D82F8481E0E1E03674A6F3FB7"},
+		{"0B432B2677937381AEF05BB02A66ECD012773062CF3FA2549E44F58ED2401710", "25D1DFF95105F5253C4022F628A996AD3A0D95FBF21D468A1B33F8C160D8F517", "FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF", "FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF", "7EB0509757E246F19449885651611CB965ECC1A187DD51B64FDA1EDC9637D5EC97582B9CB13DB3933705B32BA982AF5AF25FD78881EBB32771FC5922EFC66EA3"},
+	}
+	for _, testCase := range testCases {
+		privateKey, _ := hex.DecodeString(testCase.privateKey)
+		publicKey, _ := hex.DecodeString(testCase.publicKey)
+		auxRand, _ := hex.DecodeString(testCase.auxRand)
+		msg, _ := hex.DecodeString(testCase.msg)
+		signature, err := schnorrSign(privateKey, msg, auxRand)
+		if err != nil {
+			t.Error(err)
+		}
+		signatureHex := strings.ToUpper(hex.EncodeToString(signature))
+		if signatureHex != testCase.signature {
+			testutils.CompareError(t, "Schnorr signature different from expected signature.", testCase.signature, signatureHex)
+		}
+		if err := SchnorrVerify(publicKey, msg, signature); err != nil {
+			t.Errorf("Schnorr signature %s failed to verify. %s", testCase.signature, err)
+		}
+		//Flipping any bit of the message invalidates the signature
+		msg[0] ^= 0x01
+		if SchnorrVerify(publicKey, msg, signature) == nil {
+			t.Errorf("Schnorr signature %s verifying for a different message.", testCase.signature)
+		}
+	}
+
+	//Zero auxiliary randomness with SetFixedNonce, giving BIP340 test vector 0
+	previousSetFixedNonce := SetFixedNonce
+	SetFixedNonce = true
+	privateKey, _ := hex.DecodeString(testCases[0].privateKey)
+	signature, err := SchnorrSign(privateKey, make([]byte, 32))
+	SetFixedNonce = previousSetFixedNonce
+	if err != nil {
+		t.Error(err)
+	}
+	if signatureHex := strings.ToUpper(hex.EncodeToString(signature)); signatureHex != testCases[0].signature {
+		testutils.CompareError(t, "Schnorr signature different from expected signature.", testCases[0].signature, signatureHex)
+	}
+
+	if _, err := SchnorrSign(privateKey, make([]byte, 31)); err == nil {
+		t.Error("SchnorrSign accepting 31 byte message.")
+	}
+	if _, err := SchnorrSign(make([]byte, 32), make([]byte, 32)); err == nil {
+		t.Error("SchnorrSign accepting zero private key.")
+	}
+	curveOrder, _ := hex.DecodeString("fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141")
+	if _, err := SchnorrSign(curveOrder, make([]byte, 32)); err == nil {
+		t.Error("SchnorrSign accepting private key equal to the curve order.")
+	}
+}
+
+func TestSchnorrVerify(t *testing.T) {
+	//BIP340 test vectors 0 to 14, with the error of each invalid signature. Vectors 15 to 18 sign messages that are
+	//not 32 bytes long, which SchnorrVerify does not accept
+	testCases := []struct {
+		index     int
+		publicKey string
+		msg       string
+		signature string
+		err       error
+	}{
+		{0, "F9308A019258C31049344F85F89D5229B531C845836F99B08601F113BCE036F9", "0000000000000000000000000000000000000000000000000000000000000000", "E907831F80848D1069A5371B402410364BDF1C5F8307B0084C55F1CE2DCA821525F66A4A85EA8B71E482A74F382D2CE5EBEEE8FDB2172F477DF4900D310536C0", nil},
+		{1, "DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659", "243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89", "6896BD60EEAE296DB48A229FF71DFE071BDE413E6D43F917DC8DCF8C78DE33418906D11AC976ABCCB20B091292BFF4EA897EFCB639EA871CFA95F6DE339E4B0A", nil},
+		{2, "DD308AFEC5777E13121FA72B9CC1B7CC0139715309B086C960E18FD969774EB8", "7E2D58D8B3BCDF1ABADEC7829054F90DDA9805AAB56C77333024B9D0A508B75C", "5831AAEED7B44BB74E5EAB94BA9D4294C49BCF2A60728D8B4C200F50DD313C1BAB745879A5AD954A72C45A91C3A51D3C7ADEA98D82F8481E0E1E03674A6F3FB7", nil},
+		{3, "25D1DFF95105F5253C4022F628A996AD3A0D95FBF21D468A1B33F8C160D8F517", "FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF", "7EB0509757E246F19449885651611CB965ECC1A187DD51B64FDA1EDC9637D5EC97582B9CB13DB3933705B32BA982AF5AF25FD78881EBB32771FC5922EFC66EA3", nil},
+		//A valid signature with no known private key
+		{4, "D69C3509BB99E412E68B0FE8544E72837DFA30746D8BE2AA65975F29D22DC7B9", "4DF3C3F68FCC83B27E9D42C90431A72499F17875C81A599B566C9889B9696703", "00000000000000000000003B78CE563F89A0ED9414F5AA28AD0D96D6795F9C6376AFB1548AF603B3EB45C9F8207DEE1060CB71C04E80F593060B07D28308D7F4", nil},
+		//Public key not on the curve
+		{5, "EEFDEA4CDB677750A420FEE807EACF21EB9898AE79B9768766E4FAA04A2D4A34", "243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89", "6CFF5C3BA86C69EA4B7376F31A9BCB4F74C1976089B2D9963DA2E5543E17776969E89B4C5564D00349106B8497785DD7D1D713A8AE82B32FA79D5F7FC407D39B", ErrSchnorrPublicKey},
+		//R has an odd y
+		{6, "DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659", "243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89", "FFF97BD5755EEEA420453A14355235D382F6472F8568A18B2F057A14602975563CC27944640AC607CD107AE10923D9EF7A73C643E166BE5EBEAFA34B1AC553E2", ErrSchnorrChallenge},
+		//Negated message
+		{7, "DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659", "243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89", "1FA62E331EDBC21C394792D2AB1100A7B432B013DF3F6FF4F99FCB33E0E1515F28890B3EDB6E7189B630448B515CE4F8622A954CFE545735AAEA5134FCCDB2BD", ErrSchnorrChallenge},
+		//Negated s
+		{8, "DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659", "243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89", "6CFF5C3BA86C69EA4B7376F31A9BCB4F74C1976089B2D9963DA2E5543E177769961764B3AA9B2FFCB6EF947B6887A226E8D7C93E00C5ED0C1834FF0D0C2E6DA6", ErrSchnorrChallenge},
+		//s*G - e*P is the point at infinity, with R.x 0 and 1
+		{9, "DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659", "243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89", "0000000000000000000000000000000000000000000000000000000000000000123DDA8328AF9C23A94C1FEECFD123BA4FB73476F0D594DCB65C6425BD186051", ErrSchnorrChallenge},
+		{10, "DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659", "243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89", "00000000000000000000000000000000000000000000000000000000000000017615FBAF5AE28864013C099742DEADB4DBA87F11AC6754F93780D5A1837CF197", ErrSchnorrChallenge},
+		//R.x not the x coordinate of a point on the curve
+		{11, "DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659", "243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89", "4A298DACAE57395A15D0795DDBFD1DCB564DA82B0F269BC70A74F8220429BA1D69E89B4C5564D00349106B8497785DD7D1D713A8AE82B32FA79D5F7FC407D39B", ErrSchnorrChallenge},
+		//R.x equal to the field size
+		{12, "DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659", "243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89", "FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEFFFFFC2F69E89B4C5564D00349106B8497785DD7D1D713A8AE82B32FA79D5F7FC407D39B", ErrSchnorrR},
+		//s equal to the curve order
+		{13, "DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659", "243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89", "6CFF5C3BA86C69EA4B7376F31A9BCB4F74C1976089B2D9963DA2E5543E177769FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEBAAEDCE6AF48A03BBFD25E8CD0364141", ErrSchnorrS},
+		//Public key larger than the field size
+		{14, "FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEFFFFFC30", "243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89", "6CFF5C3BA86C69EA4B7376F31A9BCB4F74C1976089B2D9963DA2E5543E17776969E89B4C5564D00349106B8497785DD7D1D713A8AE82B32FA79D5F7FC407D39B", ErrSchnorrPublicKey},
+	}
+	for _, testCase := range testCases {
+		publicKey, _ := hex.DecodeString(testCase.publicKey)
+		msg, _ := hex.DecodeString(testCase.msg)
+		signature, _ := hex.DecodeString(testCase.signature)
+		if err := SchnorrVerify(publicKey, msg, signature); err != testCase.err {
+			testutils.CompareError(t, fmt.Sprintf("Verification of BIP340 test vector %d different from expected result.", testCase.index), testCase.err, err)
+		}
+	}
+
+	publicKey, _ := hex.DecodeString(testCases[4].publicKey)
+	msg, _ := hex.DecodeString(testCases[4].msg)
+	signature, _ := hex.DecodeString(testCases[4].signature)
+	invalidCases := []struct {
+		name      string
+		publicKey []byte
+		msg       []byte
+		signature []byte
+		err       error
+	}{
+		{"compressed public key", append([]byte{0x02}, publicKey...), msg, signature, ErrSchnorrPublicKeyLength},
+		{"31 byte message", publicKey, msg[1:], signature, ErrSchnorrMessageLength},
+		{"63 byte signature", publicKey, msg, signature[1:], ErrSchnorrSignatureLength},
+		{"negated s", publicKey, msg, append(append([]byte{}, signature[:32]...), int2octets(new(big.Int).Sub(secp256k1N, new(big.Int).SetBytes(signature[32:])), 32)...), ErrSchnorrChallenge},
+	}
+	for _, invalidCase := range invalidCases {
+		if err := SchnorrVerify(invalidCase.publicKey, invalidCase.msg, invalidCase.signature); err != invalidCase.err {
+			testutils.CompareError(t, "Verification of signature with "+invalidCase.name+" different from expected result.", invalidCase.err, err)
+		}
+	}
+}
@@ -1,36 +1,88 @@
-// Provides Bitcoin Script enum to improve code readability.
+// Provides Bitcoin Script enum to improve code readability. The opcodes are those of the txscript package, which
+// builds scripts from them.
 // See https://en.bitcoin.it/wiki/Script for full specification.
 package btcutils
 
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/txscript"
+)
+
 // OP_1 through OP_16
 const (
-	OP_1 = 81 + iota
-	OP_2 //82
-	OP_3 //83
-	OP_4 //..
-	OP_5
-	OP_6
-	OP_7
-	OP_8
-	OP_9
-	OP_10
-	OP_11
-	OP_12
-	OP_13
-	OP_14 //..
-	OP_15 //95
-	OP_16 //96
+	OP_1  = txscript.OP_1 //81
+	OP_2  = txscript.OP_2 //82
+	OP_3  = txscript.OP_3 //83
+	OP_4  = txscript.OP_4 //..
+	OP_5  = txscript.OP_5
+	OP_6  = txscript.OP_6
+	OP_7  = txscript.OP_7
+	OP_8  = txscript.OP_8
+	OP_9  = txscript.OP_9
+	OP_10 = txscript.OP_10
+	OP_11 = txscript.OP_11
+	OP_12 = txscript.OP_12
+	OP_13 = txscript.OP_13
+	OP_14 = txscript.OP_14 //..
+	OP_15 = txscript.OP_15 //95
+	OP_16 = txscript.OP_16 //96
 )
 
 // OP codes other than OP_1 through OP_16, used in P2SH Multisig transanctions.
 const (
-	OP_0             = 0
-	OP_PUSHDATA1     = 76
-	OP_PUSHDATA2     = 77
-	OP_DUP           = 118
-	OP_EQUAL         = 135
-	OP_EQUALVERIFY   = 136
-	OP_HASH160       = 169
-	OP_CHECKSIG      = 172
-	OP_CHECKMULTISIG = 174
+	OP_0             = txscript.OP_0
+	OP_PUSHDATA1     = txscript.OP_PUSHDATA1
+	OP_PUSHDATA2     = txscript.OP_PUSHDATA2
+	OP_PUSHDATA4     = txscript.OP_PUSHDATA4
+	OP_1NEGATE       = txscript.OP_1NEGATE
+	OP_RETURN        = txscript.OP_RETURN
+	OP_DROP          = txscript.OP_DROP
+	OP_DUP           = txscript.OP_DUP
+	OP_EQUAL         = txscript.OP_EQUAL
+	OP_EQUALVERIFY   = txscript.OP_EQUALVERIFY
+	OP_HASH160       = txscript.OP_HASH160
+	OP_NUMEQUAL      = txscript.OP_NUMEQUAL
+	OP_CHECKSIG      = txscript.OP_CHECKSIG
+	OP_CHECKMULTISIG = txscript.OP_CHECKMULTISIG
+	//OP_CHECKLOCKTIMEVERIFY and OP_CHECKSEQUENCEVERIFY redefine OP_NOP2 and OP_NOP3 as per BIP65 and BIP112
+	OP_CHECKLOCKTIMEVERIFY = txscript.OP_CHECKLOCKTIMEVERIFY
+	OP_CHECKSEQUENCEVERIFY = txscript.OP_CHECKSEQUENCEVERIFY
+	//OP_CHECKSIGADD replaces OP_CHECKMULTISIG in tapscript as per BIP342
+	OP_CHECKSIGADD = txscript.OP_CHECKSIGADD
 )
+
+// ScriptType is the output type of a scriptPubKey, as returned by DetectScriptType.
+type ScriptType string
+
+// Script types detected by DetectScriptType.
+const (
+	P2PK        ScriptType = "P2PK"
+	P2PKH       ScriptType = "P2PKH"
+	P2SH        ScriptType = "P2SH"
+	P2WPKH      ScriptType = "P2WPKH"
+	P2WSH       ScriptType = "P2WSH"
+	P2TR        ScriptType = "P2TR"
+	OpReturn    ScriptType = "OP_RETURN"
+	Nonstandard ScriptType = "nonstandard"
+)
+
+// DetectScriptType returns the output type of scriptPubKey, by its template as recognized by txscript.Classify.
+// Bare multisig, which has no address, and scripts of no standard template are Nonstandard.
+func DetectScriptType(scriptPubKey []byte) ScriptType {
+	switch txscript.Classify(scriptPubKey) {
+	case txscript.PubKey:
+		return P2PK
+	case txscript.PubKeyHash:
+		return P2PKH
+	case txscript.ScriptHash:
+		return P2SH
+	case txscript.WitnessPubKeyHash:
+		return P2WPKH
+	case txscript.WitnessScriptHash:
+		return P2WSH
+	case txscript.WitnessTaproot:
+		return P2TR
+	case txscript.NullData:
+		return OpReturn
+	}
+	return Nonstandard
+}
@@ -0,0 +1,38 @@
+package btcutils
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestDetectScriptType(t *testing.T) {
+	testCases := []struct {
+		scriptPubKeyHex string
+		scriptType      ScriptType
+	}{
+		//Coinbase output of the mainnet genesis block
+		{"4104678afdb0fe5548271967f1a67130b7105cd6a828e03909a67962e0ea1f61deb649f6bc3f4cef38c4f35504e51ec112de5c384df7ba0b8d578a4c702b6bf11d5fac", P2PK},
+		//Output 0 of the signed BIP143 native P2WPKH example
+		{"76a9148280b37df378db99f66f85c95a783a76ac7a6d5988ac", P2PKH},
+		//Output spent by the BIP143 P2SH-P2WPKH example
+		{"a9144733f37cf4db86fbc2efed2500b4f4e49f31202387", P2SH},
+		//Output spent by input 1 of the BIP143 native P2WPKH example
+		{"00141d0f172a0ecb48aee1be1f2687d2963ae33f71a1", P2WPKH},
+		//BIP173 mainnet P2WSH example
+		{"00201863143c14c5166804bd19203356da136c985678cd4d27a1b8c6329604903262", P2WSH},
+		//BIP86 first receiving address of the "abandon ... about" test mnemonic
+		{"5120a60869f0dbcf1dc659c9cecbaf8050135ea9e8cdc487053f1dc6880949dc684c", P2TR},
+		//BIP141 witness commitment in the coinbase of a SegWit block
+		{"6a24aa21a9ede2f61c3f71d1defd3fa999dfa36953755c690689799962b48bebd836974e8cf9", OpReturn},
+		//Bare 1-of-1 multisig and an anyone-can-spend OP_TRUE output
+		{"512103c9f4836b9a4f77fc0d81f7bcb01b7f1b35916864b9476c241ce9fc198bd2543251ae", Nonstandard},
+		{"51", Nonstandard},
+		{"", Nonstandard},
+	}
+	for _, testCase := range testCases {
+		scriptPubKey, _ := hex.DecodeString(testCase.scriptPubKeyHex)
+		if scriptType := DetectScriptType(scriptPubKey); scriptType != testCase.scriptType {
+			t.Errorf("Script type of %s detected as %s, expected %s.", testCase.scriptPubKeyHex, scriptType, testCase.scriptType)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+// Native segregated witness (BIP141) output scripts and the BIP143 signature hash used to sign
+// segwit version 0 inputs.
+package btcutils
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// NewP2WPKHScriptPubKey creates a scriptPubKey for a native P2WPKH output given the 20 byte hash of a
+// compressed public key
+func NewP2WPKHScriptPubKey(publicKeyHash []byte) ([]byte, error) {
+	if publicKeyHash == nil {
+		return nil, errors.New("publicKeyHash can't be empty.")
+	}
+	if len(publicKeyHash) != 20 {
+		return nil, fmt.Errorf("publicKeyHash should be 20 bytes long. Provided hash is %d bytes long.", len(publicKeyHash))
+	}
+	//P2WPKH scriptPubKey format:
+	//<OP_0> <pubKeyHash>
+	var scriptPubKey bytes.Buffer
+	scriptPubKey.WriteByte(byte(OP_0))               //witness version 0
+	scriptPubKey.WriteByte(byte(len(publicKeyHash))) //PUSH
+	scriptPubKey.Write(publicKeyHash)
+	return scriptPubKey.Bytes(), nil
+}
+
+// NewP2SHP2WPKHRedeemScript creates the redeem script of a nested SegWit P2SH-P2WPKH output given the 33 byte
+// compressed publicKey, which is the P2WPKH scriptPubKey OP_0 <HASH160(publicKey)>. Spending it, the scriptSig only
+// pushes this script, and the signature and public key go in the witness as for native P2WPKH.
+func NewP2SHP2WPKHRedeemScript(publicKey []byte) ([]byte, error) {
+	if len(publicKey) != 33 {
+		return nil, fmt.Errorf("P2SH-P2WPKH public keys should be 33 bytes long and compressed. Provided key is %d bytes long.", len(publicKey))
+	}
+	err := CheckPublicKeyIsValid(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	publicKeyHash, err := Hash160(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	return NewP2WPKHScriptPubKey(publicKeyHash)
+}
+
+// CreateP2SHP2WPKHScriptPubKey creates the scriptPubKey of a nested SegWit P2SH-P2WPKH output given the 33 byte
+// compressed publicKey, the P2SH scriptPubKey of the redeem script given by NewP2SHP2WPKHRedeemScript. Senders see a
+// P2SH output, but it is spent with a BIP143 signature in the witness for the SegWit fee discount.
+func CreateP2SHP2WPKHScriptPubKey(publicKey []byte) ([]byte, error) {
+	redeemScript, err := NewP2SHP2WPKHRedeemScript(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	redeemScriptHash, err := Hash160(redeemScript)
+	if err != nil {
+		return nil, err
+	}
+	return NewP2SHScriptPubKey(redeemScriptHash)
+}
+
+// NewP2WSHScriptPubKey creates a scriptPubKey for a native P2WSH output given the witness script.
+// Unlike P2SH, the script is hashed with a single SHA256 rather than HASH160.
+func NewP2WSHScriptPubKey(witnessScript []byte) ([]byte, error) {
+	if len(witnessScript) == 0 {
+		return nil, errors.New("witnessScript can't be empty.")
+	}
+	witnessScriptHash := sha256.Sum256(witnessScript)
+	//P2WSH scriptPubKey format:
+	//<OP_0> <SHA256(witnessScript)>
+	return NewWitnessScriptPubKey(0, witnessScriptHash[:])
+}
+
+// CreateP2SHP2WSHScriptPubKey creates the scriptPubKey of a nested SegWit P2SH-P2WSH output given its witnessScript,
+// such as an M-of-N multisig script. The redeem script is the P2WSH scriptPubKey OP_0 <SHA256(witnessScript)> given
+// by NewP2WSHScriptPubKey, and the scriptPubKey is the P2SH scriptPubKey of its HASH160. Spending it, the scriptSig
+// only pushes the redeem script, and the witness holds the empty item consumed by OP_CHECKMULTISIG, the signatures
+// and the witness script, as for native P2WSH.
+// Returns an error if the script is empty or longer than the 3600 byte P2WSH witness script limit.
+func CreateP2SHP2WSHScriptPubKey(witnessScript []byte) ([]byte, error) {
+	if len(witnessScript) == 0 || len(witnessScript) > MaxWitnessScriptSize {
+		return nil, fmt.Errorf("Witness script must be between 1 and %d bytes long. Provided script is %d bytes long.", MaxWitnessScriptSize, len(witnessScript))
+	}
+	redeemScript, err := NewP2WSHScriptPubKey(witnessScript)
+	if err != nil {
+		return nil, err
+	}
+	redeemScriptHash, err := Hash160(redeemScript)
+	if err != nil {
+		return nil, err
+	}
+	return NewP2SHScriptPubKey(redeemScriptHash)
+}
+
+// NewWitnessScriptPubKey creates a scriptPubKey for a native SegWit output given the witness version and program
+func NewWitnessScriptPubKey(version byte, program []byte) ([]byte, error) {
+	err := checkWitnessProgram(version, program)
+	if err != nil {
+		return nil, err
+	}
+	//Witness scriptPubKey format:
+	//<OP_0 or OP_1 through OP_16> <program>
+	var scriptPubKey bytes.Buffer
+	if version == 0 {
+		scriptPubKey.WriteByte(byte(OP_0))
+	} else {
+		scriptPubKey.WriteByte(byte(OP_1 + int(version) - 1))
+	}
+	scriptPubKey.WriteByte(byte(len(program))) //PUSH
+	scriptPubKey.Write(program)
+	return scriptPubKey.Bytes(), nil
+}
+
+// IsWitnessScriptPubKey reports whether scriptPubKey is a native SegWit output script of any witness version,
+// a version opcode followed by a single push of a 2 to 40 byte witness program.
+func IsWitnessScriptPubKey(scriptPubKey []byte) bool {
+	if len(scriptPubKey) < 4 || len(scriptPubKey) > 42 {
+		return false
+	}
+	if scriptPubKey[0] != OP_0 && (scriptPubKey[0] < OP_1 || scriptPubKey[0] > OP_1+15) {
+		return false
+	}
+	return int(scriptPubKey[1])+2 == len(scriptPubKey)
+}
+
+// WitnessSigHashes holds the BIP143 hashes of the outpoints, sequence numbers and outputs of a transaction, which
+// are the same for the signature hashes of all its inputs. Computing them once with NewWitnessSigHashes keeps signing
+// a transaction with many SegWit inputs linear in its size. The transaction must not change after they are computed.
+type WitnessSigHashes struct {
+	HashPrevouts []byte
+	HashSequence []byte
+	HashOutputs  []byte
+}
+
+// NewWitnessSigHashes calculates the double SHA256 of the serialized outpoints, sequence numbers and outputs of tx.
+func NewWitnessSigHashes(tx *Transaction) (*WitnessSigHashes, error) {
+	var prevouts bytes.Buffer
+	var sequences bytes.Buffer
+	for _, input := range tx.Inputs {
+		err := writeOutPoint(&prevouts, input)
+		if err != nil {
+			return nil, err
+		}
+		binary.Write(&sequences, binary.LittleEndian, input.Sequence)
+	}
+	var outputs bytes.Buffer
+	for _, output := range tx.Outputs {
+		writeOutput(&outputs, output)
+	}
+	return &WitnessSigHashes{
+		HashPrevouts: DoubleSHA256(prevouts.Bytes()),
+		HashSequence: DoubleSHA256(sequences.Bytes()),
+		HashOutputs:  DoubleSHA256(outputs.Bytes()),
+	}, nil
+}
+
+// CalcWitnessSigHash calculates the BIP143 signature hash for input inputIndex of tx, spending an output
+// holding amount satoshis, with the given hashType. For P2WPKH inputs the scriptCode is the P2PKH scriptPubKey
+// of the public key hash, for P2WSH inputs it is the witness script.
+// When signing several inputs of the same transaction use NewWitnessSigHashes and CalcSigHash instead.
+func CalcWitnessSigHash(tx *Transaction, inputIndex int, scriptCode []byte, amount int64, hashType SigHashType) ([]byte, error) {
+	if inputIndex < 0 || inputIndex >= len(tx.Inputs) {
+		return nil, fmt.Errorf("Input index %d out of range for transaction with %d inputs.", inputIndex, len(tx.Inputs))
+	}
+	sigHashes, err := NewWitnessSigHashes(tx)
+	if err != nil {
+		return nil, err
+	}
+	return sigHashes.CalcSigHash(tx, inputIndex, scriptCode, amount, hashType)
+}
+
+// CalcSigHash calculates the BIP143 signature hash like CalcWitnessSigHash, reusing the hashes of tx computed
+// by NewWitnessSigHashes.
+func (sigHashes *WitnessSigHashes) CalcSigHash(tx *Transaction, inputIndex int, scriptCode []byte, amount int64, hashType SigHashType) ([]byte, error) {
+	if inputIndex < 0 || inputIndex >= len(tx.Inputs) {
+		return nil, fmt.Errorf("Input index %d out of range for transaction with %d inputs.", inputIndex, len(tx.Inputs))
+	}
+	baseType := hashType.baseType()
+	//hashPrevouts is zero with SIGHASH_ANYONECANPAY
+	hashPrevouts := make([]byte, 32)
+	if !hashType.anyOneCanPay() {
+		hashPrevouts = sigHashes.HashPrevouts
+	}
+	//hashSequence is zero unless signing with SIGHASH_ALL
+	hashSequence := make([]byte, 32)
+	if !hashType.anyOneCanPay() && baseType != SigHashSingle && baseType != SigHashNone {
+		hashSequence = sigHashes.HashSequence
+	}
+	//hashOutputs covers only the output with the same index with SIGHASH_SINGLE, and is zero with
+	//SIGHASH_NONE or SIGHASH_SINGLE without a matching output
+	hashOutputs := make([]byte, 32)
+	if baseType != SigHashSingle && baseType != SigHashNone {
+		hashOutputs = sigHashes.HashOutputs
+	} else if baseType == SigHashSingle && inputIndex < len(tx.Outputs) {
+		var output bytes.Buffer
+		writeOutput(&output, tx.Outputs[inputIndex])
+		hashOutputs = DoubleSHA256(output.Bytes())
+	}
+
+	input := tx.Inputs[inputIndex]
+	var preimage bytes.Buffer
+	binary.Write(&preimage, binary.LittleEndian, tx.Version)
+	preimage.Write(hashPrevouts)
+	preimage.Write(hashSequence)
+	err := writeOutPoint(&preimage, input)
+	if err != nil {
+		return nil, err
+	}
+	WriteVarInt(&preimage, uint64(len(scriptCode)))
+	preimage.Write(scriptCode)
+	binary.Write(&preimage, binary.LittleEndian, amount)
+	binary.Write(&preimage, binary.LittleEndian, input.Sequence)
+	preimage.Write(hashOutputs)
+	binary.Write(&preimage, binary.LittleEndian, tx.LockTime)
+	binary.Write(&preimage, binary.LittleEndian, uint32(hashType))
+
+	return DoubleSHA256(preimage.Bytes()), nil
+}
@@ -0,0 +1,279 @@
+package btcutils
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/testutils"
+
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"testing"
+)
+
+// bip143NativeP2WPKHTx is the unsigned transaction of the native P2WPKH example in BIP143.
+// Input 0 spends a P2PK output, input 1 spends a P2WPKH output of 6 BTC.
+func bip143NativeP2WPKHTx() *Transaction {
+	outputScript0, _ := hex.DecodeString("76a9148280b37df378db99f66f85c95a783a76ac7a6d5988ac")
+	outputScript1, _ := hex.DecodeString("76a9143bde42dbee7e4dbe6a21b2d50ce2f0167faa815988ac")
+	return &Transaction{
+		Version: 1,
+		Inputs: []Input{
+			{TxHash: "9f96ade4b41d5433f4eda31e1738ec2b36f6e7d1420d94a6af99801a88f7f7ff", OutputIndex: 0, Sequence: 0xffffffee},
+			{TxHash: "8ac60eb9575db5b2d987e29f301b5b819ea83a5c6579d282d189cc04b8e151ef", OutputIndex: 1, Sequence: SequenceFinal},
+		},
+		Outputs: []Output{
+			{Satoshis: 112340000, ScriptPubKey: outputScript0},
+			{Satoshis: 223450000, ScriptPubKey: outputScript1},
+		},
+		LockTime: 17,
+	}
+}
+
+func TestNewP2WPKHScriptPubKey(t *testing.T) {
+	testPublicKeyHash, _ := hex.DecodeString("1d0f172a0ecb48aee1be1f2687d2963ae33f71a1")
+	testScriptPubKeyHex := "00141d0f172a0ecb48aee1be1f2687d2963ae33f71a1"
+
+	scriptPubKey, err := NewP2WPKHScriptPubKey(testPublicKeyHash)
+	if err != nil {
+		t.Error(err)
+	}
+	scriptPubKeyHex := hex.EncodeToString(scriptPubKey)
+	if scriptPubKeyHex != testScriptPubKeyHex {
+		testutils.CompareError(t, "P2WPKH scriptPubKey different from expected script.", testScriptPubKeyHex, scriptPubKeyHex)
+	}
+
+	if _, err := NewP2WPKHScriptPubKey(testPublicKeyHash[:19]); err == nil {
+		t.Error("NewP2WPKHScriptPubKey accepting 19 byte public key hash.")
+	}
+}
+
+func TestCreateP2SHP2WPKHScriptPubKey(t *testing.T) {
+	//BIP143 P2SH-P2WPKH example
+	testPublicKey, _ := hex.DecodeString("03ad1d8e89212f0b92c74d23bb710c00662ad1470198ac48c43f7d6f93a2a26873")
+	testRedeemScriptHex := "001479091972186c449eb1ded22b78e40d009bdf0089"
+	testScriptPubKeyHex := "a9144733f37cf4db86fbc2efed2500b4f4e49f31202387"
+
+	redeemScript, err := NewP2SHP2WPKHRedeemScript(testPublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(redeemScript) != testRedeemScriptHex {
+		testutils.CompareError(t, "P2SH-P2WPKH redeem script different from expected script.", testRedeemScriptHex, hex.EncodeToString(redeemScript))
+	}
+	scriptPubKey, err := CreateP2SHP2WPKHScriptPubKey(testPublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(scriptPubKey) != testScriptPubKeyHex {
+		testutils.CompareError(t, "P2SH-P2WPKH scriptPubKey different from expected script.", testScriptPubKeyHex, hex.EncodeToString(scriptPubKey))
+	}
+
+	if _, err := CreateP2SHP2WPKHScriptPubKey(append([]byte{0x04}, make([]byte, 64)...)); err == nil {
+		t.Error("CreateP2SHP2WPKHScriptPubKey accepting uncompressed public key.")
+	}
+	if _, err := CreateP2SHP2WPKHScriptPubKey(append([]byte{0x05}, testPublicKey[1:]...)); err == nil {
+		t.Error("CreateP2SHP2WPKHScriptPubKey accepting public key with invalid prefix.")
+	}
+}
+
+func TestNewP2WSHScriptPubKey(t *testing.T) {
+	//BIP173 example P2WSH output for <pubkey> OP_CHECKSIG
+	testWitnessScript, _ := hex.DecodeString("210279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798ac")
+	testScriptPubKeyHex := "00201863143c14c5166804bd19203356da136c985678cd4d27a1b8c6329604903262"
+
+	scriptPubKey, err := NewP2WSHScriptPubKey(testWitnessScript)
+	if err != nil {
+		t.Error(err)
+	}
+	scriptPubKeyHex := hex.EncodeToString(scriptPubKey)
+	if scriptPubKeyHex != testScriptPubKeyHex {
+		testutils.CompareError(t, "P2WSH scriptPubKey different from expected script.", testScriptPubKeyHex, scriptPubKeyHex)
+	}
+
+	if _, err := NewP2WSHScriptPubKey(nil); err == nil {
+		t.Error("NewP2WSHScriptPubKey accepting empty witness script.")
+	}
+}
+
+func TestCreateP2SHP2WSHScriptPubKey(t *testing.T) {
+	//BIP143 P2SH-P2WSH 6-of-6 multisig example, spent with scriptSig 220020a16b5755f7f6f96dbd65f5f0d6ab9418b89af4b1f14a1bb8a09062c35f0dcb54
+	testWitnessScript, _ := hex.DecodeString("56210307b8ae49ac90a048e9b53357a2354b3334e9c8bee813ecb98e99a7e07e8c3ba32103b28f0c28bfab54554ae8c658ac5c3e0ce6e79ad336331f78c428dd43eea8449b21034b8113d703413d57761b8b9781957b8c0ac1dfe69f492580ca4195f50376ba4a21033400f6afecb833092a9a21cfdf1ed1376e58c5d1f47de74683123987e967a8f42103a6d48b1131e94ba04d9737d61acdaa1322008af9602b3b14862c07a1789aac162102d8b661b0b3302ee2f162b09e07a55ad5dfbe673a9f01d9f0c19617681024306b56ae")
+	testScriptPubKeyHex := "a9149993a429037b5d912407a71c252019287b8d27a587"
+
+	scriptPubKey, err := CreateP2SHP2WSHScriptPubKey(testWitnessScript)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(scriptPubKey) != testScriptPubKeyHex {
+		testutils.CompareError(t, "P2SH-P2WSH scriptPubKey different from expected script.", testScriptPubKeyHex, hex.EncodeToString(scriptPubKey))
+	}
+	//The same output as paying to the P2SH-P2WSH address of the witness script
+	address, err := RedeemScriptToP2SHP2WSHAddress(testWitnessScript, &MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addressScriptPubKey, err := NewScriptPubKeyFromAddress(address, &MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(scriptPubKey, addressScriptPubKey) {
+		testutils.CompareError(t, "P2SH-P2WSH scriptPubKey different from scriptPubKey of its address.", hex.EncodeToString(addressScriptPubKey), hex.EncodeToString(scriptPubKey))
+	}
+
+	if _, err := CreateP2SHP2WSHScriptPubKey(nil); err == nil {
+		t.Error("CreateP2SHP2WSHScriptPubKey accepting empty witness script.")
+	}
+	if _, err := CreateP2SHP2WSHScriptPubKey(make([]byte, MaxWitnessScriptSize+1)); err == nil {
+		t.Error("CreateP2SHP2WSHScriptPubKey accepting witness script longer than the P2WSH limit.")
+	}
+}
+
+func TestIsWitnessScriptPubKey(t *testing.T) {
+	for _, witnessScriptPubKey := range []string{
+		"0014751e76e8199196d454941c45d1b3a323f1433bd6",
+		"00201863143c14c5166804bd19203356da136c985678cd4d27a1b8c6329604903262",
+		"51200f0c8db753acbd17343a39c2f3f4e35e4be6da749f9e35137ab220e7b238a667",
+		"60021234",
+	} {
+		testScriptPubKey, _ := hex.DecodeString(witnessScriptPubKey)
+		if !IsWitnessScriptPubKey(testScriptPubKey) {
+			t.Errorf("IsWitnessScriptPubKey rejecting witness scriptPubKey %s.", witnessScriptPubKey)
+		}
+	}
+	for _, otherScriptPubKey := range []string{
+		"a9141a8b0026343166625c7475f01e48b5ede8c0252e87",
+		"76a914199db810a3c8ae5e55c0432d2b72e55b0634f79088ac",
+		"0015751e76e8199196d454941c45d1b3a323f1433bd6",
+		"6120" + "0f0c8db753acbd17343a39c2f3f4e35e4be6da749f9e35137ab220e7b238a667",
+		"000112",
+	} {
+		testScriptPubKey, _ := hex.DecodeString(otherScriptPubKey)
+		if IsWitnessScriptPubKey(testScriptPubKey) {
+			t.Errorf("IsWitnessScriptPubKey accepting non-witness scriptPubKey %s.", otherScriptPubKey)
+		}
+	}
+}
+
+func TestNewCompressedPublicKey(t *testing.T) {
+	testPrivateKey, _ := hex.DecodeString("619c335025c7f4012e556c2a58b2506e30b8511b53ade95ea316fd8c3286feb9")
+	testPublicKeyHex := "025476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee6357"
+
+	publicKey, err := NewCompressedPublicKey(testPrivateKey)
+	if err != nil {
+		t.Error(err)
+	}
+	publicKeyHex := hex.EncodeToString(publicKey)
+	if publicKeyHex != testPublicKeyHex {
+		testutils.CompareError(t, "Compressed public key different from expected public key.", testPublicKeyHex, publicKeyHex)
+	}
+}
+
+func TestCalcWitnessSigHash(t *testing.T) {
+	//BIP143 native P2WPKH example, signing input 1
+	tx := bip143NativeP2WPKHTx()
+	testScriptCode, _ := hex.DecodeString("76a9141d0f172a0ecb48aee1be1f2687d2963ae33f71a188ac")
+	testAmount := int64(600000000)
+	testSigHashHex := "c37af31116d1b27caf68aae9e3ac82f1477929014d5b917657d0eb49478cb670"
+
+	sigHash, err := CalcWitnessSigHash(tx, 1, testScriptCode, testAmount, SigHashAll)
+	if err != nil {
+		t.Error(err)
+	}
+	sigHashHex := hex.EncodeToString(sigHash)
+	if sigHashHex != testSigHashHex {
+		testutils.CompareError(t, "BIP143 signature hash different from expected hash.", testSigHashHex, sigHashHex)
+	}
+
+	if _, err := CalcWitnessSigHash(tx, 2, testScriptCode, testAmount, SigHashAll); err == nil {
+		t.Error("CalcWitnessSigHash accepting out of range input index.")
+	}
+}
+
+func TestNewWitnessSigHashes(t *testing.T) {
+	//BIP143 native P2WPKH example intermediate hashes
+	tx := bip143NativeP2WPKHTx()
+	testHashPrevoutsHex := "96b827c8483d4e9b96712b6713a7b68d6e8003a781feba36c31143470b4efd37"
+	testHashSequenceHex := "52b0a642eea2fb7ae638c36f6252b6750293dbe574a806984b8e4d8548339a3b"
+	testHashOutputsHex := "863ef3e1a92afbfdb97f31ad0fc7683ee943e9abcf2501590ff8f6551f47e5e5"
+
+	sigHashes, err := NewWitnessSigHashes(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hashPrevoutsHex := hex.EncodeToString(sigHashes.HashPrevouts); hashPrevoutsHex != testHashPrevoutsHex {
+		testutils.CompareError(t, "hashPrevouts different from expected hash.", testHashPrevoutsHex, hashPrevoutsHex)
+	}
+	if hashSequenceHex := hex.EncodeToString(sigHashes.HashSequence); hashSequenceHex != testHashSequenceHex {
+		testutils.CompareError(t, "hashSequence different from expected hash.", testHashSequenceHex, hashSequenceHex)
+	}
+	if hashOutputsHex := hex.EncodeToString(sigHashes.HashOutputs); hashOutputsHex != testHashOutputsHex {
+		testutils.CompareError(t, "hashOutputs different from expected hash.", testHashOutputsHex, hashOutputsHex)
+	}
+
+	//Reusing the hashes gives the same signature hash as computing them for every input and hash type
+	testScriptCode, _ := hex.DecodeString("76a9141d0f172a0ecb48aee1be1f2687d2963ae33f71a188ac")
+	testAmount := int64(600000000)
+	for inputIndex := range tx.Inputs {
+		for _, hashType := range []SigHashType{
+			SigHashAll, SigHashNone, SigHashSingle,
+			SigHashAll | SigHashAnyOneCanPay, SigHashNone | SigHashAnyOneCanPay, SigHashSingle | SigHashAnyOneCanPay,
+		} {
+			sigHash, err := CalcWitnessSigHash(tx, inputIndex, testScriptCode, testAmount, hashType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cachedSigHash, err := sigHashes.CalcSigHash(tx, inputIndex, testScriptCode, testAmount, hashType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(cachedSigHash, sigHash) {
+				testutils.CompareError(t, fmt.Sprintf("Cached signature hash of input %d with hash type %#x different from expected hash.", inputIndex, hashType),
+					hex.EncodeToString(sigHash), hex.EncodeToString(cachedSigHash))
+			}
+		}
+	}
+
+	if _, err := sigHashes.CalcSigHash(tx, 2, testScriptCode, testAmount, SigHashAll); err == nil {
+		t.Error("CalcSigHash accepting out of range input index.")
+	}
+}
+
+func TestCalcWitnessSigHashTypes(t *testing.T) {
+	//BIP143 P2SH-P2WSH 6-of-6 multisig example, signed once with each hash type
+	outputScript0, _ := hex.DecodeString("76a914389ffce9cd9ae88dcc0631e88a821ffdbe9bfe2688ac")
+	outputScript1, _ := hex.DecodeString("76a9147480a33f950689af511e6e84c138dbbd3c3ee41588ac")
+	tx := &Transaction{
+		Version: 1,
+		Inputs:  []Input{{TxHash: "6eb98797a21c6c10aa74edf29d618be109f48a8e94c694f3701e08ca69186436", OutputIndex: 1, Sequence: SequenceFinal}},
+		Outputs: []Output{
+			{Satoshis: 900000000, ScriptPubKey: outputScript0},
+			{Satoshis: 87000000, ScriptPubKey: outputScript1},
+		},
+	}
+	testWitnessScript, _ := hex.DecodeString("56210307b8ae49ac90a048e9b53357a2354b3334e9c8bee813ecb98e99a7e07e8c3ba32103b28f0c28bfab54554ae8c658ac5c3e0ce6e79ad336331f78c428dd43eea8449b21034b8113d703413d57761b8b9781957b8c0ac1dfe69f492580ca4195f50376ba4a21033400f6afecb833092a9a21cfdf1ed1376e58c5d1f47de74683123987e967a8f42103a6d48b1131e94ba04d9737d61acdaa1322008af9602b3b14862c07a1789aac162102d8b661b0b3302ee2f162b09e07a55ad5dfbe673a9f01d9f0c19617681024306b56ae")
+	testAmount := int64(987654321)
+
+	testCases := []struct {
+		name           string
+		hashType       SigHashType
+		testSigHashHex string
+	}{
+		{"ALL", SigHashAll, "185c0be5263dce5b4bb50a047973c1b6272bfbd0103a89444597dc40b248ee7c"},
+		{"NONE", SigHashNone, "e9733bc60ea13c95c6527066bb975a2ff29a925e80aa14c213f686cbae5d2f36"},
+		{"SINGLE", SigHashSingle, "1e1f1c303dc025bd664acb72e583e933fae4cff9148bf78c157d1e8f78530aea"},
+		{"ALL|ANYONECANPAY", SigHashAll | SigHashAnyOneCanPay, "2a67f03e63a6a422125878b40b82da593be8d4efaafe88ee528af6e5a9955c6e"},
+		{"NONE|ANYONECANPAY", SigHashNone | SigHashAnyOneCanPay, "781ba15f3779d5542ce8ecb5c18716733a5ee42a6f51488ec96154934e2c890a"},
+		{"SINGLE|ANYONECANPAY", SigHashSingle | SigHashAnyOneCanPay, "511e8e52ed574121fc1b654970395502128263f62662e076dc6baf05c2e6a99b"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			sigHash, err := CalcWitnessSigHash(tx, 0, testWitnessScript, testAmount, testCase.hashType)
+			if err != nil {
+				t.Error(err)
+			}
+			sigHashHex := hex.EncodeToString(sigHash)
+			if sigHashHex != testCase.testSigHashHex {
+				testutils.CompareError(t, "BIP143 signature hash different from expected hash.", testCase.testSigHashHex, sigHashHex)
+			}
+		})
+	}
+}
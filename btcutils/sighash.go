@@ -0,0 +1,133 @@
+// Signature hash types, selecting which parts of a transaction a signature commits to.
+// See https://en.bitcoin.it/wiki/OP_CHECKSIG for full specification.
+package btcutils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// SigHashType is the hash type appended to a signature and committed to by the signature hash.
+type SigHashType uint32
+
+// Base hash types, optionally combined with SigHashAnyOneCanPay.
+const (
+	SigHashDefault      SigHashType = 0x00 //Taproot only, sign all inputs and outputs without appending the hash type to the signature
+	SigHashAll          SigHashType = 0x01 //Sign all inputs and outputs
+	SigHashNone         SigHashType = 0x02 //Sign all inputs and no outputs
+	SigHashSingle       SigHashType = 0x03 //Sign all inputs and only the output with the same index as the signed input
+	SigHashAnyOneCanPay SigHashType = 0x80 //Sign only the input being signed, combined with one of the above
+
+	sigHashMask = 0x1f //Mask to get the base hash type without the SigHashAnyOneCanPay flag
+)
+
+// baseType returns the hash type without the SigHashAnyOneCanPay flag.
+func (hashType SigHashType) baseType() SigHashType {
+	return hashType & sigHashMask
+}
+
+// anyOneCanPay reports whether the SigHashAnyOneCanPay flag is set.
+func (hashType SigHashType) anyOneCanPay() bool {
+	return hashType&SigHashAnyOneCanPay != 0
+}
+
+// sigHashTypeNames maps the base hash type names accepted by ParseSigHashType to their hash types.
+var sigHashTypeNames = map[string]SigHashType{
+	"default": SigHashDefault,
+	"all":     SigHashAll,
+	"none":    SigHashNone,
+	"single":  SigHashSingle,
+}
+
+// ParseSigHashType parses a hash type name, default, all, none or single, optionally followed by |anyonecanpay,
+// such as all|anyonecanpay. Names are case insensitive.
+// Returns an error if the name is not a valid hash type.
+func ParseSigHashType(name string) (SigHashType, error) {
+	baseName := strings.ToLower(name)
+	var flags SigHashType
+	if strings.HasSuffix(baseName, "|anyonecanpay") {
+		baseName = strings.TrimSuffix(baseName, "|anyonecanpay")
+		flags = SigHashAnyOneCanPay
+	}
+	hashType, ok := sigHashTypeNames[baseName]
+	//SIGHASH_DEFAULT has no ANYONECANPAY form
+	if !ok || (hashType == SigHashDefault && flags != 0) {
+		return 0, fmt.Errorf("Invalid hash type %q. Use default, all, none or single, optionally followed by |anyonecanpay.", name)
+	}
+	return hashType | flags, nil
+}
+
+// CheckSigHashType makes sure an ECDSA signature of input inputIndex of tx can be made with hashType.
+// The base type must be SIGHASH_ALL, SIGHASH_NONE or SIGHASH_SINGLE, with no flags other than SIGHASH_ANYONECANPAY,
+// and SIGHASH_SINGLE needs an output with the same index as the input. CalcSignatureHash signs the number one for
+// such an input to stay compatible with the original client, which lets the signature be reused to spend the input
+// in any transaction, so signers should never produce it.
+func CheckSigHashType(tx *Transaction, inputIndex int, hashType SigHashType) error {
+	baseType := hashType.baseType()
+	if hashType&^(SigHashAnyOneCanPay|sigHashMask) != 0 || baseType < SigHashAll || baseType > SigHashSingle {
+		return fmt.Errorf("Invalid hash type 0x%02x.", uint32(hashType))
+	}
+	if baseType == SigHashSingle && inputIndex >= len(tx.Outputs) {
+		return fmt.Errorf("SIGHASH_SINGLE input %d has no output with the same index.", inputIndex)
+	}
+	return nil
+}
+
+// CalcSignatureHash calculates the legacy (pre-SegWit) signature hash for input inputIndex of tx with the given
+// hashType. subScript is the script being satisfied, the scriptPubKey of the output being spent for P2PKH inputs
+// or the redeemScript for P2SH inputs. It replaces the scriptSig of the signed input, while the scriptSigs of the
+// other inputs are emptied.
+func CalcSignatureHash(tx *Transaction, inputIndex int, subScript []byte, hashType SigHashType) ([]byte, error) {
+	if inputIndex < 0 || inputIndex >= len(tx.Inputs) {
+		return nil, fmt.Errorf("Input index %d out of range for transaction with %d inputs.", inputIndex, len(tx.Inputs))
+	}
+	baseType := hashType.baseType()
+	//SIGHASH_SINGLE without an output at the input's index signs the number one instead of the transaction,
+	//a bug in the original client kept for compatibility
+	if baseType == SigHashSingle && inputIndex >= len(tx.Outputs) {
+		hash := make([]byte, 32)
+		hash[0] = 0x01
+		return hash, nil
+	}
+	txCopy := &Transaction{Version: tx.Version, LockTime: tx.LockTime}
+	for i, input := range tx.Inputs {
+		//SIGHASH_ANYONECANPAY signs only the input being signed
+		if hashType.anyOneCanPay() && i != inputIndex {
+			continue
+		}
+		input.ScriptSig = nil
+		input.Witness = nil
+		if i == inputIndex {
+			input.ScriptSig = subScript
+		} else if baseType == SigHashNone || baseType == SigHashSingle {
+			//Other inputs may be updated with SIGHASH_NONE and SIGHASH_SINGLE, so their sequence is not signed
+			input.Sequence = 0
+		}
+		txCopy.Inputs = append(txCopy.Inputs, input)
+	}
+	switch baseType {
+	case SigHashNone:
+		txCopy.Outputs = nil
+	case SigHashSingle:
+		//Outputs before the input's index are blanked with a value of -1 and an empty scriptPubKey
+		txCopy.Outputs = make([]Output, inputIndex+1)
+		for i := 0; i < inputIndex; i++ {
+			txCopy.Outputs[i] = Output{Satoshis: 0xffffffffffffffff}
+		}
+		txCopy.Outputs[inputIndex] = tx.Outputs[inputIndex]
+	default:
+		txCopy.Outputs = tx.Outputs
+	}
+	serializedTx, err := txCopy.serialize(false)
+	if err != nil {
+		return nil, err
+	}
+	//Hash type is appended in little-endian format before hashing
+	var preimage bytes.Buffer
+	preimage.Write(serializedTx)
+	binary.Write(&preimage, binary.LittleEndian, uint32(hashType))
+
+	return DoubleSHA256(preimage.Bytes()), nil
+}
@@ -0,0 +1,124 @@
+package btcutils
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/testutils"
+
+	"encoding/hex"
+	"testing"
+)
+
+func TestCalcSignatureHash(t *testing.T) {
+	//Three inputs and two outputs, so input 2 has no matching output for SIGHASH_SINGLE
+	outputScript0, _ := hex.DecodeString("a9141a8b0026343166625c7475f01e48b5ede8c0252e87")
+	outputScript1, _ := hex.DecodeString("76a914569076ba39fc4ff6a2291d9ea9196d8c08f9c7ab88ac")
+	tx := &Transaction{
+		Version: 1,
+		Inputs: []Input{
+			{TxHash: "3ad337270ac0ba14fbce812291b7d95338c878709ea8123a4d88c3c29efbc6ac", OutputIndex: 0, ScriptSig: []byte{OP_1}, Sequence: SequenceFinal},
+			{TxHash: "02b082113e35d5386285094c2829e7e2963fa0b5369fb7f4b79c4c90877dcd3d", OutputIndex: 1, ScriptSig: []byte{OP_1}, Sequence: 0xfffffffe},
+			{TxHash: "d073ced3663e40d4917c8fa5858b5cc4c95c4a7e5d3b33512ba94824da8c7b50", OutputIndex: 2, ScriptSig: []byte{OP_1}, Sequence: 0xfffffffd},
+		},
+		Outputs: []Output{
+			{Satoshis: 65600, ScriptPubKey: outputScript0},
+			{Satoshis: 55600, ScriptPubKey: outputScript1},
+		},
+		LockTime: 500000,
+	}
+	testSubScript, _ := hex.DecodeString("76a9149203e47a16f799ded03532e3e452606fdc52007e88ac")
+
+	testCases := []struct {
+		name           string
+		inputIndex     int
+		hashType       SigHashType
+		testSigHashHex string
+	}{
+		{"ALL", 1, SigHashAll, "9645d469aeaffa95e3e9df7e5b3d81458ecb226e0a70330dda5c1af8214a18f3"},
+		{"NONE", 1, SigHashNone, "419b34fab2f785f52d4cfd92c102604c5aa1ce258c1c4183049a6fc36bd03126"},
+		{"SINGLE", 1, SigHashSingle, "a1e52c9e3875427f34e220cf47b0697a225609b471e0f2150ccff4b6debad521"},
+		{"ALL|ANYONECANPAY", 1, SigHashAll | SigHashAnyOneCanPay, "49f015bf1bc19c3ca2aaf21733c93c567ede97ea663dcca918acc1925c960bdd"},
+		{"NONE|ANYONECANPAY", 1, SigHashNone | SigHashAnyOneCanPay, "2e1ccb737d3a0e3cf550243bebed11b8806395063760aacdb4a94a6336993d48"},
+		{"SINGLE|ANYONECANPAY", 1, SigHashSingle | SigHashAnyOneCanPay, "f57684b7eb7ee95005c2c52040b77fe88ab88bbd18bc54b059d5a5b292ffb534"},
+		{"ALL first input", 0, SigHashAll, "325eb87bab9d7e22d109f6f520c1e42e33218ca5c2b1570bb2311f2c8905d15e"},
+		{"SINGLE first input", 0, SigHashSingle, "c21dc1635b9fa5275c7329730bfadf7e856f514e1a34d321d8b6ce090a295479"},
+		//SIGHASH_SINGLE bug, signing the number one when there is no output with the input's index
+		{"SINGLE without output", 2, SigHashSingle, "0100000000000000000000000000000000000000000000000000000000000000"},
+		{"SINGLE|ANYONECANPAY without output", 2, SigHashSingle | SigHashAnyOneCanPay, "0100000000000000000000000000000000000000000000000000000000000000"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			sigHash, err := CalcSignatureHash(tx, testCase.inputIndex, testSubScript, testCase.hashType)
+			if err != nil {
+				t.Error(err)
+			}
+			sigHashHex := hex.EncodeToString(sigHash)
+			if sigHashHex != testCase.testSigHashHex {
+				testutils.CompareError(t, "Signature hash different from expected hash.", testCase.testSigHashHex, sigHashHex)
+			}
+		})
+	}
+
+	if _, err := CalcSignatureHash(tx, 3, testSubScript, SigHashAll); err == nil {
+		t.Error("CalcSignatureHash accepting out of range input index.")
+	}
+	//The transaction itself is left unchanged
+	if len(tx.Inputs) != 3 || len(tx.Inputs[0].ScriptSig) != 1 || tx.Inputs[2].Sequence != 0xfffffffd {
+		t.Error("CalcSignatureHash modifying the signed transaction.")
+	}
+}
+
+func TestParseSigHashType(t *testing.T) {
+	testCases := map[string]SigHashType{
+		"default":             SigHashDefault,
+		"all":                 SigHashAll,
+		"none":                SigHashNone,
+		"single":              SigHashSingle,
+		"all|anyonecanpay":    SigHashAll | SigHashAnyOneCanPay,
+		"none|anyonecanpay":   SigHashNone | SigHashAnyOneCanPay,
+		"SINGLE|ANYONECANPAY": SigHashSingle | SigHashAnyOneCanPay,
+	}
+	for name, testHashType := range testCases {
+		hashType, err := ParseSigHashType(name)
+		if err != nil {
+			t.Error(err)
+		}
+		if hashType != testHashType {
+			testutils.CompareError(t, "Parsed hash type different from expected hash type.", testHashType, hashType)
+		}
+	}
+	for _, invalidName := range []string{"", "alll", "anyonecanpay", "default|anyonecanpay", "all|anyonecanpay|anyonecanpay", "0x01"} {
+		if _, err := ParseSigHashType(invalidName); err == nil {
+			t.Errorf("ParseSigHashType accepting invalid hash type %q.", invalidName)
+		}
+	}
+}
+
+func TestCheckSigHashType(t *testing.T) {
+	//Two inputs and one output, so input 1 has no matching output for SIGHASH_SINGLE
+	tx := &Transaction{
+		Version: 1,
+		Inputs: []Input{
+			{TxHash: "3ad337270ac0ba14fbce812291b7d95338c878709ea8123a4d88c3c29efbc6ac", Sequence: SequenceFinal},
+			{TxHash: "02b082113e35d5386285094c2829e7e2963fa0b5369fb7f4b79c4c90877dcd3d", Sequence: SequenceFinal},
+		},
+		Outputs: []Output{{Satoshis: 65600}},
+	}
+	for _, hashType := range []SigHashType{SigHashAll, SigHashNone, SigHashSingle, SigHashAll | SigHashAnyOneCanPay, SigHashSingle | SigHashAnyOneCanPay} {
+		if err := CheckSigHashType(tx, 0, hashType); err != nil {
+			t.Error(err)
+		}
+	}
+	if err := CheckSigHashType(tx, 1, SigHashNone); err != nil {
+		t.Error(err)
+	}
+	if err := CheckSigHashType(tx, 1, SigHashSingle); err == nil {
+		t.Error("CheckSigHashType accepting SIGHASH_SINGLE input without an output with the same index.")
+	}
+	if err := CheckSigHashType(tx, 1, SigHashSingle|SigHashAnyOneCanPay); err == nil {
+		t.Error("CheckSigHashType accepting SIGHASH_SINGLE|SIGHASH_ANYONECANPAY input without an output with the same index.")
+	}
+	for _, hashType := range []SigHashType{SigHashDefault, SigHashAnyOneCanPay, 0x04, 0x41, 0x101} {
+		if err := CheckSigHashType(tx, 0, hashType); err == nil {
+			t.Errorf("CheckSigHashType accepting invalid hash type 0x%02x.", uint32(hashType))
+		}
+	}
+}
@@ -0,0 +1,175 @@
+// DER encoded ECDSA signature handling following the BIP66 strict DER rules, normalizing signatures to the low-S
+// form required by Bitcoin relay policy.
+package btcutils
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// secp256k1HalfN is half the order of the secp256k1 base point, the largest S value of a low-S signature.
+var secp256k1HalfN = new(big.Int).Rsh(secp256k1N, 1)
+
+const (
+	//Smallest and largest strict DER signatures, not counting the sighash type byte
+	minDERSignatureLength = 8
+	maxDERSignatureLength = 72
+	//Largest R or S value, not counting a leading zero added to keep it positive
+	maxDERValueLength = 32
+)
+
+// EncodeDER encodes the big-endian R and S values of an ECDSA signature as a strict DER signature in the form
+// 0x30 <length> 0x02 <length R> <R> 0x02 <length S> <S>. Values are minimally encoded, with leading zeros stripped
+// and a single zero byte added only where the top bit is set, so the value isn't read as negative.
+func EncodeDER(r []byte, s []byte) ([]byte, error) {
+	rInteger, err := derInteger(r)
+	if err != nil {
+		return nil, fmt.Errorf("Signature R value is invalid. %s", err)
+	}
+	sInteger, err := derInteger(s)
+	if err != nil {
+		return nil, fmt.Errorf("Signature S value is invalid. %s", err)
+	}
+	signature := []byte{0x30, byte(len(rInteger) + len(sInteger))}
+	signature = append(signature, rInteger...)
+	return append(signature, sInteger...), nil
+}
+
+// DecodeDER decodes a strict DER signature, without a trailing sighash type byte, into its big-endian R and S
+// values with any leading zero byte removed. Signatures breaking any of the BIP66 strict DER rules are rejected.
+// See https://github.com/bitcoin/bips/blob/master/bip-0066.mediawiki for full specification.
+func DecodeDER(signature []byte) ([]byte, []byte, error) {
+	if len(signature) < minDERSignatureLength || len(signature) > maxDERSignatureLength {
+		return nil, nil, fmt.Errorf("Signature is %d bytes long. DER signatures are %d to %d bytes long.", len(signature), minDERSignatureLength, maxDERSignatureLength)
+	}
+	if signature[0] != 0x30 {
+		return nil, nil, errors.New("Signature is not a DER encoded sequence.")
+	}
+	if int(signature[1]) != len(signature)-2 {
+		return nil, nil, fmt.Errorf("Signature length %d does not match DER sequence length %d.", len(signature)-2, signature[1])
+	}
+	rLength := int(signature[3])
+	if 5+rLength >= len(signature) {
+		return nil, nil, fmt.Errorf("Signature R value length %d is past the end of the signature.", rLength)
+	}
+	sLength := int(signature[5+rLength])
+	if rLength+sLength+6 != len(signature) {
+		return nil, nil, errors.New("Signature R and S value lengths do not match the signature length.")
+	}
+	r, err := parseDERInteger(signature[2 : 4+rLength])
+	if err != nil {
+		return nil, nil, fmt.Errorf("Signature R value is invalid. %s", err)
+	}
+	s, err := parseDERInteger(signature[4+rLength:])
+	if err != nil {
+		return nil, nil, fmt.Errorf("Signature S value is invalid. %s", err)
+	}
+	return r, s, nil
+}
+
+// ParseDERSignature parses a signature as it appears in a scriptSig or witness, a strict DER signature followed by
+// its sighash type byte, into its big-endian R and S values and its hash type. Signatures breaking any of the BIP66
+// strict DER rules are rejected, as nodes reject them with CheckSignatureEncoding.
+func ParseDERSignature(signature []byte) ([]byte, []byte, SigHashType, error) {
+	if len(signature) == 0 {
+		return nil, nil, 0, errors.New("Signature cannot be empty.")
+	}
+	r, s, err := DecodeDER(signature[:len(signature)-1])
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	return r, s, SigHashType(signature[len(signature)-1]), nil
+}
+
+// SerializeDERSignature encodes the big-endian R and S values of an ECDSA signature as a strict DER signature
+// followed by the sighash type byte, ready to be pushed in a scriptSig or witness.
+func SerializeDERSignature(r []byte, s []byte, hashType SigHashType) ([]byte, error) {
+	signature, err := EncodeDER(r, s)
+	if err != nil {
+		return nil, err
+	}
+	return append(signature, byte(hashType)), nil
+}
+
+// NormalizeSignatureS parses a DER encoded ECDSA signature and, if its S value is greater than half the secp256k1
+// curve order, replaces S with N-S and re-encodes the signature. Both forms are valid signatures of the same hash,
+// but nodes only relay the low-S form. R is left unchanged.
+func NormalizeSignatureS(signature []byte) ([]byte, error) {
+	r, s, err := DecodeDER(signature)
+	if err != nil {
+		return nil, err
+	}
+	if new(big.Int).SetBytes(s).Cmp(secp256k1HalfN) <= 0 {
+		return signature, nil
+	}
+	return EncodeDER(r, normalizeS(s))
+}
+
+// CheckSignatureIsLowS makes sure the S value of a DER encoded ECDSA signature is no greater than half the secp256k1
+// curve order, as nodes enforcing the BIP62 low-S rule require. Returns an error or nil if the signature is low-S.
+func CheckSignatureIsLowS(signature []byte) error {
+	_, s, err := DecodeDER(signature)
+	if err != nil {
+		return err
+	}
+	if new(big.Int).SetBytes(s).Cmp(secp256k1HalfN) > 0 {
+		return errors.New("Signature S value is greater than half the curve order, nodes will not relay it.")
+	}
+	return nil
+}
+
+// isLowR reports whether the big-endian R value of a signature is below 2^255, so its DER encoding needs no leading
+// zero byte and the signature is at most 71 bytes long.
+func isLowR(r []byte) bool {
+	return len(r) < maxDERValueLength || r[0] < 0x80
+}
+
+// normalizeS returns N-S if S is greater than half the secp256k1 curve order, and S otherwise.
+func normalizeS(s []byte) []byte {
+	sValue := new(big.Int).SetBytes(s)
+	if sValue.Cmp(secp256k1HalfN) <= 0 {
+		return s
+	}
+	return new(big.Int).Sub(secp256k1N, sValue).Bytes()
+}
+
+// parseDERInteger parses a DER encoded positive integer 0x02 <length> <value> filling all of data, returning the
+// value without its leading zero byte, if any.
+func parseDERInteger(data []byte) ([]byte, error) {
+	if data[0] != 0x02 {
+		return nil, errors.New("Value is not a DER encoded integer.")
+	}
+	value := data[2:]
+	if len(value) == 0 {
+		return nil, errors.New("Value is empty.")
+	}
+	if value[0]&0x80 != 0 {
+		return nil, errors.New("Value is negative.")
+	}
+	if len(value) > 1 && value[0] == 0x00 {
+		if value[1]&0x80 == 0 {
+			return nil, errors.New("Value has an unnecessary leading zero byte.")
+		}
+		value = value[1:]
+	}
+	return value, nil
+}
+
+// derInteger encodes the big-endian value as a DER integer, stripping leading zeros and adding a single zero byte
+// if the top bit is set so it isn't negative.
+func derInteger(value []byte) ([]byte, error) {
+	for len(value) > 0 && value[0] == 0x00 {
+		value = value[1:]
+	}
+	if len(value) == 0 {
+		return nil, errors.New("Value is zero.")
+	}
+	if len(value) > maxDERValueLength {
+		return nil, fmt.Errorf("Value is %d bytes long. Values are at most %d bytes long.", len(value), maxDERValueLength)
+	}
+	if value[0]&0x80 != 0 {
+		value = append([]byte{0x00}, value...)
+	}
+	return append([]byte{0x02, byte(len(value))}, value...), nil
+}
@@ -0,0 +1,229 @@
+package btcutils
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/testutils"
+
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+func TestNormalizeSignatureS(t *testing.T) {
+	testCases := []struct {
+		signature  string
+		normalized string
+	}{
+		//High-S form of the RFC 6979 signature of "Satoshi Nakamoto" with private key 1
+		{"3046022100934b1ea10a4b3c1757e2b0c017d0b6143ce3c9a7e6a4a49860d7a6ab210ee3d8022100dbbd3162d46e9f9bef7feb87c16dc13b4f6568a87f4e83f728e2443ba586675c", "3045022100934b1ea10a4b3c1757e2b0c017d0b6143ce3c9a7e6a4a49860d7a6ab210ee3d802202442ce9d2b916064108014783e923ec36b49743e2ffa1c4496f01a512aafd9e5"},
+		//Low-S signatures are unchanged
+		{"3045022100934b1ea10a4b3c1757e2b0c017d0b6143ce3c9a7e6a4a49860d7a6ab210ee3d802202442ce9d2b916064108014783e923ec36b49743e2ffa1c4496f01a512aafd9e5", "3045022100934b1ea10a4b3c1757e2b0c017d0b6143ce3c9a7e6a4a49860d7a6ab210ee3d802202442ce9d2b916064108014783e923ec36b49743e2ffa1c4496f01a512aafd9e5"},
+		//S = N-1 becomes S = 1
+		{"3026020101022100fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364140", "3006020101020101"},
+	}
+	for _, testCase := range testCases {
+		signature, _ := hex.DecodeString(testCase.signature)
+		normalized, err := NormalizeSignatureS(signature)
+		if err != nil {
+			t.Error(err)
+		}
+		normalizedHex := hex.EncodeToString(normalized)
+		if normalizedHex != testCase.normalized {
+			testutils.CompareError(t, "Normalized signature different from expected signature.", testCase.normalized, normalizedHex)
+		}
+	}
+
+	invalidSignatures := []string{
+		"",
+		"3006020101020101ff", //trailing byte outside sequence
+		"3107020101020101ff", //not a sequence
+		"300702010102010100", //trailing byte inside sequence
+		"3006030101020101",   //R not an integer
+		"3006020501020101",   //R length past end of signature
+		"300702010102000000", //empty S
+	}
+	for _, invalidSignature := range invalidSignatures {
+		signature, _ := hex.DecodeString(invalidSignature)
+		if _, err := NormalizeSignatureS(signature); err == nil {
+			t.Errorf("NormalizeSignatureS accepting invalid signature %s.", invalidSignature)
+		}
+	}
+}
+
+func TestEncodeDER(t *testing.T) {
+	testCases := []struct {
+		r         string
+		s         string
+		signature string
+	}{
+		//Top bit of R set, so R gets a leading zero byte
+		{"934b1ea10a4b3c1757e2b0c017d0b6143ce3c9a7e6a4a49860d7a6ab210ee3d8", "2442ce9d2b916064108014783e923ec36b49743e2ffa1c4496f01a512aafd9e5", "3045022100934b1ea10a4b3c1757e2b0c017d0b6143ce3c9a7e6a4a49860d7a6ab210ee3d802202442ce9d2b916064108014783e923ec36b49743e2ffa1c4496f01a512aafd9e5"},
+		//Leading zeros stripped
+		{"0000000000000000000000000000000000000000000000000000000000000001", "0001", "3006020101020101"},
+		//Zero byte kept only where needed
+		{"0080", "7f", "30070202008002017f"},
+	}
+	for _, testCase := range testCases {
+		r, _ := hex.DecodeString(testCase.r)
+		s, _ := hex.DecodeString(testCase.s)
+		signature, err := EncodeDER(r, s)
+		if err != nil {
+			t.Error(err)
+		}
+		signatureHex := hex.EncodeToString(signature)
+		if signatureHex != testCase.signature {
+			testutils.CompareError(t, "DER signature different from expected signature.", testCase.signature, signatureHex)
+		}
+	}
+
+	invalidCases := []struct {
+		r string
+		s string
+	}{
+		{"", "01"},
+		{"01", "0000"},
+		{"01", "010000000000000000000000000000000000000000000000000000000000000000"},
+	}
+	for _, testCase := range invalidCases {
+		r, _ := hex.DecodeString(testCase.r)
+		s, _ := hex.DecodeString(testCase.s)
+		if _, err := EncodeDER(r, s); err == nil {
+			t.Errorf("EncodeDER accepting invalid values R %s and S %s.", testCase.r, testCase.s)
+		}
+	}
+}
+
+func TestDecodeDER(t *testing.T) {
+	testCases := []struct {
+		signature string
+		r         string
+		s         string
+	}{
+		{"3045022100934b1ea10a4b3c1757e2b0c017d0b6143ce3c9a7e6a4a49860d7a6ab210ee3d802202442ce9d2b916064108014783e923ec36b49743e2ffa1c4496f01a512aafd9e5", "934b1ea10a4b3c1757e2b0c017d0b6143ce3c9a7e6a4a49860d7a6ab210ee3d8", "2442ce9d2b916064108014783e923ec36b49743e2ffa1c4496f01a512aafd9e5"},
+		{"3006020101020101", "01", "01"},
+		{"300702020080020101", "80", "01"},
+	}
+	for _, testCase := range testCases {
+		signature, _ := hex.DecodeString(testCase.signature)
+		r, s, err := DecodeDER(signature)
+		if err != nil {
+			t.Error(err)
+		}
+		rHex := hex.EncodeToString(r)
+		if rHex != testCase.r {
+			testutils.CompareError(t, "Decoded R value different from expected value.", testCase.r, rHex)
+		}
+		sHex := hex.EncodeToString(s)
+		if sHex != testCase.s {
+			testutils.CompareError(t, "Decoded S value different from expected value.", testCase.s, sHex)
+		}
+	}
+
+	invalidSignatures := []string{
+		"30050201010201",     //too short
+		"3006020101020101ff", //trailing byte outside sequence
+		"3106020101020101",   //not a sequence
+		"3007020101020101",   //sequence length too long
+		"3006020501020101",   //R length past end of signature
+		"3006020101020201",   //S length past end of signature
+		"3006030101020101",   //R not an integer
+		"3006020101030101",   //S not an integer
+		"3006020002020101",   //empty R
+		"3006020181020101",   //negative R
+		"3006020101020181",   //negative S
+		"300702020001020101", //R with unnecessary leading zero
+		"300702010102020001", //S with unnecessary leading zero
+		"3049022100934b1ea10a4b3c1757e2b0c017d0b6143ce3c9a7e6a4a49860d7a6ab210ee3d802240000000000000000000000000000000000000000000000000000000000000000000001", //over 72 bytes
+	}
+	for _, invalidSignature := range invalidSignatures {
+		signature, _ := hex.DecodeString(invalidSignature)
+		if _, _, err := DecodeDER(signature); err == nil {
+			t.Errorf("DecodeDER accepting invalid signature %s.", invalidSignature)
+		}
+	}
+}
+
+func TestParseDERSignature(t *testing.T) {
+	testSignatureHex := "3045022100934b1ea10a4b3c1757e2b0c017d0b6143ce3c9a7e6a4a49860d7a6ab210ee3d802202442ce9d2b916064108014783e923ec36b49743e2ffa1c4496f01a512aafd9e5"
+	testR := "934b1ea10a4b3c1757e2b0c017d0b6143ce3c9a7e6a4a49860d7a6ab210ee3d8"
+	testS := "2442ce9d2b916064108014783e923ec36b49743e2ffa1c4496f01a512aafd9e5"
+	for _, testHashType := range []SigHashType{SigHashAll, SigHashSingle | SigHashAnyOneCanPay} {
+		r, _ := hex.DecodeString(testR)
+		s, _ := hex.DecodeString(testS)
+		signature, err := SerializeDERSignature(r, s, testHashType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		signatureHex := hex.EncodeToString(signature)
+		testSerializedHex := testSignatureHex + hex.EncodeToString([]byte{byte(testHashType)})
+		if signatureHex != testSerializedHex {
+			testutils.CompareError(t, "Serialized signature different from expected signature.", testSerializedHex, signatureHex)
+		}
+		r, s, hashType, err := ParseDERSignature(signature)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hex.EncodeToString(r) != testR || hex.EncodeToString(s) != testS || hashType != testHashType {
+			testutils.CompareError(t, "Parsed signature different from serialized signature.", testR+" "+testS, hex.EncodeToString(r)+" "+hex.EncodeToString(s))
+		}
+	}
+
+	//Malformed signatures in the order of the BIP66 IsValidSignatureEncoding checks, each with a SIGHASH_ALL byte
+	invalidSignatures := []string{
+		"",                 //empty
+		"3005020101020101", //shorter than 9 bytes
+		"3046022100934b1ea10a4b3c1757e2b0c017d0b6143ce3c9a7e6a4a49860d7a6ab210ee3d80221000000000000000000000000000000000000000000000000000000000000000000010101", //longer than 73 bytes
+		"310602010102010101",   //not a compound sequence
+		"300702010102010101",   //sequence length doesn't match signature length
+		"300602050102010101",   //R length past end of signature
+		"300602010102020101",   //S length doesn't match signature length
+		"3006020101020101ff01", //trailing byte between S and hash type
+		"300603010102010101",   //R not an integer
+		"300602000202010101",   //zero length R
+		"300602018102010101",   //negative R
+		"30070202000102010101", //R with unnecessary leading zero
+		"300602010103010101",   //S not an integer
+		"300602020101020001",   //zero length S
+		"300602010102018101",   //negative S
+		"30070201010202000101", //S with unnecessary leading zero
+	}
+	for _, invalidSignature := range invalidSignatures {
+		signature, _ := hex.DecodeString(invalidSignature)
+		if _, _, _, err := ParseDERSignature(signature); err == nil {
+			t.Errorf("ParseDERSignature accepting invalid signature %s.", invalidSignature)
+		}
+	}
+
+	if _, err := SerializeDERSignature([]byte{}, []byte{0x01}, SigHashAll); err == nil {
+		t.Error("SerializeDERSignature accepting zero R value.")
+	}
+}
+
+func TestCheckSignatureIsLowS(t *testing.T) {
+	lowS, _ := hex.DecodeString("3045022100934b1ea10a4b3c1757e2b0c017d0b6143ce3c9a7e6a4a49860d7a6ab210ee3d802202442ce9d2b916064108014783e923ec36b49743e2ffa1c4496f01a512aafd9e5")
+	if err := CheckSignatureIsLowS(lowS); err != nil {
+		t.Error(err)
+	}
+	highS, _ := hex.DecodeString("3046022100934b1ea10a4b3c1757e2b0c017d0b6143ce3c9a7e6a4a49860d7a6ab210ee3d8022100dbbd3162d46e9f9bef7feb87c16dc13b4f6568a87f4e83f728e2443ba586675c")
+	if err := CheckSignatureIsLowS(highS); err == nil {
+		t.Error("CheckSignatureIsLowS accepting high-S signature.")
+	}
+}
+
+func TestSignHashLowS(t *testing.T) {
+	//With the fixed nonce, the raw signature of this hash has S = a8b7d8ca...ff8ffd6d, greater than N/2
+	previousSetFixedNonce := SetFixedNonce
+	SetFixedNonce = true
+	defer func() { SetFixedNonce = previousSetFixedNonce }()
+	testPrivateKey, _ := hex.DecodeString("14af2e44085b848139e69e36ba73bff5790b6ce07d6063280b9cc79e37c76ee5")
+	testSignatureHex := "304402206d6caac248af96f6afa7f904f550253a0f3ef3f5aa2fe6838a95b216691468e2022057482735686ad798654c616ffbfeab4d697d11b96c85687706f3c675d0a643d4"
+	hash := sha256.Sum256([]byte("Satoshi Nakamoto 1"))
+
+	signature, err := SignHash(hash[:], testPrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signatureHex := hex.EncodeToString(signature)
+	if signatureHex != testSignatureHex {
+		testutils.CompareError(t, "Signature different from expected low-S signature.", testSignatureHex, signatureHex)
+	}
+}
@@ -0,0 +1,169 @@
+// Taproot (SegWit version 1) outputs and their key path spending as per BIP341.
+// See https://github.com/bitcoin/bips/blob/master/bip-0341.mediawiki for full specification.
+package btcutils
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// CreateP2TRScriptPubKey creates the scriptPubKey of a Taproot output paying to the 32 byte x-only outputKey, as
+// returned by TweakPublicKey, in the form OP_1 <outputKey>.
+func CreateP2TRScriptPubKey(outputKey []byte) ([]byte, error) {
+	if len(outputKey) != 32 {
+		return nil, fmt.Errorf("Taproot output key should be 32 bytes long. Provided key is %d bytes long.", len(outputKey))
+	}
+	return NewWitnessScriptPubKey(1, outputKey)
+}
+
+// TaprootTweakKey tweaks the 32 byte x-only internalKey with the merkleRoot of the script tree to give the Taproot
+// output key Q = P + H_TapTweak(P || merkleRoot)G. merkleRoot is empty for outputs that can only be spent with the
+// key path, as recommended by BIP86, committing to H_TapTweak(P) only. Returns the 32 byte x-only output key, and
+// parity, true if Q has an odd y coordinate, as script path spends give in their control block.
+func TaprootTweakKey(internalKey []byte, merkleRoot []byte) ([]byte, bool, error) {
+	outputKeyPoint, err := tweakPublicKeyPoint(internalKey, merkleRoot)
+	if err != nil {
+		return nil, false, err
+	}
+	return int2octets(outputKeyPoint.x, 32), outputKeyPoint.y.Bit(0) == 1, nil
+}
+
+// TweakPublicKey returns the 32 byte x-only Taproot output key of internalKey tweaked with merkleRoot, as
+// TaprootTweakKey does, for callers that only need the key the output pays to.
+func TweakPublicKey(internalKey []byte, merkleRoot []byte) ([]byte, error) {
+	outputKey, _, err := TaprootTweakKey(internalKey, merkleRoot)
+	return outputKey, err
+}
+
+// tweakPublicKeyPoint returns the Taproot output key point of internalKey tweaked with merkleRoot, whose x coordinate
+// is the output key returned by TweakPublicKey and whose y parity goes in control blocks.
+func tweakPublicKeyPoint(internalKey []byte, merkleRoot []byte) (*ecPoint, error) {
+	if len(internalKey) != 32 {
+		return nil, fmt.Errorf("Taproot internal key should be 32 bytes long. Provided key is %d bytes long.", len(internalKey))
+	}
+	tweak, err := tapTweak(internalKey, merkleRoot)
+	if err != nil {
+		return nil, err
+	}
+	internalKeyPoint, err := liftX(new(big.Int).SetBytes(internalKey))
+	if err != nil {
+		return nil, err
+	}
+	outputKeyPoint := ecAdd(internalKeyPoint, ecMul(tweak, secp256k1G))
+	if outputKeyPoint == nil {
+		return nil, errors.New("Tweaked Taproot output key is the point at infinity.")
+	}
+	return outputKeyPoint, nil
+}
+
+// TaprootTweakPrivateKey tweaks privateKey with the merkleRoot of the script tree to give the private key of the
+// Taproot output key returned by TaprootTweakKey for the same merkleRoot. Key path spends are signed with the
+// tweaked key.
+func TaprootTweakPrivateKey(privateKey []byte, merkleRoot []byte) ([]byte, error) {
+	d, err := parsePrivateKeyScalar(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	//The internal key is x-only, so negate the private key if needed for its public key to have an even y
+	internalKeyPoint := ecMul(d, secp256k1G)
+	if internalKeyPoint.y.Bit(0) == 1 {
+		d.Sub(secp256k1N, d)
+	}
+	tweak, err := tapTweak(int2octets(internalKeyPoint.x, 32), merkleRoot)
+	if err != nil {
+		return nil, err
+	}
+	d.Add(d, tweak).Mod(d, secp256k1N)
+	if d.Sign() == 0 {
+		return nil, errors.New("Tweaked Taproot private key is zero.")
+	}
+	return int2octets(d, 32), nil
+}
+
+// tapTweak computes the tweak H_TapTweak(internalKey || merkleRoot) as an integer, which must be less than N.
+func tapTweak(internalKey []byte, merkleRoot []byte) (*big.Int, error) {
+	if len(merkleRoot) != 0 && len(merkleRoot) != 32 {
+		return nil, fmt.Errorf("Taproot merkle root should be empty or 32 bytes long. Provided root is %d bytes long.", len(merkleRoot))
+	}
+	tweak := new(big.Int).SetBytes(taggedHash("TapTweak", internalKey, merkleRoot))
+	if tweak.Cmp(secp256k1N) >= 0 {
+		return nil, errors.New("Taproot tweak is not less than the secp256k1 curve order.")
+	}
+	return tweak, nil
+}
+
+// CalcTaprootSigHash calculates the BIP341 signature hash for a key path spend of input inputIndex of tx with the
+// given hashType. Unlike BIP143, the signature commits to the amount and scriptPubKey of every output being spent,
+// so prevOuts must hold the output spent by each input of tx, in the same order.
+func CalcTaprootSigHash(tx *Transaction, inputIndex int, prevOuts []Output, hashType SigHashType) ([]byte, error) {
+	if inputIndex < 0 || inputIndex >= len(tx.Inputs) {
+		return nil, fmt.Errorf("Input index %d out of range for transaction with %d inputs.", inputIndex, len(tx.Inputs))
+	}
+	if len(prevOuts) != len(tx.Inputs) {
+		return nil, fmt.Errorf("Taproot signature hash requires the spent output of each of the %d inputs. %d spent outputs provided.", len(tx.Inputs), len(prevOuts))
+	}
+	baseType := hashType.baseType()
+	if hashType != SigHashDefault && (hashType&^(SigHashAnyOneCanPay|sigHashMask) != 0 || baseType < SigHashAll || baseType > SigHashSingle) {
+		return nil, fmt.Errorf("Invalid Taproot hash type 0x%02x.", uint32(hashType))
+	}
+	if baseType == SigHashSingle && inputIndex >= len(tx.Outputs) {
+		return nil, fmt.Errorf("SIGHASH_SINGLE input %d has no output with the same index.", inputIndex)
+	}
+
+	var sigMsg bytes.Buffer
+	sigMsg.WriteByte(0x00) //epoch
+	sigMsg.WriteByte(byte(hashType))
+	binary.Write(&sigMsg, binary.LittleEndian, tx.Version)
+	binary.Write(&sigMsg, binary.LittleEndian, tx.LockTime)
+	//Unless signing with SIGHASH_ANYONECANPAY, commit to the outpoints, amounts, scriptPubKeys and sequences of all inputs
+	if !hashType.anyOneCanPay() {
+		var prevouts, amounts, scriptPubKeys, sequences bytes.Buffer
+		for i, input := range tx.Inputs {
+			err := writeOutPoint(&prevouts, input)
+			if err != nil {
+				return nil, err
+			}
+			binary.Write(&amounts, binary.LittleEndian, prevOuts[i].Satoshis)
+			WriteVarInt(&scriptPubKeys, uint64(len(prevOuts[i].ScriptPubKey)))
+			scriptPubKeys.Write(prevOuts[i].ScriptPubKey)
+			binary.Write(&sequences, binary.LittleEndian, input.Sequence)
+		}
+		for _, data := range []*bytes.Buffer{&prevouts, &amounts, &scriptPubKeys, &sequences} {
+			hash := sha256.Sum256(data.Bytes())
+			sigMsg.Write(hash[:])
+		}
+	}
+	//Commit to all outputs unless signing with SIGHASH_NONE or SIGHASH_SINGLE
+	if baseType != SigHashNone && baseType != SigHashSingle {
+		var outputs bytes.Buffer
+		for _, output := range tx.Outputs {
+			writeOutput(&outputs, output)
+		}
+		hash := sha256.Sum256(outputs.Bytes())
+		sigMsg.Write(hash[:])
+	}
+	sigMsg.WriteByte(0x00) //spend_type, a key path spend without annex
+	if hashType.anyOneCanPay() {
+		input := tx.Inputs[inputIndex]
+		err := writeOutPoint(&sigMsg, input)
+		if err != nil {
+			return nil, err
+		}
+		writeOutput(&sigMsg, prevOuts[inputIndex])
+		binary.Write(&sigMsg, binary.LittleEndian, input.Sequence)
+	} else {
+		binary.Write(&sigMsg, binary.LittleEndian, uint32(inputIndex))
+	}
+	if baseType == SigHashSingle {
+		var output bytes.Buffer
+		writeOutput(&output, tx.Outputs[inputIndex])
+		hash := sha256.Sum256(output.Bytes())
+		sigMsg.Write(hash[:])
+	}
+
+	return taggedHash("TapSighash", sigMsg.Bytes()), nil
+}
@@ -0,0 +1,188 @@
+package btcutils
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/testutils"
+
+	"encoding/hex"
+	"testing"
+)
+
+func TestTaprootTweakKey(t *testing.T) {
+	testCases := []struct {
+		internalKey string
+		merkleRoot  string
+		outputKey   string
+		parity      bool
+		address     string
+	}{
+		//BIP341 wallet test vectors, without and with a script tree
+		{"d6889cb081036e0faefa3a35157ad71086b123b2b144b649798b494c300a961d", "", "53a1f6e454df1aa2776a2814a721372d6258050de330b3c6d10ee8f4e0dda343", true, "bc1p2wsldez5mud2yam29q22wgfh9439spgduvct83k3pm50fcxa5dps59h4z5"},
+		{"187791b6f712a8ea41c8ecdd0ee77fab3e85263b37e1ec18a3651926b3a6cf27", "5b75adecf53548f3ec6ad7d78383bf84cc57b55a3127c72b9a2481752dd88b21", "147c9c57132f6e7ecddba9800bb0c4449251c92a1e60371ee77557b6620f3ea3", true, "bc1pz37fc4cn9ah8anwm4xqqhvxygjf9rjf2resrw8h8w4tmvcs0863sa2e586"},
+		//BIP86 first receiving address
+		{"cc8a4bc64d897bddc5fbc2f670f7a8ba0b386779106cf1223c6fc5d7cd6fc115", "", "a60869f0dbcf1dc659c9cecbaf8050135ea9e8cdc487053f1dc6880949dc684c", true, "bc1p5cyxnuxmeuwuvkwfem96lqzszd02n6xdcjrs20cac6yqjjwudpxqkedrcr"},
+		//Public key of BIP340 test vector 0, whose output key has an even y
+		{"f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9", "", "418c46636d9e1a683f58e35b42336e776fdcc3b2d4e39e7a0bf1ab0716e3c5fa", false, "bc1pgxxyvcmdncdxs06cudd5yvmwwahaesaj6n3eu7st7x4sw9hrchaqjy33gs"},
+	}
+	for _, testCase := range testCases {
+		internalKey, _ := hex.DecodeString(testCase.internalKey)
+		merkleRoot, _ := hex.DecodeString(testCase.merkleRoot)
+		outputKey, parity, err := TaprootTweakKey(internalKey, merkleRoot)
+		if err != nil {
+			t.Error(err)
+		}
+		outputKeyHex := hex.EncodeToString(outputKey)
+		if outputKeyHex != testCase.outputKey {
+			testutils.CompareError(t, "Taproot output key different from expected key.", testCase.outputKey, outputKeyHex)
+		}
+		if parity != testCase.parity {
+			testutils.CompareError(t, "Taproot output key parity different from expected parity.", testCase.parity, parity)
+		}
+		if outputKey, _ := TweakPublicKey(internalKey, merkleRoot); hex.EncodeToString(outputKey) != testCase.outputKey {
+			testutils.CompareError(t, "TweakPublicKey output key different from expected key.", testCase.outputKey, hex.EncodeToString(outputKey))
+		}
+		scriptPubKey, err := CreateP2TRScriptPubKey(outputKey)
+		if err != nil {
+			t.Error(err)
+		}
+		scriptPubKeyHex := hex.EncodeToString(scriptPubKey)
+		if scriptPubKeyHex != "5120"+testCase.outputKey {
+			testutils.CompareError(t, "P2TR scriptPubKey different from expected scriptPubKey.", "5120"+testCase.outputKey, scriptPubKeyHex)
+		}
+		address, err := Bech32Encode(MainNetParams.Bech32HRP, 1, outputKey)
+		if err != nil {
+			t.Error(err)
+		}
+		if address != testCase.address {
+			testutils.CompareError(t, "P2TR address different from expected address.", testCase.address, address)
+		}
+	}
+
+	if _, _, err := TaprootTweakKey(make([]byte, 31), nil); err == nil {
+		t.Error("TaprootTweakKey accepting 31 byte internal key.")
+	}
+	//x coordinate with no point on the curve
+	notOnCurve, _ := hex.DecodeString("eefdea4cdb677750a420fee807eacf21eb9898ae79b9768766e4faa04a2d4a34")
+	if _, _, err := TaprootTweakKey(notOnCurve, nil); err == nil {
+		t.Error("TaprootTweakKey accepting x coordinate not on the curve.")
+	}
+	internalKey, _ := hex.DecodeString(testCases[0].internalKey)
+	if _, _, err := TaprootTweakKey(internalKey, make([]byte, 31)); err == nil {
+		t.Error("TaprootTweakKey accepting 31 byte merkle root.")
+	}
+	if _, err := CreateP2TRScriptPubKey(make([]byte, 33)); err == nil {
+		t.Error("CreateP2TRScriptPubKey accepting 33 byte output key.")
+	}
+}
+
+func TestTaprootTweakPrivateKey(t *testing.T) {
+	//BIP341 wallet test vector for key path spending
+	testPrivateKey, _ := hex.DecodeString("6b973d88838f27366ed61c9ad6367663045cb456e28335c109e30717ae0c6baa")
+	testTweakedPrivateKeyHex := "2405b971772ad26915c8dcdf10f238753a9b837e5f8e6a86fd7c0cce5b7296d9"
+	tweakedPrivateKey, err := TaprootTweakPrivateKey(testPrivateKey, nil)
+	if err != nil {
+		t.Error(err)
+	}
+	tweakedPrivateKeyHex := hex.EncodeToString(tweakedPrivateKey)
+	if tweakedPrivateKeyHex != testTweakedPrivateKeyHex {
+		testutils.CompareError(t, "Tweaked private key different from expected key.", testTweakedPrivateKeyHex, tweakedPrivateKeyHex)
+	}
+
+	//The tweaked private key is the private key of the tweaked public key, whatever the parity of the internal key
+	testPrivateKeys := []string{
+		"6b973d88838f27366ed61c9ad6367663045cb456e28335c109e30717ae0c6baa",
+		"0000000000000000000000000000000000000000000000000000000000000003",
+		"412738f0e9f2b776748136ae6a297f7eda80112a47d865c5df7612174a5bac45",
+	}
+	testMerkleRoot, _ := hex.DecodeString("5b75adecf53548f3ec6ad7d78383bf84cc57b55a3127c72b9a2481752dd88b21")
+	for _, testPrivateKeyHex := range testPrivateKeys {
+		for _, merkleRoot := range [][]byte{nil, testMerkleRoot} {
+			privateKey, _ := hex.DecodeString(testPrivateKeyHex)
+			publicKey, err := NewCompressedPublicKey(privateKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			outputKey, err := TweakPublicKey(publicKey[1:], merkleRoot)
+			if err != nil {
+				t.Error(err)
+			}
+			tweakedPrivateKey, err := TaprootTweakPrivateKey(privateKey, merkleRoot)
+			if err != nil {
+				t.Error(err)
+			}
+			tweakedPublicKey, err := NewCompressedPublicKey(tweakedPrivateKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if hex.EncodeToString(tweakedPublicKey[1:]) != hex.EncodeToString(outputKey) {
+				testutils.CompareError(t, "Public key of tweaked private key different from tweaked public key.", outputKey, tweakedPublicKey[1:])
+			}
+		}
+	}
+}
+
+func TestCalcTaprootSigHash(t *testing.T) {
+	//Expected hashes computed with an independent implementation of the BIP341 signature message
+	outputScript0, _ := hex.DecodeString("a9141a8b0026343166625c7475f01e48b5ede8c0252e87")
+	outputScript1, _ := hex.DecodeString("512053a1f6e454df1aa2776a2814a721372d6258050de330b3c6d10ee8f4e0dda343")
+	prevOutScript1, _ := hex.DecodeString("0014751e76e8199196d454941c45d1b3a323f1433bd6")
+	tx := &Transaction{
+		Version: 2,
+		Inputs: []Input{
+			{TxHash: "3ad337270ac0ba14fbce812291b7d95338c878709ea8123a4d88c3c29efbc6ac", OutputIndex: 0, Sequence: SequenceFinal},
+			{TxHash: "02b082113e35d5386285094c2829e7e2963fa0b5369fb7f4b79c4c90877dcd3d", OutputIndex: 1, Sequence: 0xfffffffd},
+		},
+		Outputs: []Output{
+			{Satoshis: 65600, ScriptPubKey: outputScript0},
+			{Satoshis: 55600, ScriptPubKey: outputScript1},
+		},
+		LockTime: 500000,
+	}
+	prevOuts := []Output{
+		{Satoshis: 100000, ScriptPubKey: outputScript1},
+		{Satoshis: 30000, ScriptPubKey: prevOutScript1},
+	}
+
+	testCases := []struct {
+		name           string
+		inputIndex     int
+		hashType       SigHashType
+		testSigHashHex string
+	}{
+		{"DEFAULT", 0, SigHashDefault, "5a1b002dcea2c85d9de772495c0a1da8fa66fcd0def501c84a7c5c93bc634336"},
+		{"DEFAULT second input", 1, SigHashDefault, "4618e69ea3adae6b810ad52b569beb9c96b3979d2d3a3f4551aa9426988e0dd3"},
+		{"ALL", 0, SigHashAll, "9245d466f0eb6ad9c1e48c7c7ee2b9b2195fbde2abc47d79e77cc9f44cfa6ac3"},
+		{"NONE", 0, SigHashNone, "a1772708964c46136b96daa8e3dc3838603b87f3dddaeec1ee84fe946f2bdcee"},
+		{"SINGLE", 1, SigHashSingle, "7363bbf414c8784d9c506d70f8e47f8f4424c7056ff263e24fb69213b8cccaa8"},
+		{"ALL|ANYONECANPAY", 0, SigHashAll | SigHashAnyOneCanPay, "fa2923c98bb7ef7978a4b1a7d6803023c07f9cf28e7e6f5090c8ffc5558fcfcc"},
+		{"NONE|ANYONECANPAY", 1, SigHashNone | SigHashAnyOneCanPay, "a5401f6f968b4069435296e66bd87c7b30b61bc9577fe03fbe143528458b3d0d"},
+		{"SINGLE|ANYONECANPAY", 1, SigHashSingle | SigHashAnyOneCanPay, "80a35c51b186a78f77c945dfaeec63b4e0d152878320d5a9b3443d77595c762c"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			sigHash, err := CalcTaprootSigHash(tx, testCase.inputIndex, prevOuts, testCase.hashType)
+			if err != nil {
+				t.Error(err)
+			}
+			sigHashHex := hex.EncodeToString(sigHash)
+			if sigHashHex != testCase.testSigHashHex {
+				testutils.CompareError(t, "BIP341 signature hash different from expected hash.", testCase.testSigHashHex, sigHashHex)
+			}
+		})
+	}
+
+	if _, err := CalcTaprootSigHash(tx, 2, prevOuts, SigHashDefault); err == nil {
+		t.Error("CalcTaprootSigHash accepting out of range input index.")
+	}
+	if _, err := CalcTaprootSigHash(tx, 0, prevOuts[:1], SigHashDefault); err == nil {
+		t.Error("CalcTaprootSigHash accepting missing spent output.")
+	}
+	for _, hashType := range []SigHashType{0x04, 0x80, 0x84, 0x41} {
+		if _, err := CalcTaprootSigHash(tx, 0, prevOuts, hashType); err == nil {
+			t.Errorf("CalcTaprootSigHash accepting invalid hash type 0x%02x.", uint32(hashType))
+		}
+	}
+	tx.Outputs = tx.Outputs[:1]
+	if _, err := CalcTaprootSigHash(tx, 1, prevOuts, SigHashSingle); err == nil {
+		t.Error("CalcTaprootSigHash accepting SIGHASH_SINGLE without matching output.")
+	}
+}
@@ -0,0 +1,158 @@
+// Tapscript (BIP342) leaf scripts of Taproot script trees, and the BIP341 control blocks that prove a leaf script
+// is committed to by a Taproot output key.
+// See https://github.com/bitcoin/bips/blob/master/bip-0342.mediawiki for full specification.
+package btcutils
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// TapscriptLeafVersion is the BIP341 leaf version of BIP342 tapscript leaves.
+const TapscriptLeafVersion = 0xc0
+
+// MaxTapscriptMultiSigKeys is the most public keys a tapscript multisig can have, as spending it puts a signature or
+// empty item for each key on the stack, which is limited to 1000 items together with the script.
+const MaxTapscriptMultiSigKeys = 999
+
+// taprootMaxMerklePathLength is the deepest a leaf can be in a Taproot script tree.
+const taprootMaxMerklePathLength = 128
+
+// UnspendableInternalKey is the x-only public key H from BIP341, lift_x(SHA256(G)), which nobody knows the private
+// key of. Using it as the internal key disables the key path, so the output can only be spent with its scripts.
+var UnspendableInternalKey, _ = hex.DecodeString("50929b74c1a04954b78b4b6035e97a5e078a5a0f28ec96d547bfee9ace803ac0")
+
+// XOnlyPublicKey returns the 32 byte BIP340 x-only form of a compressed or uncompressed public key, its x coordinate.
+// Both public keys with the same x coordinate have the same x-only key.
+func XOnlyPublicKey(publicKey []byte) ([]byte, error) {
+	point, err := parsePublicKeyPoint(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	return int2octets(point.x, 32), nil
+}
+
+// NewMOfNTapscript creates a M-of-N Multisig tapscript leaf given m, n and n public keys
+func NewMOfNTapscript(m int, n int, publicKeys [][]byte) ([]byte, error) {
+	if len(publicKeys) != n {
+		return nil, fmt.Errorf("Need exactly %d public keys to create Taproot address for %d-of-%d multisig transaction. Only %d keys provided.", n, m, n, len(publicKeys))
+	}
+	return CreateMultiSigTapscript(m, publicKeys)
+}
+
+// CreateMultiSigTapscript creates the BIP342 tapscript leaf of an M-of-N multisig, m the number of signatures
+// needed and publicKeys the N compressed or uncompressed public keys, in the order given, in the form
+// <pubkey1> OP_CHECKSIG <pubkey2> OP_CHECKSIGADD ... <pubkeyN> OP_CHECKSIGADD <m> OP_NUMEQUAL.
+// Public keys are pushed in their 32 byte x-only form. Unlike OP_CHECKMULTISIG there is no limit of 20 keys, N may
+// be up to MaxTapscriptMultiSigKeys.
+func CreateMultiSigTapscript(m int, publicKeys [][]byte) ([]byte, error) {
+	n := len(publicKeys)
+	if n < 1 || n > MaxTapscriptMultiSigKeys {
+		return nil, fmt.Errorf("N must be between 1 and %d. Provided N is %d.", MaxTapscriptMultiSigKeys, n)
+	}
+	if m < 1 || m > n {
+		return nil, fmt.Errorf("M must be between 1 and N (%d). Provided M is %d.", n, m)
+	}
+	var script bytes.Buffer
+	for i, publicKey := range publicKeys {
+		xOnlyPublicKey, err := XOnlyPublicKey(publicKey)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid public key %d. %s", i+1, err)
+		}
+		script.WriteByte(byte(len(xOnlyPublicKey)))
+		script.Write(xOnlyPublicKey)
+		if i == 0 {
+			script.WriteByte(OP_CHECKSIG)
+		} else {
+			script.WriteByte(OP_CHECKSIGADD)
+		}
+	}
+	writeScriptNumber(&script, int64(m))
+	script.WriteByte(OP_NUMEQUAL)
+	return script.Bytes(), nil
+}
+
+// TapLeafHash returns the BIP341 hash of a tapscript leaf, H_TapLeaf(leaf version || compact size || script).
+// For a script tree with a single leaf it is the merkle root the internal key is tweaked with.
+func TapLeafHash(script []byte) []byte {
+	return TapLeafVersionHash(TapscriptLeafVersion, script)
+}
+
+// TapLeafVersionHash returns the BIP341 hash of a leaf script with any leafVersion, such as the versions future
+// soft forks may give meaning to. TapLeafHash is the hash of a tapscript leaf.
+func TapLeafVersionHash(leafVersion byte, script []byte) []byte {
+	var leaf bytes.Buffer
+	leaf.WriteByte(leafVersion)
+	WriteVarInt(&leaf, uint64(len(script)))
+	leaf.Write(script)
+	return taggedHash("TapLeaf", leaf.Bytes())
+}
+
+// TapBranchHash returns the BIP341 hash of a script tree branch with children hashes a and b,
+// H_TapBranch of the two hashes in lexicographic order, so the order of the children does not matter.
+func TapBranchHash(a []byte, b []byte) []byte {
+	if bytes.Compare(a, b) > 0 {
+		a, b = b, a
+	}
+	return taggedHash("TapBranch", a, b)
+}
+
+// CreateTaprootControlBlock creates the BIP341 control block needed to spend the Taproot output of internalKey with
+// the tapscript leaf script. merklePath holds the hashes of the leaf's sibling at each level of the script tree from
+// the leaf up, and is empty for a tree with a single leaf. Returns the control block, leaf version and output key
+// parity followed by the internal key and merklePath, and the 32 byte x-only output key it commits to.
+func CreateTaprootControlBlock(internalKey []byte, script []byte, merklePath [][]byte) ([]byte, []byte, error) {
+	if len(merklePath) > taprootMaxMerklePathLength {
+		return nil, nil, fmt.Errorf("Taproot merkle path can have at most %d hashes. Provided path has %d hashes.", taprootMaxMerklePathLength, len(merklePath))
+	}
+	merkleRoot := TapLeafHash(script)
+	for _, hash := range merklePath {
+		if len(hash) != 32 {
+			return nil, nil, fmt.Errorf("Taproot merkle path hashes should be 32 bytes long. Provided hash is %d bytes long.", len(hash))
+		}
+		merkleRoot = TapBranchHash(merkleRoot, hash)
+	}
+	outputKeyPoint, err := tweakPublicKeyPoint(internalKey, merkleRoot)
+	if err != nil {
+		return nil, nil, err
+	}
+	controlBlock := []byte{TapscriptLeafVersion | byte(outputKeyPoint.y.Bit(0))}
+	controlBlock = append(controlBlock, internalKey...)
+	for _, hash := range merklePath {
+		controlBlock = append(controlBlock, hash...)
+	}
+	return controlBlock, int2octets(outputKeyPoint.x, 32), nil
+}
+
+// VerifyTaprootControlBlock checks that controlBlock proves the tapscript leaf script is committed to by the 32 byte
+// x-only Taproot outputKey, as BIP341 script path spends are validated: the internal key tweaked with the merkle
+// root rebuilt from the leaf and the merkle path must give the output key, with the parity given by the control
+// block. Returns an error with a helpful message or nil if the control block is valid.
+func VerifyTaprootControlBlock(outputKey []byte, script []byte, controlBlock []byte) error {
+	if len(outputKey) != 32 {
+		return fmt.Errorf("Taproot output key should be 32 bytes long. Provided key is %d bytes long.", len(outputKey))
+	}
+	if len(controlBlock) < 33 || (len(controlBlock)-33)%32 != 0 || len(controlBlock) > 33+32*taprootMaxMerklePathLength {
+		return fmt.Errorf("Control block should be 33 bytes long plus 32 bytes for each merkle path hash, up to %d hashes. Provided control block is %d bytes long.", taprootMaxMerklePathLength, len(controlBlock))
+	}
+	if controlBlock[0]&0xfe != TapscriptLeafVersion {
+		return fmt.Errorf("Control block leaf version 0x%02x is not the tapscript leaf version 0x%02x.", controlBlock[0]&0xfe, TapscriptLeafVersion)
+	}
+	merkleRoot := TapLeafHash(script)
+	for i := 33; i < len(controlBlock); i += 32 {
+		merkleRoot = TapBranchHash(merkleRoot, controlBlock[i:i+32])
+	}
+	outputKeyPoint, err := tweakPublicKeyPoint(controlBlock[1:33], merkleRoot)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(int2octets(outputKeyPoint.x, 32), outputKey) {
+		return errors.New("Control block does not commit to the leaf script with the output key.")
+	}
+	if outputKeyPoint.y.Bit(0) != uint(controlBlock[0]&0x01) {
+		return errors.New("Control block output key parity does not match the output key.")
+	}
+	return nil
+}
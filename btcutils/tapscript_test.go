@@ -0,0 +1,160 @@
+package btcutils
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/testutils"
+
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestXOnlyPublicKey(t *testing.T) {
+	//Compressed and uncompressed public keys of private key 1, the generator point
+	testPublicKeyHexes := []string{
+		"0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798",
+		"0479be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8",
+	}
+	testXOnlyPublicKeyHex := "79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"
+	for _, testPublicKeyHex := range testPublicKeyHexes {
+		testPublicKey, _ := hex.DecodeString(testPublicKeyHex)
+		xOnlyPublicKey, err := XOnlyPublicKey(testPublicKey)
+		if err != nil {
+			t.Error(err)
+		}
+		xOnlyPublicKeyHex := hex.EncodeToString(xOnlyPublicKey)
+		if xOnlyPublicKeyHex != testXOnlyPublicKeyHex {
+			testutils.CompareError(t, "x-only public key different from expected key.", testXOnlyPublicKeyHex, xOnlyPublicKeyHex)
+		}
+	}
+	testXOnlyPublicKey, _ := hex.DecodeString(testXOnlyPublicKeyHex)
+	if _, err := XOnlyPublicKey(testXOnlyPublicKey); err == nil {
+		t.Error("XOnlyPublicKey accepting 32 byte public key.")
+	}
+}
+
+func TestCreateMultiSigTapscript(t *testing.T) {
+	testPublicKeyHexes := []string{
+		"025476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee6357",
+		"0331393af9984375830971ab5d3094c6a7d02db3568b2b06212a7090094549701b",
+		"0479be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8",
+	}
+	testPublicKeys := make([][]byte, len(testPublicKeyHexes))
+	for i, testPublicKeyHex := range testPublicKeyHexes {
+		testPublicKeys[i], _ = hex.DecodeString(testPublicKeyHex)
+	}
+	//<pubkey1> OP_CHECKSIG <pubkey2> OP_CHECKSIGADD <pubkey3> OP_CHECKSIGADD OP_2 OP_NUMEQUAL
+	testScriptHex := "20" + "5476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee6357" + "ac" +
+		"20" + "31393af9984375830971ab5d3094c6a7d02db3568b2b06212a7090094549701b" + "ba" +
+		"20" + "79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798" + "ba" +
+		"52" + "9c"
+
+	script, err := CreateMultiSigTapscript(2, testPublicKeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scriptHex := hex.EncodeToString(script)
+	if scriptHex != testScriptHex {
+		testutils.CompareError(t, "Tapscript multisig script different from expected script.", testScriptHex, scriptHex)
+	}
+
+	//More than 16 signatures are pushed as a script number, and more than 20 keys are allowed
+	manyPublicKeys := make([][]byte, 21)
+	for i := range manyPublicKeys {
+		manyPublicKeys[i] = testPublicKeys[i%len(testPublicKeys)]
+	}
+	script, err = CreateMultiSigTapscript(17, manyPublicKeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(hex.EncodeToString(script), "ba01119c") {
+		testutils.CompareError(t, "Tapscript multisig script with 17 signatures has unexpected ending.", "...ba01119c", hex.EncodeToString(script))
+	}
+
+	if _, err := CreateMultiSigTapscript(0, testPublicKeys); err == nil {
+		t.Error("CreateMultiSigTapscript accepting M of 0.")
+	}
+	if _, err := CreateMultiSigTapscript(4, testPublicKeys); err == nil {
+		t.Error("CreateMultiSigTapscript accepting M larger than N.")
+	}
+	if _, err := CreateMultiSigTapscript(1, make([][]byte, MaxTapscriptMultiSigKeys+1)); err == nil {
+		t.Error("CreateMultiSigTapscript accepting more than MaxTapscriptMultiSigKeys public keys.")
+	}
+	if _, err := CreateMultiSigTapscript(1, [][]byte{testPublicKeys[0][1:]}); err == nil {
+		t.Error("CreateMultiSigTapscript accepting invalid public key.")
+	}
+}
+
+func TestCreateTaprootControlBlock(t *testing.T) {
+	//BIP341 wallet test vector with a single leaf script
+	testInternalKey, _ := hex.DecodeString("187791b6f712a8ea41c8ecdd0ee77fab3e85263b37e1ec18a3651926b3a6cf27")
+	testScript, _ := hex.DecodeString("20d85a959b0290bf19bb89ed43c916be835475d013da4b362117393e25a48229b8ac")
+	testLeafHashHex := "5b75adecf53548f3ec6ad7d78383bf84cc57b55a3127c72b9a2481752dd88b21"
+	testControlBlockHex := "c1187791b6f712a8ea41c8ecdd0ee77fab3e85263b37e1ec18a3651926b3a6cf27"
+	testOutputKeyHex := "147c9c57132f6e7ecddba9800bb0c4449251c92a1e60371ee77557b6620f3ea3"
+
+	leafHashHex := hex.EncodeToString(TapLeafHash(testScript))
+	if leafHashHex != testLeafHashHex {
+		testutils.CompareError(t, "Tapscript leaf hash different from expected hash.", testLeafHashHex, leafHashHex)
+	}
+	controlBlock, outputKey, err := CreateTaprootControlBlock(testInternalKey, testScript, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	controlBlockHex := hex.EncodeToString(controlBlock)
+	if controlBlockHex != testControlBlockHex {
+		testutils.CompareError(t, "Control block different from expected control block.", testControlBlockHex, controlBlockHex)
+	}
+	outputKeyHex := hex.EncodeToString(outputKey)
+	if outputKeyHex != testOutputKeyHex {
+		testutils.CompareError(t, "Taproot output key different from expected key.", testOutputKeyHex, outputKeyHex)
+	}
+	err = VerifyTaprootControlBlock(outputKey, testScript, controlBlock)
+	if err != nil {
+		t.Error(err)
+	}
+
+	//Two leaf tree, each leaf proven with the hash of the other, committing to the same output key
+	otherScript, _ := CreateMultiSigTapscript(1, [][]byte{append([]byte{0x02}, testInternalKey...)})
+	treeOutputKey, err := TweakPublicKey(testInternalKey, TapBranchHash(TapLeafHash(testScript), TapLeafHash(otherScript)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, leaves := range [][2][]byte{{testScript, otherScript}, {otherScript, testScript}} {
+		controlBlock, outputKey, err := CreateTaprootControlBlock(testInternalKey, leaves[0], [][]byte{TapLeafHash(leaves[1])})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(outputKey, treeOutputKey) {
+			testutils.CompareError(t, "Output key of script tree leaf different from expected key.", hex.EncodeToString(treeOutputKey), hex.EncodeToString(outputKey))
+		}
+		err = VerifyTaprootControlBlock(treeOutputKey, leaves[0], controlBlock)
+		if err != nil {
+			t.Error(err)
+		}
+		if err := VerifyTaprootControlBlock(treeOutputKey, leaves[1], controlBlock); err == nil {
+			t.Error("VerifyTaprootControlBlock accepting control block for another leaf.")
+		}
+	}
+
+	invalidControlBlocks := map[string][]byte{
+		"wrong parity":           append([]byte{0xc0}, controlBlock[1:]...),
+		"wrong leaf version":     append([]byte{0xc2}, controlBlock[1:]...),
+		"wrong internal key":     append([]byte{0xc1}, UnspendableInternalKey...),
+		"truncated":              controlBlock[:32],
+		"partial path hash":      append(append([]byte{}, controlBlock...), make([]byte, 31)...),
+		"unexpected path":        append(append([]byte{}, controlBlock...), make([]byte, 32)...),
+		"internal key off curve": append([]byte{0xc1}, bytes.Repeat([]byte{0xff}, 32)...),
+	}
+	for name, invalidControlBlock := range invalidControlBlocks {
+		if err := VerifyTaprootControlBlock(outputKey, testScript, invalidControlBlock); err == nil {
+			t.Errorf("VerifyTaprootControlBlock accepting control block with %s.", name)
+		}
+	}
+	if _, _, err := CreateTaprootControlBlock(testInternalKey, testScript, [][]byte{make([]byte, 31)}); err == nil {
+		t.Error("CreateTaprootControlBlock accepting 31 byte merkle path hash.")
+	}
+	if _, _, err := CreateTaprootControlBlock(testInternalKey[1:], testScript, nil); err == nil {
+		t.Error("CreateTaprootControlBlock accepting 31 byte internal key.")
+	}
+}
@@ -0,0 +1,330 @@
+// Bitcoin transaction model and its wire serialization, including the BIP141 extended serialization format
+// used when any input carries witness data.
+package btcutils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Transaction is a Bitcoin transaction made up of its inputs and outputs, version and lock time.
+type Transaction struct {
+	Version  uint32
+	Inputs   []Input
+	Outputs  []Output
+	LockTime uint32
+}
+
+// HasWitness reports whether any input of the transaction carries witness data, in which case the
+// transaction is serialized in the BIP141 extended format.
+func (tx *Transaction) HasWitness() bool {
+	for _, input := range tx.Inputs {
+		if len(input.Witness) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Serialize encodes the transaction in Bitcoin wire format. Transactions with witness data are encoded
+// as <version> <marker 0x00> <flag 0x01> <inputs> <outputs> <witnesses> <lock time> as per BIP141.
+func (tx *Transaction) Serialize() ([]byte, error) {
+	if len(tx.Inputs) == 0 {
+		return nil, errors.New("Transaction must have at least one input.")
+	}
+	if len(tx.Outputs) == 0 {
+		return nil, errors.New("Transaction must have at least one output.")
+	}
+	return tx.serialize(tx.HasWitness())
+}
+
+// serialize encodes the transaction in Bitcoin wire format, in the BIP141 extended format if hasWitness is set.
+// Unlike Serialize, transactions without inputs or outputs are allowed, as needed for signature hash preimages.
+func (tx *Transaction) serialize(hasWitness bool) ([]byte, error) {
+	var buffer bytes.Buffer
+	binary.Write(&buffer, binary.LittleEndian, tx.Version)
+	if hasWitness {
+		buffer.WriteByte(0x00) //marker
+		buffer.WriteByte(0x01) //flag
+	}
+	WriteVarInt(&buffer, uint64(len(tx.Inputs))) //# of inputs
+	for _, input := range tx.Inputs {
+		err := writeOutPoint(&buffer, input)
+		if err != nil {
+			return nil, err
+		}
+		//scriptSig length. To allow scriptSig > 255 bytes, we use variable length integer syntax from protocol spec
+		WriteVarInt(&buffer, uint64(len(input.ScriptSig)))
+		buffer.Write(input.ScriptSig)
+		//sequence_no. Normally 0xFFFFFFFF.
+		binary.Write(&buffer, binary.LittleEndian, input.Sequence)
+	}
+	WriteVarInt(&buffer, uint64(len(tx.Outputs))) //# of outputs
+	for _, output := range tx.Outputs {
+		writeOutput(&buffer, output)
+	}
+	if hasWitness {
+		//One witness stack per input, empty stacks are written as a zero item count
+		for _, input := range tx.Inputs {
+			WriteVarInt(&buffer, uint64(len(input.Witness)))
+			for _, item := range input.Witness {
+				WriteVarInt(&buffer, uint64(len(item)))
+				buffer.Write(item)
+			}
+		}
+	}
+	binary.Write(&buffer, binary.LittleEndian, tx.LockTime)
+
+	return buffer.Bytes(), nil
+}
+
+// DeserializeTransaction decodes a transaction in Bitcoin wire format, in either the legacy or the BIP141 extended
+// format. The whole of data must be a single transaction, trailing bytes are rejected.
+func DeserializeTransaction(data []byte) (*Transaction, error) {
+	r := bytes.NewReader(data)
+	tx, err := readTransaction(r)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to deserialize transaction. %s", err)
+	}
+	if r.Len() != 0 {
+		return nil, fmt.Errorf("Failed to deserialize transaction. %d unexpected bytes after the transaction at byte %d.", r.Len(), offset(r))
+	}
+	return tx, nil
+}
+
+// TxID returns the ID of a serialized transaction, the double SHA256 of its serialization without witness data, in
+// the big-endian hex form displayed by block explorers and Bitcoin Core. The TxID of a SegWit transaction does not
+// change when its witnesses do.
+func TxID(rawTx []byte) (string, error) {
+	tx, err := DeserializeTransaction(rawTx)
+	if err != nil {
+		return "", err
+	}
+	stripped, err := tx.serialize(false)
+	if err != nil {
+		return "", err
+	}
+	return hashToHex(DoubleSHA256(stripped)), nil
+}
+
+// WTxID returns the BIP141 witness ID of a serialized transaction, the double SHA256 of its full serialization
+// including witness data, in big-endian hex form. For transactions without witness data it is equal to the TxID.
+func WTxID(rawTx []byte) (string, error) {
+	_, err := DeserializeTransaction(rawTx)
+	if err != nil {
+		return "", err
+	}
+	return hashToHex(DoubleSHA256(rawTx)), nil
+}
+
+// hashToHex encodes a hash in the reversed, big-endian hex form used to display transaction hashes.
+func hashToHex(hash []byte) string {
+	reversed := make([]byte, len(hash))
+	for i := range hash {
+		reversed[i] = hash[len(hash)-i-1]
+	}
+	return hex.EncodeToString(reversed)
+}
+
+// readTransaction reads a transaction from r. A zero input count followed by a 0x01 flag byte is the BIP141 marker
+// and flag rather than an empty input list, as transactions without inputs are invalid. Like Serialize, it refuses
+// transactions without outputs, so every transaction read can be serialized again. Errors name the field that could
+// not be read and the byte offset it starts at.
+func readTransaction(r *bytes.Reader) (*Transaction, error) {
+	tx := &Transaction{}
+	if err := binary.Read(r, binary.LittleEndian, &tx.Version); err != nil {
+		return nil, fieldError("version", 0, err)
+	}
+	start := offset(r)
+	inputCount, err := readCount(r)
+	if err != nil {
+		return nil, fieldError("input count", start, err)
+	}
+	hasWitness := false
+	if inputCount == 0 {
+		start = offset(r)
+		flag, err := r.ReadByte()
+		if err != nil {
+			return nil, fieldError("flag", start, err)
+		}
+		if flag != 0x01 {
+			return nil, fmt.Errorf("Unknown transaction serialization flag 0x%02x at byte %d.", flag, start)
+		}
+		hasWitness = true
+		start = offset(r)
+		if inputCount, err = readCount(r); err != nil {
+			return nil, fieldError("input count", start, err)
+		}
+	}
+	tx.Inputs = make([]Input, inputCount)
+	for i := range tx.Inputs {
+		if err := readInput(r, i, &tx.Inputs[i]); err != nil {
+			return nil, err
+		}
+	}
+	start = offset(r)
+	outputCount, err := readCount(r)
+	if err != nil {
+		return nil, fieldError("output count", start, err)
+	}
+	if outputCount == 0 {
+		return nil, fmt.Errorf("Transaction must have at least one output. Output count at byte %d is zero.", start)
+	}
+	tx.Outputs = make([]Output, outputCount)
+	for i := range tx.Outputs {
+		if tx.Outputs[i], err = readOutput(r, i); err != nil {
+			return nil, err
+		}
+	}
+	if hasWitness {
+		for i := range tx.Inputs {
+			start = offset(r)
+			itemCount, err := readCount(r)
+			if err != nil {
+				return nil, fieldError(fmt.Sprintf("input %d witness item count", i), start, err)
+			}
+			tx.Inputs[i].Witness = make([][]byte, itemCount)
+			for j := range tx.Inputs[i].Witness {
+				start = offset(r)
+				if tx.Inputs[i].Witness[j], err = readBytes(r); err != nil {
+					return nil, fieldError(fmt.Sprintf("input %d witness item %d", i, j), start, err)
+				}
+			}
+		}
+		if !tx.HasWitness() {
+			return nil, errors.New("Transaction in extended format has no witness data.")
+		}
+	}
+	start = offset(r)
+	if err := binary.Read(r, binary.LittleEndian, &tx.LockTime); err != nil {
+		return nil, fieldError("lock time", start, err)
+	}
+	return tx, nil
+}
+
+// readInput reads input index as its outpoint, length prefixed scriptSig and sequence number.
+func readInput(r *bytes.Reader, index int, input *Input) error {
+	start := offset(r)
+	inputTxBytes := make([]byte, 32)
+	if _, err := io.ReadFull(r, inputTxBytes); err != nil {
+		return fieldError(fmt.Sprintf("input %d transaction hash", index), start, err)
+	}
+	//Convert input transaction hash back to big-endian form
+	for i, j := 0, len(inputTxBytes)-1; i < j; i, j = i+1, j-1 {
+		inputTxBytes[i], inputTxBytes[j] = inputTxBytes[j], inputTxBytes[i]
+	}
+	input.TxHash = hex.EncodeToString(inputTxBytes)
+	start = offset(r)
+	if err := binary.Read(r, binary.LittleEndian, &input.OutputIndex); err != nil {
+		return fieldError(fmt.Sprintf("input %d output index", index), start, err)
+	}
+	start = offset(r)
+	scriptSig, err := readBytes(r)
+	if err != nil {
+		return fieldError(fmt.Sprintf("input %d scriptSig", index), start, err)
+	}
+	input.ScriptSig = scriptSig
+	start = offset(r)
+	if err := binary.Read(r, binary.LittleEndian, &input.Sequence); err != nil {
+		return fieldError(fmt.Sprintf("input %d sequence", index), start, err)
+	}
+	return nil
+}
+
+// readOutput reads output index as its 8 byte satoshi value followed by the length prefixed scriptPubKey.
+func readOutput(r *bytes.Reader, index int) (Output, error) {
+	var output Output
+	start := offset(r)
+	if err := binary.Read(r, binary.LittleEndian, &output.Satoshis); err != nil {
+		return output, fieldError(fmt.Sprintf("output %d value", index), start, err)
+	}
+	start = offset(r)
+	scriptPubKey, err := readBytes(r)
+	if err != nil {
+		return output, fieldError(fmt.Sprintf("output %d scriptPubKey", index), start, err)
+	}
+	output.ScriptPubKey = scriptPubKey
+	return output, nil
+}
+
+// countError is the error of a count of more items than the bytes left, as a truncated transaction has.
+type countError struct {
+	count uint64
+	left  int
+}
+
+func (e countError) Error() string {
+	return fmt.Sprintf("Count %d is larger than the %d bytes left.", e.count, e.left)
+}
+
+// fieldError describes err reading field of a transaction, starting at byte offset start. Running out of bytes is
+// reported as the transaction being truncated, rather than the bare EOF binary.Read and io.ReadFull return.
+func fieldError(field string, start int, err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return fmt.Errorf("Transaction truncated in %s at byte %d.", field, start)
+	}
+	if _, ok := err.(countError); ok {
+		return fmt.Errorf("Transaction truncated in %s at byte %d. %s", field, start, err)
+	}
+	return fmt.Errorf("Invalid %s at byte %d. %s", field, start, err)
+}
+
+// offset returns the number of bytes already read from r.
+func offset(r *bytes.Reader) int {
+	return int(r.Size()) - r.Len()
+}
+
+// readCount reads a variable length integer count of items, each at least one byte long, so a count larger than
+// the bytes left can be rejected before allocating for it.
+func readCount(r *bytes.Reader) (int, error) {
+	count, err := ReadVarInt(r)
+	if err != nil {
+		return 0, err
+	}
+	if count > uint64(r.Len()) {
+		return 0, countError{count: count, left: r.Len()}
+	}
+	return int(count), nil
+}
+
+// readBytes reads a variable length integer length followed by that many bytes.
+func readBytes(r *bytes.Reader) ([]byte, error) {
+	length, err := readCount(r)
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, length)
+	_, err = io.ReadFull(r, data)
+	return data, err
+}
+
+// writeOutPoint writes the outpoint spent by an input, the little-endian input transaction hash followed
+// by the output index.
+func writeOutPoint(w io.Writer, input Input) error {
+	inputTxBytes, err := hex.DecodeString(input.TxHash)
+	if err != nil {
+		return err
+	}
+	if len(inputTxBytes) != 32 {
+		return fmt.Errorf("Input transaction hash should be 32 bytes long. Provided hash %s is %d bytes long.", input.TxHash, len(inputTxBytes))
+	}
+	//Convert input transaction hash to little-endian form
+	inputTxBytesReversed := make([]byte, len(inputTxBytes))
+	for i := 0; i < len(inputTxBytes); i++ {
+		inputTxBytesReversed[i] = inputTxBytes[len(inputTxBytes)-i-1]
+	}
+	w.Write(inputTxBytesReversed)
+	//Ouput index of input transaction
+	return binary.Write(w, binary.LittleEndian, input.OutputIndex)
+}
+
+// writeOutput writes an output as its 8 byte satoshi value followed by the length prefixed scriptPubKey.
+func writeOutput(w io.Writer, output Output) {
+	binary.Write(w, binary.LittleEndian, output.Satoshis)
+	WriteVarInt(w, uint64(len(output.ScriptPubKey)))
+	w.Write(output.ScriptPubKey)
+}
@@ -0,0 +1,269 @@
+package btcutils
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/testutils"
+
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestTransactionSerialize(t *testing.T) {
+	tx := bip143NativeP2WPKHTx()
+	testUnsignedHex := "0100000002fff7f7881a8099afa6940d42d1e7f6362bec38171ea3edf433541db4e4ad969f0000000000eeffffffef51e1b804cc89d182d279655c3aa89e815b1b309fe287d9b2b55d57b90ec68a0100000000ffffffff02202cb206000000001976a9148280b37df378db99f66f85c95a783a76ac7a6d5988ac9093510d000000001976a9143bde42dbee7e4dbe6a21b2d50ce2f0167faa815988ac11000000"
+
+	unsigned, err := tx.Serialize()
+	if err != nil {
+		t.Error(err)
+	}
+	unsignedHex := hex.EncodeToString(unsigned)
+	if unsignedHex != testUnsignedHex {
+		testutils.CompareError(t, "Serialized transaction different from expected transaction.", testUnsignedHex, unsignedHex)
+	}
+	if tx.HasWitness() {
+		t.Error("Transaction without witness data reporting witness data.")
+	}
+
+	//Signed BIP143 native P2WPKH example, serialized with marker, flag and witnesses
+	tx.Inputs[0].ScriptSig, _ = hex.DecodeString("4830450221008b9d1dc26ba6a9cb62127b02742fa9d754cd3bebf337f7a55d114c8e5cdd30be022040529b194ba3f9281a99f2b1c0a19c0489bc22ede944ccf4ecbab4cc618ef3ed01")
+	signature, _ := hex.DecodeString("304402203609e17b84f6a7d30c80bfa610b5b4542f32a8a0d5447a12fb1366d7f01cc44a0220573a954c4518331561406f90300e8f3358f51928d43c212a8caed02de67eebee01")
+	publicKey, _ := hex.DecodeString("025476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee6357")
+	tx.Inputs[1].Witness = [][]byte{signature, publicKey}
+	testSignedHex := "01000000000102fff7f7881a8099afa6940d42d1e7f6362bec38171ea3edf433541db4e4ad969f00000000494830450221008b9d1dc26ba6a9cb62127b02742fa9d754cd3bebf337f7a55d114c8e5cdd30be022040529b194ba3f9281a99f2b1c0a19c0489bc22ede944ccf4ecbab4cc618ef3ed01eeffffffef51e1b804cc89d182d279655c3aa89e815b1b309fe287d9b2b55d57b90ec68a0100000000ffffffff02202cb206000000001976a9148280b37df378db99f66f85c95a783a76ac7a6d5988ac9093510d000000001976a9143bde42dbee7e4dbe6a21b2d50ce2f0167faa815988ac000247304402203609e17b84f6a7d30c80bfa610b5b4542f32a8a0d5447a12fb1366d7f01cc44a0220573a954c4518331561406f90300e8f3358f51928d43c212a8caed02de67eebee0121025476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee635711000000"
+
+	signed, err := tx.Serialize()
+	if err != nil {
+		t.Error(err)
+	}
+	signedHex := hex.EncodeToString(signed)
+	if signedHex != testSignedHex {
+		testutils.CompareError(t, "Serialized witness transaction different from expected transaction.", testSignedHex, signedHex)
+	}
+}
+
+func TestTransactionSerializeLockTime(t *testing.T) {
+	testCases := []struct {
+		lockTime    uint32
+		lockTimeHex string
+	}{
+		{0, "00000000"},
+		{840000, "40d10c00"},     //Block height
+		{1735689600, "80857467"}, //Unix timestamp
+		{0xffffffff, "ffffffff"}, //Largest lock time
+	}
+	for _, testCase := range testCases {
+		tx := bip143NativeP2WPKHTx()
+		tx.LockTime = testCase.lockTime
+		serialized, err := tx.Serialize()
+		if err != nil {
+			t.Fatal(err)
+		}
+		//Lock time is the last 4 bytes, little-endian
+		lockTimeHex := hex.EncodeToString(serialized[len(serialized)-4:])
+		if lockTimeHex != testCase.lockTimeHex {
+			testutils.CompareError(t, "Serialized lock time different from expected lock time.", testCase.lockTimeHex, lockTimeHex)
+		}
+	}
+}
+
+func TestTransactionSerializeLargeCounts(t *testing.T) {
+	//Consolidation of 300 inputs to 253 outputs, both counts needing the 3 byte variable length integer form
+	testInput := Input{TxHash: "8ac60eb9575db5b2d987e29f301b5b819ea83a5c6579d282d189cc04b8e151ef", OutputIndex: 1, Sequence: SequenceFinal}
+	testOutputScript, _ := hex.DecodeString("a9141a8b0026343166625c7475f01e48b5ede8c0252e87")
+	tx := &Transaction{Version: 1}
+	for i := 0; i < 300; i++ {
+		tx.Inputs = append(tx.Inputs, testInput)
+	}
+	for i := 0; i < 253; i++ {
+		tx.Outputs = append(tx.Outputs, Output{Satoshis: 1000, ScriptPubKey: testOutputScript})
+	}
+	testInputHex := "ef51e1b804cc89d182d279655c3aa89e815b1b309fe287d9b2b55d57b90ec68a0100000000ffffffff"
+	testOutputHex := "e80300000000000017a9141a8b0026343166625c7475f01e48b5ede8c0252e87"
+	testTransactionHex := "01000000" + "fd2c01" + strings.Repeat(testInputHex, 300) +
+		"fdfd00" + strings.Repeat(testOutputHex, 253) + "00000000"
+
+	serialized, err := tx.Serialize()
+	if err != nil {
+		t.Error(err)
+	}
+	serializedHex := hex.EncodeToString(serialized)
+	if serializedHex != testTransactionHex {
+		testutils.CompareError(t, "Serialized transaction with large input and output counts different from expected transaction.", testTransactionHex, serializedHex)
+	}
+}
+
+func TestDeserializeTransaction(t *testing.T) {
+	//Unsigned and signed BIP143 native P2WPKH examples from TestTransactionSerialize
+	testTransactionHexes := []string{
+		"0100000002fff7f7881a8099afa6940d42d1e7f6362bec38171ea3edf433541db4e4ad969f0000000000eeffffffef51e1b804cc89d182d279655c3aa89e815b1b309fe287d9b2b55d57b90ec68a0100000000ffffffff02202cb206000000001976a9148280b37df378db99f66f85c95a783a76ac7a6d5988ac9093510d000000001976a9143bde42dbee7e4dbe6a21b2d50ce2f0167faa815988ac11000000",
+		"01000000000102fff7f7881a8099afa6940d42d1e7f6362bec38171ea3edf433541db4e4ad969f00000000494830450221008b9d1dc26ba6a9cb62127b02742fa9d754cd3bebf337f7a55d114c8e5cdd30be022040529b194ba3f9281a99f2b1c0a19c0489bc22ede944ccf4ecbab4cc618ef3ed01eeffffffef51e1b804cc89d182d279655c3aa89e815b1b309fe287d9b2b55d57b90ec68a0100000000ffffffff02202cb206000000001976a9148280b37df378db99f66f85c95a783a76ac7a6d5988ac9093510d000000001976a9143bde42dbee7e4dbe6a21b2d50ce2f0167faa815988ac000247304402203609e17b84f6a7d30c80bfa610b5b4542f32a8a0d5447a12fb1366d7f01cc44a0220573a954c4518331561406f90300e8f3358f51928d43c212a8caed02de67eebee0121025476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee635711000000",
+	}
+	for _, testTransactionHex := range testTransactionHexes {
+		testTransaction, _ := hex.DecodeString(testTransactionHex)
+		tx, err := DeserializeTransaction(testTransaction)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tx.Inputs[0].TxHash != "9f96ade4b41d5433f4eda31e1738ec2b36f6e7d1420d94a6af99801a88f7f7ff" {
+			testutils.CompareError(t, "Deserialized input transaction hash different from expected hash.", "9f96ade4b41d5433f4eda31e1738ec2b36f6e7d1420d94a6af99801a88f7f7ff", tx.Inputs[0].TxHash)
+		}
+		serialized, err := tx.Serialize()
+		if err != nil {
+			t.Fatal(err)
+		}
+		serializedHex := hex.EncodeToString(serialized)
+		if serializedHex != testTransactionHex {
+			testutils.CompareError(t, "Reserialized transaction different from deserialized transaction.", testTransactionHex, serializedHex)
+		}
+	}
+
+	invalidTransactionHexes := []string{
+		//Trailing byte
+		testTransactionHexes[0] + "00",
+		//Truncated lock time
+		testTransactionHexes[0][:len(testTransactionHexes[0])-2],
+		//Extended format with empty witnesses
+		"0100000000010101000000000000000000000000000000000000000000000000000000000000000000000000ffffffff010000000000000000000000000000",
+		//Input count larger than the transaction
+		"01000000fdff00",
+		//No outputs
+		"010000000101000000000000000000000000000000000000000000000000000000000000000000000000ffffffff0000000000",
+	}
+	for _, invalidTransactionHex := range invalidTransactionHexes {
+		invalidTransaction, _ := hex.DecodeString(invalidTransactionHex)
+		if _, err := DeserializeTransaction(invalidTransaction); err == nil {
+			t.Errorf("DeserializeTransaction accepting invalid transaction %s.", invalidTransactionHex)
+		}
+	}
+}
+
+func TestDeserializeTransactionRoundTrip(t *testing.T) {
+	//2-of-3 multisig spend with a 348 byte scriptSig, needing the 3 byte variable length integer form
+	largeScriptSig := append([]byte{0x00}, bytes.Repeat([]byte{0x47}, 347)...)
+	largeScriptTx := &Transaction{
+		Version: 1,
+		Inputs:  []Input{{TxHash: "02b082113e35d5386285094c2829e7e2963fa0b5369fb7f4b79c4c90877dcd3d", ScriptSig: largeScriptSig, Sequence: SequenceFinal}},
+		Outputs: []Output{{Satoshis: 55600, ScriptPubKey: bytes.Repeat([]byte{0x6a}, 300)}},
+	}
+	largeScriptTransaction, err := largeScriptTx.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	testTransactionHexes := []string{
+		//Coinbase transaction of the mainnet genesis block
+		"01000000010000000000000000000000000000000000000000000000000000000000000000ffffffff4d04ffff001d0104455468652054696d65732030332f4a616e2f32303039204368616e63656c6c6f72206f6e206272696e6b206f66207365636f6e64206261696c6f757420666f722062616e6b73ffffffff0100f2052a01000000434104678afdb0fe5548271967f1a67130b7105cd6a828e03909a67962e0ea1f61deb649f6bc3f4cef38c4f35504e51ec112de5c384df7ba0b8d578a4c702b6bf11d5fac00000000",
+		//First transaction between two people, in mainnet block 170, spending P2PK to two P2PK outputs
+		"0100000001c997a5e56e104102fa209c6a852dd90660a20b2d9c352423edce25857fcd3704000000004847304402204e45e16932b8af514961a1d3a1a25fdf3f4f7732e9d624c6c61548ab5fb8cd410220181522ec8eca07de4860a4acdd12909d831cc56cbbac4622082221a8768d1d0901ffffffff0200ca9a3b00000000434104ae1a62fe09c5f51b13905f07f06b99a2f7159b2225f374cd378d71302fa28414e7aab37397f554a7df5f142c21c1b7303b8a0626f1baded5c72a704f7e6cd84cac00286bee0000000043410411db93e1dcdb8a016b49840f8c53bc1eb68a382e97b1482ecad7b148a6909a5cb2e0eaddfb84ccf9744464f82e160bfa9b8b64f9d4c03f999b8643f656b412a3ac00000000",
+		//Signed BIP143 native P2WPKH example, with a legacy and a SegWit input
+		"01000000000102fff7f7881a8099afa6940d42d1e7f6362bec38171ea3edf433541db4e4ad969f00000000494830450221008b9d1dc26ba6a9cb62127b02742fa9d754cd3bebf337f7a55d114c8e5cdd30be022040529b194ba3f9281a99f2b1c0a19c0489bc22ede944ccf4ecbab4cc618ef3ed01eeffffffef51e1b804cc89d182d279655c3aa89e815b1b309fe287d9b2b55d57b90ec68a0100000000ffffffff02202cb206000000001976a9148280b37df378db99f66f85c95a783a76ac7a6d5988ac9093510d000000001976a9143bde42dbee7e4dbe6a21b2d50ce2f0167faa815988ac000247304402203609e17b84f6a7d30c80bfa610b5b4542f32a8a0d5447a12fb1366d7f01cc44a0220573a954c4518331561406f90300e8f3358f51928d43c212a8caed02de67eebee0121025476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee635711000000",
+		hex.EncodeToString(largeScriptTransaction),
+	}
+	for _, testTransactionHex := range testTransactionHexes {
+		testTransaction, _ := hex.DecodeString(testTransactionHex)
+		tx, err := DeserializeTransaction(testTransaction)
+		if err != nil {
+			t.Fatal(err)
+		}
+		serialized, err := tx.Serialize()
+		if err != nil {
+			t.Fatal(err)
+		}
+		serializedHex := hex.EncodeToString(serialized)
+		if serializedHex != testTransactionHex {
+			testutils.CompareError(t, "Reserialized transaction different from deserialized transaction.", testTransactionHex, serializedHex)
+		}
+		//Every truncation of a transaction is refused with an error saying where it ends
+		for i := 0; i < len(testTransaction); i++ {
+			_, err := DeserializeTransaction(testTransaction[:i])
+			if err == nil || !strings.Contains(err.Error(), "Transaction truncated in ") {
+				t.Fatalf("DeserializeTransaction not refusing transaction truncated to %d bytes as truncated. %v", i, err)
+			}
+		}
+	}
+	tx, err := DeserializeTransaction(largeScriptTransaction)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(tx.Inputs[0].ScriptSig, largeScriptSig) {
+		t.Error("Deserialized scriptSig different from expected scriptSig.")
+	}
+
+	_, err = DeserializeTransaction(largeScriptTransaction[:50])
+	if err == nil || err.Error() != "Failed to deserialize transaction. Transaction truncated in input 0 scriptSig at byte 41. Count 348 is larger than the 6 bytes left." {
+		t.Errorf("DeserializeTransaction error not naming the truncated field. %v", err)
+	}
+}
+
+func TestTxID(t *testing.T) {
+	testCases := []struct {
+		transactionHex string
+		txID           string
+		wTxID          string
+	}{
+		//Coinbase transaction of the mainnet genesis block
+		{
+			"01000000010000000000000000000000000000000000000000000000000000000000000000ffffffff4d04ffff001d0104455468652054696d65732030332f4a616e2f32303039204368616e63656c6c6f72206f6e206272696e6b206f66207365636f6e64206261696c6f757420666f722062616e6b73ffffffff0100f2052a01000000434104678afdb0fe5548271967f1a67130b7105cd6a828e03909a67962e0ea1f61deb649f6bc3f4cef38c4f35504e51ec112de5c384df7ba0b8d578a4c702b6bf11d5fac00000000",
+			"4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b",
+			"4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b",
+		},
+		//Signed native P2WPKH example of BIP143, whose witness only changes the WTxID
+		{
+			"01000000000102fff7f7881a8099afa6940d42d1e7f6362bec38171ea3edf433541db4e4ad969f00000000494830450221008b9d1dc26ba6a9cb62127b02742fa9d754cd3bebf337f7a55d114c8e5cdd30be022040529b194ba3f9281a99f2b1c0a19c0489bc22ede944ccf4ecbab4cc618ef3ed01eeffffffef51e1b804cc89d182d279655c3aa89e815b1b309fe287d9b2b55d57b90ec68a0100000000ffffffff02202cb206000000001976a9148280b37df378db99f66f85c95a783a76ac7a6d5988ac9093510d000000001976a9143bde42dbee7e4dbe6a21b2d50ce2f0167faa815988ac000247304402203609e17b84f6a7d30c80bfa610b5b4542f32a8a0d5447a12fb1366d7f01cc44a0220573a954c4518331561406f90300e8f3358f51928d43c212a8caed02de67eebee0121025476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee635711000000",
+			"e8151a2af31c368a35053ddd4bdb285a8595c769a3ad83e0fa02314a602d4609",
+			"c36c38370907df2324d9ce9d149d191192f338b37665a82e78e76a12c909b762",
+		},
+	}
+	for _, testCase := range testCases {
+		testTransaction, _ := hex.DecodeString(testCase.transactionHex)
+		txID, err := TxID(testTransaction)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if txID != testCase.txID {
+			testutils.CompareError(t, "Transaction ID different from expected ID.", testCase.txID, txID)
+		}
+		wTxID, err := WTxID(testTransaction)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if wTxID != testCase.wTxID {
+			testutils.CompareError(t, "Witness transaction ID different from expected ID.", testCase.wTxID, wTxID)
+		}
+	}
+
+	//Malformed transaction
+	if _, err := TxID([]byte{0x01, 0x00}); err == nil {
+		t.Error("TxID accepting malformed transaction.")
+	}
+	if _, err := WTxID([]byte{0x01, 0x00}); err == nil {
+		t.Error("WTxID accepting malformed transaction.")
+	}
+}
+
+func FuzzDeserializeTransaction(f *testing.F) {
+	for _, seedHex := range []string{
+		//Unsigned and signed BIP143 native P2WPKH examples
+		"0100000002fff7f7881a8099afa6940d42d1e7f6362bec38171ea3edf433541db4e4ad969f0000000000eeffffffef51e1b804cc89d182d279655c3aa89e815b1b309fe287d9b2b55d57b90ec68a0100000000ffffffff02202cb206000000001976a9148280b37df378db99f66f85c95a783a76ac7a6d5988ac9093510d000000001976a9143bde42dbee7e4dbe6a21b2d50ce2f0167faa815988ac11000000",
+		"01000000000102fff7f7881a8099afa6940d42d1e7f6362bec38171ea3edf433541db4e4ad969f00000000494830450221008b9d1dc26ba6a9cb62127b02742fa9d754cd3bebf337f7a55d114c8e5cdd30be022040529b194ba3f9281a99f2b1c0a19c0489bc22ede944ccf4ecbab4cc618ef3ed01eeffffffef51e1b804cc89d182d279655c3aa89e815b1b309fe287d9b2b55d57b90ec68a0100000000ffffffff02202cb206000000001976a9148280b37df378db99f66f85c95a783a76ac7a6d5988ac9093510d000000001976a9143bde42dbee7e4dbe6a21b2d50ce2f0167faa815988ac000247304402203609e17b84f6a7d30c80bfa610b5b4542f32a8a0d5447a12fb1366d7f01cc44a0220573a954c4518331561406f90300e8f3358f51928d43c212a8caed02de67eebee0121025476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee635711000000",
+		//Extended format with empty witnesses
+		"0100000000010101000000000000000000000000000000000000000000000000000000000000000000000000ffffffff010000000000000000000000000000",
+	} {
+		seed, _ := hex.DecodeString(seedHex)
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		tx, err := DeserializeTransaction(data)
+		if err != nil {
+			return
+		}
+		//Any accepted transaction serializes back to the same bytes
+		serialized, err := tx.Serialize()
+		if err != nil {
+			t.Fatalf("Serializing deserialized transaction %x: %s", data, err)
+		}
+		if !bytes.Equal(serialized, data) {
+			testutils.CompareError(t, "Reserialized transaction different from deserialized transaction.", hex.EncodeToString(data), hex.EncodeToString(serialized))
+		}
+	})
+}
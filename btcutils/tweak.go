@@ -0,0 +1,99 @@
+// Curve arithmetic on keys: adding a tweak to a private key, or the tweak times the base point to a public key, so
+// both keys stay a matching pair, as BIP32 child key derivation does.
+package btcutils
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// TweakAddPrivateKey returns the 32 byte private key (privateKey + tweak) mod N. Returns an error if the 32 byte
+// tweak is not less than N or the result is zero, in which case the tweak can't be used.
+func TweakAddPrivateKey(privateKey []byte, tweak []byte) ([]byte, error) {
+	d, err := parsePrivateKeyScalar(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	t, err := parseTweak(tweak)
+	if err != nil {
+		return nil, err
+	}
+	d.Add(d, t).Mod(d, secp256k1N)
+	if d.Sign() == 0 {
+		return nil, errors.New("Tweaked private key is zero.")
+	}
+	return int2octets(d, 32), nil
+}
+
+// TweakAddPublicKey returns the 33 byte compressed public key publicKey + tweak*G, which is the public key of the
+// private key returned by TweakAddPrivateKey for the same tweak. publicKey may be compressed or uncompressed.
+// Returns an error if the 32 byte tweak is not less than N or the result is the point at infinity.
+func TweakAddPublicKey(publicKey []byte, tweak []byte) ([]byte, error) {
+	publicKeyPoint, err := parsePublicKeyPoint(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	t, err := parseTweak(tweak)
+	if err != nil {
+		return nil, err
+	}
+	tweakedPoint := ecAdd(publicKeyPoint, ecMul(t, secp256k1G))
+	if tweakedPoint == nil {
+		return nil, errors.New("Tweaked public key is the point at infinity.")
+	}
+	return compressPoint(tweakedPoint), nil
+}
+
+// CheckPublicKeyIsOnCurve makes sure a compressed or uncompressed public key is a point on the secp256k1 curve,
+// which CheckPublicKeyIsValid doesn't check. Returns an error with a helpful message or nil if key is valid.
+func CheckPublicKeyIsOnCurve(publicKey []byte) error {
+	_, err := parsePublicKeyPoint(publicKey)
+	return err
+}
+
+// parseTweak converts a 32 byte tweak to an integer, which must be less than N.
+func parseTweak(tweak []byte) (*big.Int, error) {
+	if len(tweak) != 32 {
+		return nil, fmt.Errorf("Tweak should be 32 bytes long. Provided tweak is %d bytes long.", len(tweak))
+	}
+	t := new(big.Int).SetBytes(tweak)
+	if t.Cmp(secp256k1N) >= 0 {
+		return nil, errors.New("Tweak is not less than the secp256k1 curve order.")
+	}
+	return t, nil
+}
+
+// parsePublicKeyPoint converts a 33 byte compressed or 65 byte uncompressed public key to a point on the curve.
+func parsePublicKeyPoint(publicKey []byte) (*ecPoint, error) {
+	err := CheckPublicKeyIsValid(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	x := new(big.Int).SetBytes(publicKey[1:33])
+	if len(publicKey) == 65 {
+		point := &ecPoint{x: x, y: new(big.Int).SetBytes(publicKey[33:])}
+		//y^2 = x^3 + 7
+		left := new(big.Int).Exp(point.y, big.NewInt(2), secp256k1P)
+		right := new(big.Int).Exp(x, big.NewInt(3), secp256k1P)
+		right.Add(right, big.NewInt(7)).Mod(right, secp256k1P)
+		if x.Cmp(secp256k1P) >= 0 || point.y.Cmp(secp256k1P) >= 0 || left.Cmp(right) != 0 {
+			return nil, errors.New("Public key is not on the secp256k1 curve.")
+		}
+		return point, nil
+	}
+	//liftX gives the even y, 0x03 keys have the odd one
+	point, err := liftX(x)
+	if err != nil {
+		return nil, err
+	}
+	if publicKey[0] == 0x03 {
+		point.y.Sub(secp256k1P, point.y)
+	}
+	return point, nil
+}
+
+// compressPoint encodes a point as a 33 byte compressed public key, 0x02 or 0x03 for an even or odd y followed by x.
+func compressPoint(point *ecPoint) []byte {
+	return append([]byte{0x02 + byte(point.y.Bit(0))}, int2octets(point.x, 32)...)
+}
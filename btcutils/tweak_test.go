@@ -0,0 +1,70 @@
+package btcutils
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/testutils"
+
+	"encoding/hex"
+	"testing"
+)
+
+func TestTweakAdd(t *testing.T) {
+	testPrivateKey, _ := hex.DecodeString("412738f0e9f2b776748136ae6a297f7eda80112a47d865c5df7612174a5bac45")
+	testTweak, _ := hex.DecodeString("1d0b5be3b2b4e4b1fbf2b0b1c4aa1b4c5ef4df5f5c34f1e1ec0d2bd91e0a6f12")
+	testTweakedPrivateKeyHex := "5e3294d49ca79c287073e7602ed39acb3974f089a40d57a7cb833df068661b57"
+	testTweakedPublicKeyHex := "0215c2ee0b9b833de896c8da59529f8ae00a36fd70e4e35563d47d194283b05a6b"
+
+	tweakedPrivateKey, err := TweakAddPrivateKey(testPrivateKey, testTweak)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tweakedPrivateKeyHex := hex.EncodeToString(tweakedPrivateKey)
+	if tweakedPrivateKeyHex != testTweakedPrivateKeyHex {
+		testutils.CompareError(t, "Tweaked private key different from expected key.", testTweakedPrivateKeyHex, tweakedPrivateKeyHex)
+	}
+	//Tweaking either form of the public key gives the compressed public key of the tweaked private key
+	for _, compressed := range []bool{true, false} {
+		publicKey, err := newPublicKey(testPrivateKey, compressed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tweakedPublicKey, err := TweakAddPublicKey(publicKey, testTweak)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tweakedPublicKeyHex := hex.EncodeToString(tweakedPublicKey)
+		if tweakedPublicKeyHex != testTweakedPublicKeyHex {
+			testutils.CompareError(t, "Tweaked public key different from expected key.", testTweakedPublicKeyHex, tweakedPublicKeyHex)
+		}
+	}
+
+	//N - private key tweaks the private key to zero and the public key to the point at infinity
+	negatedPrivateKey, _ := hex.DecodeString("bed8c70f160d48898b7ec95195d6807fe02ecbbc67703a75e05c4c7585da94fc")
+	if _, err := TweakAddPrivateKey(testPrivateKey, negatedPrivateKey); err == nil {
+		t.Error("TweakAddPrivateKey returning zero private key.")
+	}
+	publicKey, _ := NewCompressedPublicKey(testPrivateKey)
+	if _, err := TweakAddPublicKey(publicKey, negatedPrivateKey); err == nil {
+		t.Error("TweakAddPublicKey returning point at infinity.")
+	}
+	curveOrder, _ := hex.DecodeString("fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141")
+	if _, err := TweakAddPrivateKey(testPrivateKey, curveOrder); err == nil {
+		t.Error("TweakAddPrivateKey accepting tweak equal to the curve order.")
+	}
+}
+
+func TestCheckPublicKeyIsOnCurve(t *testing.T) {
+	validPublicKey, _ := hex.DecodeString("0331393af9984375830971ab5d3094c6a7d02db3568b2b06212a7090094549701b")
+	if err := CheckPublicKeyIsOnCurve(validPublicKey); err != nil {
+		t.Error(err)
+	}
+	//x = 5 is not the x coordinate of any point on the curve
+	invalidPublicKey, _ := hex.DecodeString("020000000000000000000000000000000000000000000000000000000000000005")
+	if err := CheckPublicKeyIsOnCurve(invalidPublicKey); err == nil {
+		t.Error("CheckPublicKeyIsOnCurve accepting public key not on the curve.")
+	}
+	//Uncompressed key with the y coordinate changed
+	invalidPublicKey, _ = hex.DecodeString("0431393af9984375830971ab5d3094c6a7d02db3568b2b06212a7090094549701bbb9e84d9477451acc42638963635899ce91bacb451a1bb6da73ddfbcf596bdde")
+	if err := CheckPublicKeyIsOnCurve(invalidPublicKey); err == nil {
+		t.Error("CheckPublicKeyIsOnCurve accepting public key not on the curve.")
+	}
+}
@@ -0,0 +1,73 @@
+// Variable length integer (CompactSize) encoding as used throughout the Bitcoin protocol for counts and lengths.
+// See https://en.bitcoin.it/wiki/Protocol_documentation#Variable_length_integer for full specification.
+package btcutils
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Prefix bytes signifying the size of the integer that follows in a variable length integer.
+const (
+	varIntPrefix16 = 0xfd
+	varIntPrefix32 = 0xfe
+	varIntPrefix64 = 0xff
+)
+
+// WriteVarInt writes n to w as a Bitcoin variable length integer.
+// Values below 0xfd are written as a single byte, larger values as a prefix byte followed by
+// a 2, 4 or 8 byte little-endian integer.
+func WriteVarInt(w io.Writer, n uint64) error {
+	var varInt []byte
+	switch {
+	case n < varIntPrefix16:
+		varInt = []byte{byte(n)}
+	case n <= math.MaxUint16:
+		varInt = make([]byte, 3)
+		varInt[0] = varIntPrefix16
+		binary.LittleEndian.PutUint16(varInt[1:], uint16(n))
+	case n <= math.MaxUint32:
+		varInt = make([]byte, 5)
+		varInt[0] = varIntPrefix32
+		binary.LittleEndian.PutUint32(varInt[1:], uint32(n))
+	default:
+		varInt = make([]byte, 9)
+		varInt[0] = varIntPrefix64
+		binary.LittleEndian.PutUint64(varInt[1:], n)
+	}
+	_, err := w.Write(varInt)
+	return err
+}
+
+// ReadVarInt reads a Bitcoin variable length integer from r.
+// Non-canonical encodings, where the value would fit in a shorter encoding, are rejected as they are by
+// Bitcoin Core.
+func ReadVarInt(r io.Reader) (uint64, error) {
+	var prefix [1]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		return 0, err
+	}
+	var size int
+	var min uint64
+	switch prefix[0] {
+	case varIntPrefix16:
+		size, min = 2, varIntPrefix16
+	case varIntPrefix32:
+		size, min = 4, math.MaxUint16+1
+	case varIntPrefix64:
+		size, min = 8, math.MaxUint32+1
+	default:
+		return uint64(prefix[0]), nil
+	}
+	value := make([]byte, 8)
+	if _, err := io.ReadFull(r, value[:size]); err != nil {
+		return 0, err
+	}
+	n := binary.LittleEndian.Uint64(value)
+	if n < min {
+		return 0, fmt.Errorf("Non-canonical variable length integer 0x%02x%x.", prefix[0], value[:size])
+	}
+	return n, nil
+}
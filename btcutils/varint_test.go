@@ -0,0 +1,57 @@
+package btcutils
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/testutils"
+
+	"bytes"
+	"encoding/hex"
+	"math"
+	"testing"
+)
+
+func TestVarInt(t *testing.T) {
+	testCases := []struct {
+		n      uint64
+		varInt string
+	}{
+		{0, "00"},
+		{252, "fc"},
+		{253, "fdfd00"},
+		{0xffff, "fdffff"},
+		{0x10000, "fe00000100"},
+		{math.MaxUint32, "feffffffff"},
+		{math.MaxUint32 + 1, "ff0000000001000000"},
+		{math.MaxUint64, "ffffffffffffffffff"},
+	}
+	for _, testCase := range testCases {
+		var buffer bytes.Buffer
+		if err := WriteVarInt(&buffer, testCase.n); err != nil {
+			t.Error(err)
+		}
+		varInt := hex.EncodeToString(buffer.Bytes())
+		if varInt != testCase.varInt {
+			testutils.CompareError(t, "Variable length integer different from expected encoding.", testCase.varInt, varInt)
+		}
+		n, err := ReadVarInt(&buffer)
+		if err != nil {
+			t.Error(err)
+		}
+		if n != testCase.n {
+			testutils.CompareError(t, "Decoded variable length integer different from expected value.", testCase.n, n)
+		}
+	}
+	//Truncated variable length integers should be rejected
+	for _, truncated := range []string{"", "fd00", "fe000001", "ff00000000010000"} {
+		truncatedBytes, _ := hex.DecodeString(truncated)
+		if _, err := ReadVarInt(bytes.NewReader(truncatedBytes)); err == nil {
+			t.Errorf("ReadVarInt accepting truncated variable length integer %s.", truncated)
+		}
+	}
+	//Values that fit a shorter encoding should be rejected
+	for _, nonCanonical := range []string{"fd0000", "fdfc00", "feffff0000", "ffffffffff00000000"} {
+		nonCanonicalBytes, _ := hex.DecodeString(nonCanonical)
+		if _, err := ReadVarInt(bytes.NewReader(nonCanonicalBytes)); err == nil {
+			t.Errorf("ReadVarInt accepting non-canonical variable length integer %s.", nonCanonical)
+		}
+	}
+}
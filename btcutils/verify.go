@@ -0,0 +1,249 @@
+package btcutils
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/txscript"
+
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+)
+
+// inputSigChecker checks the signatures and lock times of input inputIndex of tx for txscript.Execute. Signatures
+// are checked against the BIP143 signature hash of an output holding amount satoshis if witness is set, otherwise
+// against the legacy signature hash.
+type inputSigChecker struct {
+	tx         *Transaction
+	inputIndex int
+	amount     int64
+	witness    bool
+}
+
+func (c *inputSigChecker) CheckSig(signature []byte, publicKey []byte, script []byte) bool {
+	if len(signature) == 0 {
+		return false
+	}
+	hashType := SigHashType(signature[len(signature)-1])
+	if CheckSigHashType(c.tx, c.inputIndex, hashType) != nil {
+		return false
+	}
+	var hash []byte
+	var err error
+	if c.witness {
+		hash, err = CalcWitnessSigHash(c.tx, c.inputIndex, script, c.amount, hashType)
+	} else {
+		hash, err = CalcSignatureHash(c.tx, c.inputIndex, script, hashType)
+	}
+	if err != nil {
+		return false
+	}
+	return VerifySignature(hash, signature[:len(signature)-1], publicKey)
+}
+
+func (c *inputSigChecker) CheckLockTime(lockTime int64) error {
+	return CheckLockTimeVerify(c.tx, c.inputIndex, lockTime)
+}
+
+func (c *inputSigChecker) CheckSequence(sequence int64) error {
+	return CheckSequenceVerify(c.tx, c.inputIndex, sequence)
+}
+
+// CheckInputKey checks, before signing, that input can be spent with publicKey or script, so a key or script given
+// for another output is refused instead of giving a transaction that can never be valid. prevScriptPubKey is the
+// scriptPubKey of the output input spends, such as the output of its previous transaction. P2SH and P2WSH outputs are
+// checked against the HASH160 or SHA256 of script, their redeem script or witness script, if given. Otherwise the
+// output must pay to publicKey: as P2PK, as P2PKH of publicKey as given, compressed or not, as P2WPKH or nested
+// P2SH-P2WPKH if it is a 33 byte compressed key, or as a BIP86 P2TR output without a script tree. Other P2SH outputs,
+// such as multisig, can only be checked against their redeem script.
+// Returns an error naming the address input spends and the address of the key or script given if they differ.
+func CheckInputKey(input Input, prevScriptPubKey []byte, publicKey []byte, script []byte, params *NetworkParams) error {
+	var provided []byte
+	var err error
+	scriptType := DetectScriptType(prevScriptPubKey)
+	outpoint := fmt.Sprintf("%s:%d", input.TxHash, input.OutputIndex)
+	switch {
+	case scriptType == P2SH && len(script) > 0:
+		var scriptHash []byte
+		if scriptHash, err = Hash160(script); err == nil {
+			provided, err = NewP2SHScriptPubKey(scriptHash)
+		}
+	case scriptType == P2WSH && len(script) > 0:
+		provided, err = NewP2WSHScriptPubKey(script)
+	case len(publicKey) == 0:
+		return fmt.Errorf("No key or script to check input %s against, which spends a %s output.", outpoint, scriptType)
+	case scriptType == P2PK:
+		provided, err = CreateP2PKScriptPubKey(publicKey)
+	case scriptType == P2PKH:
+		var publicKeyHash []byte
+		if publicKeyHash, err = Hash160(publicKey); err == nil {
+			provided, err = NewP2PKHScriptPubKey(publicKeyHash)
+		}
+	case scriptType == P2WPKH:
+		//SegWit outputs only pay to compressed keys
+		if len(publicKey) != 33 {
+			return fmt.Errorf("Input %s spends a P2WPKH output, which pays to a 33 byte compressed public key, not a %d byte key.", outpoint, len(publicKey))
+		}
+		provided, err = NewP2WPKHScriptPubKey(PublicKeyToHash160(publicKey))
+	case scriptType == P2SH:
+		//A key alone can only be checked as nested P2SH-P2WPKH
+		if len(publicKey) != 33 {
+			return fmt.Errorf("Input %s spends a P2SH output. Provide its redeem script to check it, as a %d byte key can't be the compressed key of a P2SH-P2WPKH output.", outpoint, len(publicKey))
+		}
+		provided, err = CreateP2SHP2WPKHScriptPubKey(publicKey)
+	case scriptType == P2TR:
+		var compressedPublicKey, outputKey []byte
+		if compressedPublicKey, err = CompressPublicKey(publicKey); err == nil {
+			if outputKey, err = TweakPublicKey(compressedPublicKey[1:], nil); err == nil {
+				provided, err = CreateP2TRScriptPubKey(outputKey)
+			}
+		}
+	default:
+		return fmt.Errorf("Input %s spends a %s output, which can't be checked against a key.", outpoint, scriptType)
+	}
+	if err != nil {
+		return fmt.Errorf("Cannot check the key of input %s. %s", outpoint, err)
+	}
+	if bytes.Equal(provided, prevScriptPubKey) {
+		return nil
+	}
+	if len(script) > 0 && (scriptType == P2SH || scriptType == P2WSH) {
+		return fmt.Errorf("Script does not control input %s (expects address %s, you provided a script for address %s).", outpoint, describeScriptPubKey(prevScriptPubKey, params), describeScriptPubKey(provided, params))
+	}
+	if scriptType == P2SH {
+		return fmt.Errorf("Private key does not control input %s as P2SH-P2WPKH (expects address %s, you provided key for address %s). Provide the redeem script of other P2SH outputs, such as multisig.", outpoint, describeScriptPubKey(prevScriptPubKey, params), describeScriptPubKey(provided, params))
+	}
+	return fmt.Errorf("Private key does not control input %s (expects address %s, you provided key for address %s).", outpoint, describeScriptPubKey(prevScriptPubKey, params), describeScriptPubKey(provided, params))
+}
+
+// describeScriptPubKey returns the address of scriptPubKey for params, or its hex if it has no address, as for P2PK.
+func describeScriptPubKey(scriptPubKey []byte, params *NetworkParams) string {
+	address, err := ScriptPubKeyToAddress(scriptPubKey, params)
+	if err != nil {
+		return fmt.Sprintf("of scriptPubKey %x", scriptPubKey)
+	}
+	return address
+}
+
+// VerifyInputScript runs the scripts of input inputIndex of tx with the txscript interpreter, to prove it spends
+// prevScriptPubKey, the scriptPubKey of the output it spends, before the transaction is broadcast. amount is the
+// number of satoshis the output holds, signed by SegWit inputs only. The scriptSig is run, then the scriptPubKey on
+// the stack it leaves. For P2SH outputs the scriptSig must be push only, and the redeemScript it pushes last is run
+// on the rest of its stack. Native and nested P2WPKH and P2WSH outputs are spent by their witness as per BIP141,
+// with an empty scriptSig or one pushing just the redeemScript. Taproot outputs are not supported.
+// Returns an error naming the script that failed, with the opcode and stack given by txscript.ExecError, or nil if
+// the input is valid.
+func VerifyInputScript(tx *Transaction, inputIndex int, prevScriptPubKey []byte, amount int64) error {
+	if inputIndex < 0 || inputIndex >= len(tx.Inputs) {
+		return fmt.Errorf("Input index %d out of range for a transaction with %d inputs.", inputIndex, len(tx.Inputs))
+	}
+	input := tx.Inputs[inputIndex]
+	checker := &inputSigChecker{tx: tx, inputIndex: inputIndex, amount: amount}
+	class := txscript.Classify(prevScriptPubKey)
+	if class == txscript.WitnessTaproot {
+		return errors.New("Taproot inputs can't be verified by the interpreter.")
+	}
+	if class == txscript.ScriptHash {
+		if _, err := txscript.PushedData(input.ScriptSig); err != nil {
+			return fmt.Errorf("scriptSig of a P2SH input must be push only. %s", err)
+		}
+	}
+	stack, err := txscript.Execute(input.ScriptSig, nil, checker)
+	if err != nil {
+		return fmt.Errorf("scriptSig failed. %s", err)
+	}
+	scriptSigStack := stack
+	stack, err = txscript.Execute(prevScriptPubKey, stack, checker)
+	if err != nil {
+		return fmt.Errorf("scriptPubKey failed. %s", err)
+	}
+	if err := checkFinalStack("scriptPubKey", stack, false); err != nil {
+		return err
+	}
+
+	if IsWitnessScriptPubKey(prevScriptPubKey) {
+		if len(input.ScriptSig) != 0 {
+			return errors.New("scriptSig of a native SegWit input must be empty.")
+		}
+		return verifyWitness(prevScriptPubKey, input.Witness, checker)
+	}
+	if class != txscript.ScriptHash {
+		if len(input.Witness) != 0 {
+			return fmt.Errorf("Witness given for input spending a %s output, which has no witness program.", class)
+		}
+		return nil
+	}
+	//P2SH: the redeemScript pushed last by the scriptSig is run on the rest of the stack it left
+	redeemScript := scriptSigStack[len(scriptSigStack)-1]
+	if IsWitnessScriptPubKey(redeemScript) {
+		if !bytes.Equal(input.ScriptSig, append([]byte{byte(len(redeemScript))}, redeemScript...)) {
+			return errors.New("scriptSig of a nested SegWit input must push only its redeemScript.")
+		}
+		return verifyWitness(redeemScript, input.Witness, checker)
+	}
+	if len(input.Witness) != 0 {
+		return errors.New("Witness given for input whose redeemScript has no witness program.")
+	}
+	stack, err = txscript.Execute(redeemScript, scriptSigStack[:len(scriptSigStack)-1], checker)
+	if err != nil {
+		return fmt.Errorf("redeemScript failed. %s", err)
+	}
+	return checkFinalStack("redeemScript", stack, false)
+}
+
+// verifyWitness runs witness against the version 0 witness program of witnessScriptPubKey. A 20 byte program is the
+// hash of the public key a P2WPKH witness <signature> <public key> must satisfy, checked with the P2PKH script of the
+// hash. A 32 byte program is the SHA256 of the witness script, which the witness pushes last and is run on the
+// other items. Either must leave exactly one true item, as per the BIP141 clean stack rule.
+func verifyWitness(witnessScriptPubKey []byte, witness [][]byte, checker *inputSigChecker) error {
+	checker.witness = true
+	version, program := witnessScriptPubKey[0], witnessScriptPubKey[2:]
+	if version != txscript.OP_0 {
+		return fmt.Errorf("Witness version %d can't be verified by the interpreter.", version-txscript.OP_1+1)
+	}
+	switch len(program) {
+	case 20:
+		if len(witness) != 2 {
+			return fmt.Errorf("P2WPKH witness must have 2 items, a signature and a public key, not %d.", len(witness))
+		}
+		script, err := NewP2PKHScriptPubKey(program)
+		if err != nil {
+			return err
+		}
+		stack, err := txscript.Execute(script, witness, checker)
+		if err != nil {
+			return fmt.Errorf("P2WPKH witness failed. %s", err)
+		}
+		return checkFinalStack("P2WPKH witness", stack, true)
+	case 32:
+		if len(witness) == 0 {
+			return errors.New("P2WSH witness must push the witness script.")
+		}
+		witnessScript := witness[len(witness)-1]
+		witnessScriptHash := sha256.Sum256(witnessScript)
+		if !bytes.Equal(witnessScriptHash[:], program) {
+			return fmt.Errorf("SHA256 of witness script %x is %x, not the witness program %x.", witnessScript, witnessScriptHash, program)
+		}
+		for i, item := range witness[:len(witness)-1] {
+			if len(item) > txscript.MaxElementSize {
+				return fmt.Errorf("Witness item %d is %d bytes long, longer than the %d byte stack element limit.", i, len(item), txscript.MaxElementSize)
+			}
+		}
+		stack, err := txscript.Execute(witnessScript, witness[:len(witness)-1], checker)
+		if err != nil {
+			return fmt.Errorf("Witness script failed. %s", err)
+		}
+		return checkFinalStack("Witness script", stack, true)
+	}
+	return fmt.Errorf("Version 0 witness program must be 20 or 32 bytes, not %d.", len(program))
+}
+
+// checkFinalStack makes sure the script named name left true on top of stack, and nothing else if clean is set.
+func checkFinalStack(name string, stack [][]byte, clean bool) error {
+	if len(stack) == 0 || !txscript.IsTrue(stack[len(stack)-1]) {
+		return fmt.Errorf("%s ended without true on top of the stack. Stack: %s", name, txscript.FormatStack(stack))
+	}
+	if clean && len(stack) != 1 {
+		return fmt.Errorf("%s ended with %d items on the stack instead of one. Stack: %s", name, len(stack), txscript.FormatStack(stack))
+	}
+	return nil
+}
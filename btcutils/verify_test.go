@@ -0,0 +1,162 @@
+package btcutils
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// testWeightTransactionByName deserializes the signed transaction of testWeightTransactions named name.
+func testWeightTransactionByName(name string) *Transaction {
+	for _, test := range testWeightTransactions {
+		if test.name == name {
+			rawTx, _ := hex.DecodeString(test.hex)
+			tx, _ := DeserializeTransaction(rawTx)
+			return tx
+		}
+	}
+	return nil
+}
+
+func TestVerifyInputScript(t *testing.T) {
+	uncompressedPublicKey, _ := hex.DecodeString("0431393af9984375830971ab5d3094c6a7d02db3568b2b06212a7090094549701bbb9e84d9477451acc42638963635899ce91bacb451a1bb6da73ddfbcf596bddf")
+	uncompressedPublicKeyHash, _ := Hash160(uncompressedPublicKey)
+	p2pkhScriptPubKey, _ := NewP2PKHScriptPubKey(uncompressedPublicKeyHash)
+	//BIP143 native P2WPKH example outputs
+	p2pkScriptPubKey, _ := hex.DecodeString("2103c9f4836b9a4f77fc0d81f7bcb01b7f1b35916864b9476c241ce9fc198bd25432ac")
+	p2wpkhScriptPubKey, _ := hex.DecodeString("00141d0f172a0ecb48aee1be1f2687d2963ae33f71a1")
+	//BIP143 P2SH-P2WPKH example output
+	p2shP2WPKHScriptPubKey, _ := hex.DecodeString("a9144733f37cf4db86fbc2efed2500b4f4e49f31202387")
+	p2trScriptPubKey := append([]byte{0x51, 0x20}, make([]byte, 32)...)
+
+	testCases := []struct {
+		name          string
+		scriptPubKeys [][]byte
+		amounts       []int64
+	}{
+		{"P2PKH", [][]byte{p2pkhScriptPubKey}, []int64{0}},
+		{"P2WPKH", [][]byte{p2wpkhScriptPubKey}, []int64{600000000}},
+		{"P2SH-P2WPKH", [][]byte{p2shP2WPKHScriptPubKey}, []int64{1000000000}},
+		{"P2PK and P2WPKH", [][]byte{p2pkScriptPubKey, p2wpkhScriptPubKey}, []int64{625000000, 600000000}},
+	}
+	for _, testCase := range testCases {
+		tx := testWeightTransactionByName(testCase.name)
+		for i := range tx.Inputs {
+			if err := VerifyInputScript(tx, i, testCase.scriptPubKeys[i], testCase.amounts[i]); err != nil {
+				t.Errorf("Input %d of %s transaction not verified. %s", i, testCase.name, err)
+			}
+		}
+	}
+
+	compressedScriptPubKey, _ := NewP2PKHScriptPubKey(PublicKeyToHash160(uncompressedPublicKey))
+	otherOutputTx := testWeightTransactionByName("P2PKH")
+	otherOutputTx.Inputs[0].OutputIndex = 1
+	witnessTx := testWeightTransactionByName("P2PKH")
+	witnessTx.Inputs[0].Witness = [][]byte{{0x01}}
+
+	failingCases := []struct {
+		name         string
+		tx           *Transaction
+		scriptPubKey []byte
+		amount       int64
+		reason       string
+	}{
+		//Signed with the uncompressed public key, spending an output paying the compressed one
+		{"compressed public key", testWeightTransactionByName("P2PKH"), compressedScriptPubKey, 0, "scriptPubKey failed. OP_EQUALVERIFY at offset 23 failed. Top of stack is false."},
+		//Signature made for another output of the same transaction
+		{"other output", otherOutputTx, p2pkhScriptPubKey, 0, "scriptPubKey failed. OP_CHECKSIG at offset 24 failed. Signature 304402206d6caac2"},
+		{"witness", witnessTx, p2pkhScriptPubKey, 0, "Witness given for input spending a P2PKH output"},
+		{"wrong amount", testWeightTransactionByName("P2WPKH"), p2wpkhScriptPubKey, 600000001, "P2WPKH witness failed. OP_CHECKSIG at offset 24 failed."},
+		{"P2SH of another script", testWeightTransactionByName("P2PKH"), p2shP2WPKHScriptPubKey, 0, "scriptPubKey ended without true on top of the stack."},
+		{"Taproot", testWeightTransactionByName("P2TR"), p2trScriptPubKey, 100000, "Taproot inputs can't be verified"},
+	}
+	for _, testCase := range failingCases {
+		err := VerifyInputScript(testCase.tx, 0, testCase.scriptPubKey, testCase.amount)
+		if err == nil || !strings.Contains(err.Error(), testCase.reason) {
+			t.Errorf("VerifyInputScript with %s failed with %v, expected %q.", testCase.name, err, testCase.reason)
+		}
+	}
+	if err := VerifyInputScript(otherOutputTx, 1, p2pkhScriptPubKey, 0); err == nil {
+		t.Error("VerifyInputScript accepting input index out of range.")
+	}
+}
+
+func TestCheckInputKey(t *testing.T) {
+	privateKey, _ := hex.DecodeString("619c335025c7f4012e556c2a58b2506e30b8511b53ade95ea316fd8c3286feb9")
+	otherPrivateKey, _ := hex.DecodeString("0000000000000000000000000000000000000000000000000000000000000001")
+	publicKey, _ := NewCompressedPublicKey(privateKey)
+	uncompressedPublicKey, _ := NewPublicKey(privateKey)
+	otherPublicKey, _ := NewCompressedPublicKey(otherPrivateKey)
+	publicKeyHash := PublicKeyToHash160(publicKey)
+	uncompressedPublicKeyHash, _ := Hash160(uncompressedPublicKey)
+	p2pkScriptPubKey, _ := CreateP2PKScriptPubKey(publicKey)
+	p2pkhScriptPubKey, _ := NewP2PKHScriptPubKey(publicKeyHash)
+	uncompressedP2PKHScriptPubKey, _ := NewP2PKHScriptPubKey(uncompressedPublicKeyHash)
+	p2wpkhScriptPubKey, _ := NewP2WPKHScriptPubKey(publicKeyHash)
+	p2shP2WPKHScriptPubKey, _ := CreateP2SHP2WPKHScriptPubKey(publicKey)
+	outputKey, _ := TweakPublicKey(publicKey[1:], nil)
+	p2trScriptPubKey, _ := CreateP2TRScriptPubKey(outputKey)
+	redeemScript, _ := NewMOfNRedeemScript(1, 2, [][]byte{publicKey, otherPublicKey})
+	redeemScriptHash, _ := Hash160(redeemScript)
+	p2shScriptPubKey, _ := NewP2SHScriptPubKey(redeemScriptHash)
+	p2wshScriptPubKey, _ := NewP2WSHScriptPubKey(redeemScript)
+	input := Input{TxHash: "3ad337270ac0ba14fbce812291b7d95338c878709ea8123a4d88c3c29efbc6ac", OutputIndex: 1}
+
+	testCases := []struct {
+		name             string
+		prevScriptPubKey []byte
+		publicKey        []byte
+		script           []byte
+	}{
+		{"P2PK", p2pkScriptPubKey, publicKey, nil},
+		{"P2PKH", p2pkhScriptPubKey, publicKey, nil},
+		{"uncompressed P2PKH", uncompressedP2PKHScriptPubKey, uncompressedPublicKey, nil},
+		{"P2WPKH", p2wpkhScriptPubKey, publicKey, nil},
+		{"P2SH-P2WPKH", p2shP2WPKHScriptPubKey, publicKey, nil},
+		{"P2TR", p2trScriptPubKey, publicKey, nil},
+		{"P2SH multisig", p2shScriptPubKey, nil, redeemScript},
+		{"P2WSH multisig", p2wshScriptPubKey, nil, redeemScript},
+	}
+	for _, testCase := range testCases {
+		if err := CheckInputKey(input, testCase.prevScriptPubKey, testCase.publicKey, testCase.script, &MainNetParams); err != nil {
+			t.Errorf("%s input refused. %s", testCase.name, err)
+		}
+		//The other key, or a script of it alone, is for another address
+		otherScript, _ := NewMOfNRedeemScript(1, 1, [][]byte{otherPublicKey})
+		if testCase.script == nil {
+			otherScript = nil
+		}
+		if err := CheckInputKey(input, testCase.prevScriptPubKey, otherPublicKey, otherScript, &MainNetParams); err == nil {
+			t.Errorf("%s input accepting a key or script of another output.", testCase.name)
+		}
+	}
+
+	//The error names the outpoint, the address spent and the address of the key given
+	otherAddress, _ := PublicKeyToP2PKHAddress(otherPublicKey, &MainNetParams)
+	address, _ := PublicKeyToP2PKHAddress(publicKey, &MainNetParams)
+	err := CheckInputKey(input, p2pkhScriptPubKey, otherPublicKey, nil, &MainNetParams)
+	if err == nil || !strings.Contains(err.Error(), input.TxHash+":1") || !strings.Contains(err.Error(), "expects address "+address) || !strings.Contains(err.Error(), "key for address "+otherAddress) {
+		t.Errorf("CheckInputKey error %q does not name input %s:1, expected address %s and provided address %s.", err, input.TxHash, address, otherAddress)
+	}
+	//An uncompressed key pays to another P2PKH address than its compressed form
+	if err := CheckInputKey(input, p2pkhScriptPubKey, uncompressedPublicKey, nil, &MainNetParams); err == nil {
+		t.Error("CheckInputKey accepting the uncompressed key of a compressed P2PKH output.")
+	}
+	//SegWit outputs only pay to compressed keys
+	for _, prevScriptPubKey := range [][]byte{p2wpkhScriptPubKey, p2shP2WPKHScriptPubKey} {
+		if err := CheckInputKey(input, prevScriptPubKey, uncompressedPublicKey, nil, &MainNetParams); err == nil {
+			t.Errorf("CheckInputKey accepting the uncompressed key of SegWit scriptPubKey %x.", prevScriptPubKey)
+		}
+	}
+	//A P2SH multisig output checked against a key alone asks for its redeem script
+	for _, key := range [][]byte{publicKey, uncompressedPublicKey} {
+		if err := CheckInputKey(input, p2shScriptPubKey, key, nil, &MainNetParams); err == nil || !strings.Contains(err.Error(), "redeem script") {
+			t.Errorf("CheckInputKey error %q for a P2SH multisig output checked against a key does not ask for the redeem script.", err)
+		}
+	}
+	for _, prevScriptPubKey := range [][]byte{p2shScriptPubKey, {OP_1}} {
+		if err := CheckInputKey(input, prevScriptPubKey, nil, nil, &MainNetParams); err == nil {
+			t.Errorf("CheckInputKey accepting scriptPubKey %x without a key or script.", prevScriptPubKey)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+// Encoding and decoding of private keys in Wallet Import Format (WIF), as exported by Bitcoin wallets.
+// See https://en.bitcoin.it/wiki/Wallet_import_format for full specification.
+package btcutils
+
+import (
+	"errors"
+	"fmt"
+)
+
+// wifCompressedSuffix follows the private key in WIF private keys whose public key is compressed.
+const wifCompressedSuffix = 0x01
+
+// ParseWIF decodes a WIF private key for the network given by params, validating its checksum. Returns the 32 byte
+// private key and whether the WIF marks its public key as compressed, in which case addresses and signatures must
+// use the compressed public key. Errors deliberately don't include the private key, which would otherwise end up
+// in logs.
+func ParseWIF(wif string, params *NetworkParams) ([]byte, bool, error) {
+	version, payload, err := DecodeAddress(wif)
+	if err != nil {
+		return nil, false, errors.New("Provided private key is not a valid Base58Check encoded key.")
+	}
+	if version != params.WIFVersion {
+		names := NetworkNames(func(network *NetworkParams) bool { return network.WIFVersion == version })
+		if names != "" {
+			return nil, false, fmt.Errorf("Provided private key is a %s key, but the selected network is %s.", names, params.Name)
+		}
+		return nil, false, fmt.Errorf("Provided private key has unknown version byte 0x%02x.", version)
+	}
+	switch {
+	case len(payload) == 32:
+		return payload, false, nil
+	case len(payload) == 33 && payload[32] == wifCompressedSuffix:
+		return payload[:32], true, nil
+	}
+	return nil, false, fmt.Errorf("Provided private key should be 32 bytes long, optionally followed by the compressed suffix 0x01. Decoded key is %d bytes long.", len(payload))
+}
+
+// DecodeWIF decodes a WIF private key for whichever network its version byte belongs to, validating its checksum,
+// for when the network is not known in advance. Test networks share a version byte, so TestNet3Params is returned
+// for all of them. Returns the 32 byte private key, whether the WIF marks its public key as compressed and the
+// network parameters.
+func DecodeWIF(wif string) ([]byte, bool, *NetworkParams, error) {
+	version, _, err := DecodeAddress(wif)
+	if err != nil {
+		return nil, false, nil, errors.New("Provided private key is not a valid Base58Check encoded key.")
+	}
+	for _, params := range networks {
+		if params.WIFVersion == version {
+			privateKey, compressed, err := ParseWIF(wif, params)
+			if err != nil {
+				return nil, false, nil, err
+			}
+			return privateKey, compressed, params, nil
+		}
+	}
+	return nil, false, nil, fmt.Errorf("Provided private key has unknown version byte 0x%02x.", version)
+}
+
+// EncodeWIF encodes a 32 byte private key as a WIF private key for the network given by params, eg. starting with
+// '5' on mainnet, or with 'K'/'L' on mainnet and 'c' on test networks if the public key is compressed.
+func EncodeWIF(privateKey []byte, compressed bool, params *NetworkParams) (string, error) {
+	err := checkPrivateKeyLength(privateKey)
+	if err != nil {
+		return "", err
+	}
+	payload := privateKey
+	if compressed {
+		payload = append(append([]byte{}, privateKey...), wifCompressedSuffix)
+	}
+	return base58CheckEncode(params.WIFVersion, payload), nil
+}
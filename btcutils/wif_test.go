@@ -0,0 +1,138 @@
+package btcutils
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/testutils"
+
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestParseWIF(t *testing.T) {
+	testPrivateKeyHex := "412738f0e9f2b776748136ae6a297f7eda80112a47d865c5df7612174a5bac45"
+	testCases := []struct {
+		wif        string
+		params     *NetworkParams
+		compressed bool
+	}{
+		{"5JJyqG4bb15zqi7fTA4b227aUxQhBo1Ux6qX69ngeXYLr7fk2hs", &MainNetParams, false},
+		{"KyQMr1Usf5P4HzUQiSzq9Vv2nPZaNe58NCm9ojndzdoLbfKEvPms", &MainNetParams, true},
+		{"cPmMJvUj695KTRwg6roxWpR6Qcrz36ApSEucvAF9VkTLrQNaTjoe", &TestNet3Params, true},
+		{"cPmMJvUj695KTRwg6roxWpR6Qcrz36ApSEucvAF9VkTLrQNaTjoe", &RegTestParams, true},
+	}
+	for _, testCase := range testCases {
+		privateKey, compressed, err := ParseWIF(testCase.wif, testCase.params)
+		if err != nil {
+			t.Error(err)
+		}
+		privateKeyHex := hex.EncodeToString(privateKey)
+		if privateKeyHex != testPrivateKeyHex {
+			testutils.CompareError(t, "Parsed WIF private key different from expected key.", testPrivateKeyHex, privateKeyHex)
+		}
+		if compressed != testCase.compressed {
+			testutils.CompareError(t, "Parsed WIF compression different from expected compression.", testCase.compressed, compressed)
+		}
+	}
+
+	invalidCases := []struct {
+		wif    string
+		params *NetworkParams
+	}{
+		{"5JJyqG4bb15zqi7fTA4b227aUxQhBo1Ux6qX69ngeXYLr7fk2hs", &TestNet3Params}, //mainnet key on testnet
+		{"cPmMJvUj695KTRwg6roxWpR6Qcrz36ApSEucvAF9VkTLrQNaTjoe", &MainNetParams}, //testnet key on mainnet
+		{"5JJyqG4bb15zqi7fTA4b227aUxQhBo1Ux6qX69ngeXYLr7fk2ht", &MainNetParams},  //bad checksum
+		{"KyQMr1Usf5P4HzUQiSzq9Vv2nPZaNe58NCm9ojndzdoLbfNXqbJu", &MainNetParams}, //compressed suffix 0x02
+		{"yV6B2boMPgCHviqb29Wuo239bktFE9GPuXUfKkt89HAZB1ere", &MainNetParams},    //31 byte key
+		{"13LSqJeZBpqLHzmLkJ5mvRHiM11waShFUP", &MainNetParams},                   //address
+	}
+	for _, testCase := range invalidCases {
+		if _, _, err := ParseWIF(testCase.wif, testCase.params); err == nil {
+			t.Errorf("ParseWIF accepting invalid private key %s on %s.", testCase.wif, testCase.params.Name)
+		}
+	}
+}
+
+func TestDecodeWIF(t *testing.T) {
+	//Bitcoin wiki Wallet import format example, and its compressed and testnet forms
+	testPrivateKeyHex := "0c28fca386c7a227600b2fe50b7cae11ec86d3bf1fbe471be89827e19d72aa1d"
+	testCases := []struct {
+		wif        string
+		compressed bool
+		params     *NetworkParams
+	}{
+		{"5HueCGU8rMjxEXxiPuD5BDku4MkFqeZyd4dZ1jvhTVqvbTLvyTJ", false, &MainNetParams},
+		{"KwdMAjGmerYanjeui5SHS7JkmpZvVipYvB2LJGU1ZxJwYvP98617", true, &MainNetParams},
+		{"91gGn1HgSap6CbU12F6z3pJri26xzp7Ay1VW6NHCoEayNXwRpu2", false, &TestNet3Params},
+		{"cMzLdeGd5vEqxB8B6VFQoRopQ3sLAAvEzDAoQgvX54xwofSWj1fx", true, &TestNet3Params},
+	}
+	for _, testCase := range testCases {
+		privateKey, compressed, params, err := DecodeWIF(testCase.wif)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if hex.EncodeToString(privateKey) != testPrivateKeyHex {
+			testutils.CompareError(t, "Decoded WIF private key different from expected key.", testPrivateKeyHex, hex.EncodeToString(privateKey))
+		}
+		if compressed != testCase.compressed {
+			testutils.CompareError(t, "Decoded WIF compression different from expected compression.", testCase.compressed, compressed)
+		}
+		if params != testCase.params {
+			testutils.CompareError(t, "Decoded WIF network different from expected network.", testCase.params.Name, params.Name)
+		}
+		//Encoding the decoded key gives back the same WIF
+		wif, err := EncodeWIF(privateKey, compressed, params)
+		if err != nil {
+			t.Error(err)
+		}
+		if wif != testCase.wif {
+			testutils.CompareError(t, "Re-encoded WIF private key different from expected key.", testCase.wif, wif)
+		}
+	}
+
+	invalidWIFs := []string{
+		"5HueCGU8rMjxEXxiPuD5BDku4MkFqeZyd4dZ1jvhTVqvbTLvyTK",  //bad checksum
+		"KyQMr1Usf5P4HzUQiSzq9Vv2nPZaNe58NCm9ojndzdoLbfNXqbJu", //compressed suffix 0x02
+		"13LSqJeZBpqLHzmLkJ5mvRHiM11waShFUP",                   //address, with unknown version byte for a private key
+	}
+	for _, invalidWIF := range invalidWIFs {
+		if _, _, _, err := DecodeWIF(invalidWIF); err == nil {
+			t.Errorf("DecodeWIF accepting invalid private key %s.", invalidWIF)
+		}
+	}
+}
+
+func TestEncodeWIF(t *testing.T) {
+	testPrivateKey, _ := hex.DecodeString("0000000000000000000000000000000000000000000000000000000000000001")
+	testCases := []struct {
+		compressed bool
+		params     *NetworkParams
+		wif        string
+	}{
+		{false, &MainNetParams, "5HpHagT65TZzG1PH3CSu63k8DbpvD8s5ip4nEB3kEsreAnchuDf"},
+		{true, &MainNetParams, "KwDiBf89QgGbjEhKnhXJuH7LrciVrZi3qYjgd9M7rFU73sVHnoWn"},
+		{false, &TestNet3Params, "91avARGdfge8E4tZfYLoxeJ5sGBdNJQH4kvjJoQFacbgwmaKkrx"},
+		{true, &TestNet3Params, "cMahea7zqjxrtgAbB7LSGbcQUr1uX1ojuat9jZodMN87JcbXMTcA"},
+	}
+	for _, testCase := range testCases {
+		wif, err := EncodeWIF(testPrivateKey, testCase.compressed, testCase.params)
+		if err != nil {
+			t.Error(err)
+		}
+		if wif != testCase.wif {
+			testutils.CompareError(t, "WIF private key different from expected key.", testCase.wif, wif)
+		}
+		//Encoded keys parse back to the same key and compression
+		privateKey, compressed, err := ParseWIF(wif, testCase.params)
+		if err != nil {
+			t.Error(err)
+		}
+		if !bytes.Equal(privateKey, testPrivateKey) || compressed != testCase.compressed {
+			t.Errorf("WIF private key %s does not parse back to the encoded key.", wif)
+		}
+	}
+
+	if _, err := EncodeWIF(testPrivateKey[1:], true, &MainNetParams); err == nil {
+		t.Error("EncodeWIF accepting private key of wrong length.")
+	}
+}
@@ -0,0 +1,237 @@
+// Package hdwallet implements BIP32 hierarchical deterministic (HD) wallets, deriving a tree of keys from a single
+// seed so every key of a multi-address wallet can be recovered from one backup.
+// See https://github.com/bitcoin/bips/blob/master/bip-0032.mediawiki for full specification.
+package hdwallet
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/btcutils"
+
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// HardenedKeyStart is the index of the first hardened child key. Hardened child keys can only be derived from the
+// parent private key, so a leaked child private key and parent public key can't reveal the parent private key.
+const HardenedKeyStart = 0x80000000
+
+const (
+	//Seeds are between 128 and 512 bits
+	minSeedLength = 16
+	maxSeedLength = 64
+	//4 byte version, 1 byte depth, 4 byte parent fingerprint, 4 byte child index, 32 byte chain code, 33 byte key
+	serializedKeyLength = 78
+)
+
+// masterKeyHMACKey is the HMAC-SHA512 key used to derive the master key from a seed.
+var masterKeyHMACKey = []byte("Bitcoin seed")
+
+// ExtendedKey is a BIP32 extended private or public key, the key together with the chain code needed to derive its
+// child keys and its position in the key tree.
+type ExtendedKey struct {
+	params            *btcutils.NetworkParams
+	key               []byte //32 byte private key, or 33 byte compressed public key
+	chainCode         []byte
+	depth             byte
+	parentFingerprint []byte
+	childIndex        uint32
+	isPrivate         bool
+}
+
+// NewMasterKey derives the mainnet master extended private key, the root of the key tree, from a seed of 16 to 64
+// bytes. Returns an error in the astronomically unlikely case the seed gives an invalid private key, in which
+// case a different seed must be used.
+func NewMasterKey(seed []byte) (*ExtendedKey, error) {
+	return NewMasterKeyForNetwork(seed, &btcutils.MainNetParams)
+}
+
+// NewMasterKeyForNetwork derives the master extended private key from a seed like NewMasterKey, serialized for the
+// network given by params.
+func NewMasterKeyForNetwork(seed []byte, params *btcutils.NetworkParams) (*ExtendedKey, error) {
+	if len(seed) < minSeedLength || len(seed) > maxSeedLength {
+		return nil, fmt.Errorf("Seed should be %d to %d bytes long. Provided seed is %d bytes long.", minSeedLength, maxSeedLength, len(seed))
+	}
+	//I = HMAC-SHA512(Key = "Bitcoin seed", Data = seed), the left half is the private key and right the chain code
+	hash := hmacSHA512(masterKeyHMACKey, seed)
+	err := btcutils.CheckPrivateKeyIsValid(hash[:32])
+	if err != nil {
+		return nil, errors.New("Seed gives an invalid master private key. Use a different seed.")
+	}
+	return &ExtendedKey{
+		params:            params,
+		key:               hash[:32],
+		chainCode:         hash[32:],
+		parentFingerprint: make([]byte, 4),
+		isPrivate:         true,
+	}, nil
+}
+
+// Child derives the child key with the given index, a private key for private extended keys and a public key for
+// public ones. Indexes from HardenedKeyStart up give hardened child keys, which need the private key.
+// Returns an error in the astronomically unlikely case index gives an invalid key, in which case the next index
+// should be used instead.
+func (k *ExtendedKey) Child(index uint32) (*ExtendedKey, error) {
+	if k.depth == 255 {
+		return nil, errors.New("Cannot derive a child key deeper than depth 255.")
+	}
+	publicKey := k.PublicKey()
+	//Hardened keys hash 0x00 || private key || index, normal keys compressed public key || index
+	var data bytes.Buffer
+	if index >= HardenedKeyStart {
+		if !k.isPrivate {
+			return nil, fmt.Errorf("Cannot derive hardened child key %d from a public extended key.", index)
+		}
+		data.WriteByte(0x00)
+		data.Write(k.key)
+	} else {
+		data.Write(publicKey)
+	}
+	binary.Write(&data, binary.BigEndian, index)
+	hash := hmacSHA512(k.chainCode, data.Bytes())
+
+	//Child private key is parent private key + left half of the hash, and child public key the matching point
+	var childKey []byte
+	var err error
+	if k.isPrivate {
+		childKey, err = btcutils.TweakAddPrivateKey(k.key, hash[:32])
+	} else {
+		childKey, err = btcutils.TweakAddPublicKey(k.key, hash[:32])
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Child key %d is invalid, use the next index. %s", index, err)
+	}
+	parentHash, err := btcutils.Hash160(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	return &ExtendedKey{
+		params:            k.params,
+		key:               childKey,
+		chainCode:         hash[32:],
+		depth:             k.depth + 1,
+		parentFingerprint: parentHash[:4],
+		childIndex:        index,
+		isPrivate:         k.isPrivate,
+	}, nil
+}
+
+// PublicKey returns the 33 byte compressed public key of the extended key.
+func (k *ExtendedKey) PublicKey() []byte {
+	if !k.isPrivate {
+		return k.key
+	}
+	//The private key was checked when the extended key was created, so this can't fail
+	publicKey, _ := btcutils.NewCompressedPublicKey(k.key)
+	return publicKey
+}
+
+// PrivateKey returns the 32 byte private key of a private extended key.
+func (k *ExtendedKey) PrivateKey() ([]byte, error) {
+	if !k.isPrivate {
+		return nil, errors.New("Public extended key has no private key.")
+	}
+	return k.key, nil
+}
+
+// IsPrivate reports whether the extended key is a private extended key.
+func (k *ExtendedKey) IsPrivate() bool {
+	return k.isPrivate
+}
+
+// Neuter returns the public extended key of the extended key, which can derive the same normal child public keys
+// without being able to spend from them.
+func (k *ExtendedKey) Neuter() *ExtendedKey {
+	if !k.isPrivate {
+		return k
+	}
+	return &ExtendedKey{
+		params:            k.params,
+		key:               k.PublicKey(),
+		chainCode:         k.chainCode,
+		depth:             k.depth,
+		parentFingerprint: k.parentFingerprint,
+		childIndex:        k.childIndex,
+	}
+}
+
+// String serializes the extended key as a Base58Check encoded xprv or xpub string, or tprv/tpub for test networks.
+func (k *ExtendedKey) String() string {
+	version := k.params.HDPublicKeyVersion
+	if k.isPrivate {
+		version = k.params.HDPrivateKeyVersion
+	}
+	var payload bytes.Buffer
+	binary.Write(&payload, binary.BigEndian, version)
+	payload.WriteByte(k.depth)
+	payload.Write(k.parentFingerprint)
+	binary.Write(&payload, binary.BigEndian, k.childIndex)
+	payload.Write(k.chainCode)
+	//Private keys are padded to 33 bytes with a leading zero, public keys are compressed
+	if k.isPrivate {
+		payload.WriteByte(0x00)
+	}
+	payload.Write(k.key)
+	return btcutils.Base58CheckEncode(payload.Bytes())
+}
+
+// ParseExtendedKey decodes a Base58Check encoded extended key for the network given by params, xprv or xpub for
+// mainnet and tprv or tpub for test networks, validating its checksum and key. Errors deliberately don't include
+// the key, which would otherwise end up in logs.
+func ParseExtendedKey(extendedKey string, params *btcutils.NetworkParams) (*ExtendedKey, error) {
+	decoded, err := btcutils.Base58CheckDecode(extendedKey)
+	if err != nil {
+		return nil, errors.New("Provided extended key is not a valid Base58Check encoded key.")
+	}
+	if len(decoded) != serializedKeyLength {
+		return nil, fmt.Errorf("Provided extended key should be %d bytes long. Decoded key is %d bytes long.", serializedKeyLength, len(decoded))
+	}
+	version := binary.BigEndian.Uint32(decoded[:4])
+	if version != params.HDPrivateKeyVersion && version != params.HDPublicKeyVersion {
+		names := btcutils.NetworkNames(func(network *btcutils.NetworkParams) bool {
+			return network.HDPrivateKeyVersion == version || network.HDPublicKeyVersion == version
+		})
+		if names != "" {
+			return nil, fmt.Errorf("Provided extended key is a %s key, but the selected network is %s.", names, params.Name)
+		}
+		return nil, fmt.Errorf("Provided extended key has unknown version 0x%08x.", version)
+	}
+	k := &ExtendedKey{
+		params:            params,
+		depth:             decoded[4],
+		parentFingerprint: decoded[5:9],
+		childIndex:        binary.BigEndian.Uint32(decoded[9:13]),
+		chainCode:         decoded[13:45],
+		isPrivate:         version == params.HDPrivateKeyVersion,
+	}
+	if k.depth == 0 && (!bytes.Equal(k.parentFingerprint, make([]byte, 4)) || k.childIndex != 0) {
+		return nil, errors.New("Provided master extended key has a parent fingerprint or child index.")
+	}
+	//Private keys are padded to 33 bytes with a leading zero, public keys are compressed
+	if k.isPrivate {
+		if decoded[45] != 0x00 {
+			return nil, errors.New("Provided extended private key should have a private key prefixed with 0x00.")
+		}
+		k.key = decoded[46:]
+		err = btcutils.CheckPrivateKeyIsValid(k.key)
+	} else {
+		if decoded[45] != 0x02 && decoded[45] != 0x03 {
+			return nil, errors.New("Provided extended public key should have a compressed public key.")
+		}
+		k.key = decoded[45:]
+		err = btcutils.CheckPublicKeyIsOnCurve(k.key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Provided extended key has an invalid key. %s", err)
+	}
+	return k, nil
+}
+
+// hmacSHA512 computes HMAC-SHA512 of data with key.
+func hmacSHA512(key []byte, data []byte) []byte {
+	mac := hmac.New(sha512.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
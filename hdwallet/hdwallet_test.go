@@ -0,0 +1,190 @@
+package hdwallet
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/btcutils"
+	"github.com/CryptoProcessing/go-bitcoin-multisig/testutils"
+
+	"encoding/hex"
+	"testing"
+)
+
+// testExtendedKeys are the expected extended public and private keys at one level of a BIP32 test vector chain.
+type testExtendedKeys struct {
+	xpub string
+	xprv string
+}
+
+// checkChain derives each index of path in turn from parent and checks the keys against the expected keys of each
+// level. Normal child public keys are also derived from the parent public key, which must give the same key.
+func checkChain(t *testing.T, parent *ExtendedKey, path []uint32, expected []testExtendedKeys) {
+	for i, index := range path {
+		child, err := parent.Child(index)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkExtendedKey(t, child, expected[i])
+		if index < HardenedKeyStart {
+			publicChild, err := parent.Neuter().Child(index)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if publicChild.String() != expected[i].xpub {
+				testutils.CompareError(t, "Child public key derived from public key different from expected key.", expected[i].xpub, publicChild.String())
+			}
+		}
+		parent = child
+	}
+}
+
+// checkExtendedKey checks a private extended key serializes to the expected keys, and that the expected keys parse
+// back to the same keys.
+func checkExtendedKey(t *testing.T, key *ExtendedKey, expected testExtendedKeys) {
+	if key.String() != expected.xprv {
+		testutils.CompareError(t, "Extended private key different from expected key.", expected.xprv, key.String())
+	}
+	if key.Neuter().String() != expected.xpub {
+		testutils.CompareError(t, "Extended public key different from expected key.", expected.xpub, key.Neuter().String())
+	}
+	for _, serialized := range []string{expected.xprv, expected.xpub} {
+		parsed, err := ParseExtendedKey(serialized, &btcutils.MainNetParams)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parsed.String() != serialized {
+			testutils.CompareError(t, "Parsed extended key different from expected key.", serialized, parsed.String())
+		}
+	}
+}
+
+func TestBIP32Vectors(t *testing.T) {
+	//Test vectors 1 and 2 from BIP32
+	testCases := []struct {
+		seed     string
+		path     []uint32
+		expected []testExtendedKeys
+	}{
+		{
+			//m/0H/1/2H/2/1000000000
+			"000102030405060708090a0b0c0d0e0f",
+			[]uint32{HardenedKeyStart, 1, HardenedKeyStart + 2, 2, 1000000000},
+			[]testExtendedKeys{
+				{"xpub661MyMwAqRbcFtXgS5sYJABqqG9YLmC4Q1Rdap9gSE8NqtwybGhePY2gZ29ESFjqJoCu1Rupje8YtGqsefD265TMg7usUDFdp6W1EGMcet8", "xprv9s21ZrQH143K3QTDL4LXw2F7HEK3wJUD2nW2nRk4stbPy6cq3jPPqjiChkVvvNKmPGJxWUtg6LnF5kejMRNNU3TGtRBeJgk33yuGBxrMPHi"},
+				{"xpub68Gmy5EdvgibQVfPdqkBBCHxA5htiqg55crXYuXoQRKfDBFA1WEjWgP6LHhwBZeNK1VTsfTFUHCdrfp1bgwQ9xv5ski8PX9rL2dZXvgGDnw", "xprv9uHRZZhk6KAJC1avXpDAp4MDc3sQKNxDiPvvkX8Br5ngLNv1TxvUxt4cV1rGL5hj6KCesnDYUhd7oWgT11eZG7XnxHrnYeSvkzY7d2bhkJ7"},
+				{"xpub6ASuArnXKPbfEwhqN6e3mwBcDTgzisQN1wXN9BJcM47sSikHjJf3UFHKkNAWbWMiGj7Wf5uMash7SyYq527Hqck2AxYysAA7xmALppuCkwQ", "xprv9wTYmMFdV23N2TdNG573QoEsfRrWKQgWeibmLntzniatZvR9BmLnvSxqu53Kw1UmYPxLgboyZQaXwTCg8MSY3H2EU4pWcQDnRnrVA1xe8fs"},
+				{"xpub6D4BDPcP2GT577Vvch3R8wDkScZWzQzMMUm3PWbmWvVJrZwQY4VUNgqFJPMM3No2dFDFGTsxxpG5uJh7n7epu4trkrX7x7DogT5Uv6fcLW5", "xprv9z4pot5VBttmtdRTWfWQmoH1taj2axGVzFqSb8C9xaxKymcFzXBDptWmT7FwuEzG3ryjH4ktypQSAewRiNMjANTtpgP4mLTj34bhnZX7UiM"},
+				{"xpub6FHa3pjLCk84BayeJxFW2SP4XRrFd1JYnxeLeU8EqN3vDfZmbqBqaGJAyiLjTAwm6ZLRQUMv1ZACTj37sR62cfN7fe5JnJ7dh8zL4fiyLHV", "xprvA2JDeKCSNNZky6uBCviVfJSKyQ1mDYahRjijr5idH2WwLsEd4Hsb2Tyh8RfQMuPh7f7RtyzTtdrbdqqsunu5Mm3wDvUAKRHSC34sJ7in334"},
+				{"xpub6H1LXWLaKsWFhvm6RVpEL9P4KfRZSW7abD2ttkWP3SSQvnyA8FSVqNTEcYFgJS2UaFcxupHiYkro49S8yGasTvXEYBVPamhGW6cFJodrTHy", "xprvA41z7zogVVwxVSgdKUHDy1SKmdb533PjDz7J6N6mV6uS3ze1ai8FHa8kmHScGpWmj4WggLyQjgPie1rFSruoUihUZREPSL39UNdE3BBDu76"},
+			},
+		},
+		{
+			//m/0/2147483647H/1/2147483646H/2
+			"fffcf9f6f3f0edeae7e4e1dedbd8d5d2cfccc9c6c3c0bdbab7b4b1aeaba8a5a29f9c999693908d8a8784817e7b7875726f6c696663605d5a5754514e4b484542",
+			[]uint32{0, HardenedKeyStart + 2147483647, 1, HardenedKeyStart + 2147483646, 2},
+			[]testExtendedKeys{
+				{"xpub661MyMwAqRbcFW31YEwpkMuc5THy2PSt5bDMsktWQcFF8syAmRUapSCGu8ED9W6oDMSgv6Zz8idoc4a6mr8BDzTJY47LJhkJ8UB7WEGuduB", "xprv9s21ZrQH143K31xYSDQpPDxsXRTUcvj2iNHm5NUtrGiGG5e2DtALGdso3pGz6ssrdK4PFmM8NSpSBHNqPqm55Qn3LqFtT2emdEXVYsCzC2U"},
+				{"xpub69H7F5d8KSRgmmdJg2KhpAK8SR3DjMwAdkxj3ZuxV27CprR9LgpeyGmXUbC6wb7ERfvrnKZjXoUmmDznezpbZb7ap6r1D3tgFxHmwMkQTPH", "xprv9vHkqa6EV4sPZHYqZznhT2NPtPCjKuDKGY38FBWLvgaDx45zo9WQRUT3dKYnjwih2yJD9mkrocEZXo1ex8G81dwSM1fwqWpWkeS3v86pgKt"},
+				{"xpub6ASAVgeehLbnwdqV6UKMHVzgqAG8Gr6riv3Fxxpj8ksbH9ebxaEyBLZ85ySDhKiLDBrQSARLq1uNRts8RuJiHjaDMBU4Zn9h8LZNnBC5y4a", "xprv9wSp6B7kry3Vj9m1zSnLvN3xH8RdsPP1Mh7fAaR7aRLcQMKTR2vidYEeEg2mUCTAwCd6vnxVrcjfy2kRgVsFawNzmjuHc2YmYRmagcEPdU9"},
+				{"xpub6DF8uhdarytz3FWdA8TvFSvvAh8dP3283MY7p2V4SeE2wyWmG5mg5EwVvmdMVCQcoNJxGoWaU9DCWh89LojfZ537wTfunKau47EL2dhHKon", "xprv9zFnWC6h2cLgpmSA46vutJzBcfJ8yaJGg8cX1e5StJh45BBciYTRXSd25UEPVuesF9yog62tGAQtHjXajPPdbRCHuWS6T8XA2ECKADdw4Ef"},
+				{"xpub6ERApfZwUNrhLCkDtcHTcxd75RbzS1ed54G1LkBUHQVHQKqhMkhgbmJbZRkrgZw4koxb5JaHWkY4ALHY2grBGRjaDMzQLcgJvLJuZZvRcEL", "xprvA1RpRA33e1JQ7ifknakTFpgNXPmW2YvmhqLQYMmrj4xJXXWYpDPS3xz7iAxn8L39njGVyuoseXzU6rcxFLJ8HFsTjSyQbLYnMpCqE2VbFWc"},
+				{"xpub6FnCn6nSzZAw5Tw7cgR9bi15UV96gLZhjDstkXXxvCLsUXBGXPdSnLFbdpq8p9HmGsApME5hQTZ3emM2rnY5agb9rXpVGyy3bdW6EEgAtqt", "xprvA2nrNbFZABcdryreWet9Ea4LvTJcGsqrMzxHx98MMrotbir7yrKCEXw7nadnHM8Dq38EGfSh6dqA9QWTyefMLEcBYJUuekgW4BYPJcr9E7j"},
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		seed, _ := hex.DecodeString(testCase.seed)
+		master, err := NewMasterKey(seed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkExtendedKey(t, master, testCase.expected[0])
+		checkChain(t, master, testCase.path, testCase.expected[1:])
+	}
+}
+
+func TestBIP32LeadingZeros(t *testing.T) {
+	//Test vector 3 from BIP32, m/0H, where the master private key has a leading zero byte that must be retained.
+	//Derived from the master extended private key of the test vector rather than its seed
+	expected := []testExtendedKeys{
+		{"xpub661MyMwAqRbcEZVB4dScxMAdx6d4nFc9nvyvH3v4gJL378CSRZiYmhRoP7mBy6gSPSCYk6SzXPTf3ND1cZAceL7SfJ1Z3GC8vBgp2epUt13", "xprv9s21ZrQH143K25QhxbucbDDuQ4naNntJRi4KUfWT7xo4EKsHt2QJDu7KXp1A3u7Bi1j8ph3EGsZ9Xvz9dGuVrtHHs7pXeTzjuxBrCmmhgC6"},
+		{"xpub68NZiKmJWnxxS6aaHmn81bvJeTESw724CRDs6HbuccFQN9Ku14VQrADWgqbhhTHBaohPX4CjNLf9fq9MYo6oDaPPLPxSb7gwQN3ih19Zm4Y", "xprv9uPDJpEQgRQfDcW7BkF7eTya6RPxXeJCqCJGHuCJ4GiRVLzkTXBAJMu2qaMWPrS7AANYqdq6vcBcBUdJCVVFceUvJFjaPdGZ2y9WACViL4L"},
+	}
+	master, err := ParseExtendedKey(expected[0].xprv, &btcutils.MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkExtendedKey(t, master, expected[0])
+	checkChain(t, master, []uint32{HardenedKeyStart}, expected[1:])
+}
+
+func TestPublicKey(t *testing.T) {
+	//Private and public extended keys of test vector 1 have the same compressed public key
+	testPublicKeyHex := "0339a36013301597daef41fbe593a02cc513d0b55527ec2df1050e2e8ff49c85c2"
+	for _, serialized := range []string{"xprv9s21ZrQH143K3QTDL4LXw2F7HEK3wJUD2nW2nRk4stbPy6cq3jPPqjiChkVvvNKmPGJxWUtg6LnF5kejMRNNU3TGtRBeJgk33yuGBxrMPHi", "xpub661MyMwAqRbcFtXgS5sYJABqqG9YLmC4Q1Rdap9gSE8NqtwybGhePY2gZ29ESFjqJoCu1Rupje8YtGqsefD265TMg7usUDFdp6W1EGMcet8"} {
+		key, err := ParseExtendedKey(serialized, &btcutils.MainNetParams)
+		if err != nil {
+			t.Fatal(err)
+		}
+		publicKeyHex := hex.EncodeToString(key.PublicKey())
+		if publicKeyHex != testPublicKeyHex {
+			testutils.CompareError(t, "Extended key public key different from expected key.", testPublicKeyHex, publicKeyHex)
+		}
+	}
+}
+
+func TestNewMasterKeyForNetwork(t *testing.T) {
+	//Test vector 1 master key serialized with the testnet version
+	testTPRV := "tprv8ZgxMBicQKsPeDgjzdC36fs6bMjGApWDNLR9erAXMs5skhMv36j9MV5ecvfavji5khqjWaWSFhN3YcCUUdiKH6isR4Pwy3U5y5egddBr16m"
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	master, err := NewMasterKeyForNetwork(seed, &btcutils.TestNet3Params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if master.String() != testTPRV {
+		testutils.CompareError(t, "Testnet extended private key different from expected key.", testTPRV, master.String())
+	}
+	if _, err := ParseExtendedKey(testTPRV, &btcutils.RegTestParams); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestExtendedKeyErrors(t *testing.T) {
+	if _, err := NewMasterKey(make([]byte, 15)); err == nil {
+		t.Error("NewMasterKey accepting 15 byte seed.")
+	}
+	if _, err := NewMasterKey(make([]byte, 65)); err == nil {
+		t.Error("NewMasterKey accepting 65 byte seed.")
+	}
+	master, err := ParseExtendedKey("xpub661MyMwAqRbcFtXgS5sYJABqqG9YLmC4Q1Rdap9gSE8NqtwybGhePY2gZ29ESFjqJoCu1Rupje8YtGqsefD265TMg7usUDFdp6W1EGMcet8", &btcutils.MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := master.Child(HardenedKeyStart); err == nil {
+		t.Error("Child deriving hardened child key from public extended key.")
+	}
+	if _, err := master.PrivateKey(); err == nil {
+		t.Error("PrivateKey returning private key of public extended key.")
+	}
+
+	invalidCases := []struct {
+		extendedKey string
+		params      *btcutils.NetworkParams
+	}{
+		//Mainnet key on testnet
+		{"xprv9s21ZrQH143K3QTDL4LXw2F7HEK3wJUD2nW2nRk4stbPy6cq3jPPqjiChkVvvNKmPGJxWUtg6LnF5kejMRNNU3TGtRBeJgk33yuGBxrMPHi", &btcutils.TestNet3Params},
+		//Testnet key on mainnet
+		{"tprv8ZgxMBicQKsPeDgjzdC36fs6bMjGApWDNLR9erAXMs5skhMv36j9MV5ecvfavji5khqjWaWSFhN3YcCUUdiKH6isR4Pwy3U5y5egddBr16m", &btcutils.MainNetParams},
+		//Invalid checksum
+		{"xprv9s21ZrQH143K3QTDL4LXw2F7HEK3wJUD2nW2nRk4stbPy6cq3jPPqjiChkVvvNKmPGJxWUtg6LnF5kejMRNNU3TGtRBeJgk33yuGBxrMPHj", &btcutils.MainNetParams},
+		//Master key with a child index of 1
+		{"xprv9s21ZrQH143K5xHBs26cwZK5DysagCJvyKkvGxYZfF4mZAqjPTNZDYRPyzMWuZqh2Ah4465C1KR38McHpLVffLbyzqfTkrY5tYLVhTL5ye4", &btcutils.MainNetParams},
+		//Public key with an x coordinate of 5, which is not on the curve
+		{"xpub661MyMwAqRbcFtXgS5sYJABqqG9YLmC4Q1Rdap9gSE8NqtwybGhePY2gYym6yCVZtiQKSpLUqpuy2xafsZZR8vydJmD1kZ1yXu2LotCeeYJ", &btcutils.MainNetParams},
+		//Private key equal to the curve order
+		{"xprv9s21ZrQH143K3QTDL4LXw2F7HEK3wJUD2nW2nRk4stbPy6cq3jPPqjiChkg5hntwdZH6QYdrGVYWUCS2Xv6FCMHoYQZYQDohv67LnGTwiNd", &btcutils.MainNetParams},
+	}
+	for _, invalidCase := range invalidCases {
+		if _, err := ParseExtendedKey(invalidCase.extendedKey, invalidCase.params); err == nil {
+			t.Errorf("ParseExtendedKey accepting invalid key %s.", invalidCase.extendedKey)
+		}
+	}
+}
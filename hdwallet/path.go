@@ -0,0 +1,103 @@
+package hdwallet
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Purposes of the BIP43 key trees, the first, hardened level of the path, giving the type of address derived.
+const (
+	PurposeBIP44 = 44 //Legacy P2PKH addresses
+	PurposeBIP49 = 49 //P2SH-P2WPKH nested SegWit addresses
+	PurposeBIP84 = 84 //P2WPKH native SegWit addresses
+	PurposeBIP86 = 86 //P2TR Taproot key path addresses
+)
+
+// CoinTypeBitcoin is the SLIP44 coin type of Bitcoin mainnet, the second, hardened level of the path. Test networks
+// use coin type 1 for every coin.
+const CoinTypeBitcoin = 0
+
+// DeriveByPath derives the key at path from master, a path such as "m/44'/0'/0'/0/0" as written by BIP44. The path
+// starts with the m root marker, followed by a slash separated index for each level, hardened if followed by an
+// apostrophe or h, which adds HardenedKeyStart to the index. "m" alone gives master itself.
+// Returns an error if master is not a master key, the path is malformed, or a level can't be derived.
+func DeriveByPath(master *ExtendedKey, path string) (*ExtendedKey, error) {
+	if master.depth != 0 {
+		return nil, fmt.Errorf("Path %q starts at the master key, but the key given is at depth %d.", path, master.depth)
+	}
+	indexes, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	key := master
+	for i, index := range indexes {
+		key, err = key.Child(index)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to derive level %d of path %q. %s", i+1, path, err)
+		}
+	}
+	return key, nil
+}
+
+// parsePath parses a derivation path such as "m/44'/0'/0'/0/0" into the child index of each level below the master
+// key, with hardened indexes from HardenedKeyStart.
+func parsePath(path string) ([]uint32, error) {
+	levels := strings.Split(path, "/")
+	if levels[0] != "m" {
+		return nil, fmt.Errorf("Path %q must start with m, the master key.", path)
+	}
+	indexes := make([]uint32, 0, len(levels)-1)
+	for i, level := range levels[1:] {
+		if level == "" {
+			return nil, fmt.Errorf("Level %d of path %q is empty.", i+1, path)
+		}
+		var offset uint32
+		if strings.HasSuffix(level, "'") || strings.HasSuffix(level, "h") {
+			level = level[:len(level)-1]
+			offset = HardenedKeyStart
+		}
+		//Signs are refused, as strconv would otherwise accept "+1"
+		if level == "" || strings.IndexFunc(level, func(r rune) bool { return r < '0' || r > '9' }) != -1 {
+			return nil, fmt.Errorf("Level %d of path %q is not an index, optionally followed by ' for hardened.", i+1, path)
+		}
+		index, err := strconv.ParseUint(level, 10, 32)
+		if err != nil || index >= HardenedKeyStart {
+			return nil, fmt.Errorf("Level %d of path %q must be an index below %d.", i+1, path, HardenedKeyStart)
+		}
+		indexes = append(indexes, uint32(index)+offset)
+	}
+	return indexes, nil
+}
+
+// BIP44AccountKey derives the BIP44 account key m/44'/0'/account' of Bitcoin mainnet from master, whose external
+// chain 0 and internal (change) chain 1 hold the keys of legacy P2PKH addresses.
+func BIP44AccountKey(master *ExtendedKey, account uint32) (*ExtendedKey, error) {
+	return accountKey(master, PurposeBIP44, account)
+}
+
+// BIP49AccountKey derives the BIP49 account key m/49'/0'/account' of Bitcoin mainnet from master, whose chains hold
+// the keys of P2SH-P2WPKH addresses.
+func BIP49AccountKey(master *ExtendedKey, account uint32) (*ExtendedKey, error) {
+	return accountKey(master, PurposeBIP49, account)
+}
+
+// BIP84AccountKey derives the BIP84 account key m/84'/0'/account' of Bitcoin mainnet from master, whose chains hold
+// the keys of P2WPKH addresses.
+func BIP84AccountKey(master *ExtendedKey, account uint32) (*ExtendedKey, error) {
+	return accountKey(master, PurposeBIP84, account)
+}
+
+// BIP86AccountKey derives the BIP86 account key m/86'/0'/account' of Bitcoin mainnet from master, whose chains hold
+// the internal keys of P2TR addresses without a script tree.
+func BIP86AccountKey(master *ExtendedKey, account uint32) (*ExtendedKey, error) {
+	return accountKey(master, PurposeBIP86, account)
+}
+
+// accountKey derives the account key m/purpose'/0'/account' from master, with every level hardened.
+func accountKey(master *ExtendedKey, purpose uint32, account uint32) (*ExtendedKey, error) {
+	if account >= HardenedKeyStart {
+		return nil, fmt.Errorf("Account %d must be below %d, as it is hardened when derived.", account, HardenedKeyStart)
+	}
+	return DeriveByPath(master, fmt.Sprintf("m/%d'/%d'/%d'", purpose, CoinTypeBitcoin, account))
+}
@@ -0,0 +1,148 @@
+package hdwallet
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/btcutils"
+	"github.com/CryptoProcessing/go-bitcoin-multisig/testutils"
+
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+// testMnemonicSeed is the BIP39 seed of "abandon abandon abandon abandon abandon abandon abandon abandon abandon
+// abandon abandon about" without a passphrase, the seed of the BIP49, BIP84 and BIP86 test vectors.
+const testMnemonicSeed = "5eb00bbddcf069084889a8ab9155568165f5c453ccb85e70811aaed6f6da5fc19a5ac40b389cd370d086206dec8aa6c43daea6690f20ad3d8d48b2d2ce9e38e4"
+
+// checkSerializedKey checks key serializes to expected, ignoring the version bytes, as BIP49 and BIP84 give keys
+// with their own ypub and zpub versions.
+func checkSerializedKey(t *testing.T, name string, key *ExtendedKey, expected string) {
+	decodedExpected, err := btcutils.Base58CheckDecode(expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, _ := btcutils.Base58CheckDecode(key.String())
+	if !bytes.Equal(decoded[4:], decodedExpected[4:]) {
+		testutils.CompareError(t, name+" different from expected key.", expected, key.String())
+	}
+}
+
+func TestAccountKeys(t *testing.T) {
+	seed, _ := hex.DecodeString(testMnemonicSeed)
+	master, err := NewMasterKey(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p2pkhAddress := func(publicKey []byte) (string, error) {
+		return btcutils.PublicKeyToP2PKHAddress(publicKey, &btcutils.MainNetParams)
+	}
+	p2shP2WPKHAddress := func(publicKey []byte) (string, error) {
+		return btcutils.PublicKeyToP2SHP2WPKHAddress(publicKey, &btcutils.MainNetParams)
+	}
+	p2trAddress := func(publicKey []byte) (string, error) {
+		outputKey, err := btcutils.TweakPublicKey(publicKey[1:], nil)
+		if err != nil {
+			return "", err
+		}
+		scriptPubKey, _ := btcutils.CreateP2TRScriptPubKey(outputKey)
+		return btcutils.ScriptPubKeyToAddress(scriptPubKey, &btcutils.MainNetParams)
+	}
+	p2wpkhAddress := func(publicKey []byte) (string, error) {
+		scriptPubKey, _ := btcutils.NewP2WPKHScriptPubKey(btcutils.PublicKeyToHash160(publicKey))
+		return btcutils.ScriptPubKeyToAddress(scriptPubKey, &btcutils.MainNetParams)
+	}
+	testCases := []struct {
+		name         string
+		accountKey   func(*ExtendedKey, uint32) (*ExtendedKey, error)
+		firstPath    string //Path of the first key of the external chain, account/0/0
+		xprv         string //Account 0 extended private key
+		xpub         string //Account 0 extended public key
+		address      func([]byte) (string, error)
+		firstAddress string
+	}{
+		//Account key and address as given by wallets following BIP44, which has no test vectors
+		{"BIP44", BIP44AccountKey, "m/44'/0'/0'/0/0", "", "xpub6BosfCnifzxcFwrSzQiqu2DBVTshkCXacvNsWGYJVVhhawA7d4R5WSWGFNbi8Aw6ZRc1brxMyWMzG3DSSSSoekkudhUd9yLb6qx39T9nMdj", p2pkhAddress, "1LqBGSKuX5yYUonjxT5qGfpUsXKYYWeabA"},
+		//BIP49 test vectors are for testnet, checked by TestDeriveByPath, these are the mainnet keys
+		{"BIP49", BIP49AccountKey, "m/49'/0'/0'/0/0", "", "ypub6Ww3ibxVfGzLrAH1PNcjyAWenMTbbAosGNB6VvmSEgytSER9azLDWCxoJwW7Ke7icmizBMXrzBx9979FfaHxHcrArf3zbeJJJUZPf663zsP", p2shP2WPKHAddress, "37VucYSaXLCAsxYyAPfbSi9eh4iEcbShgf"},
+		//Test vectors from BIP84
+		{"BIP84", BIP84AccountKey, "m/84'/0'/0'/0/0", "zprvAdG4iTXWBoARxkkzNpNh8r6Qag3irQB8PzEMkAFeTRXxHpbF9z4QgEvBRmfvqWvGp42t42nvgGpNgYSJA9iefm1yYNZKEm7z6qUWCroSQnE", "zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs", p2wpkhAddress, "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu"},
+		//Test vectors from BIP86
+		{"BIP86", BIP86AccountKey, "m/86'/0'/0'/0/0", "xprv9xgqHN7yz9MwCkxsBPN5qetuNdQSUttZNKw1dcYTV4mkaAFiBVGQziHs3NRSWMkCzvgjEe3n9xV8oYywvM8at9yRqyaZVz6TYYhX98VjsUk", "xpub6BgBgsespWvERF3LHQu6CnqdvfEvtMcQjYrcRzx53QJjSxarj2afYWcLteoGVky7D3UKDP9QyrLprQ3VCECoY49yfdDEHGCtMMj92pReUsQ", p2trAddress, "bc1p5cyxnuxmeuwuvkwfem96lqzszd02n6xdcjrs20cac6yqjjwudpxqkedrcr"},
+	}
+	for _, testCase := range testCases {
+		account, err := testCase.accountKey(master, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if testCase.xprv != "" {
+			checkSerializedKey(t, testCase.name+" account private key", account, testCase.xprv)
+		}
+		checkSerializedKey(t, testCase.name+" account public key", account.Neuter(), testCase.xpub)
+		//The first key derived by path must match the one derived from the account public key, as watch-only wallets do
+		first, err := DeriveByPath(master, testCase.firstPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		external, _ := account.Neuter().Child(0)
+		publicFirst, _ := external.Child(0)
+		if !bytes.Equal(first.PublicKey(), publicFirst.PublicKey()) {
+			t.Errorf("%s first key derived by path different from the key derived from the account public key.", testCase.name)
+		}
+		address, err := testCase.address(first.PublicKey())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if address != testCase.firstAddress {
+			testutils.CompareError(t, testCase.name+" first address different from expected address.", testCase.firstAddress, address)
+		}
+	}
+	if _, err := BIP84AccountKey(master, HardenedKeyStart); err == nil {
+		t.Error("BIP84AccountKey accepting hardened account index.")
+	}
+}
+
+func TestDeriveByPath(t *testing.T) {
+	//Test vectors from BIP49, on testnet with coin type 1
+	seed, _ := hex.DecodeString(testMnemonicSeed)
+	master, err := NewMasterKeyForNetwork(seed, &btcutils.TestNet3Params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		path string
+		tprv string
+	}{
+		{"m", "tprv8ZgxMBicQKsPe5YMU9gHen4Ez3ApihUfykaqUorj9t6FDqy3nP6eoXiAo2ssvpAjoLroQxHqr3R5nE3a5dU3DHTjTgJDd7zrbniJr6nrCzd"},
+		{"m/49'/1'/0'", "tprv8gRrNu65W2Msef2BdBSUgFdRTGzC8EwVXnV7UGS3faeXtuMVtGfEdidVeGbThs4ELEoayCAzZQ4uUji9DUiAs7erdVskqju7hrBcDvDsdbY"},
+		{"m/49h/1h/0h", "tprv8gRrNu65W2Msef2BdBSUgFdRTGzC8EwVXnV7UGS3faeXtuMVtGfEdidVeGbThs4ELEoayCAzZQ4uUji9DUiAs7erdVskqju7hrBcDvDsdbY"},
+	}
+	for _, testCase := range testCases {
+		key, err := DeriveByPath(master, testCase.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key.String() != testCase.tprv {
+			testutils.CompareError(t, "Key at "+testCase.path+" different from expected key.", testCase.tprv, key.String())
+		}
+	}
+	first, err := DeriveByPath(master, "m/49'/1'/0'/0/0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address, _ := btcutils.PublicKeyToP2SHP2WPKHAddress(first.PublicKey(), &btcutils.TestNet3Params)
+	if address != "2Mww8dCYPUpKHofjgcXcBCEGmniw9CoaiD2" {
+		testutils.CompareError(t, "BIP49 first address different from expected address.", "2Mww8dCYPUpKHofjgcXcBCEGmniw9CoaiD2", address)
+	}
+
+	account, _ := DeriveByPath(master, "m/49'/1'/0'")
+	for _, path := range []string{"", "M/0", "/0", "m/", "m//0", "m/0/", "m/-1", "m/+1", "m/1''", "m/a", "m/0x1", "m/2147483648", "m/4294967296'"} {
+		if _, err := DeriveByPath(master, path); err == nil {
+			t.Errorf("DeriveByPath accepting malformed path %q.", path)
+		}
+	}
+	if _, err := DeriveByPath(account, "m/0"); err == nil {
+		t.Error("DeriveByPath accepting path from the master key for an account key.")
+	}
+	if _, err := DeriveByPath(master.Neuter(), "m/0'"); err == nil {
+		t.Error("DeriveByPath deriving hardened key from public extended key.")
+	}
+}
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/btcutils"
 	"github.com/CryptoProcessing/go-bitcoin-multisig/multisig"
 
+	"log"
 	"os"
 
 	"gopkg.in/alecthomas/kingpin.v1"
@@ -10,49 +12,202 @@ import (
 
 // Kingpin configurations for command-line subcommands and their respective flags.
 var (
-	app = kingpin.New("go-bitcoin-multisig", "A Bitcoin multisig transaction builder built in Go")
+	app                 = kingpin.New("go-bitcoin-multisig", "A Bitcoin multisig transaction builder built in Go")
+	appNetwork          = app.Flag("network", "Network to use addresses and private keys of: mainnet, testnet3, signet or regtest.").Default("mainnet").Enum("mainnet", "testnet3", "signet", "regtest")
+	appTestnet          = app.Flag("testnet", "Shorthand for --network=testnet3.").Default("false").Bool()
+	appLockTime         = app.Flag("locktime", "Lock time of the transaction, a block height below 500000000 or a Unix timestamp from 500000000. The transaction cannot be mined, or relayed, before it. 0 for no lock time.").Default("0").Int64()
+	appSequence         = app.Flag("sequence", "Sequence number of the transaction input. If left at the final 4294967295 (0xffffffff), it is replaced by 4294967293 (0xfffffffd) with --rbf, or lowered to 4294967294 (0xfffffffe) with a non-zero --locktime, as lock times are ignored for final inputs.").Default("4294967295").Int64()
+	appRBF              = app.Flag("rbf", "Signal that the transaction can be replaced by a higher fee transaction (BIP125) with input sequence 4294967293 (0xfffffffd). Use --no-rbf for the final sequence 4294967295 (0xffffffff).").Default("true").Bool()
+	appRelativeLockTime = app.Flag("relative-locktime", "BIP68 relative lock time of the transaction input, so it cannot be mined until the output it spends is a number of blocks old, such as 144, or of seconds, such as 86400s, rounded up to 512 second units. Replaces --sequence, and requires --tx-version 2.").String()
+	appTxVersion        = app.Flag("tx-version", "Version of the transaction. Version 2 enforces BIP68 relative lock times, as needed to spend OP_CHECKSEQUENCEVERIFY scripts.").Default("2").Int64()
+	appDustRelayFee     = app.Flag("dust-relay-fee", "Dust relay fee rate in satoshi per kilo virtual byte. Outputs worth less than the fee to create and spend them at this rate are dust, which nodes refuse to relay.").Default("3000").Int()
+	appAllowDust        = app.Flag("allow-dust", "Allow creating outputs below the dust threshold, which nodes will not relay.").Default("false").Bool()
+	appMaxFee           = app.Flag("max-fee", "Highest fee in satoshis, the --input-amount less the outputs, a transaction may pay. Higher fees, or fees above 10% of the satoshis sent to outputs other than change, are refused as a likely mistyped amount.").Default("1000000").Int()
+	appForceHighFee     = app.Flag("force-high-fee", "Allow a fee above --max-fee or 10% of the satoshis sent.").Default("false").Bool()
+	appSigHash          = app.Flag("sighash", "Hash type to sign inputs with: default, all, none or single, optionally followed by |anyonecanpay. The default hash type is SIGHASH_DEFAULT for Taproot and SIGHASH_ALL otherwise.").Default("default").Enum("default", "all", "none", "single", "all|anyonecanpay", "none|anyonecanpay", "single|anyonecanpay")
+	appLowR             = app.Flag("low-r", "Retry signing until the signature R value is low, so every ECDSA signature is 71 bytes. Use --no-low-r to sign with the first RFC 6979 nonce for debugging.").Default("true").Bool()
+	appOutputFormat     = app.Flag("output-format", "Output a signed raw transaction (hex) or a BIP174 PSBT in base64 (psbt), holding the unsigned transaction with the UTXO, scripts and hash type of its input and the signatures of the given private keys, for cosigners to sign in other wallets.").Default("hex").Enum("hex", "psbt")
+	appPrevTx           = app.Flag("prev-tx", "Raw hex of the input transaction, or @FILE to read it from a file. The amount and scriptPubKey of the output spent are taken from it, so --input-amount can be left out, and it is recorded in the PSBT for signers to check the amount being spent. Required with --output-format=psbt for legacy P2PKH and P2SH inputs.").String()
 
 	//keys subcommand
-	cmdKeys        = app.Command("keys", "Generate public/private key pairs valid for use on Bitcoin network. **PSEUDORANDOM AND FOR DEMONSTRATION PURPOSES ONLY. DO NOT USE IN PRODUCTION.**")
-	cmdKeysCount   = cmdKeys.Flag("count", "No. of key pairs to generate.").Default("1").Int()
-	cmdKeysConcise = cmdKeys.Flag("concise", "Turn on concise output. Default is off (verbose output).").Default("false").Bool()
+	cmdKeys           = app.Command("keys", "Generate public/private key pairs valid for use on Bitcoin network. **PSEUDORANDOM AND FOR DEMONSTRATION PURPOSES ONLY. DO NOT USE IN PRODUCTION.**")
+	cmdKeysCount      = cmdKeys.Flag("count", "No. of key pairs to generate.").Default("1").Int()
+	cmdKeysConcise    = cmdKeys.Flag("concise", "Turn on concise output. Default is off (verbose output).").Default("false").Bool()
+	cmdKeysCompressed = cmdKeys.Flag("compressed", "Generate compressed public keys and WIF private keys, as used by modern wallets. Use --no-compressed for uncompressed keys.").Default("true").Bool()
 	//address subcommand
 	cmdAddress           = app.Command("address", "Generate a multisig P2SH address with M-of-N requirements and set of public keys.")
 	cmdAddressM          = cmdAddress.Flag("m", "M, the minimum number of keys needed to spend Bitcoin in M-of-N multisig transaction.").Required().Int()
 	cmdAddressN          = cmdAddress.Flag("n", "N, the total number of possible keys that can be used to spend Bitcoin in M-of-N multisig transaction.").Required().Int()
 	cmdAddressPublicKeys = cmdAddress.Flag("public-keys", "Comma separated list of private keys to sign with. Whitespace is stripped and quotes may be placed around keys. Eg. key1,key2,\"key3\"").PlaceHolder("PUBLIC-KEYS(Comma separated)").Required().String()
+	cmdAddressP2WSH      = cmdAddress.Flag("p2wsh", "Generate only the native SegWit P2WSH address and its witness script, allowing up to 20 compressed public keys and a 3,600 byte witness script instead of the 520 byte P2SH limit.").Default("false").Bool()
+	cmdAddressNested     = cmdAddress.Flag("nested", "Generate only the nested SegWit P2SH-P2WSH address, for senders that cannot pay to native SegWit addresses, with its witness script and redeem script. Public keys must be compressed.").Default("false").Bool()
+	cmdAddressTaproot    = cmdAddress.Flag("taproot", "Generate only the Taproot P2TR address whose script path is a BIP342 OP_CHECKSIGADD multisig, with its leaf script, control block and unspendable internal key. Allows up to 999 public keys.").Default("false").Bool()
+	cmdAddressMuSig2     = cmdAddress.Flag("musig2", "Generate only the Taproot P2TR address of the BIP327 MuSig2 aggregate of the N compressed public keys, for N-of-N multisig. Spending it needs the interactive MuSig2 signing protocol, which is not implemented yet.").Default("false").Bool()
+	cmdAddressSortKeys   = cmdAddress.Flag("sort-keys", "Sort public keys before aggregating them with --musig2, so the address does not depend on the order they are given in.").Default("false").Bool()
 	//fund subcommand
-	cmdFund            = app.Command("fund", "Fund multisig address from a standard Bitcoin address.")
-	cmdFundPrivateKey  = cmdFund.Flag("private-key", "Private key of bitcoin to send.").Required().String()
-	cmdFundInputTx     = cmdFund.Flag("input-tx", "Input transaction hash of bitcoin to send.").Required().String()
-	cmdFundAmount      = cmdFund.Flag("amount", "Amount of bitcoin to send in satoshi (100,000,000 satoshi = 1 bitcoin).").Required().Int()
-	cmdFundDestination = cmdFund.Flag("destination", "Destination address. For P2SH, this should start with '3'.").Required().String()
+	cmdFund             = app.Command("fund", "Fund multisig address from a standard Bitcoin address.")
+	cmdFundPrivateKey   = cmdFund.Flag("private-key", "Private key of bitcoin to send.").Required().String()
+	cmdFundPublicKey    = cmdFund.Flag("public-key", "Optional hex public key of --private-key, compressed or uncompressed, checked against the key derived from it.").String()
+	cmdFundInputTx      = cmdFund.Flag("input-tx", "Input transaction hash of bitcoin to send. Give as TXID:VOUT or TXID:VOUT:SEQUENCE to set the output index, and the input sequence instead of --sequence.").Required().String()
+	cmdFundInputIndex   = cmdFund.Flag("input-index", "Output index (vout) of input transaction to spend.").Default("0").Int()
+	cmdFundAmount       = cmdFund.Flag("amount", "Amount of bitcoin to send in satoshi (100,000,000 satoshi = 1 bitcoin). Required unless --sweep is given.").Default("0").Int()
+	cmdFundDestination  = cmdFund.Flag("destination", "Destination address. P2PKH ('1'), P2SH ('3') and SegWit ('bc1') addresses are detected automatically. Repeat as ADDRESS:AMOUNT to fund multiple addresses.").Required().Strings()
+	cmdFundChange       = cmdFund.Flag("change-address", "Address to send change to. P2PKH ('1'), P2SH ('3') or SegWit ('bc1') addresses are accepted. Requires --input-amount and --fee.").String()
+	cmdFundInputAmount  = cmdFund.Flag("input-amount", "Amount of bitcoin in satoshi held by the input being spent, to print the fee and refuse outputs it can't pay for. Required with --change-address or --sweep.").Default("0").Int()
+	cmdFundFee          = cmdFund.Flag("fee", "Transaction fee in satoshi. Required with --change-address or --sweep unless --fee-rate is given.").Default("0").Int()
+	cmdFundFeeRate      = cmdFund.Flag("fee-rate", "Fee rate in satoshi per virtual byte, used to calculate the fee from the estimated transaction size. Requires --change-address or --sweep.").Default("0").Int()
+	cmdFundForce        = cmdFund.Flag("force", "Allow fee rates above 10,000 satoshi per virtual byte, and more than one --op-return or --op-return-text output.").Default("false").Bool()
+	cmdFundSweep        = cmdFund.Flag("sweep", "Send the whole input amount less fee to the destination, without change. Requires --input-amount and --fee or --fee-rate.").Default("false").Bool()
+	cmdFundOpReturn     = cmdFund.Flag("op-return", "Hex data to embed in a zero value OP_RETURN output, such as a document hash. At most 80 bytes. Repeat with --force for more than one OP_RETURN output.").Strings()
+	cmdFundOpReturnText = cmdFund.Flag("op-return-text", "Text to embed as UTF-8 in a zero value OP_RETURN output, after any --op-return output. At most 80 bytes. Repeat with --force for more than one OP_RETURN output.").Strings()
+	//fund-p2wpkh subcommand
+	cmdFundP2WPKH            = app.Command("fund-p2wpkh", "Fund an address from a native SegWit P2WPKH output.")
+	cmdFundP2WPKHPrivateKey  = cmdFundP2WPKH.Flag("private-key", "Private key of the P2WPKH output to send.").Required().String()
+	cmdFundP2WPKHInputTx     = cmdFundP2WPKH.Flag("input-tx", "Input transaction hash of bitcoin to send. Give as TXID:VOUT or TXID:VOUT:SEQUENCE to set the output index, and the input sequence instead of --sequence.").Required().String()
+	cmdFundP2WPKHInputIndex  = cmdFundP2WPKH.Flag("input-index", "Output index (vout) of P2WPKH input transaction to spend.").Default("0").Int()
+	cmdFundP2WPKHInputAmount = cmdFundP2WPKH.Flag("input-amount", "Amount of bitcoin in satoshi held by the P2WPKH output being spent. Required unless --prev-tx is given.").Default("0").Int()
+	cmdFundP2WPKHAmount      = cmdFundP2WPKH.Flag("amount", "Amount of bitcoin to send in satoshi (100,000,000 satoshi = 1 bitcoin). Required unless --fee-rate is given.").Default("0").Int()
+	cmdFundP2WPKHFeeRate     = cmdFundP2WPKH.Flag("fee-rate", "Fee rate in satoshi per virtual byte. Sends --input-amount less the fee estimated from the signed transaction size, instead of --amount.").Default("0").Int()
+	cmdFundP2WPKHForce       = cmdFundP2WPKH.Flag("force", "Allow fee rates above 10,000 satoshi per virtual byte.").Default("false").Bool()
+	cmdFundP2WPKHDestination = cmdFundP2WPKH.Flag("destination", "Destination address. P2PKH ('1'), P2SH ('3') or SegWit ('bc1') addresses are accepted.").Required().String()
+	//fund-p2sh-p2wpkh subcommand
+	cmdFundP2SHP2WPKH            = app.Command("fund-p2sh-p2wpkh", "Fund an address from a nested SegWit P2SH-P2WPKH output.")
+	cmdFundP2SHP2WPKHPrivateKey  = cmdFundP2SHP2WPKH.Flag("private-key", "Private key of the P2SH-P2WPKH output to send.").Required().String()
+	cmdFundP2SHP2WPKHInputTx     = cmdFundP2SHP2WPKH.Flag("input-tx", "Input transaction hash of bitcoin to send. Give as TXID:VOUT or TXID:VOUT:SEQUENCE to set the output index, and the input sequence instead of --sequence.").Required().String()
+	cmdFundP2SHP2WPKHInputIndex  = cmdFundP2SHP2WPKH.Flag("input-index", "Output index (vout) of P2SH-P2WPKH input transaction to spend.").Default("0").Int()
+	cmdFundP2SHP2WPKHInputAmount = cmdFundP2SHP2WPKH.Flag("input-amount", "Amount of bitcoin in satoshi held by the P2SH-P2WPKH output being spent. Required unless --prev-tx is given.").Default("0").Int()
+	cmdFundP2SHP2WPKHAmount      = cmdFundP2SHP2WPKH.Flag("amount", "Amount of bitcoin to send in satoshi (100,000,000 satoshi = 1 bitcoin). Required unless --fee-rate is given.").Default("0").Int()
+	cmdFundP2SHP2WPKHFeeRate     = cmdFundP2SHP2WPKH.Flag("fee-rate", "Fee rate in satoshi per virtual byte. Sends --input-amount less the fee estimated from the signed transaction size, instead of --amount.").Default("0").Int()
+	cmdFundP2SHP2WPKHForce       = cmdFundP2SHP2WPKH.Flag("force", "Allow fee rates above 10,000 satoshi per virtual byte.").Default("false").Bool()
+	cmdFundP2SHP2WPKHDestination = cmdFundP2SHP2WPKH.Flag("destination", "Destination address. P2PKH ('1'), P2SH ('3') or SegWit ('bc1') addresses are accepted.").Required().String()
+	//fund-p2tr subcommand
+	cmdFundP2TR            = app.Command("fund-p2tr", "Fund an address from a Taproot P2TR output, spending it with the key path.")
+	cmdFundP2TRPrivateKey  = cmdFundP2TR.Flag("private-key", "Private key of the P2TR output to send.").Required().String()
+	cmdFundP2TRInputTx     = cmdFundP2TR.Flag("input-tx", "Input transaction hash of bitcoin to send. Give as TXID:VOUT or TXID:VOUT:SEQUENCE to set the output index, and the input sequence instead of --sequence.").Required().String()
+	cmdFundP2TRInputIndex  = cmdFundP2TR.Flag("input-index", "Output index (vout) of P2TR input transaction to spend.").Default("0").Int()
+	cmdFundP2TRInputAmount = cmdFundP2TR.Flag("input-amount", "Amount of bitcoin in satoshi held by the P2TR output being spent. Required unless --prev-tx is given.").Default("0").Int()
+	cmdFundP2TRAmount      = cmdFundP2TR.Flag("amount", "Amount of bitcoin to send in satoshi (100,000,000 satoshi = 1 bitcoin).").Required().Int()
+	cmdFundP2TRDestination = cmdFundP2TR.Flag("destination", "Destination address. P2PKH ('1'), P2SH ('3') or SegWit ('bc1') addresses are accepted.").Required().String()
 	//spend subcommand
 	cmdSpend             = app.Command("spend", "Spend multisig balance by sending to a standard Bitcoin address.")
 	cmdSpendPrivateKeys  = cmdSpend.Flag("private-keys", "Comma separated list of private keys to sign with. Whitespace is stripped and quotes may be placed around keys. Eg. key1,key2,\"key3\"").PlaceHolder("PRIVATE-KEYS(Comma separated)").Required().String()
-	cmdSpendDestination  = cmdSpend.Flag("destination", "Public destination address to send bitcoins.").Required().String()
+	cmdSpendDestination  = cmdSpend.Flag("destination", "Public destination address to send bitcoins. P2PKH ('1'), P2SH ('3') and SegWit ('bc1') addresses are detected automatically.").Required().String()
 	cmdSpendRedeemScript = cmdSpend.Flag("redeemScript", "Hex representation of redeem script that matches redeem script in P2SH input transaction.").Required().String()
-	cmdSpendInputTx      = cmdSpend.Flag("input-tx", "Input transaction hash of bitcoin to send.").Required().String()
-	cmdSpendAmount       = cmdSpend.Flag("amount", "Amount of bitcoin to send in satoshi (100,000,000 satoshi = 1 bitcoin).").Required().Int()
+	cmdSpendInputTx      = cmdSpend.Flag("input-tx", "Input transaction hash of bitcoin to send. Give as TXID:VOUT or TXID:VOUT:SEQUENCE to set the output index, and the input sequence instead of --sequence.").Required().String()
+	cmdSpendInputIndex   = cmdSpend.Flag("input-index", "Output index (vout) of P2SH input transaction to spend.").Default("0").Int()
+	cmdSpendAmount       = cmdSpend.Flag("amount", "Amount of bitcoin to send in satoshi (100,000,000 satoshi = 1 bitcoin). Required unless --sweep is given.").Default("0").Int()
+	cmdSpendInputAmount  = cmdSpend.Flag("input-amount", "Amount of bitcoin in satoshi held by the P2SH input being spent, to print the fee and refuse an --amount it can't pay for. Required with --sweep.").Default("0").Int()
+	cmdSpendFee          = cmdSpend.Flag("fee", "Transaction fee in satoshi. Required with --sweep unless --fee-rate is given.").Default("0").Int()
+	cmdSpendFeeRate      = cmdSpend.Flag("fee-rate", "Fee rate in satoshi per virtual byte, used to calculate the fee from the estimated transaction size. Requires --sweep.").Default("0").Int()
+	cmdSpendForce        = cmdSpend.Flag("force", "Allow fee rates above 10,000 satoshi per virtual byte.").Default("false").Bool()
+	cmdSpendSweep        = cmdSpend.Flag("sweep", "Send the whole input amount less fee to the destination. Requires --input-amount and --fee or --fee-rate.").Default("false").Bool()
+	//redeem-p2wsh subcommand
+	cmdRedeemP2WSH              = app.Command("redeem-p2wsh", "Spend multisig balance held by a native SegWit P2WSH output.")
+	cmdRedeemP2WSHPrivateKeys   = cmdRedeemP2WSH.Flag("private-keys", "Comma separated list of private keys to sign with. Whitespace is stripped and quotes may be placed around keys. Eg. key1,key2,\"key3\"").PlaceHolder("PRIVATE-KEYS(Comma separated)").Required().String()
+	cmdRedeemP2WSHDestination   = cmdRedeemP2WSH.Flag("destination", "Destination address. P2PKH ('1'), P2SH ('3') or SegWit ('bc1') addresses are accepted.").Required().String()
+	cmdRedeemP2WSHWitnessScript = cmdRedeemP2WSH.Flag("witness-script", "Hex representation of witness script that hashes to the P2WSH output. This is the redeem script given by the address subcommand.").Required().String()
+	cmdRedeemP2WSHNested        = cmdRedeemP2WSH.Flag("nested", "Spend a nested SegWit P2SH-P2WSH output, from address --nested, instead of a native P2WSH one. The redeem script derived from --witness-script is put in the scriptSig, and the signatures and witness script in the witness.").Default("false").Bool()
+	cmdRedeemP2WSHInputTx       = cmdRedeemP2WSH.Flag("input-tx", "Input transaction hash of bitcoin to send. Give as TXID:VOUT or TXID:VOUT:SEQUENCE to set the output index, and the input sequence instead of --sequence.").Required().String()
+	cmdRedeemP2WSHInputIndex    = cmdRedeemP2WSH.Flag("input-index", "Output index (vout) of P2WSH input transaction to spend.").Default("0").Int()
+	cmdRedeemP2WSHInputAmount   = cmdRedeemP2WSH.Flag("input-amount", "Amount of bitcoin in satoshi held by the P2WSH output being spent. Required unless --prev-tx is given.").Default("0").Int()
+	cmdRedeemP2WSHAmount        = cmdRedeemP2WSH.Flag("amount", "Amount of bitcoin to send in satoshi (100,000,000 satoshi = 1 bitcoin).").Required().Int()
+	//psbt-sign subcommand
+	cmdPSBTSign            = app.Command("psbt-sign", "Add signatures to a BIP174 PSBT, such as one from Bitcoin Core or another cosigner, for every input the private keys can sign.")
+	cmdPSBTSignPSBT        = cmdPSBTSign.Flag("psbt", "Base64 PSBT to sign.").Required().String()
+	cmdPSBTSignPrivateKeys = cmdPSBTSign.Flag("private-keys", "Comma separated list of private keys to sign with. Whitespace is stripped and quotes may be placed around keys. Eg. key1,key2,\"key3\"").PlaceHolder("PRIVATE-KEYS(Comma separated)").Required().String()
+	//psbt-combine subcommand
+	cmdPSBTCombine     = app.Command("psbt-combine", "Combine copies of a BIP174 PSBT signed by different cosigners into one PSBT holding all of their signatures.")
+	cmdPSBTCombinePSBT = cmdPSBTCombine.Flag("psbt", "Base64 PSBT to combine. Repeat for each PSBT, at least twice.").Required().Strings()
+	//psbt-finalize subcommand
+	cmdPSBTFinalize     = app.Command("psbt-finalize", "Finalize a BIP174 PSBT with enough partial signatures, building the final scriptSig or witness of each input.")
+	cmdPSBTFinalizePSBT = cmdPSBTFinalize.Flag("psbt", "Base64 PSBT to finalize.").Required().String()
+	//psbt-extract subcommand
+	cmdPSBTExtract     = app.Command("psbt-extract", "Extract the signed transaction of a finalized BIP174 PSBT, ready to broadcast.")
+	cmdPSBTExtractPSBT = cmdPSBTExtract.Flag("psbt", "Base64 finalized PSBT to extract the transaction of.").Required().String()
+	//sign-hash subcommand
+	cmdSignHash           = app.Command("sign-hash", "Sign an already computed 32 byte hash, such as a signature hash from an external tool.")
+	cmdSignHashPrivateKey = cmdSignHash.Flag("private-key", "Private key to sign with.").Required().String()
+	cmdSignHashHash       = cmdSignHash.Flag("hash", "32 byte hash to sign, in hex.").Required().String()
+	cmdSignHashSchnorr    = cmdSignHash.Flag("schnorr", "Sign with a 64 byte BIP340 Schnorr signature, verified with the x-only public key, instead of a DER encoded ECDSA signature.").Default("false").Bool()
+	//decode subcommand
+	cmdDecode     = app.Command("decode", "Decode a raw transaction, showing its version, lock time, size and IDs, and each of its inputs and outputs with their scripts raw and disassembled and the standard template and address they follow.")
+	cmdDecodeTx   = cmdDecode.Flag("tx", "Raw transaction to decode, in hex, with or without witness data.").Required().String()
+	cmdDecodeJSON = cmdDecode.Flag("json", "Output the decoded transaction as JSON, with the field names of bitcoin-cli decoderawtransaction and amounts in satoshis.").Default("false").Bool()
+	//verify subcommand
+	cmdVerify        = app.Command("verify", "Verify that each input of a signed transaction satisfies the scripts of the output it spends, before broadcasting it.")
+	cmdVerifyTx      = cmdVerify.Flag("tx", "Signed raw transaction to verify, in hex.").Required().String()
+	cmdVerifyPrevOut = cmdVerify.Flag("prevout", "Output spent by an input, as ADDRESS:AMOUNT or SCRIPTPUBKEY:AMOUNT with the scriptPubKey in hex. The amount in satoshis is only needed for SegWit inputs. Repeat once per input, in input order.").Required().Strings()
 )
 
 func main() {
-	switch kingpin.MustParse(app.Parse(os.Args[1:])) {
+	var err error
+	command := kingpin.MustParse(app.Parse(os.Args[1:]))
+	network := *appNetwork
+	if *appTestnet {
+		network = "testnet3"
+	}
+	btcutils.DisableLowR = !*appLowR
+	txFlags := multisig.TxFlags{LockTime: *appLockTime, Sequence: *appSequence, RBF: *appRBF, RelativeLockTime: *appRelativeLockTime, TxVersion: *appTxVersion, DustRelayFee: *appDustRelayFee, AllowDust: *appAllowDust, MaxFee: *appMaxFee, ForceHighFee: *appForceHighFee, SigHash: *appSigHash, OutputFormat: *appOutputFormat, PrevTx: *appPrevTx, Network: network}
+	switch command {
 
 	//keys -- Generate public/private key pairs
 	case cmdKeys.FullCommand():
-		multisig.OutputKeys(*cmdKeysCount, *cmdKeysConcise)
+		err = multisig.OutputKeys(*cmdKeysCount, *cmdKeysConcise, *cmdKeysCompressed, network)
 
 	//address -- Create a multisig P2SH address
 	case cmdAddress.FullCommand():
-		multisig.OutputAddress(*cmdAddressM, *cmdAddressN, *cmdAddressPublicKeys)
+		err = multisig.OutputAddress(*cmdAddressM, *cmdAddressN, *cmdAddressPublicKeys, *cmdAddressP2WSH, *cmdAddressNested, *cmdAddressTaproot, *cmdAddressMuSig2, *cmdAddressSortKeys, network)
 
 	//address -- Fund a P2SH address
 	case cmdFund.FullCommand():
-		multisig.OutputFund(*cmdFundPrivateKey, *cmdFundInputTx, *cmdFundAmount, *cmdFundDestination)
+		err = multisig.OutputFund(multisig.FundFlags{PrivateKey: *cmdFundPrivateKey, PublicKey: *cmdFundPublicKey, InputTx: *cmdFundInputTx, InputIndex: *cmdFundInputIndex, Amount: *cmdFundAmount, Destinations: *cmdFundDestination, ChangeAddress: *cmdFundChange, InputAmount: *cmdFundInputAmount, Fee: *cmdFundFee, FeeRate: *cmdFundFeeRate, Force: *cmdFundForce, Sweep: *cmdFundSweep, OpReturns: *cmdFundOpReturn, OpReturnTexts: *cmdFundOpReturnText, TxFlags: txFlags})
+
+	//address -- Fund an address from a P2WPKH output
+	case cmdFundP2WPKH.FullCommand():
+		err = multisig.OutputFundP2WPKH(multisig.FundP2WPKHFlags{PrivateKey: *cmdFundP2WPKHPrivateKey, InputTx: *cmdFundP2WPKHInputTx, InputIndex: *cmdFundP2WPKHInputIndex, InputAmount: *cmdFundP2WPKHInputAmount, Amount: *cmdFundP2WPKHAmount, FeeRate: *cmdFundP2WPKHFeeRate, Force: *cmdFundP2WPKHForce, Destination: *cmdFundP2WPKHDestination, TxFlags: txFlags})
+
+	//fund-p2sh-p2wpkh -- Fund an address from a nested P2SH-P2WPKH output
+	case cmdFundP2SHP2WPKH.FullCommand():
+		err = multisig.OutputFundP2SHP2WPKH(multisig.FundP2WPKHFlags{PrivateKey: *cmdFundP2SHP2WPKHPrivateKey, InputTx: *cmdFundP2SHP2WPKHInputTx, InputIndex: *cmdFundP2SHP2WPKHInputIndex, InputAmount: *cmdFundP2SHP2WPKHInputAmount, Amount: *cmdFundP2SHP2WPKHAmount, FeeRate: *cmdFundP2SHP2WPKHFeeRate, Force: *cmdFundP2SHP2WPKHForce, Destination: *cmdFundP2SHP2WPKHDestination, TxFlags: txFlags})
+
+	//address -- Fund an address from a P2TR output
+	case cmdFundP2TR.FullCommand():
+		err = multisig.OutputFundP2TR(multisig.FundP2TRFlags{PrivateKey: *cmdFundP2TRPrivateKey, InputTx: *cmdFundP2TRInputTx, InputIndex: *cmdFundP2TRInputIndex, InputAmount: *cmdFundP2TRInputAmount, Amount: *cmdFundP2TRAmount, Destination: *cmdFundP2TRDestination, TxFlags: txFlags})
 
 	//address -- Spend a multisig P2SH address
 	case cmdSpend.FullCommand():
-		multisig.OutputSpend(*cmdSpendPrivateKeys, *cmdSpendDestination, *cmdSpendRedeemScript, *cmdSpendInputTx, *cmdSpendAmount)
+		err = multisig.OutputSpend(multisig.SpendFlags{PrivateKeys: *cmdSpendPrivateKeys, Destination: *cmdSpendDestination, RedeemScript: *cmdSpendRedeemScript, InputTx: *cmdSpendInputTx, InputIndex: *cmdSpendInputIndex, Amount: *cmdSpendAmount, InputAmount: *cmdSpendInputAmount, Fee: *cmdSpendFee, FeeRate: *cmdSpendFeeRate, Force: *cmdSpendForce, Sweep: *cmdSpendSweep, TxFlags: txFlags})
+
+	//address -- Spend a multisig P2WSH output
+	case cmdRedeemP2WSH.FullCommand():
+		err = multisig.OutputRedeemP2WSH(multisig.RedeemP2WSHFlags{PrivateKeys: *cmdRedeemP2WSHPrivateKeys, Destination: *cmdRedeemP2WSHDestination, WitnessScript: *cmdRedeemP2WSHWitnessScript, Nested: *cmdRedeemP2WSHNested, InputTx: *cmdRedeemP2WSHInputTx, InputIndex: *cmdRedeemP2WSHInputIndex, InputAmount: *cmdRedeemP2WSHInputAmount, Amount: *cmdRedeemP2WSHAmount, TxFlags: txFlags})
+
+	//psbt-sign -- Sign the inputs of a PSBT
+	case cmdPSBTSign.FullCommand():
+		err = multisig.OutputPSBTSign(*cmdPSBTSignPSBT, *cmdPSBTSignPrivateKeys, *appSigHash, network)
+	//psbt-combine -- Combine signed copies of a PSBT
+	case cmdPSBTCombine.FullCommand():
+		err = multisig.OutputPSBTCombine(*cmdPSBTCombinePSBT)
+	//psbt-finalize -- Finalize a signed PSBT
+	case cmdPSBTFinalize.FullCommand():
+		err = multisig.OutputPSBTFinalize(*cmdPSBTFinalizePSBT)
+	//psbt-extract -- Extract the transaction of a finalized PSBT
+	case cmdPSBTExtract.FullCommand():
+		err = multisig.OutputPSBTExtract(*cmdPSBTExtractPSBT)
+
+	//sign-hash -- Sign a 32 byte hash
+	case cmdSignHash.FullCommand():
+		err = multisig.OutputSignHash(*cmdSignHashPrivateKey, *cmdSignHashHash, *cmdSignHashSchnorr, network)
+
+	//decode -- Decode a transaction and disassemble its scripts
+	case cmdDecode.FullCommand():
+		err = multisig.OutputDecode(*cmdDecodeTx, *cmdDecodeJSON, network)
+
+	//verify -- Verify the input scripts of a transaction
+	case cmdVerify.FullCommand():
+		err = multisig.OutputVerify(*cmdVerifyTx, *cmdVerifyPrevOut, network)
+	}
+	if err != nil {
+		log.Fatal(err)
 	}
 }
@@ -1,22 +1,64 @@
 // Package multisig contains the main starting threads for each of the subcommands for go-bitcoin-multisig.
 //
-// address.go - Generating P2SH addresses.
+// address.go - Generating P2SH, P2WSH, nested P2SH-P2WSH, Taproot and MuSig2 aggregate key addresses.
 package multisig
 
 import (
-	"github.com/prettymuchbryce/hellobitcoin/base58check"
 	"github.com/CryptoProcessing/go-bitcoin-multisig/btcutils"
 
 	"encoding/csv"
 	"encoding/hex"
+	"errors"
 	"fmt"
-	"log"
 	"strings"
 )
 
 //OutputAddress formats and prints relevant outputs to the user.
-func OutputAddress(flagM int, flagN int, flagPublicKeys string) {
-	P2SHAddress, redeemScriptHex := generateAddress(flagM, flagN, flagPublicKeys)
+func OutputAddress(flagM int, flagN int, flagPublicKeys string, flagP2WSH bool, flagNested bool, flagTaproot bool, flagMuSig2 bool, flagSortKeys bool, flagNetwork string) error {
+	if flagSortKeys && !flagMuSig2 {
+		return errors.New("--sort-keys can only be used with --musig2.")
+	}
+	if flagMuSig2 {
+		if flagP2WSH || flagNested || flagTaproot {
+			return errors.New("--musig2 cannot be used with --p2wsh, --nested or --taproot.")
+		}
+		return outputMuSig2Address(flagM, flagN, flagPublicKeys, flagSortKeys, flagNetwork)
+	}
+	if flagTaproot {
+		if flagP2WSH || flagNested {
+			return errors.New("--taproot cannot be used with --p2wsh or --nested.")
+		}
+		return outputTaprootAddress(flagM, flagN, flagPublicKeys, flagNetwork)
+	}
+	if flagNested {
+		if flagP2WSH {
+			return errors.New("--nested and --p2wsh cannot be used together.")
+		}
+		return outputNestedAddress(flagM, flagN, flagPublicKeys, flagNetwork)
+	}
+	P2SHAddress, P2WSHAddress, redeemScriptHex, err := generateAddress(flagM, flagN, flagPublicKeys, flagP2WSH, flagNetwork)
+	if err != nil {
+		return err
+	}
+	if flagP2WSH {
+		//Output P2WSH and witnessScript only
+		fmt.Printf(`
+-----------------------------------------------------------------------------------------------------------------------------------
+Your native SegWit *P2WSH ADDRESS* is:
+%v
+Give this to sender funding multisig address with Bitcoin.
+-----------------------------------------------------------------------------------------------------------------------------------
+-----------------------------------------------------------------------------------------------------------------------------------
+Your *WITNESS SCRIPT* is:
+%v
+Keep private and provide this to redeem multisig balance later with the redeem-p2wsh subcommand.
+-----------------------------------------------------------------------------------------------------------------------------------
+`,
+			P2WSHAddress,
+			redeemScriptHex,
+		)
+		return nil
+	}
 
 	if flagM*73+flagN*66 > 496 {
 		fmt.Printf(`
@@ -48,39 +90,298 @@ Keep private and provide this to redeem multisig balance later.
 		P2SHAddress,
 		redeemScriptHex,
 	)
+	if P2WSHAddress != "" {
+		fmt.Printf(`
+-----------------------------------------------------------------------------------------------------------------------------------
+Your native SegWit *P2WSH ADDRESS* is:
+%v
+Alternatively give this to sender funding multisig address with Bitcoin.
+The redeem script above is the witness script needed to spend from this address.
+-----------------------------------------------------------------------------------------------------------------------------------
+`,
+			P2WSHAddress,
+		)
+	}
+	return nil
 }
 
 // generateAddress is the high-level logic for creating P2SH multisig addresses with the 'go-bitcoin-multisig address' subcommand.
-// Takes flagM (number of keys required to spend), flagN (total number of keys)
-// and flagPublicKeys (comma separated list of N public keys) as arguments.
-func generateAddress(flagM int, flagN int, flagPublicKeys string) (string, string) {
-	//Convert public keys argument into slice of public key bytes with necessary tidying
-	flagPublicKeys = strings.Replace(flagPublicKeys, "'", "\"", -1) //Replace single quotes with double since csv package only recognizes double quotes
-	publicKeyStrings, err := csv.NewReader(strings.NewReader(flagPublicKeys)).Read()
+// Takes flagM (number of keys required to spend), flagN (total number of keys), flagPublicKeys (comma separated list
+// of N public keys), flagP2WSH (generate only the P2WSH address) and flagNetwork (name of the network to encode
+// addresses for) as arguments. Returns the P2SH address, the native SegWit P2WSH address for the same script, which
+// is only generated if all public keys are compressed as required for P2WSH to be standard, and the redeem script in
+// hex. With flagP2WSH no P2SH address is returned, and the script is held to the P2WSH witness script limits of 20
+// compressed public keys and 3600 bytes rather than the 520 byte P2SH limit.
+func generateAddress(flagM int, flagN int, flagPublicKeys string, flagP2WSH bool, flagNetwork string) (string, string, string, error) {
+	publicKeys, err := parsePublicKeys(flagPublicKeys)
 	if err != nil {
-		log.Fatal(err)
+		return "", "", "", err
 	}
-	publicKeys := make([][]byte, len(publicKeyStrings))
-	for i, publicKeyString := range publicKeyStrings {
-		publicKeyString = strings.TrimSpace(publicKeyString)   //Trim whitespace
-		publicKeys[i], err = hex.DecodeString(publicKeyString) //Get private keys as slice of raw bytes
+	params, err := btcutils.NetworkParamsByName(flagNetwork)
+	if err != nil {
+		return "", "", "", err
+	}
+	if flagP2WSH {
+		//Create witnessScript from public keys, and get P2WSH address by Bech32 encoding its SHA256
+		witnessScript, err := btcutils.NewMOfNWitnessScript(flagM, flagN, publicKeys)
+		if err != nil {
+			return "", "", "", err
+		}
+		P2WSHAddress, err := btcutils.RedeemScriptToP2WSHAddress(witnessScript, params)
 		if err != nil {
-			log.Fatal(err, "\n", "Offending publicKey: \n", publicKeyString)
+			return "", "", "", err
 		}
+		return "", P2WSHAddress, hex.EncodeToString(witnessScript), nil
 	}
 	//Create redeemScript from public keys
 	redeemScript, err := btcutils.NewMOfNRedeemScript(flagM, flagN, publicKeys)
 	if err != nil {
-		log.Fatal(err)
+		return "", "", "", err
 	}
-	redeemScriptHash, err := btcutils.Hash160(redeemScript)
+	//Get P2SH address by base58 encoding HASH160 of the redeemScript with network P2SH prefix
+	P2SHAddress, err := btcutils.RedeemScriptToP2SHAddress(redeemScript, params)
 	if err != nil {
-		log.Fatal(err)
+		return "", "", "", err
+	}
+	//Get P2WSH address by Bech32 encoding SHA256 of the redeemScript as a version 0 witness program
+	P2WSHAddress := ""
+	compressed := true
+	for _, publicKey := range publicKeys {
+		compressed = compressed && len(publicKey) == 33
+	}
+	if compressed {
+		P2WSHAddress, err = btcutils.RedeemScriptToP2WSHAddress(redeemScript, params)
+		if err != nil {
+			return "", "", "", err
+		}
 	}
-	//Get P2SH address by base58 encoding with P2SH prefix 0x05
-	P2SHAddress := base58check.Encode("05", redeemScriptHash)
 	//Get redeemScript in Hex
 	redeemScriptHex := hex.EncodeToString(redeemScript)
 
-	return P2SHAddress, redeemScriptHex
+	return P2SHAddress, P2WSHAddress, redeemScriptHex, nil
+}
+
+// outputNestedAddress formats and prints the nested SegWit P2SH-P2WSH address with its witness script and redeem
+// script, telling the user which goes where when spending, as mixing them up is the most common mistake.
+func outputNestedAddress(flagM int, flagN int, flagPublicKeys string, flagNetwork string) error {
+	nestedAddress, redeemScriptHex, witnessScriptHex, err := generateNestedAddress(flagM, flagN, flagPublicKeys, flagNetwork)
+	if err != nil {
+		return err
+	}
+	fmt.Printf(`
+-----------------------------------------------------------------------------------------------------------------------------------
+Your nested SegWit *P2SH-P2WSH ADDRESS* is:
+%v
+Give this to sender funding multisig address with Bitcoin, if they cannot pay to native SegWit addresses.
+-----------------------------------------------------------------------------------------------------------------------------------
+-----------------------------------------------------------------------------------------------------------------------------------
+Your *WITNESS SCRIPT* is:
+%v
+Keep private and provide this to redeem multisig balance later with redeem-p2wsh --nested.
+When spending, it goes in the input WITNESS after the signatures. It never goes in the scriptSig.
+-----------------------------------------------------------------------------------------------------------------------------------
+-----------------------------------------------------------------------------------------------------------------------------------
+Your *REDEEM SCRIPT* is:
+%v
+This is OP_0 followed by the SHA256 of the witness script. When spending, it is the only item in the input SCRIPTSIG.
+It is not the witness script, and redeem-p2wsh --nested derives it from the witness script for you.
+-----------------------------------------------------------------------------------------------------------------------------------
+`,
+		nestedAddress,
+		witnessScriptHex,
+		redeemScriptHex,
+	)
+	return nil
+}
+
+// generateNestedAddress is the high-level logic for creating nested SegWit P2SH-P2WSH multisig addresses with the
+// 'go-bitcoin-multisig address --nested' subcommand. Takes flagM (number of keys required to spend), flagN (total
+// number of keys), flagPublicKeys (comma separated list of N compressed public keys) and flagNetwork (name of the
+// network to encode the address for) as arguments. Returns the P2SH address, the redeem script OP_0 <SHA256 of
+// witness script> that goes in the scriptSig when spending, and the multisig witness script that goes in the witness,
+// both in hex. The witness script is held to the P2WSH limits of 20 public keys and 3600 bytes.
+func generateNestedAddress(flagM int, flagN int, flagPublicKeys string, flagNetwork string) (string, string, string, error) {
+	publicKeys, err := parsePublicKeys(flagPublicKeys)
+	if err != nil {
+		return "", "", "", err
+	}
+	params, err := btcutils.NetworkParamsByName(flagNetwork)
+	if err != nil {
+		return "", "", "", err
+	}
+	witnessScript, err := btcutils.NewMOfNWitnessScript(flagM, flagN, publicKeys)
+	if err != nil {
+		return "", "", "", err
+	}
+	//The redeemScript is the P2WSH scriptPubKey of the witnessScript, and the address is the P2SH address of that
+	redeemScript, err := btcutils.NewP2WSHScriptPubKey(witnessScript)
+	if err != nil {
+		return "", "", "", err
+	}
+	nestedAddress, err := btcutils.RedeemScriptToP2SHP2WSHAddress(witnessScript, params)
+	if err != nil {
+		return "", "", "", err
+	}
+	return nestedAddress, hex.EncodeToString(redeemScript), hex.EncodeToString(witnessScript), nil
+}
+
+// outputTaprootAddress formats and prints the Taproot address with its tapscript leaf, control block and internal key.
+func outputTaprootAddress(flagM int, flagN int, flagPublicKeys string, flagNetwork string) error {
+	taprootAddress, leafScriptHex, controlBlockHex, internalKeyHex, err := generateTaprootAddress(flagM, flagN, flagPublicKeys, flagNetwork)
+	if err != nil {
+		return err
+	}
+	fmt.Printf(`
+-----------------------------------------------------------------------------------------------------------------------------------
+Your Taproot *P2TR ADDRESS* is:
+%v
+Give this to sender funding multisig address with Bitcoin.
+-----------------------------------------------------------------------------------------------------------------------------------
+-----------------------------------------------------------------------------------------------------------------------------------
+Your *LEAF SCRIPT* is:
+%v
+Keep private and provide this to redeem multisig balance later. It is the only script in the Taproot script tree.
+-----------------------------------------------------------------------------------------------------------------------------------
+-----------------------------------------------------------------------------------------------------------------------------------
+Your *CONTROL BLOCK* is:
+%v
+When spending, it goes last in the input WITNESS, after the signatures and the leaf script.
+-----------------------------------------------------------------------------------------------------------------------------------
+-----------------------------------------------------------------------------------------------------------------------------------
+Your *INTERNAL KEY* is:
+%v
+This is the BIP341 unspendable key H, so the address can only be spent with the leaf script, never with the key path.
+-----------------------------------------------------------------------------------------------------------------------------------
+`,
+		taprootAddress,
+		leafScriptHex,
+		controlBlockHex,
+		internalKeyHex,
+	)
+	return nil
+}
+
+// generateTaprootAddress is the high-level logic for creating Taproot multisig addresses with the
+// 'go-bitcoin-multisig address --taproot' subcommand. Takes flagM (number of keys required to spend), flagN (total
+// number of keys), flagPublicKeys (comma separated list of N public keys) and flagNetwork (name of the network to
+// encode the address for) as arguments. The M-of-N multisig is the only leaf of the script tree, a BIP342
+// OP_CHECKSIGADD tapscript of the x-only public keys, and the internal key is btcutils.UnspendableInternalKey so the
+// key path cannot be used. Returns the Bech32m address, the leaf script, the control block needed to spend with the
+// leaf script, checked to reconstruct the output key, and the internal key, all in hex.
+func generateTaprootAddress(flagM int, flagN int, flagPublicKeys string, flagNetwork string) (string, string, string, string, error) {
+	publicKeys, err := parsePublicKeys(flagPublicKeys)
+	if err != nil {
+		return "", "", "", "", err
+	}
+	params, err := btcutils.NetworkParamsByName(flagNetwork)
+	if err != nil {
+		return "", "", "", "", err
+	}
+	leafScript, err := btcutils.NewMOfNTapscript(flagM, flagN, publicKeys)
+	if err != nil {
+		return "", "", "", "", err
+	}
+	internalKey := btcutils.UnspendableInternalKey
+	controlBlock, outputKey, err := btcutils.CreateTaprootControlBlock(internalKey, leafScript, nil)
+	if err != nil {
+		return "", "", "", "", err
+	}
+	//Make sure the control block proves the leaf script before anyone funds the address
+	err = btcutils.VerifyTaprootControlBlock(outputKey, leafScript, controlBlock)
+	if err != nil {
+		return "", "", "", "", err
+	}
+	//Get P2TR address by Bech32m encoding the output key as a version 1 witness program
+	taprootAddress, err := btcutils.Bech32Encode(params.Bech32HRP, 1, outputKey)
+	if err != nil {
+		return "", "", "", "", err
+	}
+	return taprootAddress, hex.EncodeToString(leafScript), hex.EncodeToString(controlBlock), hex.EncodeToString(internalKey), nil
+}
+
+// outputMuSig2Address formats and prints the Taproot address of the MuSig2 aggregate key with the aggregate key, and
+// warns that it cannot be spent with this tool.
+func outputMuSig2Address(flagM int, flagN int, flagPublicKeys string, flagSortKeys bool, flagNetwork string) error {
+	taprootAddress, aggregateKeyHex, err := generateMuSig2Address(flagM, flagN, flagPublicKeys, flagSortKeys, flagNetwork)
+	if err != nil {
+		return err
+	}
+	fmt.Printf(`
+-----------------------------------------------------------------------------------------------------------------------------------
+WARNING:
+Spending from this address needs all %d signers to run the interactive MuSig2 signing protocol, which go-bitcoin-multisig
+does not implement yet. Only fund it if you have other MuSig2 signing software, and test it with a small amount first.
+-----------------------------------------------------------------------------------------------------------------------------------
+-----------------------------------------------------------------------------------------------------------------------------------
+Your MuSig2 Taproot *P2TR ADDRESS* is:
+%v
+Give this to sender funding multisig address with Bitcoin. It looks like any single key Taproot address.
+-----------------------------------------------------------------------------------------------------------------------------------
+-----------------------------------------------------------------------------------------------------------------------------------
+Your MuSig2 *AGGREGATE PUBLIC KEY* is:
+%v
+This x-only key is the Taproot internal key. Signers need it and the public keys in the same order to sign.
+-----------------------------------------------------------------------------------------------------------------------------------
+`,
+		flagN,
+		taprootAddress,
+		aggregateKeyHex,
+	)
+	return nil
+}
+
+// generateMuSig2Address is the high-level logic for creating N-of-N MuSig2 addresses with the
+// 'go-bitcoin-multisig address --musig2' subcommand. Takes flagM (number of keys required to spend, which must be N),
+// flagN (total number of keys), flagPublicKeys (comma separated list of N compressed public keys), flagSortKeys (sort
+// public keys before aggregating them) and flagNetwork (name of the network to encode the address for) as arguments.
+// The public keys are aggregated with BIP327 KeyAgg, and the x-only aggregate key is the internal key of a Taproot
+// output with no script tree, as BIP86 recommends. Returns the Bech32m address and the x-only aggregate key in hex.
+func generateMuSig2Address(flagM int, flagN int, flagPublicKeys string, flagSortKeys bool, flagNetwork string) (string, string, error) {
+	if flagM != flagN {
+		return "", "", fmt.Errorf("MuSig2 key aggregation is N-of-N, so M must equal N. Provided M is %d and N is %d.", flagM, flagN)
+	}
+	publicKeys, err := parsePublicKeys(flagPublicKeys)
+	if err != nil {
+		return "", "", err
+	}
+	if len(publicKeys) != flagN {
+		return "", "", fmt.Errorf("Need exactly %d public keys to create MuSig2 address for %d-of-%d multisig. Only %d keys provided.", flagN, flagM, flagN, len(publicKeys))
+	}
+	params, err := btcutils.NetworkParamsByName(flagNetwork)
+	if err != nil {
+		return "", "", err
+	}
+	keyAggContext, err := btcutils.MuSig2KeyAgg(publicKeys, flagSortKeys)
+	if err != nil {
+		return "", "", err
+	}
+	taprootContext, err := keyAggContext.TaprootTweak(nil)
+	if err != nil {
+		return "", "", err
+	}
+	//Get P2TR address by Bech32m encoding the tweaked aggregate key as a version 1 witness program
+	taprootAddress, err := btcutils.Bech32Encode(params.Bech32HRP, 1, taprootContext.XOnlyPublicKey())
+	if err != nil {
+		return "", "", err
+	}
+	return taprootAddress, hex.EncodeToString(keyAggContext.XOnlyPublicKey()), nil
+}
+
+// parsePublicKeys converts a comma separated list of hex public keys into a slice of public key bytes with necessary
+// tidying. Whitespace is stripped and keys may be quoted.
+func parsePublicKeys(flagPublicKeys string) ([][]byte, error) {
+	flagPublicKeys = strings.Replace(flagPublicKeys, "'", "\"", -1) //Replace single quotes with double since csv package only recognizes double quotes
+	publicKeyStrings, err := csv.NewReader(strings.NewReader(flagPublicKeys)).Read()
+	if err != nil {
+		return nil, err
+	}
+	publicKeys := make([][]byte, len(publicKeyStrings))
+	for i, publicKeyString := range publicKeyStrings {
+		publicKeyString = strings.TrimSpace(publicKeyString)   //Trim whitespace
+		publicKeys[i], err = hex.DecodeString(publicKeyString) //Get public keys as slice of raw bytes
+		if err != nil {
+			return nil, fmt.Errorf("%v\nOffending publicKey: \n%s", err, publicKeyString)
+		}
+	}
+	return publicKeys, nil
 }
@@ -1,7 +1,11 @@
 package multisig
 
 import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/btcutils"
 	"github.com/CryptoProcessing/go-bitcoin-multisig/testutils"
+
+	"encoding/hex"
+	"strings"
 	"testing"
 )
 
@@ -14,13 +18,19 @@ func TestGenerateAddress(t *testing.T) {
 		testAddress := "347N1Thc213QqfYCz3PZkjoJpNv5b14kBd"
 		testRedeemScriptHex := "524104a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575fa349b5694ed3155b136f09e63975a1700c9f4d4df849323dac06cf3bd6458cd41046ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640de68c2fe913d363a51154a0c62d7adea1b822d05035077418267b1a1379790187410411ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef992f63280567f52f5ba870678b4ab4ff6c8ea600bd217870a8b4f1f09f3a8e8353ae"
 
-		P2SHAddress, redeemScriptHex := generateAddress(testM, testN, testPublicKeys)
+		P2SHAddress, P2WSHAddress, redeemScriptHex, err := generateAddress(testM, testN, testPublicKeys, false, "mainnet")
+		if err != nil {
+			t.Error(err)
+		}
 		if testAddress != P2SHAddress {
 			testutils.CompareError(t, "Generated P2SH address different from expected address.", testAddress, P2SHAddress)
 		}
 		if testRedeemScriptHex != redeemScriptHex {
 			testutils.CompareError(t, "Generated P2SH address different from expected address.", testRedeemScriptHex, redeemScriptHex)
 		}
+		if P2WSHAddress != "" {
+			t.Error("P2WSH address generated for uncompressed public keys.")
+		}
 	}
 	{
 		//7-of-7 multisig test
@@ -30,13 +40,19 @@ func TestGenerateAddress(t *testing.T) {
 		testAddress := "3ErDPiDD7AsJDqKkayMA39iLJevTjDCjUa"
 		testRedeemScriptHex := "57410446f1c8de232a065da428bf76e44b41f59a46620dec0aedfc9b5ab651e91f2051d610fddc78b8eba38a634bfe9a74bb015a88c52b9b844c74997035e08a695ce94104704e19d4fc234a42d707d41053c87011f990b564949532d72cab009e136bd60d7d0602f925fce79da77c0dfef4a49c6f44bd0540faef548e37557d74b36da1244104b75a8cb10fd3f1785addbafdb41b409ecd6ffd50d5ad71d8a3cdc5503bcb35d3d13cdf23f6d0eb6ab88446276e2ba5b92d8786da7e5c0fb63aafb62f87443d284104033a82ccb1291bbc27cf541c6c487c213f25db85c620ecb9cbb76ca461ef13db5a80b90c3ae7d2a5e47623cdf520a2586cac7e41f779103a71a1fe177189781e41045e3b4030be5fd9c4c40e7076bd49f022118d90ae9182de61f3a1adb2ff511c97e8a6a82a9292b01878a18c08b7cd658ebdf80e6ed3f26783b25ba1a52fa9e52d4104c93ceb8f4482e131addc58d3efa0b4967bb7c574de15786d55379cc4a43a61571518abe0f05ebf188bcce9580aa70b3f5b1024ca579819c8810ff79967de3f234104a66f63d2941f0befcfba4b73495a7b99fc7ed28cb41e7934e1de82d852628766dc96ee1e196387a68e7fd8898862c2260f1f2557ac2147af07900695f15abd3f57ae"
 
-		P2SHAddress, redeemScriptHex := generateAddress(testM, testN, testPublicKeys)
+		P2SHAddress, P2WSHAddress, redeemScriptHex, err := generateAddress(testM, testN, testPublicKeys, false, "mainnet")
+		if err != nil {
+			t.Error(err)
+		}
 		if testAddress != P2SHAddress {
 			testutils.CompareError(t, "Generated P2SH address different from expected address.", testAddress, P2SHAddress)
 		}
 		if testRedeemScriptHex != redeemScriptHex {
 			testutils.CompareError(t, "Generated P2SH address different from expected address.", testRedeemScriptHex, redeemScriptHex)
 		}
+		if P2WSHAddress != "" {
+			t.Error("P2WSH address generated for uncompressed public keys.")
+		}
 	}
 	{
 		//5-of-7 multisig test
@@ -46,12 +62,245 @@ func TestGenerateAddress(t *testing.T) {
 		testAddress := "34wgSuG9qtaNEV4MGye9UJcffcFTxnmXSC"
 		testRedeemScriptHex := "554104c22e4293d1d462eef905e592ad4aff332aa52c3415b824cd85cf594258d92c836fe797187bc2459261e0597c4ef351c5d0c26f7a60165221e221a38e448ad08c4104bb28684dfe23852a7c276827dd448c955007e7ccbfacbf536e13f1097b30430ebec5af0bc001e50d3f0e796d52ba43e3c07337bfed2a842659d51632f2b21d2841048f8551173f8e7414ff0e144899b3f70accd957e6913f5cf877bd576f6c16f0aa67fb9b96e0df10562b4f7ba4060acd22f142329ff83f1d96e27f4e4394adeda24104aa81def7dda6a4f40be2f3287ee3423f255b07965104a7888df075217c9ee5b3e9e2e70115d43bfecbff8062f8289f5cab3d0ebd96c9f55c85f6147ff3a5e9494104493aa5f89ec34184a235b2c9f608eade1634636f94f64b59419875e15cb86a6d8c708a9d5eda3304cb983b2325a57af881ed75f28179f5f263d7758039b68d894104dc284f749208d7fec57937bc5e72187b064df7d29b7aa82cae273e9a1c91beae9c510e0fd632a3db272c67db04061ea761d1ed91fdb8ab07e354047c64ce405d41042fc7796f54dd482db20f1bcce584f930ae74d5f27fc8336e2701bd0243d681281810c57e079947ebdfdfc8860ed34b0ba32db82a85249adc7c64ab547d48af6457ae"
 
-		P2SHAddress, redeemScriptHex := generateAddress(testM, testN, testPublicKeys)
+		P2SHAddress, P2WSHAddress, redeemScriptHex, err := generateAddress(testM, testN, testPublicKeys, false, "mainnet")
+		if err != nil {
+			t.Error(err)
+		}
+		if testAddress != P2SHAddress {
+			testutils.CompareError(t, "Generated P2SH address different from expected address.", testAddress, P2SHAddress)
+		}
+		if testRedeemScriptHex != redeemScriptHex {
+			testutils.CompareError(t, "Generated P2SH address different from expected address.", testRedeemScriptHex, redeemScriptHex)
+		}
+		if P2WSHAddress != "" {
+			t.Error("P2WSH address generated for uncompressed public keys.")
+		}
+	}
+	{
+		//2-of-3 multisig test with compressed public keys
+		testM := 2
+		testN := 3
+		testPublicKeys := "025476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee6357,03b6446545566e0fecb2f2243db709bbf4e9fff78d48c9438acc911a36015e896a,033d63026f5f0de46e40dc09c61c1559240ea4381df7e05045f6ae2ac506efbaf2"
+		testAddress := "3C5FuuWF53tDtxUmZ2xVUiBdjSwHvsgguG"
+		testP2WSHAddress := "bc1qcdkgag7pz4cqgn664ek7ael7ukrvwtwvt9g2rhhznsvafsg6k6dqesjmyu"
+		testRedeemScriptHex := "5221025476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee63572103b6446545566e0fecb2f2243db709bbf4e9fff78d48c9438acc911a36015e896a21033d63026f5f0de46e40dc09c61c1559240ea4381df7e05045f6ae2ac506efbaf253ae"
+
+		P2SHAddress, P2WSHAddress, redeemScriptHex, err := generateAddress(testM, testN, testPublicKeys, false, "mainnet")
+		if err != nil {
+			t.Error(err)
+		}
 		if testAddress != P2SHAddress {
 			testutils.CompareError(t, "Generated P2SH address different from expected address.", testAddress, P2SHAddress)
 		}
+		if testP2WSHAddress != P2WSHAddress {
+			testutils.CompareError(t, "Generated P2WSH address different from expected address.", testP2WSHAddress, P2WSHAddress)
+		}
 		if testRedeemScriptHex != redeemScriptHex {
 			testutils.CompareError(t, "Generated P2SH address different from expected address.", testRedeemScriptHex, redeemScriptHex)
 		}
 	}
+	{
+		//2-of-3 multisig test with compressed public keys on testnet3
+		testM := 2
+		testN := 3
+		testPublicKeys := "025476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee6357,03b6446545566e0fecb2f2243db709bbf4e9fff78d48c9438acc911a36015e896a,033d63026f5f0de46e40dc09c61c1559240ea4381df7e05045f6ae2ac506efbaf2"
+		testAddress := "2N3dTyeSGgWPa6k7KEAaN6fAtwo9TgPCZqH"
+		testP2WSHAddress := "tb1qcdkgag7pz4cqgn664ek7ael7ukrvwtwvt9g2rhhznsvafsg6k6dqwcy57n"
+
+		P2SHAddress, P2WSHAddress, _, err := generateAddress(testM, testN, testPublicKeys, false, "testnet3")
+		if err != nil {
+			t.Error(err)
+		}
+		if testAddress != P2SHAddress {
+			testutils.CompareError(t, "Generated testnet P2SH address different from expected address.", testAddress, P2SHAddress)
+		}
+		if testP2WSHAddress != P2WSHAddress {
+			testutils.CompareError(t, "Generated testnet P2WSH address different from expected address.", testP2WSHAddress, P2WSHAddress)
+		}
+	}
+	{
+		//Native SegWit addresses use the regtest human readable part
+		testPublicKeys := "025476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee6357,03b6446545566e0fecb2f2243db709bbf4e9fff78d48c9438acc911a36015e896a,033d63026f5f0de46e40dc09c61c1559240ea4381df7e05045f6ae2ac506efbaf2"
+		testAddress := "2N3dTyeSGgWPa6k7KEAaN6fAtwo9TgPCZqH"
+		testP2WSHAddress := "bcrt1qcdkgag7pz4cqgn664ek7ael7ukrvwtwvt9g2rhhznsvafsg6k6dqrpwjtf"
+
+		P2SHAddress, P2WSHAddress, _, err := generateAddress(2, 3, testPublicKeys, false, "regtest")
+		if err != nil {
+			t.Error(err)
+		}
+		if testAddress != P2SHAddress {
+			testutils.CompareError(t, "Generated regtest P2SH address different from expected address.", testAddress, P2SHAddress)
+		}
+		if testP2WSHAddress != P2WSHAddress {
+			testutils.CompareError(t, "Generated regtest P2WSH address different from expected address.", testP2WSHAddress, P2WSHAddress)
+		}
+		if _, _, _, err := generateAddress(2, 3, testPublicKeys, false, "testnet"); err == nil {
+			t.Error("generateAddress accepting unknown network.")
+		}
+	}
+}
+
+func TestGenerateAddressP2WSH(t *testing.T) {
+	//Same 2-of-3 witness script and P2WSH address as without --p2wsh, but no P2SH address
+	testPublicKeys := "025476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee6357,03b6446545566e0fecb2f2243db709bbf4e9fff78d48c9438acc911a36015e896a,033d63026f5f0de46e40dc09c61c1559240ea4381df7e05045f6ae2ac506efbaf2"
+	testP2WSHAddress := "bc1qcdkgag7pz4cqgn664ek7ael7ukrvwtwvt9g2rhhznsvafsg6k6dqesjmyu"
+	testWitnessScriptHex := "5221025476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee63572103b6446545566e0fecb2f2243db709bbf4e9fff78d48c9438acc911a36015e896a21033d63026f5f0de46e40dc09c61c1559240ea4381df7e05045f6ae2ac506efbaf253ae"
+
+	P2SHAddress, P2WSHAddress, witnessScriptHex, err := generateAddress(2, 3, testPublicKeys, true, "mainnet")
+	if err != nil {
+		t.Error(err)
+	}
+	if P2SHAddress != "" {
+		t.Error("P2SH address generated with --p2wsh.")
+	}
+	if testP2WSHAddress != P2WSHAddress {
+		testutils.CompareError(t, "Generated P2WSH address different from expected address.", testP2WSHAddress, P2WSHAddress)
+	}
+	if testWitnessScriptHex != witnessScriptHex {
+		testutils.CompareError(t, "Generated witness script different from expected script.", testWitnessScriptHex, witnessScriptHex)
+	}
+
+	//Compressed public keys of private keys 1 to 20, too many for P2SH but not for P2WSH
+	publicKeyStrings := make([]string, 20)
+	for i := range publicKeyStrings {
+		privateKey := make([]byte, 32)
+		privateKey[31] = byte(i + 1)
+		publicKey, _ := btcutils.NewCompressedPublicKey(privateKey)
+		publicKeyStrings[i] = hex.EncodeToString(publicKey)
+	}
+	testManyPublicKeys := strings.Join(publicKeyStrings, ",")
+	_, P2WSHAddress, witnessScriptHex, err = generateAddress(15, 20, testManyPublicKeys, true, "mainnet")
+	if err != nil {
+		t.Error(err)
+	}
+	if !strings.HasPrefix(P2WSHAddress, "bc1q") || !strings.HasSuffix(witnessScriptHex, "0114ae") {
+		t.Errorf("Unexpected 15-of-20 P2WSH address %s or witness script %s.", P2WSHAddress, witnessScriptHex)
+	}
+	if _, _, _, err := generateAddress(15, 20, testManyPublicKeys, false, "mainnet"); err == nil {
+		t.Error("generateAddress accepting 15-of-20 P2SH redeem script over 520 bytes.")
+	}
+
+	//Uncompressed public keys are not standard in P2WSH
+	testUncompressedPublicKeys := "04a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575fa349b5694ed3155b136f09e63975a1700c9f4d4df849323dac06cf3bd6458cd,046ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640de68c2fe913d363a51154a0c62d7adea1b822d05035077418267b1a1379790187"
+	if _, _, _, err := generateAddress(1, 2, testUncompressedPublicKeys, true, "mainnet"); err == nil {
+		t.Error("generateAddress accepting uncompressed public keys with --p2wsh.")
+	}
+}
+
+func TestGenerateNestedAddress(t *testing.T) {
+	testPublicKeys := "025476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee6357,03b6446545566e0fecb2f2243db709bbf4e9fff78d48c9438acc911a36015e896a,033d63026f5f0de46e40dc09c61c1559240ea4381df7e05045f6ae2ac506efbaf2"
+	testCases := []struct {
+		network          string
+		address          string
+		redeemScriptHex  string
+		witnessScriptHex string
+	}{
+		{"mainnet", "3FawY1gpSKNqz23RtxWPcTMA6cqS43hceq", "0020c36c8ea3c11570044f5aae6deee7fee586c72dcc5950a1dee29c19d4c11ab69a", "5221025476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee63572103b6446545566e0fecb2f2243db709bbf4e9fff78d48c9438acc911a36015e896a21033d63026f5f0de46e40dc09c61c1559240ea4381df7e05045f6ae2ac506efbaf253ae"},
+		{"testnet3", "2N799bkcr3mtCBofya68GEQLRJy3bqMWn88", "0020c36c8ea3c11570044f5aae6deee7fee586c72dcc5950a1dee29c19d4c11ab69a", "5221025476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee63572103b6446545566e0fecb2f2243db709bbf4e9fff78d48c9438acc911a36015e896a21033d63026f5f0de46e40dc09c61c1559240ea4381df7e05045f6ae2ac506efbaf253ae"},
+	}
+	for _, testCase := range testCases {
+		nestedAddress, redeemScriptHex, witnessScriptHex, err := generateNestedAddress(2, 3, testPublicKeys, testCase.network)
+		if err != nil {
+			t.Error(err)
+		}
+		if nestedAddress != testCase.address {
+			testutils.CompareError(t, "Generated P2SH-P2WSH address different from expected address.", testCase.address, nestedAddress)
+		}
+		if redeemScriptHex != testCase.redeemScriptHex {
+			testutils.CompareError(t, "Generated P2SH-P2WSH redeem script different from expected script.", testCase.redeemScriptHex, redeemScriptHex)
+		}
+		if witnessScriptHex != testCase.witnessScriptHex {
+			testutils.CompareError(t, "Generated P2SH-P2WSH witness script different from expected script.", testCase.witnessScriptHex, witnessScriptHex)
+		}
+	}
+
+	//Uncompressed public keys are not standard in P2WSH, nested or not
+	testUncompressedPublicKeys := "04a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575fa349b5694ed3155b136f09e63975a1700c9f4d4df849323dac06cf3bd6458cd,046ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640de68c2fe913d363a51154a0c62d7adea1b822d05035077418267b1a1379790187"
+	if _, _, _, err := generateNestedAddress(1, 2, testUncompressedPublicKeys, "mainnet"); err == nil {
+		t.Error("generateNestedAddress accepting uncompressed public keys.")
+	}
+	if err := OutputAddress(2, 3, testPublicKeys, true, true, false, false, false, "mainnet"); err == nil {
+		t.Error("OutputAddress accepting --nested with --p2wsh.")
+	}
+}
+
+func TestGenerateTaprootAddress(t *testing.T) {
+	testPublicKeys := "025476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee6357,03b6446545566e0fecb2f2243db709bbf4e9fff78d48c9438acc911a36015e896a,033d63026f5f0de46e40dc09c61c1559240ea4381df7e05045f6ae2ac506efbaf2"
+	testLeafScriptHex := "205476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee6357ac20b6446545566e0fecb2f2243db709bbf4e9fff78d48c9438acc911a36015e896aba203d63026f5f0de46e40dc09c61c1559240ea4381df7e05045f6ae2ac506efbaf2ba529c"
+	testControlBlockHex := "c050929b74c1a04954b78b4b6035e97a5e078a5a0f28ec96d547bfee9ace803ac0"
+	testInternalKeyHex := "50929b74c1a04954b78b4b6035e97a5e078a5a0f28ec96d547bfee9ace803ac0"
+	testCases := []struct {
+		network string
+		address string
+	}{
+		{"mainnet", "bc1pj0xm6nj29552t2yml20psu70g0a9379gc59yrq79h44zw4cecvcqtlryf6"},
+		{"testnet3", "tb1pj0xm6nj29552t2yml20psu70g0a9379gc59yrq79h44zw4cecvcquh4tn4"},
+	}
+	for _, testCase := range testCases {
+		taprootAddress, leafScriptHex, controlBlockHex, internalKeyHex, err := generateTaprootAddress(2, 3, testPublicKeys, testCase.network)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if taprootAddress != testCase.address {
+			testutils.CompareError(t, "Generated Taproot address different from expected address.", testCase.address, taprootAddress)
+		}
+		if leafScriptHex != testLeafScriptHex {
+			testutils.CompareError(t, "Generated Taproot leaf script different from expected script.", testLeafScriptHex, leafScriptHex)
+		}
+		if controlBlockHex != testControlBlockHex {
+			testutils.CompareError(t, "Generated Taproot control block different from expected control block.", testControlBlockHex, controlBlockHex)
+		}
+		if internalKeyHex != testInternalKeyHex {
+			testutils.CompareError(t, "Generated Taproot internal key different from expected key.", testInternalKeyHex, internalKeyHex)
+		}
+	}
+
+	if _, _, _, _, err := generateTaprootAddress(2, 4, testPublicKeys, "mainnet"); err == nil {
+		t.Error("generateTaprootAddress accepting fewer public keys than N.")
+	}
+	if _, _, _, _, err := generateTaprootAddress(4, 3, testPublicKeys, "mainnet"); err == nil {
+		t.Error("generateTaprootAddress accepting M larger than N.")
+	}
+	if err := OutputAddress(2, 3, testPublicKeys, false, true, true, false, false, "mainnet"); err == nil {
+		t.Error("OutputAddress accepting --taproot with --nested.")
+	}
+}
+
+func TestGenerateMuSig2Address(t *testing.T) {
+	testPublicKeys := "025476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee6357,03b6446545566e0fecb2f2243db709bbf4e9fff78d48c9438acc911a36015e896a,033d63026f5f0de46e40dc09c61c1559240ea4381df7e05045f6ae2ac506efbaf2"
+	testCases := []struct {
+		sortKeys     bool
+		network      string
+		address      string
+		aggregateKey string
+	}{
+		{false, "mainnet", "bc1pktqsu3ed46069sdgs5d8z3hrhgvl0pktv9zp58ga9jvh620ndkkqs6tx79", "0bde40826d2e790b90b250b2cae00c566799698c607a7dc941d30e468bd1b88b"},
+		{false, "testnet3", "tb1pktqsu3ed46069sdgs5d8z3hrhgvl0pktv9zp58ga9jvh620ndkkq8jafy2", "0bde40826d2e790b90b250b2cae00c566799698c607a7dc941d30e468bd1b88b"},
+		{true, "mainnet", "bc1p64008g77dnygznts3709fzjcvy9khx34h2t5d8m9gjpx2mxx48yq5lvp98", "9e0355582078e1e4c2695e8b24dc468a209b2b7ed4988704d46c1e1547868d02"},
+	}
+	for _, testCase := range testCases {
+		taprootAddress, aggregateKeyHex, err := generateMuSig2Address(3, 3, testPublicKeys, testCase.sortKeys, testCase.network)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if taprootAddress != testCase.address {
+			testutils.CompareError(t, "Generated MuSig2 address different from expected address.", testCase.address, taprootAddress)
+		}
+		if aggregateKeyHex != testCase.aggregateKey {
+			testutils.CompareError(t, "Generated MuSig2 aggregate key different from expected key.", testCase.aggregateKey, aggregateKeyHex)
+		}
+	}
+
+	if _, _, err := generateMuSig2Address(2, 3, testPublicKeys, false, "mainnet"); err == nil {
+		t.Error("generateMuSig2Address accepting M smaller than N.")
+	}
+	if _, _, err := generateMuSig2Address(4, 4, testPublicKeys, false, "mainnet"); err == nil {
+		t.Error("generateMuSig2Address accepting fewer public keys than N.")
+	}
+	if err := OutputAddress(3, 3, testPublicKeys, false, false, true, true, false, "mainnet"); err == nil {
+		t.Error("OutputAddress accepting --musig2 with --taproot.")
+	}
+	if err := OutputAddress(3, 3, testPublicKeys, false, false, false, false, true, "mainnet"); err == nil {
+		t.Error("OutputAddress accepting --sort-keys without --musig2.")
+	}
 }
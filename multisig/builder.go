@@ -0,0 +1,487 @@
+// builder.go - Building and signing funding and spending transactions from Go code, without the command line. The
+// fund, fund-p2wpkh, fund-p2sh-p2wpkh, fund-p2tr, spend and redeem-p2wsh subcommands parse their flags and call into
+// these.
+package multisig
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/btcutils"
+
+	"errors"
+	"fmt"
+)
+
+// FundP2SHOptions describes a transaction spending a single P2PKH output, such as one funding a P2SH multisig
+// address, to be signed by FundP2SH.
+type FundP2SHOptions struct {
+	PrivateKey       string               //WIF private key the input pays to, compressed or not as the WIF says
+	Input            btcutils.Input       //Output spent, with the sequence of the input. Its scriptSig is ignored
+	InputAmount      int64                //Satoshis held by the output spent, required to check the fee unless ForceHighFee is set
+	Outputs          []btcutils.Output    //Outputs paid to, such as btcutils.NewScriptPubKeyFromAddress of each destination
+	Version          uint32               //Transaction version, 2 if zero
+	LockTime         uint32               //Transaction lock time
+	SigHashType      btcutils.SigHashType //Hash type to sign with, SIGHASH_ALL if zero
+	Network          string               //Name of the network PrivateKey is encoded for, mainnet if empty
+	MaxFee           *int64               //Highest fee in satoshis, btcutils.DefaultMaxFee if nil. Zero refuses any fee
+	Change           int64                //Satoshis of Outputs paid back to the sender, not counted as sent when checking the fee
+	ForceHighFee     bool                 //Allow a fee above MaxFee or btcutils.MaxFeePercent of the amount sent
+	PrevScriptPubKey []byte               //Optional scriptPubKey of the output spent, checked to pay to PrivateKey
+}
+
+// FundP2SH builds the transaction described by opts and signs its P2PKH input. Returns the signed transaction, ready
+// to broadcast, or an error if the key, input or outputs are invalid, the fee is too high or unknown, or the input
+// pays to another key than PrivateKey by opts.PrevScriptPubKey, as checked by btcutils.CheckInputKey.
+func FundP2SH(opts FundP2SHOptions) ([]byte, error) {
+	params, err := builderNetworkParams(opts.Network)
+	if err != nil {
+		return nil, err
+	}
+	privateKey, compressed, err := btcutils.ParseWIF(opts.PrivateKey, params)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid private key. %s", err)
+	}
+	var publicKey []byte
+	if compressed {
+		publicKey, err = btcutils.NewCompressedPublicKey(privateKey)
+	} else {
+		publicKey, err = btcutils.NewPublicKey(privateKey)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if opts.PrevScriptPubKey != nil {
+		err = btcutils.CheckInputKey(opts.Input, opts.PrevScriptPubKey, publicKey, nil, params)
+		if err != nil {
+			return nil, err
+		}
+	}
+	publicKeyHash, err := btcutils.Hash160(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	scriptPubKey, err := btcutils.NewP2PKHScriptPubKey(publicKeyHash)
+	if err != nil {
+		return nil, err
+	}
+	tx, hashType, err := builderTransaction(opts.Input, opts.Outputs, opts.Version, opts.LockTime, opts.SigHashType)
+	if err != nil {
+		return nil, err
+	}
+	err = builderCheckFee(opts.InputAmount, opts.Outputs, opts.Change, opts.MaxFee, opts.ForceHighFee)
+	if err != nil {
+		return nil, err
+	}
+	return signP2PKHTransaction(tx, privateKey, publicKey, scriptPubKey, hashType)
+}
+
+// SpendP2SHOptions describes a transaction spending a single P2SH multisig output, to be signed by SpendP2SH.
+type SpendP2SHOptions struct {
+	PrivateKeys      []string             //WIF private keys to sign with, in the order of their public keys in RedeemScript
+	RedeemScript     []byte               //Script the P2SH output pays to, such as an M-of-N multisig
+	Input            btcutils.Input       //Output spent, with the sequence of the input. Its scriptSig is ignored
+	InputAmount      int64                //Satoshis held by the output spent, required to check the fee unless ForceHighFee is set
+	Outputs          []btcutils.Output    //Outputs paid to, such as btcutils.NewScriptPubKeyFromAddress of each destination
+	Version          uint32               //Transaction version, 2 if zero
+	LockTime         uint32               //Transaction lock time
+	SigHashType      btcutils.SigHashType //Hash type to sign with, SIGHASH_ALL if zero
+	Network          string               //Name of the network PrivateKeys are encoded for, mainnet if empty
+	MaxFee           *int64               //Highest fee in satoshis, btcutils.DefaultMaxFee if nil. Zero refuses any fee
+	Change           int64                //Satoshis of Outputs paid back to the sender, not counted as sent when checking the fee
+	ForceHighFee     bool                 //Allow a fee above MaxFee or btcutils.MaxFeePercent of the amount sent
+	PrevScriptPubKey []byte               //Optional scriptPubKey of the output spent, checked to pay to RedeemScript
+}
+
+// SpendP2SH builds the transaction described by opts and signs its P2SH input with each of the private keys.
+// Returns the signed transaction, ready to broadcast, or an error if the fee is too high or unknown, or the keys,
+// redeemScript, input or outputs are invalid, including an input whose relative lock time does not satisfy an
+// OP_CHECKSEQUENCEVERIFY of the redeemScript, or one paying to another script than RedeemScript by
+// opts.PrevScriptPubKey, as checked by btcutils.CheckInputKey.
+func SpendP2SH(opts SpendP2SHOptions) ([]byte, error) {
+	if len(opts.RedeemScript) == 0 {
+		return nil, errors.New("Redeem script cannot be empty.")
+	}
+	if len(opts.PrivateKeys) == 0 {
+		return nil, errors.New("At least one private key is needed to spend a P2SH output.")
+	}
+	params, err := builderNetworkParams(opts.Network)
+	if err != nil {
+		return nil, err
+	}
+	if opts.PrevScriptPubKey != nil {
+		err = btcutils.CheckInputKey(opts.Input, opts.PrevScriptPubKey, nil, opts.RedeemScript, params)
+		if err != nil {
+			return nil, err
+		}
+	}
+	//Compression doesn't matter here since the public keys are given by the redeemScript
+	privateKeys := make([][]byte, len(opts.PrivateKeys))
+	for i, wif := range opts.PrivateKeys {
+		privateKeys[i], _, err = btcutils.ParseWIF(wif, params)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid private key %d. %s", i+1, err)
+		}
+	}
+	tx, hashType, err := builderTransaction(opts.Input, opts.Outputs, opts.Version, opts.LockTime, opts.SigHashType)
+	if err != nil {
+		return nil, err
+	}
+	err = builderCheckFee(opts.InputAmount, opts.Outputs, opts.Change, opts.MaxFee, opts.ForceHighFee)
+	if err != nil {
+		return nil, err
+	}
+	relativeLockTimes, err := btcutils.FindCheckSequenceVerify(opts.RedeemScript)
+	if err != nil {
+		return nil, err
+	}
+	for _, relativeLockTime := range relativeLockTimes {
+		err = btcutils.CheckSequenceVerify(tx, 0, relativeLockTime)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return signMultisigTransaction(tx, privateKeys, opts.RedeemScript, hashType)
+}
+
+// FundP2WPKHOptions describes a transaction spending a single native SegWit P2WPKH output, to be signed by
+// FundP2WPKH.
+type FundP2WPKHOptions struct {
+	PrivateKey       string               //Compressed WIF private key the input pays to
+	Input            btcutils.Input       //Output spent, with the sequence of the input. Its scriptSig is ignored
+	InputAmount      int64                //Satoshis held by the output spent, committed to by the BIP143 signature
+	Outputs          []btcutils.Output    //Outputs paid to, such as btcutils.NewScriptPubKeyFromAddress of each destination
+	Version          uint32               //Transaction version, 2 if zero
+	LockTime         uint32               //Transaction lock time
+	SigHashType      btcutils.SigHashType //Hash type to sign with, SIGHASH_ALL if zero
+	Network          string               //Name of the network PrivateKey is encoded for, mainnet if empty
+	MaxFee           *int64               //Highest fee in satoshis, btcutils.DefaultMaxFee if nil. Zero refuses any fee
+	Change           int64                //Satoshis of Outputs paid back to the sender, not counted as sent when checking the fee
+	ForceHighFee     bool                 //Allow a fee above MaxFee or btcutils.MaxFeePercent of the amount sent
+	PrevScriptPubKey []byte               //Optional scriptPubKey of the output spent, checked to pay to PrivateKey
+}
+
+// FundP2WPKH builds the transaction described by opts and signs its P2WPKH input, putting the signature and public
+// key in the witness. Returns the signed transaction, ready to broadcast, or an error if the key, input amount,
+// input or outputs are invalid, the fee is too high, or the input pays to another key than PrivateKey by
+// opts.PrevScriptPubKey.
+func FundP2WPKH(opts FundP2WPKHOptions) ([]byte, error) {
+	return fundP2WPKH(opts, false)
+}
+
+// FundP2SHP2WPKHOptions describes a transaction spending a single nested SegWit P2SH-P2WPKH output, to be signed by
+// FundP2SHP2WPKH. The fields are those of FundP2WPKHOptions.
+type FundP2SHP2WPKHOptions FundP2WPKHOptions
+
+// FundP2SHP2WPKH builds the transaction described by opts and signs its P2SH-P2WPKH input, pushing the redeem script
+// OP_0 <HASH160(pubkey)> in the scriptSig and putting the signature and public key in the witness. Returns the signed
+// transaction, ready to broadcast, or an error as FundP2WPKH does.
+func FundP2SHP2WPKH(opts FundP2SHP2WPKHOptions) ([]byte, error) {
+	return fundP2WPKH(FundP2WPKHOptions(opts), true)
+}
+
+// FundP2TROptions describes a transaction spending a single Taproot P2TR output with the key path, to be signed by
+// FundP2TR. The output must pay to the public key of PrivateKey tweaked without a script tree, as BIP86 wallets do.
+type FundP2TROptions struct {
+	PrivateKey       string               //WIF private key the input pays to, compressed or not
+	Input            btcutils.Input       //Output spent, with the sequence of the input. Its scriptSig is ignored
+	InputAmount      int64                //Satoshis held by the output spent, committed to by the BIP341 signature
+	Outputs          []btcutils.Output    //Outputs paid to, such as btcutils.NewScriptPubKeyFromAddress of each destination
+	Version          uint32               //Transaction version, 2 if zero
+	LockTime         uint32               //Transaction lock time
+	SigHashType      btcutils.SigHashType //Hash type to sign with, SIGHASH_DEFAULT if zero
+	Network          string               //Name of the network PrivateKey is encoded for, mainnet if empty
+	MaxFee           *int64               //Highest fee in satoshis, btcutils.DefaultMaxFee if nil. Zero refuses any fee
+	Change           int64                //Satoshis of Outputs paid back to the sender, not counted as sent when checking the fee
+	ForceHighFee     bool                 //Allow a fee above MaxFee or btcutils.MaxFeePercent of the amount sent
+	PrevScriptPubKey []byte               //Optional scriptPubKey of the output spent, checked to pay to PrivateKey
+}
+
+// FundP2TR builds the transaction described by opts and signs its P2TR input with a BIP340 Schnorr signature of the
+// tweaked private key. Returns the signed transaction, ready to broadcast, or an error if the key, input amount, input
+// or outputs are invalid, the fee is too high, or the input pays to another key than PrivateKey by
+// opts.PrevScriptPubKey.
+func FundP2TR(opts FundP2TROptions) ([]byte, error) {
+	if opts.InputAmount <= 0 {
+		return nil, errors.New("Input amount is required to sign a P2TR input.")
+	}
+	params, err := builderNetworkParams(opts.Network)
+	if err != nil {
+		return nil, err
+	}
+	//Taproot keys are x-only, so WIF compression doesn't matter
+	privateKey, _, err := btcutils.ParseWIF(opts.PrivateKey, params)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid private key. %s", err)
+	}
+	publicKey, err := btcutils.NewCompressedPublicKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	if opts.PrevScriptPubKey != nil {
+		err = btcutils.CheckInputKey(opts.Input, opts.PrevScriptPubKey, publicKey, nil, params)
+		if err != nil {
+			return nil, err
+		}
+	}
+	outputKey, err := btcutils.TweakPublicKey(publicKey[1:], nil)
+	if err != nil {
+		return nil, err
+	}
+	inputScriptPubKey, err := btcutils.CreateP2TRScriptPubKey(outputKey)
+	if err != nil {
+		return nil, err
+	}
+	//A zero hash type is SIGHASH_DEFAULT for Taproot, so the SIGHASH_ALL default for ECDSA inputs is not used
+	tx, _, err := builderTransaction(opts.Input, opts.Outputs, opts.Version, opts.LockTime, btcutils.SigHashAll)
+	if err != nil {
+		return nil, err
+	}
+	err = builderCheckFee(opts.InputAmount, opts.Outputs, opts.Change, opts.MaxFee, opts.ForceHighFee)
+	if err != nil {
+		return nil, err
+	}
+	prevOuts := []btcutils.Output{{Satoshis: uint64(opts.InputAmount), ScriptPubKey: inputScriptPubKey}}
+	sigHash, err := btcutils.CalcTaprootSigHash(tx, 0, prevOuts, opts.SigHashType)
+	if err != nil {
+		return nil, err
+	}
+	//Key path spends are signed with the private key tweaked the same way as the output key
+	tweakedPrivateKey, err := btcutils.TaprootTweakPrivateKey(privateKey, nil)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := btcutils.SchnorrSign(tweakedPrivateKey, sigHash)
+	if err != nil {
+		return nil, err
+	}
+	//P2TR key path witness format:
+	//<64 byte Schnorr signature>, followed by the hash type byte unless signing with SIGHASH_DEFAULT
+	if opts.SigHashType != btcutils.SigHashDefault {
+		signature = append(signature, byte(opts.SigHashType))
+	}
+	tx.Inputs[0].Witness = [][]byte{signature}
+	return tx.Serialize()
+}
+
+// RedeemP2WSHOptions describes a transaction spending a single native SegWit P2WSH multisig output, or a nested
+// P2SH-P2WSH one, to be signed by RedeemP2WSH.
+type RedeemP2WSHOptions struct {
+	PrivateKeys      []string             //WIF private keys to sign with, in the order of their public keys in WitnessScript
+	WitnessScript    []byte               //Script the P2WSH output pays to the SHA256 of, such as an M-of-N multisig
+	Nested           bool                 //Spend a P2SH-P2WSH output, pushing the P2WSH scriptPubKey in the scriptSig
+	Input            btcutils.Input       //Output spent, with the sequence of the input. Its scriptSig is ignored
+	InputAmount      int64                //Satoshis held by the output spent, committed to by the BIP143 signatures
+	Outputs          []btcutils.Output    //Outputs paid to, such as btcutils.NewScriptPubKeyFromAddress of each destination
+	Version          uint32               //Transaction version, 2 if zero
+	LockTime         uint32               //Transaction lock time
+	SigHashType      btcutils.SigHashType //Hash type to sign with, SIGHASH_ALL if zero
+	Network          string               //Name of the network PrivateKeys are encoded for, mainnet if empty
+	MaxFee           *int64               //Highest fee in satoshis, btcutils.DefaultMaxFee if nil. Zero refuses any fee
+	Change           int64                //Satoshis of Outputs paid back to the sender, not counted as sent when checking the fee
+	ForceHighFee     bool                 //Allow a fee above MaxFee or btcutils.MaxFeePercent of the amount sent
+	PrevScriptPubKey []byte               //Optional scriptPubKey of the output spent, checked to pay to WitnessScript
+}
+
+// RedeemP2WSH builds the transaction described by opts and signs its P2WSH input with each of the private keys,
+// putting the signatures and witness script in the witness. Returns the signed transaction, ready to broadcast, or an
+// error if the keys, witness script, input amount, input or outputs are invalid, the fee is too high, or the input
+// pays to another script than WitnessScript by opts.PrevScriptPubKey.
+func RedeemP2WSH(opts RedeemP2WSHOptions) ([]byte, error) {
+	if len(opts.WitnessScript) == 0 || len(opts.WitnessScript) > btcutils.MaxWitnessScriptSize {
+		return nil, fmt.Errorf("Witness script must be between 1 and %d bytes long. Provided script is %d bytes long.", btcutils.MaxWitnessScriptSize, len(opts.WitnessScript))
+	}
+	if len(opts.PrivateKeys) == 0 {
+		return nil, errors.New("At least one private key is needed to spend a P2WSH output.")
+	}
+	if opts.InputAmount <= 0 {
+		return nil, errors.New("Input amount is required to sign a P2WSH input.")
+	}
+	params, err := builderNetworkParams(opts.Network)
+	if err != nil {
+		return nil, err
+	}
+	//Compression doesn't matter here since the public keys are given by the witness script
+	privateKeys := make([][]byte, len(opts.PrivateKeys))
+	for i, wif := range opts.PrivateKeys {
+		privateKeys[i], _, err = btcutils.ParseWIF(wif, params)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid private key %d. %s", i+1, err)
+		}
+	}
+	tx, hashType, err := builderTransaction(opts.Input, opts.Outputs, opts.Version, opts.LockTime, opts.SigHashType)
+	if err != nil {
+		return nil, err
+	}
+	err = builderCheckFee(opts.InputAmount, opts.Outputs, opts.Change, opts.MaxFee, opts.ForceHighFee)
+	if err != nil {
+		return nil, err
+	}
+	//Nested P2SH-P2WSH inputs only push the redeemScript OP_0 <SHA256(witnessScript)> in the scriptSig, and pay to
+	//its P2SH, while native ones pay to the P2WSH of the witness script
+	script := opts.WitnessScript
+	if opts.Nested {
+		script, err = btcutils.NewP2WSHScriptPubKey(opts.WitnessScript)
+		if err != nil {
+			return nil, err
+		}
+		tx.Inputs[0].ScriptSig = append([]byte{byte(len(script))}, script...)
+	}
+	if opts.PrevScriptPubKey != nil {
+		err = btcutils.CheckInputKey(opts.Input, opts.PrevScriptPubKey, nil, script, params)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = btcutils.CheckSigHashType(tx, 0, hashType)
+	if err != nil {
+		return nil, err
+	}
+	//The BIP143 scriptCode of a P2WSH input is the witness script itself
+	sigHash, err := btcutils.CalcWitnessSigHash(tx, 0, opts.WitnessScript, opts.InputAmount, hashType)
+	if err != nil {
+		return nil, err
+	}
+	//P2WSH multisig witness format:
+	//<empty> <sig1 + hash type> ... <sigm + hash type> <witnessScript>
+	//The empty item is consumed by the OP_CHECKMULTISIG off-by-one error, like OP_0 in a P2SH scriptSig
+	witness := [][]byte{{}}
+	for _, privateKey := range privateKeys {
+		signature, err := btcutils.SignHash(sigHash, privateKey)
+		if err != nil {
+			return nil, err
+		}
+		witness = append(witness, append(signature, byte(hashType)))
+	}
+	tx.Inputs[0].Witness = append(witness, opts.WitnessScript)
+	return tx.Serialize()
+}
+
+// fundP2WPKH builds and signs the transaction described by opts, spending a native P2WPKH output, or a nested
+// P2SH-P2WPKH one if nested is set.
+func fundP2WPKH(opts FundP2WPKHOptions, nested bool) ([]byte, error) {
+	outputType := "P2WPKH"
+	if nested {
+		outputType = "P2SH-P2WPKH"
+	}
+	if opts.InputAmount <= 0 {
+		return nil, fmt.Errorf("Input amount is required to sign a %s input.", outputType)
+	}
+	params, err := builderNetworkParams(opts.Network)
+	if err != nil {
+		return nil, err
+	}
+	privateKey, compressed, err := btcutils.ParseWIF(opts.PrivateKey, params)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid private key. %s", err)
+	}
+	if !compressed {
+		return nil, fmt.Errorf("%s outputs can only be spent with a compressed WIF private key.", outputType)
+	}
+	//P2WPKH outputs are always locked to the hash of a compressed public key
+	publicKey, err := btcutils.NewCompressedPublicKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	if opts.PrevScriptPubKey != nil {
+		err = btcutils.CheckInputKey(opts.Input, opts.PrevScriptPubKey, publicKey, nil, params)
+		if err != nil {
+			return nil, err
+		}
+	}
+	publicKeyHash, err := btcutils.Hash160(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	//The BIP143 scriptCode of a P2WPKH input is the P2PKH scriptPubKey of the same public key hash
+	scriptCode, err := btcutils.NewP2PKHScriptPubKey(publicKeyHash)
+	if err != nil {
+		return nil, err
+	}
+	tx, hashType, err := builderTransaction(opts.Input, opts.Outputs, opts.Version, opts.LockTime, opts.SigHashType)
+	if err != nil {
+		return nil, err
+	}
+	err = builderCheckFee(opts.InputAmount, opts.Outputs, opts.Change, opts.MaxFee, opts.ForceHighFee)
+	if err != nil {
+		return nil, err
+	}
+	if nested {
+		//P2SH-P2WPKH scriptSig format:
+		//<redeemScript>
+		redeemScript, err := btcutils.NewP2SHP2WPKHRedeemScript(publicKey)
+		if err != nil {
+			return nil, err
+		}
+		tx.Inputs[0].ScriptSig = append([]byte{byte(len(redeemScript))}, redeemScript...)
+	}
+	err = btcutils.CheckSigHashType(tx, 0, hashType)
+	if err != nil {
+		return nil, err
+	}
+	sigHash, err := btcutils.CalcWitnessSigHash(tx, 0, scriptCode, opts.InputAmount, hashType)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := btcutils.SignHash(sigHash, privateKey)
+	if err != nil {
+		return nil, err
+	}
+	//P2WPKH witness format, the same for nested P2SH-P2WPKH:
+	//<signature + hash type> <compressed pubkey>
+	tx.Inputs[0].Witness = [][]byte{append(signature, byte(hashType)), publicKey}
+	return tx.Serialize()
+}
+
+// builderNetworkParams returns the parameters of the network named network, or mainnet if it is empty.
+func builderNetworkParams(network string) (*btcutils.NetworkParams, error) {
+	if network == "" {
+		return &btcutils.MainNetParams, nil
+	}
+	return btcutils.NetworkParamsByName(network)
+}
+
+// builderTransaction returns the unsigned transaction spending input to outputs, and the hash type to sign it with,
+// filling in the defaults of version and hashType.
+func builderTransaction(input btcutils.Input, outputs []btcutils.Output, version uint32, lockTime uint32, hashType btcutils.SigHashType) (*btcutils.Transaction, btcutils.SigHashType, error) {
+	if version == 0 {
+		version = btcutils.RelativeLockTimeVersion
+	}
+	if hashType == 0 {
+		hashType = btcutils.SigHashAll
+	}
+	if len(outputs) == 0 {
+		return nil, 0, errors.New("At least one output is needed.")
+	}
+	_, err := btcutils.CheckOutputs(outputs)
+	if err != nil {
+		return nil, 0, err
+	}
+	//The scriptSig is set when signing
+	input.ScriptSig = nil
+	input.Witness = nil
+	tx := &btcutils.Transaction{Version: version, Inputs: []btcutils.Input{input}, Outputs: outputs, LockTime: lockTime}
+	return tx, hashType, nil
+}
+
+// builderCheckFee refuses a fee, inputAmount less the outputs, above maxFee satoshis, or btcutils.DefaultMaxFee if
+// nil, or above btcutils.MaxFeePercent of the satoshis sent to outputs, less the change satoshis paid back to the
+// sender, unless forceHighFee is set. Without inputAmount the fee is unknown, so it is refused unless forceHighFee
+// is set.
+func builderCheckFee(inputAmount int64, outputs []btcutils.Output, change int64, maxFee *int64, forceHighFee bool) error {
+	if maxFee != nil && *maxFee < 0 {
+		return fmt.Errorf("Maximum fee cannot be negative. Provided maximum is %d satoshis.", *maxFee)
+	}
+	if forceHighFee {
+		return nil
+	}
+	if inputAmount <= 0 {
+		return errors.New("Input amount is required to check the fee. Set ForceHighFee to sign without checking it.")
+	}
+	ceiling := int64(btcutils.DefaultMaxFee)
+	if maxFee != nil {
+		ceiling = *maxFee
+	}
+	fee, err := btcutils.CheckFee(uint64(inputAmount), outputs)
+	if err != nil {
+		return err
+	}
+	return btcutils.CheckMaxFee(int64(fee), inputAmount-int64(fee)-change, ceiling)
+}
@@ -0,0 +1,330 @@
+package multisig
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/btcutils"
+	"github.com/CryptoProcessing/go-bitcoin-multisig/testutils"
+
+	"encoding/hex"
+	"testing"
+)
+
+func TestFundP2SH(t *testing.T) {
+	btcutils.SetFixedNonce = true //SetFixedNonce set to true to get repeatable signatures with a fixed nonce for testing.
+	//Same transaction as the first generateFund test
+	testFinalTransanctionHex := "0100000001acc6fb9ec2c3884d3a12a89e7078c83853d9b7912281cefb14bac00a2737d33a000000008a47304402206d6caac248af96f6afa7f904f550253a0f3ef3f5aa2fe6838a95b216691468e202207d1c7fb129adec15700c378e142c506b5bbadafdedbb62f614dd0bb128faeecd01410431393af9984375830971ab5d3094c6a7d02db3568b2b06212a7090094549701bbb9e84d9477451acc42638963635899ce91bacb451a1bb6da73ddfbcf596bddfffffffff01400001000000000017a9141a8b0026343166625c7475f01e48b5ede8c0252e8700000000"
+	testScriptPubKey, _ := btcutils.NewScriptPubKeyFromAddress("347N1Thc213QqfYCz3PZkjoJpNv5b14kBd", &btcutils.MainNetParams)
+	opts := FundP2SHOptions{
+		PrivateKey:  "5JJyqG4bb15zqi7fTA4b227aUxQhBo1Ux6qX69ngeXYLr7fk2hs",
+		Input:       btcutils.Input{TxHash: "3ad337270ac0ba14fbce812291b7d95338c878709ea8123a4d88c3c29efbc6ac", Sequence: btcutils.SequenceFinal},
+		InputAmount: 70000,
+		Outputs:     []btcutils.Output{{Satoshis: 65600, ScriptPubKey: testScriptPubKey}},
+		Version:     1,
+	}
+	finalTransaction, err := FundP2SH(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	finalTransactionHex := hex.EncodeToString(finalTransaction)
+	if finalTransactionHex != testFinalTransanctionHex {
+		testutils.CompareError(t, "Funding transaction different from expected transaction.", testFinalTransanctionHex, finalTransactionHex)
+	}
+
+	//The uncompressed WIF signs for the P2PKH output of the uncompressed public key, not of its compressed form
+	uncompressedPublicKey, _ := hex.DecodeString("0431393af9984375830971ab5d3094c6a7d02db3568b2b06212a7090094549701bbb9e84d9477451acc42638963635899ce91bacb451a1bb6da73ddfbcf596bddf")
+	uncompressedPublicKeyHash, _ := btcutils.Hash160(uncompressedPublicKey)
+	opts.PrevScriptPubKey, _ = btcutils.NewP2PKHScriptPubKey(uncompressedPublicKeyHash)
+	if _, err := FundP2SH(opts); err != nil {
+		t.Error(err)
+	}
+	invalidOpts := opts
+	invalidOpts.PrevScriptPubKey, _ = btcutils.NewP2PKHScriptPubKey(btcutils.PublicKeyToHash160(uncompressedPublicKey))
+	if _, err := FundP2SH(invalidOpts); err == nil {
+		t.Error("FundP2SH accepting input paying to the compressed public key of an uncompressed WIF.")
+	}
+
+	invalidOpts = opts
+	invalidOpts.Network = "testnet"
+	if _, err := FundP2SH(invalidOpts); err == nil {
+		t.Error("FundP2SH accepting mainnet private key on testnet.")
+	}
+	invalidOpts = opts
+	invalidOpts.Outputs = nil
+	if _, err := FundP2SH(invalidOpts); err == nil {
+		t.Error("FundP2SH accepting no outputs.")
+	}
+
+	//The fee is only left unchecked with ForceHighFee
+	invalidOpts = opts
+	invalidOpts.InputAmount = 0
+	if _, err := FundP2SH(invalidOpts); err == nil {
+		t.Error("FundP2SH accepting unknown fee.")
+	}
+	invalidOpts.ForceHighFee = true
+	if _, err := FundP2SH(invalidOpts); err != nil {
+		t.Error(err)
+	}
+	invalidOpts = opts
+	invalidOpts.InputAmount = 700000
+	if _, err := FundP2SH(invalidOpts); err == nil {
+		t.Error("FundP2SH accepting fee above 10% of the amount sent.")
+	} else if _, ok := err.(*btcutils.HighFeeError); !ok {
+		t.Errorf("FundP2SH returned %v, expected a HighFeeError.", err)
+	}
+	invalidOpts.InputAmount = 90000000
+	invalidOpts.Outputs = []btcutils.Output{{Satoshis: 88000000, ScriptPubKey: testScriptPubKey}}
+	if _, err := FundP2SH(invalidOpts); err == nil {
+		t.Error("FundP2SH accepting fee above the default maximum fee.")
+	}
+	maxFee := int64(3000000)
+	invalidOpts.MaxFee = &maxFee
+	if _, err := FundP2SH(invalidOpts); err != nil {
+		t.Error(err)
+	}
+	//A MaxFee of zero refuses any fee, as --max-fee 0 does
+	maxFee = 0
+	invalidOpts = opts
+	invalidOpts.MaxFee = &maxFee
+	if _, err := FundP2SH(invalidOpts); err == nil {
+		t.Error("FundP2SH accepting a fee with MaxFee zero.")
+	}
+	maxFee = -1
+	if _, err := FundP2SH(invalidOpts); err == nil {
+		t.Error("FundP2SH accepting negative MaxFee.")
+	}
+	//Change is not counted as sent, so a 10000 satoshi fee for sending 50000 is too high
+	invalidOpts = opts
+	invalidOpts.InputAmount = 1000000
+	invalidOpts.Outputs = []btcutils.Output{{Satoshis: 50000, ScriptPubKey: testScriptPubKey}, {Satoshis: 940000, ScriptPubKey: testScriptPubKey}}
+	if _, err := FundP2SH(invalidOpts); err != nil {
+		t.Error(err)
+	}
+	invalidOpts.Change = 940000
+	if _, err := FundP2SH(invalidOpts); err == nil {
+		t.Error("FundP2SH accepting fee above 10% of the amount sent excluding change.")
+	} else if highFeeError, ok := err.(*btcutils.HighFeeError); !ok || highFeeError.Sent != 50000 {
+		t.Errorf("FundP2SH returned %v, expected a HighFeeError for 50000 satoshis sent.", err)
+	}
+}
+
+func TestSpendP2SH(t *testing.T) {
+	btcutils.SetFixedNonce = true
+	//Same 2-of-3 spend as the third generateSpend test
+	testFinalTransactionHex := "01000000013dcd7d87904c9cb7f4b79f36b5a03f96e2e729284c09856238d5353e1182b00200000000fd5c010047304402206d6caac248af96f6afa7f904f550253a0f3ef3f5aa2fe6838a95b216691468e20220106d4068c7b29336dc39b96234e1b55fdbd79287eeb147d9405b189d4368b0c60147304402206d6caac248af96f6afa7f904f550253a0f3ef3f5aa2fe6838a95b216691468e202204b14745bcc78dbac7e57c5cd64fb5d351a00632293dd01d5e567b402a51ba831014cc9524104a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575fa349b5694ed3155b136f09e63975a1700c9f4d4df849323dac06cf3bd6458cd41046ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640de68c2fe913d363a51154a0c62d7adea1b822d05035077418267b1a1379790187410411ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef992f63280567f52f5ba870678b4ab4ff6c8ea600bd217870a8b4f1f09f3a8e8353aeffffffff0130d90000000000001976a914569076ba39fc4ff6a2291d9ea9196d8c08f9c7ab88ac00000000"
+	testRedeemScript, _ := hex.DecodeString("524104a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575fa349b5694ed3155b136f09e63975a1700c9f4d4df849323dac06cf3bd6458cd41046ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640de68c2fe913d363a51154a0c62d7adea1b822d05035077418267b1a1379790187410411ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef992f63280567f52f5ba870678b4ab4ff6c8ea600bd217870a8b4f1f09f3a8e8353ae")
+	testScriptPubKey, _ := hex.DecodeString("76a914569076ba39fc4ff6a2291d9ea9196d8c08f9c7ab88ac")
+	opts := SpendP2SHOptions{
+		PrivateKeys:  []string{"5JruagvxNLXTnkksyLMfgFgf3CagJ3Ekxu5oGxpTm5mPfTAPez3", "5JjHVMwJdjPEPQhq34WMUhzLcEd4SD7HgZktEh8WHstWcCLRceV"},
+		RedeemScript: testRedeemScript,
+		Input:        btcutils.Input{TxHash: "02b082113e35d5386285094c2829e7e2963fa0b5369fb7f4b79c4c90877dcd3d", Sequence: btcutils.SequenceFinal},
+		InputAmount:  60000,
+		Outputs:      []btcutils.Output{{Satoshis: 55600, ScriptPubKey: testScriptPubKey}},
+		Version:      1,
+		Network:      "mainnet",
+	}
+	finalTransaction, err := SpendP2SH(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	finalTransactionHex := hex.EncodeToString(finalTransaction)
+	if finalTransactionHex != testFinalTransactionHex {
+		testutils.CompareError(t, "Spend transaction different from expected transaction.", testFinalTransactionHex, finalTransactionHex)
+	}
+
+	redeemScriptHash, _ := btcutils.Hash160(testRedeemScript)
+	opts.PrevScriptPubKey, _ = btcutils.NewP2SHScriptPubKey(redeemScriptHash)
+	if _, err := SpendP2SH(opts); err != nil {
+		t.Error(err)
+	}
+	invalidOpts := opts
+	invalidOpts.PrevScriptPubKey, _ = btcutils.NewP2SHScriptPubKey(make([]byte, 20))
+	if _, err := SpendP2SH(invalidOpts); err == nil {
+		t.Error("SpendP2SH accepting input paying to another redeemScript.")
+	}
+	invalidOpts = opts
+	invalidOpts.RedeemScript = nil
+	if _, err := SpendP2SH(invalidOpts); err == nil {
+		t.Error("SpendP2SH accepting empty redeemScript.")
+	}
+	invalidOpts = opts
+	invalidOpts.PrivateKeys = []string{"not a key"}
+	if _, err := SpendP2SH(invalidOpts); err == nil {
+		t.Error("SpendP2SH accepting invalid private key.")
+	}
+	invalidOpts = opts
+	invalidOpts.Outputs = []btcutils.Output{{Satoshis: 0, ScriptPubKey: testScriptPubKey}}
+	if _, err := SpendP2SH(invalidOpts); err == nil {
+		t.Error("SpendP2SH accepting zero amount output.")
+	}
+	invalidOpts = opts
+	invalidOpts.InputAmount = 0
+	if _, err := SpendP2SH(invalidOpts); err == nil {
+		t.Error("SpendP2SH accepting unknown fee.")
+	}
+	invalidOpts.InputAmount = 600000
+	if _, err := SpendP2SH(invalidOpts); err == nil {
+		t.Error("SpendP2SH accepting fee above 10% of the amount sent.")
+	}
+}
+
+// checkBuilderTransaction checks that the single input of the serialized transaction spends amount satoshis paid to
+// prevScriptPubKey.
+func checkBuilderTransaction(t *testing.T, serialized []byte, prevScriptPubKey []byte, amount int64) {
+	tx, err := btcutils.DeserializeTransaction(serialized)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := btcutils.VerifyInputScript(tx, 0, prevScriptPubKey, amount); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFundP2WPKH(t *testing.T) {
+	btcutils.SetFixedNonce = true
+	testScriptPubKey, _ := btcutils.NewScriptPubKeyFromAddress("347N1Thc213QqfYCz3PZkjoJpNv5b14kBd", &btcutils.MainNetParams)
+	privateKey, _, _ := btcutils.ParseWIF("KyQMr1Usf5P4HzUQiSzq9Vv2nPZaNe58NCm9ojndzdoLbfKEvPms", &btcutils.MainNetParams)
+	publicKey, _ := btcutils.NewCompressedPublicKey(privateKey)
+	p2wpkhScriptPubKey, _ := btcutils.NewP2WPKHScriptPubKey(btcutils.PublicKeyToHash160(publicKey))
+	p2shP2WPKHScriptPubKey, _ := btcutils.CreateP2SHP2WPKHScriptPubKey(publicKey)
+	opts := FundP2WPKHOptions{
+		PrivateKey:       "KyQMr1Usf5P4HzUQiSzq9Vv2nPZaNe58NCm9ojndzdoLbfKEvPms",
+		Input:            btcutils.Input{TxHash: "3ad337270ac0ba14fbce812291b7d95338c878709ea8123a4d88c3c29efbc6ac", OutputIndex: 1, Sequence: btcutils.SequenceFinal},
+		InputAmount:      100000,
+		Outputs:          []btcutils.Output{{Satoshis: 95000, ScriptPubKey: testScriptPubKey}},
+		PrevScriptPubKey: p2wpkhScriptPubKey,
+	}
+	finalTransaction, err := FundP2WPKH(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkBuilderTransaction(t, finalTransaction, p2wpkhScriptPubKey, 100000)
+	//The same key spends the nested P2SH-P2WPKH output, with the redeem script in the scriptSig
+	nestedOpts := FundP2SHP2WPKHOptions(opts)
+	nestedOpts.PrevScriptPubKey = p2shP2WPKHScriptPubKey
+	finalTransaction, err = FundP2SHP2WPKH(nestedOpts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkBuilderTransaction(t, finalTransaction, p2shP2WPKHScriptPubKey, 100000)
+
+	invalidOpts := opts
+	invalidOpts.PrevScriptPubKey, _ = btcutils.NewP2WPKHScriptPubKey(make([]byte, 20))
+	if _, err := FundP2WPKH(invalidOpts); err == nil {
+		t.Error("FundP2WPKH accepting input paying to another key.")
+	}
+	invalidOpts = opts
+	invalidOpts.PrivateKey = "5JJyqG4bb15zqi7fTA4b227aUxQhBo1Ux6qX69ngeXYLr7fk2hs"
+	invalidOpts.PrevScriptPubKey = nil
+	if _, err := FundP2WPKH(invalidOpts); err == nil {
+		t.Error("FundP2WPKH accepting uncompressed WIF private key.")
+	}
+	invalidOpts = opts
+	invalidOpts.InputAmount = 0
+	if _, err := FundP2SHP2WPKH(FundP2SHP2WPKHOptions(invalidOpts)); err == nil {
+		t.Error("FundP2SHP2WPKH accepting missing input amount.")
+	}
+}
+
+func TestFundP2TR(t *testing.T) {
+	btcutils.SetFixedNonce = true
+	testScriptPubKey, _ := btcutils.NewScriptPubKeyFromAddress("bc1p2wsldez5mud2yam29q22wgfh9439spgduvct83k3pm50fcxa5dps59h4z5", &btcutils.MainNetParams)
+	privateKey, _, _ := btcutils.ParseWIF("KyQMr1Usf5P4HzUQiSzq9Vv2nPZaNe58NCm9ojndzdoLbfKEvPms", &btcutils.MainNetParams)
+	publicKey, _ := btcutils.NewCompressedPublicKey(privateKey)
+	outputKey, _ := btcutils.TweakPublicKey(publicKey[1:], nil)
+	p2trScriptPubKey, _ := btcutils.CreateP2TRScriptPubKey(outputKey)
+	opts := FundP2TROptions{
+		PrivateKey:       "KyQMr1Usf5P4HzUQiSzq9Vv2nPZaNe58NCm9ojndzdoLbfKEvPms",
+		Input:            btcutils.Input{TxHash: "3ad337270ac0ba14fbce812291b7d95338c878709ea8123a4d88c3c29efbc6ac", OutputIndex: 1, Sequence: btcutils.SequenceFinal},
+		InputAmount:      100000,
+		Outputs:          []btcutils.Output{{Satoshis: 95000, ScriptPubKey: testScriptPubKey}},
+		PrevScriptPubKey: p2trScriptPubKey,
+	}
+	prevOuts := []btcutils.Output{{Satoshis: 100000, ScriptPubKey: p2trScriptPubKey}}
+	//SIGHASH_DEFAULT signatures leave out the hash type byte, which other hash types append
+	for _, hashType := range []btcutils.SigHashType{btcutils.SigHashDefault, btcutils.SigHashAll} {
+		opts.SigHashType = hashType
+		finalTransaction, err := FundP2TR(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tx, _ := btcutils.DeserializeTransaction(finalTransaction)
+		sigHash, _ := btcutils.CalcTaprootSigHash(tx, 0, prevOuts, hashType)
+		signature := tx.Inputs[0].Witness[0]
+		if hashType != btcutils.SigHashDefault {
+			if len(signature) != 65 || signature[64] != byte(hashType) {
+				t.Fatalf("P2TR witness is %x, expected a 65 byte Schnorr signature ending in hash type 0x%02x.", tx.Inputs[0].Witness, byte(hashType))
+			}
+			signature = signature[:64]
+		}
+		if err := btcutils.SchnorrVerify(outputKey, sigHash, signature); err != nil {
+			t.Error(err)
+		}
+	}
+
+	invalidOpts := opts
+	invalidOpts.PrevScriptPubKey = testScriptPubKey
+	if _, err := FundP2TR(invalidOpts); err == nil {
+		t.Error("FundP2TR accepting input paying to another output key.")
+	}
+	invalidOpts = opts
+	invalidOpts.InputAmount = 0
+	if _, err := FundP2TR(invalidOpts); err == nil {
+		t.Error("FundP2TR accepting missing input amount.")
+	}
+}
+
+func TestRedeemP2WSH(t *testing.T) {
+	btcutils.SetFixedNonce = true
+	//Same 2-of-3 spend as the first generateRedeemP2WSH test
+	testFinalTransactionHex := "01000000000101ef51e1b804cc89d182d279655c3aa89e815b1b309fe287d9b2b55d57b90ec68a0000000000ffffffff01905f01000000000017a9141a8b0026343166625c7475f01e48b5ede8c0252e87040047304402206d6caac248af96f6afa7f904f550253a0f3ef3f5aa2fe6838a95b216691468e20220070871ffb9ab843f8c0ba1cc20ab05cfa812e7677f75f28f812501a49ec75b640147304402206d6caac248af96f6afa7f904f550253a0f3ef3f5aa2fe6838a95b216691468e202204ad635e630e71e1839bfed3b232f85af425406a1bcb85fba8c20827826d76be301695221025476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee63572103b6446545566e0fecb2f2243db709bbf4e9fff78d48c9438acc911a36015e896a21033d63026f5f0de46e40dc09c61c1559240ea4381df7e05045f6ae2ac506efbaf253ae00000000"
+	testWitnessScript, _ := hex.DecodeString("5221025476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee63572103b6446545566e0fecb2f2243db709bbf4e9fff78d48c9438acc911a36015e896a21033d63026f5f0de46e40dc09c61c1559240ea4381df7e05045f6ae2ac506efbaf253ae")
+	testScriptPubKey, _ := btcutils.NewScriptPubKeyFromAddress("347N1Thc213QqfYCz3PZkjoJpNv5b14kBd", &btcutils.MainNetParams)
+	p2wshScriptPubKey, _ := btcutils.NewP2WSHScriptPubKey(testWitnessScript)
+	p2shP2WSHScriptPubKey, _ := btcutils.CreateP2SHP2WSHScriptPubKey(testWitnessScript)
+	opts := RedeemP2WSHOptions{
+		PrivateKeys:   []string{"KzVTBhbMaKrAYagJ11VdTaBrb6yzLykLGyuMBkf9sCFPDxdT8shL", "L4euB5ik61Qcp67f5DLo3jiF2uumTMMTa6i8ys2SW82g8PzNJZTc"},
+		WitnessScript: testWitnessScript,
+		Input:         btcutils.Input{TxHash: "8ac60eb9575db5b2d987e29f301b5b819ea83a5c6579d282d189cc04b8e151ef", Sequence: btcutils.SequenceFinal},
+		InputAmount:   100000,
+		Outputs:       []btcutils.Output{{Satoshis: 90000, ScriptPubKey: testScriptPubKey}},
+		Version:       1,
+		ForceHighFee:  true,
+	}
+	finalTransaction, err := RedeemP2WSH(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	finalTransactionHex := hex.EncodeToString(finalTransaction)
+	if finalTransactionHex != testFinalTransactionHex {
+		testutils.CompareError(t, "P2WSH spending transaction different from expected transaction.", testFinalTransactionHex, finalTransactionHex)
+	}
+	checkBuilderTransaction(t, finalTransaction, p2wshScriptPubKey, 100000)
+	nestedOpts := opts
+	nestedOpts.Nested = true
+	nestedOpts.PrevScriptPubKey = p2shP2WSHScriptPubKey
+	finalTransaction, err = RedeemP2WSH(nestedOpts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkBuilderTransaction(t, finalTransaction, p2shP2WSHScriptPubKey, 100000)
+
+	invalidOpts := opts
+	invalidOpts.PrevScriptPubKey = p2shP2WSHScriptPubKey
+	if _, err := RedeemP2WSH(invalidOpts); err == nil {
+		t.Error("RedeemP2WSH accepting native spend of a nested P2SH-P2WSH output.")
+	}
+	invalidOpts = opts
+	invalidOpts.WitnessScript = nil
+	if _, err := RedeemP2WSH(invalidOpts); err == nil {
+		t.Error("RedeemP2WSH accepting empty witness script.")
+	}
+	invalidOpts = opts
+	invalidOpts.PrivateKeys = nil
+	if _, err := RedeemP2WSH(invalidOpts); err == nil {
+		t.Error("RedeemP2WSH accepting no private keys.")
+	}
+	invalidOpts = opts
+	invalidOpts.InputAmount = 0
+	if _, err := RedeemP2WSH(invalidOpts); err == nil {
+		t.Error("RedeemP2WSH accepting missing input amount.")
+	}
+}
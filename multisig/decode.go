@@ -0,0 +1,252 @@
+// decode.go - Decoding a raw transaction and disassembling its scripts.
+package multisig
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/btcutils"
+	"github.com/CryptoProcessing/go-bitcoin-multisig/txscript"
+
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// decodedTransaction is the breakdown of a transaction printed by the decode subcommand, with the JSON field names
+// of bitcoin-cli decoderawtransaction, except for amounts being in satoshis.
+type decodedTransaction struct {
+	TxID     string          `json:"txid"`
+	WTxID    string          `json:"hash"`
+	Version  uint32          `json:"version"`
+	Size     int             `json:"size"`
+	VSize    int             `json:"vsize"`
+	Weight   int             `json:"weight"`
+	LockTime uint32          `json:"locktime"`
+	Inputs   []decodedInput  `json:"vin"`
+	Outputs  []decodedOutput `json:"vout"`
+}
+
+type decodedInput struct {
+	TxID      string        `json:"txid"`
+	Vout      uint32        `json:"vout"`
+	ScriptSig decodedScript `json:"scriptSig"`
+	Witness   []string      `json:"txinwitness,omitempty"`
+	Sequence  uint32        `json:"sequence"`
+}
+
+type decodedOutput struct {
+	Satoshis     uint64        `json:"satoshis"`
+	N            int           `json:"n"`
+	ScriptPubKey decodedScript `json:"scriptPubKey"`
+}
+
+type decodedScript struct {
+	Asm     string `json:"asm"`
+	Hex     string `json:"hex"`
+	Type    string `json:"type,omitempty"`
+	Address string `json:"address,omitempty"`
+}
+
+// OutputDecode formats and prints relevant outputs to the user.
+func OutputDecode(flagTx string, flagJSON bool, flagNetwork string) error {
+	decoded, err := generateDecode(flagTx, flagJSON, flagNetwork)
+	if err != nil {
+		return err
+	}
+	if flagJSON {
+		fmt.Println(decoded)
+		return nil
+	}
+	fmt.Printf(`
+-----------------------------------------------------------------------------------------------------------------------------------
+Decoded transaction, with its scripts raw and disassembled:
+%v-----------------------------------------------------------------------------------------------------------------------------------
+`,
+		decoded,
+	)
+	return nil
+}
+
+// generateDecode is the high-level logic for decoding a transaction with the 'go-bitcoin-multisig decode'
+// subcommand. Takes flagTx (raw transaction in hex, such as one printed by fund or spend, in the legacy or the
+// BIP141 extended serialization format), flagJSON (whether to output JSON) and flagNetwork (name of the network
+// output addresses are encoded for) as arguments.
+// Returns the version, lock time, size, IDs, inputs and outputs of the transaction, as indented JSON if flagJSON is
+// set, otherwise as text followed by the description of every script of the transaction, see describeScripts.
+func generateDecode(flagTx string, flagJSON bool, flagNetwork string) (string, error) {
+	params, err := btcutils.NetworkParamsByName(flagNetwork)
+	if err != nil {
+		return "", err
+	}
+	rawTx, err := hex.DecodeString(strings.TrimSpace(flagTx))
+	if err != nil {
+		return "", fmt.Errorf("Invalid --tx. %s", err)
+	}
+	tx, err := btcutils.DeserializeTransaction(rawTx)
+	if err != nil {
+		return "", fmt.Errorf("Invalid --tx. %s", err)
+	}
+	decoded, err := decodeTransaction(tx, rawTx, params)
+	if err != nil {
+		return "", err
+	}
+	if flagJSON {
+		decodedJSON, err := json.MarshalIndent(decoded, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		return string(decodedJSON), nil
+	}
+	return fmt.Sprintf(`Transaction ID (txid):	%s
+Witness transaction ID (wtxid):	%s
+Version:	%d
+Lock time:	%d
+Size:	%d bytes, %d virtual bytes, %d weight units
+%v`,
+		decoded.TxID,
+		decoded.WTxID,
+		decoded.Version,
+		decoded.LockTime,
+		decoded.Size, decoded.VSize, decoded.Weight,
+		describeScripts(tx, params),
+	), nil
+}
+
+// decodeTransaction breaks tx, deserialized from rawTx, down into a decodedTransaction. Output addresses are encoded
+// for the network given by params, and left out for outputs without one.
+func decodeTransaction(tx *btcutils.Transaction, rawTx []byte, params *btcutils.NetworkParams) (*decodedTransaction, error) {
+	txID, err := btcutils.TxID(rawTx)
+	if err != nil {
+		return nil, err
+	}
+	wTxID, err := btcutils.WTxID(rawTx)
+	if err != nil {
+		return nil, err
+	}
+	weight, err := btcutils.TransactionWeight(tx)
+	if err != nil {
+		return nil, err
+	}
+	vSize, err := btcutils.VirtualSize(tx)
+	if err != nil {
+		return nil, err
+	}
+	decoded := &decodedTransaction{
+		TxID:     txID,
+		WTxID:    wTxID,
+		Version:  tx.Version,
+		Size:     len(rawTx),
+		VSize:    vSize,
+		Weight:   weight,
+		LockTime: tx.LockTime,
+		Inputs:   make([]decodedInput, len(tx.Inputs)),
+		Outputs:  make([]decodedOutput, len(tx.Outputs)),
+	}
+	for i, input := range tx.Inputs {
+		disassembly, _ := txscript.Disasm(input.ScriptSig)
+		decoded.Inputs[i] = decodedInput{
+			TxID:      input.TxHash,
+			Vout:      input.OutputIndex,
+			ScriptSig: decodedScript{Asm: disassembly, Hex: hex.EncodeToString(input.ScriptSig)},
+			Sequence:  input.Sequence,
+		}
+		for _, item := range input.Witness {
+			decoded.Inputs[i].Witness = append(decoded.Inputs[i].Witness, hex.EncodeToString(item))
+		}
+	}
+	for i, output := range tx.Outputs {
+		disassembly, _ := txscript.Disasm(output.ScriptPubKey)
+		address, _ := btcutils.ScriptPubKeyToAddress(output.ScriptPubKey, params)
+		decoded.Outputs[i] = decodedOutput{
+			Satoshis: output.Satoshis,
+			N:        i,
+			ScriptPubKey: decodedScript{
+				Asm:     disassembly,
+				Hex:     hex.EncodeToString(output.ScriptPubKey),
+				Type:    string(txscript.Classify(output.ScriptPubKey)),
+				Address: address,
+			},
+		}
+	}
+	return decoded, nil
+}
+
+// printScripts prints every script of the final transaction, raw and disassembled, to debug why it fails to spend.
+func printScripts(finalTransactionHex string) {
+	finalTransaction, err := hex.DecodeString(finalTransactionHex)
+	if err != nil {
+		return
+	}
+	tx, err := btcutils.DeserializeTransaction(finalTransaction)
+	if err != nil {
+		return
+	}
+	fmt.Printf(`
+-----------------------------------------------------------------------------------------------------------------------------------
+Transaction scripts:
+%v-----------------------------------------------------------------------------------------------------------------------------------
+`,
+		describeScripts(tx, nil),
+	)
+}
+
+// describeScripts describes the scriptSig and witness of each input of tx and the scriptPubKey of each output,
+// with each script shown in hex and disassembled by txscript.Disasm. Templates recognized by txscript.Classify are
+// named for scriptPubKeys, and for the redeem script or witness script an input reveals as the last item it pushes.
+// Outputs paying to an address are given their address on the network of params, unless params is nil.
+func describeScripts(tx *btcutils.Transaction, params *btcutils.NetworkParams) string {
+	var description strings.Builder
+	for i, input := range tx.Inputs {
+		fmt.Fprintf(&description, "Input %d spending %s:%d with sequence 0x%08x\n", i, input.TxHash, input.OutputIndex, input.Sequence)
+		description.WriteString(describeScript("scriptSig", input.ScriptSig))
+		if pushes, err := txscript.PushedData(input.ScriptSig); err == nil {
+			if redeemScript, class, ok := revealedScript(pushes); ok {
+				description.WriteString(describeScript("redeem script ("+string(class)+")", redeemScript))
+			}
+		}
+		for j, item := range input.Witness {
+			fmt.Fprintf(&description, "\twitness item %d:\t%x\n", j, item)
+		}
+		if witnessScript, class, ok := revealedScript(input.Witness); ok {
+			description.WriteString(describeScript("witness script ("+string(class)+")", witnessScript))
+		}
+	}
+	for i, output := range tx.Outputs {
+		payee := string(txscript.Classify(output.ScriptPubKey))
+		if params != nil {
+			if address, err := btcutils.ScriptPubKeyToAddress(output.ScriptPubKey, params); err == nil {
+				payee += " address " + address
+			}
+		}
+		fmt.Fprintf(&description, "Output %d paying %d satoshis to %s\n", i, output.Satoshis, payee)
+		description.WriteString(describeScript("scriptPubKey", output.ScriptPubKey))
+	}
+	return description.String()
+}
+
+// describeScript shows script in hex, then disassembled on the next line. Disassembly errors are already shown in
+// brackets by txscript.Disasm.
+func describeScript(name string, script []byte) string {
+	if len(script) == 0 {
+		return fmt.Sprintf("\t%s:\t(empty)\n", name)
+	}
+	disassembly, _ := txscript.Disasm(script)
+	return fmt.Sprintf("\t%s:\t%x\n\t\t%s\n", name, script, disassembly)
+}
+
+// revealedScript returns the last of the items an input pushes if it is a script, a redeem script in a scriptSig or
+// a witness script in a witness. Scripts are told from signatures and public keys by having a standard template or,
+// like the time-locked multisig redeem scripts, ending in OP_CHECKMULTISIG.
+func revealedScript(items [][]byte) ([]byte, txscript.ScriptClass, bool) {
+	if len(items) == 0 || len(items[len(items)-1]) == 0 {
+		return nil, "", false
+	}
+	script := items[len(items)-1]
+	class := txscript.Classify(script)
+	if class != txscript.NonStandard {
+		return script, class, true
+	}
+	if _, err := txscript.Disasm(script); err == nil && script[len(script)-1] == txscript.OP_CHECKMULTISIG {
+		return script, class, true
+	}
+	return nil, "", false
+}
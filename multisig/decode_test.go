@@ -0,0 +1,93 @@
+package multisig
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/btcutils"
+
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestGenerateDecode(t *testing.T) {
+	//2-of-3 spend from TestGenerateSpend
+	testSpendHex := "01000000013dcd7d87904c9cb7f4b79f36b5a03f96e2e729284c09856238d5353e1182b00200000000fd5c010047304402206d6caac248af96f6afa7f904f550253a0f3ef3f5aa2fe6838a95b216691468e20220106d4068c7b29336dc39b96234e1b55fdbd79287eeb147d9405b189d4368b0c60147304402206d6caac248af96f6afa7f904f550253a0f3ef3f5aa2fe6838a95b216691468e202204b14745bcc78dbac7e57c5cd64fb5d351a00632293dd01d5e567b402a51ba831014cc9524104a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575fa349b5694ed3155b136f09e63975a1700c9f4d4df849323dac06cf3bd6458cd41046ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640de68c2fe913d363a51154a0c62d7adea1b822d05035077418267b1a1379790187410411ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef992f63280567f52f5ba870678b4ab4ff6c8ea600bd217870a8b4f1f09f3a8e8353aeffffffff0130d90000000000001976a914569076ba39fc4ff6a2291d9ea9196d8c08f9c7ab88ac00000000"
+	description, err := generateDecode(testSpendHex, false, "mainnet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, testLine := range []string{
+		"Version:\t1\nLock time:\t0\nSize:\t",
+		"Input 0 spending 02b082113e35d5386285094c2829e7e2963fa0b5369fb7f4b79c4c90877dcd3d:0 with sequence 0xffffffff\n",
+		"\t\tOP_0 OP_DATA_71 304402206d6caac248af96f6afa7f904f550253a0f3ef3f5aa2fe6838a95b216691468e20220106d4068c7b29336dc39b96234e1b55fdbd79287eeb147d9405b189d4368b0c601 OP_DATA_71 ",
+		" OP_PUSHDATA1 201 524104a882",
+		"\tredeem script (multisig):\t524104a882",
+		"\t\tOP_2 OP_DATA_65 04a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575fa349b5694ed3155b136f09e63975a1700c9f4d4df849323dac06cf3bd6458cd OP_DATA_65 ",
+		" OP_3 OP_CHECKMULTISIG\n",
+		"Output 0 paying 55600 satoshis to P2PKH address 18tiB1yNTzJMCg6bQS1Eh29dvJngq8QTfx\n\tscriptPubKey:\t76a914569076ba39fc4ff6a2291d9ea9196d8c08f9c7ab88ac\n\t\tOP_DUP OP_HASH160 OP_DATA_20 569076ba39fc4ff6a2291d9ea9196d8c08f9c7ab OP_EQUALVERIFY OP_CHECKSIG\n",
+	} {
+		if !strings.Contains(description, testLine) {
+			t.Errorf("Decoded spend missing %q:\n%s", testLine, description)
+		}
+	}
+
+	//P2PKH fund from TestGenerateFund, whose scriptSig reveals a public key rather than a redeem script
+	testFundHex := "0100000001acc6fb9ec2c3884d3a12a89e7078c83853d9b7912281cefb14bac00a2737d33a000000008a47304402206d6caac248af96f6afa7f904f550253a0f3ef3f5aa2fe6838a95b216691468e202207d1c7fb129adec15700c378e142c506b5bbadafdedbb62f614dd0bb128faeecd01410431393af9984375830971ab5d3094c6a7d02db3568b2b06212a7090094549701bbb9e84d9477451acc42638963635899ce91bacb451a1bb6da73ddfbcf596bddfffffffff01400001000000000017a9141a8b0026343166625c7475f01e48b5ede8c0252e8700000000"
+	description, err = generateDecode(testFundHex, false, "mainnet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(description, "Output 0 paying 65600 satoshis to P2SH address 347N1Thc213QqfYCz3PZkjoJpNv5b14kBd\n\tscriptPubKey:\ta9141a8b0026343166625c7475f01e48b5ede8c0252e87\n\t\tOP_HASH160 OP_DATA_20 1a8b0026343166625c7475f01e48b5ede8c0252e OP_EQUAL\n") {
+		t.Errorf("Decoded fund missing its P2SH output:\n%s", description)
+	}
+	if strings.Contains(description, "redeem script") {
+		t.Errorf("Decoded fund showing the public key of its scriptSig as a redeem script:\n%s", description)
+	}
+
+	//P2WSH redeem, decoded as JSON from the BIP141 extended serialization format
+	btcutils.SetFixedNonce = true
+	testPublicKeys := "025476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee6357,03b6446545566e0fecb2f2243db709bbf4e9fff78d48c9438acc911a36015e896a,033d63026f5f0de46e40dc09c61c1559240ea4381df7e05045f6ae2ac506efbaf2"
+	_, _, witnessScriptHex, err := generateAddress(2, 3, testPublicKeys, false, "mainnet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testRedeemHex, err := generateRedeemP2WSH(&RedeemP2WSHFlags{PrivateKeys: "KzVTBhbMaKrAYagJ11VdTaBrb6yzLykLGyuMBkf9sCFPDxdT8shL,L4euB5ik61Qcp67f5DLo3jiF2uumTMMTa6i8ys2SW82g8PzNJZTc", Destination: "bc1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3qccfmv3", WitnessScript: witnessScriptHex, InputTx: "8ac60eb9575db5b2d987e29f301b5b819ea83a5c6579d282d189cc04b8e151ef", InputAmount: 100000, Amount: 90000, TxFlags: TxFlags{Sequence: 0xfffffffd, TxVersion: 2, DustRelayFee: 3000, MaxFee: 1000000, ForceHighFee: true, SigHash: "default", OutputFormat: "hex", Network: "mainnet"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	decodedJSON, err := generateDecode(testRedeemHex, true, "mainnet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded decodedTransaction
+	if err := json.Unmarshal([]byte(decodedJSON), &decoded); err != nil {
+		t.Fatalf("generateDecode output not valid JSON. %s\n%s", err, decodedJSON)
+	}
+	rawRedeem, _ := hex.DecodeString(testRedeemHex)
+	redeemTxID, _ := btcutils.TxID(rawRedeem)
+	redeemWTxID, _ := btcutils.WTxID(rawRedeem)
+	if !strings.Contains(decodedJSON, `"txid": "`+redeemTxID+`"`) || !strings.Contains(decodedJSON, `"hash": "`+redeemWTxID+`"`) {
+		t.Errorf("Decoded redeem missing txid %s or wtxid %s:\n%s", redeemTxID, redeemWTxID, decodedJSON)
+	}
+	if decoded.Version != 2 || decoded.TxID == decoded.WTxID || decoded.Size != len(testRedeemHex)/2 || decoded.VSize >= decoded.Size || decoded.VSize != (decoded.Weight+3)/4 {
+		t.Errorf("Decoded redeem with unexpected version, IDs or size:\n%s", decodedJSON)
+	}
+	if len(decoded.Inputs) != 1 || decoded.Inputs[0].Sequence != 0xfffffffd || len(decoded.Inputs[0].Witness) != 4 || decoded.Inputs[0].Witness[3] != witnessScriptHex || decoded.Inputs[0].ScriptSig.Hex != "" {
+		t.Errorf("Decoded redeem with unexpected input:\n%s", decodedJSON)
+	}
+	testOutput := decodedOutput{90000, 0, decodedScript{"OP_0 OP_DATA_32 1863143c14c5166804bd19203356da136c985678cd4d27a1b8c6329604903262", "00201863143c14c5166804bd19203356da136c985678cd4d27a1b8c6329604903262", "P2WSH", "bc1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3qccfmv3"}}
+	if len(decoded.Outputs) != 1 || decoded.Outputs[0] != testOutput {
+		t.Errorf("Decoded redeem with unexpected output:\n%s", decodedJSON)
+	}
+
+	for _, invalidTx := range []string{"zz", testFundHex[:len(testFundHex)-2]} {
+		if _, err := generateDecode(invalidTx, false, "mainnet"); err == nil {
+			t.Errorf("generateDecode accepting invalid --tx %s.", invalidTx)
+		}
+	}
+	//Malformed transactions are refused with the byte offset of the field that could not be read
+	_, err = generateDecode(testRedeemHex[:10]+"02"+testRedeemHex[12:], false, "mainnet")
+	if err == nil || err.Error() != "Invalid --tx. Failed to deserialize transaction. Unknown transaction serialization flag 0x02 at byte 5." {
+		t.Errorf("generateDecode error not naming the offset of the invalid flag. %v", err)
+	}
+}
@@ -2,19 +2,132 @@
 package multisig
 
 import (
-	"github.com/prettymuchbryce/hellobitcoin/base58check"
 	"github.com/CryptoProcessing/go-bitcoin-multisig/btcutils"
 
 	"bytes"
 	"encoding/hex"
+	"errors"
 	"fmt"
-	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
+// TxFlags holds the global flags shared by the subcommands building a transaction. Their generate functions decode
+// PrevTx once and fill in the InputAmount of the subcommand flags from it when left out, so the fee is printed
+// without decoding PrevTx again.
+type TxFlags struct {
+	LockTime         int64  //Lock time of the transaction
+	Sequence         int64  //Sequence number of the input
+	RBF              bool   //Signal BIP125 replaceability
+	RelativeLockTime string //BIP68 relative lock time of the input, which requires a TxVersion of 2
+	TxVersion        int64  //Transaction version
+	DustRelayFee     int    //Dust relay fee rate in Satoshis per kilo virtual byte
+	AllowDust        bool   //Allow outputs below the dust threshold
+	MaxFee           int    //Highest fee in Satoshis
+	ForceHighFee     bool   //Allow fees above MaxFee or btcutils.MaxFeePercent of the amount sent
+	SigHash          string //Name of the hash type to sign with
+	OutputFormat     string //hex, or psbt for a signed base64 PSBT instead of the final transaction
+	PrevTx           string //Raw hex of the input transaction, or @FILE, recorded in a PSBT and the source of the input amount when it is 0
+	Network          string //Name of the network addresses and keys are encoded for
+}
+
+// FundFlags holds the flags of the fund subcommand.
+type FundFlags struct {
+	PrivateKey    string   //Private key of input Bitcoins to fund with
+	PublicKey     string   //Optional hex public key of PrivateKey, refused unless it is the compressed or uncompressed key derived from it
+	InputTx       string   //Input transaction hash of Bitcoins to fund with
+	InputIndex    int      //Output index of the input transaction to spend
+	Amount        int      //Amount in Satoshis to send to a single destination
+	Destinations  []string //Destination addresses being funded, each optionally as ADDRESS:AMOUNT
+	ChangeAddress string   //Optional address to send change to
+	InputAmount   int      //Amount in Satoshis of the input being spent
+	Fee           int      //Transaction fee in Satoshis
+	FeeRate       int      //Alternatively, fee rate in Satoshis per virtual byte
+	Force         bool     //Allow fee rates above btcutils.MaxFeeRate, and more than one OP_RETURN output
+	Sweep         bool     //Send the whole input less fee instead of Amount
+	OpReturns     []string //Data of OP_RETURN outputs, as hex
+	OpReturnTexts []string //Text of OP_RETURN outputs, embedded as UTF-8
+	TxFlags
+}
+
 //OutputFund formats and prints relevant outputs to the user.
-func OutputFund(flagPrivateKey string, flagInputTx string, flagAmount int, flagP2SHDestination string) {
-	finalTransactionHex := generateFund(flagPrivateKey, flagInputTx, flagAmount, flagP2SHDestination)
+func OutputFund(flags FundFlags) error {
+	finalTransactionHex, change, estimatedSize, err := generateFund(&flags)
+	if err != nil {
+		return err
+	}
+	printInputNote("input transaction", flags.InputTx, flags.InputIndex, flags.LockTime, flags.Sequence, flags.RBF, flags.RelativeLockTime, flags.TxVersion)
+
+	if flags.Sweep {
+		fmt.Printf(`
+-----------------------------------------------------------------------------------------------------------------------------------
+Sweeping entire input of %d satoshis, less transaction fee, to:
+%v
+-----------------------------------------------------------------------------------------------------------------------------------
+`,
+			flags.InputAmount,
+			flags.Destinations[0],
+		)
+	}
+
+	if flags.ChangeAddress != "" {
+		changeThreshold, err := changeDustThreshold(flags.ChangeAddress, flags.DustRelayFee, flags.Network)
+		if err != nil {
+			return err
+		}
+		if change >= changeThreshold {
+			fmt.Printf(`
+-----------------------------------------------------------------------------------------------------------------------------------
+Sending %d satoshis change to:
+%v
+-----------------------------------------------------------------------------------------------------------------------------------
+`,
+				change,
+				flags.ChangeAddress,
+			)
+		} else {
+			fmt.Printf(`
+-----------------------------------------------------------------------------------------------------------------------------------
+NOTE:
+Change of %d satoshis is below the dust threshold of %d satoshis, so no change output was created.
+The change has been added to the transaction fee instead.
+-----------------------------------------------------------------------------------------------------------------------------------
+`,
+				change,
+				changeThreshold,
+			)
+		}
+	}
+
+	//The data was already checked by generateFund
+	opReturns, _ := opReturnData(flags.OpReturns, flags.OpReturnTexts)
+	for _, data := range opReturns {
+		fmt.Printf(`
+-----------------------------------------------------------------------------------------------------------------------------------
+OP_RETURN output carrying %d bytes of data:
+hex:	%x
+`,
+			len(data),
+			data,
+		)
+		if isPrintable(data) {
+			fmt.Printf(`text:	%s
+`,
+				data,
+			)
+		}
+		fmt.Printf(`-----------------------------------------------------------------------------------------------------------------------------------
+`)
+	}
 
+	if flags.OutputFormat == "psbt" {
+		printPSBT(finalTransactionHex)
+		return nil
+	}
 	//Output our final transaction
 	fmt.Printf(`
 -----------------------------------------------------------------------------------------------------------------------------------
@@ -22,93 +135,685 @@ Your raw funding transaction is:
 %v
 Broadcast this transaction to fund your P2SH address.
 -----------------------------------------------------------------------------------------------------------------------------------
+-----------------------------------------------------------------------------------------------------------------------------------
+Estimated transaction size:	%d bytes
+Actual transaction size:	%d bytes
+-----------------------------------------------------------------------------------------------------------------------------------
 `,
 		finalTransactionHex,
+		estimatedSize,
+		len(finalTransactionHex)/2,
 	)
+	printFeeNote(finalTransactionHex, flags.InputAmount)
+	printTransactionIDs(finalTransactionHex)
+	printScripts(finalTransactionHex)
+	return nil
 }
 
-// generateFund is the high-level logic for funding any P2SH address with the 'go-bitcoin-multisig fund' subcommand.
-// Takes flagPrivateKey (private key of input Bitcoins to fund with), flagInputTx (input transaction hash of
-// Bitcoins to fund with), flagAmount (amount in Satoshis to send, with balance left over from input being used
-// as transaction fee) and flagP2SHDestination (destination P2SH multisig address which is being funded) as arguments.
-func generateFund(flagPrivateKey string, flagInputTx string, flagAmount int, flagP2SHDestination string) string {
+// generateFund is the high-level logic for funding any P2SH address with the 'go-bitcoin-multisig fund' subcommand,
+// from the flags described by FundFlags. Without a change address, balance left over from input is used as
+// transaction fee. Returns the final transaction hex, the change in Satoshis, which is only included as an output if
+// it is at least the dust threshold of the change address, the estimated size of the transaction in bytes and any
+// error encountered.
+func generateFund(flags *FundFlags) (string, int, int, error) {
+	input, lockTime, err := parseInput(flags.InputTx, flags.InputIndex, flags.LockTime, flags.Sequence, flags.RBF, flags.RelativeLockTime, flags.TxVersion)
+	if err != nil {
+		return "", 0, 0, err
+	}
+	prevTx, err := decodePrevTx(flags.PrevTx, input)
+	if err != nil {
+		return "", 0, 0, err
+	}
+	//The amount of the output spent is taken from the previous transaction if given
+	flags.InputAmount, err = prevTxInputAmount(prevTx, input, flags.InputAmount)
+	if err != nil {
+		return "", 0, 0, err
+	}
+	version, err := parseTxVersion(flags.TxVersion)
+	if err != nil {
+		return "", 0, 0, err
+	}
+	hashType, err := parseSigHashType(flags.SigHash)
+	if err != nil {
+		return "", 0, 0, err
+	}
+	err = checkOutputFormat(flags.OutputFormat)
+	if err != nil {
+		return "", 0, 0, err
+	}
+	err = btcutils.CheckFeeRate(flags.FeeRate, flags.Force)
+	if err != nil {
+		return "", 0, 0, err
+	}
+	if flags.Sweep {
+		if flags.Amount != 0 {
+			return "", 0, 0, errors.New("--sweep and --amount cannot be used together.")
+		}
+		if flags.ChangeAddress != "" {
+			return "", 0, 0, errors.New("--sweep and --change-address cannot be used together.")
+		}
+		if len(flags.Destinations) != 1 || strings.Contains(flags.Destinations[0], ":") {
+			return "", 0, 0, errors.New("--sweep requires a single --destination without an amount.")
+		}
+	}
+	if flags.ChangeAddress != "" {
+		if flags.InputAmount <= 0 {
+			return "", 0, 0, errors.New("--input-amount is required when sending change to --change-address.")
+		}
+		if flags.Fee <= 0 && flags.FeeRate <= 0 {
+			return "", 0, 0, errors.New("--fee or --fee-rate is required when sending change to --change-address.")
+		}
+		if flags.Fee > 0 && flags.FeeRate > 0 {
+			return "", 0, 0, errors.New("--fee and --fee-rate cannot be used together.")
+		}
+	} else if flags.FeeRate > 0 && !flags.Sweep {
+		return "", 0, 0, errors.New("--fee-rate can only be used when sending change to --change-address or sweeping with --sweep.")
+	}
+	params, err := btcutils.NetworkParamsByName(flags.Network)
+	if err != nil {
+		return "", 0, 0, err
+	}
 	//Get private key as decoded raw bytes
-	privateKey := base58check.Decode(flagPrivateKey)
+	privateKey, compressed, err := btcutils.ParseWIF(flags.PrivateKey, params)
+	if err != nil {
+		return "", 0, 0, fmt.Errorf("Invalid --private-key. %s", err)
+	}
 	//In order to construct the raw transaction we need the input transaction hash,
-	//the P2SH destination address, the number of satoshis to send, and the scriptSig
+	//the destination addresses, the number of satoshis to send, and the scriptSig
 	//which is temporarily (prior to signing) the ScriptPubKey of the input transaction.
-	publicKey, err := btcutils.NewPublicKey(privateKey)
+	//The input was paid to the hash of the compressed public key if the WIF says so, as by most wallets.
+	var publicKey []byte
+	if compressed {
+		publicKey, err = btcutils.NewCompressedPublicKey(privateKey)
+	} else {
+		publicKey, err = btcutils.NewPublicKey(privateKey)
+	}
 	if err != nil {
-		log.Fatal(err)
+		return "", 0, 0, err
+	}
+	if flags.PublicKey != "" {
+		err = checkPublicKey(flags.PublicKey, privateKey, publicKey, params)
+		if err != nil {
+			return "", 0, 0, err
+		}
+	}
+	//Refuse a key the input doesn't pay to before signing, if the previous transaction is known
+	if prevTx != nil {
+		err = checkPrevTxKey(prevTx, input, publicKey, nil, params)
+		if err != nil {
+			return "", 0, 0, err
+		}
 	}
 	publicKeyHash, err := btcutils.Hash160(publicKey)
 	if err != nil {
-		log.Fatal(err)
+		return "", 0, 0, err
 	}
 	tempScriptSig, err := btcutils.NewP2PKHScriptPubKey(publicKeyHash)
 	if err != nil {
-		log.Fatal(err)
+		return "", 0, 0, err
+	}
+	//Create our outputs, one per destination
+	outputs, err := parseDestinations(flags.Destinations, flags.Amount, params)
+	if err != nil {
+		return "", 0, 0, err
+	}
+	if !flags.Sweep && outputs[0].Satoshis == 0 {
+		return "", 0, 0, errors.New("--amount is required unless sweeping the whole input with --sweep.")
 	}
-	redeemScriptHash := base58check.Decode(flagP2SHDestination)
-	//Create our scriptPubKey
-	scriptPubKey, err := btcutils.NewP2SHScriptPubKey(redeemScriptHash)
+	//Data outputs follow the destinations, worth nothing so they do not change the outputs total
+	opReturns, err := opReturnData(flags.OpReturns, flags.OpReturnTexts)
 	if err != nil {
-		log.Fatal(err)
+		return "", 0, 0, err
 	}
-	//Create unsigned raw transaction
-	rawTransaction, err := btcutils.NewRawTransaction(flagInputTx, flagAmount, tempScriptSig, scriptPubKey)
+	opReturnOutputs, err := parseOpReturns(opReturns, flags.Force)
 	if err != nil {
-		log.Fatal(err)
+		return "", 0, 0, err
+	}
+	outputs = append(outputs, opReturnOutputs...)
+	outputsTotal := 0
+	for _, output := range outputs {
+		outputsTotal += int(output.Satoshis)
+	}
+	scriptSigSizes := []int{btcutils.EstimateP2PKHScriptSigSize(len(publicKey))}
+	estimatedSize := btcutils.EstimateSize(scriptSigSizes, outputs)
+	if flags.Sweep {
+		amount, err := sweepAmount(flags.InputAmount, flags.Fee, flags.FeeRate, estimatedSize)
+		if err != nil {
+			return "", 0, 0, err
+		}
+		outputs[0].Satoshis = uint64(amount)
+	}
+	change := 0
+	paidChange := 0 //Change paid back to an output, not counted as sent when comparing the fee to the amount sent
+	if flags.ChangeAddress != "" {
+		changeScriptPubKey, err := btcutils.NewScriptPubKeyFromAddress(flags.ChangeAddress, params)
+		if err != nil {
+			return "", 0, 0, fmt.Errorf("Invalid --change-address. %s", err)
+		}
+		changeOutput := btcutils.Output{ScriptPubKey: changeScriptPubKey}
+		fee := flags.Fee
+		if flags.FeeRate > 0 {
+			//Size the fee for a transaction including the change output
+			fee = flags.FeeRate * btcutils.EstimateSize(scriptSigSizes, append(outputs, changeOutput))
+		}
+		change = flags.InputAmount - outputsTotal - fee
+		if change < 0 {
+			return "", 0, 0, fmt.Errorf("Outputs (%d) and fee (%d) together exceed --input-amount (%d) by %d satoshis.", outputsTotal, fee, flags.InputAmount, -change)
+		}
+		//Pay change back to change address, unless it is too small to be worth an output
+		if change >= btcutils.DustThreshold(changeScriptPubKey, flags.DustRelayFee) {
+			changeOutput.Satoshis = uint64(change)
+			outputs = append(outputs, changeOutput)
+			paidChange = change
+			estimatedSize = btcutils.EstimateSize(scriptSigSizes, outputs)
+		}
+	} else if flags.InputAmount > 0 && !flags.Sweep && outputsTotal+flags.Fee > flags.InputAmount {
+		return "", 0, 0, fmt.Errorf("Outputs (%d) and fee (%d) together exceed --input-amount (%d) by %d satoshis.", outputsTotal, flags.Fee, flags.InputAmount, outputsTotal+flags.Fee-flags.InputAmount)
 	}
-	//After completing the raw transaction, we append
-	//SIGHASH_ALL in little-endian format to the end of the raw transaction.
-	hashCodeType, err := hex.DecodeString("01000000")
+	_, err = btcutils.CheckOutputs(outputs)
 	if err != nil {
-		log.Fatal(err)
+		return "", 0, 0, err
 	}
-	var rawTransactionBuffer bytes.Buffer
-	rawTransactionBuffer.Write(rawTransaction)
-	rawTransactionBuffer.Write(hashCodeType)
-	rawTransactionWithHashCodeType := rawTransactionBuffer.Bytes()
-	//Sign the raw transaction, and output it to the console.
-	finalTransaction, err := signP2PKHTransaction(rawTransactionWithHashCodeType, privateKey, scriptPubKey, flagInputTx, flagAmount)
+	err = checkDust(outputs, flags.DustRelayFee, flags.AllowDust)
 	if err != nil {
-		log.Fatal(err)
+		return "", 0, 0, err
+	}
+	err = checkHighFee(flags.InputAmount, outputs, paidChange, flags.MaxFee, flags.ForceHighFee)
+	if err != nil {
+		return "", 0, 0, err
+	}
+	//Create unsigned transaction
+	tx := &btcutils.Transaction{Version: version, Inputs: []btcutils.Input{input}, Outputs: outputs, LockTime: lockTime}
+	if flags.OutputFormat == "psbt" {
+		//The input spends the P2PKH output of the public key, used in place of the scriptSig when signing
+		utxo := btcutils.Output{Satoshis: uint64(flags.InputAmount), ScriptPubKey: tempScriptSig}
+		psbtBase64, err := generatePSBT(tx, utxo, prevTx, nil, nil, hashType, [][]byte{privateKey})
+		return psbtBase64, change, estimatedSize, err
+	}
+	//Sign the transaction with the --sighash hash type, and output it to the console.
+	//Without --input-amount the fee is unknown, which printFeeNote warns about, so it is left unchecked
+	maxFee := int64(flags.MaxFee)
+	finalTransaction, err := FundP2SH(FundP2SHOptions{PrivateKey: flags.PrivateKey, Input: input, InputAmount: int64(flags.InputAmount), Outputs: outputs, Version: version, LockTime: lockTime, SigHashType: hashType, Network: flags.Network, MaxFee: &maxFee, Change: int64(paidChange), ForceHighFee: flags.ForceHighFee || flags.InputAmount <= 0})
+	if err != nil {
+		return "", 0, 0, err
 	}
 	finalTransactionHex := hex.EncodeToString(finalTransaction)
 
-	return finalTransactionHex
+	return finalTransactionHex, change, estimatedSize, nil
+}
+
+// checkInputIndex validates the output index of an input transaction given on the command line,
+// which must be non-negative and fit in the 4 byte outpoint index field.
+func checkInputIndex(flagInputIndex int) (uint32, error) {
+	if flagInputIndex < 0 || int64(flagInputIndex) > math.MaxUint32 {
+		return 0, fmt.Errorf("--input-index must be between 0 and %d. Provided index is %d.", uint32(math.MaxUint32), flagInputIndex)
+	}
+	return uint32(flagInputIndex), nil
+}
+
+// parseSigHashType parses the --sighash hash type to sign ECDSA inputs with. The default hash type is
+// SIGHASH_ALL, as SIGHASH_DEFAULT only exists for Taproot.
+func parseSigHashType(flagSigHash string) (btcutils.SigHashType, error) {
+	hashType, err := btcutils.ParseSigHashType(flagSigHash)
+	if err != nil {
+		return 0, err
+	}
+	if hashType == btcutils.SigHashDefault {
+		return btcutils.SigHashAll, nil
+	}
+	return hashType, nil
+}
+
+// parseLockTime validates the --locktime and --sequence flags, returning the lock time of the transaction and the
+// sequence number of its input. With flagRBF the default sequence of btcutils.SequenceFinal is replaced by
+// btcutils.SequenceRBF, signalling the transaction can be replaced with a higher fee one as per BIP125. A lock time
+// is only enforced if an input is not final, so otherwise a final sequence is lowered to
+// btcutils.SequenceMaxNonFinal when the lock time is not zero.
+func parseLockTime(flagLockTime int64, flagSequence int64, flagRBF bool) (uint32, uint32, error) {
+	err := btcutils.CheckLockTime(flagLockTime)
+	if err != nil {
+		return 0, 0, err
+	}
+	if flagSequence < 0 || flagSequence > math.MaxUint32 {
+		return 0, 0, fmt.Errorf("--sequence must be between 0 and %d. Provided sequence is %d.", uint32(math.MaxUint32), flagSequence)
+	}
+	sequence := uint32(flagSequence)
+	if flagRBF && sequence == btcutils.SequenceFinal {
+		sequence = btcutils.SequenceRBF
+	}
+	if flagLockTime != 0 && sequence == btcutils.SequenceFinal {
+		sequence = btcutils.SequenceMaxNonFinal
+	}
+	return uint32(flagLockTime), sequence, nil
+}
+
+// parseTxVersion validates the --tx-version flag. Versions 1 and 2 are standard, and only version 2 transactions
+// enforce BIP68 relative lock times.
+func parseTxVersion(flagTxVersion int64) (uint32, error) {
+	if flagTxVersion < 1 || flagTxVersion > btcutils.RelativeLockTimeVersion {
+		return 0, fmt.Errorf("--tx-version must be 1 or %d. Provided version is %d.", btcutils.RelativeLockTimeVersion, flagTxVersion)
+	}
+	return uint32(flagTxVersion), nil
 }
 
-// signP2PKHTransaction signs a raw P2PKH transaction, given a private key and the scriptPubKey, inputTx and amount
-// to construct the final transaction.
-func signP2PKHTransaction(rawTransaction []byte, privateKey []byte, scriptPubKey []byte, inputTx string, amount int) ([]byte, error) {
-	publicKey, err := btcutils.NewPublicKey(privateKey)
+// parseRelativeLockTime parses the --relative-locktime flag, a number of blocks such as 144, or of seconds with an s
+// suffix such as 86400s, into the BIP68 sequence number of the input.
+func parseRelativeLockTime(flagRelativeLockTime string) (uint32, error) {
+	value := strings.TrimSuffix(flagRelativeLockTime, "s")
+	seconds := value != flagRelativeLockTime
+	number, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid --relative-locktime %s. Relative lock times should be a number of blocks, or of seconds followed by s.", flagRelativeLockTime)
+	}
+	return btcutils.NewRelativeLockTimeSequence(number, seconds)
+}
+
+// parseInput parses the input being spent from flagInputTx, given as TXID, TXID:VOUT or TXID:VOUT:SEQUENCE, with the
+// output index otherwise given by flagInputIndex and the sequence by flagSequence, flagRBF and flagLockTime as
+// parseLockTime returns it, or by flagRelativeLockTime, which requires a flagTxVersion of at least 2. A sequence
+// given with the input is used as it is, and is refused if it is final while the transaction has a lock time, which
+// would then be ignored. SEQUENCE may be decimal or hexadecimal with 0x.
+// Returns the input, without scriptSig, and the lock time of the transaction.
+func parseInput(flagInputTx string, flagInputIndex int, flagLockTime int64, flagSequence int64, flagRBF bool, flagRelativeLockTime string, flagTxVersion int64) (btcutils.Input, uint32, error) {
+	fields := strings.Split(flagInputTx, ":")
+	if len(fields) > 3 {
+		return btcutils.Input{}, 0, fmt.Errorf("Invalid input %s. Inputs should be given as TXID, TXID:VOUT or TXID:VOUT:SEQUENCE.", flagInputTx)
+	}
+	if len(fields) > 1 {
+		if flagInputIndex != 0 {
+			return btcutils.Input{}, 0, errors.New("--input-index cannot be used with TXID:VOUT inputs.")
+		}
+		var err error
+		flagInputIndex, err = strconv.Atoi(fields[1])
+		if err != nil {
+			return btcutils.Input{}, 0, fmt.Errorf("Invalid output index in input %s. Output index should be a number.", flagInputTx)
+		}
+	}
+	inputIndex, err := checkInputIndex(flagInputIndex)
+	if err != nil {
+		return btcutils.Input{}, 0, err
+	}
+	lockTime, sequence, err := parseLockTime(flagLockTime, flagSequence, flagRBF)
+	if err != nil {
+		return btcutils.Input{}, 0, err
+	}
+	version, err := parseTxVersion(flagTxVersion)
+	if err != nil {
+		return btcutils.Input{}, 0, err
+	}
+	if flagRelativeLockTime != "" {
+		if version < btcutils.RelativeLockTimeVersion {
+			return btcutils.Input{}, 0, fmt.Errorf("--relative-locktime requires --tx-version %d, as relative lock times are ignored in version %d transactions.", btcutils.RelativeLockTimeVersion, version)
+		}
+		if flagSequence != int64(btcutils.SequenceFinal) || len(fields) > 2 {
+			return btcutils.Input{}, 0, errors.New("--relative-locktime cannot be used with --sequence or TXID:VOUT:SEQUENCE inputs.")
+		}
+		sequence, err = parseRelativeLockTime(flagRelativeLockTime)
+		if err != nil {
+			return btcutils.Input{}, 0, err
+		}
+	}
+	if len(fields) > 2 {
+		if flagSequence != int64(btcutils.SequenceFinal) {
+			return btcutils.Input{}, 0, errors.New("--sequence cannot be used with TXID:VOUT:SEQUENCE inputs.")
+		}
+		inputSequence, err := strconv.ParseUint(fields[2], 0, 32)
+		if err != nil {
+			return btcutils.Input{}, 0, fmt.Errorf("Invalid sequence in input %s. Sequence should be between 0 and %d.", flagInputTx, uint32(math.MaxUint32))
+		}
+		sequence = uint32(inputSequence)
+		if lockTime != 0 && sequence == btcutils.SequenceFinal {
+			return btcutils.Input{}, 0, fmt.Errorf("Input %s has the final sequence 0x%08x, so --locktime would be ignored. Use a lower sequence such as 0x%08x.", flagInputTx, btcutils.SequenceFinal, btcutils.SequenceMaxNonFinal)
+		}
+	}
+	return btcutils.Input{TxHash: fields[0], OutputIndex: inputIndex, Sequence: sequence}, lockTime, nil
+}
+
+// printInputNote tells the user the output being spent, described by inputDescription, and the sequence number of
+// the input, whether it signals replaceability, and when the transaction can be mined if it has a lock time or a
+// relative lock time.
+func printInputNote(inputDescription string, flagInputTx string, flagInputIndex int, flagLockTime int64, flagSequence int64, flagRBF bool, flagRelativeLockTime string, flagTxVersion int64) {
+	input, lockTime, err := parseInput(flagInputTx, flagInputIndex, flagLockTime, flagSequence, flagRBF, flagRelativeLockTime, flagTxVersion)
+	if err != nil {
+		return
+	}
+	replaceable := "does not signal"
+	if input.Sequence <= btcutils.SequenceRBF {
+		replaceable = "signals"
+	}
+	fmt.Printf(`
+-----------------------------------------------------------------------------------------------------------------------------------
+Spending output index %d of %v:
+%v
+Input sequence is 0x%08x, so the version %d transaction %v it can be replaced by a higher fee transaction (BIP125).
+`,
+		input.OutputIndex,
+		inputDescription,
+		input.TxHash,
+		input.Sequence,
+		flagTxVersion,
+		replaceable,
+	)
+	if relativeLockTime, seconds, ok := btcutils.RelativeLockTime(uint32(flagTxVersion), input.Sequence); ok {
+		unit := "blocks"
+		if seconds {
+			unit = "seconds"
+		}
+		fmt.Printf(`This transaction cannot be mined until the output it spends is %d %v old (BIP68 relative lock time).
+`,
+			relativeLockTime,
+			unit,
+		)
+	}
+	if lockTime != 0 {
+		lockedUntil := fmt.Sprintf("block height %d", lockTime)
+		if lockTime >= btcutils.LockTimeThreshold {
+			lockedUntil = time.Unix(int64(lockTime), 0).UTC().Format(time.RFC1123)
+		}
+		fmt.Printf(`This transaction cannot be mined before %v.
+`,
+			lockedUntil,
+		)
+		//Only the default sequence is lowered, never one given with the input
+		if input.Sequence != uint32(flagSequence) && !flagRBF && flagRelativeLockTime == "" && strings.Count(flagInputTx, ":") < 2 {
+			fmt.Printf(`NOTE: Lock times are ignored when every input is final, so the input sequence has been lowered from 0x%08x.
+`,
+				btcutils.SequenceFinal,
+			)
+		}
+	}
+	fmt.Printf(`-----------------------------------------------------------------------------------------------------------------------------------
+`)
+}
+
+// printFeeNote prints the fee implied by the outputs of the final transaction, if flagInputAmount, the amount held
+// by its input as given or taken from --prev-tx, is known, and the fee rate it pays over the virtual size of the
+// transaction. Otherwise it warns that the fee is unknown, and so was not checked against --max-fee.
+func printFeeNote(finalTransactionHex string, flagInputAmount int) {
+	if flagInputAmount <= 0 {
+		fmt.Printf(`
+-----------------------------------------------------------------------------------------------------------------------------------
+WARNING:
+The fee is unknown without --input-amount or --prev-tx, so it was not checked against --max-fee.
+Every satoshi the input holds beyond the outputs is paid as fee, so check the amounts before broadcasting.
+-----------------------------------------------------------------------------------------------------------------------------------
+`)
+		return
+	}
+	finalTransaction, err := hex.DecodeString(finalTransactionHex)
+	if err != nil {
+		return
+	}
+	tx, err := btcutils.DeserializeTransaction(finalTransaction)
+	if err != nil {
+		return
+	}
+	fee, err := btcutils.CheckFee(uint64(flagInputAmount), tx.Outputs)
+	if err != nil {
+		return
+	}
+	virtualSize, err := btcutils.VirtualSize(tx)
+	if err != nil {
+		return
+	}
+	fmt.Printf(`
+-----------------------------------------------------------------------------------------------------------------------------------
+Transaction fee:	%d satoshis (%.2f sat/vB over a virtual size of %d vbytes), the input amount of %d satoshis less the outputs.
+-----------------------------------------------------------------------------------------------------------------------------------
+`,
+		fee,
+		float64(fee)/float64(virtualSize),
+		virtualSize,
+		flagInputAmount,
+	)
+}
+
+// printTransactionIDs prints the ID of the final transaction, to look it up once broadcast, and for transactions
+// with witness data the witness ID as well, since only the transaction ID is unaffected by the witnesses.
+func printTransactionIDs(finalTransactionHex string) {
+	finalTransaction, err := hex.DecodeString(finalTransactionHex)
+	if err != nil {
+		return
+	}
+	txID, err := btcutils.TxID(finalTransaction)
+	if err != nil {
+		return
+	}
+	wTxID, err := btcutils.WTxID(finalTransaction)
+	if err != nil {
+		return
+	}
+	fmt.Printf(`
+-----------------------------------------------------------------------------------------------------------------------------------
+Transaction ID (txid) to monitor once broadcast:
+%v
+`,
+		txID,
+	)
+	if wTxID != txID {
+		fmt.Printf(`Witness transaction ID (wtxid):
+%v
+`,
+			wTxID,
+		)
+	}
+	fmt.Printf(`-----------------------------------------------------------------------------------------------------------------------------------
+`)
+}
+
+// parseDestinations creates an output for each --destination flag, with the scriptPubKey template chosen by the
+// address version byte. Destinations are given as ADDRESS:AMOUNT, or as a lone ADDRESS paying flagAmount Satoshis
+// which is only allowed when there is a single destination. Addresses must be encoded for the network given by params.
+func parseDestinations(flagDestinations []string, flagAmount int, params *btcutils.NetworkParams) ([]btcutils.Output, error) {
+	if len(flagDestinations) == 0 {
+		return nil, errors.New("At least one --destination is required.")
+	}
+	outputs := make([]btcutils.Output, len(flagDestinations))
+	for i, destination := range flagDestinations {
+		address := destination
+		amount := flagAmount
+		if separator := strings.LastIndex(destination, ":"); separator >= 0 {
+			if flagAmount != 0 {
+				return nil, errors.New("--amount cannot be used with ADDRESS:AMOUNT destinations.")
+			}
+			address = destination[:separator]
+			var err error
+			amount, err = strconv.Atoi(destination[separator+1:])
+			if err != nil || amount <= 0 {
+				return nil, fmt.Errorf("Invalid amount in destination %s. Amount should be a positive number of satoshis.", destination)
+			}
+		} else if len(flagDestinations) > 1 {
+			return nil, fmt.Errorf("Destination %s has no amount. Multiple destinations must each be given as ADDRESS:AMOUNT.", destination)
+		}
+		scriptPubKey, err := btcutils.NewScriptPubKeyFromAddress(address, params)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid --destination. %s", err)
+		}
+		outputs[i] = btcutils.Output{Satoshis: uint64(amount), ScriptPubKey: scriptPubKey}
+	}
+	return outputs, nil
+}
+
+// parseOpReturns creates a zero value OP_RETURN output carrying each of opReturns. More than one OP_RETURN output is
+// non-standard, so it is refused unless flagForce is set.
+func parseOpReturns(opReturns [][]byte, flagForce bool) ([]btcutils.Output, error) {
+	if len(opReturns) > 1 && !flagForce {
+		return nil, fmt.Errorf("%d --op-return and --op-return-text outputs given. Transactions with more than one OP_RETURN output are not relayed by most nodes, use --force to create them anyway.", len(opReturns))
+	}
+	outputs := make([]btcutils.Output, len(opReturns))
+	for i, data := range opReturns {
+		scriptPubKey, err := btcutils.CreateOPReturnScriptPubKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid OP_RETURN output %d. %s", i+1, err)
+		}
+		outputs[i] = btcutils.Output{Satoshis: 0, ScriptPubKey: scriptPubKey}
+	}
+	return outputs, nil
+}
+
+// opReturnData returns the data of each OP_RETURN output, decoded from the hex of each --op-return flag, such as a
+// document hash, followed by the UTF-8 bytes of each --op-return-text flag. Text is never read as hex, so text
+// that happens to be valid hex is embedded as given.
+func opReturnData(flagOpReturns []string, flagOpReturnTexts []string) ([][]byte, error) {
+	var opReturns [][]byte
+	for _, flagOpReturn := range flagOpReturns {
+		data, err := hex.DecodeString(flagOpReturn)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid --op-return %s. Data must be hex, use --op-return-text to embed text. %s", flagOpReturn, err)
+		}
+		opReturns = append(opReturns, data)
+	}
+	for _, flagOpReturnText := range flagOpReturnTexts {
+		opReturns = append(opReturns, []byte(flagOpReturnText))
+	}
+	return opReturns, nil
+}
+
+// isPrintable reports whether data is non-empty UTF-8 text without control characters, so it can be shown as text.
+func isPrintable(data []byte) bool {
+	if len(data) == 0 || !utf8.Valid(data) {
+		return false
+	}
+	for _, r := range string(data) {
+		if !unicode.IsPrint(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// sweepAmount calculates the single output amount when sweeping a whole input of flagInputAmount Satoshis,
+// less either the fixed flagFee or flagFeeRate times the estimatedSize of the transaction. Sweeps leaving
+// nothing to send are refused.
+func sweepAmount(flagInputAmount int, flagFee int, flagFeeRate int, estimatedSize int) (int, error) {
+	if flagInputAmount <= 0 {
+		return 0, errors.New("--input-amount is required when sweeping with --sweep.")
+	}
+	if flagFee <= 0 && flagFeeRate <= 0 {
+		return 0, errors.New("--fee or --fee-rate is required when sweeping with --sweep.")
+	}
+	if flagFee > 0 && flagFeeRate > 0 {
+		return 0, errors.New("--fee and --fee-rate cannot be used together.")
+	}
+	fee := flagFee
+	if flagFeeRate > 0 {
+		fee = flagFeeRate * estimatedSize
+	}
+	amount := flagInputAmount - fee
+	if amount <= 0 {
+		return 0, fmt.Errorf("Sweeping %d satoshis with a fee of %d satoshis leaves nothing to send.", flagInputAmount, fee)
+	}
+	return amount, nil
+}
+
+// checkDust refuses outputs worth less than the dust threshold of their scriptPubKey at flagDustRelayFee satoshis
+// per kilo virtual byte, unless flagAllowDust is set.
+func checkDust(outputs []btcutils.Output, flagDustRelayFee int, flagAllowDust bool) error {
+	if flagDustRelayFee < 0 {
+		return fmt.Errorf("--dust-relay-fee cannot be negative. Provided fee rate is %d sat/kvB.", flagDustRelayFee)
+	}
+	if flagAllowDust {
+		return nil
+	}
+	for _, output := range outputs {
+		err := btcutils.CheckDust(output, flagDustRelayFee)
+		if err != nil {
+			return fmt.Errorf("%s Nodes will not relay it, use --allow-dust to create it anyway.", err)
+		}
+	}
+	return nil
+}
+
+// checkHighFee refuses a fee, flagInputAmount less the outputs, above flagMaxFee satoshis or above
+// btcutils.MaxFeePercent of the satoshis sent to outputs other than change, which holds change satoshis, unless
+// flagForceHighFee is set. Nothing is checked without flagInputAmount, as the fee is then unknown, which
+// printFeeNote warns about instead.
+func checkHighFee(flagInputAmount int, outputs []btcutils.Output, change int, flagMaxFee int, flagForceHighFee bool) error {
+	if flagMaxFee < 0 {
+		return fmt.Errorf("--max-fee cannot be negative. Provided maximum is %d satoshis.", flagMaxFee)
+	}
+	if flagInputAmount <= 0 || flagForceHighFee {
+		return nil
+	}
+	fee, err := btcutils.CheckFee(uint64(flagInputAmount), outputs)
+	if err != nil {
+		return fmt.Errorf("Invalid --amount. %s", err)
+	}
+	sent := int64(flagInputAmount) - int64(fee) - int64(change)
+	err = btcutils.CheckMaxFee(int64(fee), sent, int64(flagMaxFee))
+	if highFeeError, ok := err.(*btcutils.HighFeeError); ok && !highFeeError.Relative {
+		return fmt.Errorf("%s Raise the ceiling with --max-fee, or use --force-high-fee to send it anyway.", err)
+	} else if ok {
+		return fmt.Errorf("%s Use --force-high-fee to send it anyway.", err)
+	}
+	return err
+}
+
+// changeDustThreshold returns the dust threshold in satoshis of change sent to flagChangeAddress at
+// flagDustRelayFee satoshis per kilo virtual byte. Smaller change is added to the transaction fee instead.
+func changeDustThreshold(flagChangeAddress string, flagDustRelayFee int, flagNetwork string) (int, error) {
+	params, err := btcutils.NetworkParamsByName(flagNetwork)
+	if err != nil {
+		return 0, err
+	}
+	changeScriptPubKey, err := btcutils.NewScriptPubKeyFromAddress(flagChangeAddress, params)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid --change-address. %s", err)
+	}
+	return btcutils.DustThreshold(changeScriptPubKey, flagDustRelayFee), nil
+}
+
+// checkPublicKey checks that flagPublicKey, in hex, is the compressed or uncompressed public key of privateKey.
+// Otherwise the error names the P2PKH address of publicKey, the key signing the input, to show which key is held.
+func checkPublicKey(flagPublicKey string, privateKey []byte, publicKey []byte, params *btcutils.NetworkParams) error {
+	givenKey, err := hex.DecodeString(flagPublicKey)
+	if err != nil {
+		return fmt.Errorf("Invalid --public-key. %s", err)
+	}
+	compressedKey, err := btcutils.NewCompressedPublicKey(privateKey)
+	if err != nil {
+		return err
+	}
+	uncompressedKey, err := btcutils.NewPublicKey(privateKey)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(givenKey, compressedKey) || bytes.Equal(givenKey, uncompressedKey) {
+		return nil
+	}
+	address, err := btcutils.PublicKeyToP2PKHAddress(publicKey, params)
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("--public-key %s does not belong to --private-key, which is the key of address %s.", flagPublicKey, address)
+}
+
+// signP2PKHTransaction signs the single P2PKH input of tx with hashType, given a private key and its public key,
+// compressed or uncompressed to match the input being spent, and the scriptPubKey of the output being spent.
+// Returns the serialized signed transaction.
+func signP2PKHTransaction(tx *btcutils.Transaction, privateKey []byte, publicKey []byte, scriptPubKey []byte, hashType btcutils.SigHashType) ([]byte, error) {
+	err := btcutils.CheckSigHashType(tx, 0, hashType)
 	if err != nil {
 		return nil, err
 	}
-	signature, err := btcutils.NewSignature(rawTransaction, privateKey)
+	sigHash, err := btcutils.CalcSignatureHash(tx, 0, scriptPubKey, hashType)
 	if err != nil {
 		return nil, err
 	}
-	hashCodeType, err := hex.DecodeString("01")
+	signature, err := btcutils.SignHash(sigHash, privateKey)
 	if err != nil {
 		return nil, err
 	}
-	//signatureLength is +1 to add hashCodeType
+	//signatureLength is +1 to add hash type
 	signatureLength := byte(len(signature) + 1)
 	//Create scriptSig
 	var buffer bytes.Buffer
 	buffer.WriteByte(signatureLength)
 	buffer.Write(signature)
-	buffer.WriteByte(hashCodeType[0])
+	buffer.WriteByte(byte(hashType))
 	buffer.WriteByte(byte(len(publicKey)))
 	buffer.Write(publicKey)
-	scriptSig := buffer.Bytes()
 	//Finally create transaction with actual scriptSig
-	signedRawTransaction, err := btcutils.NewRawTransaction(inputTx, amount, scriptSig, scriptPubKey)
-	if err != nil {
-		return nil, err
-	}
-	return signedRawTransaction, nil
+	tx.Inputs[0].ScriptSig = buffer.Bytes()
+	return tx.Serialize()
 }
@@ -0,0 +1,170 @@
+// fund_p2sh_p2wpkh.go - Funding an address from a nested SegWit P2SH-P2WPKH output.
+package multisig
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/btcutils"
+
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// OutputFundP2SHP2WPKH formats and prints relevant outputs to the user.
+func OutputFundP2SHP2WPKH(flags FundP2WPKHFlags) error {
+	finalTransactionHex, err := generateFundP2SHP2WPKH(&flags)
+	if err != nil {
+		return err
+	}
+	printInputNote("P2SH-P2WPKH input transaction", flags.InputTx, flags.InputIndex, flags.LockTime, flags.Sequence, flags.RBF, flags.RelativeLockTime, flags.TxVersion)
+	if flags.OutputFormat == "psbt" {
+		printPSBT(finalTransactionHex)
+		return nil
+	}
+
+	//Output our final transaction
+	fmt.Printf(`
+-----------------------------------------------------------------------------------------------------------------------------------
+Your raw funding transaction is:
+%v
+Broadcast this transaction to fund your address.
+-----------------------------------------------------------------------------------------------------------------------------------
+`,
+		finalTransactionHex,
+	)
+	printFeeNote(finalTransactionHex, flags.InputAmount)
+	printTransactionIDs(finalTransactionHex)
+	printScripts(finalTransactionHex)
+	return nil
+}
+
+// generateFundP2SHP2WPKH is the high-level logic for spending a nested SegWit P2SH-P2WPKH output with the
+// 'go-bitcoin-multisig fund-p2sh-p2wpkh' subcommand. Takes the same flags as generateFundP2WPKH, with PrivateKey the
+// private key of the P2SH-P2WPKH output and InputAmount the amount it holds, which is committed to by the BIP143
+// signature.
+// The scriptSig only pushes the redeem script OP_0 <HASH160(pubkey)>, and the signature and public key go in the
+// witness as for native P2WPKH, so the input gets the SegWit fee discount although the output is a '3' address.
+// Balance left over from input is used as transaction fee, so with FeeRate the whole input less the estimated
+// fee is sent.
+func generateFundP2SHP2WPKH(flags *FundP2WPKHFlags) (string, error) {
+	input, lockTime, err := parseInput(flags.InputTx, flags.InputIndex, flags.LockTime, flags.Sequence, flags.RBF, flags.RelativeLockTime, flags.TxVersion)
+	if err != nil {
+		return "", err
+	}
+	prevTx, err := decodePrevTx(flags.PrevTx, input)
+	if err != nil {
+		return "", err
+	}
+	//The amount of the output spent is taken from the previous transaction if given
+	flags.InputAmount, err = prevTxInputAmount(prevTx, input, flags.InputAmount)
+	if err != nil {
+		return "", err
+	}
+	version, err := parseTxVersion(flags.TxVersion)
+	if err != nil {
+		return "", err
+	}
+	hashType, err := parseSigHashType(flags.SigHash)
+	if err != nil {
+		return "", err
+	}
+	err = checkOutputFormat(flags.OutputFormat)
+	if err != nil {
+		return "", err
+	}
+	if flags.InputAmount <= 0 {
+		return "", errors.New("--input-amount, or --prev-tx to take it from, is required to sign a P2SH-P2WPKH input.")
+	}
+	if flags.Amount > flags.InputAmount {
+		return "", fmt.Errorf("--amount (%d) exceeds --input-amount (%d).", flags.Amount, flags.InputAmount)
+	}
+	err = btcutils.CheckFeeRate(flags.FeeRate, flags.Force)
+	if err != nil {
+		return "", err
+	}
+	if flags.FeeRate > 0 && flags.Amount != 0 {
+		return "", errors.New("--amount and --fee-rate cannot be used together.")
+	}
+	//Get private key as decoded raw bytes
+	params, err := btcutils.NetworkParamsByName(flags.Network)
+	if err != nil {
+		return "", err
+	}
+	privateKey, compressed, err := btcutils.ParseWIF(flags.PrivateKey, params)
+	if err != nil {
+		return "", fmt.Errorf("Invalid --private-key. %s", err)
+	}
+	if !compressed {
+		return "", errors.New("P2SH-P2WPKH outputs can only be spent with a compressed WIF private key.")
+	}
+	publicKey, err := btcutils.NewCompressedPublicKey(privateKey)
+	if err != nil {
+		return "", err
+	}
+	//Refuse a key the input doesn't pay to before signing, if the previous transaction is known
+	if prevTx != nil {
+		err = checkPrevTxKey(prevTx, input, publicKey, nil, params)
+		if err != nil {
+			return "", err
+		}
+	}
+	redeemScript, err := btcutils.NewP2SHP2WPKHRedeemScript(publicKey)
+	if err != nil {
+		return "", err
+	}
+	scriptPubKey, err := btcutils.NewScriptPubKeyFromAddress(flags.Destination, params)
+	if err != nil {
+		return "", fmt.Errorf("Invalid --destination. %s", err)
+	}
+	//P2SH-P2WPKH scriptSig format:
+	//<redeemScript>
+	input.ScriptSig = append([]byte{byte(len(redeemScript))}, redeemScript...)
+	tx := &btcutils.Transaction{
+		Version:  version,
+		Inputs:   []btcutils.Input{input},
+		Outputs:  []btcutils.Output{{Satoshis: uint64(flags.Amount), ScriptPubKey: scriptPubKey}},
+		LockTime: lockTime,
+	}
+	if flags.FeeRate > 0 {
+		//The scriptSig is final, so estimate the signed size with a placeholder signature and public key in the witness
+		unsigned := *tx
+		unsigned.Inputs = []btcutils.Input{input}
+		unsigned.Inputs[0].Witness = [][]byte{make([]byte, 72), publicKey}
+		fee, err := btcutils.EstimateFee(&unsigned, int64(flags.FeeRate))
+		if err != nil {
+			return "", err
+		}
+		if fee >= int64(flags.InputAmount) {
+			return "", fmt.Errorf("Sending %d satoshis with a fee of %d satoshis leaves nothing to send.", flags.InputAmount, fee)
+		}
+		tx.Outputs[0].Satoshis = uint64(int64(flags.InputAmount) - fee)
+	}
+	_, err = btcutils.CheckOutputs(tx.Outputs)
+	if err != nil {
+		return "", err
+	}
+	err = checkDust(tx.Outputs, flags.DustRelayFee, flags.AllowDust)
+	if err != nil {
+		return "", err
+	}
+	err = checkHighFee(flags.InputAmount, tx.Outputs, 0, flags.MaxFee, flags.ForceHighFee)
+	if err != nil {
+		return "", err
+	}
+	if flags.OutputFormat == "psbt" {
+		inputScriptPubKey, err := btcutils.CreateP2SHP2WPKHScriptPubKey(publicKey)
+		if err != nil {
+			return "", err
+		}
+		utxo := btcutils.Output{Satoshis: uint64(flags.InputAmount), ScriptPubKey: inputScriptPubKey}
+		return generatePSBT(tx, utxo, prevTx, redeemScript, nil, hashType, [][]byte{privateKey})
+	}
+	//Sign the transaction with the --sighash hash type
+	maxFee := int64(flags.MaxFee)
+	finalTransaction, err := FundP2SHP2WPKH(FundP2SHP2WPKHOptions{PrivateKey: flags.PrivateKey, Input: input, InputAmount: int64(flags.InputAmount), Outputs: tx.Outputs, Version: version, LockTime: lockTime, SigHashType: hashType, Network: flags.Network, MaxFee: &maxFee, ForceHighFee: flags.ForceHighFee})
+	if err != nil {
+		return "", err
+	}
+	finalTransactionHex := hex.EncodeToString(finalTransaction)
+
+	return finalTransactionHex, nil
+}
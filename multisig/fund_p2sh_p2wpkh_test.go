@@ -0,0 +1,83 @@
+package multisig
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/btcutils"
+	"github.com/CryptoProcessing/go-bitcoin-multisig/testutils"
+
+	"encoding/hex"
+	"testing"
+)
+
+func TestGenerateFundP2SHP2WPKH(t *testing.T) {
+	btcutils.SetFixedNonce = true //SetFixedNonce set to true to get repeatable signatures with a fixed nonce for testing.
+	//Spends the P2SH-P2WPKH output 38BW8nqpHSWpkf5sXrQd2xYwvnPJwP59ic of the BIP143 P2SH-P2WPKH example, with its lock
+	//time and sequence, to the first output of the example. The scriptSig pushes the redeemScript only
+	testPrivateKeyWIF := "L57KYn5isHFThD4cohjJgLTZA2vaxnMMKWngnzbttF159yH9dARf"
+	testInputTx := "77541aeb3c4dac9260b68f74f44c973081a9d4cb2ebe8038b2d70faa201b6bdb"
+	testInputIndex := 1
+	testInputAmount := 1000000000
+	testAmount := 999990000
+	testDestination := "1Fyxts6r24DpEieygQiNnWxUdb18ANa5p7"
+	testFinalTransanctionHex := "01000000000101db6b1b20aa0fd7b23880be2ecbd4a98130974cf4748fb66092ac4d3ceb1a547701000000" +
+		"1716001479091972186c449eb1ded22b78e40d009bdf0089" + "feffffff01f0a29a3b000000001976a914a457b684d7f0d539a46a45bbc043f35b59d0d96388ac" +
+		"0247304402206d6caac248af96f6afa7f904f550253a0f3ef3f5aa2fe6838a95b216691468e202202eaf1481f7e4a6eb33dc90f11d1e38ddd3b04c9e646fa44b523f4c7400ded57f01" +
+		"2103ad1d8e89212f0b92c74d23bb710c00662ad1470198ac48c43f7d6f93a2a26873" + "92040000"
+
+	finalTransactionHex, err := generateFundP2SHP2WPKH(&FundP2WPKHFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, InputIndex: testInputIndex, InputAmount: testInputAmount, Amount: testAmount, Destination: testDestination, TxFlags: TxFlags{LockTime: 1170, Sequence: 0xfffffffe, TxVersion: 1, DustRelayFee: 3000, MaxFee: 1000000, SigHash: "default", OutputFormat: "hex", Network: "mainnet"}})
+	if err != nil {
+		t.Error(err)
+	}
+	if finalTransactionHex != testFinalTransanctionHex {
+		testutils.CompareError(t, "Generated P2SH-P2WPKH funding transaction different from expected transaction.", testFinalTransanctionHex, finalTransactionHex)
+	}
+}
+
+func TestGenerateFundP2SHP2WPKHFeeRate(t *testing.T) {
+	testPrivateKeyWIF := "L57KYn5isHFThD4cohjJgLTZA2vaxnMMKWngnzbttF159yH9dARf"
+	testInputTx := "77541aeb3c4dac9260b68f74f44c973081a9d4cb2ebe8038b2d70faa201b6bdb"
+	testInputAmount := 1000000000
+	testFeeRate := 10
+	testDestination := "1Fyxts6r24DpEieygQiNnWxUdb18ANa5p7"
+	//One P2SH-P2WPKH input and one P2PKH output weigh 542, estimated at 136 virtual bytes
+	testAmount := uint64(testInputAmount - 136*testFeeRate)
+
+	finalTransactionHex, err := generateFundP2SHP2WPKH(&FundP2WPKHFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, InputIndex: 1, InputAmount: testInputAmount, FeeRate: testFeeRate, Destination: testDestination, TxFlags: testTxFlags})
+	if err != nil {
+		t.Fatal(err)
+	}
+	finalTransaction, _ := hex.DecodeString(finalTransactionHex)
+	tx, err := btcutils.DeserializeTransaction(finalTransaction)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.Outputs[0].Satoshis != testAmount {
+		testutils.CompareError(t, "Amount sent with fee rate different from expected amount.", testAmount, tx.Outputs[0].Satoshis)
+	}
+	virtualSize, err := btcutils.VirtualSize(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if virtualSize > 136 || virtualSize < 134 {
+		testutils.CompareError(t, "Signed transaction virtual size not within 2 virtual bytes of the estimate.", 136, virtualSize)
+	}
+}
+
+func TestGenerateFundP2SHP2WPKHErrors(t *testing.T) {
+	testPrivateKeyWIF := "L57KYn5isHFThD4cohjJgLTZA2vaxnMMKWngnzbttF159yH9dARf"
+	testInputTx := "77541aeb3c4dac9260b68f74f44c973081a9d4cb2ebe8038b2d70faa201b6bdb"
+	testDestination := "1Fyxts6r24DpEieygQiNnWxUdb18ANa5p7"
+
+	if _, err := generateFundP2SHP2WPKH(&FundP2WPKHFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, InputIndex: 1, Amount: 1000, Destination: testDestination, TxFlags: testTxFlags}); err == nil {
+		t.Error("generateFundP2SHP2WPKH accepting missing input amount.")
+	}
+	if _, err := generateFundP2SHP2WPKH(&FundP2WPKHFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, InputIndex: 1, InputAmount: 1000, Amount: 2000, Destination: testDestination, TxFlags: testTxFlags}); err == nil {
+		t.Error("generateFundP2SHP2WPKH accepting amount larger than input amount.")
+	}
+	//Uncompressed WIF of the same private key
+	if _, err := generateFundP2SHP2WPKH(&FundP2WPKHFlags{PrivateKey: "5Kbxro1cmUF9mTJ8fDrTfNB6URTBsFMUG52jzzumP2p9C94uKCh", InputTx: testInputTx, InputIndex: 1, InputAmount: 100000, Amount: 90000, Destination: testDestination, TxFlags: testTxFlags}); err == nil {
+		t.Error("generateFundP2SHP2WPKH accepting uncompressed private key.")
+	}
+	if _, err := generateFundP2SHP2WPKH(&FundP2WPKHFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, InputIndex: 1, InputAmount: 100000, Amount: 90000, FeeRate: 10, Destination: testDestination, TxFlags: testTxFlags}); err == nil {
+		t.Error("generateFundP2SHP2WPKH accepting --amount with --fee-rate.")
+	}
+}
@@ -0,0 +1,152 @@
+// fund_p2tr.go - Funding an address from a Taproot P2TR output with a key path spend.
+package multisig
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/btcutils"
+
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// FundP2TRFlags holds the flags of the fund-p2tr subcommand.
+type FundP2TRFlags struct {
+	PrivateKey  string //Private key of the P2TR output
+	InputTx     string //Input transaction hash
+	InputIndex  int    //Output index of the input transaction to spend
+	InputAmount int    //Amount in Satoshis held by the P2TR output, which is committed to by the BIP341 signature
+	Amount      int    //Amount in Satoshis to send
+	Destination string //Destination address
+	TxFlags
+}
+
+// OutputFundP2TR formats and prints relevant outputs to the user.
+func OutputFundP2TR(flags FundP2TRFlags) error {
+	finalTransactionHex, err := generateFundP2TR(&flags)
+	if err != nil {
+		return err
+	}
+	printInputNote("P2TR input transaction", flags.InputTx, flags.InputIndex, flags.LockTime, flags.Sequence, flags.RBF, flags.RelativeLockTime, flags.TxVersion)
+	if flags.OutputFormat == "psbt" {
+		printPSBT(finalTransactionHex)
+		return nil
+	}
+
+	//Output our final transaction
+	fmt.Printf(`
+-----------------------------------------------------------------------------------------------------------------------------------
+Your raw funding transaction is:
+%v
+Broadcast this transaction to fund your address.
+-----------------------------------------------------------------------------------------------------------------------------------
+`,
+		finalTransactionHex,
+	)
+	printFeeNote(finalTransactionHex, flags.InputAmount)
+	printTransactionIDs(finalTransactionHex)
+	printScripts(finalTransactionHex)
+	return nil
+}
+
+// generateFundP2TR is the high-level logic for spending a Taproot P2TR output with the key path with the
+// 'go-bitcoin-multisig fund-p2tr' subcommand, from the flags described by FundP2TRFlags. The output must pay to the
+// private key's public key tweaked without a script tree, as BIP86 wallets do. SigHash default signs with
+// SIGHASH_DEFAULT. Balance left over from input is used as transaction fee.
+func generateFundP2TR(flags *FundP2TRFlags) (string, error) {
+	input, lockTime, err := parseInput(flags.InputTx, flags.InputIndex, flags.LockTime, flags.Sequence, flags.RBF, flags.RelativeLockTime, flags.TxVersion)
+	if err != nil {
+		return "", err
+	}
+	prevTx, err := decodePrevTx(flags.PrevTx, input)
+	if err != nil {
+		return "", err
+	}
+	//The amount of the output spent is taken from the previous transaction if given
+	flags.InputAmount, err = prevTxInputAmount(prevTx, input, flags.InputAmount)
+	if err != nil {
+		return "", err
+	}
+	version, err := parseTxVersion(flags.TxVersion)
+	if err != nil {
+		return "", err
+	}
+	hashType, err := btcutils.ParseSigHashType(flags.SigHash)
+	if err != nil {
+		return "", err
+	}
+	err = checkOutputFormat(flags.OutputFormat)
+	if err != nil {
+		return "", err
+	}
+	if flags.InputAmount <= 0 {
+		return "", errors.New("--input-amount, or --prev-tx to take it from, is required to sign a P2TR input.")
+	}
+	if flags.Amount > flags.InputAmount {
+		return "", fmt.Errorf("--amount (%d) exceeds --input-amount (%d).", flags.Amount, flags.InputAmount)
+	}
+	//Get private key as decoded raw bytes. Taproot keys are x-only, so WIF compression doesn't matter
+	params, err := btcutils.NetworkParamsByName(flags.Network)
+	if err != nil {
+		return "", err
+	}
+	privateKey, _, err := btcutils.ParseWIF(flags.PrivateKey, params)
+	if err != nil {
+		return "", fmt.Errorf("Invalid --private-key. %s", err)
+	}
+	//The internal key is the x coordinate of the public key, tweaked to give the output key the input pays to
+	publicKey, err := btcutils.NewCompressedPublicKey(privateKey)
+	if err != nil {
+		return "", err
+	}
+	//Refuse a key the input doesn't pay to before signing, if the previous transaction is known
+	if prevTx != nil {
+		err = checkPrevTxKey(prevTx, input, publicKey, nil, params)
+		if err != nil {
+			return "", err
+		}
+	}
+	outputKey, err := btcutils.TweakPublicKey(publicKey[1:], nil)
+	if err != nil {
+		return "", err
+	}
+	inputScriptPubKey, err := btcutils.CreateP2TRScriptPubKey(outputKey)
+	if err != nil {
+		return "", err
+	}
+	scriptPubKey, err := btcutils.NewScriptPubKeyFromAddress(flags.Destination, params)
+	if err != nil {
+		return "", fmt.Errorf("Invalid --destination. %s", err)
+	}
+	//Native SegWit inputs have an empty scriptSig, the signature goes in the witness instead
+	tx := &btcutils.Transaction{
+		Version:  version,
+		Inputs:   []btcutils.Input{input},
+		Outputs:  []btcutils.Output{{Satoshis: uint64(flags.Amount), ScriptPubKey: scriptPubKey}},
+		LockTime: lockTime,
+	}
+	_, err = btcutils.CheckOutputs(tx.Outputs)
+	if err != nil {
+		return "", err
+	}
+	err = checkDust(tx.Outputs, flags.DustRelayFee, flags.AllowDust)
+	if err != nil {
+		return "", err
+	}
+	err = checkHighFee(flags.InputAmount, tx.Outputs, 0, flags.MaxFee, flags.ForceHighFee)
+	if err != nil {
+		return "", err
+	}
+	if flags.OutputFormat == "psbt" {
+		utxo := btcutils.Output{Satoshis: uint64(flags.InputAmount), ScriptPubKey: inputScriptPubKey}
+		return generatePSBT(tx, utxo, prevTx, nil, nil, hashType, [][]byte{privateKey})
+	}
+	//Sign the transaction with the --sighash hash type
+	maxFee := int64(flags.MaxFee)
+	finalTransaction, err := FundP2TR(FundP2TROptions{PrivateKey: flags.PrivateKey, Input: input, InputAmount: int64(flags.InputAmount), Outputs: tx.Outputs, Version: version, LockTime: lockTime, SigHashType: hashType, Network: flags.Network, MaxFee: &maxFee, ForceHighFee: flags.ForceHighFee})
+	if err != nil {
+		return "", err
+	}
+	finalTransactionHex := hex.EncodeToString(finalTransaction)
+
+	return finalTransactionHex, nil
+}
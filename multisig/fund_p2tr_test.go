@@ -0,0 +1,65 @@
+package multisig
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/btcutils"
+	"github.com/CryptoProcessing/go-bitcoin-multisig/testutils"
+
+	"encoding/hex"
+	"testing"
+)
+
+func TestGenerateFundP2TR(t *testing.T) {
+	btcutils.SetFixedNonce = true //SetFixedNonce set to true to get repeatable signatures with zero auxiliary randomness for testing.
+	testPrivateKeyWIF := "KyQMr1Usf5P4HzUQiSzq9Vv2nPZaNe58NCm9ojndzdoLbfKEvPms"
+	testInputTx := "3ad337270ac0ba14fbce812291b7d95338c878709ea8123a4d88c3c29efbc6ac"
+	testInputIndex := 1
+	testInputAmount := 100000
+	testAmount := 90000
+	testDestination := "bc1p2wsldez5mud2yam29q22wgfh9439spgduvct83k3pm50fcxa5dps59h4z5"
+	testFinalTransanctionHex := "01000000000101acc6fb9ec2c3884d3a12a89e7078c83853d9b7912281cefb14bac00a2737d33a0100000000ffffffff01905f01000000000022512053a1f6e454df1aa2776a2814a721372d6258050de330b3c6d10ee8f4e0dda3430140f713faa59c59561c72fab211c442c9966827edb7690375645e0fb6c4a6b60da607aca3a7d480223a9fc92bc9b0d7e91231f97b24589100eca7b04d88926fe3e200000000"
+
+	finalTransactionHex, err := generateFundP2TR(&FundP2TRFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, InputIndex: testInputIndex, InputAmount: testInputAmount, Amount: testAmount, Destination: testDestination, TxFlags: TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: 3000, MaxFee: 1000000, ForceHighFee: true, SigHash: "default", OutputFormat: "hex", Network: "mainnet"}})
+	if err != nil {
+		t.Error(err)
+	}
+	if finalTransactionHex != testFinalTransanctionHex {
+		testutils.CompareError(t, "Generated P2TR funding transaction different from expected transaction.", testFinalTransanctionHex, finalTransactionHex)
+	}
+}
+
+func TestGenerateFundP2TRErrors(t *testing.T) {
+	testPrivateKeyWIF := "KyQMr1Usf5P4HzUQiSzq9Vv2nPZaNe58NCm9ojndzdoLbfKEvPms"
+	testInputTx := "3ad337270ac0ba14fbce812291b7d95338c878709ea8123a4d88c3c29efbc6ac"
+	testDestination := "347N1Thc213QqfYCz3PZkjoJpNv5b14kBd"
+
+	if _, err := generateFundP2TR(&FundP2TRFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, Amount: 1000, Destination: testDestination, TxFlags: testTxFlags}); err == nil {
+		t.Error("generateFundP2TR accepting missing input amount.")
+	}
+	if _, err := generateFundP2TR(&FundP2TRFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, InputAmount: 1000, Amount: 2000, Destination: testDestination, TxFlags: testTxFlags}); err == nil {
+		t.Error("generateFundP2TR accepting amount larger than input amount.")
+	}
+	if _, err := generateFundP2TR(&FundP2TRFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, InputIndex: -1, InputAmount: 2000, Amount: 1000, Destination: testDestination, TxFlags: testTxFlags}); err == nil {
+		t.Error("generateFundP2TR accepting negative input index.")
+	}
+	if _, err := generateFundP2TR(&FundP2TRFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, InputAmount: 2000, Amount: 1000, Destination: "tb1p2wsldez5mud2yam29q22wgfh9439spgduvct83k3pm50fcxa5dpsrdp6cm", TxFlags: testTxFlags}); err == nil {
+		t.Error("generateFundP2TR accepting testnet destination on mainnet.")
+	}
+}
+
+func TestGenerateFundP2TRSigHash(t *testing.T) {
+	btcutils.SetFixedNonce = true //SetFixedNonce set to true to get repeatable signatures with zero auxiliary randomness for testing.
+	finalTransactionHex, err := generateFundP2TR(&FundP2TRFlags{PrivateKey: "KyQMr1Usf5P4HzUQiSzq9Vv2nPZaNe58NCm9ojndzdoLbfKEvPms", InputTx: "3ad337270ac0ba14fbce812291b7d95338c878709ea8123a4d88c3c29efbc6ac", InputIndex: 1, InputAmount: 100000, Amount: 90000, Destination: "bc1p2wsldez5mud2yam29q22wgfh9439spgduvct83k3pm50fcxa5dps59h4z5", TxFlags: TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: 3000, MaxFee: 1000000, ForceHighFee: true, SigHash: "single|anyonecanpay", OutputFormat: "hex", Network: "mainnet"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	finalTransaction, _ := hex.DecodeString(finalTransactionHex)
+	tx, err := btcutils.DeserializeTransaction(finalTransaction)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//Any hash type other than SIGHASH_DEFAULT is appended to the 64 byte Schnorr signature
+	signature := tx.Inputs[0].Witness[0]
+	if len(signature) != 65 || signature[64] != 0x83 {
+		testutils.CompareError(t, "Signature hash type different from expected hash type.", "65 byte signature ending in 0x83", hex.EncodeToString(signature))
+	}
+}
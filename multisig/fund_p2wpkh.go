@@ -0,0 +1,182 @@
+// fund_p2wpkh.go - Funding an address from a native SegWit P2WPKH output.
+package multisig
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/btcutils"
+
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// FundP2WPKHFlags holds the flags of the fund-p2wpkh and fund-p2sh-p2wpkh subcommands.
+type FundP2WPKHFlags struct {
+	PrivateKey  string //Private key of the P2WPKH or P2SH-P2WPKH output
+	InputTx     string //Input transaction hash
+	InputIndex  int    //Output index of the input transaction to spend
+	InputAmount int    //Amount in Satoshis held by the output spent, which is committed to by the BIP143 signature
+	Amount      int    //Amount in Satoshis to send
+	FeeRate     int    //Alternatively, fee rate in Satoshis per virtual byte, sending the input amount less the estimated fee
+	Force       bool   //Allow fee rates above btcutils.MaxFeeRate
+	Destination string //Destination address
+	TxFlags
+}
+
+// OutputFundP2WPKH formats and prints relevant outputs to the user.
+func OutputFundP2WPKH(flags FundP2WPKHFlags) error {
+	finalTransactionHex, err := generateFundP2WPKH(&flags)
+	if err != nil {
+		return err
+	}
+	printInputNote("P2WPKH input transaction", flags.InputTx, flags.InputIndex, flags.LockTime, flags.Sequence, flags.RBF, flags.RelativeLockTime, flags.TxVersion)
+	if flags.OutputFormat == "psbt" {
+		printPSBT(finalTransactionHex)
+		return nil
+	}
+
+	//Output our final transaction
+	fmt.Printf(`
+-----------------------------------------------------------------------------------------------------------------------------------
+Your raw funding transaction is:
+%v
+Broadcast this transaction to fund your address.
+-----------------------------------------------------------------------------------------------------------------------------------
+`,
+		finalTransactionHex,
+	)
+	printFeeNote(finalTransactionHex, flags.InputAmount)
+	printTransactionIDs(finalTransactionHex)
+	printScripts(finalTransactionHex)
+	return nil
+}
+
+// generateFundP2WPKH is the high-level logic for spending a native SegWit P2WPKH output with the
+// 'go-bitcoin-multisig fund-p2wpkh' subcommand, from the flags described by FundP2WPKHFlags.
+// Balance left over from input is used as transaction fee, so with FeeRate the whole input less the estimated
+// fee is sent.
+func generateFundP2WPKH(flags *FundP2WPKHFlags) (string, error) {
+	input, lockTime, err := parseInput(flags.InputTx, flags.InputIndex, flags.LockTime, flags.Sequence, flags.RBF, flags.RelativeLockTime, flags.TxVersion)
+	if err != nil {
+		return "", err
+	}
+	prevTx, err := decodePrevTx(flags.PrevTx, input)
+	if err != nil {
+		return "", err
+	}
+	//The amount of the output spent is taken from the previous transaction if given
+	flags.InputAmount, err = prevTxInputAmount(prevTx, input, flags.InputAmount)
+	if err != nil {
+		return "", err
+	}
+	version, err := parseTxVersion(flags.TxVersion)
+	if err != nil {
+		return "", err
+	}
+	hashType, err := parseSigHashType(flags.SigHash)
+	if err != nil {
+		return "", err
+	}
+	err = checkOutputFormat(flags.OutputFormat)
+	if err != nil {
+		return "", err
+	}
+	if flags.InputAmount <= 0 {
+		return "", errors.New("--input-amount, or --prev-tx to take it from, is required to sign a P2WPKH input.")
+	}
+	if flags.Amount > flags.InputAmount {
+		return "", fmt.Errorf("--amount (%d) exceeds --input-amount (%d).", flags.Amount, flags.InputAmount)
+	}
+	err = btcutils.CheckFeeRate(flags.FeeRate, flags.Force)
+	if err != nil {
+		return "", err
+	}
+	if flags.FeeRate > 0 && flags.Amount != 0 {
+		return "", errors.New("--amount and --fee-rate cannot be used together.")
+	}
+	//Get private key as decoded raw bytes
+	params, err := btcutils.NetworkParamsByName(flags.Network)
+	if err != nil {
+		return "", err
+	}
+	privateKey, compressed, err := btcutils.ParseWIF(flags.PrivateKey, params)
+	if err != nil {
+		return "", fmt.Errorf("Invalid --private-key. %s", err)
+	}
+	if !compressed {
+		return "", errors.New("P2WPKH outputs can only be spent with a compressed WIF private key.")
+	}
+	//P2WPKH outputs are always locked to the hash of a compressed public key
+	publicKey, err := btcutils.NewCompressedPublicKey(privateKey)
+	if err != nil {
+		return "", err
+	}
+	//Refuse a key the input doesn't pay to before signing, if the previous transaction is known
+	if prevTx != nil {
+		err = checkPrevTxKey(prevTx, input, publicKey, nil, params)
+		if err != nil {
+			return "", err
+		}
+	}
+	publicKeyHash, err := btcutils.Hash160(publicKey)
+	if err != nil {
+		return "", err
+	}
+	scriptPubKey, err := btcutils.NewScriptPubKeyFromAddress(flags.Destination, params)
+	if err != nil {
+		return "", fmt.Errorf("Invalid --destination. %s", err)
+	}
+	//Native SegWit inputs have an empty scriptSig, the signature goes in the witness instead
+	tx := &btcutils.Transaction{
+		Version:  version,
+		Inputs:   []btcutils.Input{input},
+		Outputs:  []btcutils.Output{{Satoshis: uint64(flags.Amount), ScriptPubKey: scriptPubKey}},
+		LockTime: lockTime,
+	}
+	if flags.FeeRate > 0 {
+		//Estimate the signed size with the P2WPKH scriptPubKey being spent in place of the scriptSig
+		inputScriptPubKey, err := btcutils.NewP2WPKHScriptPubKey(publicKeyHash)
+		if err != nil {
+			return "", err
+		}
+		unsigned := *tx
+		unsigned.Inputs = []btcutils.Input{input}
+		unsigned.Inputs[0].ScriptSig = inputScriptPubKey
+		fee, err := btcutils.EstimateFee(&unsigned, int64(flags.FeeRate))
+		if err != nil {
+			return "", err
+		}
+		if fee >= int64(flags.InputAmount) {
+			return "", fmt.Errorf("Sending %d satoshis with a fee of %d satoshis leaves nothing to send.", flags.InputAmount, fee)
+		}
+		tx.Outputs[0].Satoshis = uint64(int64(flags.InputAmount) - fee)
+	}
+	_, err = btcutils.CheckOutputs(tx.Outputs)
+	if err != nil {
+		return "", err
+	}
+	err = checkDust(tx.Outputs, flags.DustRelayFee, flags.AllowDust)
+	if err != nil {
+		return "", err
+	}
+	err = checkHighFee(flags.InputAmount, tx.Outputs, 0, flags.MaxFee, flags.ForceHighFee)
+	if err != nil {
+		return "", err
+	}
+	if flags.OutputFormat == "psbt" {
+		inputScriptPubKey, err := btcutils.NewP2WPKHScriptPubKey(publicKeyHash)
+		if err != nil {
+			return "", err
+		}
+		utxo := btcutils.Output{Satoshis: uint64(flags.InputAmount), ScriptPubKey: inputScriptPubKey}
+		return generatePSBT(tx, utxo, prevTx, nil, nil, hashType, [][]byte{privateKey})
+	}
+	//Sign the transaction with the --sighash hash type
+	maxFee := int64(flags.MaxFee)
+	finalTransaction, err := FundP2WPKH(FundP2WPKHOptions{PrivateKey: flags.PrivateKey, Input: input, InputAmount: int64(flags.InputAmount), Outputs: tx.Outputs, Version: version, LockTime: lockTime, SigHashType: hashType, Network: flags.Network, MaxFee: &maxFee, ForceHighFee: flags.ForceHighFee})
+	if err != nil {
+		return "", err
+	}
+	finalTransactionHex := hex.EncodeToString(finalTransaction)
+
+	return finalTransactionHex, nil
+}
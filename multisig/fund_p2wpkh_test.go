@@ -0,0 +1,150 @@
+package multisig
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/btcutils"
+	"github.com/CryptoProcessing/go-bitcoin-multisig/testutils"
+
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestGenerateFundP2WPKH(t *testing.T) {
+	btcutils.SetFixedNonce = true //SetFixedNonce set to true to get repeatable signatures with a fixed nonce for testing.
+	//Spends the P2WPKH output of the BIP143 native P2WPKH example
+	testPrivateKeyWIF := "KzVTBhbMaKrAYagJ11VdTaBrb6yzLykLGyuMBkf9sCFPDxdT8shL"
+	testInputTx := "8ac60eb9575db5b2d987e29f301b5b819ea83a5c6579d282d189cc04b8e151ef"
+	testInputIndex := 1
+	testInputAmount := 600000000
+	testAmount := 599990000
+	testDestination := "347N1Thc213QqfYCz3PZkjoJpNv5b14kBd"
+	testFinalTransanctionHex := "01000000000101ef51e1b804cc89d182d279655c3aa89e815b1b309fe287d9b2b55d57b90ec68a0100000000ffffffff01f01ec3230000000017a9141a8b0026343166625c7475f01e48b5ede8c0252e870247304402206d6caac248af96f6afa7f904f550253a0f3ef3f5aa2fe6838a95b216691468e202203c8ad85f3239a1cd07740523f3b16456f53a0572f7d72ab907a597a9179e39b30121025476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee635700000000"
+
+	finalTransactionHex, err := generateFundP2WPKH(&FundP2WPKHFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, InputIndex: testInputIndex, InputAmount: testInputAmount, Amount: testAmount, Destination: testDestination, TxFlags: testTxFlags})
+	if err != nil {
+		t.Error(err)
+	}
+	if finalTransactionHex != testFinalTransanctionHex {
+		testutils.CompareError(t, "Generated P2WPKH funding transaction different from expected transaction.", testFinalTransanctionHex, finalTransactionHex)
+	}
+}
+
+func TestGenerateFundP2WPKHFeeRate(t *testing.T) {
+	testPrivateKeyWIF := "KzVTBhbMaKrAYagJ11VdTaBrb6yzLykLGyuMBkf9sCFPDxdT8shL"
+	testInputTx := "8ac60eb9575db5b2d987e29f301b5b819ea83a5c6579d282d189cc04b8e151ef"
+	testInputAmount := 600000000
+	testFeeRate := 10
+	testDestination := "347N1Thc213QqfYCz3PZkjoJpNv5b14kBd"
+	//One P2WPKH input and one P2SH output weigh 442, estimated at 111 virtual bytes
+	testAmount := uint64(testInputAmount - 111*testFeeRate)
+
+	finalTransactionHex, err := generateFundP2WPKH(&FundP2WPKHFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, InputIndex: 1, InputAmount: testInputAmount, FeeRate: testFeeRate, Destination: testDestination, TxFlags: testTxFlags})
+	if err != nil {
+		t.Fatal(err)
+	}
+	finalTransaction, _ := hex.DecodeString(finalTransactionHex)
+	tx, err := btcutils.DeserializeTransaction(finalTransaction)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.Outputs[0].Satoshis != testAmount {
+		testutils.CompareError(t, "Amount sent with fee rate different from expected amount.", testAmount, tx.Outputs[0].Satoshis)
+	}
+	//The estimate is within 2 virtual bytes of the signed transaction
+	virtualSize, err := btcutils.VirtualSize(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if virtualSize > 111 || virtualSize < 109 {
+		testutils.CompareError(t, "Signed transaction virtual size not within 2 virtual bytes of the estimate.", 111, virtualSize)
+	}
+
+	if _, err := generateFundP2WPKH(&FundP2WPKHFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, InputIndex: 1, InputAmount: testInputAmount, Amount: 1000, FeeRate: testFeeRate, Destination: testDestination, TxFlags: testTxFlags}); err == nil {
+		t.Error("generateFundP2WPKH accepting both amount and fee rate.")
+	}
+	if _, err := generateFundP2WPKH(&FundP2WPKHFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, InputIndex: 1, InputAmount: 1000, FeeRate: testFeeRate, Destination: testDestination, TxFlags: testTxFlags}); err == nil {
+		t.Error("generateFundP2WPKH accepting fee larger than input amount.")
+	}
+	if _, err := generateFundP2WPKH(&FundP2WPKHFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, InputIndex: 1, InputAmount: testInputAmount, FeeRate: btcutils.MaxFeeRate + 1, Destination: testDestination, TxFlags: testTxFlags}); err == nil {
+		t.Error("generateFundP2WPKH accepting fee rate above maximum without force.")
+	}
+	if _, err := generateFundP2WPKH(&FundP2WPKHFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, InputIndex: 1, InputAmount: testInputAmount, Destination: testDestination, TxFlags: testTxFlags}); err == nil {
+		t.Error("generateFundP2WPKH accepting neither amount nor fee rate.")
+	}
+}
+
+func TestGenerateFundP2WPKHErrors(t *testing.T) {
+	testPrivateKeyWIF := "KzVTBhbMaKrAYagJ11VdTaBrb6yzLykLGyuMBkf9sCFPDxdT8shL"
+	testInputTx := "8ac60eb9575db5b2d987e29f301b5b819ea83a5c6579d282d189cc04b8e151ef"
+	testDestination := "347N1Thc213QqfYCz3PZkjoJpNv5b14kBd"
+
+	if _, err := generateFundP2WPKH(&FundP2WPKHFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, Amount: 1000, Destination: testDestination, TxFlags: testTxFlags}); err == nil {
+		t.Error("generateFundP2WPKH accepting missing input amount.")
+	}
+	if _, err := generateFundP2WPKH(&FundP2WPKHFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, InputAmount: 1000, Amount: 2000, Destination: testDestination, TxFlags: testTxFlags}); err == nil {
+		t.Error("generateFundP2WPKH accepting amount larger than input amount.")
+	}
+	if _, err := generateFundP2WPKH(&FundP2WPKHFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, InputAmount: 2000, Amount: -1, Destination: testDestination, TxFlags: testTxFlags}); err == nil {
+		t.Error("generateFundP2WPKH accepting negative amount.")
+	}
+	if _, err := generateFundP2WPKH(&FundP2WPKHFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, InputIndex: -1, InputAmount: 2000, Amount: 1000, Destination: testDestination, TxFlags: testTxFlags}); err == nil {
+		t.Error("generateFundP2WPKH accepting negative input index.")
+	}
+	if _, err := generateFundP2WPKH(&FundP2WPKHFlags{PrivateKey: "5JJyqG4bb15zqi7fTA4b227aUxQhBo1Ux6qX69ngeXYLr7fk2hs", InputTx: testInputTx, InputAmount: 2000, Amount: 1000, Destination: testDestination, TxFlags: testTxFlags}); err == nil {
+		t.Error("generateFundP2WPKH accepting uncompressed WIF private key.")
+	}
+}
+
+func TestGenerateFundP2WPKHPrevTx(t *testing.T) {
+	btcutils.SetFixedNonce = true
+	testPrivateKeyWIF := "KzVTBhbMaKrAYagJ11VdTaBrb6yzLykLGyuMBkf9sCFPDxdT8shL"
+	testPublicKey, _ := hex.DecodeString("025476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee6357")
+	testScriptPubKey, _ := btcutils.NewP2WPKHScriptPubKey(btcutils.PublicKeyToHash160(testPublicKey))
+	testDestination := "347N1Thc213QqfYCz3PZkjoJpNv5b14kBd"
+	testPrevTxHex, testInputTx := testPrevTx(t, testScriptPubKey, 100000)
+
+	//The input amount, committed to by the signature, is taken from the previous transaction
+	expectedTransactionHex, err := generateFundP2WPKH(&FundP2WPKHFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, InputIndex: 1, InputAmount: 100000, Amount: 95000, Destination: testDestination, TxFlags: testTxFlags})
+	if err != nil {
+		t.Fatal(err)
+	}
+	prevTxFile, err := ioutil.TempFile("", "prev-tx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(prevTxFile.Name())
+	prevTxFile.WriteString(testPrevTxHex + "\n")
+	prevTxFile.Close()
+	for _, flagPrevTx := range []string{testPrevTxHex, "@" + prevTxFile.Name()} {
+		flags := FundP2WPKHFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, InputIndex: 1, Amount: 95000, Destination: testDestination, TxFlags: TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: 3000, MaxFee: 1000000, SigHash: "default", OutputFormat: "hex", PrevTx: flagPrevTx, Network: "mainnet"}}
+		finalTransactionHex, err := generateFundP2WPKH(&flags)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if finalTransactionHex != expectedTransactionHex {
+			testutils.CompareError(t, "P2WPKH funding transaction with the input amount of --prev-tx different from expected transaction.", expectedTransactionHex, finalTransactionHex)
+		}
+		//OutputFundP2WPKH prints the fee from the input amount filled in, without decoding --prev-tx again
+		if flags.InputAmount != 100000 {
+			t.Errorf("generateFundP2WPKH filled in an input amount of %d satoshis, expected 100000.", flags.InputAmount)
+		}
+	}
+
+	if _, err := generateFundP2WPKH(&FundP2WPKHFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, InputIndex: 1, InputAmount: 100001, Amount: 95000, Destination: testDestination, TxFlags: TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: 3000, MaxFee: 1000000, SigHash: "default", OutputFormat: "hex", PrevTx: testPrevTxHex, Network: "mainnet"}}); err == nil {
+		t.Error("generateFundP2WPKH accepting --input-amount different from the output of --prev-tx.")
+	}
+	if _, err := generateFundP2WPKH(&FundP2WPKHFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, InputIndex: 2, Amount: 95000, Destination: testDestination, TxFlags: TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: 3000, MaxFee: 1000000, SigHash: "default", OutputFormat: "hex", PrevTx: testPrevTxHex, Network: "mainnet"}}); err == nil {
+		t.Error("generateFundP2WPKH accepting --input-index out of range of --prev-tx.")
+	}
+	if _, err := generateFundP2WPKH(&FundP2WPKHFlags{PrivateKey: testPrivateKeyWIF, InputTx: strings.Repeat("22", 32), InputIndex: 1, Amount: 95000, Destination: testDestination, TxFlags: TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: 3000, MaxFee: 1000000, SigHash: "default", OutputFormat: "hex", PrevTx: testPrevTxHex, Network: "mainnet"}}); err == nil {
+		t.Error("generateFundP2WPKH accepting --prev-tx that is not --input-tx.")
+	}
+	if _, err := generateFundP2WPKH(&FundP2WPKHFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, InputIndex: 1, Amount: 95000, Destination: testDestination, TxFlags: TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: 3000, MaxFee: 1000000, SigHash: "default", OutputFormat: "hex", PrevTx: "@" + prevTxFile.Name() + ".missing", Network: "mainnet"}}); err == nil {
+		t.Error("generateFundP2WPKH accepting --prev-tx file that does not exist.")
+	}
+	if _, err := generateFundP2WPKH(&FundP2WPKHFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, InputIndex: 1, Amount: 95000, Destination: testDestination, TxFlags: testTxFlags}); err == nil {
+		t.Error("generateFundP2WPKH accepting neither --input-amount nor --prev-tx.")
+	}
+}
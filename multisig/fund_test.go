@@ -4,10 +4,18 @@ import (
 	"github.com/CryptoProcessing/go-bitcoin-multisig/btcutils"
 	"github.com/CryptoProcessing/go-bitcoin-multisig/testutils"
 
+	"encoding/hex"
+	"fmt"
+	"math"
 	"reflect"
+	"strings"
 	"testing"
 )
 
+// testTxFlags holds the default global flags of main.go, with SIGHASH_ALL and version 1 transactions the expected
+// transactions were signed with
+var testTxFlags = TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: 3000, MaxFee: 1000000, SigHash: "default", OutputFormat: "hex", Network: "mainnet"}
+
 func TestGenerateFund(t *testing.T) {
 	btcutils.SetFixedNonce = true //SetFixedNonce set to true to get repeatable signatures with a fixed nonce for testing.
 	{
@@ -17,7 +25,10 @@ func TestGenerateFund(t *testing.T) {
 		testP2SHDestination := "347N1Thc213QqfYCz3PZkjoJpNv5b14kBd"
 		testFinalTransanctionHex := "0100000001acc6fb9ec2c3884d3a12a89e7078c83853d9b7912281cefb14bac00a2737d33a000000008a47304402206d6caac248af96f6afa7f904f550253a0f3ef3f5aa2fe6838a95b216691468e202207d1c7fb129adec15700c378e142c506b5bbadafdedbb62f614dd0bb128faeecd01410431393af9984375830971ab5d3094c6a7d02db3568b2b06212a7090094549701bbb9e84d9477451acc42638963635899ce91bacb451a1bb6da73ddfbcf596bddfffffffff01400001000000000017a9141a8b0026343166625c7475f01e48b5ede8c0252e8700000000"
 
-		finalTransactionHex := generateFund(testPrivateKeyWIF, testInputTx, testAmount, testP2SHDestination)
+		finalTransactionHex, _, _, err := generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, Amount: testAmount, Destinations: []string{testP2SHDestination}, TxFlags: testTxFlags})
+		if err != nil {
+			t.Error(err)
+		}
 		if finalTransactionHex != testFinalTransanctionHex {
 			testutils.CompareError(t, "Generated funding transaction different from expected transaction.", testFinalTransanctionHex, finalTransactionHex)
 		}
@@ -29,7 +40,10 @@ func TestGenerateFund(t *testing.T) {
 		testP2SHDestination := "3ErDPiDD7AsJDqKkayMA39iLJevTjDCjUa"
 		testFinalTransanctionHex := "01000000019f47d9bab82f8e92a61d74908456e2507257105cd7f0813c6fa68f647c864826000000008a47304402206d6caac248af96f6afa7f904f550253a0f3ef3f5aa2fe6838a95b216691468e2022004ad7b55e6c3a595bb770f2a172c99c2b03c903401c4fd05718b2e470ac70a4b014104ff4c2ce7513a6c896ebfaaa4ae52cea35374e0eac90ccb8f4e5fa14b8322e2bae4c65116c7af2ba6a82831e48c451fc29a66d49c24757130ebf07c142bbcbe75ffffffff01b01102000000000017a9149056f3c2a8cbd11340fa2ee4736dea1d298c9d118700000000"
 
-		finalTransactionHex := generateFund(testPrivateKeyWIF, testInputTx, testAmount, testP2SHDestination)
+		finalTransactionHex, _, _, err := generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, Amount: testAmount, Destinations: []string{testP2SHDestination}, TxFlags: testTxFlags})
+		if err != nil {
+			t.Error(err)
+		}
 		if finalTransactionHex != testFinalTransanctionHex {
 			testutils.CompareError(t, "Generated funding transaction different from expected transaction.", testFinalTransanctionHex, finalTransactionHex)
 		}
@@ -41,7 +55,10 @@ func TestGenerateFund(t *testing.T) {
 		testP2SHDestination := "34wgSuG9qtaNEV4MGye9UJcffcFTxnmXSC"
 		testFinalTransanctionHex := "0100000001507b8cda2448a92b51333b5d7e4a5cc9c45c8b85a58f7c91d4403e66d3ce73d0000000008a47304402206d6caac248af96f6afa7f904f550253a0f3ef3f5aa2fe6838a95b216691468e2022017a181a29869fb641bab86b1fe60fefdf918ed44ec0ab32409effff94af606dc014104d95cf578183f346117b9743722bb6df93e1c62990824a1fc6645fd3dee45fa7ea5f164da7b518c3fd08a623664410df5a3b5f6ef1c5a285e834fd57c5a24a41effffffff0110fc02000000000017a91423ae5bc99220a608aefb8455cdf7f43bfdbae67d8700000000"
 
-		finalTransactionHex := generateFund(testPrivateKeyWIF, testInputTx, testAmount, testP2SHDestination)
+		finalTransactionHex, _, _, err := generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, Amount: testAmount, Destinations: []string{testP2SHDestination}, TxFlags: testTxFlags})
+		if err != nil {
+			t.Error(err)
+		}
 		if finalTransactionHex != testFinalTransanctionHex {
 			testutils.CompareError(t, "Generated funding transaction different from expected transaction.", testFinalTransanctionHex, finalTransactionHex)
 		}
@@ -54,11 +71,17 @@ func TestSignP2PKHTransaction(t *testing.T) {
 		testInputTx := "3ad337270ac0ba14fbce812291b7d95338c878709ea8123a4d88c3c29efbc6ac"
 		testAmount := 65600
 		testScriptPubKey := []byte{169, 20, 26, 139, 0, 38, 52, 49, 102, 98, 92, 116, 117, 240, 30, 72, 181, 237, 232, 192, 37, 46, 135}
-		testRawTx := []byte{1, 0, 0, 0, 1, 172, 198, 251, 158, 194, 195, 136, 77, 58, 18, 168, 158, 112, 120, 200, 56, 83, 217, 183, 145, 34, 129, 206, 251, 20, 186, 192, 10, 39, 55, 211, 58, 0, 0, 0, 0, 25, 118, 169, 20, 146, 3, 228, 122, 22, 247, 153, 222, 208, 53, 50, 227, 228, 82, 96, 111, 220, 82, 0, 126, 136, 172, 255, 255, 255, 255, 1, 64, 0, 1, 0, 0, 0, 0, 0, 23, 169, 20, 26, 139, 0, 38, 52, 49, 102, 98, 92, 116, 117, 240, 30, 72, 181, 237, 232, 192, 37, 46, 135, 0, 0, 0, 0}
-		testSignedTx := []byte{1, 0, 0, 0, 1, 172, 198, 251, 158, 194, 195, 136, 77, 58, 18, 168, 158, 112, 120, 200, 56, 83, 217, 183, 145, 34, 129, 206, 251, 20, 186, 192, 10, 39, 55, 211, 58, 0, 0, 0, 0, 138, 71, 48, 68, 2, 32, 109, 108, 170, 194, 72, 175, 150, 246, 175, 167, 249, 4, 245, 80, 37, 58, 15, 62, 243, 245, 170, 47, 230, 131, 138, 149, 178, 22, 105, 20, 104, 226, 2, 32, 121, 239, 192, 104, 145, 56, 231, 141, 41, 172, 104, 123, 214, 135, 215, 255, 145, 125, 106, 219, 104, 4, 242, 63, 219, 107, 193, 152, 184, 110, 20, 41, 1, 65, 4, 31, 94, 124, 86, 83, 22, 214, 220, 255, 68, 144, 37, 212, 245, 109, 15, 125, 62, 188, 143, 134, 225, 79, 52, 23, 48, 146, 180, 180, 96, 82, 136, 25, 21, 66, 0, 130, 244, 216, 175, 215, 116, 19, 108, 62, 70, 207, 235, 149, 85, 153, 140, 40, 104, 214, 135, 189, 203, 127, 61, 30, 232, 22, 147, 255, 255, 255, 255, 1, 64, 0, 1, 0, 0, 0, 0, 0, 23, 169, 20, 26, 139, 0, 38, 52, 49, 102, 98, 92, 116, 117, 240, 30, 72, 181, 237, 232, 192, 37, 46, 135, 0, 0, 0, 0}
+		testInputScriptPubKey := []byte{118, 169, 20, 146, 3, 228, 122, 22, 247, 153, 222, 208, 53, 50, 227, 228, 82, 96, 111, 220, 82, 0, 126, 136, 172}
+		testSignedTx := []byte{1, 0, 0, 0, 1, 172, 198, 251, 158, 194, 195, 136, 77, 58, 18, 168, 158, 112, 120, 200, 56, 83, 217, 183, 145, 34, 129, 206, 251, 20, 186, 192, 10, 39, 55, 211, 58, 0, 0, 0, 0, 138, 71, 48, 68, 2, 32, 109, 108, 170, 194, 72, 175, 150, 246, 175, 167, 249, 4, 245, 80, 37, 58, 15, 62, 243, 245, 170, 47, 230, 131, 138, 149, 178, 22, 105, 20, 104, 226, 2, 32, 71, 152, 127, 173, 87, 119, 50, 80, 161, 179, 43, 125, 235, 63, 215, 41, 123, 99, 51, 196, 9, 172, 131, 122, 77, 184, 222, 92, 90, 228, 51, 205, 1, 65, 4, 31, 94, 124, 86, 83, 22, 214, 220, 255, 68, 144, 37, 212, 245, 109, 15, 125, 62, 188, 143, 134, 225, 79, 52, 23, 48, 146, 180, 180, 96, 82, 136, 25, 21, 66, 0, 130, 244, 216, 175, 215, 116, 19, 108, 62, 70, 207, 235, 149, 85, 153, 140, 40, 104, 214, 135, 189, 203, 127, 61, 30, 232, 22, 147, 255, 255, 255, 255, 1, 64, 0, 1, 0, 0, 0, 0, 0, 23, 169, 20, 26, 139, 0, 38, 52, 49, 102, 98, 92, 116, 117, 240, 30, 72, 181, 237, 232, 192, 37, 46, 135, 0, 0, 0, 0}
 
 		btcutils.SetFixedNonce = true
-		signedTx, err := signP2PKHTransaction(testRawTx, testPrivateKey, testScriptPubKey, testInputTx, testAmount)
+		testOutputs := []btcutils.Output{{Satoshis: uint64(testAmount), ScriptPubKey: testScriptPubKey}}
+		testPublicKey, err := btcutils.NewPublicKey(testPrivateKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		testTx := &btcutils.Transaction{Version: 1, Inputs: []btcutils.Input{{TxHash: testInputTx, OutputIndex: 0, Sequence: btcutils.SequenceFinal}}, Outputs: testOutputs}
+		signedTx, err := signP2PKHTransaction(testTx, testPrivateKey, testPublicKey, testInputScriptPubKey, btcutils.SigHashAll)
 		if err != nil {
 			t.Error(err)
 		}
@@ -66,4 +89,752 @@ func TestSignP2PKHTransaction(t *testing.T) {
 			testutils.CompareError(t, "Generated signature different from expected signature.", testSignedTx, signedTx)
 		}
 	}
+	{
+		//SIGHASH_SINGLE|ANYONECANPAY commits to only this input and its matching output, appending 0x83 to the signature
+		testPrivateKey, _ := hex.DecodeString("412738f0e9f2b776748136ae6a297f7eda80112a47d865c5df7612174a5bac45")
+		testPublicKey, _ := hex.DecodeString("0331393af9984375830971ab5d3094c6a7d02db3568b2b06212a7090094549701b")
+		testInputScriptPubKey, _ := hex.DecodeString("76a914ecb4e3ab8e5bf3ad3ed621f2bd7acd199f3b883788ac")
+		testOutputScriptPubKey, _ := hex.DecodeString("a9141a8b0026343166625c7475f01e48b5ede8c0252e87")
+		testSignedTxHex := "0100000001acc6fb9ec2c3884d3a12a89e7078c83853d9b7912281cefb14bac00a2737d33a000000006a47304402206d6caac248af96f6afa7f904f550253a0f3ef3f5aa2fe6838a95b216691468e202200adf71c3840669c3a550fb53159d9c1b5bb72822245fd0125b2594d9aae3ec0583210331393af9984375830971ab5d3094c6a7d02db3568b2b06212a7090094549701bffffffff01400001000000000017a9141a8b0026343166625c7475f01e48b5ede8c0252e8700000000"
+
+		btcutils.SetFixedNonce = true
+		testTx := &btcutils.Transaction{Version: 1, Inputs: []btcutils.Input{{TxHash: "3ad337270ac0ba14fbce812291b7d95338c878709ea8123a4d88c3c29efbc6ac", OutputIndex: 0, Sequence: btcutils.SequenceFinal}}, Outputs: []btcutils.Output{{Satoshis: 65600, ScriptPubKey: testOutputScriptPubKey}}}
+		signedTx, err := signP2PKHTransaction(testTx, testPrivateKey, testPublicKey, testInputScriptPubKey, btcutils.SigHashSingle|btcutils.SigHashAnyOneCanPay)
+		if err != nil {
+			t.Error(err)
+		}
+		signedTxHex := hex.EncodeToString(signedTx)
+		if signedTxHex != testSignedTxHex {
+			testutils.CompareError(t, "Generated signature different from expected signature.", testSignedTxHex, signedTxHex)
+		}
+	}
+}
+
+func TestGenerateFundInputIndex(t *testing.T) {
+	btcutils.SetFixedNonce = true
+	testPrivateKeyWIF := "5JJyqG4bb15zqi7fTA4b227aUxQhBo1Ux6qX69ngeXYLr7fk2hs"
+	testInputTx := "3ad337270ac0ba14fbce812291b7d95338c878709ea8123a4d88c3c29efbc6ac"
+	testInputIndex := 3
+	testAmount := 65600
+	testP2SHDestination := "347N1Thc213QqfYCz3PZkjoJpNv5b14kBd"
+	//Output index is 4 little-endian bytes directly after version (4 bytes), input count (1 byte) and input hash (32 bytes)
+	testOutputIndexHex := "03000000"
+
+	finalTransactionHex, _, _, err := generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, InputIndex: testInputIndex, Amount: testAmount, Destinations: []string{testP2SHDestination}, TxFlags: testTxFlags})
+	if err != nil {
+		t.Error(err)
+	}
+	outputIndexHex := finalTransactionHex[74:82]
+	if outputIndexHex != testOutputIndexHex {
+		testutils.CompareError(t, "Funding transaction output index different from expected index.", testOutputIndexHex, outputIndexHex)
+	}
+}
+
+func TestGenerateFundChange(t *testing.T) {
+	btcutils.SetFixedNonce = true
+	testPrivateKeyWIF := "5JJyqG4bb15zqi7fTA4b227aUxQhBo1Ux6qX69ngeXYLr7fk2hs"
+	testInputTx := "3ad337270ac0ba14fbce812291b7d95338c878709ea8123a4d88c3c29efbc6ac"
+	testAmount := 65600
+	testP2SHDestination := "347N1Thc213QqfYCz3PZkjoJpNv5b14kBd"
+	testChangeAddress := "13LSqJeZBpqLHzmLkJ5mvRHiM11waShFUP"
+	testInputAmount := 100000
+	testFee := 10000
+	testChange := 24400
+	//Two outputs, P2SH destination followed by P2PKH change, then lock time
+	testOutputsHex := "02" + "400001000000000017a9141a8b0026343166625c7475f01e48b5ede8c0252e87" + "505f0000000000001976a914199db810a3c8ae5e55c0432d2b72e55b0634f79088ac" + "00000000"
+
+	finalTransactionHex, change, _, err := generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, Amount: testAmount, Destinations: []string{testP2SHDestination}, ChangeAddress: testChangeAddress, InputAmount: testInputAmount, Fee: testFee, TxFlags: TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: 3000, MaxFee: 1000000, ForceHighFee: true, SigHash: "default", OutputFormat: "hex", Network: "mainnet"}})
+	if err != nil {
+		t.Error(err)
+	}
+	if change != testChange {
+		testutils.CompareError(t, "Funding transaction change different from expected change.", testChange, change)
+	}
+	if !strings.HasSuffix(finalTransactionHex, testOutputsHex) {
+		testutils.CompareError(t, "Funding transaction outputs different from expected outputs.", testOutputsHex, finalTransactionHex)
+	}
+
+	//Change below the 546 satoshi dust threshold of P2PKH outputs is added to the fee rather than creating an output
+	testDustThreshold := 546
+	testDustInputAmount := testAmount + testFee + testDustThreshold - 1
+	testNoChangeOutputsHex := "01" + "400001000000000017a9141a8b0026343166625c7475f01e48b5ede8c0252e87" + "00000000"
+	finalTransactionHex, change, _, err = generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, Amount: testAmount, Destinations: []string{testP2SHDestination}, ChangeAddress: testChangeAddress, InputAmount: testDustInputAmount, Fee: testFee, TxFlags: TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: 3000, MaxFee: 1000000, ForceHighFee: true, SigHash: "default", OutputFormat: "hex", Network: "mainnet"}})
+	if err != nil {
+		t.Error(err)
+	}
+	if change != testDustThreshold-1 {
+		testutils.CompareError(t, "Funding transaction change different from expected change.", testDustThreshold-1, change)
+	}
+	if !strings.HasSuffix(finalTransactionHex, testNoChangeOutputsHex) {
+		testutils.CompareError(t, "Funding transaction with dust change has unexpected outputs.", testNoChangeOutputsHex, finalTransactionHex)
+	}
+}
+
+func TestGenerateFundP2PKHDestination(t *testing.T) {
+	btcutils.SetFixedNonce = true
+	testPrivateKeyWIF := "5JJyqG4bb15zqi7fTA4b227aUxQhBo1Ux6qX69ngeXYLr7fk2hs"
+	testInputTx := "3ad337270ac0ba14fbce812291b7d95338c878709ea8123a4d88c3c29efbc6ac"
+	testP2PKHDestination := "13LSqJeZBpqLHzmLkJ5mvRHiM11waShFUP"
+	testP2SHChangeAddress := "347N1Thc213QqfYCz3PZkjoJpNv5b14kBd"
+	testInputAmount := 100000
+	testFee := 10000
+	//P2PKH destination followed by P2SH change, then lock time
+	testOutputsHex := "02" + "40000100000000001976a914199db810a3c8ae5e55c0432d2b72e55b0634f79088ac" + "505f00000000000017a9141a8b0026343166625c7475f01e48b5ede8c0252e87" + "00000000"
+
+	finalTransactionHex, change, _, err := generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, Amount: 65600, Destinations: []string{testP2PKHDestination}, ChangeAddress: testP2SHChangeAddress, InputAmount: testInputAmount, Fee: testFee, TxFlags: TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: 3000, MaxFee: 1000000, ForceHighFee: true, SigHash: "default", OutputFormat: "hex", Network: "mainnet"}})
+	if err != nil {
+		t.Error(err)
+	}
+	if change != 24400 {
+		testutils.CompareError(t, "Funding transaction change different from expected change.", 24400, change)
+	}
+	if !strings.HasSuffix(finalTransactionHex, testOutputsHex) {
+		testutils.CompareError(t, "Funding transaction outputs different from expected outputs.", testOutputsHex, finalTransactionHex)
+	}
+
+	//Sweep of input amount less fee to the P2PKH destination
+	testSweepOutputsHex := "01" + "905f0100000000001976a914199db810a3c8ae5e55c0432d2b72e55b0634f79088ac" + "00000000"
+	finalTransactionHex, _, _, err = generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, Destinations: []string{testP2PKHDestination}, InputAmount: testInputAmount, Fee: testFee, Sweep: true, TxFlags: TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: 3000, MaxFee: 1000000, ForceHighFee: true, SigHash: "default", OutputFormat: "hex", Network: "mainnet"}})
+	if err != nil {
+		t.Error(err)
+	}
+	if !strings.HasSuffix(finalTransactionHex, testSweepOutputsHex) {
+		testutils.CompareError(t, "Sweep transaction outputs different from expected outputs.", testSweepOutputsHex, finalTransactionHex)
+	}
+
+	//545 satoshis is above the 540 satoshi P2SH dust threshold but below the 546 satoshi P2PKH one
+	if _, _, _, err := generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, Amount: 545, Destinations: []string{testP2SHChangeAddress}, TxFlags: TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: 3000, MaxFee: 1000000, ForceHighFee: true, SigHash: "default", OutputFormat: "hex", Network: "mainnet"}}); err != nil {
+		t.Error(err)
+	}
+	if _, _, _, err := generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, Amount: 545, Destinations: []string{testP2PKHDestination}, TxFlags: TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: 3000, MaxFee: 1000000, ForceHighFee: true, SigHash: "default", OutputFormat: "hex", Network: "mainnet"}}); err == nil {
+		t.Error("generateFund accepting P2PKH output below the dust threshold.")
+	}
+}
+
+func TestGenerateFundDust(t *testing.T) {
+	btcutils.SetFixedNonce = true
+	testPrivateKeyWIF := "5JJyqG4bb15zqi7fTA4b227aUxQhBo1Ux6qX69ngeXYLr7fk2hs"
+	testInputTx := "3ad337270ac0ba14fbce812291b7d95338c878709ea8123a4d88c3c29efbc6ac"
+	testP2SHDestination := "347N1Thc213QqfYCz3PZkjoJpNv5b14kBd"
+
+	//P2SH output below its 540 satoshi dust threshold, with the threshold in the error
+	_, _, _, err := generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, Amount: 539, Destinations: []string{testP2SHDestination}, TxFlags: TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: 3000, MaxFee: 1000000, ForceHighFee: true, SigHash: "default", OutputFormat: "hex", Network: "mainnet"}})
+	if err == nil {
+		t.Error("generateFund accepting output below the dust threshold.")
+	} else if !strings.Contains(err.Error(), "540 satoshis") {
+		testutils.CompareError(t, "Dust error without the dust threshold.", "540 satoshis", err)
+	}
+	if _, _, _, err := generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, Amount: 540, Destinations: []string{testP2SHDestination}, TxFlags: TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: 3000, MaxFee: 1000000, ForceHighFee: true, SigHash: "default", OutputFormat: "hex", Network: "mainnet"}}); err != nil {
+		t.Error(err)
+	}
+	//Dust allowed with --allow-dust, or a lower dust relay fee rate
+	if _, _, _, err := generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, Amount: 100, Destinations: []string{testP2SHDestination}, TxFlags: TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: 3000, AllowDust: true, MaxFee: 1000000, ForceHighFee: true, SigHash: "default", OutputFormat: "hex", Network: "mainnet"}}); err != nil {
+		t.Error(err)
+	}
+	if _, _, _, err := generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, Amount: 180, Destinations: []string{testP2SHDestination}, TxFlags: TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: 1000, MaxFee: 1000000, ForceHighFee: true, SigHash: "default", OutputFormat: "hex", Network: "mainnet"}}); err != nil {
+		t.Error(err)
+	}
+	//Negative dust relay fee rate
+	if _, _, _, err := generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, Amount: 65600, Destinations: []string{testP2SHDestination}, TxFlags: TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: -1, MaxFee: 1000000, ForceHighFee: true, SigHash: "default", OutputFormat: "hex", Network: "mainnet"}}); err == nil {
+		t.Error("generateFund accepting negative dust relay fee rate.")
+	}
+	//Change above the 182 satoshi P2PKH dust threshold at 1000 sat/kvB creates an output
+	_, change, _, err := generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, Amount: 65600, Destinations: []string{testP2SHDestination}, ChangeAddress: "13LSqJeZBpqLHzmLkJ5mvRHiM11waShFUP", InputAmount: 65600 + 10000 + 545, Fee: 10000, TxFlags: TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: 1000, MaxFee: 1000000, ForceHighFee: true, SigHash: "default", OutputFormat: "hex", Network: "mainnet"}})
+	if err != nil {
+		t.Error(err)
+	}
+	if change != 545 {
+		testutils.CompareError(t, "Funding transaction change different from expected change.", 545, change)
+	}
+}
+
+func TestGenerateFundHighFee(t *testing.T) {
+	btcutils.SetFixedNonce = true
+	testPrivateKeyWIF := "5JJyqG4bb15zqi7fTA4b227aUxQhBo1Ux6qX69ngeXYLr7fk2hs"
+	testInputTx := "3ad337270ac0ba14fbce812291b7d95338c878709ea8123a4d88c3c29efbc6ac"
+	testP2SHDestination := "347N1Thc213QqfYCz3PZkjoJpNv5b14kBd"
+	testChangeAddress := "13LSqJeZBpqLHzmLkJ5mvRHiM11waShFUP"
+
+	//Sending 10000 of 100000 satoshis without change leaves a 90000 satoshi fee, far above 10% of the amount sent
+	_, _, _, err := generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, Amount: 10000, Destinations: []string{testP2SHDestination}, InputAmount: 100000, TxFlags: testTxFlags})
+	if err == nil || !strings.Contains(err.Error(), "90000") || !strings.Contains(err.Error(), "--force-high-fee") {
+		t.Errorf("generateFund accepting fee above 10%% of the amount sent. %v", err)
+	}
+	if _, _, _, err := generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, Amount: 10000, Destinations: []string{testP2SHDestination}, InputAmount: 100000, TxFlags: TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: 3000, MaxFee: 1000000, ForceHighFee: true, SigHash: "default", OutputFormat: "hex", Network: "mainnet"}}); err != nil {
+		t.Error(err)
+	}
+	//A 2000000 satoshi fee is below 10% of the 30000000 satoshis sent, not counting the change, but above --max-fee
+	_, _, _, err = generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, Amount: 30000000, Destinations: []string{testP2SHDestination}, ChangeAddress: testChangeAddress, InputAmount: 50000000, Fee: 2000000, TxFlags: testTxFlags})
+	if err == nil || !strings.Contains(err.Error(), "2000000") || !strings.Contains(err.Error(), "--max-fee") {
+		t.Errorf("generateFund accepting fee above --max-fee. %v", err)
+	}
+	if _, _, _, err := generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, Amount: 30000000, Destinations: []string{testP2SHDestination}, ChangeAddress: testChangeAddress, InputAmount: 50000000, Fee: 2000000, TxFlags: TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: 3000, MaxFee: 2000000, SigHash: "default", OutputFormat: "hex", Network: "mainnet"}}); err != nil {
+		t.Error(err)
+	}
+	if _, _, _, err := generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, Amount: 30000000, Destinations: []string{testP2SHDestination}, ChangeAddress: testChangeAddress, InputAmount: 50000000, Fee: 2000000, TxFlags: TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: 3000, MaxFee: -1, ForceHighFee: true, SigHash: "default", OutputFormat: "hex", Network: "mainnet"}}); err == nil {
+		t.Error("generateFund accepting negative --max-fee.")
+	}
+}
+
+func TestGenerateFundPublicKey(t *testing.T) {
+	btcutils.SetFixedNonce = true
+	testPrivateKeyWIF := "5JJyqG4bb15zqi7fTA4b227aUxQhBo1Ux6qX69ngeXYLr7fk2hs"
+	testInputTx := "3ad337270ac0ba14fbce812291b7d95338c878709ea8123a4d88c3c29efbc6ac"
+	testP2SHDestination := "347N1Thc213QqfYCz3PZkjoJpNv5b14kBd"
+	testPublicKey := "0431393af9984375830971ab5d3094c6a7d02db3568b2b06212a7090094549701bbb9e84d9477451acc42638963635899ce91bacb451a1bb6da73ddfbcf596bddf"
+	publicKey, _ := hex.DecodeString(testPublicKey)
+	compressedPublicKey, _ := btcutils.CompressPublicKey(publicKey)
+	otherPublicKey := "04ff4c2ce7513a6c896ebfaaa4ae52cea35374e0eac90ccb8f4e5fa14b8322e2bae4c65116c7af2ba6a82831e48c451fc29a66d49c24757130ebf07c142bbcbe75"
+
+	expected, _, _, err := generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, Amount: 65600, Destinations: []string{testP2SHDestination}, TxFlags: testTxFlags})
+	if err != nil {
+		t.Fatal(err)
+	}
+	//Either form of the derived key is accepted, and the input is still signed with the key the WIF is for
+	for _, flagPublicKey := range []string{testPublicKey, hex.EncodeToString(compressedPublicKey)} {
+		finalTransactionHex, _, _, err := generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, PublicKey: flagPublicKey, InputTx: testInputTx, Amount: 65600, Destinations: []string{testP2SHDestination}, TxFlags: testTxFlags})
+		if err != nil {
+			t.Errorf("--public-key %s refused. %s", flagPublicKey, err)
+		} else if finalTransactionHex != expected {
+			testutils.CompareError(t, "Funding transaction with --public-key different from transaction without.", expected, finalTransactionHex)
+		}
+	}
+	address, _ := btcutils.PublicKeyToP2PKHAddress(publicKey, &btcutils.MainNetParams)
+	for _, flagPublicKey := range []string{otherPublicKey, "04ff4c", "not hex"} {
+		_, _, _, err := generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, PublicKey: flagPublicKey, InputTx: testInputTx, Amount: 65600, Destinations: []string{testP2SHDestination}, TxFlags: testTxFlags})
+		if err == nil {
+			t.Errorf("--public-key %s of another private key accepted.", flagPublicKey)
+		} else if flagPublicKey == otherPublicKey && !strings.Contains(err.Error(), address) {
+			t.Errorf("Error %q does not name the address %s of --private-key.", err, address)
+		}
+	}
+}
+
+func TestGenerateFundPrevTxKey(t *testing.T) {
+	btcutils.SetFixedNonce = true
+	testPrivateKeyWIF := "5JJyqG4bb15zqi7fTA4b227aUxQhBo1Ux6qX69ngeXYLr7fk2hs"
+	testDestination := "347N1Thc213QqfYCz3PZkjoJpNv5b14kBd"
+	privateKey, _, _ := btcutils.ParseWIF(testPrivateKeyWIF, &btcutils.MainNetParams)
+	publicKey, _ := btcutils.NewPublicKey(privateKey)
+	publicKeyHash, _ := btcutils.Hash160(publicKey)
+	testScriptPubKey, _ := btcutils.NewP2PKHScriptPubKey(publicKeyHash)
+	testPrevTxHex, testInputTx := testPrevTx(t, testScriptPubKey, 70000)
+
+	//Output 1 pays to the uncompressed public key of the WIF
+	if _, _, _, err := generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, InputIndex: 1, Amount: 65600, Destinations: []string{testDestination}, TxFlags: TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: 3000, MaxFee: 1000000, SigHash: "default", OutputFormat: "hex", PrevTx: testPrevTxHex, Network: "mainnet"}}); err != nil {
+		t.Error(err)
+	}
+	//Output 0 pays to another key, named in the error with the address of the key given
+	address, _ := btcutils.PublicKeyToP2PKHAddress(publicKey, &btcutils.MainNetParams)
+	_, _, _, err := generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, Amount: 65600, Destinations: []string{testDestination}, TxFlags: TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: 3000, MaxFee: 1000000, SigHash: "default", OutputFormat: "hex", PrevTx: testPrevTxHex, Network: "mainnet"}})
+	if err == nil {
+		t.Error("generateFund signing an input paying to another key.")
+	} else if !strings.Contains(err.Error(), "does not control input "+testInputTx+":0") || !strings.Contains(err.Error(), "key for address "+address) {
+		t.Errorf("Error %q does not name input %s:0 and the address %s of --private-key.", err, testInputTx, address)
+	}
+}
+
+func TestGenerateFundFeeRate(t *testing.T) {
+	btcutils.SetFixedNonce = true
+	testPrivateKeyWIF := "5JJyqG4bb15zqi7fTA4b227aUxQhBo1Ux6qX69ngeXYLr7fk2hs"
+	testInputTx := "3ad337270ac0ba14fbce812291b7d95338c878709ea8123a4d88c3c29efbc6ac"
+	testAmount := 65600
+	testP2SHDestination := "347N1Thc213QqfYCz3PZkjoJpNv5b14kBd"
+	testChangeAddress := "13LSqJeZBpqLHzmLkJ5mvRHiM11waShFUP"
+	testInputAmount := 100000
+	testFeeRate := 10
+	//Estimated size with P2SH and P2PKH outputs is 256 bytes, so fee is 2560 satoshis
+	testEstimatedSize := 256
+	testChange := testInputAmount - testAmount - testFeeRate*testEstimatedSize
+
+	finalTransactionHex, change, estimatedSize, err := generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, Amount: testAmount, Destinations: []string{testP2SHDestination}, ChangeAddress: testChangeAddress, InputAmount: testInputAmount, FeeRate: testFeeRate, TxFlags: testTxFlags})
+	if err != nil {
+		t.Error(err)
+	}
+	if estimatedSize != testEstimatedSize {
+		testutils.CompareError(t, "Estimated funding transaction size different from expected size.", testEstimatedSize, estimatedSize)
+	}
+	if change != testChange {
+		testutils.CompareError(t, "Funding transaction change different from expected change.", testChange, change)
+	}
+	//Estimate assumes the largest possible signature, so can only exceed the actual size by a couple of bytes
+	if size := len(finalTransactionHex) / 2; size > estimatedSize || estimatedSize-size > 2 {
+		testutils.CompareError(t, "Actual funding transaction size too far from estimated size.", estimatedSize, size)
+	}
+}
+
+func TestGenerateFundSweep(t *testing.T) {
+	btcutils.SetFixedNonce = true
+	testPrivateKeyWIF := "5JJyqG4bb15zqi7fTA4b227aUxQhBo1Ux6qX69ngeXYLr7fk2hs"
+	testInputTx := "3ad337270ac0ba14fbce812291b7d95338c878709ea8123a4d88c3c29efbc6ac"
+	testP2SHDestination := "347N1Thc213QqfYCz3PZkjoJpNv5b14kBd"
+	testInputAmount := 100000
+	testFee := 10000
+	//Single output of input amount less fee, 90000 satoshis, then lock time
+	testOutputsHex := "01" + "905f01000000000017a9141a8b0026343166625c7475f01e48b5ede8c0252e87" + "00000000"
+
+	finalTransactionHex, _, _, err := generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, Destinations: []string{testP2SHDestination}, InputAmount: testInputAmount, Fee: testFee, Sweep: true, TxFlags: TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: 3000, MaxFee: 1000000, ForceHighFee: true, SigHash: "default", OutputFormat: "hex", Network: "mainnet"}})
+	if err != nil {
+		t.Error(err)
+	}
+	if !strings.HasSuffix(finalTransactionHex, testOutputsHex) {
+		testutils.CompareError(t, "Sweep transaction outputs different from expected outputs.", testOutputsHex, finalTransactionHex)
+	}
+
+	//Estimated size with a single P2SH output is 222 bytes, so fee is 2220 satoshis and 97780 satoshis are swept
+	testFeeRate := 10
+	testFeeRateOutputsHex := "01" + "f47d01000000000017a9141a8b0026343166625c7475f01e48b5ede8c0252e87" + "00000000"
+	finalTransactionHex, _, _, err = generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, Destinations: []string{testP2SHDestination}, InputAmount: testInputAmount, FeeRate: testFeeRate, Sweep: true, TxFlags: TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: 3000, MaxFee: 1000000, ForceHighFee: true, SigHash: "default", OutputFormat: "hex", Network: "mainnet"}})
+	if err != nil {
+		t.Error(err)
+	}
+	if !strings.HasSuffix(finalTransactionHex, testFeeRateOutputsHex) {
+		testutils.CompareError(t, "Sweep transaction outputs different from expected outputs.", testFeeRateOutputsHex, finalTransactionHex)
+	}
+
+	//Sweep together with an explicit amount
+	if _, _, _, err := generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, Amount: 65600, Destinations: []string{testP2SHDestination}, InputAmount: testInputAmount, Fee: testFee, Sweep: true, TxFlags: TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: 3000, MaxFee: 1000000, ForceHighFee: true, SigHash: "default", OutputFormat: "hex", Network: "mainnet"}}); err == nil {
+		t.Error("generateFund accepting --sweep with --amount.")
+	}
+	//Sweep together with a change address
+	if _, _, _, err := generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, Destinations: []string{testP2SHDestination}, ChangeAddress: "13LSqJeZBpqLHzmLkJ5mvRHiM11waShFUP", InputAmount: testInputAmount, Fee: testFee, Sweep: true, TxFlags: TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: 3000, MaxFee: 1000000, ForceHighFee: true, SigHash: "default", OutputFormat: "hex", Network: "mainnet"}}); err == nil {
+		t.Error("generateFund accepting --sweep with --change-address.")
+	}
+	//Sweep without input amount
+	if _, _, _, err := generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, Destinations: []string{testP2SHDestination}, Fee: testFee, Sweep: true, TxFlags: TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: 3000, MaxFee: 1000000, ForceHighFee: true, SigHash: "default", OutputFormat: "hex", Network: "mainnet"}}); err == nil {
+		t.Error("generateFund accepting --sweep without input amount.")
+	}
+	//Sweep leaving less than the 540 satoshi dust threshold of P2SH outputs
+	if _, _, _, err := generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, Destinations: []string{testP2SHDestination}, InputAmount: testFee + 540 - 1, Fee: testFee, Sweep: true, TxFlags: TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: 3000, MaxFee: 1000000, ForceHighFee: true, SigHash: "default", OutputFormat: "hex", Network: "mainnet"}}); err == nil {
+		t.Error("generateFund accepting sweep below dust threshold.")
+	}
+	//Neither sweep nor amount
+	if _, _, _, err := generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, Destinations: []string{testP2SHDestination}, TxFlags: TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: 3000, MaxFee: 1000000, ForceHighFee: true, SigHash: "default", OutputFormat: "hex", Network: "mainnet"}}); err == nil {
+		t.Error("generateFund accepting missing amount without --sweep.")
+	}
+}
+
+func TestGenerateFundMultipleDestinations(t *testing.T) {
+	btcutils.SetFixedNonce = true
+	testPrivateKeyWIF := "5JJyqG4bb15zqi7fTA4b227aUxQhBo1Ux6qX69ngeXYLr7fk2hs"
+	testInputTx := "3ad337270ac0ba14fbce812291b7d95338c878709ea8123a4d88c3c29efbc6ac"
+	testDestinations := []string{
+		"347N1Thc213QqfYCz3PZkjoJpNv5b14kBd:65600",  //P2SH
+		"3ErDPiDD7AsJDqKkayMA39iLJevTjDCjUa:135600", //P2SH
+		"13LSqJeZBpqLHzmLkJ5mvRHiM11waShFUP:1000",   //P2PKH
+	}
+	//Three outputs in the order given, each with the scriptPubKey template matching its address, then lock time
+	testOutputsHex := "03" + "400001000000000017a9141a8b0026343166625c7475f01e48b5ede8c0252e87" + "b01102000000000017a9149056f3c2a8cbd11340fa2ee4736dea1d298c9d1187" + "e8030000000000001976a914199db810a3c8ae5e55c0432d2b72e55b0634f79088ac" + "00000000"
+
+	finalTransactionHex, _, _, err := generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, Destinations: testDestinations, TxFlags: testTxFlags})
+	if err != nil {
+		t.Error(err)
+	}
+	if !strings.HasSuffix(finalTransactionHex, testOutputsHex) {
+		testutils.CompareError(t, "Funding transaction outputs different from expected outputs.", testOutputsHex, finalTransactionHex)
+	}
+
+	//Outputs exceeding the known input amount
+	if _, _, _, err := generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, Destinations: testDestinations, InputAmount: 200000, TxFlags: testTxFlags}); err == nil {
+		t.Error("generateFund accepting outputs exceeding input amount.")
+	}
+	//Multiple destinations without amounts
+	if _, _, _, err := generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, Amount: 65600, Destinations: []string{"347N1Thc213QqfYCz3PZkjoJpNv5b14kBd", "3ErDPiDD7AsJDqKkayMA39iLJevTjDCjUa"}, TxFlags: testTxFlags}); err == nil {
+		t.Error("generateFund accepting multiple destinations without amounts.")
+	}
+	//Invalid destination amounts
+	for _, invalidDestination := range []string{"347N1Thc213QqfYCz3PZkjoJpNv5b14kBd:", "347N1Thc213QqfYCz3PZkjoJpNv5b14kBd:abc", "347N1Thc213QqfYCz3PZkjoJpNv5b14kBd:-5"} {
+		if _, _, _, err := generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, Destinations: []string{invalidDestination}, TxFlags: testTxFlags}); err == nil {
+			t.Errorf("generateFund accepting invalid destination %s.", invalidDestination)
+		}
+	}
+	//--amount together with ADDRESS:AMOUNT
+	if _, _, _, err := generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, Amount: 65600, Destinations: testDestinations, TxFlags: testTxFlags}); err == nil {
+		t.Error("generateFund accepting --amount with ADDRESS:AMOUNT destinations.")
+	}
+}
+
+func TestGenerateFundOpReturn(t *testing.T) {
+	btcutils.SetFixedNonce = true
+	testPrivateKeyWIF := "5JJyqG4bb15zqi7fTA4b227aUxQhBo1Ux6qX69ngeXYLr7fk2hs"
+	testInputTx := "3ad337270ac0ba14fbce812291b7d95338c878709ea8123a4d88c3c29efbc6ac"
+	testDestination := "347N1Thc213QqfYCz3PZkjoJpNv5b14kBd"
+	testDocumentHash := "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	//Destination output, then zero value OP_RETURN outputs pushing the hash and the text, then lock time
+	testOutputsHex := "03" + "400001000000000017a9141a8b0026343166625c7475f01e48b5ede8c0252e87" + "000000000000000022" + "6a20" + testDocumentHash + "00000000000000000d" + "6a0b" + hex.EncodeToString([]byte("hello world")) + "00000000"
+
+	finalTransactionHex, _, _, err := generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, Amount: 65600, Destinations: []string{testDestination}, Force: true, OpReturns: []string{testDocumentHash}, OpReturnTexts: []string{"hello world"}, TxFlags: testTxFlags})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(finalTransactionHex, testOutputsHex) {
+		testutils.CompareError(t, "Funding transaction outputs different from expected outputs.", testOutputsHex, finalTransactionHex)
+	}
+
+	//Text that happens to be valid hex is embedded as text, and the same value as data is decoded from hex
+	testCases := []struct {
+		opReturns     []string
+		opReturnTexts []string
+		outputHex     string
+	}{
+		{nil, []string{"cafe"}, "0000000000000000066a0463616665"},
+		{[]string{"cafe"}, nil, "0000000000000000046a02cafe"},
+		{nil, []string{"00"}, "0000000000000000046a023030"},
+		{[]string{"00"}, nil, "0000000000000000036a0100"},
+	}
+	for _, testCase := range testCases {
+		finalTransactionHex, _, _, err := generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, Amount: 65600, Destinations: []string{testDestination}, OpReturns: testCase.opReturns, OpReturnTexts: testCase.opReturnTexts, TxFlags: testTxFlags})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasSuffix(finalTransactionHex, testCase.outputHex+"00000000") {
+			testutils.CompareError(t, "OP_RETURN output different from expected output.", testCase.outputHex, finalTransactionHex)
+		}
+	}
+
+	//More than one OP_RETURN output without --force
+	if _, _, _, err := generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, Amount: 65600, Destinations: []string{testDestination}, OpReturns: []string{testDocumentHash}, OpReturnTexts: []string{"hello world"}, TxFlags: testTxFlags}); err == nil {
+		t.Error("generateFund accepting more than one OP_RETURN output without --force.")
+	}
+	//Data that isn't hex
+	if _, _, _, err := generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, Amount: 65600, Destinations: []string{testDestination}, OpReturns: []string{"hello world"}, TxFlags: testTxFlags}); err == nil {
+		t.Error("generateFund accepting --op-return data that isn't hex.")
+	}
+	//Data over 80 bytes
+	if _, _, _, err := generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, Amount: 65600, Destinations: []string{testDestination}, OpReturns: []string{strings.Repeat("a", 2*(btcutils.MaxOpReturnDataSize+1))}, TxFlags: testTxFlags}); err == nil {
+		t.Error("generateFund accepting OP_RETURN data over 80 bytes.")
+	}
+	if _, _, _, err := generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, Amount: 65600, Destinations: []string{testDestination}, OpReturnTexts: []string{strings.Repeat("a", btcutils.MaxOpReturnDataSize+1)}, TxFlags: testTxFlags}); err == nil {
+		t.Error("generateFund accepting OP_RETURN text over 80 bytes.")
+	}
+}
+
+func TestGenerateFundErrors(t *testing.T) {
+	testPrivateKeyWIF := "5JJyqG4bb15zqi7fTA4b227aUxQhBo1Ux6qX69ngeXYLr7fk2hs"
+	testInputTx := "3ad337270ac0ba14fbce812291b7d95338c878709ea8123a4d88c3c29efbc6ac"
+	testAmount := 65600
+	testP2SHDestination := "347N1Thc213QqfYCz3PZkjoJpNv5b14kBd"
+
+	//Invalid hex input transaction
+	if _, _, _, err := generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, InputTx: "3ad337270ac0ba14zz", Amount: testAmount, Destinations: []string{testP2SHDestination}, TxFlags: testTxFlags}); err == nil {
+		t.Error("generateFund accepting invalid hex input transaction.")
+	}
+	//Mistyped private key, destination and change address are refused, naming the flag
+	mistypedFlags := []struct {
+		privateKey    string
+		destination   string
+		changeAddress string
+		flag          string
+	}{
+		{"5JJyqG4bb15zqi7fTA4b227aUxQhBo1Ux6qX69ngeXYLr7fk2hT", testP2SHDestination, "", "--private-key"},
+		{testPrivateKeyWIF, "347N1Thc213QqfYCz3PZkjoJpNv5b14kBD", "", "--destination"},
+		{testPrivateKeyWIF, testP2SHDestination, "13LSqJeZBpqLHzmLkJ5mvRHiM11waShFUQ", "--change-address"},
+	}
+	for _, mistyped := range mistypedFlags {
+		_, _, _, err := generateFund(&FundFlags{PrivateKey: mistyped.privateKey, InputTx: testInputTx, Amount: testAmount, Destinations: []string{mistyped.destination}, ChangeAddress: mistyped.changeAddress, InputAmount: 100000, Fee: 10000, TxFlags: testTxFlags})
+		if err == nil {
+			t.Errorf("generateFund accepting mistyped %s.", mistyped.flag)
+		} else if !strings.Contains(err.Error(), mistyped.flag) {
+			testutils.CompareError(t, "Error for mistyped flag does not name the flag.", mistyped.flag, err)
+		}
+	}
+	//Negative amount, which would wrap around to nearly 2^64 satoshis
+	if _, _, _, err := generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, Amount: -1, Destinations: []string{testP2SHDestination}, TxFlags: testTxFlags}); err == nil {
+		t.Error("generateFund accepting negative amount.")
+	}
+	//Amount above 21,000,000 BTC
+	if _, _, _, err := generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, Amount: btcutils.MaxMoney + 1, Destinations: []string{testP2SHDestination}, TxFlags: testTxFlags}); err == nil {
+		t.Error("generateFund accepting amount above MaxMoney.")
+	}
+	//Wrong private key length. Public address decodes to 20 bytes rather than 32.
+	if _, _, _, err := generateFund(&FundFlags{PrivateKey: "13LSqJeZBpqLHzmLkJ5mvRHiM11waShFUP", InputTx: testInputTx, Amount: testAmount, Destinations: []string{testP2SHDestination}, TxFlags: testTxFlags}); err == nil {
+		t.Error("generateFund accepting private key of wrong length.")
+	}
+	//Empty destination gives empty scriptPubKey
+	if _, _, _, err := generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, Amount: testAmount, Destinations: []string{""}, TxFlags: testTxFlags}); err == nil {
+		t.Error("generateFund accepting empty destination.")
+	}
+	//Change address without input amount
+	if _, _, _, err := generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, Amount: testAmount, Destinations: []string{testP2SHDestination}, ChangeAddress: "13LSqJeZBpqLHzmLkJ5mvRHiM11waShFUP", Fee: 1000, TxFlags: testTxFlags}); err == nil {
+		t.Error("generateFund accepting change address without input amount.")
+	}
+}
+
+func TestCheckInputIndex(t *testing.T) {
+	for _, validIndex := range []int{0, 1, math.MaxUint32} {
+		inputIndex, err := checkInputIndex(validIndex)
+		if err != nil {
+			t.Error(err)
+		}
+		if int(inputIndex) != validIndex {
+			testutils.CompareError(t, "Checked input index different from expected index.", validIndex, inputIndex)
+		}
+	}
+	for _, invalidIndex := range []int{-1, math.MaxUint32 + 1} {
+		if _, err := checkInputIndex(invalidIndex); err == nil {
+			t.Errorf("checkInputIndex accepting invalid input index %d as valid.", invalidIndex)
+		}
+	}
+}
+
+func TestParseInput(t *testing.T) {
+	testInputTx := "3ad337270ac0ba14fbce812291b7d95338c878709ea8123a4d88c3c29efbc6ac"
+	testCases := []struct {
+		inputTx    string
+		inputIndex int
+		lockTime   int64
+		sequence   int64
+		rbf        bool
+		input      btcutils.Input
+	}{
+		{testInputTx, 2, 0, 0xffffffff, false, btcutils.Input{TxHash: testInputTx, OutputIndex: 2, Sequence: 0xffffffff}},
+		{testInputTx + ":1", 0, 0, 0xffffffff, true, btcutils.Input{TxHash: testInputTx, OutputIndex: 1, Sequence: 0xfffffffd}},
+		//A sequence given with the input is neither replaced with --rbf nor lowered with --locktime
+		{testInputTx + ":1:4294967295", 0, 0, 0xffffffff, true, btcutils.Input{TxHash: testInputTx, OutputIndex: 1, Sequence: 0xffffffff}},
+		{testInputTx + ":0:0xfffffffe", 0, 840000, 0xffffffff, true, btcutils.Input{TxHash: testInputTx, OutputIndex: 0, Sequence: 0xfffffffe}},
+		{testInputTx + ":0:144", 0, 0, 0xffffffff, false, btcutils.Input{TxHash: testInputTx, OutputIndex: 0, Sequence: 144}},
+	}
+	for _, testCase := range testCases {
+		input, _, err := parseInput(testCase.inputTx, testCase.inputIndex, testCase.lockTime, testCase.sequence, testCase.rbf, "", 2)
+		if err != nil {
+			t.Error(err)
+		}
+		if !reflect.DeepEqual(input, testCase.input) {
+			testutils.CompareError(t, fmt.Sprintf("Input parsed from %s different from expected input.", testCase.inputTx), testCase.input, input)
+		}
+	}
+
+	invalidTestCases := []struct {
+		inputTx    string
+		inputIndex int
+		lockTime   int64
+		sequence   int64
+	}{
+		{testInputTx + ":1:2:3", 0, 0, 0xffffffff},
+		{testInputTx + ":one", 0, 0, 0xffffffff},
+		{testInputTx + ":-1", 0, 0, 0xffffffff},
+		//Output index given twice
+		{testInputTx + ":1", 1, 0, 0xffffffff},
+		//Sequence given twice
+		{testInputTx + ":1:0", 0, 0, 0xfffffffe},
+		{testInputTx + ":1:4294967296", 0, 0, 0xffffffff},
+		//Final sequence with a lock time, which would be ignored
+		{testInputTx + ":1:0xffffffff", 0, 840000, 0xffffffff},
+	}
+	for _, testCase := range invalidTestCases {
+		if _, _, err := parseInput(testCase.inputTx, testCase.inputIndex, testCase.lockTime, testCase.sequence, false, "", 2); err == nil {
+			t.Errorf("parseInput accepting input %s with --input-index %d, --locktime %d and --sequence %d.", testCase.inputTx, testCase.inputIndex, testCase.lockTime, testCase.sequence)
+		}
+	}
+}
+
+func TestParseInputRelativeLockTime(t *testing.T) {
+	testInputTx := "3ad337270ac0ba14fbce812291b7d95338c878709ea8123a4d88c3c29efbc6ac"
+	testCases := []struct {
+		relativeLockTime string
+		sequence         uint32
+	}{
+		{"144", 144},
+		{"0", 0},
+		//512 second units with the type flag set, rounded up
+		{"86400s", 0x004000a9},
+		{"1s", 0x00400001},
+	}
+	for _, testCase := range testCases {
+		input, _, err := parseInput(testInputTx, 0, 0, 0xffffffff, true, testCase.relativeLockTime, 2)
+		if err != nil {
+			t.Error(err)
+		}
+		if input.Sequence != testCase.sequence {
+			testutils.CompareError(t, fmt.Sprintf("Sequence of --relative-locktime %s different from expected sequence.", testCase.relativeLockTime), testCase.sequence, input.Sequence)
+		}
+	}
+
+	invalidTestCases := []struct {
+		inputTx          string
+		sequence         int64
+		relativeLockTime string
+		txVersion        int64
+	}{
+		{testInputTx, 0xffffffff, "144", 1},
+		{testInputTx, 0xffffffff, "144", 3},
+		{testInputTx, 0xffffffff, "144blocks", 2},
+		{testInputTx, 0xffffffff, "-1", 2},
+		{testInputTx, 0xffffffff, "65536", 2},
+		{testInputTx, 0xfffffffe, "144", 2},
+		{testInputTx + ":0:144", 0xffffffff, "144", 2},
+	}
+	for _, testCase := range invalidTestCases {
+		if _, _, err := parseInput(testCase.inputTx, 0, 0, testCase.sequence, true, testCase.relativeLockTime, testCase.txVersion); err == nil {
+			t.Errorf("parseInput accepting --relative-locktime %s with input %s, --sequence %d and --tx-version %d.", testCase.relativeLockTime, testCase.inputTx, testCase.sequence, testCase.txVersion)
+		}
+	}
+}
+
+func TestGenerateFundTestnet(t *testing.T) {
+	btcutils.SetFixedNonce = true //SetFixedNonce set to true to get repeatable signatures with a fixed nonce for testing.
+	//Same private key and destination hash as the first mainnet funding test, encoded for testnet3
+	testPrivateKeyWIF := "925cQzt9BEA8omcx5VxVtcfY8cmQLxYgJ3hUAn9BzGHPdCQNvYH"
+	testInputTx := "3ad337270ac0ba14fbce812291b7d95338c878709ea8123a4d88c3c29efbc6ac"
+	testAmount := 65600
+	testP2SHDestination := "2Mufa5CdddTYm3TAkfB1SNgna2j8FM6W9sq"
+	testFinalTransanctionHex := "0100000001acc6fb9ec2c3884d3a12a89e7078c83853d9b7912281cefb14bac00a2737d33a000000008a47304402206d6caac248af96f6afa7f904f550253a0f3ef3f5aa2fe6838a95b216691468e202207d1c7fb129adec15700c378e142c506b5bbadafdedbb62f614dd0bb128faeecd01410431393af9984375830971ab5d3094c6a7d02db3568b2b06212a7090094549701bbb9e84d9477451acc42638963635899ce91bacb451a1bb6da73ddfbcf596bddfffffffff01400001000000000017a9141a8b0026343166625c7475f01e48b5ede8c0252e8700000000"
+
+	finalTransactionHex, _, _, err := generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, Amount: testAmount, Destinations: []string{testP2SHDestination}, TxFlags: TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: 3000, MaxFee: 1000000, SigHash: "default", OutputFormat: "hex", Network: "testnet3"}})
+	if err != nil {
+		t.Error(err)
+	}
+	if finalTransactionHex != testFinalTransanctionHex {
+		testutils.CompareError(t, "Generated testnet funding transaction different from expected transaction.", testFinalTransanctionHex, finalTransactionHex)
+	}
+
+	//Mainnet private keys and addresses should be refused on testnet, and testnet ones on mainnet
+	if _, _, _, err := generateFund(&FundFlags{PrivateKey: "5JJyqG4bb15zqi7fTA4b227aUxQhBo1Ux6qX69ngeXYLr7fk2hs", InputTx: testInputTx, Amount: testAmount, Destinations: []string{testP2SHDestination}, TxFlags: TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: 3000, MaxFee: 1000000, SigHash: "default", OutputFormat: "hex", Network: "testnet3"}}); err == nil {
+		t.Error("generateFund accepting mainnet private key on testnet.")
+	}
+	if _, _, _, err := generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, Amount: testAmount, Destinations: []string{"347N1Thc213QqfYCz3PZkjoJpNv5b14kBd"}, TxFlags: TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: 3000, MaxFee: 1000000, SigHash: "default", OutputFormat: "hex", Network: "testnet3"}}); err == nil {
+		t.Error("generateFund accepting mainnet destination on testnet.")
+	}
+	if _, _, _, err := generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, Amount: testAmount, Destinations: []string{testP2SHDestination}, TxFlags: testTxFlags}); err == nil {
+		t.Error("generateFund accepting testnet private key on mainnet.")
+	}
+}
+
+func TestGenerateFundCompressedWIF(t *testing.T) {
+	btcutils.SetFixedNonce = true //SetFixedNonce set to true to get repeatable signatures with a fixed nonce for testing.
+	//Same private key as the first funding test, with the WIF marking its public key as compressed
+	testPrivateKeyWIF := "KyQMr1Usf5P4HzUQiSzq9Vv2nPZaNe58NCm9ojndzdoLbfKEvPms"
+	testInputTx := "3ad337270ac0ba14fbce812291b7d95338c878709ea8123a4d88c3c29efbc6ac"
+	testAmount := 65600
+	testP2SHDestination := "347N1Thc213QqfYCz3PZkjoJpNv5b14kBd"
+	testFinalTransanctionHex := "0100000001acc6fb9ec2c3884d3a12a89e7078c83853d9b7912281cefb14bac00a2737d33a000000006a47304402206d6caac248af96f6afa7f904f550253a0f3ef3f5aa2fe6838a95b216691468e202206ab27865a976b0ddee50c973f23158d3a4e96c6f45f27a5584759d09f4478b5401210331393af9984375830971ab5d3094c6a7d02db3568b2b06212a7090094549701bffffffff01400001000000000017a9141a8b0026343166625c7475f01e48b5ede8c0252e8700000000"
+
+	finalTransactionHex, _, _, err := generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, Amount: testAmount, Destinations: []string{testP2SHDestination}, TxFlags: testTxFlags})
+	if err != nil {
+		t.Error(err)
+	}
+	if finalTransactionHex != testFinalTransanctionHex {
+		testutils.CompareError(t, "Generated funding transaction with compressed WIF different from expected transaction.", testFinalTransanctionHex, finalTransactionHex)
+	}
+}
+
+func TestGenerateFundRFC6979(t *testing.T) {
+	//Signs with RFC 6979 deterministic nonces rather than the fixed testing nonce. Expected transactions computed
+	//independently, signature hash and nonces included
+	previousSetFixedNonce := btcutils.SetFixedNonce
+	btcutils.SetFixedNonce = false
+	defer func() { btcutils.SetFixedNonce, btcutils.DisableLowR = previousSetFixedNonce, false }()
+	testPrivateKeyWIF := "5JJyqG4bb15zqi7fTA4b227aUxQhBo1Ux6qX69ngeXYLr7fk2hs"
+	testInputTx := "3ad337270ac0ba14fbce812291b7d95338c878709ea8123a4d88c3c29efbc6ac"
+	testAmount := 65600
+	testP2SHDestination := "347N1Thc213QqfYCz3PZkjoJpNv5b14kBd"
+	testCases := []struct {
+		disableLowR         bool
+		finalTransactionHex string
+	}{
+		//The first nonce gives a 72 byte high R signature, the nonce with extra data counter 3 is the first with low R
+		{false, "0100000001acc6fb9ec2c3884d3a12a89e7078c83853d9b7912281cefb14bac00a2737d33a000000008a47304402201974e077efd647cb374e8b5c08bcc83bbf87224673af102215d319353aadb1c7022074c08e6a2f682602f6a50426c7397e8976316215d35b774dbfee21e73ef38bb501410431393af9984375830971ab5d3094c6a7d02db3568b2b06212a7090094549701bbb9e84d9477451acc42638963635899ce91bacb451a1bb6da73ddfbcf596bddfffffffff01400001000000000017a9141a8b0026343166625c7475f01e48b5ede8c0252e8700000000"},
+		{true, "0100000001acc6fb9ec2c3884d3a12a89e7078c83853d9b7912281cefb14bac00a2737d33a000000008b483045022100fb244ac83b257f4233920077819dfa5203a11cd330c58a37c984699bc8048e9102200caca5b3772022a5cb5ce8e31f644da4e27e2c4f121cfd9b5291e3bccf7017d701410431393af9984375830971ab5d3094c6a7d02db3568b2b06212a7090094549701bbb9e84d9477451acc42638963635899ce91bacb451a1bb6da73ddfbcf596bddfffffffff01400001000000000017a9141a8b0026343166625c7475f01e48b5ede8c0252e8700000000"},
+	}
+
+	for _, testCase := range testCases {
+		btcutils.DisableLowR = testCase.disableLowR
+		//Signing twice gives the same bytes
+		for i := 0; i < 2; i++ {
+			finalTransactionHex, _, _, err := generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, Amount: testAmount, Destinations: []string{testP2SHDestination}, TxFlags: testTxFlags})
+			if err != nil {
+				t.Error(err)
+			}
+			if finalTransactionHex != testCase.finalTransactionHex {
+				testutils.CompareError(t, "Generated funding transaction different from expected transaction.", testCase.finalTransactionHex, finalTransactionHex)
+			}
+		}
+	}
+}
+
+func TestGenerateFundSigHash(t *testing.T) {
+	//Expected transactions computed independently, with the hash type in both the signature hash preimage and the
+	//byte after the signature
+	previousSetFixedNonce := btcutils.SetFixedNonce
+	btcutils.SetFixedNonce = false
+	defer func() { btcutils.SetFixedNonce = previousSetFixedNonce }()
+	testPrivateKeyWIF := "5JJyqG4bb15zqi7fTA4b227aUxQhBo1Ux6qX69ngeXYLr7fk2hs"
+	testInputTx := "3ad337270ac0ba14fbce812291b7d95338c878709ea8123a4d88c3c29efbc6ac"
+	testAmount := 65600
+	testP2SHDestination := "347N1Thc213QqfYCz3PZkjoJpNv5b14kBd"
+	testCases := []struct {
+		sigHash             string
+		finalTransactionHex string
+	}{
+		{"all|anyonecanpay", "0100000001acc6fb9ec2c3884d3a12a89e7078c83853d9b7912281cefb14bac00a2737d33a000000008a47304402201dfdf5e1a9dcc8f9ac2ae9f465152472e13dc872f1aab6e3ebf422486c302b440220298d1be229c0b39d077edf96f5a872fcb289e7dd465ee353b69e14a05689203281410431393af9984375830971ab5d3094c6a7d02db3568b2b06212a7090094549701bbb9e84d9477451acc42638963635899ce91bacb451a1bb6da73ddfbcf596bddfffffffff01400001000000000017a9141a8b0026343166625c7475f01e48b5ede8c0252e8700000000"},
+		{"none", "0100000001acc6fb9ec2c3884d3a12a89e7078c83853d9b7912281cefb14bac00a2737d33a000000008a473044022032aa926c8f290e27e29eb448e95341e9910fb84a6925c80b9146201bca9c009b022034f32af576303809d6d78a7f14332d3e0afefbc6cc98a04b1aeeb4cc0e28282102410431393af9984375830971ab5d3094c6a7d02db3568b2b06212a7090094549701bbb9e84d9477451acc42638963635899ce91bacb451a1bb6da73ddfbcf596bddfffffffff01400001000000000017a9141a8b0026343166625c7475f01e48b5ede8c0252e8700000000"},
+		//SIGHASH_ALL is the default for P2PKH inputs
+		{"all", "0100000001acc6fb9ec2c3884d3a12a89e7078c83853d9b7912281cefb14bac00a2737d33a000000008a47304402201974e077efd647cb374e8b5c08bcc83bbf87224673af102215d319353aadb1c7022074c08e6a2f682602f6a50426c7397e8976316215d35b774dbfee21e73ef38bb501410431393af9984375830971ab5d3094c6a7d02db3568b2b06212a7090094549701bbb9e84d9477451acc42638963635899ce91bacb451a1bb6da73ddfbcf596bddfffffffff01400001000000000017a9141a8b0026343166625c7475f01e48b5ede8c0252e8700000000"},
+	}
+
+	for _, testCase := range testCases {
+		finalTransactionHex, _, _, err := generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, Amount: testAmount, Destinations: []string{testP2SHDestination}, TxFlags: TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: 3000, MaxFee: 1000000, SigHash: testCase.sigHash, OutputFormat: "hex", Network: "mainnet"}})
+		if err != nil {
+			t.Error(err)
+		}
+		if finalTransactionHex != testCase.finalTransactionHex {
+			testutils.CompareError(t, "Generated funding transaction different from expected transaction.", testCase.finalTransactionHex, finalTransactionHex)
+		}
+	}
+
+	if _, _, _, err := generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, Amount: testAmount, Destinations: []string{testP2SHDestination}, TxFlags: TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: 3000, MaxFee: 1000000, SigHash: "alll", OutputFormat: "hex", Network: "mainnet"}}); err == nil {
+		t.Error("generateFund accepting invalid --sighash.")
+	}
+}
+
+func TestGenerateFundLockTime(t *testing.T) {
+	//Expected transactions computed independently, with the lock time and sequence committed to by the signature
+	previousSetFixedNonce := btcutils.SetFixedNonce
+	btcutils.SetFixedNonce = false
+	defer func() { btcutils.SetFixedNonce = previousSetFixedNonce }()
+	testPrivateKeyWIF := "5JJyqG4bb15zqi7fTA4b227aUxQhBo1Ux6qX69ngeXYLr7fk2hs"
+	testInputTx := "3ad337270ac0ba14fbce812291b7d95338c878709ea8123a4d88c3c29efbc6ac"
+	testAmount := 65600
+	testP2SHDestination := "347N1Thc213QqfYCz3PZkjoJpNv5b14kBd"
+	testCases := []struct {
+		lockTime            int64
+		sequence            int64
+		rbf                 bool
+		finalTransactionHex string
+	}{
+		//Block height lock time, with the final sequence lowered to 0xfffffffe so the lock time is enforced
+		{840000, 0xffffffff, false, "0100000001acc6fb9ec2c3884d3a12a89e7078c83853d9b7912281cefb14bac00a2737d33a000000008a47304402203ef74440a3f449ce516e9098c8574ce46e83c454b9fb487b7b965d4fe7cbdd6602205bee8a94a2b28ba5e38b47f53b4054901c3f7dbe929b30d7cb7b1ef32384e65e01410431393af9984375830971ab5d3094c6a7d02db3568b2b06212a7090094549701bbb9e84d9477451acc42638963635899ce91bacb451a1bb6da73ddfbcf596bddffeffffff01400001000000000017a9141a8b0026343166625c7475f01e48b5ede8c0252e8740d10c00"},
+		//Unix timestamp lock time of 2025-01-01, with the given sequence
+		{1735689600, 0xfffffffd, false, "0100000001acc6fb9ec2c3884d3a12a89e7078c83853d9b7912281cefb14bac00a2737d33a000000008a473044022043d69c9246e6e631640f0337c0792bc4b78bc1b32eede1545999ea05906f8ee502200372a430007f80d9d08ecc125fcf2d9cdfcfaa15a4815bfa7aa453b7d0e0af4c01410431393af9984375830971ab5d3094c6a7d02db3568b2b06212a7090094549701bbb9e84d9477451acc42638963635899ce91bacb451a1bb6da73ddfbcf596bddffdffffff01400001000000000017a9141a8b0026343166625c7475f01e48b5ede8c0252e8780857467"},
+		//No lock time with --rbf, replacing the final sequence with the BIP125 sequence 0xfffffffd
+		{0, 0xffffffff, true, "0100000001acc6fb9ec2c3884d3a12a89e7078c83853d9b7912281cefb14bac00a2737d33a000000008a47304402201f0c76aa7fcc3a8318c2777d61c8b33e93d4247bf5acc153fa3ed05f1dbe44bb022005b81a1c182f09eb5f63d99df601febb090160b20fddb7649b3203a767a9d64701410431393af9984375830971ab5d3094c6a7d02db3568b2b06212a7090094549701bbb9e84d9477451acc42638963635899ce91bacb451a1bb6da73ddfbcf596bddffdffffff01400001000000000017a9141a8b0026343166625c7475f01e48b5ede8c0252e8700000000"},
+	}
+
+	for _, testCase := range testCases {
+		finalTransactionHex, _, _, err := generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, Amount: testAmount, Destinations: []string{testP2SHDestination}, TxFlags: TxFlags{LockTime: testCase.lockTime, Sequence: testCase.sequence, RBF: testCase.rbf, TxVersion: 1, DustRelayFee: 3000, MaxFee: 1000000, SigHash: "default", OutputFormat: "hex", Network: "mainnet"}})
+		if err != nil {
+			t.Error(err)
+		}
+		if finalTransactionHex != testCase.finalTransactionHex {
+			testutils.CompareError(t, "Generated funding transaction different from expected transaction.", testCase.finalTransactionHex, finalTransactionHex)
+		}
+	}
+
+	invalidLockTimes := []struct {
+		lockTime int64
+		sequence int64
+	}{
+		{-1, 0xffffffff},
+		//Timestamp in milliseconds
+		{1735689600000, 0xffffffff},
+		//Timestamp with a digit missing, too far in the future for a block height
+		{173568960, 0xffffffff},
+		{840000, -1},
+		{840000, 0x100000000},
+	}
+	for _, invalidLockTime := range invalidLockTimes {
+		if _, _, _, err := generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, Amount: testAmount, Destinations: []string{testP2SHDestination}, TxFlags: TxFlags{LockTime: invalidLockTime.lockTime, Sequence: invalidLockTime.sequence, TxVersion: 1, DustRelayFee: 3000, MaxFee: 1000000, SigHash: "default", OutputFormat: "hex", Network: "mainnet"}}); err == nil {
+			t.Errorf("generateFund accepting --locktime %d with --sequence %d.", invalidLockTime.lockTime, invalidLockTime.sequence)
+		}
+	}
 }
@@ -2,18 +2,17 @@
 package multisig
 
 import (
-	"github.com/prettymuchbryce/hellobitcoin/base58check"
 	"github.com/CryptoProcessing/go-bitcoin-multisig/btcutils"
 
 	"encoding/hex"
+	"errors"
 	"fmt"
-	"log"
 )
 
 //OutputKeys formats and prints relevant outputs to the user.
-func OutputKeys(flagKeyCount int, flagConcise bool) {
+func OutputKeys(flagKeyCount int, flagConcise bool, flagCompressed bool, flagNetwork string) error {
 	if flagKeyCount < 1 || flagKeyCount > 100 {
-		log.Fatal("--count <count> must be between 1 and 100")
+		return errors.New("--count <count> must be between 1 and 100")
 	}
 
 	if !flagConcise {
@@ -27,7 +26,10 @@ func OutputKeys(flagKeyCount int, flagConcise bool) {
 		fmt.Println("----------------------------------------------------------------------")
 	}
 
-	privateKeyWIFs, publicKeyHexs, publicAddresses := generateKeys(flagKeyCount)
+	privateKeyWIFs, publicKeyHexs, publicAddresses, err := generateKeys(flagKeyCount, flagCompressed, flagNetwork)
+	if err != nil {
+		return err
+	}
 
 	for i := 0; i <= flagKeyCount-1; i++ {
 
@@ -51,35 +53,52 @@ func OutputKeys(flagKeyCount int, flagConcise bool) {
 		fmt.Println(publicAddresses[i])
 		fmt.Println("-------------------------------------------------------------")
 	}
+	return nil
 }
 
 // generateKeys is the high-level logic for generating public/private key pairs with the 'go-bitcoin-multisig keys' subcommand.
-// Takes flagCount (desired number of key pairs) and flagConcise (true hides warnings and helpful messages for conciseness)
-// as arguments.
-func generateKeys(flagKeyCount int) ([]string, []string, []string) {
+// Takes flagCount (desired number of key pairs), flagCompressed (use the 33 byte compressed public key, as modern
+// wallets do, and mark the WIF private key as compressed) and flagNetwork (name of the network to encode addresses
+// and private keys for) as arguments.
+func generateKeys(flagKeyCount int, flagCompressed bool, flagNetwork string) ([]string, []string, []string, error) {
+	params, err := btcutils.NetworkParamsByName(flagNetwork)
+	if err != nil {
+		return nil, nil, nil, err
+	}
 	publicKeyHexs := make([]string, flagKeyCount)
 	publicAddresses := make([]string, flagKeyCount)
 	privateKeyWIFs := make([]string, flagKeyCount)
 
 	for i := 0; i <= flagKeyCount-1; i++ {
 		//Generate private key
-		privateKey := btcutils.NewPrivateKey()
+		privateKey, err := btcutils.NewPrivateKey()
+		if err != nil {
+			return nil, nil, nil, err
+		}
 		//Generate public key from private key
-		publicKey, err := btcutils.NewPublicKey(privateKey)
+		var publicKey []byte
+		if flagCompressed {
+			publicKey, err = btcutils.NewCompressedPublicKey(privateKey)
+		} else {
+			publicKey, err = btcutils.NewPublicKey(privateKey)
+		}
 		if err != nil {
-			log.Fatal(err)
+			return nil, nil, nil, err
 		}
 		//Get hex encoded version of public key
 		publicKeyHexs[i] = hex.EncodeToString(publicKey)
-		//Get public address by hashing with SHA256 and RIPEMD160 and base58 encoding with mainnet prefix 00
-		publicKeyHash, err := btcutils.Hash160(publicKey)
+		//Get public address by hashing with SHA256 and RIPEMD160 and base58 encoding with network P2PKH prefix
+		publicAddresses[i], err = btcutils.PublicKeyToP2PKHAddress(publicKey, params)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		//Get private key in Wallet Import Format (WIF) by base58 encoding with network WIF prefix.
+		//Compressed keys are suffixed with 0x01 so wallets derive the compressed public key and address.
+		privateKeyWIFs[i], err = btcutils.EncodeWIF(privateKey, flagCompressed, params)
 		if err != nil {
-			log.Fatal(err)
+			return nil, nil, nil, err
 		}
-		publicAddresses[i] = base58check.Encode("00", publicKeyHash)
-		//Get private key in Wallet Import Format (WIF) by base58 encoding with prefix 80
-		privateKeyWIFs[i] = base58check.Encode("80", privateKey)
 	}
 
-	return privateKeyWIFs, publicKeyHexs, publicAddresses
+	return privateKeyWIFs, publicKeyHexs, publicAddresses, nil
 }
@@ -3,12 +3,16 @@ package multisig
 import (
 	"github.com/CryptoProcessing/go-bitcoin-multisig/btcutils"
 
+	"bytes"
 	"encoding/hex"
 	"testing"
 )
 
 func TestGenerateKeys(t *testing.T) {
-	privateKeyWIFs, publicKeyHexs, publicAddresses := generateKeys(1)
+	privateKeyWIFs, publicKeyHexs, publicAddresses, err := generateKeys(1, false, "mainnet")
+	if err != nil {
+		t.Fatal(err)
+	}
 	publicKey, err := hex.DecodeString(publicKeyHexs[0])
 	if err != nil {
 		t.Error(err)
@@ -17,6 +21,9 @@ func TestGenerateKeys(t *testing.T) {
 	if err != nil {
 		t.Error(err)
 	}
+	if len(publicKey) != 65 {
+		t.Error("Generated public key is wrong length. Should be 65 bytes long for uncompressed public key.")
+	}
 	if privateKeyWIFs[0] == "" {
 		t.Error("Generated private key cannot be empty.")
 	}
@@ -36,4 +43,81 @@ func TestGenerateKeys(t *testing.T) {
 	if publicAddresses[0][0:1] != "1" {
 		t.Error("Generated public address has wrong prefix. Should be '5' for mainnet P2PKH addresses.")
 	}
+	checkGeneratedKey(t, privateKeyWIFs[0], publicKey, publicAddresses[0], false, &btcutils.MainNetParams)
+
+	//Testnet3 keys and addresses
+	privateKeyWIFs, _, publicAddresses, err = generateKeys(1, false, "testnet3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if privateKeyWIFs[0][0:1] != "9" {
+		t.Error("Generated private key has wrong prefix. Should be '9' for testnet private key.")
+	}
+	if publicAddresses[0][0:1] != "m" && publicAddresses[0][0:1] != "n" {
+		t.Error("Generated public address has wrong prefix. Should be 'm' or 'n' for testnet P2PKH addresses.")
+	}
+}
+
+func TestGenerateKeysCompressed(t *testing.T) {
+	privateKeyWIFs, publicKeyHexs, publicAddresses, err := generateKeys(1, true, "mainnet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey, err := hex.DecodeString(publicKeyHexs[0])
+	if err != nil {
+		t.Error(err)
+	}
+	if len(publicKey) != 33 || (publicKey[0] != 0x02 && publicKey[0] != 0x03) {
+		t.Error("Generated public key should be a 33 byte compressed public key starting with 0x02 or 0x03.")
+	}
+	if len(privateKeyWIFs[0]) != 52 {
+		t.Error("Generated private key is wrong length. Should be 52 characters long for compressed private key.")
+	}
+	if privateKeyWIFs[0][0:1] != "K" && privateKeyWIFs[0][0:1] != "L" {
+		t.Error("Generated private key has wrong prefix. Should be 'K' or 'L' for mainnet compressed private key.")
+	}
+	checkGeneratedKey(t, privateKeyWIFs[0], publicKey, publicAddresses[0], true, &btcutils.MainNetParams)
+
+	//Testnet3 keys and addresses
+	privateKeyWIFs, publicKeyHexs, publicAddresses, err = generateKeys(1, true, "testnet3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if privateKeyWIFs[0][0:1] != "c" {
+		t.Error("Generated private key has wrong prefix. Should be 'c' for testnet compressed private key.")
+	}
+	publicKey, _ = hex.DecodeString(publicKeyHexs[0])
+	checkGeneratedKey(t, privateKeyWIFs[0], publicKey, publicAddresses[0], true, &btcutils.TestNet3Params)
+}
+
+// checkGeneratedKey checks a generated WIF private key decodes with the expected compression, and that its public
+// key and P2PKH address match the generated ones.
+func checkGeneratedKey(t *testing.T, privateKeyWIF string, publicKey []byte, publicAddress string, compressed bool, params *btcutils.NetworkParams) {
+	privateKey, wifCompressed, err := btcutils.ParseWIF(privateKeyWIF, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wifCompressed != compressed {
+		t.Errorf("Generated private key compression is %t. Should be %t.", wifCompressed, compressed)
+	}
+	var expectedPublicKey []byte
+	if compressed {
+		expectedPublicKey, err = btcutils.NewCompressedPublicKey(privateKey)
+	} else {
+		expectedPublicKey, err = btcutils.NewPublicKey(privateKey)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(publicKey, expectedPublicKey) {
+		t.Error("Generated public key does not match generated private key.")
+	}
+	version, publicKeyHash, err := btcutils.DecodeAddress(publicAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedPublicKeyHash, _ := btcutils.Hash160(publicKey)
+	if version != params.P2PKHVersion || !bytes.Equal(publicKeyHash, expectedPublicKeyHash) {
+		t.Error("Generated public address does not match generated public key.")
+	}
 }
@@ -0,0 +1,142 @@
+// psbt.go - Exporting transactions as BIP174 PSBTs, for cosigners to sign in other wallets.
+package multisig
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/btcutils"
+	"github.com/CryptoProcessing/go-bitcoin-multisig/psbt"
+
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// checkOutputFormat validates flagOutputFormat, which is hex for a signed raw transaction, or psbt for a BIP174 PSBT.
+func checkOutputFormat(flagOutputFormat string) error {
+	if flagOutputFormat != "hex" && flagOutputFormat != "psbt" {
+		return fmt.Errorf("Unknown output format %s. Use hex or psbt.", flagOutputFormat)
+	}
+	return nil
+}
+
+// generatePSBT returns the base64 encoded BIP174 PSBT of tx, whose single input spends utxo, in place of the signed
+// transaction. The PSBT input holds what other signers need instead of a scriptSig and witness: the UTXO, the
+// redeemScript and witnessScript of the input, if any, and hashType.
+// SegWit inputs record utxo as the witness UTXO, whose amount BIP143 and BIP341 signatures commit to. Legacy
+// signatures do not commit to the amount, so signers check it against the whole previous transaction prevTx, as
+// decoded by decodePrevTx, which is required for legacy inputs. It is also recorded for SegWit inputs if given.
+// Each of privateKeys adds its signature to the PSBT, so the cosigners holding the remaining keys of a multisig only
+// need to add theirs before finalizing it.
+func generatePSBT(tx *btcutils.Transaction, utxo btcutils.Output, prevTx *btcutils.Transaction, redeemScript []byte, witnessScript []byte, hashType btcutils.SigHashType, privateKeys [][]byte) (string, error) {
+	//The unsigned transaction of a PSBT has empty scriptSigs and witnesses
+	unsignedTx := *tx
+	unsignedTx.Inputs = make([]btcutils.Input, len(tx.Inputs))
+	for i, input := range tx.Inputs {
+		input.ScriptSig = nil
+		input.Witness = nil
+		unsignedTx.Inputs[i] = input
+	}
+	p, err := psbt.New(&unsignedTx)
+	if err != nil {
+		return "", err
+	}
+	input := &p.Inputs[0]
+	//Nested SegWit outputs pay to the P2SH of a witness program redeemScript
+	segWit := btcutils.IsWitnessScriptPubKey(utxo.ScriptPubKey) || btcutils.IsWitnessScriptPubKey(redeemScript)
+	if prevTx != nil {
+		prevOutput := prevTx.Outputs[unsignedTx.Inputs[0].OutputIndex]
+		if !bytes.Equal(prevOutput.ScriptPubKey, utxo.ScriptPubKey) {
+			return "", fmt.Errorf("Output %d of --prev-tx does not pay to the keys or script being spent.", unsignedTx.Inputs[0].OutputIndex)
+		}
+		if segWit && prevOutput.Satoshis != utxo.Satoshis {
+			return "", fmt.Errorf("--input-amount (%d) is different from the %d satoshis held by the output of --prev-tx.", utxo.Satoshis, prevOutput.Satoshis)
+		}
+		input.NonWitnessUtxo = prevTx
+	} else if !segWit {
+		return "", errors.New("--prev-tx is required for a PSBT spending a legacy input, as signers check the amount it spends against the previous transaction.")
+	}
+	if segWit {
+		input.WitnessUtxo = &utxo
+	}
+	input.RedeemScript = redeemScript
+	input.WitnessScript = witnessScript
+	input.SigHashType = hashType
+	for i, privateKey := range privateKeys {
+		err = psbt.Sign(p, 0, privateKey, nil)
+		if err != nil {
+			return "", fmt.Errorf("Cannot sign PSBT with private key %d. %s", i+1, err)
+		}
+	}
+	return p.SerializeBase64()
+}
+
+// checkPrevTxKey checks, before signing, that the output input spends in the previous transaction prevTx can be
+// spent with publicKey or script, as btcutils.CheckInputKey does.
+func checkPrevTxKey(prevTx *btcutils.Transaction, input btcutils.Input, publicKey []byte, script []byte, params *btcutils.NetworkParams) error {
+	return btcutils.CheckInputKey(input, prevTx.Outputs[input.OutputIndex].ScriptPubKey, publicKey, script, params)
+}
+
+// prevTxInputAmount returns the amount held by the output input spends, taken from the previous transaction prevTx
+// if given, so --input-amount can be left out. Without prevTx flagInputAmount is returned as it is.
+// Returns an error if flagInputAmount is given and different from the amount of the output.
+func prevTxInputAmount(prevTx *btcutils.Transaction, input btcutils.Input, flagInputAmount int) (int, error) {
+	if prevTx == nil {
+		return flagInputAmount, nil
+	}
+	amount := int(prevTx.Outputs[input.OutputIndex].Satoshis)
+	if flagInputAmount != 0 && flagInputAmount != amount {
+		return 0, fmt.Errorf("--input-amount (%d) is different from the %d satoshis held by output %d of --prev-tx.", flagInputAmount, amount, input.OutputIndex)
+	}
+	return amount, nil
+}
+
+// decodePrevTx decodes the raw hex previous transaction flagPrevTx, or the hex in the file named after an @, checking
+// it is the transaction input spends an output of, and that it has the output. Returns nil without flagPrevTx.
+// The subcommands decode it once, right after parsing the input, for prevTxInputAmount, checkPrevTxKey and
+// generatePSBT.
+func decodePrevTx(flagPrevTx string, input btcutils.Input) (*btcutils.Transaction, error) {
+	if flagPrevTx == "" {
+		return nil, nil
+	}
+	if strings.HasPrefix(flagPrevTx, "@") {
+		data, err := ioutil.ReadFile(flagPrevTx[1:])
+		if err != nil {
+			return nil, fmt.Errorf("Cannot read --prev-tx. %s", err)
+		}
+		flagPrevTx = string(data)
+	}
+	rawTx, err := hex.DecodeString(strings.TrimSpace(flagPrevTx))
+	if err != nil {
+		return nil, fmt.Errorf("Invalid --prev-tx. %s", err)
+	}
+	prevTx, err := btcutils.DeserializeTransaction(rawTx)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid --prev-tx. %s", err)
+	}
+	txID, err := btcutils.TxID(rawTx)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(txID, input.TxHash) {
+		return nil, fmt.Errorf("--prev-tx has transaction ID %s, not the input transaction %s.", txID, input.TxHash)
+	}
+	if int(input.OutputIndex) >= len(prevTx.Outputs) {
+		return nil, fmt.Errorf("--prev-tx has %d outputs, so no output %d to spend.", len(prevTx.Outputs), input.OutputIndex)
+	}
+	return prevTx, nil
+}
+
+// printPSBT prints the base64 PSBT output in place of a signed transaction with --output-format=psbt.
+func printPSBT(psbtBase64 string) {
+	fmt.Printf(`
+-----------------------------------------------------------------------------------------------------------------------------------
+Your PSBT (BIP174) is:
+%v
+Import it into a wallet such as Bitcoin Core or Sparrow for any remaining cosigners to sign, then finalize and broadcast it.
+-----------------------------------------------------------------------------------------------------------------------------------
+`,
+		psbtBase64,
+	)
+}
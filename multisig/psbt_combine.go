@@ -0,0 +1,43 @@
+// psbt_combine.go - Merging copies of a PSBT signed by different cosigners, as a BIP174 combiner.
+package multisig
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/psbt"
+
+	"errors"
+	"fmt"
+)
+
+// OutputPSBTCombine formats and prints relevant outputs to the user.
+func OutputPSBTCombine(flagPSBTs []string) error {
+	psbtBase64, err := generatePSBTCombine(flagPSBTs)
+	if err != nil {
+		return err
+	}
+	printPSBT(psbtBase64)
+	return nil
+}
+
+// generatePSBTCombine is the high-level logic for combining PSBTs with the 'go-bitcoin-multisig psbt-combine'
+// subcommand. Takes flagPSBTs (base64 PSBTs of the same unsigned transaction, such as the copies signed by each
+// cosigner with psbt-sign) as argument.
+// Returns the base64 PSBT holding the signatures, scripts and BIP32 derivations of all of them, or an error if the
+// unsigned transactions are different or a field has different values in two PSBTs.
+func generatePSBTCombine(flagPSBTs []string) (string, error) {
+	if len(flagPSBTs) < 2 {
+		return "", errors.New("At least two --psbt are needed to combine.")
+	}
+	psbts := make([]*psbt.PSBT, len(flagPSBTs))
+	for i, flagPSBT := range flagPSBTs {
+		p, err := psbt.DeserializeBase64(flagPSBT)
+		if err != nil {
+			return "", fmt.Errorf("Invalid --psbt %d. %s", i+1, err)
+		}
+		psbts[i] = p
+	}
+	combined, err := psbt.CombinePSBTs(psbts)
+	if err != nil {
+		return "", err
+	}
+	return combined.SerializeBase64()
+}
@@ -0,0 +1,63 @@
+package multisig
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/btcutils"
+	"github.com/CryptoProcessing/go-bitcoin-multisig/psbt"
+	"github.com/CryptoProcessing/go-bitcoin-multisig/testutils"
+
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestGeneratePSBTCombine(t *testing.T) {
+	btcutils.SetFixedNonce = true
+	//2-of-3 P2SH multisig PSBT signed independently by two cosigners with the spend subcommand
+	testPrivateKeys := []string{"KzVTBhbMaKrAYagJ11VdTaBrb6yzLykLGyuMBkf9sCFPDxdT8shL", "L4euB5ik61Qcp67f5DLo3jiF2uumTMMTa6i8ys2SW82g8PzNJZTc"}
+	testDestination := "1DJrhysUSzjNhP1GYJkgQkkEtCTgnnEWXi"
+	testRedeemScriptHex := "5221025476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee63572103b6446545566e0fecb2f2243db709bbf4e9fff78d48c9438acc911a36015e896a21033d63026f5f0de46e40dc09c61c1559240ea4381df7e05045f6ae2ac506efbaf253ae"
+	testRedeemScript, _ := hex.DecodeString(testRedeemScriptHex)
+	testRedeemScriptHash, _ := btcutils.Hash160(testRedeemScript)
+	testScriptPubKey, _ := btcutils.NewP2SHScriptPubKey(testRedeemScriptHash)
+	testPrevTxHex, testInputTx := testPrevTx(t, testScriptPubKey, 95000)
+	var testPSBTs []string
+	for _, privateKey := range testPrivateKeys {
+		testPSBT, _, err := generateSpend(&SpendFlags{PrivateKeys: privateKey, Destination: testDestination, RedeemScript: testRedeemScriptHex, InputTx: testInputTx, InputIndex: 1, Amount: 90000, TxFlags: TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: 3000, MaxFee: 1000000, SigHash: "default", OutputFormat: "psbt", PrevTx: testPrevTxHex, Network: "mainnet"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		testPSBTs = append(testPSBTs, testPSBT)
+	}
+
+	psbtBase64, err := generatePSBTCombine(testPSBTs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := psbt.DeserializeBase64(psbtBase64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	finalTransactionHex, _, err := generateSpend(&SpendFlags{PrivateKeys: strings.Join(testPrivateKeys, ","), Destination: testDestination, RedeemScript: testRedeemScriptHex, InputTx: testInputTx, InputIndex: 1, Amount: 90000, TxFlags: testTxFlags})
+	if err != nil {
+		t.Fatal(err)
+	}
+	finalizedHex := testFinalizePSBT(t, p)
+	if finalizedHex != finalTransactionHex {
+		testutils.CompareError(t, "Finalized combined PSBT different from signed spend transaction.", finalTransactionHex, finalizedHex)
+	}
+
+	if _, err := generatePSBTCombine(testPSBTs[:1]); err == nil {
+		t.Error("generatePSBTCombine accepting a single PSBT.")
+	}
+	if _, err := generatePSBTCombine([]string{testPSBTs[0], "not a PSBT"}); err == nil {
+		t.Error("generatePSBTCombine accepting invalid base64 PSBT.")
+	}
+	//Same input, different amount sent
+	otherPSBT, _, err := generateSpend(&SpendFlags{PrivateKeys: testPrivateKeys[1], Destination: testDestination, RedeemScript: testRedeemScriptHex, InputTx: testInputTx, InputIndex: 1, Amount: 89000, TxFlags: TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: 3000, MaxFee: 1000000, SigHash: "default", OutputFormat: "psbt", PrevTx: testPrevTxHex, Network: "mainnet"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := generatePSBTCombine([]string{testPSBTs[0], otherPSBT}); err == nil {
+		t.Error("generatePSBTCombine accepting PSBTs with different unsigned transactions.")
+	}
+}
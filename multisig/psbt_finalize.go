@@ -0,0 +1,86 @@
+// psbt_finalize.go - Finalizing a fully signed PSBT and extracting the transaction to broadcast, as the BIP174
+// finalizer and extractor.
+package multisig
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/psbt"
+
+	"encoding/hex"
+	"fmt"
+)
+
+// OutputPSBTFinalize formats and prints relevant outputs to the user.
+func OutputPSBTFinalize(flagPSBT string) error {
+	psbtBase64, err := generatePSBTFinalize(flagPSBT)
+	if err != nil {
+		return err
+	}
+	fmt.Printf(`
+-----------------------------------------------------------------------------------------------------------------------------------
+Your finalized PSBT is:
+%v
+Extract the transaction to broadcast with psbt-extract.
+-----------------------------------------------------------------------------------------------------------------------------------
+`,
+		psbtBase64,
+	)
+	return nil
+}
+
+// generatePSBTFinalize is the high-level logic for finalizing a PSBT with the 'go-bitcoin-multisig psbt-finalize'
+// subcommand. Takes flagPSBT (base64 PSBT with enough partial signatures for each input) as argument.
+// The partial signatures of each input are turned into its final scriptSig or witness, in the order of the public
+// keys of a multisig redeemScript or witnessScript, and the fields no longer needed are removed from the input.
+// Returns the finalized PSBT in base64, or an error naming the public keys missing a valid signature if a multisig
+// input has fewer than M.
+func generatePSBTFinalize(flagPSBT string) (string, error) {
+	p, err := psbt.DeserializeBase64(flagPSBT)
+	if err != nil {
+		return "", fmt.Errorf("Invalid --psbt. %s", err)
+	}
+	err = psbt.Finalize(p)
+	if err != nil {
+		return "", err
+	}
+	return p.SerializeBase64()
+}
+
+// OutputPSBTExtract formats and prints relevant outputs to the user.
+func OutputPSBTExtract(flagPSBT string) error {
+	finalTransactionHex, err := generatePSBTExtract(flagPSBT)
+	if err != nil {
+		return err
+	}
+	fmt.Printf(`
+-----------------------------------------------------------------------------------------------------------------------------------
+Your raw transaction is:
+%v
+Broadcast this transaction to spend the inputs of the PSBT.
+-----------------------------------------------------------------------------------------------------------------------------------
+`,
+		finalTransactionHex,
+	)
+	printTransactionIDs(finalTransactionHex)
+	printScripts(finalTransactionHex)
+	return nil
+}
+
+// generatePSBTExtract is the high-level logic for extracting the transaction of a PSBT with the
+// 'go-bitcoin-multisig psbt-extract' subcommand. Takes flagPSBT (base64 PSBT finalized with psbt-finalize) as
+// argument.
+// Returns the signed transaction in hex, ready to broadcast, or an error if an input of the PSBT is not finalized.
+func generatePSBTExtract(flagPSBT string) (string, error) {
+	p, err := psbt.DeserializeBase64(flagPSBT)
+	if err != nil {
+		return "", fmt.Errorf("Invalid --psbt. %s", err)
+	}
+	tx, err := psbt.Extract(p)
+	if err != nil {
+		return "", fmt.Errorf("%s Finalize the PSBT with psbt-finalize first.", err)
+	}
+	finalTransaction, err := tx.Serialize()
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(finalTransaction), nil
+}
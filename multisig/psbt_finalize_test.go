@@ -0,0 +1,64 @@
+package multisig
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/btcutils"
+	"github.com/CryptoProcessing/go-bitcoin-multisig/testutils"
+
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestGeneratePSBTFinalizeExtract(t *testing.T) {
+	btcutils.SetFixedNonce = true
+	//2-of-3 P2SH multisig PSBT signed by the first cosigner with the spend subcommand, then by the second
+	testPrivateKeys := []string{"KzVTBhbMaKrAYagJ11VdTaBrb6yzLykLGyuMBkf9sCFPDxdT8shL", "L4euB5ik61Qcp67f5DLo3jiF2uumTMMTa6i8ys2SW82g8PzNJZTc"}
+	testDestination := "1DJrhysUSzjNhP1GYJkgQkkEtCTgnnEWXi"
+	testRedeemScriptHex := "5221025476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee63572103b6446545566e0fecb2f2243db709bbf4e9fff78d48c9438acc911a36015e896a21033d63026f5f0de46e40dc09c61c1559240ea4381df7e05045f6ae2ac506efbaf253ae"
+	testRedeemScript, _ := hex.DecodeString(testRedeemScriptHex)
+	testRedeemScriptHash, _ := btcutils.Hash160(testRedeemScript)
+	testScriptPubKey, _ := btcutils.NewP2SHScriptPubKey(testRedeemScriptHash)
+	testPrevTxHex, testInputTx := testPrevTx(t, testScriptPubKey, 95000)
+	testPSBT, _, err := generateSpend(&SpendFlags{PrivateKeys: testPrivateKeys[0], Destination: testDestination, RedeemScript: testRedeemScriptHex, InputTx: testInputTx, InputIndex: 1, Amount: 90000, TxFlags: TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: 3000, MaxFee: 1000000, SigHash: "default", OutputFormat: "psbt", PrevTx: testPrevTxHex, Network: "mainnet"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//One of the two signatures needed, missing from the second and third public keys
+	_, err = generatePSBTFinalize(testPSBT)
+	if err == nil {
+		t.Error("generatePSBTFinalize accepting 2-of-3 multisig PSBT with one signature.")
+	} else if !strings.Contains(err.Error(), "03b6446545566e0fecb2f2243db709bbf4e9fff78d48c9438acc911a36015e896a") || !strings.Contains(err.Error(), "033d63026f5f0de46e40dc09c61c1559240ea4381df7e05045f6ae2ac506efbaf2") {
+		t.Errorf("generatePSBTFinalize error not naming the public keys missing a signature. %s", err)
+	}
+	if _, err := generatePSBTExtract(testPSBT); err == nil {
+		t.Error("generatePSBTExtract accepting PSBT that is not finalized.")
+	}
+
+	signedPSBT, _, err := generatePSBTSign(testPSBT, testPrivateKeys[1], "default", "mainnet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	finalizedPSBT, err := generatePSBTFinalize(signedPSBT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	extractedHex, err := generatePSBTExtract(finalizedPSBT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	finalTransactionHex, _, err := generateSpend(&SpendFlags{PrivateKeys: strings.Join(testPrivateKeys, ","), Destination: testDestination, RedeemScript: testRedeemScriptHex, InputTx: testInputTx, InputIndex: 1, Amount: 90000, TxFlags: testTxFlags})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if extractedHex != finalTransactionHex {
+		testutils.CompareError(t, "Extracted transaction different from signed spend transaction.", finalTransactionHex, extractedHex)
+	}
+
+	if _, err := generatePSBTFinalize("not a PSBT"); err == nil {
+		t.Error("generatePSBTFinalize accepting invalid base64 PSBT.")
+	}
+	if _, err := generatePSBTExtract("not a PSBT"); err == nil {
+		t.Error("generatePSBTExtract accepting invalid base64 PSBT.")
+	}
+}
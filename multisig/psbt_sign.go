@@ -0,0 +1,97 @@
+// psbt_sign.go - Adding signatures to a PSBT created elsewhere, as a BIP174 signer.
+package multisig
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/btcutils"
+	"github.com/CryptoProcessing/go-bitcoin-multisig/psbt"
+
+	"errors"
+	"fmt"
+)
+
+// OutputPSBTSign formats and prints relevant outputs to the user.
+func OutputPSBTSign(flagPSBT string, flagPrivateKeys string, flagSigHash string, flagNetwork string) error {
+	psbtBase64, signedInputs, err := generatePSBTSign(flagPSBT, flagPrivateKeys, flagSigHash, flagNetwork)
+	if err != nil {
+		return err
+	}
+	fmt.Printf(`
+-----------------------------------------------------------------------------------------------------------------------------------
+Signed PSBT inputs: %v
+-----------------------------------------------------------------------------------------------------------------------------------
+`,
+		signedInputs,
+	)
+	printPSBT(psbtBase64)
+	return nil
+}
+
+// generatePSBTSign is the high-level logic for signing a PSBT with the 'go-bitcoin-multisig psbt-sign' subcommand.
+// Takes flagPSBT (base64 PSBT, such as one from Bitcoin Core's walletcreatefundedpsbt or another cosigner),
+// flagPrivateKeys (comma separated list of private keys to sign with), flagSigHash (name of the hash type to sign
+// with, or default for the hash type of each input) and flagNetwork (name of the network the keys are encoded for)
+// as arguments.
+// Every input expecting a signature from one of the keys, as psbt.CanSign tells from its UTXO and scripts, gets a
+// partial signature with the BIP143 signature hash for SegWit inputs and the legacy one otherwise. Inputs that are
+// already finalized are left as they are. Returns the updated PSBT in base64 and the indexes of the inputs signed.
+// Refuses to sign if an input has no UTXO, or its hash type is different from a hash type other than default given
+// with flagSigHash, which is used for inputs without a hash type instead.
+func generatePSBTSign(flagPSBT string, flagPrivateKeys string, flagSigHash string, flagNetwork string) (string, []int, error) {
+	p, err := psbt.DeserializeBase64(flagPSBT)
+	if err != nil {
+		return "", nil, fmt.Errorf("Invalid --psbt. %s", err)
+	}
+	hashType, err := btcutils.ParseSigHashType(flagSigHash)
+	if err != nil {
+		return "", nil, err
+	}
+	params, err := btcutils.NetworkParamsByName(flagNetwork)
+	if err != nil {
+		return "", nil, err
+	}
+	privateKeys, err := parsePrivateKeys(flagPrivateKeys, params)
+	if err != nil {
+		return "", nil, err
+	}
+	var signedInputs []int
+	for i := range p.Inputs {
+		input := &p.Inputs[i]
+		if len(input.FinalScriptSig) > 0 || len(input.FinalScriptWitness) > 0 {
+			continue
+		}
+		signed := false
+		for _, privateKey := range privateKeys {
+			canSign, err := psbt.CanSign(p, i, privateKey)
+			if err != nil {
+				return "", nil, fmt.Errorf("Cannot sign PSBT input %d. %s", i, err)
+			}
+			if !canSign {
+				continue
+			}
+			//The hash type of the PSBT is what the other signers agreed to, so it is only set if missing
+			if flagSigHash != "default" {
+				if input.SigHashType == 0 {
+					input.SigHashType = hashType
+				} else if input.SigHashType != hashType {
+					return "", nil, fmt.Errorf("PSBT input %d has hash type 0x%02x, not the --sighash hash type 0x%02x.", i, uint32(input.SigHashType), uint32(hashType))
+				}
+			}
+			err = psbt.Sign(p, i, privateKey, nil)
+			if err != nil {
+				return "", nil, fmt.Errorf("Cannot sign PSBT input %d. %s", i, err)
+			}
+			signed = true
+		}
+		if signed {
+			signedInputs = append(signedInputs, i)
+		}
+	}
+	if len(signedInputs) == 0 {
+		return "", nil, errors.New("None of the --private-keys can sign any input of the PSBT.")
+	}
+	psbtBase64, err := p.SerializeBase64()
+	if err != nil {
+		return "", nil, err
+	}
+	return psbtBase64, signedInputs, nil
+}
@@ -0,0 +1,79 @@
+package multisig
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/btcutils"
+	"github.com/CryptoProcessing/go-bitcoin-multisig/psbt"
+	"github.com/CryptoProcessing/go-bitcoin-multisig/testutils"
+
+	"encoding/hex"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGeneratePSBTSign(t *testing.T) {
+	btcutils.SetFixedNonce = true
+	//2-of-3 P2SH multisig PSBT signed by the first cosigner with the spend subcommand, then by the second
+	testPrivateKeys := []string{"KzVTBhbMaKrAYagJ11VdTaBrb6yzLykLGyuMBkf9sCFPDxdT8shL", "L4euB5ik61Qcp67f5DLo3jiF2uumTMMTa6i8ys2SW82g8PzNJZTc"}
+	testDestination := "1DJrhysUSzjNhP1GYJkgQkkEtCTgnnEWXi"
+	testRedeemScriptHex := "5221025476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee63572103b6446545566e0fecb2f2243db709bbf4e9fff78d48c9438acc911a36015e896a21033d63026f5f0de46e40dc09c61c1559240ea4381df7e05045f6ae2ac506efbaf253ae"
+	testRedeemScript, _ := hex.DecodeString(testRedeemScriptHex)
+	testRedeemScriptHash, _ := btcutils.Hash160(testRedeemScript)
+	testScriptPubKey, _ := btcutils.NewP2SHScriptPubKey(testRedeemScriptHash)
+	testPrevTxHex, testInputTx := testPrevTx(t, testScriptPubKey, 95000)
+	testPSBT, _, err := generateSpend(&SpendFlags{PrivateKeys: testPrivateKeys[0], Destination: testDestination, RedeemScript: testRedeemScriptHex, InputTx: testInputTx, InputIndex: 1, Amount: 90000, TxFlags: TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: 3000, MaxFee: 1000000, SigHash: "default", OutputFormat: "psbt", PrevTx: testPrevTxHex, Network: "mainnet"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	psbtBase64, signedInputs, err := generatePSBTSign(testPSBT, testPrivateKeys[1], "all", "mainnet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(signedInputs, []int{0}) {
+		testutils.CompareError(t, "Signed PSBT inputs different from expected inputs.", []int{0}, signedInputs)
+	}
+	p, err := psbt.DeserializeBase64(psbtBase64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	finalTransactionHex, _, err := generateSpend(&SpendFlags{PrivateKeys: strings.Join(testPrivateKeys, ","), Destination: testDestination, RedeemScript: testRedeemScriptHex, InputTx: testInputTx, InputIndex: 1, Amount: 90000, TxFlags: testTxFlags})
+	if err != nil {
+		t.Fatal(err)
+	}
+	finalizedHex := testFinalizePSBT(t, p)
+	if finalizedHex != finalTransactionHex {
+		testutils.CompareError(t, "Finalized PSBT different from signed spend transaction.", finalTransactionHex, finalizedHex)
+	}
+
+	if _, _, err := generatePSBTSign(testPSBT, testPrivateKeys[1], "all|anyonecanpay", "mainnet"); err == nil {
+		t.Error("generatePSBTSign accepting --sighash different from the hash type of the PSBT input.")
+	}
+	if _, _, err := generatePSBTSign(testPSBT, "5JJyqG4bb15zqi7fTA4b227aUxQhBo1Ux6qX69ngeXYLr7fk2hs", "default", "mainnet"); err == nil {
+		t.Error("generatePSBTSign accepting private key that cannot sign any input.")
+	}
+	if _, _, err := generatePSBTSign("not a PSBT", testPrivateKeys[1], "default", "mainnet"); err == nil {
+		t.Error("generatePSBTSign accepting invalid base64 PSBT.")
+	}
+}
+
+func TestGeneratePSBTSignMissingUtxo(t *testing.T) {
+	//A PSBT as created by a coordinator that has not added the UTXO of its input
+	testScriptPubKey, _ := hex.DecodeString("76a914ffe9c0061097cc3b636f2cb0460fa4fc427d2b4588ac")
+	tx := &btcutils.Transaction{
+		Version: 2,
+		Inputs:  []btcutils.Input{{TxHash: strings.Repeat("11", 32), Sequence: btcutils.SequenceFinal}},
+		Outputs: []btcutils.Output{{Satoshis: 90000, ScriptPubKey: testScriptPubKey}},
+	}
+	p, err := psbt.New(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testPSBT, err := p.SerializeBase64()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := generatePSBTSign(testPSBT, "KzVTBhbMaKrAYagJ11VdTaBrb6yzLykLGyuMBkf9sCFPDxdT8shL", "default", "mainnet"); err == nil {
+		t.Error("generatePSBTSign accepting PSBT input without a UTXO.")
+	}
+}
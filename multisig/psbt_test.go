@@ -0,0 +1,240 @@
+package multisig
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/btcutils"
+	"github.com/CryptoProcessing/go-bitcoin-multisig/psbt"
+	"github.com/CryptoProcessing/go-bitcoin-multisig/testutils"
+
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// testPrevTx returns the raw hex and transaction ID of a transaction paying satoshis to scriptPubKey in its second
+// output, standing in for the input transaction of a PSBT.
+func testPrevTx(t *testing.T, scriptPubKey []byte, satoshis uint64) (string, string) {
+	otherScriptPubKey, _ := hex.DecodeString("76a914ffe9c0061097cc3b636f2cb0460fa4fc427d2b4588ac")
+	tx := &btcutils.Transaction{
+		Version:  2,
+		Inputs:   []btcutils.Input{{TxHash: strings.Repeat("11", 32), ScriptSig: []byte{btcutils.OP_0}, Sequence: btcutils.SequenceFinal}},
+		Outputs:  []btcutils.Output{{Satoshis: 5000, ScriptPubKey: otherScriptPubKey}, {Satoshis: satoshis, ScriptPubKey: scriptPubKey}},
+		LockTime: 0,
+	}
+	rawTx, err := tx.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	txID, err := btcutils.TxID(rawTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hex.EncodeToString(rawTx), txID
+}
+
+// testFinalizePSBT finalizes a PSBT holding all the signatures it needs, returning the extracted transaction hex.
+func testFinalizePSBT(t *testing.T, p *psbt.PSBT) string {
+	err := psbt.Finalize(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := psbt.Extract(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	finalTransaction, err := tx.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hex.EncodeToString(finalTransaction)
+}
+
+func TestGenerateSpendPSBT(t *testing.T) {
+	btcutils.SetFixedNonce = true
+	//2-of-3 P2SH multisig, the first cosigner signing the PSBT and the second adding their signature to it
+	testPrivateKeys := []string{"KzVTBhbMaKrAYagJ11VdTaBrb6yzLykLGyuMBkf9sCFPDxdT8shL", "L4euB5ik61Qcp67f5DLo3jiF2uumTMMTa6i8ys2SW82g8PzNJZTc"}
+	testDestination := "1DJrhysUSzjNhP1GYJkgQkkEtCTgnnEWXi"
+	testRedeemScriptHex := "5221025476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee63572103b6446545566e0fecb2f2243db709bbf4e9fff78d48c9438acc911a36015e896a21033d63026f5f0de46e40dc09c61c1559240ea4381df7e05045f6ae2ac506efbaf253ae"
+	testRedeemScript, _ := hex.DecodeString(testRedeemScriptHex)
+	testRedeemScriptHash, _ := btcutils.Hash160(testRedeemScript)
+	testScriptPubKey, _ := btcutils.NewP2SHScriptPubKey(testRedeemScriptHash)
+	testPrevTxHex, testInputTx := testPrevTx(t, testScriptPubKey, 95000)
+
+	psbtBase64, _, err := generateSpend(&SpendFlags{PrivateKeys: testPrivateKeys[0], Destination: testDestination, RedeemScript: testRedeemScriptHex, InputTx: testInputTx, InputIndex: 1, Amount: 90000, TxFlags: TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: 3000, MaxFee: 1000000, SigHash: "default", OutputFormat: "psbt", PrevTx: testPrevTxHex, Network: "mainnet"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := psbt.DeserializeBase64(psbtBase64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := p.Inputs[0]
+	if input.NonWitnessUtxo == nil || input.WitnessUtxo != nil {
+		t.Error("PSBT spending a legacy P2SH input does not have only a non-witness UTXO.")
+	}
+	if !bytes.Equal(input.RedeemScript, testRedeemScript) {
+		testutils.CompareError(t, "PSBT redeem script different from expected script.", testRedeemScriptHex, hex.EncodeToString(input.RedeemScript))
+	}
+	if input.SigHashType != btcutils.SigHashAll {
+		testutils.CompareError(t, "PSBT hash type different from expected hash type.", btcutils.SigHashAll, input.SigHashType)
+	}
+	if len(input.PartialSigs) != 1 {
+		testutils.CompareError(t, "PSBT partial signature count different from expected count.", 1, len(input.PartialSigs))
+	}
+	if len(p.UnsignedTx.Inputs[0].ScriptSig) != 0 {
+		t.Error("PSBT unsigned transaction has a scriptSig.")
+	}
+
+	//Once the second cosigner signs, the PSBT finalizes to the transaction signed by both keys
+	privateKey, _, _ := btcutils.ParseWIF(testPrivateKeys[1], &btcutils.MainNetParams)
+	err = psbt.Sign(p, 0, privateKey, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	finalTransactionHex, _, err := generateSpend(&SpendFlags{PrivateKeys: strings.Join(testPrivateKeys, ","), Destination: testDestination, RedeemScript: testRedeemScriptHex, InputTx: testInputTx, InputIndex: 1, Amount: 90000, TxFlags: testTxFlags})
+	if err != nil {
+		t.Fatal(err)
+	}
+	finalizedHex := testFinalizePSBT(t, p)
+	if finalizedHex != finalTransactionHex {
+		testutils.CompareError(t, "Finalized PSBT different from signed spend transaction.", finalTransactionHex, finalizedHex)
+	}
+
+	if _, _, err := generateSpend(&SpendFlags{PrivateKeys: testPrivateKeys[0], Destination: testDestination, RedeemScript: testRedeemScriptHex, InputTx: testInputTx, InputIndex: 1, Amount: 90000, TxFlags: TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: 3000, MaxFee: 1000000, SigHash: "default", OutputFormat: "psbt", Network: "mainnet"}}); err == nil {
+		t.Error("generateSpend accepting PSBT of legacy input without --prev-tx.")
+	}
+	if _, _, err := generateSpend(&SpendFlags{PrivateKeys: testPrivateKeys[0], Destination: testDestination, RedeemScript: testRedeemScriptHex, InputTx: testInputTx, Amount: 90000, TxFlags: TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: 3000, MaxFee: 1000000, SigHash: "default", OutputFormat: "psbt", PrevTx: testPrevTxHex, Network: "mainnet"}}); err == nil {
+		t.Error("generateSpend accepting --prev-tx output that does not pay to the redeem script.")
+	}
+	if _, _, err := generateSpend(&SpendFlags{PrivateKeys: testPrivateKeys[0], Destination: testDestination, RedeemScript: testRedeemScriptHex, InputTx: strings.Repeat("22", 32), InputIndex: 1, Amount: 90000, TxFlags: TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: 3000, MaxFee: 1000000, SigHash: "default", OutputFormat: "psbt", PrevTx: testPrevTxHex, Network: "mainnet"}}); err == nil {
+		t.Error("generateSpend accepting --prev-tx that is not the input transaction.")
+	}
+	if _, _, err := generateSpend(&SpendFlags{PrivateKeys: testPrivateKeys[0], Destination: testDestination, RedeemScript: testRedeemScriptHex, InputTx: testInputTx, InputIndex: 1, Amount: 90000, TxFlags: TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: 3000, MaxFee: 1000000, SigHash: "default", OutputFormat: "json", Network: "mainnet"}}); err == nil {
+		t.Error("generateSpend accepting unknown output format.")
+	}
+}
+
+func TestGenerateRedeemP2WSHPSBT(t *testing.T) {
+	btcutils.SetFixedNonce = true
+	//The nested 2-of-3 P2SH-P2WSH output of TestGenerateRedeemP2WSHNested
+	testPrivateKeys := "KzVTBhbMaKrAYagJ11VdTaBrb6yzLykLGyuMBkf9sCFPDxdT8shL,L4euB5ik61Qcp67f5DLo3jiF2uumTMMTa6i8ys2SW82g8PzNJZTc"
+	testDestination := "347N1Thc213QqfYCz3PZkjoJpNv5b14kBd"
+	testWitnessScript := "5221025476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee63572103b6446545566e0fecb2f2243db709bbf4e9fff78d48c9438acc911a36015e896a21033d63026f5f0de46e40dc09c61c1559240ea4381df7e05045f6ae2ac506efbaf253ae"
+	testInputTx := "8ac60eb9575db5b2d987e29f301b5b819ea83a5c6579d282d189cc04b8e151ef"
+
+	//SegWit inputs only need the witness UTXO, so --prev-tx is optional
+	psbtBase64, err := generateRedeemP2WSH(&RedeemP2WSHFlags{PrivateKeys: testPrivateKeys, Destination: testDestination, WitnessScript: testWitnessScript, Nested: true, InputTx: testInputTx, InputAmount: 100000, Amount: 90000, TxFlags: TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: 3000, MaxFee: 1000000, ForceHighFee: true, SigHash: "default", OutputFormat: "psbt", Network: "mainnet"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := psbt.DeserializeBase64(psbtBase64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := p.Inputs[0]
+	if input.WitnessUtxo == nil || input.WitnessUtxo.Satoshis != 100000 {
+		t.Fatalf("PSBT witness UTXO %v different from expected 100000 satoshi output.", input.WitnessUtxo)
+	}
+	if hex.EncodeToString(input.WitnessScript) != testWitnessScript {
+		testutils.CompareError(t, "PSBT witness script different from expected script.", testWitnessScript, hex.EncodeToString(input.WitnessScript))
+	}
+	if len(input.RedeemScript) != 34 || len(input.PartialSigs) != 2 {
+		t.Errorf("PSBT of nested P2WSH input has a %d byte redeem script and %d partial signatures instead of 34 bytes and 2 signatures.", len(input.RedeemScript), len(input.PartialSigs))
+	}
+	finalTransactionHex, err := generateRedeemP2WSH(&RedeemP2WSHFlags{PrivateKeys: testPrivateKeys, Destination: testDestination, WitnessScript: testWitnessScript, Nested: true, InputTx: testInputTx, InputAmount: 100000, Amount: 90000, TxFlags: TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: 3000, MaxFee: 1000000, ForceHighFee: true, SigHash: "default", OutputFormat: "hex", Network: "mainnet"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	finalizedHex := testFinalizePSBT(t, p)
+	if finalizedHex != finalTransactionHex {
+		testutils.CompareError(t, "Finalized PSBT different from signed P2SH-P2WSH spending transaction.", finalTransactionHex, finalizedHex)
+	}
+
+	//A --prev-tx holding a different amount than --input-amount is refused
+	witnessScript, _ := hex.DecodeString(testWitnessScript)
+	scriptPubKey, _ := btcutils.CreateP2SHP2WSHScriptPubKey(witnessScript)
+	testPrevTxHex, testPrevTxID := testPrevTx(t, scriptPubKey, 100001)
+	if _, err := generateRedeemP2WSH(&RedeemP2WSHFlags{PrivateKeys: testPrivateKeys, Destination: testDestination, WitnessScript: testWitnessScript, Nested: true, InputTx: testPrevTxID, InputIndex: 1, InputAmount: 100000, Amount: 90000, TxFlags: TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: 3000, MaxFee: 1000000, ForceHighFee: true, SigHash: "default", OutputFormat: "psbt", PrevTx: testPrevTxHex, Network: "mainnet"}}); err == nil {
+		t.Error("generateRedeemP2WSH accepting --prev-tx with an amount different from --input-amount.")
+	}
+}
+
+func TestGenerateFundPSBT(t *testing.T) {
+	btcutils.SetFixedNonce = true
+	//P2PKH input of the uncompressed public key of the WIF
+	testPrivateKeyWIF := "5JJyqG4bb15zqi7fTA4b227aUxQhBo1Ux6qX69ngeXYLr7fk2hs"
+	testDestination := "347N1Thc213QqfYCz3PZkjoJpNv5b14kBd"
+	privateKey, _, _ := btcutils.ParseWIF(testPrivateKeyWIF, &btcutils.MainNetParams)
+	publicKey, _ := btcutils.NewPublicKey(privateKey)
+	publicKeyHash, _ := btcutils.Hash160(publicKey)
+	testScriptPubKey, _ := btcutils.NewP2PKHScriptPubKey(publicKeyHash)
+	testPrevTxHex, testInputTx := testPrevTx(t, testScriptPubKey, 70000)
+
+	psbtBase64, _, _, err := generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, InputIndex: 1, Amount: 65600, Destinations: []string{testDestination}, TxFlags: TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: 3000, MaxFee: 1000000, SigHash: "default", OutputFormat: "psbt", PrevTx: testPrevTxHex, Network: "mainnet"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := psbt.DeserializeBase64(psbtBase64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	finalTransactionHex, _, _, err := generateFund(&FundFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, InputIndex: 1, Amount: 65600, Destinations: []string{testDestination}, TxFlags: testTxFlags})
+	if err != nil {
+		t.Fatal(err)
+	}
+	finalizedHex := testFinalizePSBT(t, p)
+	if finalizedHex != finalTransactionHex {
+		testutils.CompareError(t, "Finalized PSBT different from signed funding transaction.", finalTransactionHex, finalizedHex)
+	}
+}
+
+func TestGenerateFundSegWitPSBT(t *testing.T) {
+	btcutils.SetFixedNonce = true
+	{
+		//P2SH-P2WPKH input of TestGenerateFundP2SHP2WPKH
+		testPrivateKeyWIF := "L57KYn5isHFThD4cohjJgLTZA2vaxnMMKWngnzbttF159yH9dARf"
+		testInputTx := "77541aeb3c4dac9260b68f74f44c973081a9d4cb2ebe8038b2d70faa201b6bdb"
+		testDestination := "1Fyxts6r24DpEieygQiNnWxUdb18ANa5p7"
+		psbtBase64, err := generateFundP2SHP2WPKH(&FundP2WPKHFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, InputIndex: 1, InputAmount: 1000000000, Amount: 999990000, Destination: testDestination, TxFlags: TxFlags{LockTime: 1170, Sequence: 0xfffffffe, TxVersion: 1, DustRelayFee: 3000, MaxFee: 1000000, ForceHighFee: true, SigHash: "default", OutputFormat: "psbt", Network: "mainnet"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		p, err := psbt.DeserializeBase64(psbtBase64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		finalTransactionHex, err := generateFundP2SHP2WPKH(&FundP2WPKHFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, InputIndex: 1, InputAmount: 1000000000, Amount: 999990000, Destination: testDestination, TxFlags: TxFlags{LockTime: 1170, Sequence: 0xfffffffe, TxVersion: 1, DustRelayFee: 3000, MaxFee: 1000000, ForceHighFee: true, SigHash: "default", OutputFormat: "hex", Network: "mainnet"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		finalizedHex := testFinalizePSBT(t, p)
+		if finalizedHex != finalTransactionHex {
+			testutils.CompareError(t, "Finalized PSBT different from signed P2SH-P2WPKH funding transaction.", finalTransactionHex, finalizedHex)
+		}
+	}
+	{
+		//P2TR key path input of TestGenerateFundP2TR, signed with SIGHASH_DEFAULT, which leaves the PSBT hash type out
+		testPrivateKeyWIF := "KyQMr1Usf5P4HzUQiSzq9Vv2nPZaNe58NCm9ojndzdoLbfKEvPms"
+		testInputTx := "3ad337270ac0ba14fbce812291b7d95338c878709ea8123a4d88c3c29efbc6ac"
+		testDestination := "bc1p2wsldez5mud2yam29q22wgfh9439spgduvct83k3pm50fcxa5dps59h4z5"
+		psbtBase64, err := generateFundP2TR(&FundP2TRFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, InputIndex: 1, InputAmount: 100000, Amount: 90000, Destination: testDestination, TxFlags: TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: 3000, MaxFee: 1000000, ForceHighFee: true, SigHash: "default", OutputFormat: "psbt", Network: "mainnet"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		p, err := psbt.DeserializeBase64(psbtBase64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Inputs[0].SigHashType != btcutils.SigHashDefault || len(p.Inputs[0].TaprootKeySig) != 64 {
+			t.Errorf("PSBT of P2TR input has hash type %d and a %d byte key signature instead of no hash type and 64 bytes.", p.Inputs[0].SigHashType, len(p.Inputs[0].TaprootKeySig))
+		}
+		finalTransactionHex, err := generateFundP2TR(&FundP2TRFlags{PrivateKey: testPrivateKeyWIF, InputTx: testInputTx, InputIndex: 1, InputAmount: 100000, Amount: 90000, Destination: testDestination, TxFlags: TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: 3000, MaxFee: 1000000, ForceHighFee: true, SigHash: "default", OutputFormat: "hex", Network: "mainnet"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		finalizedHex := testFinalizePSBT(t, p)
+		if finalizedHex != finalTransactionHex {
+			testutils.CompareError(t, "Finalized PSBT different from signed P2TR funding transaction.", finalTransactionHex, finalizedHex)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+// redeem_p2wsh.go - Spending native SegWit P2WSH, or nested P2SH-P2WSH, multisig funds to a Bitcoin address.
+package multisig
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/btcutils"
+
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// RedeemP2WSHFlags holds the flags of the redeem-p2wsh subcommand.
+type RedeemP2WSHFlags struct {
+	PrivateKeys   string //Comma separated list of M private keys
+	Destination   string //Destination address of spent funds
+	WitnessScript string //Multisig witness script, in the same format as a P2SH redeem script, that hashes to the P2WSH output
+	Nested        bool   //Spend a nested P2SH-P2WSH output instead, putting the redeem script derived from the witness script in the scriptSig
+	InputTx       string //Input transaction hash of P2WSH input to spend
+	InputIndex    int    //Output index of the P2WSH input transaction to spend
+	InputAmount   int    //Amount in Satoshis held by the P2WSH output, which is committed to by the BIP143 signatures
+	Amount        int    //Amount in Satoshis to send
+	TxFlags
+}
+
+// OutputRedeemP2WSH formats and prints relevant outputs to the user.
+func OutputRedeemP2WSH(flags RedeemP2WSHFlags) error {
+	finalTransactionHex, err := generateRedeemP2WSH(&flags)
+	if err != nil {
+		return err
+	}
+	printInputNote("P2WSH input transaction", flags.InputTx, flags.InputIndex, flags.LockTime, flags.Sequence, flags.RBF, flags.RelativeLockTime, flags.TxVersion)
+	if flags.OutputFormat == "psbt" {
+		printPSBT(finalTransactionHex)
+		return nil
+	}
+
+	//Output our final transaction
+	fmt.Printf(`
+-----------------------------------------------------------------------------------------------------------------------------------
+Your raw spending transaction is:
+%v
+Broadcast this transaction to spend your multisig P2WSH funds.
+-----------------------------------------------------------------------------------------------------------------------------------
+`,
+		finalTransactionHex,
+	)
+	printFeeNote(finalTransactionHex, flags.InputAmount)
+	printTransactionIDs(finalTransactionHex)
+	printScripts(finalTransactionHex)
+	return nil
+}
+
+// generateRedeemP2WSH is the high-level logic for spending from a native SegWit P2WSH multisig address with the
+// 'go-bitcoin-multisig redeem-p2wsh' subcommand, from the flags described by RedeemP2WSHFlags. Balance left over from
+// input is used as transaction fee. With OutputFormat psbt, a base64 PSBT signed by PrivateKeys is returned instead
+// of the final transaction, for the remaining cosigners to sign.
+func generateRedeemP2WSH(flags *RedeemP2WSHFlags) (string, error) {
+	input, lockTime, err := parseInput(flags.InputTx, flags.InputIndex, flags.LockTime, flags.Sequence, flags.RBF, flags.RelativeLockTime, flags.TxVersion)
+	if err != nil {
+		return "", err
+	}
+	prevTx, err := decodePrevTx(flags.PrevTx, input)
+	if err != nil {
+		return "", err
+	}
+	//The amount of the output spent is taken from the previous transaction if given
+	flags.InputAmount, err = prevTxInputAmount(prevTx, input, flags.InputAmount)
+	if err != nil {
+		return "", err
+	}
+	version, err := parseTxVersion(flags.TxVersion)
+	if err != nil {
+		return "", err
+	}
+	hashType, err := parseSigHashType(flags.SigHash)
+	if err != nil {
+		return "", err
+	}
+	err = checkOutputFormat(flags.OutputFormat)
+	if err != nil {
+		return "", err
+	}
+	if flags.InputAmount <= 0 {
+		return "", errors.New("--input-amount, or --prev-tx to take it from, is required to sign a P2WSH input.")
+	}
+	if flags.Amount > flags.InputAmount {
+		return "", fmt.Errorf("--amount (%d) exceeds --input-amount (%d).", flags.Amount, flags.InputAmount)
+	}
+	//Convert witnessScript hex to raw bytes
+	witnessScript, err := hex.DecodeString(flags.WitnessScript)
+	if err != nil {
+		return "", err
+	}
+	if len(witnessScript) == 0 || len(witnessScript) > btcutils.MaxWitnessScriptSize {
+		return "", fmt.Errorf("Witness script must be between 1 and %d bytes long. Provided script is %d bytes long.", btcutils.MaxWitnessScriptSize, len(witnessScript))
+	}
+	//Convert private-keys argument into slice of private key bytes
+	params, err := btcutils.NetworkParamsByName(flags.Network)
+	if err != nil {
+		return "", err
+	}
+	privateKeys, err := parsePrivateKeys(flags.PrivateKeys, params)
+	if err != nil {
+		return "", err
+	}
+	scriptPubKey, err := btcutils.NewScriptPubKeyFromAddress(flags.Destination, params)
+	if err != nil {
+		return "", fmt.Errorf("Invalid --destination. %s", err)
+	}
+	//Native SegWit inputs have an empty scriptSig, the signatures and witness script go in the witness instead.
+	//Nested P2SH-P2WSH inputs only push the redeemScript OP_0 <SHA256(witnessScript)> in the scriptSig.
+	p2wshScriptPubKey, err := btcutils.NewP2WSHScriptPubKey(witnessScript)
+	if err != nil {
+		return "", err
+	}
+	var redeemScript []byte
+	if flags.Nested {
+		redeemScript = p2wshScriptPubKey
+		input.ScriptSig = append([]byte{byte(len(redeemScript))}, redeemScript...)
+	}
+	//Refuse a script the input doesn't pay to before signing, if the previous transaction is known. Nested outputs
+	//pay to the P2SH of the redeemScript, native ones to the P2WSH of the witness script
+	if prevTx != nil {
+		script := witnessScript
+		if flags.Nested {
+			script = redeemScript
+		}
+		err = checkPrevTxKey(prevTx, input, nil, script, params)
+		if err != nil {
+			return "", err
+		}
+	}
+	tx := &btcutils.Transaction{
+		Version:  version,
+		Inputs:   []btcutils.Input{input},
+		Outputs:  []btcutils.Output{{Satoshis: uint64(flags.Amount), ScriptPubKey: scriptPubKey}},
+		LockTime: lockTime,
+	}
+	_, err = btcutils.CheckOutputs(tx.Outputs)
+	if err != nil {
+		return "", err
+	}
+	err = checkDust(tx.Outputs, flags.DustRelayFee, flags.AllowDust)
+	if err != nil {
+		return "", err
+	}
+	err = checkHighFee(flags.InputAmount, tx.Outputs, 0, flags.MaxFee, flags.ForceHighFee)
+	if err != nil {
+		return "", err
+	}
+	if flags.OutputFormat == "psbt" {
+		inputScriptPubKey := p2wshScriptPubKey
+		if flags.Nested {
+			inputScriptPubKey, err = btcutils.CreateP2SHP2WSHScriptPubKey(witnessScript)
+			if err != nil {
+				return "", err
+			}
+		}
+		utxo := btcutils.Output{Satoshis: uint64(flags.InputAmount), ScriptPubKey: inputScriptPubKey}
+		return generatePSBT(tx, utxo, prevTx, redeemScript, witnessScript, hashType, privateKeys)
+	}
+	//Sign the transaction with the --sighash hash type
+	privateKeyStrings, err := splitPrivateKeys(flags.PrivateKeys)
+	if err != nil {
+		return "", err
+	}
+	maxFee := int64(flags.MaxFee)
+	finalTransaction, err := RedeemP2WSH(RedeemP2WSHOptions{PrivateKeys: privateKeyStrings, WitnessScript: witnessScript, Nested: flags.Nested, Input: input, InputAmount: int64(flags.InputAmount), Outputs: tx.Outputs, Version: version, LockTime: lockTime, SigHashType: hashType, Network: flags.Network, MaxFee: &maxFee, ForceHighFee: flags.ForceHighFee})
+	if err != nil {
+		return "", err
+	}
+	finalTransactionHex := hex.EncodeToString(finalTransaction)
+
+	return finalTransactionHex, nil
+}
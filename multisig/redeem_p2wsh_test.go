@@ -0,0 +1,68 @@
+package multisig
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/btcutils"
+	"github.com/CryptoProcessing/go-bitcoin-multisig/testutils"
+
+	"testing"
+)
+
+func TestGenerateRedeemP2WSH(t *testing.T) {
+	btcutils.SetFixedNonce = true //SetFixedNonce set to true to get repeatable signatures with a fixed nonce for testing.
+	//Spends a 2-of-3 P2WSH output to bc1qcdkgag7pz4cqgn664ek7ael7ukrvwtwvt9g2rhhznsvafsg6k6dqesjmyu
+	testPrivateKeys := "KzVTBhbMaKrAYagJ11VdTaBrb6yzLykLGyuMBkf9sCFPDxdT8shL,L4euB5ik61Qcp67f5DLo3jiF2uumTMMTa6i8ys2SW82g8PzNJZTc"
+	testDestination := "347N1Thc213QqfYCz3PZkjoJpNv5b14kBd"
+	testWitnessScript := "5221025476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee63572103b6446545566e0fecb2f2243db709bbf4e9fff78d48c9438acc911a36015e896a21033d63026f5f0de46e40dc09c61c1559240ea4381df7e05045f6ae2ac506efbaf253ae"
+	testInputTx := "8ac60eb9575db5b2d987e29f301b5b819ea83a5c6579d282d189cc04b8e151ef"
+	testInputIndex := 0
+	testInputAmount := 100000
+	testAmount := 90000
+	testFinalTransanctionHex := "01000000000101ef51e1b804cc89d182d279655c3aa89e815b1b309fe287d9b2b55d57b90ec68a0000000000ffffffff01905f01000000000017a9141a8b0026343166625c7475f01e48b5ede8c0252e87040047304402206d6caac248af96f6afa7f904f550253a0f3ef3f5aa2fe6838a95b216691468e20220070871ffb9ab843f8c0ba1cc20ab05cfa812e7677f75f28f812501a49ec75b640147304402206d6caac248af96f6afa7f904f550253a0f3ef3f5aa2fe6838a95b216691468e202204ad635e630e71e1839bfed3b232f85af425406a1bcb85fba8c20827826d76be301695221025476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee63572103b6446545566e0fecb2f2243db709bbf4e9fff78d48c9438acc911a36015e896a21033d63026f5f0de46e40dc09c61c1559240ea4381df7e05045f6ae2ac506efbaf253ae00000000"
+
+	finalTransactionHex, err := generateRedeemP2WSH(&RedeemP2WSHFlags{PrivateKeys: testPrivateKeys, Destination: testDestination, WitnessScript: testWitnessScript, InputTx: testInputTx, InputIndex: testInputIndex, InputAmount: testInputAmount, Amount: testAmount, TxFlags: TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: 3000, MaxFee: 1000000, ForceHighFee: true, SigHash: "default", OutputFormat: "hex", Network: "mainnet"}})
+	if err != nil {
+		t.Error(err)
+	}
+	if finalTransactionHex != testFinalTransanctionHex {
+		testutils.CompareError(t, "Generated P2WSH spending transaction different from expected transaction.", testFinalTransanctionHex, finalTransactionHex)
+	}
+}
+
+func TestGenerateRedeemP2WSHNested(t *testing.T) {
+	btcutils.SetFixedNonce = true
+	//Spends the same 2-of-3 output nested in P2SH, 3FawY1gpSKNqz23RtxWPcTMA6cqS43hceq. The BIP143 signature hash is the
+	//same as for the native P2WSH output, so only the scriptSig differs, pushing the redeemScript OP_0 <SHA256(witnessScript)>
+	testPrivateKeys := "KzVTBhbMaKrAYagJ11VdTaBrb6yzLykLGyuMBkf9sCFPDxdT8shL,L4euB5ik61Qcp67f5DLo3jiF2uumTMMTa6i8ys2SW82g8PzNJZTc"
+	testDestination := "347N1Thc213QqfYCz3PZkjoJpNv5b14kBd"
+	testWitnessScript := "5221025476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee63572103b6446545566e0fecb2f2243db709bbf4e9fff78d48c9438acc911a36015e896a21033d63026f5f0de46e40dc09c61c1559240ea4381df7e05045f6ae2ac506efbaf253ae"
+	testInputTx := "8ac60eb9575db5b2d987e29f301b5b819ea83a5c6579d282d189cc04b8e151ef"
+	testFinalTransanctionHex := "01000000000101ef51e1b804cc89d182d279655c3aa89e815b1b309fe287d9b2b55d57b90ec68a00000000232200" + "20c36c8ea3c11570044f5aae6deee7fee586c72dcc5950a1dee29c19d4c11ab69a" + "ffffffff01905f01000000000017a9141a8b0026343166625c7475f01e48b5ede8c0252e87040047304402206d6caac248af96f6afa7f904f550253a0f3ef3f5aa2fe6838a95b216691468e20220070871ffb9ab843f8c0ba1cc20ab05cfa812e7677f75f28f812501a49ec75b640147304402206d6caac248af96f6afa7f904f550253a0f3ef3f5aa2fe6838a95b216691468e202204ad635e630e71e1839bfed3b232f85af425406a1bcb85fba8c20827826d76be301695221025476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee63572103b6446545566e0fecb2f2243db709bbf4e9fff78d48c9438acc911a36015e896a21033d63026f5f0de46e40dc09c61c1559240ea4381df7e05045f6ae2ac506efbaf253ae00000000"
+
+	finalTransactionHex, err := generateRedeemP2WSH(&RedeemP2WSHFlags{PrivateKeys: testPrivateKeys, Destination: testDestination, WitnessScript: testWitnessScript, Nested: true, InputTx: testInputTx, InputAmount: 100000, Amount: 90000, TxFlags: TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: 3000, MaxFee: 1000000, ForceHighFee: true, SigHash: "default", OutputFormat: "hex", Network: "mainnet"}})
+	if err != nil {
+		t.Error(err)
+	}
+	if finalTransactionHex != testFinalTransanctionHex {
+		testutils.CompareError(t, "Generated P2SH-P2WSH spending transaction different from expected transaction.", testFinalTransanctionHex, finalTransactionHex)
+	}
+}
+
+func TestGenerateRedeemP2WSHErrors(t *testing.T) {
+	testPrivateKeys := "KzVTBhbMaKrAYagJ11VdTaBrb6yzLykLGyuMBkf9sCFPDxdT8shL,L4euB5ik61Qcp67f5DLo3jiF2uumTMMTa6i8ys2SW82g8PzNJZTc"
+	testDestination := "347N1Thc213QqfYCz3PZkjoJpNv5b14kBd"
+	testWitnessScript := "5221025476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee63572103b6446545566e0fecb2f2243db709bbf4e9fff78d48c9438acc911a36015e896a21033d63026f5f0de46e40dc09c61c1559240ea4381df7e05045f6ae2ac506efbaf253ae"
+	testInputTx := "8ac60eb9575db5b2d987e29f301b5b819ea83a5c6579d282d189cc04b8e151ef"
+
+	if _, err := generateRedeemP2WSH(&RedeemP2WSHFlags{PrivateKeys: testPrivateKeys, Destination: testDestination, WitnessScript: testWitnessScript, InputTx: testInputTx, Amount: 1000, TxFlags: testTxFlags}); err == nil {
+		t.Error("generateRedeemP2WSH accepting missing input amount.")
+	}
+	if _, err := generateRedeemP2WSH(&RedeemP2WSHFlags{PrivateKeys: testPrivateKeys, Destination: testDestination, WitnessScript: testWitnessScript, InputTx: testInputTx, InputAmount: 1000, Amount: 2000, TxFlags: testTxFlags}); err == nil {
+		t.Error("generateRedeemP2WSH accepting amount larger than input amount.")
+	}
+	if _, err := generateRedeemP2WSH(&RedeemP2WSHFlags{PrivateKeys: testPrivateKeys, Destination: testDestination, InputTx: testInputTx, InputAmount: 2000, Amount: 1000, TxFlags: testTxFlags}); err == nil {
+		t.Error("generateRedeemP2WSH accepting empty witness script.")
+	}
+	if _, err := generateRedeemP2WSH(&RedeemP2WSHFlags{PrivateKeys: testPrivateKeys, Destination: testDestination, WitnessScript: testWitnessScript, InputTx: testInputTx, InputIndex: -1, InputAmount: 2000, Amount: 1000, TxFlags: testTxFlags}); err == nil {
+		t.Error("generateRedeemP2WSH accepting negative input index.")
+	}
+}
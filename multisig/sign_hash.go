@@ -0,0 +1,93 @@
+// sign_hash.go - Signing an already computed 32 byte hash, for external workflows.
+package multisig
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/btcutils"
+
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// OutputSignHash formats and prints relevant outputs to the user.
+func OutputSignHash(flagPrivateKey string, flagHash string, flagSchnorr bool, flagNetwork string) error {
+	signatureHex, publicKeyHex, err := generateSignHash(flagPrivateKey, flagHash, flagSchnorr, flagNetwork)
+	if err != nil {
+		return err
+	}
+	signatureType := "DER encoded ECDSA"
+	if flagSchnorr {
+		signatureType = "BIP340 Schnorr"
+	}
+	fmt.Printf(`
+-----------------------------------------------------------------------------------------------------------------------------------
+Your %s *SIGNATURE* is:
+%v
+It has no hash type byte appended.
+-----------------------------------------------------------------------------------------------------------------------------------
+-----------------------------------------------------------------------------------------------------------------------------------
+Your *PUBLIC KEY* to verify it with is:
+%v
+-----------------------------------------------------------------------------------------------------------------------------------
+`,
+		signatureType,
+		signatureHex,
+		publicKeyHex,
+	)
+	return nil
+}
+
+// generateSignHash is the high-level logic for signing a hash with the 'go-bitcoin-multisig sign-hash' subcommand.
+// Takes flagPrivateKey (WIF private key to sign with), flagHash (32 byte hash to sign in hex, such as a signature
+// hash computed elsewhere), flagSchnorr (sign with BIP340 Schnorr rather than ECDSA) and flagNetwork (name of the
+// network the private key is encoded for) as arguments. Returns the signature and the public key it verifies with,
+// in hex. ECDSA signatures are DER encoded and verify with the compressed or uncompressed public key the WIF private
+// key is for. Schnorr signatures are 64 bytes and verify with the 32 byte x-only public key. The hash is signed as
+// given, without the Taproot tweak used for key path spends.
+func generateSignHash(flagPrivateKey string, flagHash string, flagSchnorr bool, flagNetwork string) (string, string, error) {
+	params, err := btcutils.NetworkParamsByName(flagNetwork)
+	if err != nil {
+		return "", "", err
+	}
+	privateKey, compressed, err := btcutils.ParseWIF(flagPrivateKey, params)
+	if err != nil {
+		return "", "", fmt.Errorf("Invalid --private-key. %s", err)
+	}
+	hash, err := hex.DecodeString(strings.TrimSpace(flagHash))
+	if err != nil {
+		return "", "", fmt.Errorf("Invalid --hash. %s", err)
+	}
+	if len(hash) != 32 {
+		return "", "", fmt.Errorf("--hash should be 32 bytes long. Provided hash is %d bytes long.", len(hash))
+	}
+	if flagSchnorr {
+		//BIP340 public keys are x-only, the same for both forms of the public key
+		publicKey, err := btcutils.NewCompressedPublicKey(privateKey)
+		if err != nil {
+			return "", "", err
+		}
+		xOnlyPublicKey, err := btcutils.XOnlyPublicKey(publicKey)
+		if err != nil {
+			return "", "", err
+		}
+		signature, err := btcutils.SchnorrSign(privateKey, hash)
+		if err != nil {
+			return "", "", err
+		}
+		return hex.EncodeToString(signature), hex.EncodeToString(xOnlyPublicKey), nil
+	}
+	var publicKey []byte
+	if compressed {
+		publicKey, err = btcutils.NewCompressedPublicKey(privateKey)
+	} else {
+		publicKey, err = btcutils.NewPublicKey(privateKey)
+	}
+	if err != nil {
+		return "", "", err
+	}
+	signature, err := btcutils.SignHash(hash, privateKey)
+	if err != nil {
+		return "", "", err
+	}
+	return hex.EncodeToString(signature), hex.EncodeToString(publicKey), nil
+}
@@ -0,0 +1,68 @@
+package multisig
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/btcutils"
+	"github.com/CryptoProcessing/go-bitcoin-multisig/testutils"
+
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestGenerateSignHashSchnorr(t *testing.T) {
+	btcutils.SetFixedNonce = true
+	//BIP340 test vector 0, private key 3 signing a message of zeros with auxiliary data of zeros
+	testPrivateKey := "KwDiBf89QgGbjEhKnhXJuH7LrciVrZi3qYjgd9M7rFU74sHUHy8S"
+	testHash := strings.Repeat("00", 32)
+	testSignatureHex := "e907831f80848d1069a5371b402410364bdf1c5f8307b0084c55f1ce2dca821525f66a4a85ea8b71e482a74f382d2ce5ebeee8fdb2172f477df4900d310536c0"
+	testPublicKeyHex := "f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9"
+
+	signatureHex, publicKeyHex, err := generateSignHash(testPrivateKey, testHash, true, "mainnet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signatureHex != testSignatureHex {
+		testutils.CompareError(t, "Generated Schnorr signature different from expected signature.", testSignatureHex, signatureHex)
+	}
+	if publicKeyHex != testPublicKeyHex {
+		testutils.CompareError(t, "Generated x-only public key different from expected key.", testPublicKeyHex, publicKeyHex)
+	}
+}
+
+func TestGenerateSignHashECDSA(t *testing.T) {
+	btcutils.SetFixedNonce = true
+	//Compressed and uncompressed WIF of private key 3
+	testPrivateKeys := map[string]string{
+		"KwDiBf89QgGbjEhKnhXJuH7LrciVrZi3qYjgd9M7rFU74sHUHy8S": "02f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9",
+		"5HpHagT65TZzG1PH3CSu63k8DbpvD8s5ip4nEB3kEsreB1FQ8BZ":  "04f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9388f7b0f632de8140fe337e62a37f3566500a99934c2231b6cb9fd7584b8e672",
+	}
+	testHash := "7fa4c1b6c7e26e3c7c73b0a1c3a0bfb5a6f8a9b1dbb2e0c1f7a6b8c9d0e1f2a3"
+	for testPrivateKey, testPublicKeyHex := range testPrivateKeys {
+		signatureHex, publicKeyHex, err := generateSignHash(testPrivateKey, testHash, false, "mainnet")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if publicKeyHex != testPublicKeyHex {
+			testutils.CompareError(t, "Generated public key different from expected key.", testPublicKeyHex, publicKeyHex)
+		}
+		hash, _ := hex.DecodeString(testHash)
+		signature, _ := hex.DecodeString(signatureHex)
+		publicKey, _ := hex.DecodeString(publicKeyHex)
+		if !btcutils.VerifySignature(hash, signature, publicKey) {
+			t.Error("Generated ECDSA signature does not verify with the public key.")
+		}
+	}
+}
+
+func TestGenerateSignHashErrors(t *testing.T) {
+	testPrivateKey := "KwDiBf89QgGbjEhKnhXJuH7LrciVrZi3qYjgd9M7rFU74sHUHy8S"
+	if _, _, err := generateSignHash(testPrivateKey, strings.Repeat("00", 31), true, "mainnet"); err == nil {
+		t.Error("generateSignHash accepting 31 byte hash.")
+	}
+	if _, _, err := generateSignHash(testPrivateKey, "zz", false, "mainnet"); err == nil {
+		t.Error("generateSignHash accepting invalid hex hash.")
+	}
+	if _, _, err := generateSignHash(testPrivateKey, strings.Repeat("00", 32), false, "testnet"); err == nil {
+		t.Error("generateSignHash accepting mainnet private key on testnet.")
+	}
+}
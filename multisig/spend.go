@@ -2,138 +2,287 @@
 package multisig
 
 import (
-	"github.com/prettymuchbryce/hellobitcoin/base58check"
 	"github.com/CryptoProcessing/go-bitcoin-multisig/btcutils"
+	"github.com/CryptoProcessing/go-bitcoin-multisig/txscript"
 
-	"bytes"
-	"encoding/binary"
 	"encoding/csv"
 	"encoding/hex"
+	"errors"
 	"fmt"
-	"log"
 	"strings"
 )
 
+// SpendFlags holds the flags of the spend subcommand.
+type SpendFlags struct {
+	PrivateKeys  string //Comma separated list of M private keys
+	Destination  string //Destination address of spent funds
+	RedeemScript string //redeemScript that matches P2SH script
+	InputTx      string //Input transaction hash of P2SH input to spend
+	InputIndex   int    //Output index of the P2SH input transaction to spend
+	Amount       int    //Amount in Satoshis to send
+	InputAmount  int    //Satoshis held by the P2SH input
+	Fee          int    //Transaction fee in Satoshis when sweeping
+	FeeRate      int    //Alternatively, fee rate in Satoshis per virtual byte times the estimated size when sweeping
+	Force        bool   //Allow fee rates above btcutils.MaxFeeRate
+	Sweep        bool   //Empty the whole P2SH input of InputAmount Satoshis less the fee instead of sending Amount
+	TxFlags
+}
+
 //OutputSpend formats and prints relevant outputs to the user.
-func OutputSpend(flagPrivateKeys string, flagDestination string, flagRedeemScript string, flagInputTx string, flagAmount int) {
-	finalTransactionHex := generateSpend(flagPrivateKeys, flagDestination, flagRedeemScript, flagInputTx, flagAmount)
+func OutputSpend(flags SpendFlags) error {
+	finalTransactionHex, estimatedSize, err := generateSpend(&flags)
+	if err != nil {
+		return err
+	}
+	printInputNote("input transaction", flags.InputTx, flags.InputIndex, flags.LockTime, flags.Sequence, flags.RBF, flags.RelativeLockTime, flags.TxVersion)
+	if flags.OutputFormat == "psbt" {
+		printPSBT(finalTransactionHex)
+		return nil
+	}
 	//Output final transaction
-	//Output our final transaction
 	fmt.Printf(`
 -----------------------------------------------------------------------------------------------------------------------------------
 Your raw spending transaction is:
 %v
 Broadcast this transaction to spend your multisig P2SH funds.
 -----------------------------------------------------------------------------------------------------------------------------------
+-----------------------------------------------------------------------------------------------------------------------------------
+Estimated transaction size:	%d bytes
+Actual transaction size:	%d bytes
+-----------------------------------------------------------------------------------------------------------------------------------
 `,
 		finalTransactionHex,
+		estimatedSize,
+		len(finalTransactionHex)/2,
 	)
+	printFeeNote(finalTransactionHex, flags.InputAmount)
+	printTransactionIDs(finalTransactionHex)
+	printScripts(finalTransactionHex)
+	return nil
 }
 
-// generateSpend is the high-level logic for spending from a P2SH multisig address with the 'go-bitcoin-multisig spend' subcommand.
-// Takes flagPrivateKeys (comma separated list of M private keys), flagDestination (destination address of spent funds),
-// flagRedeemScript (redeemScript that matches P2SH script), flagInputTx (input transaction hash of P2SH input to spend)
-// and flagAmount (amount in Satoshis to send, with balance left over from input being used as transaction fee) as arguments.
-func generateSpend(flagPrivateKeys string, flagDestination string, flagRedeemScript string, flagInputTx string, flagAmount int) string {
+// generateSpend is the high-level logic for spending from a P2SH multisig address with the 'go-bitcoin-multisig spend'
+// subcommand, from the flags described by SpendFlags. Balance left over from input is used as transaction fee.
+// InputAmount is optional unless sweeping, and refuses an amount the input can't pay for. The relative lock time of
+// the input must satisfy any OP_CHECKSEQUENCEVERIFY in the redeemScript. With OutputFormat psbt, a base64 PSBT signed
+// by PrivateKeys is returned instead of the final transaction, for the remaining cosigners to sign, with PrevTx the
+// raw hex of the input transaction, which signers of a legacy P2SH input need.
+// Returns the final transaction hex, the estimated size of the transaction in bytes and any error encountered.
+func generateSpend(flags *SpendFlags) (string, int, error) {
+	input, lockTime, err := parseInput(flags.InputTx, flags.InputIndex, flags.LockTime, flags.Sequence, flags.RBF, flags.RelativeLockTime, flags.TxVersion)
+	if err != nil {
+		return "", 0, err
+	}
+	prevTx, err := decodePrevTx(flags.PrevTx, input)
+	if err != nil {
+		return "", 0, err
+	}
+	//The amount of the output spent is taken from the previous transaction if given
+	flags.InputAmount, err = prevTxInputAmount(prevTx, input, flags.InputAmount)
+	if err != nil {
+		return "", 0, err
+	}
+	version, err := parseTxVersion(flags.TxVersion)
+	if err != nil {
+		return "", 0, err
+	}
+	hashType, err := parseSigHashType(flags.SigHash)
+	if err != nil {
+		return "", 0, err
+	}
+	err = checkOutputFormat(flags.OutputFormat)
+	if err != nil {
+		return "", 0, err
+	}
+	err = btcutils.CheckFeeRate(flags.FeeRate, flags.Force)
+	if err != nil {
+		return "", 0, err
+	}
+	if flags.Sweep {
+		if flags.Amount != 0 {
+			return "", 0, errors.New("--sweep and --amount cannot be used together.")
+		}
+	} else {
+		if flags.Amount <= 0 {
+			return "", 0, errors.New("--amount is required unless sweeping the whole input with --sweep.")
+		}
+		if flags.Fee != 0 || flags.FeeRate != 0 {
+			return "", 0, errors.New("--fee and --fee-rate can only be used when sweeping with --sweep.")
+		}
+	}
+	if flags.InputAmount < 0 {
+		return "", 0, fmt.Errorf("--input-amount cannot be negative. Provided amount is %d satoshis.", flags.InputAmount)
+	}
 	//First we create the raw transaction.
 	//In order to construct the raw transaction we need the input transaction hash,
 	//the destination address, the number of satoshis to send, and the scriptSig
 	//which is temporarily (prior to signing) the redeemScript of the input P2SH transaction.
 
 	//Convert redeemScript hex to raw bytes
-	redeemScript, err := hex.DecodeString(flagRedeemScript)
+	redeemScript, err := hex.DecodeString(flags.RedeemScript)
 	if err != nil {
-		log.Fatal(err)
+		return "", 0, err
 	}
-	//Convert private-keys argument into slice of private key bytes with necessary tidying
-	flagPrivateKeys = strings.Replace(flagPrivateKeys, "'", "\"", -1) //Replace single quotes with double since csv package only recognizes double quotes
-	privateKeyStrings, err := csv.NewReader(strings.NewReader(flagPrivateKeys)).Read()
+	if len(redeemScript) == 0 {
+		return "", 0, errors.New("Redeem script cannot be empty.")
+	}
+	//Convert private-keys argument into slice of private key bytes
+	params, err := btcutils.NetworkParamsByName(flags.Network)
 	if err != nil {
-		log.Fatal(err)
+		return "", 0, err
 	}
-	privateKeys := make([][]byte, len(privateKeyStrings))
-	for i, privateKeyString := range privateKeyStrings {
-		privateKeyString = strings.TrimSpace(privateKeyString) //Trim whitespace
-		if privateKeyString == "" {
-			log.Fatal("Provided private key cannot be empty.")
+	//Refuse a redeemScript the input doesn't pay to before signing, if the previous transaction is known
+	if prevTx != nil {
+		err = checkPrevTxKey(prevTx, input, nil, redeemScript, params)
+		if err != nil {
+			return "", 0, err
+		}
+	}
+	privateKeys, err := parsePrivateKeys(flags.PrivateKeys, params)
+	if err != nil {
+		return "", 0, err
+	}
+	privateKeyStrings, err := splitPrivateKeys(flags.PrivateKeys)
+	if err != nil {
+		return "", 0, err
+	}
+	//Create scriptPubKey with provided destination address
+	scriptPubKey, err := btcutils.NewScriptPubKeyFromAddress(flags.Destination, params)
+	if err != nil {
+		return "", 0, fmt.Errorf("Invalid --destination. %s", err)
+	}
+	//Multisig scriptSig dominates the transaction size
+	scriptSigSize := btcutils.EstimateMultisigScriptSigSize(len(privateKeys), len(redeemScript))
+	estimatedSize := btcutils.EstimateSize([]int{scriptSigSize}, []btcutils.Output{{ScriptPubKey: scriptPubKey}})
+	amount := flags.Amount
+	if flags.Sweep {
+		amount, err = sweepAmount(flags.InputAmount, flags.Fee, flags.FeeRate, estimatedSize)
+		if err != nil {
+			return "", 0, err
 		}
-		privateKeys[i] = base58check.Decode(privateKeyString) //Get private keys as slice of raw bytes
 	}
-	//Create scriptPubKey with provided destination public key
-	publicKeyHash := base58check.Decode(flagDestination)
-	scriptPubKey, err := btcutils.NewP2PKHScriptPubKey(publicKeyHash)
+	//Create unsigned transaction
+	tx := &btcutils.Transaction{Version: version, Inputs: []btcutils.Input{input}, Outputs: []btcutils.Output{{Satoshis: uint64(amount), ScriptPubKey: scriptPubKey}}, LockTime: lockTime}
+	_, err = btcutils.CheckOutputs(tx.Outputs)
 	if err != nil {
-		log.Fatal(err)
+		return "", 0, err
+	}
+	//A transaction spending more than its input holds has a negative fee, and can never be valid
+	if flags.InputAmount > 0 {
+		if _, err := btcutils.CheckFee(uint64(flags.InputAmount), tx.Outputs); err != nil {
+			return "", 0, fmt.Errorf("Invalid --amount. %s", err)
+		}
 	}
-	//Create unsigned raw transaction
-	//scriptSig in unsigned transaction is serialized redeemScript of input P2SH transaction.
-	rawTransaction, err := btcutils.NewRawTransaction(flagInputTx, flagAmount, redeemScript, scriptPubKey)
+	err = checkDust(tx.Outputs, flags.DustRelayFee, flags.AllowDust)
 	if err != nil {
-		log.Fatal(err)
+		return "", 0, err
 	}
-	//After completing the raw transaction, we append
-	//SIGHASH_ALL in little-endian format to the end of the raw transaction.
-	hashCodeType, err := hex.DecodeString("01000000")
+	err = checkHighFee(flags.InputAmount, tx.Outputs, 0, flags.MaxFee, flags.ForceHighFee)
 	if err != nil {
-		log.Fatal(err)
+		return "", 0, err
 	}
-	var rawTransactionBuffer bytes.Buffer
-	rawTransactionBuffer.Write(rawTransaction)
-	rawTransactionBuffer.Write(hashCodeType)
-	rawTransactionWithHashCodeType := rawTransactionBuffer.Bytes()
-	//Sign transaction
-	finalTransaction, err := signMultisigTransaction(rawTransactionWithHashCodeType, privateKeys, scriptPubKey, redeemScript, flagInputTx, flagAmount)
+	//A redeemScript with OP_CHECKSEQUENCEVERIFY can only be spent by an input with a long enough relative lock time
+	relativeLockTimes, err := btcutils.FindCheckSequenceVerify(redeemScript)
 	if err != nil {
-		log.Fatal(err)
+		return "", 0, err
+	}
+	for _, relativeLockTime := range relativeLockTimes {
+		err = btcutils.CheckSequenceVerify(tx, 0, relativeLockTime)
+		if err != nil {
+			return "", 0, fmt.Errorf("%s Set the relative lock time of the input with --relative-locktime.", err)
+		}
+	}
+	if flags.OutputFormat == "psbt" {
+		//Other cosigners sign the P2SH output of the redeemScript in their own wallets
+		redeemScriptHash, err := btcutils.Hash160(redeemScript)
+		if err != nil {
+			return "", 0, err
+		}
+		inputScriptPubKey, err := btcutils.NewP2SHScriptPubKey(redeemScriptHash)
+		if err != nil {
+			return "", 0, err
+		}
+		utxo := btcutils.Output{Satoshis: uint64(flags.InputAmount), ScriptPubKey: inputScriptPubKey}
+		psbtBase64, err := generatePSBT(tx, utxo, prevTx, redeemScript, nil, hashType, privateKeys)
+		return psbtBase64, estimatedSize, err
+	}
+	//Sign transaction with the --sighash hash type
+	//Without --input-amount the fee is unknown, which printFeeNote warns about, so it is left unchecked
+	maxFee := int64(flags.MaxFee)
+	finalTransaction, err := SpendP2SH(SpendP2SHOptions{PrivateKeys: privateKeyStrings, RedeemScript: redeemScript, Input: input, InputAmount: int64(flags.InputAmount), Outputs: tx.Outputs, Version: version, LockTime: lockTime, SigHashType: hashType, Network: flags.Network, MaxFee: &maxFee, ForceHighFee: flags.ForceHighFee || flags.InputAmount <= 0})
+	if err != nil {
+		return "", 0, err
 	}
 	finalTransactionHex := hex.EncodeToString(finalTransaction)
 
-	return finalTransactionHex
+	return finalTransactionHex, estimatedSize, nil
 }
 
-// signMultisigTransaction signs a raw P2PKH transaction, given slice of private keys and the scriptPubKey, inputTx,
-// redeemScript and amount to construct the final transaction.
-func signMultisigTransaction(rawTransaction []byte, orderedPrivateKeys [][]byte, scriptPubKey []byte, redeemScript []byte, inputTx string, amount int) ([]byte, error) {
-	//Hash type SIGHASH_ALL
-	hashCodeType, err := hex.DecodeString("01")
+// parsePrivateKeys converts a comma separated list of private keys into a slice of private key bytes with necessary
+// tidying. Whitespace is stripped and keys may be quoted. Keys must be encoded for the network given by params.
+func parsePrivateKeys(flagPrivateKeys string, params *btcutils.NetworkParams) ([][]byte, error) {
+	privateKeyStrings, err := splitPrivateKeys(flagPrivateKeys)
+	if err != nil {
+		return nil, err
+	}
+	privateKeys := make([][]byte, len(privateKeyStrings))
+	for i, privateKeyString := range privateKeyStrings {
+		//Get private keys as slice of raw bytes. Compression doesn't matter here since the public keys are given by the redeemScript
+		privateKeys[i], _, err = btcutils.ParseWIF(privateKeyString, params)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid --private-keys key %d. %s", i+1, err)
+		}
+	}
+	return privateKeys, nil
+}
+
+// splitPrivateKeys splits a comma separated list of private keys into the WIF of each key, stripping whitespace and
+// quotes.
+func splitPrivateKeys(flagPrivateKeys string) ([]string, error) {
+	flagPrivateKeys = strings.Replace(flagPrivateKeys, "'", "\"", -1) //Replace single quotes with double since csv package only recognizes double quotes
+	privateKeyStrings, err := csv.NewReader(strings.NewReader(flagPrivateKeys)).Read()
+	if err != nil {
+		return nil, err
+	}
+	for i, privateKeyString := range privateKeyStrings {
+		privateKeyStrings[i] = strings.TrimSpace(privateKeyString) //Trim whitespace
+		if privateKeyStrings[i] == "" {
+			return nil, errors.New("Provided private key cannot be empty.")
+		}
+	}
+	return privateKeyStrings, nil
+}
+
+// signMultisigTransaction signs the single P2SH multisig input of tx with hashType, given slice of private keys in
+// the same order as their public keys in redeemScript. Returns the serialized signed transaction.
+func signMultisigTransaction(tx *btcutils.Transaction, orderedPrivateKeys [][]byte, redeemScript []byte, hashType btcutils.SigHashType) ([]byte, error) {
+	err := btcutils.CheckSigHashType(tx, 0, hashType)
+	if err != nil {
+		return nil, err
+	}
+	//The signature hash is over the transaction with the redeemScript in place of the scriptSig
+	sigHash, err := btcutils.CalcSignatureHash(tx, 0, redeemScript, hashType)
 	if err != nil {
 		return nil, err
 	}
 	//Generate signatures for each provided key
 	signatures := make([][]byte, len(orderedPrivateKeys))
 	for i, privateKey := range orderedPrivateKeys {
-		signatures[i], err = btcutils.NewSignature(rawTransaction, privateKey)
+		signatures[i], err = btcutils.SignHash(sigHash, privateKey)
 		if err != nil {
 			return nil, err
 		}
 	}
-	//redeemScript length. To allow redeemScript > 255 bytes, we use OP_PUSHDATA2 and use two bytes to specify length
-	var redeemScriptLengthBytes []byte
-	var requiredOP_PUSHDATA int
-	if len(redeemScript) < 255 {
-		requiredOP_PUSHDATA = btcutils.OP_PUSHDATA1 //OP_PUSHDATA1 specifies next *one byte* will be length to be pushed to stack
-		redeemScriptLengthBytes = []byte{byte(len(redeemScript))}
-	} else {
-		requiredOP_PUSHDATA = btcutils.OP_PUSHDATA2 //OP_PUSHDATA2 specifies next *two bytes* will be length to be pushed to stack
-		redeemScriptLengthBytes = make([]byte, 2)
-		binary.LittleEndian.PutUint16(redeemScriptLengthBytes, uint16(len(redeemScript)))
-	}
-	//Create scriptSig
-	var buffer bytes.Buffer
-	buffer.WriteByte(byte(btcutils.OP_0)) //OP_0 for Multisig off-by-one error
+	//Create scriptSig, pushing each signature and the redeemScript with the smallest push opcode for its length
+	builder := txscript.NewBuilder().AddOp(txscript.OP_0) //OP_0 for Multisig off-by-one error
 	for _, signature := range signatures {
-		buffer.WriteByte(byte(len(signature) + 1)) //PUSH each signature. Add one for hash type byte
-		buffer.Write(signature)                    // Signature bytes
-		buffer.WriteByte(hashCodeType[0])          //hash type
-	}
-	buffer.WriteByte(byte(requiredOP_PUSHDATA)) //OP_PUSHDATA1 or OP_PUSHDATA2 depending on size of redeemScript
-	buffer.Write(redeemScriptLengthBytes)       //PUSH redeemScript
-	buffer.Write(redeemScript)                  //redeemScript
-	scriptSig := buffer.Bytes()
-	//Finally create transaction with actual scriptSig
-	signedRawTransaction, err := btcutils.NewRawTransaction(inputTx, amount, scriptSig, scriptPubKey)
+		builder.AddData(append(signature, byte(hashType))) //Signature followed by hash type
+	}
+	scriptSig, err := builder.AddData(redeemScript).Script()
 	if err != nil {
 		return nil, err
 	}
-	return signedRawTransaction, nil
+	//Finally create transaction with actual scriptSig
+	tx.Inputs[0].ScriptSig = scriptSig
+	return tx.Serialize()
 }
@@ -3,7 +3,12 @@ package multisig
 import (
 	"github.com/CryptoProcessing/go-bitcoin-multisig/btcutils"
 	"github.com/CryptoProcessing/go-bitcoin-multisig/testutils"
+	"github.com/CryptoProcessing/go-bitcoin-multisig/txscript"
+
+	"bytes"
+	"encoding/hex"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -18,7 +23,10 @@ func TestGenerateSpend(t *testing.T) {
 		testAmount := 145600
 		testFinalTransactionHex := "0100000001da69765bad9cc46a70480a153b8e229c41f38eecb57699693d5c4444e036e0c200000000fd3d030047304402206d6caac248af96f6afa7f904f550253a0f3ef3f5aa2fe6838a95b216691468e2022016de9b7ae8eaba28b761c09b5f5d58732aeb98bb0121e4f8411cb471824b13780147304402206d6caac248af96f6afa7f904f550253a0f3ef3f5aa2fe6838a95b216691468e202204f43b84c9ef4371ee5382e44002824485e1e2f6919eedbaf26e406f46318fbbd0147304402206d6caac248af96f6afa7f904f550253a0f3ef3f5aa2fe6838a95b216691468e202206876e87463a637f8168eed56da177f78c9a01e0439c46c937d86af182efd9e670147304402206d6caac248af96f6afa7f904f550253a0f3ef3f5aa2fe6838a95b216691468e2022010b0ea71218abe8d5be9a586ae4c87b32215ed7eb28508c6dcde6c2c796c11620147304402206d6caac248af96f6afa7f904f550253a0f3ef3f5aa2fe6838a95b216691468e2022070be464546c146a92dad100ead8f7bae32af8650ee763105e0cb5182b5063471014dd101554104c22e4293d1d462eef905e592ad4aff332aa52c3415b824cd85cf594258d92c836fe797187bc2459261e0597c4ef351c5d0c26f7a60165221e221a38e448ad08c4104bb28684dfe23852a7c276827dd448c955007e7ccbfacbf536e13f1097b30430ebec5af0bc001e50d3f0e796d52ba43e3c07337bfed2a842659d51632f2b21d2841048f8551173f8e7414ff0e144899b3f70accd957e6913f5cf877bd576f6c16f0aa67fb9b96e0df10562b4f7ba4060acd22f142329ff83f1d96e27f4e4394adeda24104aa81def7dda6a4f40be2f3287ee3423f255b07965104a7888df075217c9ee5b3e9e2e70115d43bfecbff8062f8289f5cab3d0ebd96c9f55c85f6147ff3a5e9494104493aa5f89ec34184a235b2c9f608eade1634636f94f64b59419875e15cb86a6d8c708a9d5eda3304cb983b2325a57af881ed75f28179f5f263d7758039b68d894104dc284f749208d7fec57937bc5e72187b064df7d29b7aa82cae273e9a1c91beae9c510e0fd632a3db272c67db04061ea761d1ed91fdb8ab07e354047c64ce405d41042fc7796f54dd482db20f1bcce584f930ae74d5f27fc8336e2701bd0243d681281810c57e079947ebdfdfc8860ed34b0ba32db82a85249adc7c64ab547d48af6457aeffffffff01c0380200000000001976a914870212de342646df8eb8874964f78ae2929f063e88ac00000000"
 
-		finalTransactionHex := generateSpend(testPrivateKeys, testDestination, testRedeemScript, testInputTx, testAmount)
+		finalTransactionHex, _, err := generateSpend(&SpendFlags{PrivateKeys: testPrivateKeys, Destination: testDestination, RedeemScript: testRedeemScript, InputTx: testInputTx, Amount: testAmount, TxFlags: testTxFlags})
+		if err != nil {
+			t.Error(err)
+		}
 		if testFinalTransactionHex != finalTransactionHex {
 			testutils.CompareError(t, "Generated spend transaction different from expected transaction.", testFinalTransactionHex, finalTransactionHex)
 		}
@@ -32,13 +40,17 @@ func TestGenerateSpend(t *testing.T) {
 		testAmount := 75600
 		testFinalTransactionHex := "0100000001f7889145d64a374c98a6d4930d20c070001b4fcb50cc67a76ed615b127ab628400000000fdcd030047304402206d6caac248af96f6afa7f904f550253a0f3ef3f5aa2fe6838a95b216691468e20220792733272f3be0f852c4603d132327ba851c32dbdc98d4087521ace999111d590147304402206d6caac248af96f6afa7f904f550253a0f3ef3f5aa2fe6838a95b216691468e2022056a02e4af79e085d9d577045b26774374c879374f3933dd2106e7e5cb64e8f080147304402206d6caac248af96f6afa7f904f550253a0f3ef3f5aa2fe6838a95b216691468e2022016c85973985bd4afa0f5df71f8213512c8268c6db9f3267ce7bc8d3af75d25280147304402206d6caac248af96f6afa7f904f550253a0f3ef3f5aa2fe6838a95b216691468e202207d61422f4f32a06d93e9d78ad628bf33058a2a7763ce6ba93a09803ff372b8d20147304402206d6caac248af96f6afa7f904f550253a0f3ef3f5aa2fe6838a95b216691468e202201b64ecacd19fb31d446e446838edbd2af9da307fadf76b48ce6008cd21d0d8680147304402206d6caac248af96f6afa7f904f550253a0f3ef3f5aa2fe6838a95b216691468e2022059cf7b566d5e7af104f1a257499b47a89db5a5bff482b2399734baaa605c490c0147304402206d6caac248af96f6afa7f904f550253a0f3ef3f5aa2fe6838a95b216691468e202200949969d89e6b890f342f8a9b5382f414324317a25c411ecb07a87a6b3c27c25014dd10157410446f1c8de232a065da428bf76e44b41f59a46620dec0aedfc9b5ab651e91f2051d610fddc78b8eba38a634bfe9a74bb015a88c52b9b844c74997035e08a695ce94104704e19d4fc234a42d707d41053c87011f990b564949532d72cab009e136bd60d7d0602f925fce79da77c0dfef4a49c6f44bd0540faef548e37557d74b36da1244104b75a8cb10fd3f1785addbafdb41b409ecd6ffd50d5ad71d8a3cdc5503bcb35d3d13cdf23f6d0eb6ab88446276e2ba5b92d8786da7e5c0fb63aafb62f87443d284104033a82ccb1291bbc27cf541c6c487c213f25db85c620ecb9cbb76ca461ef13db5a80b90c3ae7d2a5e47623cdf520a2586cac7e41f779103a71a1fe177189781e41045e3b4030be5fd9c4c40e7076bd49f022118d90ae9182de61f3a1adb2ff511c97e8a6a82a9292b01878a18c08b7cd658ebdf80e6ed3f26783b25ba1a52fa9e52d4104c93ceb8f4482e131addc58d3efa0b4967bb7c574de15786d55379cc4a43a61571518abe0f05ebf188bcce9580aa70b3f5b1024ca579819c8810ff79967de3f234104a66f63d2941f0befcfba4b73495a7b99fc7ed28cb41e7934e1de82d852628766dc96ee1e196387a68e7fd8898862c2260f1f2557ac2147af07900695f15abd3f57aeffffffff0150270100000000001976a9149203e47a16f799ded03532e3e452606fdc52007e88ac00000000"
 
-		finalTransactionHex := generateSpend(testPrivateKeys, testDestination, testRedeemScript, testInputTx, testAmount)
+		finalTransactionHex, _, err := generateSpend(&SpendFlags{PrivateKeys: testPrivateKeys, Destination: testDestination, RedeemScript: testRedeemScript, InputTx: testInputTx, Amount: testAmount, TxFlags: testTxFlags})
+		if err != nil {
+			t.Error(err)
+		}
 		if testFinalTransactionHex != finalTransactionHex {
 			testutils.CompareError(t, "Generated spend transaction different from expected transaction.", testFinalTransactionHex, finalTransactionHex)
 		}
 	}
 	{
-		//7-of-7 spending multisig test
+		//7-of-7 spending multisig test. With the fixed nonce both raw signatures have a high S value, so this checks
+		//they are normalized to low-S
 		testPrivateKeys := "5JruagvxNLXTnkksyLMfgFgf3CagJ3Ekxu5oGxpTm5mPfTAPez3,5JjHVMwJdjPEPQhq34WMUhzLcEd4SD7HgZktEh8WHstWcCLRceV"
 		testDestination := "18tiB1yNTzJMCg6bQS1Eh29dvJngq8QTfx"
 		testRedeemScript := "524104a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575fa349b5694ed3155b136f09e63975a1700c9f4d4df849323dac06cf3bd6458cd41046ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640de68c2fe913d363a51154a0c62d7adea1b822d05035077418267b1a1379790187410411ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef992f63280567f52f5ba870678b4ab4ff6c8ea600bd217870a8b4f1f09f3a8e8353ae"
@@ -46,28 +58,290 @@ func TestGenerateSpend(t *testing.T) {
 		testAmount := 55600
 		testFinalTransactionHex := "01000000013dcd7d87904c9cb7f4b79f36b5a03f96e2e729284c09856238d5353e1182b00200000000fd5c010047304402206d6caac248af96f6afa7f904f550253a0f3ef3f5aa2fe6838a95b216691468e20220106d4068c7b29336dc39b96234e1b55fdbd79287eeb147d9405b189d4368b0c60147304402206d6caac248af96f6afa7f904f550253a0f3ef3f5aa2fe6838a95b216691468e202204b14745bcc78dbac7e57c5cd64fb5d351a00632293dd01d5e567b402a51ba831014cc9524104a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575fa349b5694ed3155b136f09e63975a1700c9f4d4df849323dac06cf3bd6458cd41046ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640de68c2fe913d363a51154a0c62d7adea1b822d05035077418267b1a1379790187410411ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef992f63280567f52f5ba870678b4ab4ff6c8ea600bd217870a8b4f1f09f3a8e8353aeffffffff0130d90000000000001976a914569076ba39fc4ff6a2291d9ea9196d8c08f9c7ab88ac00000000"
 
-		finalTransactionHex := generateSpend(testPrivateKeys, testDestination, testRedeemScript, testInputTx, testAmount)
+		finalTransactionHex, estimatedSize, err := generateSpend(&SpendFlags{PrivateKeys: testPrivateKeys, Destination: testDestination, RedeemScript: testRedeemScript, InputTx: testInputTx, Amount: testAmount, TxFlags: testTxFlags})
+		if err != nil {
+			t.Error(err)
+		}
 		if testFinalTransactionHex != finalTransactionHex {
 			testutils.CompareError(t, "Generated spend transaction different from expected transaction.", testFinalTransactionHex, finalTransactionHex)
 		}
+		//Estimate assumes the largest possible signatures, so can only exceed the actual size by a couple of bytes per signature
+		if size := len(finalTransactionHex) / 2; size > estimatedSize || estimatedSize-size > 2*2 {
+			testutils.CompareError(t, "Actual spend transaction size too far from estimated size.", estimatedSize, size)
+		}
+	}
+}
+
+func TestGenerateSpendInputIndex(t *testing.T) {
+	btcutils.SetFixedNonce = true
+	testPrivateKeys := "5JruagvxNLXTnkksyLMfgFgf3CagJ3Ekxu5oGxpTm5mPfTAPez3,5JjHVMwJdjPEPQhq34WMUhzLcEd4SD7HgZktEh8WHstWcCLRceV"
+	testDestination := "18tiB1yNTzJMCg6bQS1Eh29dvJngq8QTfx"
+	testRedeemScript := "524104a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575fa349b5694ed3155b136f09e63975a1700c9f4d4df849323dac06cf3bd6458cd41046ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640de68c2fe913d363a51154a0c62d7adea1b822d05035077418267b1a1379790187410411ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef992f63280567f52f5ba870678b4ab4ff6c8ea600bd217870a8b4f1f09f3a8e8353ae"
+	testInputTx := "02b082113e35d5386285094c2829e7e2963fa0b5369fb7f4b79c4c90877dcd3d"
+	testAmount := 55600
+	//Output index is 4 little-endian bytes directly after version (4 bytes), input count (1 byte) and input hash (32 bytes)
+	testOutputIndexHex := "03000000"
+
+	finalTransactionHex, _, err := generateSpend(&SpendFlags{PrivateKeys: testPrivateKeys, Destination: testDestination, RedeemScript: testRedeemScript, InputTx: testInputTx, InputIndex: 3, Amount: testAmount, TxFlags: testTxFlags})
+	if err != nil {
+		t.Fatal(err)
+	}
+	outputIndexHex := finalTransactionHex[(5+32)*2 : (5+36)*2]
+	if outputIndexHex != testOutputIndexHex {
+		testutils.CompareError(t, "Spend transaction output index different from expected index.", testOutputIndexHex, outputIndexHex)
+	}
+	//The signatures commit to the output index, so they differ from those spending output index 0
+	otherTransactionHex, _, err := generateSpend(&SpendFlags{PrivateKeys: testPrivateKeys, Destination: testDestination, RedeemScript: testRedeemScript, InputTx: testInputTx, Amount: testAmount, TxFlags: testTxFlags})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if otherTransactionHex[(5+36)*2:] == finalTransactionHex[(5+36)*2:] {
+		t.Error("Spend transaction signatures not committing to the output index.")
+	}
+}
+
+func TestGenerateSpendCheckSequenceVerify(t *testing.T) {
+	testPrivateKeys := "5JruagvxNLXTnkksyLMfgFgf3CagJ3Ekxu5oGxpTm5mPfTAPez3,5JjHVMwJdjPEPQhq34WMUhzLcEd4SD7HgZktEh8WHstWcCLRceV"
+	testPublicKeys := []string{
+		"04a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575fa349b5694ed3155b136f09e63975a1700c9f4d4df849323dac06cf3bd6458cd",
+		"0411ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef992f63280567f52f5ba870678b4ab4ff6c8ea600bd217870a8b4f1f09f3a8e83",
+	}
+	//<144> OP_CHECKSEQUENCEVERIFY OP_DROP OP_2 <pubkey1> <pubkey2> OP_2 OP_CHECKMULTISIG, 144 blocks after funding
+	testRedeemScript := "029000b27552" + "41" + testPublicKeys[0] + "41" + testPublicKeys[1] + "52ae"
+	testDestination := "18tiB1yNTzJMCg6bQS1Eh29dvJngq8QTfx"
+	testInputTx := "02b082113e35d5386285094c2829e7e2963fa0b5369fb7f4b79c4c90877dcd3d"
+	testAmount := 55600
+
+	for _, relativeLockTime := range []string{"144", "1000"} {
+		finalTransactionHex, _, err := generateSpend(&SpendFlags{PrivateKeys: testPrivateKeys, Destination: testDestination, RedeemScript: testRedeemScript, InputTx: testInputTx, Amount: testAmount, TxFlags: TxFlags{Sequence: 0xffffffff, RBF: true, RelativeLockTime: relativeLockTime, TxVersion: 2, DustRelayFee: 3000, MaxFee: 1000000, SigHash: "default", OutputFormat: "hex", Network: "mainnet"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkMultisigSpend(t, finalTransactionHex, testRedeemScript, testPublicKeys)
+	}
+
+	invalidTestCases := []struct {
+		relativeLockTime string
+		txVersion        int64
+	}{
+		//Relative lock time too short, or in seconds rather than blocks
+		{"143", 2},
+		{"86400s", 2},
+		//No relative lock time
+		{"", 2},
+		{"144", 1},
+	}
+	for _, testCase := range invalidTestCases {
+		if _, _, err := generateSpend(&SpendFlags{PrivateKeys: testPrivateKeys, Destination: testDestination, RedeemScript: testRedeemScript, InputTx: testInputTx, Amount: testAmount, TxFlags: TxFlags{Sequence: 0xffffffff, RBF: true, RelativeLockTime: testCase.relativeLockTime, TxVersion: testCase.txVersion, DustRelayFee: 3000, MaxFee: 1000000, SigHash: "default", OutputFormat: "hex", Network: "mainnet"}}); err == nil {
+			t.Errorf("generateSpend accepting --relative-locktime %q with --tx-version %d for a redeem script needing 144 blocks.", testCase.relativeLockTime, testCase.txVersion)
+		}
+	}
+}
+
+// checkMultisigSpend runs the redeem script of a signed P2SH multisig spend locally, checking its relative lock times
+// and that each signature is valid for the public key at the same position, as the spend test signs with the keys
+// in redeem script order.
+func checkMultisigSpend(t *testing.T, finalTransactionHex string, redeemScriptHex string, publicKeyHexes []string) {
+	finalTransaction, _ := hex.DecodeString(finalTransactionHex)
+	redeemScript, _ := hex.DecodeString(redeemScriptHex)
+	tx, err := btcutils.DeserializeTransaction(finalTransaction)
+	if err != nil {
+		t.Fatal(err)
+	}
+	relativeLockTimes, err := btcutils.FindCheckSequenceVerify(redeemScript)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, relativeLockTime := range relativeLockTimes {
+		if err := btcutils.CheckSequenceVerify(tx, 0, relativeLockTime); err != nil {
+			t.Error(err)
+		}
+	}
+	//OP_0 <sig1> ... <sigm> OP_PUSHDATA1 <redeemScript>
+	scriptSig := tx.Inputs[0].ScriptSig
+	if len(scriptSig) == 0 || scriptSig[0] != btcutils.OP_0 {
+		t.Fatal("Multisig scriptSig does not start with OP_0.")
+	}
+	i := 1
+	for _, publicKeyHex := range publicKeyHexes {
+		length := int(scriptSig[i])
+		signature := scriptSig[i+1 : i+length]
+		hashType := btcutils.SigHashType(scriptSig[i+length])
+		i += 1 + length
+		sigHash, err := btcutils.CalcSignatureHash(tx, 0, redeemScript, hashType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		publicKey, _ := hex.DecodeString(publicKeyHex)
+		if !btcutils.VerifySignature(sigHash, signature, publicKey) {
+			t.Errorf("Signature %x not valid for public key %s.", signature, publicKeyHex)
+		}
+	}
+	if !bytes.Equal(scriptSig[i:], append([]byte{btcutils.OP_PUSHDATA1, byte(len(redeemScript))}, redeemScript...)) {
+		t.Error("Multisig scriptSig does not end with the redeem script.")
+	}
+}
+
+func TestGenerateSpendErrors(t *testing.T) {
+	testPrivateKeys := "5JruagvxNLXTnkksyLMfgFgf3CagJ3Ekxu5oGxpTm5mPfTAPez3,5JjHVMwJdjPEPQhq34WMUhzLcEd4SD7HgZktEh8WHstWcCLRceV"
+	testDestination := "18tiB1yNTzJMCg6bQS1Eh29dvJngq8QTfx"
+	testRedeemScript := "524104a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575fa349b5694ed3155b136f09e63975a1700c9f4d4df849323dac06cf3bd6458cd41046ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640de68c2fe913d363a51154a0c62d7adea1b822d05035077418267b1a1379790187410411ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef992f63280567f52f5ba870678b4ab4ff6c8ea600bd217870a8b4f1f09f3a8e8353ae"
+	testInputTx := "02b082113e35d5386285094c2829e7e2963fa0b5369fb7f4b79c4c90877dcd3d"
+	testAmount := 55600
+
+	//Invalid hex redeem script
+	if _, _, err := generateSpend(&SpendFlags{PrivateKeys: testPrivateKeys, Destination: testDestination, RedeemScript: "52zz", InputTx: testInputTx, Amount: testAmount, TxFlags: testTxFlags}); err == nil {
+		t.Error("generateSpend accepting invalid hex redeem script.")
+	}
+	//Empty redeem script
+	if _, _, err := generateSpend(&SpendFlags{PrivateKeys: testPrivateKeys, Destination: testDestination, InputTx: testInputTx, Amount: testAmount, TxFlags: testTxFlags}); err == nil {
+		t.Error("generateSpend accepting empty redeem script.")
+	}
+	//Wrong private key length. Public address decodes to 20 bytes rather than 32.
+	if _, _, err := generateSpend(&SpendFlags{PrivateKeys: "5JruagvxNLXTnkksyLMfgFgf3CagJ3Ekxu5oGxpTm5mPfTAPez3,18tiB1yNTzJMCg6bQS1Eh29dvJngq8QTfx", Destination: testDestination, RedeemScript: testRedeemScript, InputTx: testInputTx, Amount: testAmount, TxFlags: testTxFlags}); err == nil {
+		t.Error("generateSpend accepting private key of wrong length.")
+	}
+	//Empty private key
+	if _, _, err := generateSpend(&SpendFlags{PrivateKeys: "5JruagvxNLXTnkksyLMfgFgf3CagJ3Ekxu5oGxpTm5mPfTAPez3, ", Destination: testDestination, RedeemScript: testRedeemScript, InputTx: testInputTx, Amount: testAmount, TxFlags: testTxFlags}); err == nil {
+		t.Error("generateSpend accepting empty private key.")
+	}
+}
+
+func TestGenerateSpendSweep(t *testing.T) {
+	btcutils.SetFixedNonce = true
+	testPrivateKeys := "5JruagvxNLXTnkksyLMfgFgf3CagJ3Ekxu5oGxpTm5mPfTAPez3,5JjHVMwJdjPEPQhq34WMUhzLcEd4SD7HgZktEh8WHstWcCLRceV"
+	testDestination := "18tiB1yNTzJMCg6bQS1Eh29dvJngq8QTfx"
+	testRedeemScript := "524104a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575fa349b5694ed3155b136f09e63975a1700c9f4d4df849323dac06cf3bd6458cd41046ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640de68c2fe913d363a51154a0c62d7adea1b822d05035077418267b1a1379790187410411ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef992f63280567f52f5ba870678b4ab4ff6c8ea600bd217870a8b4f1f09f3a8e8353ae"
+	testInputTx := "02b082113e35d5386285094c2829e7e2963fa0b5369fb7f4b79c4c90877dcd3d"
+	//Sweeping 60600 satoshis with a 5000 satoshi fee sends the same 55600 satoshis as the spend example
+	testInputAmount := 60600
+	testFee := 5000
+	testFinalTransactionHex := "01000000013dcd7d87904c9cb7f4b79f36b5a03f96e2e729284c09856238d5353e1182b00200000000fd5c010047304402206d6caac248af96f6afa7f904f550253a0f3ef3f5aa2fe6838a95b216691468e20220106d4068c7b29336dc39b96234e1b55fdbd79287eeb147d9405b189d4368b0c60147304402206d6caac248af96f6afa7f904f550253a0f3ef3f5aa2fe6838a95b216691468e202204b14745bcc78dbac7e57c5cd64fb5d351a00632293dd01d5e567b402a51ba831014cc9524104a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575fa349b5694ed3155b136f09e63975a1700c9f4d4df849323dac06cf3bd6458cd41046ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640de68c2fe913d363a51154a0c62d7adea1b822d05035077418267b1a1379790187410411ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef992f63280567f52f5ba870678b4ab4ff6c8ea600bd217870a8b4f1f09f3a8e8353aeffffffff0130d90000000000001976a914569076ba39fc4ff6a2291d9ea9196d8c08f9c7ab88ac00000000"
+
+	finalTransactionHex, _, err := generateSpend(&SpendFlags{PrivateKeys: testPrivateKeys, Destination: testDestination, RedeemScript: testRedeemScript, InputTx: testInputTx, InputAmount: testInputAmount, Fee: testFee, Sweep: true, TxFlags: testTxFlags})
+	if err != nil {
+		t.Error(err)
+	}
+	if testFinalTransactionHex != finalTransactionHex {
+		testutils.CompareError(t, "Generated sweep transaction different from expected transaction.", testFinalTransactionHex, finalTransactionHex)
+	}
+
+	//Sweep together with an explicit amount
+	if _, _, err := generateSpend(&SpendFlags{PrivateKeys: testPrivateKeys, Destination: testDestination, RedeemScript: testRedeemScript, InputTx: testInputTx, Amount: 55600, InputAmount: testInputAmount, Fee: testFee, Sweep: true, TxFlags: testTxFlags}); err == nil {
+		t.Error("generateSpend accepting --sweep with --amount.")
+	}
+	//Sweep leaving less than the 546 satoshi dust threshold of P2PKH outputs
+	if _, _, err := generateSpend(&SpendFlags{PrivateKeys: testPrivateKeys, Destination: testDestination, RedeemScript: testRedeemScript, InputTx: testInputTx, InputAmount: testFee + 546 - 1, Fee: testFee, Sweep: true, TxFlags: testTxFlags}); err == nil {
+		t.Error("generateSpend accepting sweep below dust threshold.")
+	}
+	//Fee without sweep
+	if _, _, err := generateSpend(&SpendFlags{PrivateKeys: testPrivateKeys, Destination: testDestination, RedeemScript: testRedeemScript, InputTx: testInputTx, Amount: 55600, Fee: testFee, TxFlags: testTxFlags}); err == nil {
+		t.Error("generateSpend accepting --fee without --sweep.")
+	}
+	//Input amount without sweep only checks the amount sent, leaving the legacy signatures unchanged
+	withInputAmountHex, _, err := generateSpend(&SpendFlags{PrivateKeys: testPrivateKeys, Destination: testDestination, RedeemScript: testRedeemScript, InputTx: testInputTx, Amount: 55600, InputAmount: testInputAmount, TxFlags: testTxFlags})
+	if err != nil {
+		t.Fatal(err)
+	}
+	withoutInputAmountHex, _, _ := generateSpend(&SpendFlags{PrivateKeys: testPrivateKeys, Destination: testDestination, RedeemScript: testRedeemScript, InputTx: testInputTx, Amount: 55600, TxFlags: testTxFlags})
+	if withInputAmountHex != withoutInputAmountHex {
+		testutils.CompareError(t, "Spend with --input-amount different from spend without.", withoutInputAmountHex, withInputAmountHex)
+	}
+	//Amount above the input amount, which would be a negative fee
+	if _, _, err := generateSpend(&SpendFlags{PrivateKeys: testPrivateKeys, Destination: testDestination, RedeemScript: testRedeemScript, InputTx: testInputTx, Amount: testInputAmount + 1, InputAmount: testInputAmount, TxFlags: testTxFlags}); err == nil || !strings.Contains(err.Error(), "more than the inputs hold") {
+		t.Errorf("generateSpend accepting --amount above --input-amount. %v", err)
+	}
+	if _, _, err := generateSpend(&SpendFlags{PrivateKeys: testPrivateKeys, Destination: testDestination, RedeemScript: testRedeemScript, InputTx: testInputTx, Amount: 55600, InputAmount: -1, TxFlags: testTxFlags}); err == nil {
+		t.Error("generateSpend accepting negative --input-amount.")
+	}
+}
+
+func TestGenerateSpendHighFee(t *testing.T) {
+	btcutils.SetFixedNonce = true
+	testPrivateKeys := "5JruagvxNLXTnkksyLMfgFgf3CagJ3Ekxu5oGxpTm5mPfTAPez3,5JjHVMwJdjPEPQhq34WMUhzLcEd4SD7HgZktEh8WHstWcCLRceV"
+	testDestination := "18tiB1yNTzJMCg6bQS1Eh29dvJngq8QTfx"
+	testRedeemScript := "524104a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575fa349b5694ed3155b136f09e63975a1700c9f4d4df849323dac06cf3bd6458cd41046ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640de68c2fe913d363a51154a0c62d7adea1b822d05035077418267b1a1379790187410411ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef992f63280567f52f5ba870678b4ab4ff6c8ea600bd217870a8b4f1f09f3a8e8353ae"
+	testInputTx := "02b082113e35d5386285094c2829e7e2963fa0b5369fb7f4b79c4c90877dcd3d"
+
+	//Sending 5560 satoshis, a mistyped 55600, from the 60600 satoshi input leaves a 55040 satoshi fee
+	_, _, err := generateSpend(&SpendFlags{PrivateKeys: testPrivateKeys, Destination: testDestination, RedeemScript: testRedeemScript, InputTx: testInputTx, Amount: 5560, InputAmount: 60600, TxFlags: testTxFlags})
+	if err == nil || !strings.Contains(err.Error(), "55040") {
+		t.Errorf("generateSpend accepting fee above 10%% of the amount sent. %v", err)
+	}
+	if _, _, err := generateSpend(&SpendFlags{PrivateKeys: testPrivateKeys, Destination: testDestination, RedeemScript: testRedeemScript, InputTx: testInputTx, Amount: 5560, InputAmount: 60600, TxFlags: TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: 3000, MaxFee: 1000000, ForceHighFee: true, SigHash: "default", OutputFormat: "hex", Network: "mainnet"}}); err != nil {
+		t.Error(err)
+	}
+	//Sweeping with a 5000 satoshi fee is within 10% of the 55600 satoshis sent, but not within a lowered --max-fee
+	if _, _, err := generateSpend(&SpendFlags{PrivateKeys: testPrivateKeys, Destination: testDestination, RedeemScript: testRedeemScript, InputTx: testInputTx, InputAmount: 60600, Fee: 5000, Sweep: true, TxFlags: TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: 3000, MaxFee: 4999, SigHash: "default", OutputFormat: "hex", Network: "mainnet"}}); err == nil {
+		t.Error("generateSpend accepting fee above --max-fee.")
+	}
+}
+
+func TestGenerateSpendDust(t *testing.T) {
+	btcutils.SetFixedNonce = true
+	testPrivateKeys := "5JruagvxNLXTnkksyLMfgFgf3CagJ3Ekxu5oGxpTm5mPfTAPez3,5JjHVMwJdjPEPQhq34WMUhzLcEd4SD7HgZktEh8WHstWcCLRceV"
+	testDestination := "18tiB1yNTzJMCg6bQS1Eh29dvJngq8QTfx"
+	testRedeemScript := "524104a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575fa349b5694ed3155b136f09e63975a1700c9f4d4df849323dac06cf3bd6458cd41046ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640de68c2fe913d363a51154a0c62d7adea1b822d05035077418267b1a1379790187410411ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef992f63280567f52f5ba870678b4ab4ff6c8ea600bd217870a8b4f1f09f3a8e8353ae"
+	testInputTx := "02b082113e35d5386285094c2829e7e2963fa0b5369fb7f4b79c4c90877dcd3d"
+
+	//P2PKH output below its 546 satoshi dust threshold
+	if _, _, err := generateSpend(&SpendFlags{PrivateKeys: testPrivateKeys, Destination: testDestination, RedeemScript: testRedeemScript, InputTx: testInputTx, Amount: 545, TxFlags: testTxFlags}); err == nil {
+		t.Error("generateSpend accepting output below the dust threshold.")
+	}
+	if _, _, err := generateSpend(&SpendFlags{PrivateKeys: testPrivateKeys, Destination: testDestination, RedeemScript: testRedeemScript, InputTx: testInputTx, Amount: 545, TxFlags: TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: 3000, AllowDust: true, MaxFee: 1000000, SigHash: "default", OutputFormat: "hex", Network: "mainnet"}}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestGenerateSpendDestinationTypes(t *testing.T) {
+	btcutils.SetFixedNonce = true
+	testPrivateKeys := "5JruagvxNLXTnkksyLMfgFgf3CagJ3Ekxu5oGxpTm5mPfTAPez3,5JjHVMwJdjPEPQhq34WMUhzLcEd4SD7HgZktEh8WHstWcCLRceV"
+	testRedeemScript := "524104a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575fa349b5694ed3155b136f09e63975a1700c9f4d4df849323dac06cf3bd6458cd41046ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640de68c2fe913d363a51154a0c62d7adea1b822d05035077418267b1a1379790187410411ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef992f63280567f52f5ba870678b4ab4ff6c8ea600bd217870a8b4f1f09f3a8e8353ae"
+	testInputTx := "02b082113e35d5386285094c2829e7e2963fa0b5369fb7f4b79c4c90877dcd3d"
+	testAmount := 65600
+	testCases := []struct {
+		destination     string
+		scriptPubKeyHex string
+	}{
+		{"347N1Thc213QqfYCz3PZkjoJpNv5b14kBd", "a9141a8b0026343166625c7475f01e48b5ede8c0252e87"},                                                   //P2SH
+		{"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4", "0014751e76e8199196d454941c45d1b3a323f1433bd6"},                                             //P2WPKH
+		{"bc1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3qccfmv3", "00201863143c14c5166804bd19203356da136c985678cd4d27a1b8c6329604903262"}, //P2WSH
+		{"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0", "512079be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"}, //P2TR
+	}
+	for _, testCase := range testCases {
+		finalTransactionHex, _, err := generateSpend(&SpendFlags{PrivateKeys: testPrivateKeys, Destination: testCase.destination, RedeemScript: testRedeemScript, InputTx: testInputTx, Amount: testAmount, TxFlags: testTxFlags})
+		if err != nil {
+			t.Fatal(err)
+		}
+		//Single output followed by the 4 byte lock time
+		finalTransaction, _ := hex.DecodeString(finalTransactionHex)
+		scriptPubKey, _ := hex.DecodeString(testCase.scriptPubKeyHex)
+		outputScript := finalTransaction[len(finalTransaction)-4-len(scriptPubKey) : len(finalTransaction)-4]
+		if !bytes.Equal(outputScript, scriptPubKey) || int(finalTransaction[len(finalTransaction)-5-len(scriptPubKey)]) != len(scriptPubKey) {
+			testutils.CompareError(t, "Spend output scriptPubKey different from expected scriptPubKey.", testCase.scriptPubKeyHex, hex.EncodeToString(outputScript))
+		}
+	}
+	//Unknown version byte
+	if _, _, err := generateSpend(&SpendFlags{PrivateKeys: testPrivateKeys, Destination: "5HpHagT65TZzG1PH3CSu63k8DbpvD8s5ip4nEB3kEsreAnchuDf", RedeemScript: testRedeemScript, InputTx: testInputTx, Amount: testAmount, TxFlags: testTxFlags}); err == nil {
+		t.Error("generateSpend accepting destination with an unknown version byte.")
 	}
 }
 
 func TestSignMultisigTransaction(t *testing.T) {
 	btcutils.SetFixedNonce = true //SetFixedNonce set to true to get repeatable signatures with a fixed nonce for testing.
 	{
-		testRawTransanction := []byte{1, 0, 0, 0, 1, 61, 205, 125, 135, 144, 76, 156, 183, 244, 183, 159, 54, 181, 160, 63, 150, 226, 231, 41, 40, 76, 9, 133, 98, 56, 213, 53, 62, 17, 130, 176, 2, 0, 0, 0, 0, 201, 82, 65, 4, 168, 130, 212, 20, 228, 120, 3, 156, 213, 181, 42, 146, 255, 177, 61, 213, 230, 189, 69, 21, 73, 116, 57, 223, 253, 105, 26, 15, 18, 175, 149, 117, 250, 52, 155, 86, 148, 237, 49, 85, 177, 54, 240, 158, 99, 151, 90, 23, 0, 201, 244, 212, 223, 132, 147, 35, 218, 192, 108, 243, 189, 100, 88, 205, 65, 4, 108, 227, 29, 185, 189, 213, 67, 231, 47, 227, 3, 154, 31, 28, 4, 125, 171, 135, 3, 124, 54, 166, 105, 255, 144, 226, 141, 161, 132, 143, 100, 13, 230, 140, 47, 233, 19, 211, 99, 165, 17, 84, 160, 198, 45, 122, 222, 161, 184, 34, 208, 80, 53, 7, 116, 24, 38, 123, 26, 19, 121, 121, 1, 135, 65, 4, 17, 255, 211, 108, 112, 119, 101, 56, 208, 121, 251, 174, 17, 125, 195, 142, 255, 175, 179, 51, 4, 175, 131, 206, 72, 148, 88, 151, 71, 174, 225, 239, 153, 47, 99, 40, 5, 103, 245, 47, 91, 168, 112, 103, 139, 74, 180, 255, 108, 142, 166, 0, 189, 33, 120, 112, 168, 180, 241, 240, 159, 58, 142, 131, 83, 174, 255, 255, 255, 255, 1, 48, 217, 0, 0, 0, 0, 0, 0, 25, 118, 169, 20, 86, 144, 118, 186, 57, 252, 79, 246, 162, 41, 29, 158, 169, 25, 109, 140, 8, 249, 199, 171, 136, 172, 0, 0, 0, 0, 1, 0, 0, 0}
 		testOrderedPrivateKeys := [][]byte{
 			[]byte{137, 165, 141, 245, 104, 126, 111, 88, 250, 23, 75, 123, 32, 161, 84, 132, 246, 150, 102, 14, 91, 248, 78, 160, 54, 237, 253, 196, 124, 205, 97, 198},
 			[]byte{120, 86, 226, 122, 244, 47, 75, 154, 241, 209, 174, 51, 83, 165, 92, 104, 125, 6, 106, 57, 81, 117, 39, 120, 142, 130, 212, 196, 42, 85, 199, 89},
 		}
 		testScriptPubKey := []byte{118, 169, 20, 86, 144, 118, 186, 57, 252, 79, 246, 162, 41, 29, 158, 169, 25, 109, 140, 8, 249, 199, 171, 136, 172}
-		testRedeemScript := []byte{82, 65, 4, 168, 130, 212, 20, 228, 120, 3, 156, 213, 181, 42, 146, 255, 177, 61, 213, 230, 189, 69, 21, 73, 116, 57, 223, 253, 105, 26, 15, 18, 175, 149, 117, 250, 52, 155, 86, 148, 237, 49, 85, 177, 54, 240, 158, 99, 151, 90, 23, 0, 201, 244, 212, 223, 132, 147, 35, 218, 192, 108, 243, 189, 100, 88, 205, 65, 4, 108, 227, 29, 185, 189, 213, 67, 231, 47, 227, 3, 154, 31, 28, 4, 125, 171, 135, 3, 124, 54, 166, 105, 255, 144, 226, 141, 161, 132, 143, 100, 13, 230, 140, 47, 233, 19, 211, 99, 165, 17, 84, 160, 198, 45, 122, 222, 161, 184, 34, 208, 80, 53, 7, 116, 24, 38, 123, 26, 19, 121, 121, 1, 135, 65, 4, 17, 255, 211, 108, 112, 119, 101, 56, 208, 121, 251, 174, 17, 125, 195, 142, 255, 175, 179, 51, 4, 175, 131, 206, 72, 148, 88, 151, 71, 174, 225, 239, 153, 47, 99, 40, 5, 103, 245, 47, 91, 168, 112, 103, 139, 74, 180, 255, 108, 142, 166, 0, 189, 33, 120, 112, 168, 180, 241, 240, 159, 58, 142, 131, 83, 17}
+		testRedeemScript := []byte{82, 65, 4, 168, 130, 212, 20, 228, 120, 3, 156, 213, 181, 42, 146, 255, 177, 61, 213, 230, 189, 69, 21, 73, 116, 57, 223, 253, 105, 26, 15, 18, 175, 149, 117, 250, 52, 155, 86, 148, 237, 49, 85, 177, 54, 240, 158, 99, 151, 90, 23, 0, 201, 244, 212, 223, 132, 147, 35, 218, 192, 108, 243, 189, 100, 88, 205, 65, 4, 108, 227, 29, 185, 189, 213, 67, 231, 47, 227, 3, 154, 31, 28, 4, 125, 171, 135, 3, 124, 54, 166, 105, 255, 144, 226, 141, 161, 132, 143, 100, 13, 230, 140, 47, 233, 19, 211, 99, 165, 17, 84, 160, 198, 45, 122, 222, 161, 184, 34, 208, 80, 53, 7, 116, 24, 38, 123, 26, 19, 121, 121, 1, 135, 65, 4, 17, 255, 211, 108, 112, 119, 101, 56, 208, 121, 251, 174, 17, 125, 195, 142, 255, 175, 179, 51, 4, 175, 131, 206, 72, 148, 88, 151, 71, 174, 225, 239, 153, 47, 99, 40, 5, 103, 245, 47, 91, 168, 112, 103, 139, 74, 180, 255, 108, 142, 166, 0, 189, 33, 120, 112, 168, 180, 241, 240, 159, 58, 142, 131, 83, 174}
 		testInputTx := "02b082113e35d5386285094c2829e7e2963fa0b5369fb7f4b79c4c90877dcd3d"
 		testAmount := 55600
-		testSignedTx := []byte{1, 0, 0, 0, 1, 61, 205, 125, 135, 144, 76, 156, 183, 244, 183, 159, 54, 181, 160, 63, 150, 226, 231, 41, 40, 76, 9, 133, 98, 56, 213, 53, 62, 17, 130, 176, 2, 0, 0, 0, 0, 253, 92, 1, 0, 71, 48, 68, 2, 32, 109, 108, 170, 194, 72, 175, 150, 246, 175, 167, 249, 4, 245, 80, 37, 58, 15, 62, 243, 245, 170, 47, 230, 131, 138, 149, 178, 22, 105, 20, 104, 226, 2, 32, 16, 109, 64, 104, 199, 178, 147, 54, 220, 57, 185, 98, 52, 225, 181, 95, 219, 215, 146, 135, 238, 177, 71, 217, 64, 91, 24, 157, 67, 104, 176, 198, 1, 71, 48, 68, 2, 32, 109, 108, 170, 194, 72, 175, 150, 246, 175, 167, 249, 4, 245, 80, 37, 58, 15, 62, 243, 245, 170, 47, 230, 131, 138, 149, 178, 22, 105, 20, 104, 226, 2, 32, 75, 20, 116, 91, 204, 120, 219, 172, 126, 87, 197, 205, 100, 251, 93, 53, 26, 0, 99, 34, 147, 221, 1, 213, 229, 103, 180, 2, 165, 27, 168, 49, 1, 76, 201, 82, 65, 4, 168, 130, 212, 20, 228, 120, 3, 156, 213, 181, 42, 146, 255, 177, 61, 213, 230, 189, 69, 21, 73, 116, 57, 223, 253, 105, 26, 15, 18, 175, 149, 117, 250, 52, 155, 86, 148, 237, 49, 85, 177, 54, 240, 158, 99, 151, 90, 23, 0, 201, 244, 212, 223, 132, 147, 35, 218, 192, 108, 243, 189, 100, 88, 205, 65, 4, 108, 227, 29, 185, 189, 213, 67, 231, 47, 227, 3, 154, 31, 28, 4, 125, 171, 135, 3, 124, 54, 166, 105, 255, 144, 226, 141, 161, 132, 143, 100, 13, 230, 140, 47, 233, 19, 211, 99, 165, 17, 84, 160, 198, 45, 122, 222, 161, 184, 34, 208, 80, 53, 7, 116, 24, 38, 123, 26, 19, 121, 121, 1, 135, 65, 4, 17, 255, 211, 108, 112, 119, 101, 56, 208, 121, 251, 174, 17, 125, 195, 142, 255, 175, 179, 51, 4, 175, 131, 206, 72, 148, 88, 151, 71, 174, 225, 239, 153, 47, 99, 40, 5, 103, 245, 47, 91, 168, 112, 103, 139, 74, 180, 255, 108, 142, 166, 0, 189, 33, 120, 112, 168, 180, 241, 240, 159, 58, 142, 131, 83, 17, 255, 255, 255, 255, 1, 48, 217, 0, 0, 0, 0, 0, 0, 25, 118, 169, 20, 86, 144, 118, 186, 57, 252, 79, 246, 162, 41, 29, 158, 169, 25, 109, 140, 8, 249, 199, 171, 136, 172, 0, 0, 0, 0}
+		testSignedTx := []byte{1, 0, 0, 0, 1, 61, 205, 125, 135, 144, 76, 156, 183, 244, 183, 159, 54, 181, 160, 63, 150, 226, 231, 41, 40, 76, 9, 133, 98, 56, 213, 53, 62, 17, 130, 176, 2, 0, 0, 0, 0, 253, 92, 1, 0, 71, 48, 68, 2, 32, 109, 108, 170, 194, 72, 175, 150, 246, 175, 167, 249, 4, 245, 80, 37, 58, 15, 62, 243, 245, 170, 47, 230, 131, 138, 149, 178, 22, 105, 20, 104, 226, 2, 32, 16, 109, 64, 104, 199, 178, 147, 54, 220, 57, 185, 98, 52, 225, 181, 95, 219, 215, 146, 135, 238, 177, 71, 217, 64, 91, 24, 157, 67, 104, 176, 198, 1, 71, 48, 68, 2, 32, 109, 108, 170, 194, 72, 175, 150, 246, 175, 167, 249, 4, 245, 80, 37, 58, 15, 62, 243, 245, 170, 47, 230, 131, 138, 149, 178, 22, 105, 20, 104, 226, 2, 32, 75, 20, 116, 91, 204, 120, 219, 172, 126, 87, 197, 205, 100, 251, 93, 53, 26, 0, 99, 34, 147, 221, 1, 213, 229, 103, 180, 2, 165, 27, 168, 49, 1, 76, 201, 82, 65, 4, 168, 130, 212, 20, 228, 120, 3, 156, 213, 181, 42, 146, 255, 177, 61, 213, 230, 189, 69, 21, 73, 116, 57, 223, 253, 105, 26, 15, 18, 175, 149, 117, 250, 52, 155, 86, 148, 237, 49, 85, 177, 54, 240, 158, 99, 151, 90, 23, 0, 201, 244, 212, 223, 132, 147, 35, 218, 192, 108, 243, 189, 100, 88, 205, 65, 4, 108, 227, 29, 185, 189, 213, 67, 231, 47, 227, 3, 154, 31, 28, 4, 125, 171, 135, 3, 124, 54, 166, 105, 255, 144, 226, 141, 161, 132, 143, 100, 13, 230, 140, 47, 233, 19, 211, 99, 165, 17, 84, 160, 198, 45, 122, 222, 161, 184, 34, 208, 80, 53, 7, 116, 24, 38, 123, 26, 19, 121, 121, 1, 135, 65, 4, 17, 255, 211, 108, 112, 119, 101, 56, 208, 121, 251, 174, 17, 125, 195, 142, 255, 175, 179, 51, 4, 175, 131, 206, 72, 148, 88, 151, 71, 174, 225, 239, 153, 47, 99, 40, 5, 103, 245, 47, 91, 168, 112, 103, 139, 74, 180, 255, 108, 142, 166, 0, 189, 33, 120, 112, 168, 180, 241, 240, 159, 58, 142, 131, 83, 174, 255, 255, 255, 255, 1, 48, 217, 0, 0, 0, 0, 0, 0, 25, 118, 169, 20, 86, 144, 118, 186, 57, 252, 79, 246, 162, 41, 29, 158, 169, 25, 109, 140, 8, 249, 199, 171, 136, 172, 0, 0, 0, 0}
 
-		signedTx, err := signMultisigTransaction(testRawTransanction, testOrderedPrivateKeys, testScriptPubKey, testRedeemScript, testInputTx, testAmount)
+		testTx := &btcutils.Transaction{Version: 1, Inputs: []btcutils.Input{{TxHash: testInputTx, OutputIndex: 0, Sequence: btcutils.SequenceFinal}}, Outputs: []btcutils.Output{{Satoshis: uint64(testAmount), ScriptPubKey: testScriptPubKey}}}
+		signedTx, err := signMultisigTransaction(testTx, testOrderedPrivateKeys, testRedeemScript, btcutils.SigHashAll)
 		if err != nil {
 			t.Error(err)
 		}
@@ -76,3 +350,189 @@ func TestSignMultisigTransaction(t *testing.T) {
 		}
 	}
 }
+
+func TestGenerateSpendRFC6979(t *testing.T) {
+	//Signs the README example with RFC 6979 deterministic nonces rather than the fixed testing nonce. Expected
+	//transactions computed independently, signature hashes and nonces included
+	previousSetFixedNonce := btcutils.SetFixedNonce
+	btcutils.SetFixedNonce = false
+	defer func() { btcutils.SetFixedNonce, btcutils.DisableLowR = previousSetFixedNonce, false }()
+	testPrivateKeys := "5JruagvxNLXTnkksyLMfgFgf3CagJ3Ekxu5oGxpTm5mPfTAPez3,5JjHVMwJdjPEPQhq34WMUhzLcEd4SD7HgZktEh8WHstWcCLRceV"
+	testDestination := "18tiB1yNTzJMCg6bQS1Eh29dvJngq8QTfx"
+	testRedeemScript := "524104a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575fa349b5694ed3155b136f09e63975a1700c9f4d4df849323dac06cf3bd6458cd41046ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640de68c2fe913d363a51154a0c62d7adea1b822d05035077418267b1a1379790187410411ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef992f63280567f52f5ba870678b4ab4ff6c8ea600bd217870a8b4f1f09f3a8e8353ae"
+	testInputTx := "02b082113e35d5386285094c2829e7e2963fa0b5369fb7f4b79c4c90877dcd3d"
+	testAmount := 55600
+	testCases := []struct {
+		disableLowR         bool
+		finalTransactionHex string
+	}{
+		//The first nonces give 72 byte high R signatures, extra data counters 1 and 4 are the first with low R
+		{false, "01000000013dcd7d87904c9cb7f4b79f36b5a03f96e2e729284c09856238d5353e1182b00200000000fd5c01004730440220288cda5e24e4974c57400f1168a0e2423619ed53b21100a1a939de0131f3051702207190d948558bb478a4b4b1d5eeb0a71e4fedd353dcd6432ce96e7d164b86756b01473044022072cdadb0ed013a1104c051cf3bcad78c32746c7f1022b4ebd00e64dddaf910b302205451cf574866dade3e96fafd2e36d87013c66f441bbf790910e11303d72ead55014cc9524104a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575fa349b5694ed3155b136f09e63975a1700c9f4d4df849323dac06cf3bd6458cd41046ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640de68c2fe913d363a51154a0c62d7adea1b822d05035077418267b1a1379790187410411ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef992f63280567f52f5ba870678b4ab4ff6c8ea600bd217870a8b4f1f09f3a8e8353aeffffffff0130d90000000000001976a914569076ba39fc4ff6a2291d9ea9196d8c08f9c7ab88ac00000000"},
+		{true, "01000000013dcd7d87904c9cb7f4b79f36b5a03f96e2e729284c09856238d5353e1182b00200000000fd5e0100483045022100af4831eb0cee1b9642ff8691acb53c2fd8e28bbd6c0389af69c132b342405c8502200627e67cbb0bee502421be4d2b8e370d24eb17ff7c3d77d604a5c07ee5934a74014830450221009bfee48a5ae99a8fc0f78c631b0b2beb6d96337fc9d1e95c333a08bdcecb3647022040a30b3528b136821077d0bb45c324d7eedcd0ac61af7019ba1286e9277b7c54014cc9524104a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575fa349b5694ed3155b136f09e63975a1700c9f4d4df849323dac06cf3bd6458cd41046ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640de68c2fe913d363a51154a0c62d7adea1b822d05035077418267b1a1379790187410411ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef992f63280567f52f5ba870678b4ab4ff6c8ea600bd217870a8b4f1f09f3a8e8353aeffffffff0130d90000000000001976a914569076ba39fc4ff6a2291d9ea9196d8c08f9c7ab88ac00000000"},
+	}
+
+	for _, testCase := range testCases {
+		btcutils.DisableLowR = testCase.disableLowR
+		//Signing twice gives the same bytes
+		for i := 0; i < 2; i++ {
+			finalTransactionHex, _, err := generateSpend(&SpendFlags{PrivateKeys: testPrivateKeys, Destination: testDestination, RedeemScript: testRedeemScript, InputTx: testInputTx, Amount: testAmount, TxFlags: testTxFlags})
+			if err != nil {
+				t.Error(err)
+			}
+			if testCase.finalTransactionHex != finalTransactionHex {
+				testutils.CompareError(t, "Generated spend transaction different from expected transaction.", testCase.finalTransactionHex, finalTransactionHex)
+			}
+		}
+	}
+}
+
+func TestGenerateFundThenSpend(t *testing.T) {
+	btcutils.SetFixedNonce = true
+	//2-of-3 multisig address, funded from a P2PKH output and then spent by the first two cosigners
+	testPublicKeys := []string{
+		"025476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee6357",
+		"03b6446545566e0fecb2f2243db709bbf4e9fff78d48c9438acc911a36015e896a",
+		"033d63026f5f0de46e40dc09c61c1559240ea4381df7e05045f6ae2ac506efbaf2",
+	}
+	testPrivateKeys := "KzVTBhbMaKrAYagJ11VdTaBrb6yzLykLGyuMBkf9sCFPDxdT8shL,L4euB5ik61Qcp67f5DLo3jiF2uumTMMTa6i8ys2SW82g8PzNJZTc"
+	testDestination := "1DJrhysUSzjNhP1GYJkgQkkEtCTgnnEWXi"
+	P2SHAddress, _, redeemScriptHex, err := generateAddress(2, 3, strings.Join(testPublicKeys, ","), false, "mainnet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fundTransactionHex, _, _, err := generateFund(&FundFlags{PrivateKey: "5JJyqG4bb15zqi7fTA4b227aUxQhBo1Ux6qX69ngeXYLr7fk2hs", InputTx: "3ad337270ac0ba14fbce812291b7d95338c878709ea8123a4d88c3c29efbc6ac", Amount: 65600, Destinations: []string{P2SHAddress}, TxFlags: testTxFlags})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fundTransaction, _ := hex.DecodeString(fundTransactionHex)
+	fundTxID, err := btcutils.TxID(fundTransaction)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	finalTransactionHex, _, err := generateSpend(&SpendFlags{PrivateKeys: testPrivateKeys, Destination: testDestination, RedeemScript: redeemScriptHex, InputTx: fundTxID, Amount: 60000, TxFlags: testTxFlags})
+	if err != nil {
+		t.Fatal(err)
+	}
+	finalTransaction, _ := hex.DecodeString(finalTransactionHex)
+	tx, err := btcutils.DeserializeTransaction(finalTransaction)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tx.Inputs) != 1 || tx.Inputs[0].TxHash != fundTxID || tx.Inputs[0].OutputIndex != 0 {
+		t.Errorf("Spend transaction not spending output 0 of funding transaction %s.", fundTxID)
+	}
+	if len(tx.Outputs) != 1 || tx.Outputs[0].Satoshis != 60000 {
+		t.Error("Spend transaction not paying 60000 Satoshis to the destination.")
+	}
+	//The P2SH output funded is the one spent by the redeem script
+	fundTx, _ := btcutils.DeserializeTransaction(fundTransaction)
+	redeemScript, _ := hex.DecodeString(redeemScriptHex)
+	redeemScriptHash, _ := btcutils.Hash160(redeemScript)
+	scriptPubKey, _ := btcutils.NewP2SHScriptPubKey(redeemScriptHash)
+	if !bytes.Equal(fundTx.Outputs[0].ScriptPubKey, scriptPubKey) {
+		t.Error("Funding transaction not paying to the P2SH scriptPubKey of the redeem script.")
+	}
+	checkMultisigSpend(t, finalTransactionHex, redeemScriptHex, testPublicKeys[:2])
+}
+
+func TestSignMultisigTransactionScriptSigSize(t *testing.T) {
+	btcutils.SetFixedNonce = true
+	//Largest low R signature with its hash type byte, the size EstimateMultisigScriptSigSize assumes for each signature
+	const estimatedSignatureLength = 72
+	testCases := []struct {
+		m int
+		n int
+	}{
+		{1, 2},
+		{15, 15},
+	}
+	for _, testCase := range testCases {
+		privateKeys := make([][]byte, testCase.n)
+		publicKeys := make([][]byte, testCase.n)
+		for i := range privateKeys {
+			privateKeys[i] = bytes.Repeat([]byte{byte(i + 1)}, 32)
+			publicKeys[i], _ = btcutils.NewCompressedPublicKey(privateKeys[i])
+		}
+		redeemScript, err := btcutils.CreateMultiSigRedeemScript(testCase.m, publicKeys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tx := &btcutils.Transaction{Version: 2, Inputs: []btcutils.Input{{TxHash: "02b082113e35d5386285094c2829e7e2963fa0b5369fb7f4b79c4c90877dcd3d", OutputIndex: 0, Sequence: btcutils.SequenceFinal}}, Outputs: []btcutils.Output{{Satoshis: 55600, ScriptPubKey: []byte{btcutils.OP_RETURN}}}}
+		if _, err := signMultisigTransaction(tx, privateKeys[:testCase.m], redeemScript, btcutils.SigHashAll); err != nil {
+			t.Fatal(err)
+		}
+		scriptSig := tx.Inputs[0].ScriptSig
+		pushes, err := txscript.PushedData(scriptSig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pushes) != testCase.m+2 || !bytes.Equal(pushes[len(pushes)-1], redeemScript) {
+			t.Fatalf("%d-of-%d scriptSig %x does not push OP_0, %d signatures and the redeem script.", testCase.m, testCase.n, scriptSig, testCase.m)
+		}
+		//Signatures can come out shorter than the estimate assumes, pad the actual length by the difference
+		actualLength := len(scriptSig)
+		for _, signature := range pushes[1 : len(pushes)-1] {
+			actualLength += estimatedSignatureLength - len(signature)
+		}
+		if estimate := btcutils.EstimateMultisigScriptSigSize(testCase.m, len(redeemScript)); estimate != actualLength {
+			t.Errorf("%d-of-%d scriptSig size estimate %d different from actual size %d.", testCase.m, testCase.n, estimate, actualLength)
+		}
+	}
+}
+
+func TestGenerateSpendRedeemScriptPush(t *testing.T) {
+	btcutils.SetFixedNonce = true
+	privateKeys := [][]byte{bytes.Repeat([]byte{1}, 32), bytes.Repeat([]byte{2}, 32)}
+	compressedPublicKey, _ := btcutils.NewCompressedPublicKey(privateKeys[0])
+	compressedWIF, _ := btcutils.EncodeWIF(privateKeys[0], true, &btcutils.MainNetParams)
+	var uncompressedPublicKeys [][]byte
+	var uncompressedWIFs []string
+	for _, privateKey := range privateKeys {
+		publicKey, _ := btcutils.NewPublicKey(privateKey)
+		uncompressedPublicKeys = append(uncompressedPublicKeys, publicKey)
+		wif, _ := btcutils.EncodeWIF(privateKey, false, &btcutils.MainNetParams)
+		uncompressedWIFs = append(uncompressedWIFs, wif)
+	}
+	//1-of-1 has a 37 byte redeem script, pushed by its length alone
+	oneOfOne, err := btcutils.CreateMultiSigRedeemScript(1, [][]byte{compressedPublicKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	//<117 bytes> OP_DROP followed by 2-of-2 with uncompressed keys is 255 bytes, the longest OP_PUSHDATA1 push
+	twoOfTwo, err := btcutils.CreateMultiSigRedeemScript(2, uncompressedPublicKeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	longRedeemScript, _ := txscript.NewBuilder().AddData(make([]byte, 117)).AddOp(txscript.OP_DROP).Script()
+	longRedeemScript = append(longRedeemScript, twoOfTwo...)
+	if len(longRedeemScript) != 255 {
+		t.Fatalf("Test redeem script is %d bytes, expected 255.", len(longRedeemScript))
+	}
+	testCases := []struct {
+		privateKeys  string
+		redeemScript []byte
+		push         []byte
+	}{
+		{compressedWIF, oneOfOne, []byte{37}},
+		{strings.Join(uncompressedWIFs, ","), longRedeemScript, []byte{btcutils.OP_PUSHDATA1, 255}},
+	}
+	for _, testCase := range testCases {
+		finalTransactionHex, _, err := generateSpend(&SpendFlags{PrivateKeys: testCase.privateKeys, Destination: "18tiB1yNTzJMCg6bQS1Eh29dvJngq8QTfx", RedeemScript: hex.EncodeToString(testCase.redeemScript), InputTx: "02b082113e35d5386285094c2829e7e2963fa0b5369fb7f4b79c4c90877dcd3d", Amount: 55600, InputAmount: 60000, TxFlags: testTxFlags})
+		if err != nil {
+			t.Fatal(err)
+		}
+		finalTransaction, _ := hex.DecodeString(finalTransactionHex)
+		tx, err := btcutils.DeserializeTransaction(finalTransaction)
+		if err != nil {
+			t.Fatal(err)
+		}
+		scriptSig := tx.Inputs[0].ScriptSig
+		if !bytes.HasSuffix(scriptSig, append(testCase.push, testCase.redeemScript...)) {
+			t.Errorf("scriptSig %x does not end with the redeem script pushed by %x.", scriptSig, testCase.push)
+		}
+		redeemScriptHash, _ := btcutils.Hash160(testCase.redeemScript)
+		prevScriptPubKey, _ := btcutils.NewP2SHScriptPubKey(redeemScriptHash)
+		checkBuilderTransaction(t, finalTransaction, prevScriptPubKey, 60000)
+	}
+}
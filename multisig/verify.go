@@ -0,0 +1,96 @@
+// verify.go - Verifying the scripts of a transaction before broadcasting it.
+package multisig
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/btcutils"
+
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// OutputVerify formats and prints relevant outputs to the user.
+func OutputVerify(flagTx string, flagPrevOuts []string, flagNetwork string) error {
+	tx, results, err := generateVerify(flagTx, flagPrevOuts, flagNetwork)
+	if err != nil {
+		return err
+	}
+	failed := 0
+	fmt.Printf(`
+-----------------------------------------------------------------------------------------------------------------------------------
+`)
+	for i, result := range results {
+		if result != nil {
+			failed++
+			fmt.Printf("Input %d spending %s:%d FAILED.\n%s\n", i, tx.Inputs[i].TxHash, tx.Inputs[i].OutputIndex, result)
+		} else {
+			fmt.Printf("Input %d spending %s:%d is valid.\n", i, tx.Inputs[i].TxHash, tx.Inputs[i].OutputIndex)
+		}
+	}
+	fmt.Printf(`-----------------------------------------------------------------------------------------------------------------------------------
+`)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d inputs failed verification. Do not broadcast this transaction.", failed, len(results))
+	}
+	return nil
+}
+
+// generateVerify is the high-level logic for verifying a transaction with the 'go-bitcoin-multisig verify'
+// subcommand. Takes flagTx (signed transaction in hex), flagPrevOuts (the output spent by each input, in input order,
+// as ADDRESS:AMOUNT or SCRIPTPUBKEY:AMOUNT with the scriptPubKey in hex, the amount in satoshis being needed by SegWit
+// inputs only) and flagNetwork (name of the network addresses are encoded for) as arguments.
+// Returns the decoded transaction and the result of btcutils.VerifyInputScript for each input, nil for valid inputs.
+func generateVerify(flagTx string, flagPrevOuts []string, flagNetwork string) (*btcutils.Transaction, []error, error) {
+	params, err := btcutils.NetworkParamsByName(flagNetwork)
+	if err != nil {
+		return nil, nil, err
+	}
+	rawTx, err := hex.DecodeString(strings.TrimSpace(flagTx))
+	if err != nil {
+		return nil, nil, fmt.Errorf("Invalid --tx. %s", err)
+	}
+	tx, err := btcutils.DeserializeTransaction(rawTx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Invalid --tx. %s", err)
+	}
+	if len(flagPrevOuts) != len(tx.Inputs) {
+		return nil, nil, fmt.Errorf("Transaction has %d inputs, but %d --prevout were given. Give one per input, in input order.", len(tx.Inputs), len(flagPrevOuts))
+	}
+	prevOuts, err := parsePrevOuts(flagPrevOuts, params)
+	if err != nil {
+		return nil, nil, err
+	}
+	results := make([]error, len(tx.Inputs))
+	for i, prevOut := range prevOuts {
+		results[i] = btcutils.VerifyInputScript(tx, i, prevOut.ScriptPubKey, int64(prevOut.Satoshis))
+	}
+	return tx, results, nil
+}
+
+// parsePrevOuts parses each --prevout, ADDRESS[:AMOUNT] or SCRIPTPUBKEY[:AMOUNT] with the scriptPubKey in hex, into
+// the output it describes. A missing amount is zero.
+func parsePrevOuts(flagPrevOuts []string, params *btcutils.NetworkParams) ([]btcutils.Output, error) {
+	prevOuts := make([]btcutils.Output, len(flagPrevOuts))
+	for i, flagPrevOut := range flagPrevOuts {
+		script := flagPrevOut
+		if separator := strings.LastIndex(flagPrevOut, ":"); separator >= 0 {
+			script = flagPrevOut[:separator]
+			amount, err := strconv.ParseUint(flagPrevOut[separator+1:], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid amount in --prevout %s. Amount should be a number of satoshis.", flagPrevOut)
+			}
+			prevOuts[i].Satoshis = amount
+		}
+		scriptPubKey, err := btcutils.NewScriptPubKeyFromAddress(script, params)
+		if err != nil {
+			scriptPubKey, err = hex.DecodeString(script)
+		}
+		if err != nil || len(scriptPubKey) == 0 {
+			return nil, errors.New("Invalid --prevout " + flagPrevOut + ". Give the address or scriptPubKey in hex of the output spent.")
+		}
+		prevOuts[i].ScriptPubKey = scriptPubKey
+	}
+	return prevOuts, nil
+}
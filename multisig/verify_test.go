@@ -0,0 +1,95 @@
+package multisig
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/btcutils"
+
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestGenerateVerify(t *testing.T) {
+	btcutils.SetFixedNonce = true
+	testPublicKeys := "025476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee6357,03b6446545566e0fecb2f2243db709bbf4e9fff78d48c9438acc911a36015e896a,033d63026f5f0de46e40dc09c61c1559240ea4381df7e05045f6ae2ac506efbaf2"
+	testPrivateKeys := "KzVTBhbMaKrAYagJ11VdTaBrb6yzLykLGyuMBkf9sCFPDxdT8shL,L4euB5ik61Qcp67f5DLo3jiF2uumTMMTa6i8ys2SW82g8PzNJZTc"
+	testDestination := "1DJrhysUSzjNhP1GYJkgQkkEtCTgnnEWXi"
+	testInputTx := "8ac60eb9575db5b2d987e29f301b5b819ea83a5c6579d282d189cc04b8e151ef"
+
+	//2-of-3 P2SH multisig spend, which only needs the address spent from
+	P2SHAddress, _, redeemScriptHex, err := generateAddress(2, 3, testPublicKeys, false, "mainnet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	spendHex, _, err := generateSpend(&SpendFlags{PrivateKeys: testPrivateKeys, Destination: testDestination, RedeemScript: redeemScriptHex, InputTx: testInputTx, Amount: 60000, TxFlags: TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: 3000, MaxFee: 1000000, ForceHighFee: true, SigHash: "default", OutputFormat: "hex", Network: "mainnet"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	//The same output spent natively and nested in P2SH by P2WSH, with the amount the BIP143 signatures commit to
+	witnessScript, _ := hex.DecodeString(redeemScriptHex)
+	P2WSHAddress, _ := btcutils.RedeemScriptToP2WSHAddress(witnessScript, &btcutils.MainNetParams)
+	P2SHP2WSHAddress, _ := btcutils.RedeemScriptToP2SHP2WSHAddress(witnessScript, &btcutils.MainNetParams)
+	redeemP2WSHHex, err := generateRedeemP2WSH(&RedeemP2WSHFlags{PrivateKeys: testPrivateKeys, Destination: testDestination, WitnessScript: redeemScriptHex, InputTx: testInputTx, InputAmount: 100000, Amount: 90000, TxFlags: TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: 3000, MaxFee: 1000000, ForceHighFee: true, SigHash: "default", OutputFormat: "hex", Network: "mainnet"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	redeemP2SHP2WSHHex, err := generateRedeemP2WSH(&RedeemP2WSHFlags{PrivateKeys: testPrivateKeys, Destination: testDestination, WitnessScript: redeemScriptHex, Nested: true, InputTx: testInputTx, InputAmount: 100000, Amount: 90000, TxFlags: TxFlags{Sequence: 0xffffffff, TxVersion: 1, DustRelayFee: 3000, MaxFee: 1000000, ForceHighFee: true, SigHash: "default", OutputFormat: "hex", Network: "mainnet"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	P2SHScriptPubKey, _ := btcutils.NewScriptPubKeyFromAddress(P2SHAddress, &btcutils.MainNetParams)
+
+	testCases := []struct {
+		name    string
+		txHex   string
+		prevOut string
+	}{
+		{"P2SH", spendHex, P2SHAddress},
+		{"P2SH given as scriptPubKey", spendHex, hex.EncodeToString(P2SHScriptPubKey)},
+		{"P2WSH", redeemP2WSHHex, P2WSHAddress + ":100000"},
+		{"P2SH-P2WSH", redeemP2SHP2WSHHex, P2SHP2WSHAddress + ":100000"},
+	}
+	for _, testCase := range testCases {
+		_, results, err := generateVerify(testCase.txHex, []string{testCase.prevOut}, "mainnet")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if results[0] != nil {
+			t.Errorf("%s spend not verified. %s", testCase.name, results[0])
+		}
+	}
+
+	failingCases := []struct {
+		name    string
+		txHex   string
+		prevOut string
+		reason  string
+	}{
+		{"P2SH of another address", spendHex, "3Fh4BBqrshHn1qc7pFf294rFGbNsXNDcDa", "scriptPubKey ended without true on top of the stack."},
+		{"P2WSH with wrong amount", redeemP2WSHHex, P2WSHAddress + ":90000", "Witness script failed. OP_CHECKMULTISIG at offset 104 failed. Only 0 of 2 signatures valid"},
+		{"P2WSH without amount", redeemP2WSHHex, P2WSHAddress, "Witness script failed. OP_CHECKMULTISIG"},
+	}
+	for _, testCase := range failingCases {
+		_, results, err := generateVerify(testCase.txHex, []string{testCase.prevOut}, "mainnet")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if results[0] == nil || !strings.Contains(results[0].Error(), testCase.reason) {
+			t.Errorf("Verifying %s failed with %v, expected %q.", testCase.name, results[0], testCase.reason)
+		}
+	}
+
+	invalidFlags := map[string][]string{
+		"no --prevout":             nil,
+		"two --prevout":            {P2SHAddress, P2SHAddress},
+		"invalid --prevout":        {"not an address"},
+		"invalid --prevout amount": {P2SHAddress + ":-1"},
+	}
+	for name, flagPrevOuts := range invalidFlags {
+		if _, _, err := generateVerify(spendHex, flagPrevOuts, "mainnet"); err == nil {
+			t.Errorf("generateVerify accepting %s.", name)
+		}
+	}
+	if _, _, err := generateVerify(spendHex[:len(spendHex)-2], []string{P2SHAddress}, "mainnet"); err == nil {
+		t.Error("generateVerify accepting truncated --tx.")
+	}
+}
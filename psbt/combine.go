@@ -0,0 +1,223 @@
+// PSBT combiner role, merging copies of a PSBT signed independently by different cosigners.
+package psbt
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// CombinePSBTs merges psbts, which must all have the same unsigned transaction, into a new PSBT holding the fields
+// of each of them, as the BIP174 combiner does: partial signatures, scripts, BIP32 derivations, UTXOs, final
+// scriptSigs and witnesses, and unknown fields. The same field may be found in several PSBTs, as each cosigner
+// keeps the fields of the PSBT they were given, as long as it has the same value in all of them.
+// Returns an error, leaving psbts unchanged, if there are no PSBTs, the unsigned transactions are different, or a
+// field has different values in two PSBTs, such as two signatures of the same public key with different hash types.
+func CombinePSBTs(psbts []*PSBT) (*PSBT, error) {
+	if len(psbts) == 0 {
+		return nil, errors.New("No PSBTs to combine.")
+	}
+	//The first PSBT is copied through its serialization, so merging the others never changes it
+	serialized, err := psbts[0].Serialize()
+	if err != nil {
+		return nil, err
+	}
+	combined, err := Deserialize(serialized)
+	if err != nil {
+		return nil, err
+	}
+	unsignedTx, err := combined.UnsignedTx.Serialize()
+	if err != nil {
+		return nil, err
+	}
+	for i, p := range psbts[1:] {
+		otherTx, err := p.UnsignedTx.Serialize()
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(otherTx, unsignedTx) {
+			return nil, fmt.Errorf("PSBT %d has a different unsigned transaction than PSBT 1.", i+2)
+		}
+		if len(p.Inputs) != len(combined.Inputs) || len(p.Outputs) != len(combined.Outputs) {
+			return nil, fmt.Errorf("PSBT %d does not have a map for each input and output of its unsigned transaction.", i+2)
+		}
+		err = combine(combined, p)
+		if err != nil {
+			return nil, fmt.Errorf("Cannot combine PSBT %d. %s", i+2, err)
+		}
+	}
+	return combined, nil
+}
+
+// combine adds the fields of other to p, which have the same unsigned transaction and so the same number of inputs
+// and outputs. Returns an error if a field of other has a different value in p.
+func combine(p *PSBT, other *PSBT) error {
+	err := combineMaps("global unknown field", p.Unknowns, other.Unknowns)
+	if err != nil {
+		return err
+	}
+	for i := range p.Inputs {
+		err = p.Inputs[i].combine(&other.Inputs[i])
+		if err != nil {
+			return fmt.Errorf("Input %d: %s", i, err)
+		}
+	}
+	for i := range p.Outputs {
+		err = p.Outputs[i].combine(&other.Outputs[i])
+		if err != nil {
+			return fmt.Errorf("Output %d: %s", i, err)
+		}
+	}
+	return nil
+}
+
+// combine adds the fields of other to input. Returns an error if a field of other has a different value in input.
+func (input *Input) combine(other *Input) error {
+	if other.NonWitnessUtxo != nil {
+		if input.NonWitnessUtxo == nil {
+			input.NonWitnessUtxo = other.NonWitnessUtxo
+		} else {
+			utxo, err := input.NonWitnessUtxo.Serialize()
+			if err != nil {
+				return err
+			}
+			otherUtxo, err := other.NonWitnessUtxo.Serialize()
+			if err != nil {
+				return err
+			}
+			if !bytes.Equal(utxo, otherUtxo) {
+				return errors.New("Conflicting non-witness UTXOs.")
+			}
+		}
+	}
+	if other.WitnessUtxo != nil {
+		if input.WitnessUtxo == nil {
+			input.WitnessUtxo = other.WitnessUtxo
+		} else if input.WitnessUtxo.Satoshis != other.WitnessUtxo.Satoshis || !bytes.Equal(input.WitnessUtxo.ScriptPubKey, other.WitnessUtxo.ScriptPubKey) {
+			return errors.New("Conflicting witness UTXOs.")
+		}
+	}
+	err := combineMaps("partial signature of public key", input.PartialSigs, other.PartialSigs)
+	if err != nil {
+		return err
+	}
+	if other.SigHashType != 0 {
+		if input.SigHashType == 0 {
+			input.SigHashType = other.SigHashType
+		} else if input.SigHashType != other.SigHashType {
+			return fmt.Errorf("Conflicting hash types 0x%02x and 0x%02x.", uint32(input.SigHashType), uint32(other.SigHashType))
+		}
+	}
+	err = combineBytes("redeemScript", &input.RedeemScript, other.RedeemScript)
+	if err != nil {
+		return err
+	}
+	err = combineBytes("witnessScript", &input.WitnessScript, other.WitnessScript)
+	if err != nil {
+		return err
+	}
+	err = combineBip32Derivations(input.Bip32Derivations, other.Bip32Derivations)
+	if err != nil {
+		return err
+	}
+	err = combineBytes("final scriptSig", &input.FinalScriptSig, other.FinalScriptSig)
+	if err != nil {
+		return err
+	}
+	if len(other.FinalScriptWitness) > 0 {
+		if len(input.FinalScriptWitness) == 0 {
+			input.FinalScriptWitness = other.FinalScriptWitness
+		} else if !equalWitnesses(input.FinalScriptWitness, other.FinalScriptWitness) {
+			return errors.New("Conflicting final witnesses.")
+		}
+	}
+	err = combineBytes("Taproot key signature", &input.TaprootKeySig, other.TaprootKeySig)
+	if err != nil {
+		return err
+	}
+	return combineMaps("unknown field", input.Unknowns, other.Unknowns)
+}
+
+// combine adds the fields of other to output. Returns an error if a field of other has a different value in output.
+func (output *Output) combine(other *Output) error {
+	err := combineBytes("redeemScript", &output.RedeemScript, other.RedeemScript)
+	if err != nil {
+		return err
+	}
+	err = combineBytes("witnessScript", &output.WitnessScript, other.WitnessScript)
+	if err != nil {
+		return err
+	}
+	err = combineBip32Derivations(output.Bip32Derivations, other.Bip32Derivations)
+	if err != nil {
+		return err
+	}
+	return combineMaps("unknown field", output.Unknowns, other.Unknowns)
+}
+
+// combineBytes sets *field to other if it is empty. Returns an error naming the field if both are set to different
+// values.
+func combineBytes(name string, field *[]byte, other []byte) error {
+	if len(other) == 0 {
+		return nil
+	}
+	if len(*field) == 0 {
+		*field = other
+		return nil
+	}
+	if !bytes.Equal(*field, other) {
+		return fmt.Errorf("Conflicting %ss.", name)
+	}
+	return nil
+}
+
+// combineMaps adds the entries of other to m. Returns an error naming the entry if a key has different values.
+func combineMaps(name string, m map[string][]byte, other map[string][]byte) error {
+	for key, value := range other {
+		existing, ok := m[key]
+		if ok && !bytes.Equal(existing, value) {
+			return fmt.Errorf("Conflicting values for %s %s.", name, key)
+		}
+		m[key] = value
+	}
+	return nil
+}
+
+// combineBip32Derivations adds the derivations of other to derivations. Returns an error if a public key has
+// different derivations.
+func combineBip32Derivations(derivations map[string]Bip32Derivation, other map[string]Bip32Derivation) error {
+	for publicKeyHex, derivation := range other {
+		existing, ok := derivations[publicKeyHex]
+		if ok && !equalBip32Derivations(existing, derivation) {
+			return fmt.Errorf("Conflicting BIP32 derivations for public key %s.", publicKeyHex)
+		}
+		derivations[publicKeyHex] = derivation
+	}
+	return nil
+}
+
+// equalBip32Derivations reports whether a and b have the same master key fingerprint and path.
+func equalBip32Derivations(a Bip32Derivation, b Bip32Derivation) bool {
+	if !bytes.Equal(a.MasterKeyFingerprint, b.MasterKeyFingerprint) || len(a.Path) != len(b.Path) {
+		return false
+	}
+	for i := range a.Path {
+		if a.Path[i] != b.Path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// equalWitnesses reports whether a and b have the same stack items.
+func equalWitnesses(a [][]byte, b [][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !bytes.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
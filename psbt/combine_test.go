@@ -0,0 +1,95 @@
+package psbt
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/btcutils"
+	"github.com/CryptoProcessing/go-bitcoin-multisig/testutils"
+
+	"encoding/hex"
+	"testing"
+)
+
+// newTestCombinePSBT returns the 2-of-3 P2SH multisig PSBT of the README spend example with its redeemScript, signed
+// by each of privateKeys.
+func newTestCombinePSBT(t *testing.T, privateKeys ...string) *PSBT {
+	testRedeemScript, _ := hex.DecodeString("524104a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575fa349b5694ed3155b136f09e63975a1700c9f4d4df849323dac06cf3bd6458cd41046ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640de68c2fe913d363a51154a0c62d7adea1b822d05035077418267b1a1379790187410411ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef992f63280567f52f5ba870678b4ab4ff6c8ea600bd217870a8b4f1f09f3a8e8353ae")
+	p := newTestPSBT(t, "02b082113e35d5386285094c2829e7e2963fa0b5369fb7f4b79c4c90877dcd3d", 0, 55600, "76a914569076ba39fc4ff6a2291d9ea9196d8c08f9c7ab88ac")
+	redeemScriptHash, _ := btcutils.Hash160(testRedeemScript)
+	scriptPubKey, _ := btcutils.NewP2SHScriptPubKey(redeemScriptHash)
+	p.Inputs[0].RedeemScript = testRedeemScript
+	for _, privateKey := range privateKeys {
+		err := Sign(p, 0, parseTestWIF(t, privateKey), &btcutils.Output{Satoshis: 65600, ScriptPubKey: scriptPubKey})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return p
+}
+
+func TestCombinePSBTs(t *testing.T) {
+	testPrivateKeys := []string{"5JruagvxNLXTnkksyLMfgFgf3CagJ3Ekxu5oGxpTm5mPfTAPez3", "5JjHVMwJdjPEPQhq34WMUhzLcEd4SD7HgZktEh8WHstWcCLRceV"}
+	testSigned, _ := newTestCombinePSBT(t, testPrivateKeys...).Serialize()
+
+	//Disjoint signatures, each cosigner signing their own copy
+	first := newTestCombinePSBT(t, testPrivateKeys[0])
+	firstSerialized, _ := first.Serialize()
+	second := newTestCombinePSBT(t, testPrivateKeys[1])
+	combined, err := CombinePSBTs([]*PSBT{first, second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	serialized, err := combined.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(serialized) != hex.EncodeToString(testSigned) {
+		testutils.CompareError(t, "Combined PSBT different from PSBT signed by both keys.", hex.EncodeToString(testSigned), hex.EncodeToString(serialized))
+	}
+	if serialized, _ := first.Serialize(); hex.EncodeToString(serialized) != hex.EncodeToString(firstSerialized) {
+		t.Error("CombinePSBTs changing the PSBTs it combines.")
+	}
+
+	//Overlapping identical records, the redeemScript and the first signature in both
+	both := newTestCombinePSBT(t, testPrivateKeys...)
+	combined, err = CombinePSBTs([]*PSBT{first, both, second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	serialized, _ = combined.Serialize()
+	if hex.EncodeToString(serialized) != hex.EncodeToString(testSigned) {
+		testutils.CompareError(t, "Combined overlapping PSBTs different from PSBT signed by both keys.", hex.EncodeToString(testSigned), hex.EncodeToString(serialized))
+	}
+
+	//Conflicting signatures of the same key, signed with different hash types
+	conflicting := newTestCombinePSBT(t)
+	conflicting.Inputs[0].SigHashType = btcutils.SigHashNone
+	redeemScriptHash, _ := btcutils.Hash160(conflicting.Inputs[0].RedeemScript)
+	scriptPubKey, _ := btcutils.NewP2SHScriptPubKey(redeemScriptHash)
+	err = Sign(conflicting, 0, parseTestWIF(t, testPrivateKeys[0]), &btcutils.Output{Satoshis: 65600, ScriptPubKey: scriptPubKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conflicting.Inputs[0].SigHashType = 0
+	if _, err := CombinePSBTs([]*PSBT{first, conflicting}); err == nil {
+		t.Error("CombinePSBTs accepting conflicting partial signatures.")
+	}
+	conflicting = newTestCombinePSBT(t)
+	conflicting.Inputs[0].RedeemScript = conflicting.Inputs[0].RedeemScript[1:]
+	if _, err := CombinePSBTs([]*PSBT{first, conflicting}); err == nil {
+		t.Error("CombinePSBTs accepting conflicting redeemScripts.")
+	}
+	conflicting = newTestCombinePSBT(t)
+	conflicting.Outputs[0].Bip32Derivations["0411ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef992f63280567f52f5ba870678b4ab4ff6c8ea600bd217870a8b4f1f09f3a8e83"] = Bip32Derivation{MasterKeyFingerprint: []byte{1, 2, 3, 4}, Path: []uint32{0}}
+	derived := newTestCombinePSBT(t)
+	derived.Outputs[0].Bip32Derivations["0411ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef992f63280567f52f5ba870678b4ab4ff6c8ea600bd217870a8b4f1f09f3a8e83"] = Bip32Derivation{MasterKeyFingerprint: []byte{1, 2, 3, 4}, Path: []uint32{1}}
+	if _, err := CombinePSBTs([]*PSBT{derived, conflicting}); err == nil {
+		t.Error("CombinePSBTs accepting conflicting BIP32 derivations.")
+	}
+
+	other := newTestPSBT(t, "02b082113e35d5386285094c2829e7e2963fa0b5369fb7f4b79c4c90877dcd3d", 1, 55600, "76a914569076ba39fc4ff6a2291d9ea9196d8c08f9c7ab88ac")
+	if _, err := CombinePSBTs([]*PSBT{first, other}); err == nil {
+		t.Error("CombinePSBTs accepting PSBTs with different unsigned transactions.")
+	}
+	if _, err := CombinePSBTs(nil); err == nil {
+		t.Error("CombinePSBTs accepting no PSBTs.")
+	}
+}
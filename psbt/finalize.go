@@ -0,0 +1,224 @@
+// PSBT finalizer and extractor roles, turning partial signatures into a signed transaction ready to broadcast.
+package psbt
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/btcutils"
+	"github.com/CryptoProcessing/go-bitcoin-multisig/txscript"
+
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Finalize builds the final scriptSig and witness of every input of p that is not finalized yet, from its partial
+// signatures or Taproot key signature. Multisig inputs, bare, P2SH, P2WSH or P2SH-P2WSH, need signatures from M of
+// their N public keys, which are placed in the order of the public keys in the script as OP_CHECKMULTISIG requires.
+// P2PKH and P2WPKH inputs need the signature of the key they pay to, and Taproot inputs a key path signature.
+// Once finalized, the partial signatures, scripts and derivation paths of an input are removed, as BIP174 requires,
+// keeping only its UTXO and unknown fields.
+// Returns an error, leaving p unchanged, if any input is missing signatures or scripts, or spends an output of a
+// type that cannot be finalized.
+func Finalize(p *PSBT) error {
+	scriptSigs := make([][]byte, len(p.Inputs))
+	witnesses := make([][][]byte, len(p.Inputs))
+	for i := range p.Inputs {
+		if p.Inputs[i].finalized() {
+			continue
+		}
+		var err error
+		scriptSigs[i], witnesses[i], err = finalizeInput(p, i)
+		if err != nil {
+			return err
+		}
+	}
+	for i := range p.Inputs {
+		input := &p.Inputs[i]
+		if input.finalized() {
+			continue
+		}
+		*input = Input{
+			NonWitnessUtxo:     input.NonWitnessUtxo,
+			WitnessUtxo:        input.WitnessUtxo,
+			PartialSigs:        make(map[string][]byte),
+			Bip32Derivations:   make(map[string]Bip32Derivation),
+			FinalScriptSig:     scriptSigs[i],
+			FinalScriptWitness: witnesses[i],
+			Unknowns:           input.Unknowns,
+		}
+	}
+	return nil
+}
+
+// Extract returns the signed transaction of p, with the final scriptSig and witness of each input.
+// Returns an error if any input of p is not finalized.
+func Extract(p *PSBT) (*btcutils.Transaction, error) {
+	tx := *p.UnsignedTx
+	tx.Inputs = make([]btcutils.Input, len(p.UnsignedTx.Inputs))
+	copy(tx.Inputs, p.UnsignedTx.Inputs)
+	tx.Outputs = make([]btcutils.Output, len(p.UnsignedTx.Outputs))
+	copy(tx.Outputs, p.UnsignedTx.Outputs)
+	for i, input := range p.Inputs {
+		if !input.finalized() {
+			return nil, fmt.Errorf("Input %d is not finalized.", i)
+		}
+		tx.Inputs[i].ScriptSig = input.FinalScriptSig
+		tx.Inputs[i].Witness = input.FinalScriptWitness
+	}
+	return &tx, nil
+}
+
+// finalized reports whether the input has a final scriptSig or witness.
+func (input *Input) finalized() bool {
+	return len(input.FinalScriptSig) > 0 || len(input.FinalScriptWitness) > 0
+}
+
+// finalizeInput returns the final scriptSig and witness of input inputIndex.
+func finalizeInput(p *PSBT, inputIndex int) ([]byte, [][]byte, error) {
+	input := p.Inputs[inputIndex]
+	utxo, err := inputUtxo(p, inputIndex, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if isP2TR(utxo.ScriptPubKey) {
+		if len(input.TaprootKeySig) == 0 {
+			return nil, nil, fmt.Errorf("Input %d spends a P2TR output but has no Taproot key signature.", inputIndex)
+		}
+		return nil, [][]byte{input.TaprootKeySig}, nil
+	}
+	script, err := inputScript(p, inputIndex, utxo)
+	if err != nil {
+		return nil, nil, err
+	}
+	//P2SH inputs end their scriptSig with the redeemScript, which is all of it for nested SegWit
+	var redeemScriptPush []byte
+	if isP2SH(utxo.ScriptPubKey) {
+		redeemScriptPush, err = txscript.NewBuilder().AddData(script).Script()
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	switch {
+	case isP2WPKH(script):
+		publicKey, signature, err := findSignature(input, script[2:])
+		if err != nil {
+			return nil, nil, fmt.Errorf("Input %d spends a P2WPKH output. %s", inputIndex, err)
+		}
+		//<signature> <compressed pubkey>
+		return redeemScriptPush, [][]byte{signature, publicKey}, nil
+	case isP2WSH(script):
+		err = checkWitnessScript(p, inputIndex, script)
+		if err != nil {
+			return nil, nil, err
+		}
+		signatures, err := multisigSignatures(p, inputIndex, input.WitnessScript, utxo, true)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Input %d spends a P2WSH output. %s", inputIndex, err)
+		}
+		//<empty> <sig1> ... <sigm> <witnessScript>
+		//The empty item is consumed by the OP_CHECKMULTISIG off-by-one error
+		witness := append([][]byte{{}}, signatures...)
+		witness = append(witness, input.WitnessScript)
+		return redeemScriptPush, witness, nil
+	case isP2PKH(script):
+		publicKey, signature, err := findSignature(input, script[3:23])
+		if err != nil {
+			return nil, nil, fmt.Errorf("Input %d spends a P2PKH output. %s", inputIndex, err)
+		}
+		//<signature> <pubkey>
+		scriptSig, err := txscript.NewBuilder().AddData(signature).AddData(publicKey).Script()
+		if err != nil {
+			return nil, nil, err
+		}
+		return scriptSig, nil, nil
+	default:
+		signatures, err := multisigSignatures(p, inputIndex, script, utxo, false)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Input %d cannot be finalized. %s", inputIndex, err)
+		}
+		//OP_0 <sig1> ... <sigm> <redeemScript>, with OP_0 for the OP_CHECKMULTISIG off-by-one error
+		builder := txscript.NewBuilder().AddOp(txscript.OP_0)
+		for _, signature := range signatures {
+			builder.AddData(signature)
+		}
+		scriptSig, err := builder.Script()
+		if err != nil {
+			return nil, nil, err
+		}
+		return append(scriptSig, redeemScriptPush...), nil, nil
+	}
+}
+
+// findSignature returns the partial signature of input made by the public key hashing to publicKeyHash, along with
+// the public key.
+func findSignature(input Input, publicKeyHash []byte) ([]byte, []byte, error) {
+	for publicKeyHex, signature := range input.PartialSigs {
+		publicKey, err := hex.DecodeString(publicKeyHex)
+		if err != nil {
+			return nil, nil, err
+		}
+		hash, err := btcutils.Hash160(publicKey)
+		if err != nil {
+			return nil, nil, err
+		}
+		if bytes.Equal(hash, publicKeyHash) {
+			return publicKey, signature, nil
+		}
+	}
+	return nil, nil, errors.New("No partial signature from the public key it pays to.")
+}
+
+// multisigSignatures returns M partial signatures of input inputIndex for the M-of-N multisig script, in the order of
+// their public keys in the script. Only signatures valid for the signature hash of their hash type, BIP143 if segWit
+// and legacy otherwise, are counted, so a signature of another transaction is never finalized.
+// Returns an error naming the public keys without a valid signature if fewer than M signatures are found.
+func multisigSignatures(p *PSBT, inputIndex int, script []byte, utxo *btcutils.Output, segWit bool) ([][]byte, error) {
+	//OP_M <pubkey1> ... <pubkeyN> OP_N OP_CHECKMULTISIG
+	if txscript.Classify(script) != txscript.MultiSig {
+		return nil, errors.New("Only M-of-N multisig scripts can be finalized.")
+	}
+	m := int(script[0]) - btcutils.OP_1 + 1
+	publicKeys, err := txscript.PushedData(script[1 : len(script)-2])
+	if err != nil {
+		return nil, err
+	}
+	var signatures [][]byte
+	var missing []string
+	for _, publicKey := range publicKeys {
+		publicKeyHex := hex.EncodeToString(publicKey)
+		signature, ok := p.Inputs[inputIndex].PartialSigs[publicKeyHex]
+		if !ok || !validSignature(p, inputIndex, script, utxo, segWit, publicKey, signature) {
+			missing = append(missing, publicKeyHex)
+			continue
+		}
+		if len(signatures) < m {
+			signatures = append(signatures, signature)
+		}
+	}
+	if len(signatures) < m {
+		return nil, fmt.Errorf("%d of the %d signatures needed. No valid signature from public keys %s.", len(signatures), m, strings.Join(missing, ", "))
+	}
+	return signatures, nil
+}
+
+// validSignature reports whether signature, DER encoded and followed by its hash type, is a valid signature of input
+// inputIndex by publicKey, with script as the script code.
+func validSignature(p *PSBT, inputIndex int, script []byte, utxo *btcutils.Output, segWit bool, publicKey []byte, signature []byte) bool {
+	if len(signature) < 2 {
+		return false
+	}
+	hashType := btcutils.SigHashType(signature[len(signature)-1])
+	var sigHash []byte
+	var err error
+	if segWit {
+		sigHash, err = btcutils.CalcWitnessSigHash(p.UnsignedTx, inputIndex, script, int64(utxo.Satoshis), hashType)
+	} else {
+		sigHash, err = btcutils.CalcSignatureHash(p.UnsignedTx, inputIndex, script, hashType)
+	}
+	if err != nil {
+		return false
+	}
+	return btcutils.VerifySignature(sigHash, signature[:len(signature)-1], publicKey)
+}
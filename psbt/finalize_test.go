@@ -0,0 +1,112 @@
+package psbt
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/btcutils"
+	"github.com/CryptoProcessing/go-bitcoin-multisig/testutils"
+
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestFinalizeP2SHMultisig(t *testing.T) {
+	//Same 2-of-3 spend as the README example, which gives the low R spend transaction of the multisig package tests
+	testFinalTransactionHex := "01000000013dcd7d87904c9cb7f4b79f36b5a03f96e2e729284c09856238d5353e1182b00200000000fd5c01004730440220288cda5e24e4974c57400f1168a0e2423619ed53b21100a1a939de0131f3051702207190d948558bb478a4b4b1d5eeb0a71e4fedd353dcd6432ce96e7d164b86756b01473044022072cdadb0ed013a1104c051cf3bcad78c32746c7f1022b4ebd00e64dddaf910b302205451cf574866dade3e96fafd2e36d87013c66f441bbf790910e11303d72ead55014cc9524104a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575fa349b5694ed3155b136f09e63975a1700c9f4d4df849323dac06cf3bd6458cd41046ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640de68c2fe913d363a51154a0c62d7adea1b822d05035077418267b1a1379790187410411ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef992f63280567f52f5ba870678b4ab4ff6c8ea600bd217870a8b4f1f09f3a8e8353aeffffffff0130d90000000000001976a914569076ba39fc4ff6a2291d9ea9196d8c08f9c7ab88ac00000000"
+	testRedeemScript, _ := hex.DecodeString("524104a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575fa349b5694ed3155b136f09e63975a1700c9f4d4df849323dac06cf3bd6458cd41046ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640de68c2fe913d363a51154a0c62d7adea1b822d05035077418267b1a1379790187410411ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef992f63280567f52f5ba870678b4ab4ff6c8ea600bd217870a8b4f1f09f3a8e8353ae")
+	p := newTestPSBT(t, "02b082113e35d5386285094c2829e7e2963fa0b5369fb7f4b79c4c90877dcd3d", 0, 55600, "76a914569076ba39fc4ff6a2291d9ea9196d8c08f9c7ab88ac")
+	redeemScriptHash, _ := btcutils.Hash160(testRedeemScript)
+	scriptPubKey, _ := btcutils.NewP2SHScriptPubKey(redeemScriptHash)
+	p.Inputs[0].WitnessUtxo = &btcutils.Output{Satoshis: 65600, ScriptPubKey: scriptPubKey}
+	p.Inputs[0].RedeemScript = testRedeemScript
+
+	//Signed with the third key of the redeemScript first, finalizing places signatures in redeemScript order
+	if err := Sign(p, 0, parseTestWIF(t, "5JjHVMwJdjPEPQhq34WMUhzLcEd4SD7HgZktEh8WHstWcCLRceV"), nil); err != nil {
+		t.Fatal(err)
+	}
+	err := Finalize(p)
+	if err == nil {
+		t.Error("Finalize accepting 2-of-3 multisig input with one signature.")
+	} else if !strings.Contains(err.Error(), "04a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575fa349b5694ed3155b136f09e63975a1700c9f4d4df849323dac06cf3bd6458cd") || strings.Contains(err.Error(), "0411ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef992f63280567f52f5ba870678b4ab4ff6c8ea600bd217870a8b4f1f09f3a8e83") {
+		t.Errorf("Finalize error not naming the public keys missing a signature. %s", err)
+	}
+	//A signature of the third key placed as the signature of the first key is not counted
+	p.Inputs[0].PartialSigs["04a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575fa349b5694ed3155b136f09e63975a1700c9f4d4df849323dac06cf3bd6458cd"] = p.Inputs[0].PartialSigs["0411ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef992f63280567f52f5ba870678b4ab4ff6c8ea600bd217870a8b4f1f09f3a8e83"]
+	if err := Finalize(p); err == nil {
+		t.Error("Finalize accepting 2-of-3 multisig input with an invalid signature.")
+	}
+	if _, err := Extract(p); err == nil {
+		t.Error("Extract accepting PSBT with an input that is not finalized.")
+	}
+	if err := Sign(p, 0, parseTestWIF(t, "5JruagvxNLXTnkksyLMfgFgf3CagJ3Ekxu5oGxpTm5mPfTAPez3"), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := Finalize(p); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Inputs[0].PartialSigs) != 0 || p.Inputs[0].RedeemScript != nil || p.Inputs[0].WitnessUtxo == nil {
+		t.Error("Finalize keeping partial signatures and scripts, or removing the UTXO of the input.")
+	}
+
+	//The finalized PSBT survives serialization
+	serialized, err := p.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err = Deserialize(serialized)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := Extract(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	finalTransaction, err := tx.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	finalTransactionHex := hex.EncodeToString(finalTransaction)
+	if finalTransactionHex != testFinalTransactionHex {
+		testutils.CompareError(t, "Extracted transaction different from expected transaction.", testFinalTransactionHex, finalTransactionHex)
+	}
+	//Extracting leaves the unsigned transaction unchanged
+	if len(p.UnsignedTx.Inputs[0].ScriptSig) != 0 {
+		t.Error("Extract modifying the unsigned transaction.")
+	}
+}
+
+func TestFinalizeP2WPKH(t *testing.T) {
+	testWitnessUtxoScript, _ := hex.DecodeString("00141d0f172a0ecb48aee1be1f2687d2963ae33f71a1")
+	p := newTestPSBT(t, "8ac60eb9575db5b2d987e29f301b5b819ea83a5c6579d282d189cc04b8e151ef", 1, 599990000, "a9141a8b0026343166625c7475f01e48b5ede8c0252e87")
+	p.Inputs[0].WitnessUtxo = &btcutils.Output{Satoshis: 600000000, ScriptPubKey: testWitnessUtxoScript}
+	if err := Finalize(p); err == nil {
+		t.Error("Finalize accepting P2WPKH input without a signature.")
+	}
+	if err := Sign(p, 0, parseTestWIF(t, "KzVTBhbMaKrAYagJ11VdTaBrb6yzLykLGyuMBkf9sCFPDxdT8shL"), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := Finalize(p); err != nil {
+		t.Fatal(err)
+	}
+	tx, err := Extract(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//Native SegWit inputs have an empty scriptSig and a <signature> <compressed pubkey> witness
+	testWitness := []string{
+		"3044022056cf29e632ea490cae407fe44b7e7044b8a32d8b6a4f77406341c46fd194206b02204a15713d16cbf91064d6aed936b4b3c4b0f595a582f84f59360d9a22323ea92701",
+		"025476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee6357",
+	}
+	if len(tx.Inputs[0].ScriptSig) != 0 || len(tx.Inputs[0].Witness) != len(testWitness) {
+		t.Fatalf("Extracted P2WPKH input has a %d byte scriptSig and %d witness items, expected none and %d.", len(tx.Inputs[0].ScriptSig), len(tx.Inputs[0].Witness), len(testWitness))
+	}
+	for i, testWitnessItem := range testWitness {
+		witnessItem := hex.EncodeToString(tx.Inputs[0].Witness[i])
+		if witnessItem != testWitnessItem {
+			testutils.CompareError(t, "Extracted witness item different from expected item.", testWitnessItem, witnessItem)
+		}
+	}
+	//Finalized inputs cannot be signed again
+	if err := Sign(p, 0, parseTestWIF(t, "KzVTBhbMaKrAYagJ11VdTaBrb6yzLykLGyuMBkf9sCFPDxdT8shL"), nil); err == nil {
+		t.Error("Sign accepting finalized input.")
+	}
+}
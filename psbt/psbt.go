@@ -0,0 +1,612 @@
+// Package psbt implements version 0 of the Partially Signed Bitcoin Transaction format, so an unsigned transaction
+// and everything needed to sign it can be passed between the parties of a multisig wallet, each adding their
+// signatures independently.
+// See https://github.com/bitcoin/bips/blob/master/bip-0174.mediawiki for full specification.
+package psbt
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/btcutils"
+
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// magic starts every PSBT, "psbt" followed by 0xff.
+var magic = []byte{0x70, 0x73, 0x62, 0x74, 0xff}
+
+// Key types of the global map.
+const (
+	globalUnsignedTx = 0x00
+	globalVersion    = 0xfb
+)
+
+// Key types of the per-input maps.
+const (
+	inputNonWitnessUtxo     = 0x00
+	inputWitnessUtxo        = 0x01
+	inputPartialSig         = 0x02
+	inputSigHashType        = 0x03
+	inputRedeemScript       = 0x04
+	inputWitnessScript      = 0x05
+	inputBip32Derivation    = 0x06
+	inputFinalScriptSig     = 0x07
+	inputFinalScriptWitness = 0x08
+	inputTaprootKeySig      = 0x13
+)
+
+// Key types of the per-output maps.
+const (
+	outputRedeemScript    = 0x00
+	outputWitnessScript   = 0x01
+	outputBip32Derivation = 0x02
+)
+
+// PSBT is a partially signed transaction, the unsigned transaction together with a map of metadata for each of its
+// inputs and outputs. Key-value pairs of unknown types are kept as they are, keyed by their hex encoded key, so a
+// PSBT passes through this package unchanged.
+type PSBT struct {
+	UnsignedTx *btcutils.Transaction
+	Unknowns   map[string][]byte
+	Inputs     []Input
+	Outputs    []Output
+}
+
+// Input is the metadata of a single input. Partial signatures and BIP32 derivations are keyed by the hex encoded
+// public key. A SigHashType of 0 means no hash type was given. TaprootKeySig is the BIP371 Schnorr signature of a
+// Taproot key path spend.
+type Input struct {
+	NonWitnessUtxo     *btcutils.Transaction
+	WitnessUtxo        *btcutils.Output
+	PartialSigs        map[string][]byte
+	SigHashType        btcutils.SigHashType
+	RedeemScript       []byte
+	WitnessScript      []byte
+	Bip32Derivations   map[string]Bip32Derivation
+	FinalScriptSig     []byte
+	FinalScriptWitness [][]byte
+	TaprootKeySig      []byte
+	Unknowns           map[string][]byte
+}
+
+// Output is the metadata of a single output. BIP32 derivations are keyed by the hex encoded public key.
+type Output struct {
+	RedeemScript     []byte
+	WitnessScript    []byte
+	Bip32Derivations map[string]Bip32Derivation
+	Unknowns         map[string][]byte
+}
+
+// Bip32Derivation is where a public key comes from in an HD wallet, the fingerprint of the master key followed by
+// the child indexes of the path from it.
+type Bip32Derivation struct {
+	MasterKeyFingerprint []byte
+	Path                 []uint32
+}
+
+// New creates a PSBT for an unsigned transaction, with empty metadata for each input and output.
+// Returns an error if any input already has a scriptSig or witness.
+func New(tx *btcutils.Transaction) (*PSBT, error) {
+	if tx == nil {
+		return nil, errors.New("Unsigned transaction cannot be empty.")
+	}
+	if len(tx.Inputs) == 0 || len(tx.Outputs) == 0 {
+		return nil, errors.New("Unsigned transaction must have at least one input and one output.")
+	}
+	for i, input := range tx.Inputs {
+		if len(input.ScriptSig) > 0 || len(input.Witness) > 0 {
+			return nil, fmt.Errorf("Input %d of unsigned transaction has a scriptSig or witness. Signatures belong in the PSBT input.", i)
+		}
+	}
+	p := &PSBT{
+		UnsignedTx: tx,
+		Unknowns:   make(map[string][]byte),
+		Inputs:     make([]Input, len(tx.Inputs)),
+		Outputs:    make([]Output, len(tx.Outputs)),
+	}
+	for i := range p.Inputs {
+		p.Inputs[i] = Input{
+			PartialSigs:      make(map[string][]byte),
+			Bip32Derivations: make(map[string]Bip32Derivation),
+			Unknowns:         make(map[string][]byte),
+		}
+	}
+	for i := range p.Outputs {
+		p.Outputs[i] = Output{
+			Bip32Derivations: make(map[string]Bip32Derivation),
+			Unknowns:         make(map[string][]byte),
+		}
+	}
+	return p, nil
+}
+
+// Serialize encodes the PSBT in the BIP174 binary format: the magic bytes, the global map holding the unsigned
+// transaction, then the map of each input and each output. Fields are written in key type order, and fields of the
+// same type in key order, so equal PSBTs always give the same bytes.
+func (p *PSBT) Serialize() ([]byte, error) {
+	if p.UnsignedTx == nil {
+		return nil, errors.New("PSBT has no unsigned transaction.")
+	}
+	if len(p.Inputs) != len(p.UnsignedTx.Inputs) || len(p.Outputs) != len(p.UnsignedTx.Outputs) {
+		return nil, fmt.Errorf("PSBT has %d input and %d output maps for a transaction with %d inputs and %d outputs.", len(p.Inputs), len(p.Outputs), len(p.UnsignedTx.Inputs), len(p.UnsignedTx.Outputs))
+	}
+	unsignedTx, err := p.UnsignedTx.Serialize()
+	if err != nil {
+		return nil, err
+	}
+	if p.UnsignedTx.HasWitness() {
+		return nil, errors.New("Unsigned transaction of PSBT has witness data.")
+	}
+	var buffer bytes.Buffer
+	buffer.Write(magic)
+	writeKeyValue(&buffer, globalUnsignedTx, nil, unsignedTx)
+	err = writeUnknowns(&buffer, p.Unknowns)
+	if err != nil {
+		return nil, err
+	}
+	buffer.WriteByte(0x00) //separator
+	for i, input := range p.Inputs {
+		err = input.serialize(&buffer)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to serialize PSBT input %d. %s", i, err)
+		}
+		buffer.WriteByte(0x00)
+	}
+	for i, output := range p.Outputs {
+		err = output.serialize(&buffer)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to serialize PSBT output %d. %s", i, err)
+		}
+		buffer.WriteByte(0x00)
+	}
+	return buffer.Bytes(), nil
+}
+
+// SerializeBase64 encodes the PSBT in the BIP174 binary format as standard base64, the text form Bitcoin Core and
+// most wallets import and export PSBTs in.
+func (p *PSBT) SerializeBase64() (string, error) {
+	serialized, err := p.Serialize()
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(serialized), nil
+}
+
+// serialize writes the fields of an input map, without the separator.
+func (input *Input) serialize(w *bytes.Buffer) error {
+	if input.NonWitnessUtxo != nil {
+		//Kept in the extended format if it has witness data, the txid doesn't commit to it anyway
+		utxo, err := input.NonWitnessUtxo.Serialize()
+		if err != nil {
+			return err
+		}
+		writeKeyValue(w, inputNonWitnessUtxo, nil, utxo)
+	}
+	if input.WitnessUtxo != nil {
+		var utxo bytes.Buffer
+		binary.Write(&utxo, binary.LittleEndian, input.WitnessUtxo.Satoshis)
+		btcutils.WriteVarInt(&utxo, uint64(len(input.WitnessUtxo.ScriptPubKey)))
+		utxo.Write(input.WitnessUtxo.ScriptPubKey)
+		writeKeyValue(w, inputWitnessUtxo, nil, utxo.Bytes())
+	}
+	for _, publicKeyHex := range sortedKeys(input.PartialSigs) {
+		publicKey, err := parsePublicKey(publicKeyHex)
+		if err != nil {
+			return err
+		}
+		writeKeyValue(w, inputPartialSig, publicKey, input.PartialSigs[publicKeyHex])
+	}
+	if input.SigHashType != 0 {
+		sigHashType := make([]byte, 4)
+		binary.LittleEndian.PutUint32(sigHashType, uint32(input.SigHashType))
+		writeKeyValue(w, inputSigHashType, nil, sigHashType)
+	}
+	if len(input.RedeemScript) > 0 {
+		writeKeyValue(w, inputRedeemScript, nil, input.RedeemScript)
+	}
+	if len(input.WitnessScript) > 0 {
+		writeKeyValue(w, inputWitnessScript, nil, input.WitnessScript)
+	}
+	err := writeBip32Derivations(w, inputBip32Derivation, input.Bip32Derivations)
+	if err != nil {
+		return err
+	}
+	if len(input.FinalScriptSig) > 0 {
+		writeKeyValue(w, inputFinalScriptSig, nil, input.FinalScriptSig)
+	}
+	if len(input.FinalScriptWitness) > 0 {
+		var witness bytes.Buffer
+		btcutils.WriteVarInt(&witness, uint64(len(input.FinalScriptWitness)))
+		for _, item := range input.FinalScriptWitness {
+			btcutils.WriteVarInt(&witness, uint64(len(item)))
+			witness.Write(item)
+		}
+		writeKeyValue(w, inputFinalScriptWitness, nil, witness.Bytes())
+	}
+	if len(input.TaprootKeySig) > 0 {
+		writeKeyValue(w, inputTaprootKeySig, nil, input.TaprootKeySig)
+	}
+	return writeUnknowns(w, input.Unknowns)
+}
+
+// serialize writes the fields of an output map, without the separator.
+func (output *Output) serialize(w *bytes.Buffer) error {
+	if len(output.RedeemScript) > 0 {
+		writeKeyValue(w, outputRedeemScript, nil, output.RedeemScript)
+	}
+	if len(output.WitnessScript) > 0 {
+		writeKeyValue(w, outputWitnessScript, nil, output.WitnessScript)
+	}
+	err := writeBip32Derivations(w, outputBip32Derivation, output.Bip32Derivations)
+	if err != nil {
+		return err
+	}
+	return writeUnknowns(w, output.Unknowns)
+}
+
+// Deserialize decodes a PSBT in the BIP174 binary format. Returns an error if the PSBT is malformed, including
+// duplicate keys, fields with the wrong key or value length, a signed unsigned transaction, a non-witness UTXO that
+// isn't the transaction spent by its input, or a different number of maps than transaction inputs and outputs.
+func Deserialize(data []byte) (*PSBT, error) {
+	if !bytes.HasPrefix(data, magic) {
+		return nil, errors.New("Data is not a PSBT, it doesn't start with the magic bytes 0x70736274ff.")
+	}
+	r := bytes.NewReader(data[len(magic):])
+
+	globalMap, err := readMap(r)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read PSBT global map. %s", err)
+	}
+	var p *PSBT
+	unknowns := make(map[string][]byte)
+	for _, pair := range globalMap {
+		switch pair.key[0] {
+		case globalUnsignedTx:
+			if len(pair.key) != 1 {
+				return nil, errors.New("PSBT unsigned transaction key should be 1 byte long.")
+			}
+			tx, err := btcutils.DeserializeTransaction(pair.value)
+			if err != nil {
+				return nil, err
+			}
+			if tx.HasWitness() {
+				return nil, errors.New("PSBT unsigned transaction should be serialized without witnesses.")
+			}
+			p, err = New(tx)
+			if err != nil {
+				return nil, err
+			}
+		case globalVersion:
+			//Kept with the unknowns so it is written back, but only version 0 can be read
+			if len(pair.key) != 1 || len(pair.value) != 4 {
+				return nil, errors.New("PSBT version key should be 1 byte long and its value 4 bytes long.")
+			}
+			if version := binary.LittleEndian.Uint32(pair.value); version != 0 {
+				return nil, fmt.Errorf("PSBT version %d is not supported, only version 0 is.", version)
+			}
+			unknowns[hex.EncodeToString(pair.key)] = pair.value
+		default:
+			unknowns[hex.EncodeToString(pair.key)] = pair.value
+		}
+	}
+	if p == nil {
+		return nil, errors.New("PSBT has no unsigned transaction.")
+	}
+	p.Unknowns = unknowns
+
+	for i := range p.Inputs {
+		err = p.Inputs[i].deserialize(r, p.UnsignedTx.Inputs[i])
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read PSBT input %d. %s", i, err)
+		}
+	}
+	for i := range p.Outputs {
+		err = p.Outputs[i].deserialize(r)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read PSBT output %d. %s", i, err)
+		}
+	}
+	if r.Len() != 0 {
+		return nil, fmt.Errorf("PSBT has %d unexpected bytes after the last output map.", r.Len())
+	}
+	return p, nil
+}
+
+// deserialize reads an input map, checking its fields against txInput, the input of the unsigned transaction.
+func (input *Input) deserialize(r *bytes.Reader, txInput btcutils.Input) error {
+	pairs, err := readMap(r)
+	if err != nil {
+		return err
+	}
+	for _, pair := range pairs {
+		keyType, keyData := pair.key[0], pair.key[1:]
+		switch keyType {
+		case inputPartialSig, inputBip32Derivation:
+			err = btcutils.CheckPublicKeyIsValid(keyData)
+		case inputNonWitnessUtxo, inputWitnessUtxo, inputSigHashType, inputRedeemScript, inputWitnessScript,
+			inputFinalScriptSig, inputFinalScriptWitness, inputTaprootKeySig:
+			err = checkKeyHasNoData(pair.key)
+		}
+		if err != nil {
+			return err
+		}
+		switch keyType {
+		case inputNonWitnessUtxo:
+			input.NonWitnessUtxo, err = btcutils.DeserializeTransaction(pair.value)
+			if err != nil {
+				return err
+			}
+			hash, err := txHash(input.NonWitnessUtxo)
+			if err != nil {
+				return err
+			}
+			if hash != txInput.TxHash {
+				return fmt.Errorf("Non-witness UTXO is transaction %s, but the input spends transaction %s.", hash, txInput.TxHash)
+			}
+			if int(txInput.OutputIndex) >= len(input.NonWitnessUtxo.Outputs) {
+				return fmt.Errorf("Non-witness UTXO has no output %d spent by the input.", txInput.OutputIndex)
+			}
+		case inputWitnessUtxo:
+			input.WitnessUtxo, err = parseOutput(pair.value)
+		case inputPartialSig:
+			input.PartialSigs[hex.EncodeToString(keyData)] = pair.value
+		case inputSigHashType:
+			if len(pair.value) != 4 {
+				return fmt.Errorf("Signature hash type should be 4 bytes long. Provided hash type is %d bytes long.", len(pair.value))
+			}
+			input.SigHashType = btcutils.SigHashType(binary.LittleEndian.Uint32(pair.value))
+		case inputRedeemScript:
+			input.RedeemScript = pair.value
+		case inputWitnessScript:
+			input.WitnessScript = pair.value
+		case inputBip32Derivation:
+			input.Bip32Derivations[hex.EncodeToString(keyData)], err = parseBip32Derivation(pair.value)
+		case inputFinalScriptSig:
+			input.FinalScriptSig = pair.value
+		case inputFinalScriptWitness:
+			input.FinalScriptWitness, err = parseWitness(pair.value)
+		case inputTaprootKeySig:
+			//64 byte Schnorr signature, followed by the hash type unless it is SIGHASH_DEFAULT
+			if len(pair.value) != 64 && len(pair.value) != 65 {
+				return fmt.Errorf("Taproot key signature should be 64 or 65 bytes long. Provided signature is %d bytes long.", len(pair.value))
+			}
+			input.TaprootKeySig = pair.value
+		default:
+			input.Unknowns[hex.EncodeToString(pair.key)] = pair.value
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deserialize reads an output map.
+func (output *Output) deserialize(r *bytes.Reader) error {
+	pairs, err := readMap(r)
+	if err != nil {
+		return err
+	}
+	for _, pair := range pairs {
+		keyType, keyData := pair.key[0], pair.key[1:]
+		switch keyType {
+		case outputRedeemScript, outputWitnessScript:
+			err = checkKeyHasNoData(pair.key)
+			if keyType == outputRedeemScript {
+				output.RedeemScript = pair.value
+			} else {
+				output.WitnessScript = pair.value
+			}
+		case outputBip32Derivation:
+			err = btcutils.CheckPublicKeyIsValid(keyData)
+			if err == nil {
+				output.Bip32Derivations[hex.EncodeToString(keyData)], err = parseBip32Derivation(pair.value)
+			}
+		default:
+			output.Unknowns[hex.EncodeToString(pair.key)] = pair.value
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// keyValue is a single key-value pair of a PSBT map. The first byte of the key is its type.
+type keyValue struct {
+	key   []byte
+	value []byte
+}
+
+// DeserializeBase64 decodes a base64 encoded PSBT, as returned by SerializeBase64. Surrounding whitespace is ignored.
+func DeserializeBase64(encoded string) (*PSBT, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("PSBT is not valid base64. %s", err)
+	}
+	return Deserialize(data)
+}
+
+// readMap reads key-value pairs up to and including the 0x00 separator ending the map.
+// Returns an error if a key appears twice.
+func readMap(r *bytes.Reader) ([]keyValue, error) {
+	var pairs []keyValue
+	seen := make(map[string]bool)
+	for {
+		key, err := readBytes(r)
+		if err != nil {
+			return nil, err
+		}
+		if len(key) == 0 {
+			return pairs, nil
+		}
+		value, err := readBytes(r)
+		if err != nil {
+			return nil, err
+		}
+		if seen[string(key)] {
+			return nil, fmt.Errorf("Duplicate key %s.", hex.EncodeToString(key))
+		}
+		seen[string(key)] = true
+		pairs = append(pairs, keyValue{key, value})
+	}
+}
+
+// readBytes reads a variable length integer length followed by that many bytes.
+func readBytes(r *bytes.Reader) ([]byte, error) {
+	length, err := btcutils.ReadVarInt(r)
+	if err != nil {
+		return nil, err
+	}
+	if length > uint64(r.Len()) {
+		return nil, fmt.Errorf("Length %d is larger than the %d bytes left.", length, r.Len())
+	}
+	data := make([]byte, length)
+	_, err = io.ReadFull(r, data)
+	return data, err
+}
+
+// writeKeyValue writes a key-value pair, the key made up of keyType followed by keyData.
+func writeKeyValue(w *bytes.Buffer, keyType byte, keyData []byte, value []byte) {
+	btcutils.WriteVarInt(w, uint64(1+len(keyData)))
+	w.WriteByte(keyType)
+	w.Write(keyData)
+	btcutils.WriteVarInt(w, uint64(len(value)))
+	w.Write(value)
+}
+
+// writeUnknowns writes key-value pairs of unknown types in key order.
+func writeUnknowns(w *bytes.Buffer, unknowns map[string][]byte) error {
+	for _, keyHex := range sortedKeys(unknowns) {
+		key, err := hex.DecodeString(keyHex)
+		if err != nil || len(key) == 0 {
+			return fmt.Errorf("Unknown key %q should be non-empty hex.", keyHex)
+		}
+		writeKeyValue(w, key[0], key[1:], unknowns[keyHex])
+	}
+	return nil
+}
+
+// writeBip32Derivations writes BIP32 derivations in public key order, each as the master key fingerprint followed
+// by the little-endian child indexes.
+func writeBip32Derivations(w *bytes.Buffer, keyType byte, derivations map[string]Bip32Derivation) error {
+	publicKeyHexes := make([]string, 0, len(derivations))
+	for publicKeyHex := range derivations {
+		publicKeyHexes = append(publicKeyHexes, publicKeyHex)
+	}
+	sort.Strings(publicKeyHexes)
+	for _, publicKeyHex := range publicKeyHexes {
+		publicKey, err := parsePublicKey(publicKeyHex)
+		if err != nil {
+			return err
+		}
+		derivation := derivations[publicKeyHex]
+		if len(derivation.MasterKeyFingerprint) != 4 {
+			return fmt.Errorf("Master key fingerprint should be 4 bytes long. Provided fingerprint is %d bytes long.", len(derivation.MasterKeyFingerprint))
+		}
+		value := make([]byte, 4+4*len(derivation.Path))
+		copy(value, derivation.MasterKeyFingerprint)
+		for i, index := range derivation.Path {
+			binary.LittleEndian.PutUint32(value[4+4*i:], index)
+		}
+		writeKeyValue(w, keyType, publicKey, value)
+	}
+	return nil
+}
+
+// sortedKeys returns the keys of a map of byte values in order. Lower case hex sorts the same as the bytes it
+// encodes.
+func sortedKeys(m map[string][]byte) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// checkKeyHasNoData makes sure a key is only its type byte, as it is for fields that appear once per map.
+func checkKeyHasNoData(key []byte) error {
+	if len(key) != 1 {
+		return fmt.Errorf("Key of type 0x%02x should be 1 byte long. Provided key is %d bytes long.", key[0], len(key))
+	}
+	return nil
+}
+
+// parsePublicKey decodes a hex encoded public key used to key a map of the PSBT.
+func parsePublicKey(publicKeyHex string) ([]byte, error) {
+	publicKey, err := hex.DecodeString(publicKeyHex)
+	if err != nil {
+		return nil, err
+	}
+	return publicKey, btcutils.CheckPublicKeyIsValid(publicKey)
+}
+
+// parseOutput decodes a witness UTXO, an 8 byte satoshi value followed by the length prefixed scriptPubKey.
+func parseOutput(data []byte) (*btcutils.Output, error) {
+	r := bytes.NewReader(data)
+	output := &btcutils.Output{}
+	if err := binary.Read(r, binary.LittleEndian, &output.Satoshis); err != nil {
+		return nil, errors.New("Witness UTXO is too short.")
+	}
+	scriptPubKey, err := readBytes(r)
+	if err != nil {
+		return nil, err
+	}
+	if r.Len() != 0 {
+		return nil, errors.New("Witness UTXO has unexpected bytes after the scriptPubKey.")
+	}
+	output.ScriptPubKey = scriptPubKey
+	return output, nil
+}
+
+// parseBip32Derivation decodes a master key fingerprint followed by zero or more little-endian child indexes.
+func parseBip32Derivation(data []byte) (Bip32Derivation, error) {
+	if len(data) < 4 || len(data)%4 != 0 {
+		return Bip32Derivation{}, fmt.Errorf("BIP32 derivation should be a 4 byte fingerprint followed by 4 byte indexes. Provided derivation is %d bytes long.", len(data))
+	}
+	derivation := Bip32Derivation{MasterKeyFingerprint: data[:4], Path: make([]uint32, 0, len(data)/4-1)}
+	for i := 4; i < len(data); i += 4 {
+		derivation.Path = append(derivation.Path, binary.LittleEndian.Uint32(data[i:]))
+	}
+	return derivation, nil
+}
+
+// parseWitness decodes a witness stack, an item count followed by the length prefixed items.
+func parseWitness(data []byte) ([][]byte, error) {
+	r := bytes.NewReader(data)
+	itemCount, err := btcutils.ReadVarInt(r)
+	if err != nil {
+		return nil, err
+	}
+	if itemCount > uint64(r.Len()) {
+		return nil, fmt.Errorf("Witness item count %d is larger than the %d bytes left.", itemCount, r.Len())
+	}
+	witness := make([][]byte, itemCount)
+	for i := range witness {
+		witness[i], err = readBytes(r)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if r.Len() != 0 {
+		return nil, errors.New("Final scriptWitness has unexpected bytes after the last item.")
+	}
+	return witness, nil
+}
+
+// txHash returns the hash of a transaction in the big-endian hex form used for input transaction hashes, the
+// double SHA256 of its serialization without witnesses.
+func txHash(tx *btcutils.Transaction) (string, error) {
+	serialized, err := tx.Serialize()
+	if err != nil {
+		return "", err
+	}
+	return btcutils.TxID(serialized)
+}
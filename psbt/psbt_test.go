@@ -0,0 +1,207 @@
+package psbt
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/btcutils"
+	"github.com/CryptoProcessing/go-bitcoin-multisig/testutils"
+
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// Valid PSBTs from the BIP174 test vectors.
+const (
+	//One P2PKH input, outputs are empty
+	testP2PKHPSBTHex = "70736274ff0100750200000001268171371edff285e937adeea4b37b78000c0566cbb3ad64641713ca42171bf60000000000feffffff02d3dff505000000001976a914d0c59903c5bac2868760e90fd521a4665aa7652088ac00e1f5050000000017a9143545e6e33b832c47050f24d3eeb93c9c03948bc787b32e1300000100fda5010100000000010289a3c71eab4d20e0371bbba4cc698fa295c9463afa2e397f8533ccb62f9567e50100000017160014be18d152a9b012039daf3da7de4f53349eecb985ffffffff86f8aa43a71dff1448893a530a7237ef6b4608bbb2dd2d0171e63aec6a4890b40100000017160014fe3e9ef1a745e974d902c4355943abcb34bd5353ffffffff0200c2eb0b000000001976a91485cff1097fd9e008bb34af709c62197b38978a4888ac72fef84e2c00000017a914339725ba21efd62ac753a9bcd067d6c7a6a39d05870247304402202712be22e0270f394f568311dc7ca9a68970b8025fdd3b240229f07f8a5f3a240220018b38d7dcd314e734c9276bd6fb40f673325bc4baa144c800d2f2f02db2765c012103d2e15674941bad4a996372cb87e1856d3652606d98562fe39c5e9e7e413f210502483045022100d12b852d85dcd961d2f5f4ab660654df6eedcc794c0c33ce5cc309ffb5fce58d022067338a8e0e1725c197fb1a88af59f51e44e4255b20167c8684031c05d1f2592a01210223b72beef0965d10be0778efecd61fcac6f79a4ea169393380734464f84f2ab300000000000000"
+	//One P2PKH input and one P2SH-P2WPKH input, the first signed and finalized
+	testFinalizedPSBTHex = "70736274ff0100a00200000002ab0949a08c5af7c49b8212f417e2f15ab3f5c33dcf153821a8139f877a5b7be40000000000feffffffab0949a08c5af7c49b8212f417e2f15ab3f5c33dcf153821a8139f877a5b7be40100000000feffffff02603bea0b000000001976a914768a40bbd740cbe81d988e71de2a4d5c71396b1d88ac8e240000000000001976a9146f4620b553fa095e721b9ee0efe9fa039cca459788ac000000000001076a47304402204759661797c01b036b25928948686218347d89864b719e1f7fcf57d1e511658702205309eabf56aa4d8891ffd111fdf1336f3a29da866d7f8486d75546ceedaf93190121035cdc61fc7ba971c0b501a646a2a83b102cb43881217ca682dc86e2d73fa882920001012000e1f5050000000017a9143545e6e33b832c47050f24d3eeb93c9c03948bc787010416001485d13537f2e265405a34dbafa9e3dda01fb82308000000"
+	//One P2PKH input with a signature hash type
+	testSigHashTypePSBTHex = "70736274ff0100750200000001268171371edff285e937adeea4b37b78000c0566cbb3ad64641713ca42171bf60000000000feffffff02d3dff505000000001976a914d0c59903c5bac2868760e90fd521a4665aa7652088ac00e1f5050000000017a9143545e6e33b832c47050f24d3eeb93c9c03948bc787b32e1300000100fda5010100000000010289a3c71eab4d20e0371bbba4cc698fa295c9463afa2e397f8533ccb62f9567e50100000017160014be18d152a9b012039daf3da7de4f53349eecb985ffffffff86f8aa43a71dff1448893a530a7237ef6b4608bbb2dd2d0171e63aec6a4890b40100000017160014fe3e9ef1a745e974d902c4355943abcb34bd5353ffffffff0200c2eb0b000000001976a91485cff1097fd9e008bb34af709c62197b38978a4888ac72fef84e2c00000017a914339725ba21efd62ac753a9bcd067d6c7a6a39d05870247304402202712be22e0270f394f568311dc7ca9a68970b8025fdd3b240229f07f8a5f3a240220018b38d7dcd314e734c9276bd6fb40f673325bc4baa144c800d2f2f02db2765c012103d2e15674941bad4a996372cb87e1856d3652606d98562fe39c5e9e7e413f210502483045022100d12b852d85dcd961d2f5f4ab660654df6eedcc794c0c33ce5cc309ffb5fce58d022067338a8e0e1725c197fb1a88af59f51e44e4255b20167c8684031c05d1f2592a01210223b72beef0965d10be0778efecd61fcac6f79a4ea169393380734464f84f2ab30000000001030401000000000000"
+	//One P2SH-P2WSH 2-of-2 multisig input with its redeemScript, witnessScript, BIP32 derivations and one signature
+	testP2SHP2WSHPSBTHex = "70736274ff0100550200000001279a2323a5dfb51fc45f220fa58b0fc13e1e3342792a85d7e36cd6333b5cbc390000000000ffffffff01a05aea0b000000001976a914ffe9c0061097cc3b636f2cb0460fa4fc427d2b4588ac0000000000010120955eea0b0000000017a9146345200f68d189e1adc0df1c4d16ea8f14c0dbeb87220203b1341ccba7683b6af4f1238cd6e97e7167d569fac47f1e48d47541844355bd4646304302200424b58effaaa694e1559ea5c93bbfd4a89064224055cdf070b6771469442d07021f5c8eb0fea6516d60b8acb33ad64ede60e8785bfb3aa94b99bdf86151db9a9a010104220020771fd18ad459666dd49f3d564e3dbc42f4c84774e360ada16816a8ed488d5681010547522103b1341ccba7683b6af4f1238cd6e97e7167d569fac47f1e48d47541844355bd462103de55d1e1dac805e3f8a58c1fbf9b94c02f3dbaafe127fefca4995f26f82083bd52ae220603b1341ccba7683b6af4f1238cd6e97e7167d569fac47f1e48d47541844355bd4610b4a6ba67000000800000008004000080220603de55d1e1dac805e3f8a58c1fbf9b94c02f3dbaafe127fefca4995f26f82083bd10b4a6ba670000008000000080050000800000"
+	//Unknown key type in the input
+	testUnknownPSBTHex = "70736274ff01003f0200000001ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff0000000000ffffffff010000000000000000036a010000000000000a0f0102030405060708090f0102030405060708090a0b0c0d0e0f0000"
+	//Two P2WPKH inputs with signatures, and a global xpub kept as an unknown
+	testGlobalXpubPSBTHex = "70736274ff01009d0100000002710ea76ab45c5cb6438e607e59cc037626981805ae9e0dfd9089012abb0be5350100000000ffffffff190994d6a8b3c8c82ccbcfb2fba4106aa06639b872a8d447465c0d42588d6d670000000000ffffffff0200e1f505000000001976a914b6bc2c0ee5655a843d79afedd0ccc3f7dd64340988ac605af405000000001600141188ef8e4ce0449eaac8fb141cbf5a1176e6a088000000004f010488b21e039e530cac800000003dbc8a5c9769f031b17e77fea1518603221a18fd18f2b9a54c6c8c1ac75cbc3502f230584b155d1c7f1cd45120a653c48d650b431b67c5b2c13f27d7142037c1691027569c503100008000000080000000800001011f00e1f5050000000016001433b982f91b28f160c920b4ab95e58ce50dda3a4a220203309680f33c7de38ea6a47cd4ecd66f1f5a49747c6ffb8808ed09039243e3ad5c47304402202d704ced830c56a909344bd742b6852dccd103e963bae92d38e75254d2bb424502202d86c437195df46c0ceda084f2a291c3da2d64070f76bf9b90b195e7ef28f77201220603309680f33c7de38ea6a47cd4ecd66f1f5a49747c6ffb8808ed09039243e3ad5c1827569c5031000080000000800000008000000000010000000001011f00e1f50500000000160014388fb944307eb77ef45197d0b0b245e079f011de220202c777161f73d0b7c72b9ee7bde650293d13f095bc7656ad1f525da5fd2e10b11047304402204cb1fb5f869c942e0e26100576125439179ae88dca8a9dc3ba08f7953988faa60220521f49ca791c27d70e273c9b14616985909361e25be274ea200d7e08827e514d01220602c777161f73d0b7c72b9ee7bde650293d13f095bc7656ad1f525da5fd2e10b1101827569c5031000080000000800000008000000000000000000000220202d20ca502ee289686d21815bd43a80637b0698e1fbcdbe4caed445f6c1a0a90ef1827569c50310000800000008000000080000000000400000000"
+)
+
+func TestRoundTrip(t *testing.T) {
+	for _, testPSBTHex := range []string{testP2PKHPSBTHex, testFinalizedPSBTHex, testSigHashTypePSBTHex, testP2SHP2WSHPSBTHex, testUnknownPSBTHex, testGlobalXpubPSBTHex} {
+		testPSBT, _ := hex.DecodeString(testPSBTHex)
+		p, err := Deserialize(testPSBT)
+		if err != nil {
+			t.Fatal(err)
+		}
+		serialized, err := p.Serialize()
+		if err != nil {
+			t.Fatal(err)
+		}
+		serializedHex := hex.EncodeToString(serialized)
+		if serializedHex != testPSBTHex {
+			testutils.CompareError(t, "Reserialized PSBT different from deserialized PSBT.", testPSBTHex, serializedHex)
+		}
+	}
+}
+
+func TestBase64(t *testing.T) {
+	//BIP174 P2SH-P2WSH test vector in base64, as Bitcoin Core's decodepsbt takes it
+	testPSBTBase64 := "cHNidP8BAFUCAAAAASeaIyOl37UfxF8iD6WLD8E+HjNCeSqF1+Ns1jM7XLw5AAAAAAD/////AaBa6gsAAAAAGXapFP/pwAYQl8w7Y28ssEYPpPxCfStFiKwAAAAAAAEBIJVe6gsAAAAAF6kUY0UgD2jRieGtwN8cTRbqjxTA2+uHIgIDsTQcy6doO2r08SOM1ul+cWfVafrEfx5I1HVBhENVvUZGMEMCIAQktY7/qqaU4VWepck7v9SokGQiQFXN8HC2dxRpRC0HAh9cjrD+plFtYLisszrWTt5g6Hhb+zqpS5m9+GFR25qaAQEEIgAgdx/RitRZZm3Unz1WTj28QvTIR3TjYK2haBao7UiNVoEBBUdSIQOxNBzLp2g7avTxI4zW6X5xZ9Vp+sR/HkjUdUGEQ1W9RiED3lXR4drIBeP4pYwfv5uUwC89uq/hJ/78pJlfJvggg71SriIGA7E0HMunaDtq9PEjjNbpfnFn1Wn6xH8eSNR1QYRDVb1GELSmumcAAACAAAAAgAQAAIAiBgPeVdHh2sgF4/iljB+/m5TALz26r+En/vykmV8m+CCDvRC0prpnAAAAgAAAAIAFAACAAAA="
+	p, err := DeserializeBase64(testPSBTBase64 + "\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	serialized, err := p.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	serializedHex := hex.EncodeToString(serialized)
+	if serializedHex != testP2SHP2WSHPSBTHex {
+		testutils.CompareError(t, "PSBT decoded from base64 different from expected PSBT.", testP2SHP2WSHPSBTHex, serializedHex)
+	}
+	serializedBase64, err := p.SerializeBase64()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if serializedBase64 != testPSBTBase64 {
+		testutils.CompareError(t, "Base64 encoded PSBT different from expected PSBT.", testPSBTBase64, serializedBase64)
+	}
+
+	if _, err := DeserializeBase64("cHNidP8!"); err == nil {
+		t.Error("DeserializeBase64 accepting invalid base64.")
+	}
+	//Valid base64 of data without the PSBT magic bytes
+	if _, err := DeserializeBase64("AQIDBA=="); err == nil {
+		t.Error("DeserializeBase64 accepting data that is not a PSBT.")
+	}
+}
+
+func TestDeserialize(t *testing.T) {
+	testPSBT, _ := hex.DecodeString(testP2SHP2WSHPSBTHex)
+	p, err := Deserialize(testPSBT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testPublicKeyHex := "03b1341ccba7683b6af4f1238cd6e97e7167d569fac47f1e48d47541844355bd46"
+	input := p.Inputs[0]
+	if input.WitnessUtxo == nil || input.WitnessUtxo.Satoshis != 199909013 {
+		t.Fatalf("Deserialized witness UTXO %v different from expected 199909013 satoshi output.", input.WitnessUtxo)
+	}
+	if len(input.PartialSigs) != 1 || len(input.PartialSigs[testPublicKeyHex]) != 70 {
+		t.Errorf("Deserialized partial signatures %x different from expected 70 byte signature of %s.", input.PartialSigs, testPublicKeyHex)
+	}
+	testWitnessScriptHex := "522103b1341ccba7683b6af4f1238cd6e97e7167d569fac47f1e48d47541844355bd462103de55d1e1dac805e3f8a58c1fbf9b94c02f3dbaafe127fefca4995f26f82083bd52ae"
+	if hex.EncodeToString(input.WitnessScript) != testWitnessScriptHex {
+		testutils.CompareError(t, "Deserialized witnessScript different from expected script.", testWitnessScriptHex, hex.EncodeToString(input.WitnessScript))
+	}
+	//m/0'/0'/4' of master key with fingerprint b4a6ba67
+	derivation := input.Bip32Derivations[testPublicKeyHex]
+	testPath := []uint32{0x80000000, 0x80000000, 0x80000004}
+	if hex.EncodeToString(derivation.MasterKeyFingerprint) != "b4a6ba67" || len(derivation.Path) != 3 ||
+		derivation.Path[0] != testPath[0] || derivation.Path[1] != testPath[1] || derivation.Path[2] != testPath[2] {
+		testutils.CompareError(t, "Deserialized BIP32 derivation different from expected derivation.", testPath, derivation)
+	}
+
+	testPSBT, _ = hex.DecodeString(testSigHashTypePSBTHex)
+	p, err = Deserialize(testPSBT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Inputs[0].SigHashType != btcutils.SigHashAll {
+		testutils.CompareError(t, "Deserialized signature hash type different from expected hash type.", btcutils.SigHashAll, p.Inputs[0].SigHashType)
+	}
+	if p.Inputs[0].NonWitnessUtxo == nil || len(p.Inputs[0].NonWitnessUtxo.Outputs) != 2 {
+		t.Error("Deserialized non-witness UTXO different from expected transaction with 2 outputs.")
+	}
+
+	testPSBT, _ = hex.DecodeString(testUnknownPSBTHex)
+	p, err = Deserialize(testPSBT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testUnknownValueHex := "0102030405060708090a0b0c0d0e0f"
+	if hex.EncodeToString(p.Inputs[0].Unknowns["0f010203040506070809"]) != testUnknownValueHex {
+		testutils.CompareError(t, "Deserialized unknown value different from expected value.", testUnknownValueHex, hex.EncodeToString(p.Inputs[0].Unknowns["0f010203040506070809"]))
+	}
+}
+
+func TestNew(t *testing.T) {
+	testScriptPubKey, _ := hex.DecodeString("76a914ffe9c0061097cc3b636f2cb0460fa4fc427d2b4588ac")
+	tx := &btcutils.Transaction{
+		Version: 2,
+		Inputs:  []btcutils.Input{{TxHash: "39bc5c3b33d66ce3d7852a7942331e3ec10f8ba50f225fc41fb5dfa523239a27", Sequence: btcutils.SequenceFinal}},
+		Outputs: []btcutils.Output{{Satoshis: 199908000, ScriptPubKey: testScriptPubKey}},
+	}
+	p, err := New(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//Same transaction as the BIP174 P2SH-P2WSH test vector, with empty input and output maps
+	testPSBTHex := "70736274ff0100550200000001279a2323a5dfb51fc45f220fa58b0fc13e1e3342792a85d7e36cd6333b5cbc390000000000ffffffff01a05aea0b000000001976a914ffe9c0061097cc3b636f2cb0460fa4fc427d2b4588ac00000000000000"
+	serialized, err := p.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	serializedHex := hex.EncodeToString(serialized)
+	if serializedHex != testPSBTHex {
+		testutils.CompareError(t, "Serialized new PSBT different from expected PSBT.", testPSBTHex, serializedHex)
+	}
+
+	//Adding the P2SH-P2WSH test vector fields gives the test vector
+	testWitnessUtxoScript, _ := hex.DecodeString("a9146345200f68d189e1adc0df1c4d16ea8f14c0dbeb87")
+	p.Inputs[0].WitnessUtxo = &btcutils.Output{Satoshis: 199909013, ScriptPubKey: testWitnessUtxoScript}
+	p.Inputs[0].PartialSigs["03b1341ccba7683b6af4f1238cd6e97e7167d569fac47f1e48d47541844355bd46"], _ = hex.DecodeString("304302200424b58effaaa694e1559ea5c93bbfd4a89064224055cdf070b6771469442d07021f5c8eb0fea6516d60b8acb33ad64ede60e8785bfb3aa94b99bdf86151db9a9a01")
+	p.Inputs[0].RedeemScript, _ = hex.DecodeString("0020771fd18ad459666dd49f3d564e3dbc42f4c84774e360ada16816a8ed488d5681")
+	p.Inputs[0].WitnessScript, _ = hex.DecodeString("522103b1341ccba7683b6af4f1238cd6e97e7167d569fac47f1e48d47541844355bd462103de55d1e1dac805e3f8a58c1fbf9b94c02f3dbaafe127fefca4995f26f82083bd52ae")
+	testFingerprint, _ := hex.DecodeString("b4a6ba67")
+	//Added out of order, written in public key order
+	p.Inputs[0].Bip32Derivations["03de55d1e1dac805e3f8a58c1fbf9b94c02f3dbaafe127fefca4995f26f82083bd"] = Bip32Derivation{testFingerprint, []uint32{0x80000000, 0x80000000, 0x80000005}}
+	p.Inputs[0].Bip32Derivations["03b1341ccba7683b6af4f1238cd6e97e7167d569fac47f1e48d47541844355bd46"] = Bip32Derivation{testFingerprint, []uint32{0x80000000, 0x80000000, 0x80000004}}
+	serialized, err = p.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	serializedHex = hex.EncodeToString(serialized)
+	if serializedHex != testP2SHP2WSHPSBTHex {
+		testutils.CompareError(t, "Serialized PSBT different from expected PSBT.", testP2SHP2WSHPSBTHex, serializedHex)
+	}
+
+	tx.Inputs[0].ScriptSig = []byte{0x00}
+	if _, err := New(tx); err == nil {
+		t.Error("New accepting transaction with a scriptSig.")
+	}
+}
+
+func TestDeserializeInvalid(t *testing.T) {
+	invalidPSBTHexes := []string{
+		//Network transaction, not a PSBT
+		"0200000001268171371edff285e937adeea4b37b78000c0566cbb3ad64641713ca42171bf6000000006a473044022070b2245123e6bf474d60c5b50c043d4c691a5d2435f09a34a7662a9dc251790a022001329ca9dacf280bdf30740ec0390422422c81cb45839457aeb76fc12edd95b3012102657d118d3357b8e0f4c2cd46db7b39f6d9c38d9a70abcb9b2de5dc8dbfe4ce31feffffff02d3dff505000000001976a914d0c59903c5bac2868760e90fd521a4665aa7652088ac00e1f5050000000017a9143545e6e33b832c47050f24d3eeb93c9c03948bc787b32e1300",
+		//Missing output map
+		strings.TrimSuffix(testUnknownPSBTHex, "00"),
+		//Trailing byte
+		testUnknownPSBTHex + "00",
+		//No unsigned transaction
+		"70736274ff000000",
+		//Unsigned transaction with a scriptSig
+		"70736274ff0100400200000001ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff000000000100ffffffff010000000000000000036a0100000000000000",
+		//Duplicate unsigned transaction key
+		"70736274ff01003f0200000001ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff0000000000ffffffff010000000000000000036a0100000000000100" + "3f0200000001ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff0000000000ffffffff010000000000000000036a010000000000000000",
+		//PSBT version 1
+		"70736274ff01003f0200000001ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff0000000000ffffffff010000000000000000036a01000000000001fb0401000000000000",
+		//Signature hash type 3 bytes long
+		strings.Replace(testSigHashTypePSBTHex, "01030401000000", "010303010000", 1),
+		//Duplicate signature hash type
+		strings.Replace(testSigHashTypePSBTHex, "01030401000000", "0103040100000001030401000000", 1),
+		//Partial signature keyed by an invalid public key
+		strings.Replace(testP2SHP2WSHPSBTHex, "220203b1341c", "220205b1341c", 1),
+		//Witness UTXO key with key data
+		strings.Replace(testP2SHP2WSHPSBTHex, "00010120955eea0b", "0002010020955eea0b", 1),
+		//Non-witness UTXO of a different transaction than the input spends
+		strings.Replace(testP2PKHPSBTHex, "268171371edff285", "278171371edff285", 1),
+	}
+	for _, invalidPSBTHex := range invalidPSBTHexes {
+		invalidPSBT, _ := hex.DecodeString(invalidPSBTHex)
+		if _, err := Deserialize(invalidPSBT); err == nil {
+			t.Errorf("Deserialize accepting invalid PSBT %s.", invalidPSBTHex)
+		}
+	}
+}
@@ -0,0 +1,336 @@
+// PSBT signer role, adding the signature of one private key to an input.
+package psbt
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/btcutils"
+	"github.com/CryptoProcessing/go-bitcoin-multisig/txscript"
+
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// Sign signs input inputIndex of p with privateKey and adds the signature to the input's partial signatures, keyed
+// by the public key. utxo is the output the input spends, and can be nil if the PSBT input already has a witness
+// or non-witness UTXO. The signature hash is chosen by the scriptPubKey of the UTXO: legacy for P2PKH, P2SH and bare
+// scripts, BIP143 for P2WPKH and P2WSH, native or nested in P2SH, and BIP341 for Taproot key path spends, whose
+// Schnorr signature is stored as the Taproot key signature instead. The input's hash type is used if set, and
+// SIGHASH_ALL (SIGHASH_DEFAULT for Taproot) otherwise.
+// Returns an error if the input is already finalized, a script needed to sign is missing from the input, or the
+// public key of privateKey is not one of the keys the scripts of the input expect a signature from.
+func Sign(p *PSBT, inputIndex int, privateKey []byte, utxo *btcutils.Output) error {
+	if inputIndex < 0 || inputIndex >= len(p.Inputs) {
+		return fmt.Errorf("Input index %d is out of range. PSBT has %d inputs.", inputIndex, len(p.Inputs))
+	}
+	input := &p.Inputs[inputIndex]
+	if input.finalized() {
+		return fmt.Errorf("Input %d is already finalized.", inputIndex)
+	}
+	utxo, err := inputUtxo(p, inputIndex, utxo)
+	if err != nil {
+		return err
+	}
+	compressedPublicKey, err := btcutils.NewCompressedPublicKey(privateKey)
+	if err != nil {
+		return err
+	}
+	uncompressedPublicKey, err := btcutils.NewPublicKey(privateKey)
+	if err != nil {
+		return err
+	}
+
+	if isP2TR(utxo.ScriptPubKey) {
+		return signTaproot(p, inputIndex, privateKey, compressedPublicKey)
+	}
+	script, err := inputScript(p, inputIndex, utxo)
+	if err != nil {
+		return err
+	}
+
+	hashType := input.SigHashType
+	if hashType == 0 {
+		hashType = btcutils.SigHashAll
+	}
+	err = btcutils.CheckSigHashType(p.UnsignedTx, inputIndex, hashType)
+	if err != nil {
+		return err
+	}
+	var publicKey, sigHash []byte
+	switch {
+	case isP2WPKH(script):
+		//SegWit only allows compressed public keys
+		publicKey = compressedPublicKey
+		publicKeyHash, err := btcutils.Hash160(publicKey)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(publicKeyHash, script[2:]) {
+			return fmt.Errorf("Private key is not the key of the P2WPKH output spent by input %d.", inputIndex)
+		}
+		scriptCode, err := btcutils.NewP2PKHScriptPubKey(publicKeyHash)
+		if err != nil {
+			return err
+		}
+		sigHash, err = btcutils.CalcWitnessSigHash(p.UnsignedTx, inputIndex, scriptCode, int64(utxo.Satoshis), hashType)
+		if err != nil {
+			return err
+		}
+	case isP2WSH(script):
+		err = checkWitnessScript(p, inputIndex, script)
+		if err != nil {
+			return err
+		}
+		publicKey, err = findPublicKey(input.WitnessScript, compressedPublicKey)
+		if err != nil {
+			return fmt.Errorf("Private key is not one of the signers of input %d. %s", inputIndex, err)
+		}
+		sigHash, err = btcutils.CalcWitnessSigHash(p.UnsignedTx, inputIndex, input.WitnessScript, int64(utxo.Satoshis), hashType)
+		if err != nil {
+			return err
+		}
+	case isP2PKH(script):
+		//Legacy P2PKH outputs may pay to either form of the public key
+		for _, candidate := range [][]byte{compressedPublicKey, uncompressedPublicKey} {
+			publicKeyHash, err := btcutils.Hash160(candidate)
+			if err != nil {
+				return err
+			}
+			if bytes.Equal(publicKeyHash, script[3:23]) {
+				publicKey = candidate
+			}
+		}
+		if publicKey == nil {
+			return fmt.Errorf("Private key is not the key of the P2PKH output spent by input %d.", inputIndex)
+		}
+		sigHash, err = btcutils.CalcSignatureHash(p.UnsignedTx, inputIndex, script, hashType)
+		if err != nil {
+			return err
+		}
+	default:
+		//Legacy P2SH redeemScript or bare script, such as M-of-N multisig
+		publicKey, err = findPublicKey(script, compressedPublicKey, uncompressedPublicKey)
+		if err != nil {
+			return fmt.Errorf("Private key is not one of the signers of input %d. %s", inputIndex, err)
+		}
+		sigHash, err = btcutils.CalcSignatureHash(p.UnsignedTx, inputIndex, script, hashType)
+		if err != nil {
+			return err
+		}
+	}
+	signature, err := btcutils.SignHash(sigHash, privateKey)
+	if err != nil {
+		return err
+	}
+	input.PartialSigs[hex.EncodeToString(publicKey)] = append(signature, byte(hashType))
+	return nil
+}
+
+// CanSign reports whether input inputIndex of p expects a signature from the public key of privateKey: the key a
+// P2PKH or P2WPKH output pays to, one of the public keys of a redeemScript or witnessScript, such as an M-of-N
+// multisig, or the internal key of a Taproot key path spend. Either form of the public key is looked for in legacy
+// scripts, and only the compressed form in SegWit scripts.
+// Returns an error if the input has no UTXO, or a script needed to tell is missing or does not match the UTXO.
+func CanSign(p *PSBT, inputIndex int, privateKey []byte) (bool, error) {
+	if inputIndex < 0 || inputIndex >= len(p.Inputs) {
+		return false, fmt.Errorf("Input index %d is out of range. PSBT has %d inputs.", inputIndex, len(p.Inputs))
+	}
+	utxo, err := inputUtxo(p, inputIndex, nil)
+	if err != nil {
+		return false, err
+	}
+	compressedPublicKey, err := btcutils.NewCompressedPublicKey(privateKey)
+	if err != nil {
+		return false, err
+	}
+	uncompressedPublicKey, err := btcutils.NewPublicKey(privateKey)
+	if err != nil {
+		return false, err
+	}
+	if isP2TR(utxo.ScriptPubKey) {
+		outputKey, err := btcutils.TweakPublicKey(compressedPublicKey[1:], nil)
+		if err != nil {
+			return false, err
+		}
+		return bytes.Equal(outputKey, utxo.ScriptPubKey[2:]), nil
+	}
+	script, err := inputScript(p, inputIndex, utxo)
+	if err != nil {
+		return false, err
+	}
+	var candidates [][]byte
+	switch {
+	case isP2WPKH(script):
+		publicKeyHash, err := btcutils.Hash160(compressedPublicKey)
+		if err != nil {
+			return false, err
+		}
+		return bytes.Equal(publicKeyHash, script[2:]), nil
+	case isP2PKH(script):
+		for _, candidate := range [][]byte{compressedPublicKey, uncompressedPublicKey} {
+			publicKeyHash, err := btcutils.Hash160(candidate)
+			if err != nil {
+				return false, err
+			}
+			if bytes.Equal(publicKeyHash, script[3:23]) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case isP2WSH(script):
+		err = checkWitnessScript(p, inputIndex, script)
+		if err != nil {
+			return false, err
+		}
+		script = p.Inputs[inputIndex].WitnessScript
+		candidates = [][]byte{compressedPublicKey}
+	default:
+		candidates = [][]byte{compressedPublicKey, uncompressedPublicKey}
+	}
+	_, err = findPublicKey(script, candidates...)
+	return err == nil, nil
+}
+
+// signTaproot signs a Taproot key path spend of input inputIndex, which needs the UTXOs of every input of the
+// transaction as the BIP341 signature hash commits to all their amounts and scriptPubKeys.
+func signTaproot(p *PSBT, inputIndex int, privateKey []byte, publicKey []byte) error {
+	input := &p.Inputs[inputIndex]
+	prevOuts := make([]btcutils.Output, len(p.Inputs))
+	for i := range p.Inputs {
+		utxo, err := inputUtxo(p, i, nil)
+		if err != nil {
+			return fmt.Errorf("Taproot signatures commit to the UTXOs of all inputs. %s", err)
+		}
+		prevOuts[i] = *utxo
+	}
+	//Only key path spends without a script tree are supported, as recommended by BIP86
+	outputKey, err := btcutils.TweakPublicKey(publicKey[1:], nil)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(outputKey, prevOuts[inputIndex].ScriptPubKey[2:]) {
+		return fmt.Errorf("Private key is not the internal key of the P2TR output spent by input %d.", inputIndex)
+	}
+	hashType := input.SigHashType
+	sigHash, err := btcutils.CalcTaprootSigHash(p.UnsignedTx, inputIndex, prevOuts, hashType)
+	if err != nil {
+		return err
+	}
+	tweakedPrivateKey, err := btcutils.TaprootTweakPrivateKey(privateKey, nil)
+	if err != nil {
+		return err
+	}
+	signature, err := btcutils.SchnorrSign(tweakedPrivateKey, sigHash)
+	if err != nil {
+		return err
+	}
+	//SIGHASH_DEFAULT signatures are 64 bytes, any other hash type is appended
+	if hashType != btcutils.SigHashDefault {
+		signature = append(signature, byte(hashType))
+	}
+	input.TaprootKeySig = signature
+	return nil
+}
+
+// inputUtxo returns the output spent by input inputIndex, from the PSBT's witness or non-witness UTXO, or utxo if
+// the PSBT has neither. A utxo different from the one in the PSBT is an error.
+func inputUtxo(p *PSBT, inputIndex int, utxo *btcutils.Output) (*btcutils.Output, error) {
+	input := p.Inputs[inputIndex]
+	var recorded *btcutils.Output
+	if input.WitnessUtxo != nil {
+		recorded = input.WitnessUtxo
+	} else if input.NonWitnessUtxo != nil {
+		outputIndex := p.UnsignedTx.Inputs[inputIndex].OutputIndex
+		if int(outputIndex) >= len(input.NonWitnessUtxo.Outputs) {
+			return nil, fmt.Errorf("Non-witness UTXO of input %d has no output %d.", inputIndex, outputIndex)
+		}
+		recorded = &input.NonWitnessUtxo.Outputs[outputIndex]
+	}
+	switch {
+	case recorded == nil && utxo == nil:
+		return nil, fmt.Errorf("Input %d has no UTXO. Provide the output it spends.", inputIndex)
+	case recorded == nil:
+		return utxo, nil
+	case utxo != nil && (utxo.Satoshis != recorded.Satoshis || !bytes.Equal(utxo.ScriptPubKey, recorded.ScriptPubKey)):
+		return nil, fmt.Errorf("Provided UTXO is different from the UTXO of input %d.", inputIndex)
+	}
+	return recorded, nil
+}
+
+// inputScript returns the script input inputIndex spending utxo has to satisfy, which is the redeemScript of the
+// input for P2SH outputs, including nested SegWit, and the scriptPubKey of utxo otherwise.
+// Returns an error if a P2SH input has no redeemScript or it does not hash to the P2SH output.
+func inputScript(p *PSBT, inputIndex int, utxo *btcutils.Output) ([]byte, error) {
+	script := utxo.ScriptPubKey
+	if !isP2SH(script) {
+		return script, nil
+	}
+	redeemScript := p.Inputs[inputIndex].RedeemScript
+	if len(redeemScript) == 0 {
+		return nil, fmt.Errorf("Input %d spends a P2SH output but has no redeemScript.", inputIndex)
+	}
+	redeemScriptHash, err := btcutils.Hash160(redeemScript)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(redeemScriptHash, script[2:22]) {
+		return nil, fmt.Errorf("redeemScript of input %d does not match the P2SH output it spends.", inputIndex)
+	}
+	return redeemScript, nil
+}
+
+// checkWitnessScript makes sure input inputIndex has a witnessScript hashing to the P2WSH script it spends.
+func checkWitnessScript(p *PSBT, inputIndex int, script []byte) error {
+	witnessScript := p.Inputs[inputIndex].WitnessScript
+	if len(witnessScript) == 0 {
+		return fmt.Errorf("Input %d spends a P2WSH output but has no witnessScript.", inputIndex)
+	}
+	witnessScriptHash := sha256.Sum256(witnessScript)
+	if !bytes.Equal(witnessScriptHash[:], script[2:]) {
+		return fmt.Errorf("witnessScript of input %d does not match the P2WSH output it spends.", inputIndex)
+	}
+	return nil
+}
+
+// findPublicKey returns whichever of the candidate public keys is pushed by script.
+func findPublicKey(script []byte, candidates ...[]byte) ([]byte, error) {
+	opcodes, err := txscript.ParseScript(script)
+	if err != nil {
+		return nil, err
+	}
+	for _, opcode := range opcodes {
+		for _, candidate := range candidates {
+			if bytes.Equal(opcode.Data, candidate) {
+				return candidate, nil
+			}
+		}
+	}
+	return nil, errors.New("Public key not found in script.")
+}
+
+// isP2PKH reports whether script is OP_DUP OP_HASH160 <20 bytes> OP_EQUALVERIFY OP_CHECKSIG.
+func isP2PKH(script []byte) bool {
+	return len(script) == 25 && script[0] == btcutils.OP_DUP && script[1] == btcutils.OP_HASH160 && script[2] == 20 &&
+		script[23] == btcutils.OP_EQUALVERIFY && script[24] == btcutils.OP_CHECKSIG
+}
+
+// isP2SH reports whether script is OP_HASH160 <20 bytes> OP_EQUAL.
+func isP2SH(script []byte) bool {
+	return len(script) == 23 && script[0] == btcutils.OP_HASH160 && script[1] == 20 && script[22] == btcutils.OP_EQUAL
+}
+
+// isP2WPKH reports whether script is OP_0 <20 bytes>.
+func isP2WPKH(script []byte) bool {
+	return len(script) == 22 && script[0] == btcutils.OP_0 && script[1] == 20
+}
+
+// isP2WSH reports whether script is OP_0 <32 bytes>.
+func isP2WSH(script []byte) bool {
+	return len(script) == 34 && script[0] == btcutils.OP_0 && script[1] == 32
+}
+
+// isP2TR reports whether script is OP_1 <32 bytes>.
+func isP2TR(script []byte) bool {
+	return len(script) == 34 && script[0] == btcutils.OP_1 && script[1] == 32
+}
@@ -0,0 +1,216 @@
+package psbt
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/btcutils"
+	"github.com/CryptoProcessing/go-bitcoin-multisig/testutils"
+
+	"encoding/hex"
+	"testing"
+)
+
+// newTestPSBT returns a PSBT spending a single input to a single output.
+func newTestPSBT(t *testing.T, inputTx string, inputIndex uint32, satoshis uint64, scriptPubKey string) *PSBT {
+	testScriptPubKey, _ := hex.DecodeString(scriptPubKey)
+	tx := &btcutils.Transaction{
+		Version: 1,
+		Inputs:  []btcutils.Input{{TxHash: inputTx, OutputIndex: inputIndex, Sequence: btcutils.SequenceFinal}},
+		Outputs: []btcutils.Output{{Satoshis: satoshis, ScriptPubKey: testScriptPubKey}},
+	}
+	p, err := New(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// parseTestWIF returns the private key of a mainnet WIF.
+func parseTestWIF(t *testing.T, wif string) []byte {
+	privateKey, _, err := btcutils.ParseWIF(wif, &btcutils.MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return privateKey
+}
+
+func TestSignP2PKH(t *testing.T) {
+	//Same input and signature as the low R generateFund test
+	testPublicKey := "0431393af9984375830971ab5d3094c6a7d02db3568b2b06212a7090094549701bbb9e84d9477451acc42638963635899ce91bacb451a1bb6da73ddfbcf596bddf"
+	testSignature := "304402201974e077efd647cb374e8b5c08bcc83bbf87224673af102215d319353aadb1c7022074c08e6a2f682602f6a50426c7397e8976316215d35b774dbfee21e73ef38bb501"
+	p := newTestPSBT(t, "3ad337270ac0ba14fbce812291b7d95338c878709ea8123a4d88c3c29efbc6ac", 0, 65600, "a9141a8b0026343166625c7475f01e48b5ede8c0252e87")
+	privateKey := parseTestWIF(t, "5JJyqG4bb15zqi7fTA4b227aUxQhBo1Ux6qX69ngeXYLr7fk2hs")
+	publicKey, _ := hex.DecodeString(testPublicKey)
+	publicKeyHash, _ := btcutils.Hash160(publicKey)
+	scriptPubKey, _ := btcutils.NewP2PKHScriptPubKey(publicKeyHash)
+
+	if err := Sign(p, 0, privateKey, nil); err == nil {
+		t.Error("Sign accepting input without a UTXO.")
+	}
+	err := Sign(p, 0, privateKey, &btcutils.Output{Satoshis: 100000, ScriptPubKey: scriptPubKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	signatureHex := hex.EncodeToString(p.Inputs[0].PartialSigs[testPublicKey])
+	if signatureHex != testSignature {
+		testutils.CompareError(t, "Partial signature different from expected signature.", testSignature, signatureHex)
+	}
+
+	otherPrivateKey := parseTestWIF(t, "5JruagvxNLXTnkksyLMfgFgf3CagJ3Ekxu5oGxpTm5mPfTAPez3")
+	if err := Sign(p, 0, otherPrivateKey, &btcutils.Output{Satoshis: 100000, ScriptPubKey: scriptPubKey}); err == nil {
+		t.Error("Sign accepting private key that is not the key of the P2PKH output.")
+	}
+	if err := Sign(p, 1, privateKey, nil); err == nil {
+		t.Error("Sign accepting out of range input index.")
+	}
+}
+
+func TestSignP2SHMultisig(t *testing.T) {
+	//Same 2-of-3 input and signatures as the README spend example
+	testRedeemScript, _ := hex.DecodeString("524104a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575fa349b5694ed3155b136f09e63975a1700c9f4d4df849323dac06cf3bd6458cd41046ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640de68c2fe913d363a51154a0c62d7adea1b822d05035077418267b1a1379790187410411ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef992f63280567f52f5ba870678b4ab4ff6c8ea600bd217870a8b4f1f09f3a8e8353ae")
+	testSignatures := map[string]string{
+		"04a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575fa349b5694ed3155b136f09e63975a1700c9f4d4df849323dac06cf3bd6458cd": "30440220288cda5e24e4974c57400f1168a0e2423619ed53b21100a1a939de0131f3051702207190d948558bb478a4b4b1d5eeb0a71e4fedd353dcd6432ce96e7d164b86756b01",
+		"0411ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef992f63280567f52f5ba870678b4ab4ff6c8ea600bd217870a8b4f1f09f3a8e83": "3044022072cdadb0ed013a1104c051cf3bcad78c32746c7f1022b4ebd00e64dddaf910b302205451cf574866dade3e96fafd2e36d87013c66f441bbf790910e11303d72ead5501",
+	}
+	p := newTestPSBT(t, "02b082113e35d5386285094c2829e7e2963fa0b5369fb7f4b79c4c90877dcd3d", 0, 55600, "76a914569076ba39fc4ff6a2291d9ea9196d8c08f9c7ab88ac")
+	redeemScriptHash, _ := btcutils.Hash160(testRedeemScript)
+	scriptPubKey, _ := btcutils.NewP2SHScriptPubKey(redeemScriptHash)
+	utxo := &btcutils.Output{Satoshis: 65600, ScriptPubKey: scriptPubKey}
+	privateKeys := []string{"5JruagvxNLXTnkksyLMfgFgf3CagJ3Ekxu5oGxpTm5mPfTAPez3", "5JjHVMwJdjPEPQhq34WMUhzLcEd4SD7HgZktEh8WHstWcCLRceV"}
+
+	if err := Sign(p, 0, parseTestWIF(t, privateKeys[0]), utxo); err == nil {
+		t.Error("Sign accepting P2SH input without a redeemScript.")
+	}
+	p.Inputs[0].RedeemScript = testRedeemScript
+	for _, privateKey := range privateKeys {
+		if err := Sign(p, 0, parseTestWIF(t, privateKey), utxo); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for publicKey, testSignature := range testSignatures {
+		signatureHex := hex.EncodeToString(p.Inputs[0].PartialSigs[publicKey])
+		if signatureHex != testSignature {
+			testutils.CompareError(t, "Partial signature different from expected signature.", testSignature, signatureHex)
+		}
+	}
+	if len(p.Inputs[0].PartialSigs) != len(testSignatures) {
+		testutils.CompareError(t, "Partial signature count different from expected count.", len(testSignatures), len(p.Inputs[0].PartialSigs))
+	}
+
+	if err := Sign(p, 0, parseTestWIF(t, "5JJyqG4bb15zqi7fTA4b227aUxQhBo1Ux6qX69ngeXYLr7fk2hs"), utxo); err == nil {
+		t.Error("Sign accepting private key that is not a signer of the redeemScript.")
+	}
+	p.Inputs[0].RedeemScript = testRedeemScript[1:]
+	if err := Sign(p, 0, parseTestWIF(t, privateKeys[0]), utxo); err == nil {
+		t.Error("Sign accepting redeemScript that does not match the P2SH output.")
+	}
+}
+
+func TestSignP2WPKH(t *testing.T) {
+	testPublicKey := "025476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee6357"
+	testSignature := "3044022056cf29e632ea490cae407fe44b7e7044b8a32d8b6a4f77406341c46fd194206b02204a15713d16cbf91064d6aed936b4b3c4b0f595a582f84f59360d9a22323ea92701"
+	testWitnessUtxoScript, _ := hex.DecodeString("00141d0f172a0ecb48aee1be1f2687d2963ae33f71a1")
+	p := newTestPSBT(t, "8ac60eb9575db5b2d987e29f301b5b819ea83a5c6579d282d189cc04b8e151ef", 1, 599990000, "a9141a8b0026343166625c7475f01e48b5ede8c0252e87")
+	p.Inputs[0].WitnessUtxo = &btcutils.Output{Satoshis: 600000000, ScriptPubKey: testWitnessUtxoScript}
+	privateKey := parseTestWIF(t, "KzVTBhbMaKrAYagJ11VdTaBrb6yzLykLGyuMBkf9sCFPDxdT8shL")
+
+	if err := Sign(p, 0, privateKey, &btcutils.Output{Satoshis: 500000000, ScriptPubKey: testWitnessUtxoScript}); err == nil {
+		t.Error("Sign accepting UTXO different from the witness UTXO of the input.")
+	}
+	if err := Sign(p, 0, privateKey, nil); err != nil {
+		t.Fatal(err)
+	}
+	signatureHex := hex.EncodeToString(p.Inputs[0].PartialSigs[testPublicKey])
+	if signatureHex != testSignature {
+		testutils.CompareError(t, "Partial signature different from expected signature.", testSignature, signatureHex)
+	}
+
+	p.Inputs[0].FinalScriptWitness = [][]byte{p.Inputs[0].PartialSigs[testPublicKey]}
+	if err := Sign(p, 0, privateKey, nil); err == nil {
+		t.Error("Sign accepting finalized input.")
+	}
+}
+
+func TestCanSign(t *testing.T) {
+	//2-of-3 P2WSH multisig output of the generateRedeemP2WSH test
+	testWitnessScript, _ := hex.DecodeString("5221025476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee63572103b6446545566e0fecb2f2243db709bbf4e9fff78d48c9438acc911a36015e896a21033d63026f5f0de46e40dc09c61c1559240ea4381df7e05045f6ae2ac506efbaf253ae")
+	scriptPubKey, _ := btcutils.NewP2WSHScriptPubKey(testWitnessScript)
+	p := newTestPSBT(t, "8ac60eb9575db5b2d987e29f301b5b819ea83a5c6579d282d189cc04b8e151ef", 0, 90000, "a9141a8b0026343166625c7475f01e48b5ede8c0252e87")
+	signer := parseTestWIF(t, "KzVTBhbMaKrAYagJ11VdTaBrb6yzLykLGyuMBkf9sCFPDxdT8shL")
+	other := parseTestWIF(t, "5JJyqG4bb15zqi7fTA4b227aUxQhBo1Ux6qX69ngeXYLr7fk2hs")
+
+	if _, err := CanSign(p, 0, signer); err == nil {
+		t.Error("CanSign accepting input without a UTXO.")
+	}
+	p.Inputs[0].WitnessUtxo = &btcutils.Output{Satoshis: 100000, ScriptPubKey: scriptPubKey}
+	if _, err := CanSign(p, 0, signer); err == nil {
+		t.Error("CanSign accepting P2WSH input without a witnessScript.")
+	}
+	p.Inputs[0].WitnessScript = testWitnessScript
+	canSign, err := CanSign(p, 0, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !canSign {
+		t.Error("CanSign not finding public key of a signer in the witnessScript.")
+	}
+	canSign, err = CanSign(p, 0, other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if canSign {
+		t.Error("CanSign finding public key that is not in the witnessScript.")
+	}
+
+	//P2WPKH output of the first key
+	publicKey, _ := btcutils.NewCompressedPublicKey(signer)
+	publicKeyHash, _ := btcutils.Hash160(publicKey)
+	p.Inputs[0].WitnessUtxo.ScriptPubKey, _ = btcutils.NewP2WPKHScriptPubKey(publicKeyHash)
+	for testPrivateKey, testCanSign := range map[string]bool{"KzVTBhbMaKrAYagJ11VdTaBrb6yzLykLGyuMBkf9sCFPDxdT8shL": true, "5JJyqG4bb15zqi7fTA4b227aUxQhBo1Ux6qX69ngeXYLr7fk2hs": false} {
+		canSign, err := CanSign(p, 0, parseTestWIF(t, testPrivateKey))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if canSign != testCanSign {
+			testutils.CompareError(t, "CanSign of P2WPKH input different from expected result.", testCanSign, canSign)
+		}
+	}
+	if _, err := CanSign(p, 1, signer); err == nil {
+		t.Error("CanSign accepting out of range input index.")
+	}
+}
+
+func TestSignTaproot(t *testing.T) {
+	btcutils.SetFixedNonce = true //SetFixedNonce set to true to get repeatable signatures with zero auxiliary randomness for testing.
+	defer func() { btcutils.SetFixedNonce = false }()
+	//Same input and signature as the generateFundP2TR test
+	testSignature := "f713faa59c59561c72fab211c442c9966827edb7690375645e0fb6c4a6b60da607aca3a7d480223a9fc92bc9b0d7e91231f97b24589100eca7b04d88926fe3e2"
+	p := newTestPSBT(t, "3ad337270ac0ba14fbce812291b7d95338c878709ea8123a4d88c3c29efbc6ac", 1, 90000, "512053a1f6e454df1aa2776a2814a721372d6258050de330b3c6d10ee8f4e0dda343")
+	privateKey := parseTestWIF(t, "KyQMr1Usf5P4HzUQiSzq9Vv2nPZaNe58NCm9ojndzdoLbfKEvPms")
+	publicKey, _ := btcutils.NewCompressedPublicKey(privateKey)
+	outputKey, _ := btcutils.TweakPublicKey(publicKey[1:], nil)
+	scriptPubKey, _ := btcutils.CreateP2TRScriptPubKey(outputKey)
+	p.Inputs[0].WitnessUtxo = &btcutils.Output{Satoshis: 100000, ScriptPubKey: scriptPubKey}
+
+	if err := Sign(p, 0, privateKey, nil); err != nil {
+		t.Fatal(err)
+	}
+	signatureHex := hex.EncodeToString(p.Inputs[0].TaprootKeySig)
+	if signatureHex != testSignature {
+		testutils.CompareError(t, "Taproot key signature different from expected signature.", testSignature, signatureHex)
+	}
+	//The Taproot key signature survives serialization
+	serialized, err := p.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	deserialized, err := Deserialize(serialized)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(deserialized.Inputs[0].TaprootKeySig) != testSignature {
+		testutils.CompareError(t, "Deserialized Taproot key signature different from expected signature.", testSignature, hex.EncodeToString(deserialized.Inputs[0].TaprootKeySig))
+	}
+
+	if err := Sign(p, 0, parseTestWIF(t, "KzVTBhbMaKrAYagJ11VdTaBrb6yzLykLGyuMBkf9sCFPDxdT8shL"), nil); err == nil {
+		t.Error("Sign accepting private key that is not the internal key of the P2TR output.")
+	}
+}
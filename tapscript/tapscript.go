@@ -0,0 +1,139 @@
+// Package tapscript builds BIP341 Taproot script trees, computing the merkle root an internal key is tweaked with
+// and the control block that spends the output with one of its leaf scripts.
+// See https://github.com/bitcoin/bips/blob/master/bip-0341.mediawiki for full specification.
+package tapscript
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/btcutils"
+
+	"errors"
+	"fmt"
+)
+
+// MaxDepth is the deepest a leaf can be in a script tree, as a control block holds at most 128 merkle path hashes.
+const MaxDepth = 128
+
+// annexTag is the first byte of a Taproot witness annex. Leaf versions can't be 0x50, as control blocks starting
+// with it could be mistaken for an annex.
+const annexTag = 0x50
+
+// Node is a node of a script tree, a Leaf or a Branch.
+type Node interface {
+	isNode()
+}
+
+// Leaf is a script of a script tree, with the leaf version it is run with.
+type Leaf struct {
+	Version byte   //Leaf version, btcutils.TapscriptLeafVersion for BIP342 tapscript
+	Script  []byte //Script run when the leaf is spent
+}
+
+// Branch is a node of a script tree with two children, each a Leaf or a Branch.
+type Branch struct {
+	Left  Node
+	Right Node
+}
+
+func (Leaf) isNode()   {}
+func (Branch) isNode() {}
+
+// NewLeaf returns the tapscript leaf of script, with leaf version btcutils.TapscriptLeafVersion.
+func NewLeaf(script []byte) Leaf {
+	return Leaf{Version: btcutils.TapscriptLeafVersion, Script: script}
+}
+
+// Tree is a script tree, spent with the control block of one of its leaves.
+type Tree struct {
+	Root Node
+}
+
+// leafProof is a leaf of a script tree and its merkle path, the hashes of its sibling at each level from the leaf up.
+type leafProof struct {
+	leaf       Leaf
+	merklePath [][]byte
+}
+
+// ComputeMerkleRoot computes the BIP341 merkle root of the script tree with root tree, H_TapLeaf(version ||
+// compact size || script) for a leaf and H_TapBranch of the two children hashes in lexicographic order for a
+// branch. Returns the 32 byte merkle root to tweak the internal key with, or an error if a node is nil, a leaf
+// version is invalid or a leaf is deeper than MaxDepth.
+func ComputeMerkleRoot(tree Node) ([]byte, error) {
+	merkleRoot, _, err := walk(tree, 0)
+	return merkleRoot, err
+}
+
+// Leaves returns the leaves of t, depth first from left to right, the order ControlBlock indexes them in.
+func (t Tree) Leaves() ([]Leaf, error) {
+	_, proofs, err := walk(t.Root, 0)
+	if err != nil {
+		return nil, err
+	}
+	leaves := make([]Leaf, len(proofs))
+	for i, proof := range proofs {
+		leaves[i] = proof.leaf
+	}
+	return leaves, nil
+}
+
+// ControlBlock creates the BIP341 control block needed in the witness to spend the Taproot output of the 32 byte
+// x-only internalKey tweaked with the merkle root of t, with leaf leafIndex of t, counting leaves depth first from
+// left to right. Returns the control block, leaf version and output key parity followed by internalKey and the
+// merkle path of the leaf, or an error if leafIndex is out of range or t is invalid.
+func (t Tree) ControlBlock(leafIndex int, internalKey []byte) ([]byte, error) {
+	merkleRoot, proofs, err := walk(t.Root, 0)
+	if err != nil {
+		return nil, err
+	}
+	if leafIndex < 0 || leafIndex >= len(proofs) {
+		return nil, fmt.Errorf("Leaf index %d out of range. Script tree has %d leaves.", leafIndex, len(proofs))
+	}
+	_, parity, err := btcutils.TaprootTweakKey(internalKey, merkleRoot)
+	if err != nil {
+		return nil, err
+	}
+	proof := proofs[leafIndex]
+	controlBlock := []byte{proof.leaf.Version}
+	if parity {
+		controlBlock[0] |= 0x01
+	}
+	controlBlock = append(controlBlock, internalKey...)
+	for _, hash := range proof.merklePath {
+		controlBlock = append(controlBlock, hash...)
+	}
+	return controlBlock, nil
+}
+
+// walk returns the hash of node, at depth branches below the root of the script tree, and the leaves under it with
+// their merkle paths up to node.
+func walk(node Node, depth int) ([]byte, []leafProof, error) {
+	switch n := node.(type) {
+	case Leaf:
+		if n.Version&0x01 != 0 || n.Version == annexTag {
+			return nil, nil, fmt.Errorf("Leaf version 0x%02x is not a valid Taproot leaf version. Leaf versions must be even and cannot be 0x%02x.", n.Version, annexTag)
+		}
+		return btcutils.TapLeafVersionHash(n.Version, n.Script), []leafProof{{leaf: n}}, nil
+	case Branch:
+		if depth >= MaxDepth {
+			return nil, nil, fmt.Errorf("Script tree leaves can be at most %d levels deep.", MaxDepth)
+		}
+		leftHash, leftProofs, err := walk(n.Left, depth+1)
+		if err != nil {
+			return nil, nil, err
+		}
+		rightHash, rightProofs, err := walk(n.Right, depth+1)
+		if err != nil {
+			return nil, nil, err
+		}
+		for i := range leftProofs {
+			leftProofs[i].merklePath = append(leftProofs[i].merklePath, rightHash)
+		}
+		for i := range rightProofs {
+			rightProofs[i].merklePath = append(rightProofs[i].merklePath, leftHash)
+		}
+		return btcutils.TapBranchHash(leftHash, rightHash), append(leftProofs, rightProofs...), nil
+	case nil:
+		return nil, nil, errors.New("Script tree node cannot be nil.")
+	default:
+		return nil, nil, fmt.Errorf("Script tree node of type %T is not a Leaf or Branch.", node)
+	}
+}
@@ -0,0 +1,185 @@
+package tapscript
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/btcutils"
+	"github.com/CryptoProcessing/go-bitcoin-multisig/testutils"
+
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+// testScriptTree is a BIP341 wallet test vector script tree, with the merkle root, output key and control block of
+// each leaf it gives with internalKey.
+type testScriptTree struct {
+	name          string
+	internalKey   string
+	tree          Node
+	merkleRoot    string
+	outputKey     string
+	controlBlocks []string
+}
+
+func testLeaf(version byte, scriptHex string) Leaf {
+	script, _ := hex.DecodeString(scriptHex)
+	return Leaf{Version: version, Script: script}
+}
+
+var testScriptTrees = []testScriptTree{
+	{
+		name:          "single leaf",
+		internalKey:   "187791b6f712a8ea41c8ecdd0ee77fab3e85263b37e1ec18a3651926b3a6cf27",
+		tree:          testLeaf(0xc0, "20d85a959b0290bf19bb89ed43c916be835475d013da4b362117393e25a48229b8ac"),
+		merkleRoot:    "5b75adecf53548f3ec6ad7d78383bf84cc57b55a3127c72b9a2481752dd88b21",
+		outputKey:     "147c9c57132f6e7ecddba9800bb0c4449251c92a1e60371ee77557b6620f3ea3",
+		controlBlocks: []string{"c1187791b6f712a8ea41c8ecdd0ee77fab3e85263b37e1ec18a3651926b3a6cf27"},
+	},
+	{
+		name:        "two leaves with different leaf versions",
+		internalKey: "ee4fe085983462a184015d1f782d6a5f8b9c2b60130aff050ce221ecf3786592",
+		tree: Branch{
+			Left:  testLeaf(0xc0, "20387671353e273264c495656e27e39ba899ea8fee3bb69fb2a680e22093447d48ac"),
+			Right: testLeaf(0xfa, "06424950333431"),
+		},
+		merkleRoot: "6c2dc106ab816b73f9d07e3cd1ef2c8c1256f519748e0813e4edd2405d277bef",
+		outputKey:  "712447206d7a5238acc7ff53fbe94a3b64539ad291c7cdbc490b7577e4b17df5",
+		controlBlocks: []string{
+			"c0ee4fe085983462a184015d1f782d6a5f8b9c2b60130aff050ce221ecf3786592f224a923cd0021ab202ab139cc56802ddb92dcfc172b9212261a539df79a112a",
+			"faee4fe085983462a184015d1f782d6a5f8b9c2b60130aff050ce221ecf37865928ad69ec7cf41c2a4001fd1f738bf1e505ce2277acdcaa63fe4765192497f47a7",
+		},
+	},
+	{
+		name:        "three leaves, even parity",
+		internalKey: "e0dfe2300b0dd746a3f8674dfd4525623639042569d829c7f0eed9602d263e6f",
+		tree: Branch{
+			Left: testLeaf(0xc0, "2072ea6adcf1d371dea8fba1035a09f3d24ed5a059799bae114084130ee5898e69ac"),
+			Right: Branch{
+				Left:  testLeaf(0xc0, "202352d137f2f3ab38d1eaa976758873377fa5ebb817372c71e2c542313d4abda8ac"),
+				Right: testLeaf(0xc0, "207337c0dd4253cb86f2c43a2351aadd82cccb12a172cd120452b9bb8324f2186aac"),
+			},
+		},
+		merkleRoot: "ccbd66c6f7e8fdab47b3a486f59d28262be857f30d4773f2d5ea47f7761ce0e2",
+		outputKey:  "91b64d5324723a985170e4dc5a0f84c041804f2cd12660fa5dec09fc21783605",
+		controlBlocks: []string{
+			"c0e0dfe2300b0dd746a3f8674dfd4525623639042569d829c7f0eed9602d263e6fffe578e9ea769027e4f5a3de40732f75a88a6353a09d767ddeb66accef85e553",
+			"c0e0dfe2300b0dd746a3f8674dfd4525623639042569d829c7f0eed9602d263e6f9e31407bffa15fefbf5090b149d53959ecdf3f62b1246780238c24501d5ceaf62645a02e0aac1fe69d69755733a9b7621b694bb5b5cde2bbfc94066ed62b9817",
+			"c0e0dfe2300b0dd746a3f8674dfd4525623639042569d829c7f0eed9602d263e6fba982a91d4fc552163cb1c0da03676102d5b7a014304c01f0c77b2b8e888de1c2645a02e0aac1fe69d69755733a9b7621b694bb5b5cde2bbfc94066ed62b9817",
+		},
+	},
+	{
+		name:        "three leaves, odd parity",
+		internalKey: "55adf4e8967fbd2e29f20ac896e60c3b0f1d5b0efa9d34941b5958c7b0a0312d",
+		tree: Branch{
+			Left: testLeaf(0xc0, "2071981521ad9fc9036687364118fb6ccd2035b96a423c59c5430e98310a11abe2ac"),
+			Right: Branch{
+				Left:  testLeaf(0xc0, "20d5094d2dbe9b76e2c245a2b89b6006888952e2faa6a149ae318d69e520617748ac"),
+				Right: testLeaf(0xc0, "20c440b462ad48c7a77f94cd4532d8f2119dcebbd7c9764557e62726419b08ad4cac"),
+			},
+		},
+		merkleRoot: "2f6b2c5397b6d68ca18e09a3f05161668ffe93a988582d55c6f07bd5b3329def",
+		outputKey:  "75169f4001aa68f15bbed28b218df1d0a62cbbcf1188c6665110c293c907b831",
+		controlBlocks: []string{
+			"c155adf4e8967fbd2e29f20ac896e60c3b0f1d5b0efa9d34941b5958c7b0a0312d3cd369a528b326bc9d2133cbd2ac21451acb31681a410434672c8e34fe757e91",
+			"c155adf4e8967fbd2e29f20ac896e60c3b0f1d5b0efa9d34941b5958c7b0a0312dd7485025fceb78b9ed667db36ed8b8dc7b1f0b307ac167fa516fe4352b9f4ef7f154e8e8e17c31d3462d7132589ed29353c6fafdb884c5a6e04ea938834f0d9d",
+			"c155adf4e8967fbd2e29f20ac896e60c3b0f1d5b0efa9d34941b5958c7b0a0312d737ed1fe30bc42b8022d717b44f0d93516617af64a64753b7a06bf16b26cd711f154e8e8e17c31d3462d7132589ed29353c6fafdb884c5a6e04ea938834f0d9d",
+		},
+	},
+}
+
+func TestComputeMerkleRoot(t *testing.T) {
+	for _, testTree := range testScriptTrees {
+		merkleRoot, err := ComputeMerkleRoot(testTree.tree)
+		if err != nil {
+			t.Fatal(err)
+		}
+		merkleRootHex := hex.EncodeToString(merkleRoot)
+		if merkleRootHex != testTree.merkleRoot {
+			testutils.CompareError(t, "Merkle root of "+testTree.name+" different from expected merkle root.", testTree.merkleRoot, merkleRootHex)
+		}
+		internalKey, _ := hex.DecodeString(testTree.internalKey)
+		outputKey, err := btcutils.TweakPublicKey(internalKey, merkleRoot)
+		if err != nil {
+			t.Fatal(err)
+		}
+		outputKeyHex := hex.EncodeToString(outputKey)
+		if outputKeyHex != testTree.outputKey {
+			testutils.CompareError(t, "Output key of "+testTree.name+" different from expected key.", testTree.outputKey, outputKeyHex)
+		}
+	}
+
+	//Leaves are hashed with their own leaf version
+	script, _ := hex.DecodeString("06424950333431")
+	merkleRoot, err := ComputeMerkleRoot(NewLeaf(script))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(merkleRoot, btcutils.TapLeafHash(script)) {
+		t.Error("Merkle root of tapscript leaf different from its btcutils.TapLeafHash.")
+	}
+
+	deepTree := Node(NewLeaf(script))
+	for i := 0; i < MaxDepth; i++ {
+		deepTree = Branch{Left: deepTree, Right: NewLeaf(script)}
+	}
+	if _, err := ComputeMerkleRoot(deepTree); err != nil {
+		t.Errorf("ComputeMerkleRoot refusing leaves %d levels deep. %s", MaxDepth, err)
+	}
+	invalidTrees := map[string]Node{
+		"nil tree":              nil,
+		"nil child":             Branch{Left: NewLeaf(script)},
+		"odd leaf version":      Leaf{Version: 0xc1, Script: script},
+		"annex leaf version":    Leaf{Version: 0x50, Script: script},
+		"leaf pointer":          &Leaf{Version: 0xc0, Script: script},
+		"leaves too deep":       Branch{Left: deepTree, Right: NewLeaf(script)},
+		"invalid leaf in right": Branch{Left: NewLeaf(script), Right: Leaf{Version: 0xff}},
+	}
+	for name, invalidTree := range invalidTrees {
+		if _, err := ComputeMerkleRoot(invalidTree); err == nil {
+			t.Errorf("ComputeMerkleRoot accepting script tree with %s.", name)
+		}
+	}
+}
+
+func TestTreeControlBlock(t *testing.T) {
+	for _, testTree := range testScriptTrees {
+		tree := Tree{Root: testTree.tree}
+		internalKey, _ := hex.DecodeString(testTree.internalKey)
+		outputKey, _ := hex.DecodeString(testTree.outputKey)
+		leaves, err := tree.Leaves()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(leaves) != len(testTree.controlBlocks) {
+			t.Fatalf("%s has %d leaves, expected %d.", testTree.name, len(leaves), len(testTree.controlBlocks))
+		}
+		for i, testControlBlockHex := range testTree.controlBlocks {
+			controlBlock, err := tree.ControlBlock(i, internalKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			controlBlockHex := hex.EncodeToString(controlBlock)
+			if controlBlockHex != testControlBlockHex {
+				testutils.CompareError(t, "Control block of "+testTree.name+" different from expected control block.", testControlBlockHex, controlBlockHex)
+			}
+			//VerifyTaprootControlBlock only knows tapscript leaves
+			if leaves[i].Version == btcutils.TapscriptLeafVersion {
+				err = btcutils.VerifyTaprootControlBlock(outputKey, leaves[i].Script, controlBlock)
+				if err != nil {
+					t.Errorf("Control block of leaf %d of %s not verified. %s", i, testTree.name, err)
+				}
+			}
+		}
+		if _, err := tree.ControlBlock(len(leaves), internalKey); err == nil {
+			t.Errorf("ControlBlock accepting leaf index %d of %s with %d leaves.", len(leaves), testTree.name, len(leaves))
+		}
+		if _, err := tree.ControlBlock(-1, internalKey); err == nil {
+			t.Error("ControlBlock accepting negative leaf index.")
+		}
+		if _, err := tree.ControlBlock(0, internalKey[1:]); err == nil {
+			t.Error("ControlBlock accepting 31 byte internal key.")
+		}
+	}
+	if _, err := (Tree{}).ControlBlock(0, btcutils.UnspendableInternalKey); err == nil {
+		t.Error("ControlBlock accepting empty script tree.")
+	}
+}
@@ -0,0 +1,353 @@
+package txbuilder
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/btcutils"
+	"github.com/CryptoProcessing/go-bitcoin-multisig/txscript"
+
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// Builder builds and signs a transaction an input or output at a time. The first error is kept and returned by Sign
+// and Build, so calls can be chained without checking each one:
+//
+//	tx, err := txbuilder.NewBuilder().AddInput(txid, 0, scriptPubKey, 100000).AddOutput(destination, 90000).Build()
+//
+// Signatures commit to the whole transaction, so adding an input or output or setting the lock time, version or a
+// sequence drops the signatures made so far, and the inputs must be signed again.
+type Builder struct {
+	tx           btcutils.Transaction
+	prevOuts     []btcutils.Output //Output spent by each input, in the same order
+	signed       []bool
+	maxFee       int64 //Highest fee in satoshis Build allows, unless forceHighFee is set
+	forceHighFee bool
+	err          error
+}
+
+// NewBuilder returns a Builder for a version 2 transaction with no inputs, outputs or lock time, refusing fees above
+// btcutils.DefaultMaxFee.
+func NewBuilder() *Builder {
+	return &Builder{tx: btcutils.Transaction{Version: 2}, maxFee: btcutils.DefaultMaxFee}
+}
+
+// AddInput adds an input spending output vout of transaction txid, in the big-endian hex form displayed by block
+// explorers, which pays value satoshis to scriptPubKey. The input is final, unless a lock time is set.
+func (b *Builder) AddInput(txid string, vout uint32, scriptPubKey []byte, value int64) *Builder {
+	if b.err != nil {
+		return b
+	}
+	if txHash, err := hex.DecodeString(txid); err != nil || len(txHash) != 32 {
+		b.err = fmt.Errorf("Input %d transaction ID %q is not 32 bytes of hex.", len(b.tx.Inputs), txid)
+		return b
+	}
+	if value <= 0 || value > btcutils.MaxMoney {
+		b.err = fmt.Errorf("Input %d amount must be between 1 and %d satoshis. Provided amount is %d satoshis.", len(b.tx.Inputs), int64(btcutils.MaxMoney), value)
+		return b
+	}
+	sequence := btcutils.SequenceFinal
+	if b.tx.LockTime != 0 {
+		sequence = btcutils.SequenceMaxNonFinal
+	}
+	b.tx.Inputs = append(b.tx.Inputs, btcutils.Input{TxHash: txid, OutputIndex: vout, Sequence: sequence})
+	b.prevOuts = append(b.prevOuts, btcutils.Output{Satoshis: uint64(value), ScriptPubKey: scriptPubKey})
+	b.signed = append(b.signed, false)
+	b.dropSignatures()
+	return b
+}
+
+// AddOutput adds an output paying value satoshis to scriptPubKey.
+func (b *Builder) AddOutput(scriptPubKey []byte, value int64) *Builder {
+	if b.err != nil {
+		return b
+	}
+	if value < 0 {
+		b.err = fmt.Errorf("Output %d amount of %d satoshis is negative.", len(b.tx.Outputs), value)
+		return b
+	}
+	b.tx.Outputs = append(b.tx.Outputs, btcutils.Output{Satoshis: uint64(value), ScriptPubKey: scriptPubKey})
+	b.dropSignatures()
+	return b
+}
+
+// SetLockTime sets the lock time of the transaction, a block height below btcutils.LockTimeThreshold or a Unix
+// timestamp from it, 0 for no lock time. Lock times are ignored if every input is final, so final inputs are given
+// sequence btcutils.SequenceMaxNonFinal when the lock time is not zero.
+func (b *Builder) SetLockTime(lt uint32) *Builder {
+	if b.err != nil {
+		return b
+	}
+	if err := btcutils.CheckLockTime(int64(lt)); err != nil {
+		b.err = err
+		return b
+	}
+	b.tx.LockTime = lt
+	for i := range b.tx.Inputs {
+		if lt != 0 && b.tx.Inputs[i].Sequence == btcutils.SequenceFinal {
+			b.tx.Inputs[i].Sequence = btcutils.SequenceMaxNonFinal
+		} else if lt == 0 && b.tx.Inputs[i].Sequence == btcutils.SequenceMaxNonFinal {
+			b.tx.Inputs[i].Sequence = btcutils.SequenceFinal
+		}
+	}
+	b.dropSignatures()
+	return b
+}
+
+// SetVersion sets the version of the transaction, 2 unless set, which lets inputs use BIP68 relative lock times.
+func (b *Builder) SetVersion(version uint32) *Builder {
+	if b.err != nil {
+		return b
+	}
+	b.tx.Version = version
+	b.dropSignatures()
+	return b
+}
+
+// SetSequence sets the sequence number of input inputIndex, in place of the one given by AddInput and SetLockTime,
+// to signal BIP125 replaceability or set a BIP68 relative lock time. Set the lock time first, as SetLockTime
+// changes the sequence of final inputs.
+func (b *Builder) SetSequence(inputIndex int, sequence uint32) *Builder {
+	if b.err != nil {
+		return b
+	}
+	if inputIndex < 0 || inputIndex >= len(b.tx.Inputs) {
+		b.err = fmt.Errorf("Input index %d out of range for transaction with %d inputs.", inputIndex, len(b.tx.Inputs))
+		return b
+	}
+	b.tx.Inputs[inputIndex].Sequence = sequence
+	b.dropSignatures()
+	return b
+}
+
+// SetMaxFee sets the highest fee in satoshis, what the inputs hold beyond the outputs, Build allows.
+func (b *Builder) SetMaxFee(maxFee int64) *Builder {
+	if b.err != nil {
+		return b
+	}
+	if maxFee < 0 {
+		b.err = fmt.Errorf("Maximum fee cannot be negative. Provided maximum is %d satoshis.", maxFee)
+		return b
+	}
+	b.maxFee = maxFee
+	return b
+}
+
+// ForceHighFee lets Build pay a fee above the maximum fee or btcutils.MaxFeePercent of the amount sent.
+func (b *Builder) ForceHighFee() *Builder {
+	b.forceHighFee = true
+	return b
+}
+
+// Sign signs input inputIndex with privKey and sigHashType, picking the signature hash from the scriptPubKey of the
+// output it spends: the legacy signature hash for P2PKH, BIP143 for P2WPKH and BIP341 for a P2TR key path spend,
+// with privKey tweaked as for a BIP86 output without a script tree. btcutils.SigHashDefault signs ECDSA inputs with
+// SIGHASH_ALL, as SIGHASH_DEFAULT only exists for Taproot.
+// Add every input and output before signing, as adding more drops the signatures.
+// Returns an error if an earlier call failed, the input doesn't exist, its scriptPubKey is of another type or
+// doesn't pay to privKey, or signing fails.
+func (b *Builder) Sign(inputIndex int, privKey []byte, sigHashType btcutils.SigHashType) (*Builder, error) {
+	if b.err != nil {
+		return b, b.err
+	}
+	if inputIndex < 0 || inputIndex >= len(b.tx.Inputs) {
+		return b, fmt.Errorf("Input index %d out of range for transaction with %d inputs.", inputIndex, len(b.tx.Inputs))
+	}
+	var err error
+	prevOut := b.prevOuts[inputIndex]
+	switch class := txscript.Classify(prevOut.ScriptPubKey); class {
+	case txscript.PubKeyHash:
+		err = b.signP2PKH(inputIndex, privKey, ecdsaSigHashType(sigHashType))
+	case txscript.WitnessPubKeyHash:
+		err = b.signP2WPKH(inputIndex, privKey, ecdsaSigHashType(sigHashType))
+	case txscript.WitnessTaproot:
+		err = b.signP2TR(inputIndex, privKey, sigHashType)
+	default:
+		err = fmt.Errorf("Input %d spends a %s script. Only P2PKH, P2WPKH and P2TR outputs can be signed.", inputIndex, class)
+	}
+	if err != nil {
+		return b, fmt.Errorf("Failed to sign input %d. %s", inputIndex, err)
+	}
+	b.signed[inputIndex] = true
+	return b, nil
+}
+
+// Build returns the signed transaction, a copy the Builder no longer changes.
+// Returns an error if an earlier call failed, the transaction has no inputs or outputs, an output amount is invalid,
+// the outputs spend more than the inputs hold, or an input is not signed. Unless ForceHighFee was called, a fee above
+// the maximum fee, or above btcutils.MaxFeePercent of the outputs, change included as the Builder can't tell it
+// apart, is refused with a *btcutils.HighFeeError as a likely mistyped amount.
+func (b *Builder) Build() (*btcutils.Transaction, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	if len(b.tx.Inputs) == 0 {
+		return nil, errors.New("Transaction must have at least one input.")
+	}
+	if len(b.tx.Outputs) == 0 {
+		return nil, errors.New("Transaction must have at least one output.")
+	}
+	var inputTotal uint64
+	for _, prevOut := range b.prevOuts {
+		inputTotal += prevOut.Satoshis
+	}
+	fee, err := btcutils.CheckFee(inputTotal, b.tx.Outputs)
+	if err != nil {
+		return nil, err
+	}
+	if !b.forceHighFee {
+		if err := btcutils.CheckMaxFee(int64(fee), int64(inputTotal-fee), b.maxFee); err != nil {
+			return nil, err
+		}
+	}
+	for i, signed := range b.signed {
+		if !signed {
+			return nil, fmt.Errorf("Input %d is not signed.", i)
+		}
+	}
+	tx := b.tx
+	tx.Inputs = append([]btcutils.Input(nil), b.tx.Inputs...)
+	tx.Outputs = append([]btcutils.Output(nil), b.tx.Outputs...)
+	return &tx, nil
+}
+
+// dropSignatures clears the scriptSigs and witnesses of the inputs signed so far, whose signatures no longer commit
+// to the transaction being built.
+func (b *Builder) dropSignatures() {
+	for i := range b.tx.Inputs {
+		b.tx.Inputs[i].ScriptSig = nil
+		b.tx.Inputs[i].Witness = nil
+		b.signed[i] = false
+	}
+}
+
+// ecdsaSigHashType returns the hash type to sign an ECDSA input with, SIGHASH_ALL for btcutils.SigHashDefault.
+func ecdsaSigHashType(sigHashType btcutils.SigHashType) btcutils.SigHashType {
+	if sigHashType == btcutils.SigHashDefault {
+		return btcutils.SigHashAll
+	}
+	return sigHashType
+}
+
+// signP2PKH signs input inputIndex, spending a P2PKH output of the compressed or uncompressed public key of privKey,
+// giving it a <signature> <public key> scriptSig.
+func (b *Builder) signP2PKH(inputIndex int, privKey []byte, sigHashType btcutils.SigHashType) error {
+	scriptPubKey := b.prevOuts[inputIndex].ScriptPubKey
+	var publicKey []byte
+	for _, newPublicKey := range []func([]byte) ([]byte, error){btcutils.NewCompressedPublicKey, btcutils.NewPublicKey} {
+		candidate, err := newPublicKey(privKey)
+		if err != nil {
+			return err
+		}
+		publicKeyHash, err := btcutils.Hash160(candidate)
+		if err != nil {
+			return err
+		}
+		keyScriptPubKey, err := btcutils.NewP2PKHScriptPubKey(publicKeyHash)
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(keyScriptPubKey, scriptPubKey) {
+			publicKey = candidate
+			break
+		}
+	}
+	if publicKey == nil {
+		return errors.New("Private key does not match the public key hash of the P2PKH output spent.")
+	}
+	if err := btcutils.CheckSigHashType(&b.tx, inputIndex, sigHashType); err != nil {
+		return err
+	}
+	sigHash, err := btcutils.CalcSignatureHash(&b.tx, inputIndex, scriptPubKey, sigHashType)
+	if err != nil {
+		return err
+	}
+	signature, err := btcutils.SignHash(sigHash, privKey)
+	if err != nil {
+		return err
+	}
+	signature = append(signature, byte(sigHashType))
+	scriptSig, err := txscript.NewBuilder().AddData(signature).AddData(publicKey).Script()
+	if err != nil {
+		return err
+	}
+	b.tx.Inputs[inputIndex].ScriptSig = scriptSig
+	return nil
+}
+
+// signP2WPKH signs input inputIndex, spending a P2WPKH output of the compressed public key of privKey, giving it a
+// <signature> <public key> witness.
+func (b *Builder) signP2WPKH(inputIndex int, privKey []byte, sigHashType btcutils.SigHashType) error {
+	prevOut := b.prevOuts[inputIndex]
+	publicKey, err := btcutils.NewCompressedPublicKey(privKey)
+	if err != nil {
+		return err
+	}
+	publicKeyHash, err := btcutils.Hash160(publicKey)
+	if err != nil {
+		return err
+	}
+	keyScriptPubKey, err := btcutils.NewP2WPKHScriptPubKey(publicKeyHash)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(keyScriptPubKey, prevOut.ScriptPubKey) {
+		return errors.New("Private key does not match the public key hash of the P2WPKH output spent.")
+	}
+	if err := btcutils.CheckSigHashType(&b.tx, inputIndex, sigHashType); err != nil {
+		return err
+	}
+	//The BIP143 scriptCode of P2WPKH inputs is the P2PKH scriptPubKey of the public key hash
+	scriptCode, err := btcutils.NewP2PKHScriptPubKey(publicKeyHash)
+	if err != nil {
+		return err
+	}
+	sigHash, err := btcutils.CalcWitnessSigHash(&b.tx, inputIndex, scriptCode, int64(prevOut.Satoshis), sigHashType)
+	if err != nil {
+		return err
+	}
+	signature, err := btcutils.SignHash(sigHash, privKey)
+	if err != nil {
+		return err
+	}
+	b.tx.Inputs[inputIndex].Witness = [][]byte{append(signature, byte(sigHashType)), publicKey}
+	return nil
+}
+
+// signP2TR signs a key path spend of input inputIndex, spending a P2TR output whose key is the public key of privKey
+// tweaked without a script tree, giving it a witness of the Schnorr signature.
+func (b *Builder) signP2TR(inputIndex int, privKey []byte, sigHashType btcutils.SigHashType) error {
+	publicKey, err := btcutils.NewCompressedPublicKey(privKey)
+	if err != nil {
+		return err
+	}
+	outputKey, err := btcutils.TweakPublicKey(publicKey[1:], nil)
+	if err != nil {
+		return err
+	}
+	keyScriptPubKey, err := btcutils.CreateP2TRScriptPubKey(outputKey)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(keyScriptPubKey, b.prevOuts[inputIndex].ScriptPubKey) {
+		return errors.New("Private key does not match the output key of the P2TR output spent.")
+	}
+	sigHash, err := btcutils.CalcTaprootSigHash(&b.tx, inputIndex, b.prevOuts, sigHashType)
+	if err != nil {
+		return err
+	}
+	tweakedPrivateKey, err := btcutils.TaprootTweakPrivateKey(privKey, nil)
+	if err != nil {
+		return err
+	}
+	signature, err := btcutils.SchnorrSign(tweakedPrivateKey, sigHash)
+	if err != nil {
+		return err
+	}
+	//The hash type byte is left out with SIGHASH_DEFAULT
+	if sigHashType != btcutils.SigHashDefault {
+		signature = append(signature, byte(sigHashType))
+	}
+	b.tx.Inputs[inputIndex].Witness = [][]byte{signature}
+	return nil
+}
@@ -0,0 +1,173 @@
+package txbuilder
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/btcutils"
+
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestBuilderP2PKHToP2SH(t *testing.T) {
+	privateKey, _ := hex.DecodeString("619c335025c7f4012e556c2a58b2506e30b8511b53ade95ea316fd8c3286feb9")
+	publicKey, _ := btcutils.NewCompressedPublicKey(privateKey)
+	p2pkhScriptPubKey, _ := btcutils.NewP2PKHScriptPubKey(btcutils.PublicKeyToHash160(publicKey))
+	redeemScriptHash, _ := hex.DecodeString("e9c3dd0c07aac76179ebc76a6c78d4d67c6c160a")
+	p2shScriptPubKey, _ := btcutils.NewP2SHScriptPubKey(redeemScriptHash)
+	txid := "3ad337270ac0ba14fbce812291b7d95338c878709ea8123a4d88c3c29efbc6ac"
+
+	builder, err := NewBuilder().AddInput(txid, 1, p2pkhScriptPubKey, 100000).AddOutput(p2shScriptPubKey, 95000).SetLockTime(700000).Sign(0, privateKey, btcutils.SigHashAll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := builder.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Serialize(); err != nil {
+		t.Fatal(err)
+	}
+	if err := btcutils.VerifyInputScript(tx, 0, p2pkhScriptPubKey, 100000); err != nil {
+		t.Error(err)
+	}
+	if tx.LockTime != 700000 || tx.Inputs[0].Sequence != btcutils.SequenceMaxNonFinal {
+		t.Errorf("Transaction has lock time %d and sequence 0x%08x, expected 700000 and 0x%08x.", tx.LockTime, tx.Inputs[0].Sequence, btcutils.SequenceMaxNonFinal)
+	}
+
+	//P2PKH outputs of the uncompressed public key are signed with the uncompressed key in the scriptSig
+	uncompressedPublicKey, _ := btcutils.NewPublicKey(privateKey)
+	uncompressedPublicKeyHash, _ := btcutils.Hash160(uncompressedPublicKey)
+	uncompressedScriptPubKey, _ := btcutils.NewP2PKHScriptPubKey(uncompressedPublicKeyHash)
+	builder, err = NewBuilder().AddInput(txid, 1, uncompressedScriptPubKey, 100000).AddOutput(p2shScriptPubKey, 95000).Sign(0, privateKey, btcutils.SigHashAll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err = builder.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := btcutils.VerifyInputScript(tx, 0, uncompressedScriptPubKey, 100000); err != nil {
+		t.Error(err)
+	}
+	if !bytes.Contains(tx.Inputs[0].ScriptSig, uncompressedPublicKey) {
+		t.Errorf("scriptSig %x does not push the uncompressed public key %x.", tx.Inputs[0].ScriptSig, uncompressedPublicKey)
+	}
+}
+
+func TestBuilderSegWitInputs(t *testing.T) {
+	privateKey, _ := hex.DecodeString("619c335025c7f4012e556c2a58b2506e30b8511b53ade95ea316fd8c3286feb9")
+	publicKey, _ := btcutils.NewCompressedPublicKey(privateKey)
+	p2wpkhScriptPubKey, _ := btcutils.NewP2WPKHScriptPubKey(btcutils.PublicKeyToHash160(publicKey))
+	outputKey, _ := btcutils.TweakPublicKey(publicKey[1:], nil)
+	p2trScriptPubKey, _ := btcutils.CreateP2TRScriptPubKey(outputKey)
+	destination, _ := hex.DecodeString("00201863143c14c5166804bd19203356da136c985678cd4d27a1b8c6329604903262")
+	txid := "3ad337270ac0ba14fbce812291b7d95338c878709ea8123a4d88c3c29efbc6ac"
+
+	builder := NewBuilder().AddInput(txid, 0, p2wpkhScriptPubKey, 50000).AddInput(txid, 1, p2trScriptPubKey, 60000).AddOutput(destination, 100000)
+	if _, err := builder.Sign(0, privateKey, btcutils.SigHashDefault); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := builder.Build(); err == nil {
+		t.Error("Build accepting unsigned input 1.")
+	}
+	if _, err := builder.Sign(1, privateKey, btcutils.SigHashDefault); err != nil {
+		t.Fatal(err)
+	}
+	tx, err := builder.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := btcutils.VerifyInputScript(tx, 0, p2wpkhScriptPubKey, 50000); err != nil {
+		t.Error(err)
+	}
+	//SIGHASH_DEFAULT signs ECDSA inputs with SIGHASH_ALL, and leaves the hash type byte out of Schnorr signatures
+	if signature := tx.Inputs[0].Witness[0]; signature[len(signature)-1] != byte(btcutils.SigHashAll) {
+		t.Errorf("P2WPKH signature has hash type 0x%02x, expected SIGHASH_ALL.", signature[len(signature)-1])
+	}
+	prevOuts := []btcutils.Output{{Satoshis: 50000, ScriptPubKey: p2wpkhScriptPubKey}, {Satoshis: 60000, ScriptPubKey: p2trScriptPubKey}}
+	sigHash, _ := btcutils.CalcTaprootSigHash(tx, 1, prevOuts, btcutils.SigHashDefault)
+	if len(tx.Inputs[1].Witness) != 1 || len(tx.Inputs[1].Witness[0]) != 64 {
+		t.Fatalf("P2TR witness is %x, expected a 64 byte Schnorr signature.", tx.Inputs[1].Witness)
+	}
+	if err := btcutils.SchnorrVerify(outputKey, sigHash, tx.Inputs[1].Witness[0]); err != nil {
+		t.Error(err)
+	}
+
+	//Adding an output after signing drops the signatures
+	builder.AddOutput(destination, 1000)
+	if _, err := builder.Build(); err == nil {
+		t.Error("Build accepting inputs signed before an output was added.")
+	}
+}
+
+func TestBuilderInvalid(t *testing.T) {
+	privateKey, _ := hex.DecodeString("619c335025c7f4012e556c2a58b2506e30b8511b53ade95ea316fd8c3286feb9")
+	otherPrivateKey, _ := hex.DecodeString("0000000000000000000000000000000000000000000000000000000000000001")
+	publicKey, _ := btcutils.NewCompressedPublicKey(privateKey)
+	p2wpkhScriptPubKey, _ := btcutils.NewP2WPKHScriptPubKey(btcutils.PublicKeyToHash160(publicKey))
+	p2shScriptPubKey, _ := btcutils.NewP2SHScriptPubKey(btcutils.PublicKeyToHash160(publicKey))
+	txid := "3ad337270ac0ba14fbce812291b7d95338c878709ea8123a4d88c3c29efbc6ac"
+
+	signCases := map[string]*Builder{
+		"invalid transaction ID": NewBuilder().AddInput("3ad3", 0, p2wpkhScriptPubKey, 50000),
+		"zero input amount":      NewBuilder().AddInput(txid, 0, p2wpkhScriptPubKey, 0),
+		"negative output amount": NewBuilder().AddInput(txid, 0, p2wpkhScriptPubKey, 50000).AddOutput(p2wpkhScriptPubKey, -1),
+		"invalid lock time":      NewBuilder().AddInput(txid, 0, p2wpkhScriptPubKey, 50000).SetLockTime(400000000),
+		"P2SH input":             NewBuilder().AddInput(txid, 0, p2shScriptPubKey, 50000),
+	}
+	for name, builder := range signCases {
+		if _, err := builder.AddOutput(p2wpkhScriptPubKey, 40000).Sign(0, privateKey, btcutils.SigHashAll); err == nil {
+			t.Errorf("Builder accepting %s.", name)
+		}
+	}
+	builder := NewBuilder().AddInput(txid, 0, p2wpkhScriptPubKey, 50000).AddOutput(p2wpkhScriptPubKey, 40000)
+	if _, err := builder.Sign(0, otherPrivateKey, btcutils.SigHashAll); err == nil {
+		t.Error("Sign accepting private key of another public key.")
+	}
+	if _, err := builder.Sign(1, privateKey, btcutils.SigHashAll); err == nil {
+		t.Error("Sign accepting out of range input index.")
+	}
+	builder = NewBuilder().AddInput(txid, 0, p2wpkhScriptPubKey, 50000).AddOutput(p2wpkhScriptPubKey, 60000)
+	if _, err := builder.Sign(0, privateKey, btcutils.SigHashAll); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := builder.Build(); err == nil {
+		t.Error("Build accepting outputs spending more than the inputs hold.")
+	}
+}
+
+func TestBuilderHighFee(t *testing.T) {
+	privateKey, _ := hex.DecodeString("619c335025c7f4012e556c2a58b2506e30b8511b53ade95ea316fd8c3286feb9")
+	publicKey, _ := btcutils.NewCompressedPublicKey(privateKey)
+	p2wpkhScriptPubKey, _ := btcutils.NewP2WPKHScriptPubKey(btcutils.PublicKeyToHash160(publicKey))
+	txid := "3ad337270ac0ba14fbce812291b7d95338c878709ea8123a4d88c3c29efbc6ac"
+
+	//A fee of 20000 satoshis is above 10% of the 30000 satoshis sent
+	builder, err := NewBuilder().AddInput(txid, 0, p2wpkhScriptPubKey, 50000).AddOutput(p2wpkhScriptPubKey, 30000).Sign(0, privateKey, btcutils.SigHashAll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := builder.Build(); err == nil {
+		t.Error("Build accepting a fee above 10% of the amount sent.")
+	} else if highFee, ok := err.(*btcutils.HighFeeError); !ok || highFee.Fee != 20000 || !highFee.Relative {
+		t.Errorf("Build returned %v, expected a relative HighFeeError for a fee of 20000 satoshis.", err)
+	}
+	if _, err := builder.ForceHighFee().Build(); err != nil {
+		t.Error(err)
+	}
+
+	//A fee of 2 BTC is a fraction of 100 BTC sent, but above the default maximum fee
+	builder, err = NewBuilder().AddInput(txid, 0, p2wpkhScriptPubKey, 10200000000).AddOutput(p2wpkhScriptPubKey, 10000000000).Sign(0, privateKey, btcutils.SigHashAll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := builder.Build(); err == nil {
+		t.Error("Build accepting a fee above the default maximum fee.")
+	}
+	if _, err := builder.SetMaxFee(300000000).Build(); err != nil {
+		t.Error(err)
+	}
+	if _, err := NewBuilder().SetMaxFee(-1).AddInput(txid, 0, p2wpkhScriptPubKey, 50000).Build(); err == nil {
+		t.Error("SetMaxFee accepting a negative maximum fee.")
+	}
+}
@@ -0,0 +1,104 @@
+package txbuilder
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/btcutils"
+	"github.com/CryptoProcessing/go-bitcoin-multisig/txscript"
+	"github.com/CryptoProcessing/go-bitcoin-multisig/utxo"
+
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// IncrementalRelayFeeRate is the incremental relay fee rate in satoshis per virtual byte of Bitcoin Core, which a
+// replacement must pay for its own size on top of the fee of the transaction it replaces, as per BIP125 rule 4.
+const IncrementalRelayFeeRate = 1
+
+// keyMap is a KeyStore of private keys by the hex of the scriptPubKey they spend.
+type keyMap map[string][]byte
+
+func (keys keyMap) PrivateKey(scriptPubKey []byte) ([]byte, error) {
+	privateKey, ok := keys[hex.EncodeToString(scriptPubKey)]
+	if !ok {
+		return nil, errors.New("No private key for the scriptPubKey.")
+	}
+	return privateKey, nil
+}
+
+// BumpFee builds a BIP125 replacement of original, spending the same inputs to the same outputs at
+// newFeeRateVByte satoshis per virtual byte, and signs it with signingKeys, private keys by the hex of the
+// scriptPubKey they spend. The fee of original can't be told from the transaction alone, so prevOuts holds the
+// output spent by each input of original, in the same order.
+// The extra fee comes out of the change output, the last output paying to a scriptPubKey of signingKeys. The
+// replacement must pay at least the fee of original plus IncrementalRelayFeeRate for its own virtual size, as
+// BIP125 rules 3 and 4 require, or nodes will not relay it.
+// Inputs keep their sequence numbers, so the replacement signals replaceability too, and are signed with
+// SIGHASH_ALL as by Builder.Sign. Only P2PKH and P2WPKH outputs can be spent.
+// Returns an error if original does not signal replaceability, has no change output, the new fee rate does not pay
+// enough, leaves change below the dust threshold or is refused by Builder.Build as too high, or an input can't be
+// signed.
+func BumpFee(original *btcutils.Transaction, prevOuts []btcutils.Output, newFeeRateVByte int64, signingKeys map[string][]byte) (*btcutils.Transaction, error) {
+	if !btcutils.IsRBFSignaled(original) {
+		return nil, fmt.Errorf("Transaction does not signal replaceability. Only transactions with an input sequence of at most 0x%08x can be replaced.", btcutils.SequenceRBF)
+	}
+	if len(prevOuts) != len(original.Inputs) {
+		return nil, fmt.Errorf("Transaction has %d inputs, but %d spent outputs were given.", len(original.Inputs), len(prevOuts))
+	}
+	if err := btcutils.CheckFeeRate(int(newFeeRateVByte), false); err != nil {
+		return nil, err
+	}
+	var inputTotal uint64
+	for _, prevOut := range prevOuts {
+		inputTotal += prevOut.Satoshis
+	}
+	originalFee, err := btcutils.CheckFee(inputTotal, original.Outputs)
+	if err != nil {
+		return nil, err
+	}
+	change := -1
+	for i, output := range original.Outputs {
+		if _, ok := signingKeys[hex.EncodeToString(output.ScriptPubKey)]; ok {
+			change = i
+		}
+	}
+	if change < 0 {
+		return nil, errors.New("Transaction has no change output to take the higher fee from.")
+	}
+
+	//Unsigned inputs hold the scriptPubKey they spend for btcutils.EstimateFee
+	tx := &btcutils.Transaction{
+		Version:  original.Version,
+		Outputs:  append([]btcutils.Output(nil), original.Outputs...),
+		LockTime: original.LockTime,
+	}
+	builder := NewBuilder().SetVersion(original.Version).SetLockTime(original.LockTime)
+	spent := make([]utxo.UTXO, len(prevOuts))
+	for i, input := range original.Inputs {
+		class := txscript.Classify(prevOuts[i].ScriptPubKey)
+		if class != txscript.PubKeyHash && class != txscript.WitnessPubKeyHash {
+			return nil, fmt.Errorf("Input %d spends a %s script. Only P2PKH and P2WPKH outputs can be spent.", i, class)
+		}
+		tx.Inputs = append(tx.Inputs, btcutils.Input{TxHash: input.TxHash, OutputIndex: input.OutputIndex, ScriptSig: prevOuts[i].ScriptPubKey, Sequence: input.Sequence})
+		builder.AddInput(input.TxHash, input.OutputIndex, prevOuts[i].ScriptPubKey, int64(prevOuts[i].Satoshis)).SetSequence(i, input.Sequence)
+		spent[i] = utxo.UTXO{TxID: input.TxHash, Vout: input.OutputIndex, ScriptPubKey: prevOuts[i].ScriptPubKey, Amount: int64(prevOuts[i].Satoshis)}
+	}
+	virtualSize, err := btcutils.EstimateFee(tx, 1)
+	if err != nil {
+		return nil, err
+	}
+	fee := newFeeRateVByte * virtualSize
+	minFee := int64(originalFee) + IncrementalRelayFeeRate*virtualSize
+	if fee < minFee {
+		return nil, fmt.Errorf("New fee of %d satoshis at %d sat/vB is below the %d satoshis a replacement must pay, the fee of %d satoshis of the original transaction plus %d sat/vB for the %d vbytes of the replacement.", fee, newFeeRateVByte, minFee, originalFee, IncrementalRelayFeeRate, virtualSize)
+	}
+	changeAmount := int64(tx.Outputs[change].Satoshis) - (fee - int64(originalFee))
+	dustThreshold := int64(btcutils.DustThreshold(tx.Outputs[change].ScriptPubKey, btcutils.DefaultDustRelayFeeRate))
+	if changeAmount < dustThreshold {
+		return nil, fmt.Errorf("Change output %d of %d satoshis cannot pay the %d satoshis more fee, leaving less than the dust threshold of %d satoshis.", change, tx.Outputs[change].Satoshis, fee-int64(originalFee), dustThreshold)
+	}
+	tx.Outputs[change].Satoshis = uint64(changeAmount)
+	for _, output := range tx.Outputs {
+		builder.AddOutput(output.ScriptPubKey, int64(output.Satoshis))
+	}
+	return signInputs(builder, spent, keyMap(signingKeys))
+}
@@ -0,0 +1,141 @@
+package txbuilder
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/btcutils"
+	"github.com/CryptoProcessing/go-bitcoin-multisig/utxo"
+
+	"encoding/hex"
+	"testing"
+)
+
+// testRBFTransaction returns a transaction signalling replaceability, paying 100000 satoshis to destination and
+// 49000 satoshis of change to changeScript from the spent outputs, with the keys it is signed with.
+func testRBFTransaction(t *testing.T, destination []byte, changeScript []byte) (*btcutils.Transaction, []btcutils.Output, map[string][]byte) {
+	privateKey, _ := hex.DecodeString("619c335025c7f4012e556c2a58b2506e30b8511b53ade95ea316fd8c3286feb9")
+	publicKey, _ := btcutils.NewCompressedPublicKey(privateKey)
+	publicKeyHash := btcutils.PublicKeyToHash160(publicKey)
+	p2pkhScriptPubKey, _ := btcutils.NewP2PKHScriptPubKey(publicKeyHash)
+	p2wpkhScriptPubKey, _ := btcutils.NewP2WPKHScriptPubKey(publicKeyHash)
+	keys := map[string][]byte{hex.EncodeToString(p2pkhScriptPubKey): privateKey, hex.EncodeToString(p2wpkhScriptPubKey): privateKey}
+
+	spent := []utxo.UTXO{testUTXO(1, p2pkhScriptPubKey, 60000), testUTXO(2, p2wpkhScriptPubKey, 90000)}
+	builder := NewBuilder()
+	var prevOuts []btcutils.Output
+	for i, u := range spent {
+		builder.AddInput(u.TxID, u.Vout, u.ScriptPubKey, u.Amount).SetSequence(i, btcutils.SequenceRBF)
+		prevOuts = append(prevOuts, btcutils.Output{Satoshis: uint64(u.Amount), ScriptPubKey: u.ScriptPubKey})
+	}
+	builder.AddOutput(destination, 100000).AddOutput(changeScript, 49000)
+	tx, err := signInputs(builder, spent, keyMap(keys))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tx, prevOuts, keys
+}
+
+// checkBIP125 checks that replacement can replace original under the five replacement rules of BIP125.
+func checkBIP125(t *testing.T, original *btcutils.Transaction, replacement *btcutils.Transaction, prevOuts []btcutils.Output) {
+	//Rule 1: the original signals replaceability
+	if !btcutils.IsRBFSignaled(original) {
+		t.Error("Rule 1: original transaction does not signal replaceability.")
+	}
+	//Rule 2: the replacement spends no unconfirmed outputs the original didn't, so every input is one of the original
+	spentByOriginal := map[string]bool{}
+	for _, input := range original.Inputs {
+		spentByOriginal[utxo.UTXO{TxID: input.TxHash, Vout: input.OutputIndex}.Outpoint()] = true
+	}
+	for i, input := range replacement.Inputs {
+		if outpoint := (utxo.UTXO{TxID: input.TxHash, Vout: input.OutputIndex}).Outpoint(); !spentByOriginal[outpoint] {
+			t.Errorf("Rule 2: replacement input %d spends %s, which the original does not.", i, outpoint)
+		}
+	}
+	//Rule 3: the replacement pays at least the fee of the original
+	var inputTotal uint64
+	for _, prevOut := range prevOuts {
+		inputTotal += prevOut.Satoshis
+	}
+	originalFee, _ := btcutils.CheckFee(inputTotal, original.Outputs)
+	replacementFee, err := btcutils.CheckFee(inputTotal, replacement.Outputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replacementFee < originalFee {
+		t.Errorf("Rule 3: replacement fee of %d satoshis is below the original fee of %d satoshis.", replacementFee, originalFee)
+	}
+	//Rule 4: the replacement pays for its own size at the incremental relay fee rate on top of the original fee
+	virtualSize, _ := btcutils.VirtualSize(replacement)
+	if replacementFee-originalFee < uint64(IncrementalRelayFeeRate*virtualSize) {
+		t.Errorf("Rule 4: replacement pays %d satoshis more than the original, less than %d sat/vB for its %d vbytes.", replacementFee-originalFee, IncrementalRelayFeeRate, virtualSize)
+	}
+	//Rule 5: at most 100 transactions are replaced, here the original alone, as the inputs are the same
+	if len(replacement.Inputs) != len(original.Inputs) {
+		t.Errorf("Rule 5: replacement has %d inputs, the original %d.", len(replacement.Inputs), len(original.Inputs))
+	}
+}
+
+func TestBumpFee(t *testing.T) {
+	destination, _ := hex.DecodeString("00201863143c14c5166804bd19203356da136c985678cd4d27a1b8c6329604903262")
+	changeScript, _ := hex.DecodeString("0014751e76e8199196d454941c45d1b3a323f1433bd6")
+	original, prevOuts, keys := testRBFTransaction(t, destination, changeScript)
+	changePrivateKey, _ := hex.DecodeString("0000000000000000000000000000000000000000000000000000000000000001")
+	keys[hex.EncodeToString(changeScript)] = changePrivateKey
+
+	replacement, err := BumpFee(original, prevOuts, 20, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkBIP125(t, original, replacement, prevOuts)
+	for i, prevOut := range prevOuts {
+		if err := btcutils.VerifyInputScript(replacement, i, prevOut.ScriptPubKey, int64(prevOut.Satoshis)); err != nil {
+			t.Errorf("Replacement input %d does not verify. %s", i, err)
+		}
+	}
+	if !btcutils.IsRBFSignaled(replacement) {
+		t.Error("Replacement does not signal replaceability.")
+	}
+	if replacement.Outputs[0].Satoshis != 100000 || replacement.Outputs[1].Satoshis >= 49000 {
+		t.Errorf("Replacement pays %d and %d satoshis, expected 100000 and less change than 49000.", replacement.Outputs[0].Satoshis, replacement.Outputs[1].Satoshis)
+	}
+	virtualSize, _ := btcutils.VirtualSize(replacement)
+	if fee := 150000 - replacement.Outputs[0].Satoshis - replacement.Outputs[1].Satoshis; fee < uint64(20*virtualSize) {
+		t.Errorf("Replacement fee of %d satoshis is below 20 sat/vB for %d vbytes.", fee, virtualSize)
+	}
+}
+
+func TestBumpFeeInvalid(t *testing.T) {
+	destination, _ := hex.DecodeString("00201863143c14c5166804bd19203356da136c985678cd4d27a1b8c6329604903262")
+	changeScript, _ := hex.DecodeString("0014751e76e8199196d454941c45d1b3a323f1433bd6")
+	original, prevOuts, keys := testRBFTransaction(t, destination, changeScript)
+
+	//No key for the change script, so the transaction has no change output
+	if _, err := BumpFee(original, prevOuts, 20, keys); err == nil {
+		t.Error("BumpFee accepting transaction without change output.")
+	}
+	changePrivateKey, _ := hex.DecodeString("0000000000000000000000000000000000000000000000000000000000000001")
+	keys[hex.EncodeToString(changeScript)] = changePrivateKey
+	//The original pays 1000 satoshis, about 4 sat/vB
+	for _, feeRate := range []int64{1, 4} {
+		if _, err := BumpFee(original, prevOuts, feeRate, keys); err == nil {
+			t.Errorf("BumpFee accepting fee rate of %d sat/vB, too low to replace the original.", feeRate)
+		}
+	}
+	//Raising the fee to 1000 sat/vB would take more than the change
+	if _, err := BumpFee(original, prevOuts, 1000, keys); err == nil {
+		t.Error("BumpFee accepting fee higher than the change.")
+	}
+	//Raising the fee to 100 sat/vB leaves change, but is above 10% of the amount sent
+	if _, err := BumpFee(original, prevOuts, 100, keys); err == nil {
+		t.Error("BumpFee accepting fee above 10% of the amount sent.")
+	}
+	if _, err := BumpFee(original, prevOuts[:1], 20, keys); err == nil {
+		t.Error("BumpFee accepting fewer spent outputs than inputs.")
+	}
+	final := *original
+	final.Inputs = append([]btcutils.Input(nil), original.Inputs...)
+	for i := range final.Inputs {
+		final.Inputs[i].Sequence = btcutils.SequenceMaxNonFinal
+	}
+	if _, err := BumpFee(&final, prevOuts, 20, keys); err == nil {
+		t.Error("BumpFee accepting transaction not signalling replaceability.")
+	}
+}
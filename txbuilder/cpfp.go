@@ -0,0 +1,75 @@
+package txbuilder
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/btcutils"
+
+	"fmt"
+)
+
+// BuildCPFP builds a Child-Pays-For-Parent transaction, spending output parentOutputIndex of parentTx to
+// childOutputScript with a fee high enough for miners to mine both. Miners weigh a transaction together with its
+// unconfirmed ancestors, so the child pays what the parent lacks for the package fee rate
+// (parentFee + childFee) / (parentVSize + childVSize) to reach targetFeeRateVByte satoshis per virtual byte.
+// The parent pays parentFeeRateVByte per virtual byte of its own, which must be below the target.
+// The size of the child, and so the fee it needs, depends on its signature, so the child is signed with the fee
+// the previous round was short, until the package reaches the target, at most MaxIterations times.
+// The parent output is spent with signingKey as by Builder.Sign, so it must be a P2PKH, P2WPKH or P2TR output,
+// signed with SIGHASH_ALL, or SIGHASH_DEFAULT for P2TR.
+// Returns an error if the output doesn't exist or can't be spent with signingKey, or would leave the child with an
+// output below the dust threshold of childOutputScript once the fee is paid.
+func BuildCPFP(parentTx *btcutils.Transaction, parentOutputIndex int, parentFeeRateVByte int64, targetFeeRateVByte int64, childOutputScript []byte, signingKey []byte) (*btcutils.Transaction, error) {
+	if parentOutputIndex < 0 || parentOutputIndex >= len(parentTx.Outputs) {
+		return nil, fmt.Errorf("Output index %d out of range for parent transaction with %d outputs.", parentOutputIndex, len(parentTx.Outputs))
+	}
+	if err := btcutils.CheckFeeRate(int(targetFeeRateVByte), false); err != nil {
+		return nil, err
+	}
+	if parentFeeRateVByte < 0 {
+		return nil, fmt.Errorf("Fee rate cannot be negative. Provided parent fee rate is %d sat/vB.", parentFeeRateVByte)
+	}
+	if parentFeeRateVByte >= targetFeeRateVByte {
+		return nil, fmt.Errorf("Parent transaction already pays %d sat/vB, at least the target fee rate of %d sat/vB.", parentFeeRateVByte, targetFeeRateVByte)
+	}
+	rawParent, err := parentTx.Serialize()
+	if err != nil {
+		return nil, err
+	}
+	parentTxID, err := btcutils.TxID(rawParent)
+	if err != nil {
+		return nil, err
+	}
+	parentVSize, err := btcutils.VirtualSize(parentTx)
+	if err != nil {
+		return nil, err
+	}
+	parentFee := parentFeeRateVByte * int64(parentVSize)
+	parentOutput := parentTx.Outputs[parentOutputIndex]
+	value := int64(parentOutput.Satoshis)
+	dustThreshold := int64(btcutils.DustThreshold(childOutputScript, btcutils.DefaultDustRelayFeeRate))
+
+	var childFee int64
+	for i := 0; i < MaxIterations; i++ {
+		if value-childFee < dustThreshold {
+			return nil, fmt.Errorf("Parent output of %d satoshis cannot pay a child fee of %d satoshis, leaving less than the dust threshold of %d satoshis.", value, childFee, dustThreshold)
+		}
+		//The fee comes from the target fee rate, which CheckFeeRate has already bounded
+		builder := NewBuilder().AddInput(parentTxID, uint32(parentOutputIndex), parentOutput.ScriptPubKey, value).AddOutput(childOutputScript, value-childFee).ForceHighFee()
+		if _, err := builder.Sign(0, signingKey, btcutils.SigHashDefault); err != nil {
+			return nil, err
+		}
+		child, err := builder.Build()
+		if err != nil {
+			return nil, err
+		}
+		childVSize, err := btcutils.VirtualSize(child)
+		if err != nil {
+			return nil, err
+		}
+		packageFee := targetFeeRateVByte * int64(parentVSize+childVSize)
+		if parentFee+childFee >= packageFee {
+			return child, nil
+		}
+		childFee = packageFee - parentFee
+	}
+	return nil, fmt.Errorf("Child fee did not settle within %d iterations.", MaxIterations)
+}
@@ -0,0 +1,89 @@
+package txbuilder
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/btcutils"
+
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestBuildCPFP(t *testing.T) {
+	privateKey, _ := hex.DecodeString("619c335025c7f4012e556c2a58b2506e30b8511b53ade95ea316fd8c3286feb9")
+	publicKey, _ := btcutils.NewCompressedPublicKey(privateKey)
+	publicKeyHash := btcutils.PublicKeyToHash160(publicKey)
+	p2pkhScriptPubKey, _ := btcutils.NewP2PKHScriptPubKey(publicKeyHash)
+	p2wpkhScriptPubKey, _ := btcutils.NewP2WPKHScriptPubKey(publicKeyHash)
+	outputKey, _ := btcutils.TweakPublicKey(publicKey[1:], nil)
+	p2trScriptPubKey, _ := btcutils.CreateP2TRScriptPubKey(outputKey)
+	destination, _ := hex.DecodeString("00201863143c14c5166804bd19203356da136c985678cd4d27a1b8c6329604903262")
+	txid := "3ad337270ac0ba14fbce812291b7d95338c878709ea8123a4d88c3c29efbc6ac"
+
+	//A parent paying 1 sat/vB, stuck below a target of 25 sat/vB
+	parentFeeRate, targetFeeRate := int64(1), int64(25)
+	for _, scriptPubKey := range [][]byte{p2pkhScriptPubKey, p2wpkhScriptPubKey, p2trScriptPubKey} {
+		parentBuilder := NewBuilder().AddInput(txid, 0, p2wpkhScriptPubKey, 100300).AddOutput(scriptPubKey, 100000)
+		if _, err := parentBuilder.Sign(0, privateKey, btcutils.SigHashAll); err != nil {
+			t.Fatal(err)
+		}
+		parent, err := parentBuilder.Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		child, err := BuildCPFP(parent, 0, parentFeeRate, targetFeeRate, destination, privateKey)
+		if err != nil {
+			t.Fatalf("Building child of %x output failed. %s", scriptPubKey, err)
+		}
+		//The script interpreter doesn't run Taproot, whose signatures TestBuilderSegWitInputs checks
+		if !bytes.Equal(scriptPubKey, p2trScriptPubKey) {
+			if err := btcutils.VerifyInputScript(child, 0, scriptPubKey, 100000); err != nil {
+				t.Error(err)
+			}
+		}
+		rawParent, _ := parent.Serialize()
+		parentTxID, _ := btcutils.TxID(rawParent)
+		if child.Inputs[0].TxHash != parentTxID || child.Inputs[0].OutputIndex != 0 {
+			t.Errorf("Child spends %s:%d, expected %s:0.", child.Inputs[0].TxHash, child.Inputs[0].OutputIndex, parentTxID)
+		}
+
+		parentVSize, _ := btcutils.VirtualSize(parent)
+		childVSize, _ := btcutils.VirtualSize(child)
+		parentFee := parentFeeRate * int64(parentVSize)
+		childFee := 100000 - int64(child.Outputs[0].Satoshis)
+		packageFeeRate := float64(parentFee+childFee) / float64(parentVSize+childVSize)
+		if packageFeeRate < float64(targetFeeRate) || packageFeeRate >= float64(targetFeeRate+1) {
+			t.Errorf("Package of child spending %x output pays %.2f sat/vB, expected %d to %d sat/vB.", scriptPubKey, packageFeeRate, targetFeeRate, targetFeeRate+1)
+		}
+	}
+}
+
+func TestBuildCPFPInvalid(t *testing.T) {
+	privateKey, _ := hex.DecodeString("619c335025c7f4012e556c2a58b2506e30b8511b53ade95ea316fd8c3286feb9")
+	publicKey, _ := btcutils.NewCompressedPublicKey(privateKey)
+	p2wpkhScriptPubKey, _ := btcutils.NewP2WPKHScriptPubKey(btcutils.PublicKeyToHash160(publicKey))
+	p2shScriptPubKey, _ := btcutils.NewP2SHScriptPubKey(btcutils.PublicKeyToHash160(publicKey))
+	txid := "3ad337270ac0ba14fbce812291b7d95338c878709ea8123a4d88c3c29efbc6ac"
+	parent := &btcutils.Transaction{
+		Version: 2,
+		Inputs:  []btcutils.Input{{TxHash: txid, Sequence: btcutils.SequenceFinal}},
+		Outputs: []btcutils.Output{{Satoshis: 100000, ScriptPubKey: p2wpkhScriptPubKey}, {Satoshis: 100000, ScriptPubKey: p2shScriptPubKey}, {Satoshis: 3000, ScriptPubKey: p2wpkhScriptPubKey}},
+	}
+
+	testCases := []struct {
+		name          string
+		outputIndex   int
+		parentFeeRate int64
+		targetFeeRate int64
+	}{
+		{"out of range output index", 3, 1, 25},
+		{"parent at the target fee rate", 0, 25, 25},
+		{"P2SH parent output", 1, 1, 25},
+		{"parent output too small for the child fee", 2, 1, 25},
+		{"target fee rate above the maximum", 0, 1, btcutils.MaxFeeRate + 1},
+	}
+	for _, testCase := range testCases {
+		if _, err := BuildCPFP(parent, testCase.outputIndex, testCase.parentFeeRate, testCase.targetFeeRate, p2wpkhScriptPubKey, privateKey); err == nil {
+			t.Errorf("BuildCPFP accepting %s.", testCase.name)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+// Package txbuilder builds signed transactions paying a set of outputs, selecting the UTXOs to spend and the change
+// to give back so the fee matches the fee rate once the inputs are signed, or from the inputs and outputs given to a
+// Builder. BuildCPFP builds a child transaction paying for a parent stuck at a low fee rate, and BumpFee a replacement
+// of a transaction at a higher fee rate.
+package txbuilder
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/btcutils"
+	"github.com/CryptoProcessing/go-bitcoin-multisig/txscript"
+	"github.com/CryptoProcessing/go-bitcoin-multisig/utxo"
+
+	"errors"
+	"fmt"
+)
+
+// MaxIterations is the number of times BuildWithAutoInputs selects inputs for the fee of the previous selection
+// before giving up. Selections settle in two or three rounds, as each extra input raises the fee by less than the
+// input brings in.
+const MaxIterations = 10
+
+// KeyStore looks up the private key to sign an input with, from the scriptPubKey of the output it spends.
+type KeyStore interface {
+	PrivateKey(scriptPubKey []byte) ([]byte, error)
+}
+
+// BuildWithAutoInputs builds a transaction paying outputs from utxos, with a fee of feeRateVByte satoshis per
+// virtual byte of the signed transaction, and signs it with the keys of keys.
+// The fee depends on the number of inputs, which depends on the fee, so UTXOs are selected largest first to cover
+// the outputs plus the fee estimated for the previous selection, until a selection covers its own fee, at most
+// MaxIterations times. The remainder is paid to changeScript, unless it is below the dust threshold of
+// changeScript at btcutils.DefaultDustRelayFeeRate, in which case it goes to the fee instead. Inputs have sequence
+// btcutils.SequenceFinal and are signed with SIGHASH_ALL as by Builder.Sign.
+// Only P2PKH and P2WPKH outputs can be spent, with the fee estimated for compressed public keys.
+// Returns an error if a UTXO can't be spent, keys has no key for a selected UTXO, utxos can't pay for the outputs
+// and fee, or the fee is refused by Builder.Build as above btcutils.DefaultMaxFee or btcutils.MaxFeePercent of the
+// amount sent.
+func BuildWithAutoInputs(utxos []utxo.UTXO, outputs []btcutils.Output, feeRateVByte int64, changeScript []byte, keys KeyStore) (*btcutils.Transaction, error) {
+	if len(outputs) == 0 {
+		return nil, errors.New("Transaction must have at least one output.")
+	}
+	outputTotal, err := btcutils.CheckOutputs(outputs)
+	if err != nil {
+		return nil, err
+	}
+	if err := btcutils.CheckFeeRate(int(feeRateVByte), false); err != nil {
+		return nil, err
+	}
+	if len(changeScript) == 0 {
+		return nil, errors.New("Change scriptPubKey is required.")
+	}
+	set := utxo.NewUTXOSet()
+	for _, u := range utxos {
+		class := txscript.Classify(u.ScriptPubKey)
+		if class != txscript.PubKeyHash && class != txscript.WitnessPubKeyHash {
+			return nil, fmt.Errorf("UTXO %s pays to a %s script. Only P2PKH and P2WPKH outputs can be spent.", u.Outpoint(), class)
+		}
+		set.Add(u)
+	}
+
+	var fee int64
+	for i := 0; i < MaxIterations; i++ {
+		selected, _, err := set.Select(int64(outputTotal)+fee, utxo.LargestFirst)
+		if err != nil {
+			return nil, err
+		}
+		var inputTotal int64
+		tx := &btcutils.Transaction{Version: 2, Outputs: outputs}
+		for _, u := range selected {
+			inputTotal += u.Amount
+			//Unsigned inputs hold the scriptPubKey they spend for btcutils.EstimateFee
+			tx.Inputs = append(tx.Inputs, btcutils.Input{TxHash: u.TxID, OutputIndex: u.Vout, ScriptSig: u.ScriptPubKey, Sequence: btcutils.SequenceFinal})
+		}
+		feeWithoutChange, err := btcutils.EstimateFee(tx, feeRateVByte)
+		if err != nil {
+			return nil, err
+		}
+		if inputTotal < int64(outputTotal)+feeWithoutChange {
+			//Reselect for the fee of this selection, which may need more inputs
+			fee = feeWithoutChange
+			continue
+		}
+		withChange := *tx
+		withChange.Outputs = append(append([]btcutils.Output(nil), outputs...), btcutils.Output{ScriptPubKey: changeScript})
+		feeWithChange, err := btcutils.EstimateFee(&withChange, feeRateVByte)
+		if err != nil {
+			return nil, err
+		}
+		change := inputTotal - int64(outputTotal) - feeWithChange
+		if change >= int64(btcutils.DustThreshold(changeScript, btcutils.DefaultDustRelayFeeRate)) {
+			withChange.Outputs[len(outputs)].Satoshis = uint64(change)
+			tx = &withChange
+		}
+		builder := NewBuilder()
+		for _, u := range selected {
+			builder.AddInput(u.TxID, u.Vout, u.ScriptPubKey, u.Amount)
+		}
+		for _, output := range tx.Outputs {
+			builder.AddOutput(output.ScriptPubKey, int64(output.Satoshis))
+		}
+		return signInputs(builder, selected, keys)
+	}
+	return nil, fmt.Errorf("Input selection did not settle on a fee within %d iterations.", MaxIterations)
+}
+
+// signInputs signs each input of builder with SIGHASH_ALL, input i spending spent[i], with the key keys holds for
+// it, and returns the transaction built.
+func signInputs(builder *Builder, spent []utxo.UTXO, keys KeyStore) (*btcutils.Transaction, error) {
+	for i, u := range spent {
+		privateKey, err := keys.PrivateKey(u.ScriptPubKey)
+		if err != nil {
+			return nil, fmt.Errorf("No private key for input %d spending %s. %s", i, u.Outpoint(), err)
+		}
+		if _, err := builder.Sign(i, privateKey, btcutils.SigHashAll); err != nil {
+			return nil, err
+		}
+	}
+	return builder.Build()
+}
@@ -0,0 +1,107 @@
+package txbuilder
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/btcutils"
+	"github.com/CryptoProcessing/go-bitcoin-multisig/utxo"
+
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// testKeyStore holds private keys by the hex of the scriptPubKey they spend.
+type testKeyStore map[string][]byte
+
+func (keys testKeyStore) PrivateKey(scriptPubKey []byte) ([]byte, error) {
+	privateKey, ok := keys[hex.EncodeToString(scriptPubKey)]
+	if !ok {
+		return nil, errors.New("Unknown scriptPubKey.")
+	}
+	return privateKey, nil
+}
+
+func testUTXO(n int, scriptPubKey []byte, amount int64) utxo.UTXO {
+	return utxo.UTXO{TxID: fmt.Sprintf("%064x", n), Vout: uint32(n), ScriptPubKey: scriptPubKey, Amount: amount}
+}
+
+func TestBuildWithAutoInputs(t *testing.T) {
+	testPrivateKey, _ := hex.DecodeString("619c335025c7f4012e556c2a58b2506e30b8511b53ade95ea316fd8c3286feb9")
+	testPublicKey, _ := btcutils.NewCompressedPublicKey(testPrivateKey)
+	testPublicKeyHash := btcutils.PublicKeyToHash160(testPublicKey)
+	p2wpkhScriptPubKey, _ := btcutils.NewP2WPKHScriptPubKey(testPublicKeyHash)
+	p2pkhScriptPubKey, _ := btcutils.NewP2PKHScriptPubKey(testPublicKeyHash)
+	keys := testKeyStore{hex.EncodeToString(p2wpkhScriptPubKey): testPrivateKey, hex.EncodeToString(p2pkhScriptPubKey): testPrivateKey}
+	destination, _ := hex.DecodeString("00201863143c14c5166804bd19203356da136c985678cd4d27a1b8c6329604903262")
+	changeScript, _ := hex.DecodeString("0014751e76e8199196d454941c45d1b3a323f1433bd6")
+	outputs := []btcutils.Output{{Satoshis: 100000, ScriptPubKey: destination}}
+	feeRate := int64(10)
+
+	testCases := []struct {
+		name      string
+		utxos     []utxo.UTXO
+		inputs    int
+		hasChange bool
+	}{
+		//The 100500 satoshi UTXO covers the output, but not the fee of spending it, so the next round adds the other
+		{"fee needing another input", []utxo.UTXO{testUTXO(1, p2wpkhScriptPubKey, 100500), testUTXO(2, p2wpkhScriptPubKey, 50000)}, 2, true},
+		//A remainder of 150 satoshis once the fee is paid is dust, so it goes to the fee instead of a change output
+		{"dust change", []utxo.UTXO{testUTXO(1, p2wpkhScriptPubKey, 101380), testUTXO(2, p2wpkhScriptPubKey, 50000)}, 1, false},
+		{"P2PKH and P2WPKH inputs", []utxo.UTXO{testUTXO(1, p2pkhScriptPubKey, 60000), testUTXO(2, p2wpkhScriptPubKey, 50000), testUTXO(3, p2wpkhScriptPubKey, 1000)}, 2, true},
+	}
+	for _, testCase := range testCases {
+		tx, err := BuildWithAutoInputs(testCase.utxos, outputs, feeRate, changeScript, keys)
+		if err != nil {
+			t.Fatalf("Building transaction with %s failed. %s", testCase.name, err)
+		}
+		if len(tx.Inputs) != testCase.inputs || (len(tx.Outputs) == 2) != testCase.hasChange {
+			t.Errorf("Transaction with %s has %d inputs and %d outputs, expected %d inputs and change %v.", testCase.name, len(tx.Inputs), len(tx.Outputs), testCase.inputs, testCase.hasChange)
+		}
+		if tx.Outputs[0].Satoshis != 100000 {
+			t.Errorf("Transaction with %s pays %d satoshis, expected 100000.", testCase.name, tx.Outputs[0].Satoshis)
+		}
+		//The fee pays for the signed transaction at the fee rate, without overpaying unless change would be dust
+		var inputTotal, outputTotal int64
+		for i, input := range tx.Inputs {
+			spent := testCase.utxos[0]
+			for _, u := range testCase.utxos {
+				if u.TxID == input.TxHash {
+					spent = u
+				}
+			}
+			inputTotal += spent.Amount
+			if err := btcutils.VerifyInputScript(tx, i, spent.ScriptPubKey, spent.Amount); err != nil {
+				t.Errorf("Input %d of transaction with %s not valid. %s", i, testCase.name, err)
+			}
+		}
+		for _, output := range tx.Outputs {
+			outputTotal += int64(output.Satoshis)
+		}
+		virtualSize, _ := btcutils.VirtualSize(tx)
+		fee, minFee := inputTotal-outputTotal, int64(virtualSize)*feeRate
+		if fee < minFee || testCase.hasChange && fee > minFee+feeRate || !testCase.hasChange && fee > minFee+int64(btcutils.DustThreshold(changeScript, btcutils.DefaultDustRelayFeeRate)) {
+			t.Errorf("Transaction with %s pays a fee of %d satoshis for %d virtual bytes at %d sat/vB.", testCase.name, fee, virtualSize, feeRate)
+		}
+	}
+
+	p2shScriptPubKey, _ := btcutils.NewP2SHScriptPubKey(testPublicKeyHash)
+	otherPrivateKey, _ := hex.DecodeString("0000000000000000000000000000000000000000000000000000000000000001")
+	invalidCases := map[string]struct {
+		utxos   []utxo.UTXO
+		outputs []btcutils.Output
+		keys    KeyStore
+	}{
+		"P2SH UTXO":           {[]utxo.UTXO{testUTXO(1, p2shScriptPubKey, 200000)}, outputs, keys},
+		"insufficient funds":  {[]utxo.UTXO{testUTXO(1, p2wpkhScriptPubKey, 100500)}, outputs, keys},
+		"no outputs":          {[]utxo.UTXO{testUTXO(1, p2wpkhScriptPubKey, 200000)}, nil, keys},
+		"missing private key": {[]utxo.UTXO{testUTXO(1, p2wpkhScriptPubKey, 200000)}, outputs, testKeyStore{}},
+		"wrong private key":   {[]utxo.UTXO{testUTXO(1, p2wpkhScriptPubKey, 200000)}, outputs, testKeyStore{hex.EncodeToString(p2wpkhScriptPubKey): otherPrivateKey}},
+		//A fee of 1530 satoshis is above 10% of the 1000 satoshis sent plus the change
+		"high fee": {[]utxo.UTXO{testUTXO(1, p2wpkhScriptPubKey, 5000)}, []btcutils.Output{{Satoshis: 1000, ScriptPubKey: destination}}, keys},
+	}
+	for name, testCase := range invalidCases {
+		if _, err := BuildWithAutoInputs(testCase.utxos, testCase.outputs, feeRate, changeScript, testCase.keys); err == nil {
+			t.Errorf("BuildWithAutoInputs accepting %s.", name)
+		}
+	}
+}
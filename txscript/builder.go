@@ -0,0 +1,120 @@
+package txscript
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+const (
+	//MaxElementSize is the largest element that can be pushed on the stack
+	MaxElementSize = 520
+	//MaxScriptSize is the largest script that can be run
+	MaxScriptSize = 10000
+)
+
+// Builder builds a script one opcode or push at a time. The first error is kept and returned by Script, so calls
+// can be chained without checking each one:
+//
+//	script, err := txscript.NewBuilder().AddOp(txscript.OP_HASH160).AddData(hash).AddOp(txscript.OP_EQUAL).Script()
+type Builder struct {
+	script []byte
+	err    error
+}
+
+// NewBuilder returns a Builder for an empty script.
+func NewBuilder() *Builder {
+	return &Builder{}
+}
+
+// AddOp adds opcode to the script. Opcodes pushing data, other than OP_0, are refused, as AddData adds them with
+// their data.
+func (b *Builder) AddOp(opcode byte) *Builder {
+	if b.err != nil {
+		return b
+	}
+	if opcode >= OP_DATA_1 && opcode <= OP_PUSHDATA4 {
+		b.err = fmt.Errorf("Push opcode 0x%02x must be added with its data by AddData.", opcode)
+		return b
+	}
+	b.script = append(b.script, opcode)
+	return b
+}
+
+// AddData adds the smallest push of data to the script, as BIP62 requires: OP_0 for empty data, OP_1NEGATE or
+// OP_1 through OP_16 for a single byte they push, a single length byte up to 75 bytes, then OP_PUSHDATA1 up to 255
+// bytes and OP_PUSHDATA2 beyond. Data longer than MaxElementSize is refused, as it can't be pushed on the stack,
+// which also keeps P2SH redeem scripts pushed in a scriptSig within the 520 byte limit.
+func (b *Builder) AddData(data []byte) *Builder {
+	if b.err != nil {
+		return b
+	}
+	if len(data) > MaxElementSize {
+		b.err = fmt.Errorf("Pushed data is %d bytes long, longer than the %d byte stack element limit.", len(data), MaxElementSize)
+		return b
+	}
+	switch {
+	case len(data) == 0:
+		b.script = append(b.script, OP_0)
+		return b
+	case len(data) == 1 && data[0] >= 1 && data[0] <= 16:
+		b.script = append(b.script, OP_1+data[0]-1)
+		return b
+	case len(data) == 1 && data[0] == 0x81:
+		b.script = append(b.script, OP_1NEGATE)
+		return b
+	case len(data) <= OP_DATA_75:
+		b.script = append(b.script, byte(len(data)))
+	case len(data) <= 0xff:
+		b.script = append(b.script, OP_PUSHDATA1, byte(len(data)))
+	default:
+		b.script = append(b.script, OP_PUSHDATA2, 0, 0)
+		binary.LittleEndian.PutUint16(b.script[len(b.script)-2:], uint16(len(data)))
+	}
+	b.script = append(b.script, data...)
+	return b
+}
+
+// AddInt64 adds the smallest push of number to the script, OP_0, OP_1NEGATE or OP_1 through OP_16 if possible,
+// otherwise the push of Number(number).
+func (b *Builder) AddInt64(number int64) *Builder {
+	return b.AddData(Number(number))
+}
+
+// Script returns the script built, or the first error adding to it. Scripts longer than MaxScriptSize are refused.
+func (b *Builder) Script() ([]byte, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	if len(b.script) > MaxScriptSize {
+		return nil, fmt.Errorf("Script is %d bytes long, longer than the %d byte limit.", len(b.script), MaxScriptSize)
+	}
+	return b.script, nil
+}
+
+// Number encodes number as the shortest script number, little-endian with the sign in the highest bit. 0 is empty.
+func Number(number int64) []byte {
+	if number == 0 {
+		return nil
+	}
+	negative := number < 0
+	magnitude := uint64(number)
+	if negative {
+		magnitude = -magnitude
+	}
+	var data []byte
+	for magnitude > 0 {
+		data = append(data, byte(magnitude))
+		magnitude >>= 8
+	}
+	//An extra byte is needed if the highest bit is taken, otherwise the sign goes in it
+	if data[len(data)-1]&0x80 != 0 {
+		if negative {
+			data = append(data, 0x80)
+		} else {
+			data = append(data, 0x00)
+		}
+	} else if negative {
+		data[len(data)-1] |= 0x80
+	}
+	return data
+}
@@ -0,0 +1,142 @@
+package txscript
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/testutils"
+
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestBuilderStandardScripts(t *testing.T) {
+	testPublicKeyHash, _ := hex.DecodeString("1d0f172a0ecb48aee1be1f2687d2963ae33f71a1")
+	testPublicKeys := []string{
+		"025476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee6357",
+		"03b6446545566e0fecb2f2243db709bbf4e9fff78d48c9438acc911a36015e896a",
+		"033d63026f5f0de46e40dc09c61c1559240ea4381df7e05045f6ae2ac506efbaf2",
+	}
+	multisigBuilder := NewBuilder().AddInt64(2)
+	for _, publicKeyHex := range testPublicKeys {
+		publicKey, _ := hex.DecodeString(publicKeyHex)
+		multisigBuilder.AddData(publicKey)
+	}
+	multisigBuilder.AddInt64(int64(len(testPublicKeys))).AddOp(OP_CHECKMULTISIG)
+
+	testCases := []struct {
+		name      string
+		builder   *Builder
+		scriptHex string
+	}{
+		//BIP143 native P2WPKH example key
+		{"P2PKH", NewBuilder().AddOp(OP_DUP).AddOp(OP_HASH160).AddData(testPublicKeyHash).AddOp(OP_EQUALVERIFY).AddOp(OP_CHECKSIG), "76a9141d0f172a0ecb48aee1be1f2687d2963ae33f71a188ac"},
+		{"P2SH", NewBuilder().AddOp(OP_HASH160).AddData(testPublicKeyHash).AddOp(OP_EQUAL), "a9141d0f172a0ecb48aee1be1f2687d2963ae33f71a187"},
+		{"P2WPKH", NewBuilder().AddOp(OP_0).AddData(testPublicKeyHash), "00141d0f172a0ecb48aee1be1f2687d2963ae33f71a1"},
+		{"2-of-3 multisig", multisigBuilder, "5221025476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee63572103b6446545566e0fecb2f2243db709bbf4e9fff78d48c9438acc911a36015e896a21033d63026f5f0de46e40dc09c61c1559240ea4381df7e05045f6ae2ac506efbaf253ae"},
+		//BIP65 time-locked P2PKH, locked until block 840000
+		{"CLTV", NewBuilder().AddInt64(840000).AddOp(OP_CHECKLOCKTIMEVERIFY).AddOp(OP_DROP), "0340d10cb175"},
+	}
+	for _, testCase := range testCases {
+		script, err := testCase.builder.Script()
+		if err != nil {
+			t.Fatal(err)
+		}
+		scriptHex := hex.EncodeToString(script)
+		if scriptHex != testCase.scriptHex {
+			testutils.CompareError(t, testCase.name+" script different from expected script.", testCase.scriptHex, scriptHex)
+		}
+	}
+}
+
+func TestBuilderAddData(t *testing.T) {
+	testCases := []struct {
+		data      []byte
+		scriptHex string
+	}{
+		{nil, "00"},            //OP_0
+		{[]byte{0x00}, "0100"}, //A zero byte is not the empty number OP_0 pushes
+		{[]byte{0x01}, "51"},   //OP_1
+		{[]byte{0x10}, "60"},   //OP_16
+		{[]byte{0x11}, "0111"}, //Direct push
+		{[]byte{0x81}, "4f"},   //OP_1NEGATE
+		{bytes.Repeat([]byte{0xab}, 75), "4b" + strings.Repeat("ab", 75)},     //Largest direct push
+		{bytes.Repeat([]byte{0xab}, 76), "4c4c" + strings.Repeat("ab", 76)},   //OP_PUSHDATA1
+		{bytes.Repeat([]byte{0xab}, 255), "4cff" + strings.Repeat("ab", 255)}, //Largest OP_PUSHDATA1
+		{bytes.Repeat([]byte{0xab}, 256), "4d0001" + strings.Repeat("ab", 256)},
+		{bytes.Repeat([]byte{0xab}, MaxElementSize), "4d0802" + strings.Repeat("ab", MaxElementSize)},
+	}
+	for _, testCase := range testCases {
+		script, err := NewBuilder().AddData(testCase.data).Script()
+		if err != nil {
+			t.Fatal(err)
+		}
+		scriptHex := hex.EncodeToString(script)
+		if scriptHex != testCase.scriptHex {
+			testutils.CompareError(t, "Push of data different from expected push.", testCase.scriptHex, scriptHex)
+		}
+	}
+
+	if _, err := NewBuilder().AddData(make([]byte, MaxElementSize+1)).Script(); err == nil {
+		t.Errorf("Builder accepting push of %d bytes.", MaxElementSize+1)
+	}
+	//The first error is kept through later additions
+	_, err := NewBuilder().AddData(make([]byte, MaxElementSize+1)).AddOp(OP_CHECKSIG).AddData(nil).Script()
+	if err == nil || !strings.Contains(err.Error(), "stack element limit") {
+		t.Errorf("Builder not keeping the first error. %v", err)
+	}
+	builder := NewBuilder()
+	for i := 0; i < 20; i++ {
+		builder.AddData(make([]byte, MaxElementSize))
+	}
+	if _, err := builder.Script(); err == nil {
+		t.Errorf("Builder accepting script longer than %d bytes.", MaxScriptSize)
+	}
+}
+
+func TestBuilderAddOp(t *testing.T) {
+	for _, opcode := range []byte{OP_DATA_1, OP_DATA_20, OP_DATA_75, OP_PUSHDATA1, OP_PUSHDATA2, OP_PUSHDATA4} {
+		if _, err := NewBuilder().AddOp(opcode).Script(); err == nil {
+			t.Errorf("AddOp accepting push opcode 0x%02x without its data.", opcode)
+		}
+	}
+	script, err := NewBuilder().AddOp(OP_0).AddOp(OP_1NEGATE).AddOp(OP_16).AddOp(OP_CHECKSIGADD).Script()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(script) != "004f60ba" {
+		testutils.CompareError(t, "Script of opcodes different from expected script.", "004f60ba", hex.EncodeToString(script))
+	}
+}
+
+func TestNumber(t *testing.T) {
+	testCases := []struct {
+		number    int64
+		scriptHex string
+	}{
+		{0, "00"},
+		{-1, "4f"},
+		{1, "51"},
+		{16, "60"},
+		{17, "0111"},
+		{-2, "0182"},
+		{127, "017f"},
+		{128, "028000"}, //Extra byte for the sign
+		{-128, "028080"},
+		{255, "02ff00"},
+		{256, "020001"},
+		{-256, "020081"},
+		{0x7fffffff, "04ffffff7f"},
+		{0xffffffff, "05ffffffff00"}, //Largest lock time, 5 bytes
+		{-0x7fffffffffffffff, "08ffffffffffffffff"},
+	}
+	for _, testCase := range testCases {
+		script, err := NewBuilder().AddInt64(testCase.number).Script()
+		if err != nil {
+			t.Fatal(err)
+		}
+		scriptHex := hex.EncodeToString(script)
+		if scriptHex != testCase.scriptHex {
+			testutils.CompareError(t, "Push of script number different from expected push.", testCase.scriptHex, scriptHex)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package txscript
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// opcodeNames names every defined opcode that isn't a data push. Opcodes missing from it are unknown, such as the
+// BIP342 OP_SUCCESS opcodes.
+var opcodeNames = map[byte]string{
+	OP_0: "OP_0", OP_1NEGATE: "OP_1NEGATE", 0x50: "OP_RESERVED",
+	OP_1: "OP_1", OP_2: "OP_2", OP_3: "OP_3", OP_4: "OP_4", OP_5: "OP_5", OP_6: "OP_6", OP_7: "OP_7", OP_8: "OP_8",
+	OP_9: "OP_9", OP_10: "OP_10", OP_11: "OP_11", OP_12: "OP_12", OP_13: "OP_13", OP_14: "OP_14",
+	OP_15: "OP_15", OP_16: "OP_16",
+	OP_NOP: "OP_NOP", 0x62: "OP_VER", OP_IF: "OP_IF", OP_NOTIF: "OP_NOTIF", 0x65: "OP_VERIF", 0x66: "OP_VERNOTIF",
+	OP_ELSE: "OP_ELSE", OP_ENDIF: "OP_ENDIF", OP_VERIFY: "OP_VERIFY", OP_RETURN: "OP_RETURN",
+	0x6b: "OP_TOALTSTACK", 0x6c: "OP_FROMALTSTACK", 0x6d: "OP_2DROP", 0x6e: "OP_2DUP", 0x6f: "OP_3DUP",
+	0x70: "OP_2OVER", 0x71: "OP_2ROT", 0x72: "OP_2SWAP", 0x73: "OP_IFDUP", 0x74: "OP_DEPTH", OP_DROP: "OP_DROP",
+	OP_DUP: "OP_DUP", 0x77: "OP_NIP", 0x78: "OP_OVER", 0x79: "OP_PICK", 0x7a: "OP_ROLL", 0x7b: "OP_ROT",
+	0x7c: "OP_SWAP", 0x7d: "OP_TUCK",
+	0x7e: "OP_CAT", 0x7f: "OP_SUBSTR", 0x80: "OP_LEFT", 0x81: "OP_RIGHT", OP_SIZE: "OP_SIZE",
+	0x83: "OP_INVERT", 0x84: "OP_AND", 0x85: "OP_OR", 0x86: "OP_XOR", OP_EQUAL: "OP_EQUAL",
+	OP_EQUALVERIFY: "OP_EQUALVERIFY", 0x89: "OP_RESERVED1", 0x8a: "OP_RESERVED2",
+	0x8b: "OP_1ADD", 0x8c: "OP_1SUB", 0x8d: "OP_2MUL", 0x8e: "OP_2DIV", 0x8f: "OP_NEGATE", 0x90: "OP_ABS",
+	0x91: "OP_NOT", 0x92: "OP_0NOTEQUAL", 0x93: "OP_ADD", 0x94: "OP_SUB", 0x95: "OP_MUL", 0x96: "OP_DIV",
+	0x97: "OP_MOD", 0x98: "OP_LSHIFT", 0x99: "OP_RSHIFT", 0x9a: "OP_BOOLAND", 0x9b: "OP_BOOLOR",
+	OP_NUMEQUAL: "OP_NUMEQUAL", OP_NUMEQUALVERIFY: "OP_NUMEQUALVERIFY", 0x9e: "OP_NUMNOTEQUAL",
+	0x9f: "OP_LESSTHAN", 0xa0: "OP_GREATERTHAN", 0xa1: "OP_LESSTHANOREQUAL", 0xa2: "OP_GREATERTHANOREQUAL",
+	0xa3: "OP_MIN", 0xa4: "OP_MAX", 0xa5: "OP_WITHIN",
+	0xa6: "OP_RIPEMD160", 0xa7: "OP_SHA1", OP_SHA256: "OP_SHA256", OP_HASH160: "OP_HASH160",
+	OP_HASH256: "OP_HASH256", 0xab: "OP_CODESEPARATOR", OP_CHECKSIG: "OP_CHECKSIG",
+	OP_CHECKSIGVERIFY: "OP_CHECKSIGVERIFY", OP_CHECKMULTISIG: "OP_CHECKMULTISIG",
+	OP_CHECKMULTISIGVERIFY: "OP_CHECKMULTISIGVERIFY", 0xb0: "OP_NOP1",
+	OP_CHECKLOCKTIMEVERIFY: "OP_CHECKLOCKTIMEVERIFY", OP_CHECKSEQUENCEVERIFY: "OP_CHECKSEQUENCEVERIFY",
+	0xb3: "OP_NOP4", 0xb4: "OP_NOP5", 0xb5: "OP_NOP6", 0xb6: "OP_NOP7", 0xb7: "OP_NOP8", 0xb8: "OP_NOP9",
+	0xb9: "OP_NOP10", OP_CHECKSIGADD: "OP_CHECKSIGADD",
+}
+
+// readOpcode reads the opcode at offset in script and, for push opcodes, the data it pushes. Returns the offset of
+// the next opcode, or an error if the push runs past the end of script.
+func readOpcode(script []byte, offset int) (byte, []byte, int, error) {
+	opcode := script[offset]
+	next := offset + 1
+	var length uint64
+	switch {
+	case opcode >= OP_DATA_1 && opcode <= OP_DATA_75:
+		length = uint64(opcode)
+	case opcode >= OP_PUSHDATA1 && opcode <= OP_PUSHDATA4:
+		lengthSize := 1 << (opcode - OP_PUSHDATA1)
+		if len(script)-next < lengthSize {
+			return opcode, nil, len(script), fmt.Errorf("Length of %s at offset %d truncated.", opcodeName(opcode), offset)
+		}
+		lengthBytes := make([]byte, 4)
+		copy(lengthBytes, script[next:next+lengthSize])
+		length = uint64(binary.LittleEndian.Uint32(lengthBytes))
+		next += lengthSize
+	default:
+		return opcode, nil, next, nil
+	}
+	if uint64(len(script)-next) < length {
+		return opcode, nil, len(script), fmt.Errorf("Push of %d bytes at offset %d truncated to %d bytes.", length, offset, len(script)-next)
+	}
+	return opcode, script[next : next+int(length)], next + int(length), nil
+}
+
+// opcodeName names opcode, with data pushes of 1 to 75 bytes named OP_DATA_1 to OP_DATA_75 by their length.
+func opcodeName(opcode byte) string {
+	if opcode >= OP_DATA_1 && opcode <= OP_DATA_75 {
+		return fmt.Sprintf("OP_DATA_%d", opcode)
+	}
+	switch opcode {
+	case OP_PUSHDATA1:
+		return "OP_PUSHDATA1"
+	case OP_PUSHDATA2:
+		return "OP_PUSHDATA2"
+	case OP_PUSHDATA4:
+		return "OP_PUSHDATA4"
+	}
+	if name, ok := opcodeNames[opcode]; ok {
+		return name
+	}
+	return fmt.Sprintf("OP_UNKNOWN_0x%02x", opcode)
+}
+
+// Disasm renders script one opcode at a time, such as "OP_HASH160 OP_DATA_20 89abcdef… OP_EQUAL". Pushes of 1 to
+// 75 bytes are shown as OP_DATA_<length> and longer pushes as OP_PUSHDATA1, 2 or 4 followed by their length, with
+// the data pushed in hex. Unknown opcodes are shown as OP_UNKNOWN_0x<opcode> and don't stop the disassembly. A push
+// running past the end of script is shown in brackets at the end, with the disassembly up to it and an error.
+func Disasm(script []byte) (string, error) {
+	var parts []string
+	for offset := 0; offset < len(script); {
+		opcode, data, next, err := readOpcode(script, offset)
+		if err != nil {
+			parts = append(parts, opcodeName(opcode), "["+err.Error()+"]")
+			return strings.Join(parts, " "), err
+		}
+		parts = append(parts, opcodeName(opcode))
+		if opcode >= OP_PUSHDATA1 && opcode <= OP_PUSHDATA4 {
+			parts = append(parts, fmt.Sprint(len(data)))
+		}
+		if opcode >= OP_DATA_1 && opcode <= OP_PUSHDATA4 {
+			parts = append(parts, hex.EncodeToString(data))
+		}
+		offset = next
+	}
+	return strings.Join(parts, " "), nil
+}
+
+// ParsedOpcode is an opcode of a script with the data it pushes. Data is empty for OP_0, the number pushed for
+// OP_1NEGATE and OP_1 through OP_16, and nil for opcodes that aren't pushes.
+type ParsedOpcode struct {
+	Opcode byte
+	Data   []byte
+}
+
+// ParseScript splits script into its opcodes, in order, with the data each pushes. Unknown opcodes are kept, as the
+// script is not run. Returns an error if a push runs past the end of script.
+func ParseScript(script []byte) ([]ParsedOpcode, error) {
+	var opcodes []ParsedOpcode
+	for offset := 0; offset < len(script); {
+		opcode, data, next, err := readOpcode(script, offset)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case opcode == OP_0:
+			data = []byte{}
+		case opcode == OP_1NEGATE:
+			data = Number(-1)
+		case opcode >= OP_1 && opcode <= OP_16:
+			data = Number(int64(opcode - OP_1 + 1))
+		}
+		opcodes = append(opcodes, ParsedOpcode{Opcode: opcode, Data: data})
+		offset = next
+	}
+	return opcodes, nil
+}
+
+// PushedData returns the data pushed by each opcode of a push-only script, such as a scriptSig, in order. OP_0 pushes
+// empty data and OP_1NEGATE and OP_1 through OP_16 push their number. Scripts with other opcodes are refused.
+func PushedData(script []byte) ([][]byte, error) {
+	var pushes [][]byte
+	for offset := 0; offset < len(script); {
+		opcode, data, next, err := readOpcode(script, offset)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case opcode == OP_0:
+			data = []byte{}
+		case opcode == OP_1NEGATE:
+			data = Number(-1)
+		case opcode >= OP_1 && opcode <= OP_16:
+			data = Number(int64(opcode - OP_1 + 1))
+		case opcode > OP_PUSHDATA4:
+			return nil, fmt.Errorf("Script isn't push only, %s at offset %d.", opcodeName(opcode), offset)
+		}
+		pushes = append(pushes, data)
+		offset = next
+	}
+	return pushes, nil
+}
+
+// ScriptClass is the standard template a scriptPubKey or redeem script follows.
+type ScriptClass string
+
+// Script classes recognized by Classify.
+const (
+	NonStandard       ScriptClass = "nonstandard"
+	PubKey            ScriptClass = "P2PK"
+	PubKeyHash        ScriptClass = "P2PKH"
+	ScriptHash        ScriptClass = "P2SH"
+	WitnessPubKeyHash ScriptClass = "P2WPKH"
+	WitnessScriptHash ScriptClass = "P2WSH"
+	WitnessTaproot    ScriptClass = "P2TR"
+	MultiSig          ScriptClass = "multisig"
+	NullData          ScriptClass = "OP_RETURN"
+)
+
+// Classify recognizes the standard template of script: P2PK and P2PKH, P2SH, native SegWit P2WPKH, P2WSH and P2TR,
+// bare M-of-N multisig with 33 or 65 byte public keys, and OP_RETURN followed only by pushes. Any other script,
+// including one that can't be parsed, is NonStandard.
+func Classify(script []byte) ScriptClass {
+	switch {
+	case len(script) == 35 && script[0] == OP_DATA_33 && script[34] == OP_CHECKSIG,
+		len(script) == 67 && script[0] == OP_DATA_65 && script[66] == OP_CHECKSIG:
+		return PubKey
+	case len(script) == 25 && script[0] == OP_DUP && script[1] == OP_HASH160 && script[2] == OP_DATA_20 &&
+		script[23] == OP_EQUALVERIFY && script[24] == OP_CHECKSIG:
+		return PubKeyHash
+	case len(script) == 23 && script[0] == OP_HASH160 && script[1] == OP_DATA_20 && script[22] == OP_EQUAL:
+		return ScriptHash
+	case len(script) == 22 && script[0] == OP_0 && script[1] == OP_DATA_20:
+		return WitnessPubKeyHash
+	case len(script) == 34 && script[0] == OP_0 && script[1] == OP_DATA_32:
+		return WitnessScriptHash
+	case len(script) == 34 && script[0] == OP_1 && script[1] == OP_DATA_32:
+		return WitnessTaproot
+	case len(script) > 0 && script[0] == OP_RETURN:
+		for offset := 1; offset < len(script); {
+			opcode, _, next, err := readOpcode(script, offset)
+			if err != nil || opcode > OP_16 || opcode == 0x50 {
+				return NonStandard
+			}
+			offset = next
+		}
+		return NullData
+	case isMultiSig(script):
+		return MultiSig
+	}
+	return NonStandard
+}
+
+// isMultiSig reports whether script is <OP_M> <public key>... <OP_N> OP_CHECKMULTISIG, with N public keys of 33 or
+// 65 bytes and M no larger than N.
+func isMultiSig(script []byte) bool {
+	if len(script) < 3 || script[len(script)-1] != OP_CHECKMULTISIG {
+		return false
+	}
+	m, n := script[0], script[len(script)-2]
+	if m < OP_1 || m > OP_16 || n < m || n > OP_16 {
+		return false
+	}
+	publicKeys := 0
+	for offset := 1; offset < len(script)-2; {
+		opcode, _, next, err := readOpcode(script, offset)
+		if err != nil || opcode != OP_DATA_33 && opcode != OP_DATA_65 || next > len(script)-2 {
+			return false
+		}
+		publicKeys++
+		offset = next
+	}
+	return publicKeys == int(n-OP_1+1)
+}
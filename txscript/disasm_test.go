@@ -0,0 +1,137 @@
+package txscript
+
+import (
+	"github.com/CryptoProcessing/go-bitcoin-multisig/testutils"
+
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestDisasm(t *testing.T) {
+	testCases := []struct {
+		scriptHex string
+		disasm    string
+	}{
+		{"", ""},
+		{"a9141d0f172a0ecb48aee1be1f2687d2963ae33f71a187", "OP_HASH160 OP_DATA_20 1d0f172a0ecb48aee1be1f2687d2963ae33f71a1 OP_EQUAL"},
+		{"0340d10cb175", "OP_DATA_3 40d10c OP_CHECKLOCKTIMEVERIFY OP_DROP"},
+		{"004f5160b2ba", "OP_0 OP_1NEGATE OP_1 OP_16 OP_CHECKSEQUENCEVERIFY OP_CHECKSIGADD"},
+		{"4c03abcdef", "OP_PUSHDATA1 3 abcdef"}, //Not minimal, shown as is
+		{"4d0100ab4e01000000cd", "OP_PUSHDATA2 1 ab OP_PUSHDATA4 1 cd"},
+		{"bbff6a", "OP_UNKNOWN_0xbb OP_UNKNOWN_0xff OP_RETURN"},
+	}
+	for _, testCase := range testCases {
+		script, _ := hex.DecodeString(testCase.scriptHex)
+		disasm, err := Disasm(script)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if disasm != testCase.disasm {
+			testutils.CompareError(t, "Disassembly different from expected disassembly.", testCase.disasm, disasm)
+		}
+	}
+
+	truncatedCases := []struct {
+		scriptHex string
+		disasm    string
+	}{
+		{"a9141d0f172a87", "OP_HASH160 OP_DATA_20 [Push of 20 bytes at offset 1 truncated to 5 bytes.]"},
+		{"764c", "OP_DUP OP_PUSHDATA1 [Length of OP_PUSHDATA1 at offset 1 truncated.]"},
+		{"4d01", "OP_PUSHDATA2 [Length of OP_PUSHDATA2 at offset 0 truncated.]"},
+		{"4effffffffab", "OP_PUSHDATA4 [Push of 4294967295 bytes at offset 0 truncated to 1 bytes.]"},
+	}
+	for _, testCase := range truncatedCases {
+		script, _ := hex.DecodeString(testCase.scriptHex)
+		disasm, err := Disasm(script)
+		if err == nil {
+			t.Errorf("Disasm accepting truncated script %s.", testCase.scriptHex)
+		}
+		if disasm != testCase.disasm {
+			testutils.CompareError(t, "Disassembly of truncated script different from expected disassembly.", testCase.disasm, disasm)
+		}
+	}
+}
+
+func TestClassify(t *testing.T) {
+	multisigScript := "5221025476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee63572103b6446545566e0fecb2f2243db709bbf4e9fff78d48c9438acc911a36015e896a21033d63026f5f0de46e40dc09c61c1559240ea4381df7e05045f6ae2ac506efbaf253ae"
+	testCases := []struct {
+		scriptHex string
+		class     ScriptClass
+	}{
+		{"2103c9f4836b9a4f77fc0d81f7bcb01b7f1b35916864b9476c241ce9fc198bd25432ac", PubKey},
+		{"76a9141d0f172a0ecb48aee1be1f2687d2963ae33f71a188ac", PubKeyHash},
+		{"a9141d0f172a0ecb48aee1be1f2687d2963ae33f71a187", ScriptHash},
+		{"00141d0f172a0ecb48aee1be1f2687d2963ae33f71a1", WitnessPubKeyHash},
+		{"0020" + strings.Repeat("ab", 32), WitnessScriptHash},
+		{"5120" + strings.Repeat("ab", 32), WitnessTaproot},
+		{multisigScript, MultiSig},
+		{"6a", NullData},
+		{"6a0b68656c6c6f20776f726c64", NullData},
+		{"6a4c0568656c6c6f", NullData},
+		{"", NonStandard},
+		{"6a76", NonStandard},                                           //OP_RETURN followed by an opcode
+		{"6a0b68656c", NonStandard},                                     //OP_RETURN followed by a truncated push
+		{"a9141d0f172a0ecb48aee1be1f2687d2963ae33f71a188", NonStandard}, //OP_EQUALVERIFY in place of OP_EQUAL
+		{"0340d10cb175" + multisigScript, NonStandard},                  //Time-locked multisig
+		{"54" + multisigScript[2:], NonStandard},                        //4-of-3
+		{multisigScript[:len(multisigScript)-4] + "52ae", NonStandard},
+		{"5121" + strings.Repeat("02", 33) + "5151ae", NonStandard}, //Public key push running into OP_N
+		{"51" + "5151ae", NonStandard},
+		{"21" + strings.Repeat("02", 33) + "ad", NonStandard}, //OP_CHECKSIGVERIFY in place of OP_CHECKSIG
+	}
+	for _, testCase := range testCases {
+		script, _ := hex.DecodeString(testCase.scriptHex)
+		if class := Classify(script); class != testCase.class {
+			testutils.CompareError(t, "Class of script "+testCase.scriptHex+" different from expected class.", string(testCase.class), string(class))
+		}
+	}
+}
+
+func TestParseScript(t *testing.T) {
+	//OP_0 OP_2 <2 bytes> OP_CHECKSEQUENCEVERIFY OP_DROP OP_PUSHDATA4 <1 byte> OP_CHECKSIG
+	script, _ := hex.DecodeString("52024142b2754e01000000abac")
+	opcodes, err := ParseScript(append([]byte{OP_0}, script...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testOpcodes := []ParsedOpcode{{OP_0, []byte{}}, {OP_2, []byte{0x02}}, {0x02, []byte{0x41, 0x42}}, {OP_CHECKSEQUENCEVERIFY, nil}, {OP_DROP, nil}, {OP_PUSHDATA4, []byte{0xab}}, {OP_CHECKSIG, nil}}
+	if len(opcodes) != len(testOpcodes) {
+		t.Fatalf("ParseScript returned %d opcodes, expected %d.", len(opcodes), len(testOpcodes))
+	}
+	for i := range testOpcodes {
+		if opcodes[i].Opcode != testOpcodes[i].Opcode || !bytes.Equal(opcodes[i].Data, testOpcodes[i].Data) || (opcodes[i].Data == nil) != (testOpcodes[i].Data == nil) {
+			testutils.CompareError(t, "Parsed opcode different from expected opcode.", testOpcodes[i], opcodes[i])
+		}
+	}
+	for _, invalidScriptHex := range []string{"0041", "764c", "4e0100"} {
+		invalidScript, _ := hex.DecodeString(invalidScriptHex)
+		if _, err := ParseScript(invalidScript); err == nil {
+			t.Errorf("ParseScript accepting script %s.", invalidScriptHex)
+		}
+	}
+}
+
+func TestPushedData(t *testing.T) {
+	script, _ := hex.DecodeString("00024142514f4c03abcdef")
+	pushes, err := PushedData(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testPushes := [][]byte{{}, {0x41, 0x42}, {0x01}, {0x81}, {0xab, 0xcd, 0xef}}
+	if len(pushes) != len(testPushes) {
+		t.Fatalf("PushedData returned %d pushes, expected %d.", len(pushes), len(testPushes))
+	}
+	for i := range testPushes {
+		if !bytes.Equal(pushes[i], testPushes[i]) {
+			testutils.CompareError(t, "Pushed data different from expected data.", hex.EncodeToString(testPushes[i]), hex.EncodeToString(pushes[i]))
+		}
+	}
+	for _, invalidScriptHex := range []string{"0041", "00ac", "0050"} {
+		invalidScript, _ := hex.DecodeString(invalidScriptHex)
+		if _, err := PushedData(invalidScript); err == nil {
+			t.Errorf("PushedData accepting script %s.", invalidScriptHex)
+		}
+	}
+}
@@ -0,0 +1,340 @@
+package txscript
+
+import (
+	"golang.org/x/crypto/ripemd160"
+
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// MaxPublicKeysPerMultiSig is the most public keys OP_CHECKMULTISIG can check signatures against.
+const MaxPublicKeysPerMultiSig = 20
+
+// SigChecker checks the parts of a script that depend on the transaction spending it, which Execute leaves to the
+// caller so this package doesn't need to know how transactions are signed.
+type SigChecker interface {
+	//CheckSig reports whether signature, with its hash type byte, is a valid signature by publicKey of the
+	//transaction, with script as the script code signed
+	CheckSig(signature []byte, publicKey []byte, script []byte) bool
+	//CheckLockTime returns an error if the transaction doesn't satisfy OP_CHECKLOCKTIMEVERIFY of lockTime
+	CheckLockTime(lockTime int64) error
+	//CheckSequence returns an error if the input doesn't satisfy OP_CHECKSEQUENCEVERIFY of sequence
+	CheckSequence(sequence int64) error
+}
+
+// ExecError is the failure of an opcode run by Execute, with the stack as it was when the opcode ran.
+type ExecError struct {
+	Offset int
+	Opcode byte
+	Stack  [][]byte
+	Err    error
+}
+
+func (e *ExecError) Error() string {
+	return fmt.Sprintf("%s at offset %d failed. %s Stack: %s", opcodeName(e.Opcode), e.Offset, e.Err, FormatStack(e.Stack))
+}
+
+// FormatStack renders stack from bottom to top, each item in hex and empty items as <>.
+func FormatStack(stack [][]byte) string {
+	items := make([]string, len(stack))
+	for i, item := range stack {
+		items[i] = fmt.Sprintf("%x", item)
+		if len(item) == 0 {
+			items[i] = "<>"
+		}
+	}
+	return "[" + strings.Join(items, " ") + "]"
+}
+
+// IsTrue reports whether a stack item counts as true, any item but zero, which can be empty, any number of zero
+// bytes, or zero bytes followed by 0x80 as negative zero.
+func IsTrue(item []byte) bool {
+	for i, b := range item {
+		if b != 0 && !(i == len(item)-1 && b == 0x80) {
+			return true
+		}
+	}
+	return false
+}
+
+// Execute runs script on stack and returns the stack it leaves. Only the opcodes standard scripts use are
+// supported: pushes, OP_0 through OP_16, OP_NOP, OP_VERIFY, OP_RETURN, OP_DROP, OP_DUP, OP_EQUAL(VERIFY),
+// OP_SHA256, OP_HASH160, OP_HASH256, OP_CHECKSIG(VERIFY), OP_CHECKMULTISIG(VERIFY), OP_CHECKLOCKTIMEVERIFY and
+// OP_CHECKSEQUENCEVERIFY. Any other opcode fails, as does a signature check failing with a signature that isn't
+// empty, as the BIP146 NULLFAIL rule requires, so the failing opcode is reported rather than a false result.
+// The script code signed is the whole script, as OP_CODESEPARATOR isn't supported.
+// Returns an *ExecError naming the opcode that failed and the stack it ran on.
+func Execute(script []byte, stack [][]byte, checker SigChecker) ([][]byte, error) {
+	if len(script) > MaxScriptSize {
+		return nil, fmt.Errorf("Script is %d bytes long, longer than the %d byte limit.", len(script), MaxScriptSize)
+	}
+	engine := &engine{stack: append([][]byte(nil), stack...), script: script, checker: checker}
+	for offset := 0; offset < len(script); {
+		opcode, data, next, err := readOpcode(script, offset)
+		if err != nil {
+			return nil, &ExecError{Offset: offset, Opcode: opcode, Stack: engine.stack, Err: err}
+		}
+		if err := engine.step(opcode, data); err != nil {
+			return nil, &ExecError{Offset: offset, Opcode: opcode, Stack: engine.before, Err: err}
+		}
+		offset = next
+	}
+	return engine.stack, nil
+}
+
+// engine holds the state of a script being run by Execute, with the stack as it was before the last opcode.
+type engine struct {
+	stack   [][]byte
+	before  [][]byte
+	script  []byte
+	checker SigChecker
+}
+
+// pop removes the top item of the stack.
+func (e *engine) pop() ([]byte, error) {
+	if len(e.stack) == 0 {
+		return nil, errors.New("Stack is empty.")
+	}
+	item := e.stack[len(e.stack)-1]
+	e.stack = e.stack[:len(e.stack)-1]
+	return item, nil
+}
+
+// popNumber removes the top item of the stack, a script number of at most 4 bytes.
+func (e *engine) popNumber() (int64, error) {
+	item, err := e.pop()
+	if err != nil {
+		return 0, err
+	}
+	return ParseNumber(item, 4)
+}
+
+// peekNumber returns the top item of the stack, a script number of at most maxLength bytes, leaving it there.
+func (e *engine) peekNumber(maxLength int) (int64, error) {
+	if len(e.stack) == 0 {
+		return 0, errors.New("Stack is empty.")
+	}
+	return ParseNumber(e.stack[len(e.stack)-1], maxLength)
+}
+
+// pushBool pushes 1 for true and empty data for false.
+func (e *engine) pushBool(value bool) {
+	if value {
+		e.stack = append(e.stack, []byte{1})
+	} else {
+		e.stack = append(e.stack, []byte{})
+	}
+}
+
+// step runs a single opcode, with data it pushes.
+func (e *engine) step(opcode byte, data []byte) error {
+	e.before = append([][]byte(nil), e.stack...)
+	switch {
+	case opcode == OP_0:
+		e.stack = append(e.stack, []byte{})
+		return nil
+	case opcode <= OP_PUSHDATA4:
+		if len(data) > MaxElementSize {
+			return fmt.Errorf("Pushed data is %d bytes long, longer than the %d byte stack element limit.", len(data), MaxElementSize)
+		}
+		e.stack = append(e.stack, data)
+		return nil
+	case opcode == OP_1NEGATE:
+		e.stack = append(e.stack, Number(-1))
+		return nil
+	case opcode >= OP_1 && opcode <= OP_16:
+		e.stack = append(e.stack, Number(int64(opcode-OP_1+1)))
+		return nil
+	}
+	switch opcode {
+	case OP_NOP:
+		return nil
+	case OP_VERIFY:
+		return e.verify()
+	case OP_RETURN:
+		return errors.New("OP_RETURN makes the script unspendable.")
+	case OP_DROP:
+		_, err := e.pop()
+		return err
+	case OP_DUP:
+		item, err := e.pop()
+		if err != nil {
+			return err
+		}
+		e.stack = append(e.stack, item, item)
+		return nil
+	case OP_EQUAL, OP_EQUALVERIFY:
+		a, err := e.pop()
+		if err != nil {
+			return err
+		}
+		b, err := e.pop()
+		if err != nil {
+			return err
+		}
+		e.pushBool(bytes.Equal(a, b))
+		if opcode == OP_EQUALVERIFY {
+			return e.verify()
+		}
+		return nil
+	case OP_SHA256, OP_HASH160, OP_HASH256:
+		item, err := e.pop()
+		if err != nil {
+			return err
+		}
+		hash := sha256.Sum256(item)
+		switch opcode {
+		case OP_HASH160:
+			ripemd160Hash := ripemd160.New()
+			ripemd160Hash.Write(hash[:])
+			e.stack = append(e.stack, ripemd160Hash.Sum(nil))
+		case OP_HASH256:
+			secondHash := sha256.Sum256(hash[:])
+			e.stack = append(e.stack, secondHash[:])
+		default:
+			e.stack = append(e.stack, hash[:])
+		}
+		return nil
+	case OP_CHECKSIG, OP_CHECKSIGVERIFY:
+		if err := e.checkSig(); err != nil {
+			return err
+		}
+		if opcode == OP_CHECKSIGVERIFY {
+			return e.verify()
+		}
+		return nil
+	case OP_CHECKMULTISIG, OP_CHECKMULTISIGVERIFY:
+		if err := e.checkMultiSig(); err != nil {
+			return err
+		}
+		if opcode == OP_CHECKMULTISIGVERIFY {
+			return e.verify()
+		}
+		return nil
+	case OP_CHECKLOCKTIMEVERIFY:
+		//Lock times are up to 5 bytes, to reach beyond 2^31 like the uint32 transaction lock time
+		lockTime, err := e.peekNumber(5)
+		if err != nil {
+			return err
+		}
+		return e.checker.CheckLockTime(lockTime)
+	case OP_CHECKSEQUENCEVERIFY:
+		sequence, err := e.peekNumber(5)
+		if err != nil {
+			return err
+		}
+		return e.checker.CheckSequence(sequence)
+	}
+	return errors.New("Opcode not supported by the interpreter.")
+}
+
+// verify removes the top item of the stack, failing unless it is true.
+func (e *engine) verify() error {
+	item, err := e.pop()
+	if err != nil {
+		return err
+	}
+	if !IsTrue(item) {
+		return errors.New("Top of stack is false.")
+	}
+	return nil
+}
+
+// checkSig runs OP_CHECKSIG on <signature> <public key>.
+func (e *engine) checkSig() error {
+	publicKey, err := e.pop()
+	if err != nil {
+		return err
+	}
+	signature, err := e.pop()
+	if err != nil {
+		return err
+	}
+	valid := len(signature) > 0 && e.checker.CheckSig(signature, publicKey, e.script)
+	if !valid && len(signature) > 0 {
+		return fmt.Errorf("Signature %x not valid for public key %x.", signature, publicKey)
+	}
+	e.pushBool(valid)
+	return nil
+}
+
+// checkMultiSig runs OP_CHECKMULTISIG on <dummy> <signature>... <M> <public key>... <N>. Signatures must be in the
+// same order as the public keys they are valid for. The dummy item, popped by an off-by-one bug of the original
+// client, must be empty as per BIP147.
+func (e *engine) checkMultiSig() error {
+	n, err := e.popNumber()
+	if err != nil {
+		return err
+	}
+	if n < 0 || n > MaxPublicKeysPerMultiSig {
+		return fmt.Errorf("Number of public keys %d not between 0 and %d.", n, MaxPublicKeysPerMultiSig)
+	}
+	publicKeys := make([][]byte, n)
+	for i := len(publicKeys) - 1; i >= 0; i-- {
+		if publicKeys[i], err = e.pop(); err != nil {
+			return err
+		}
+	}
+	m, err := e.popNumber()
+	if err != nil {
+		return err
+	}
+	if m < 0 || m > n {
+		return fmt.Errorf("Number of signatures %d not between 0 and the %d public keys.", m, n)
+	}
+	signatures := make([][]byte, m)
+	for i := len(signatures) - 1; i >= 0; i-- {
+		if signatures[i], err = e.pop(); err != nil {
+			return err
+		}
+	}
+	dummy, err := e.pop()
+	if err != nil {
+		return err
+	}
+	if len(dummy) != 0 {
+		return fmt.Errorf("Dummy item %x popped by OP_CHECKMULTISIG must be empty.", dummy)
+	}
+	valid := 0
+	for k, publicKey := range publicKeys {
+		//Stop once the signatures left outnumber the public keys left to check them against
+		if valid == len(signatures) || len(signatures)-valid > len(publicKeys)-k {
+			break
+		}
+		if len(signatures[valid]) > 0 && e.checker.CheckSig(signatures[valid], publicKey, e.script) {
+			valid++
+		}
+	}
+	if valid < len(signatures) {
+		for _, signature := range signatures {
+			if len(signature) > 0 {
+				return fmt.Errorf("Only %d of %d signatures valid for the public keys, in the order of the public keys.", valid, len(signatures))
+			}
+		}
+	}
+	e.pushBool(valid == len(signatures))
+	return nil
+}
+
+// ParseNumber decodes a script number of at most maxLength bytes, little-endian with the sign in the highest bit.
+// Numbers must be minimally encoded, as Number encodes them.
+func ParseNumber(data []byte, maxLength int) (int64, error) {
+	if len(data) > maxLength {
+		return 0, fmt.Errorf("Number %x is longer than %d bytes.", data, maxLength)
+	}
+	if len(data) > 0 && data[len(data)-1]&0x7f == 0 && (len(data) == 1 || data[len(data)-2]&0x80 == 0) {
+		return 0, fmt.Errorf("Number %x is not minimally encoded.", data)
+	}
+	var number int64
+	for i, b := range data {
+		number |= int64(b) << (8 * uint(i))
+	}
+	if len(data) > 0 && data[len(data)-1]&0x80 != 0 {
+		number &^= int64(0x80) << (8 * uint(len(data)-1))
+		number = -number
+	}
+	return number, nil
+}
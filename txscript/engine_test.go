@@ -0,0 +1,139 @@
+package txscript
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// testChecker takes a signature as valid if it is 0x30 followed by the public key, lock times up to 100 and
+// sequences up to 10.
+type testChecker struct{}
+
+func (testChecker) CheckSig(signature []byte, publicKey []byte, script []byte) bool {
+	return bytes.Equal(signature, append([]byte{0x30}, publicKey...))
+}
+
+func (testChecker) CheckLockTime(lockTime int64) error {
+	if lockTime > 100 {
+		return errors.New("Lock time not reached.")
+	}
+	return nil
+}
+
+func (testChecker) CheckSequence(sequence int64) error {
+	if sequence > 10 {
+		return errors.New("Relative lock time not reached.")
+	}
+	return nil
+}
+
+func testSignature(publicKey []byte) []byte {
+	return append([]byte{0x30}, publicKey...)
+}
+
+func TestExecute(t *testing.T) {
+	//BIP143 native P2WPKH example key
+	publicKey, _ := hex.DecodeString("025476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee6357")
+	p2pkhScript, _ := hex.DecodeString("76a9141d0f172a0ecb48aee1be1f2687d2963ae33f71a188ac")
+	keys := [][]byte{{0x02, 0x01}, {0x02, 0x02}, {0x02, 0x03}}
+	multisigScript, _ := NewBuilder().AddInt64(2).AddData(keys[0]).AddData(keys[1]).AddData(keys[2]).AddInt64(3).AddOp(OP_CHECKMULTISIG).Script()
+	cltvScript, _ := NewBuilder().AddInt64(100).AddOp(OP_CHECKLOCKTIMEVERIFY).AddOp(OP_DROP).AddInt64(10).AddOp(OP_CHECKSEQUENCEVERIFY).Script()
+
+	testCases := []struct {
+		name   string
+		script []byte
+		stack  [][]byte
+		result bool
+	}{
+		{"P2PKH", p2pkhScript, [][]byte{testSignature(publicKey), publicKey}, true},
+		{"P2PKH with empty signature", p2pkhScript, [][]byte{{}, publicKey}, false},
+		{"2-of-3 multisig with keys 1 and 3", multisigScript, [][]byte{{}, testSignature(keys[0]), testSignature(keys[2])}, true},
+		{"2-of-3 multisig with keys 2 and 3", multisigScript, [][]byte{{}, testSignature(keys[1]), testSignature(keys[2])}, true},
+		{"2-of-3 multisig with empty signatures", multisigScript, [][]byte{{}, {}, {}}, false},
+		{"time locks", cltvScript, nil, true},
+		{"OP_EQUAL", []byte{OP_1, OP_DATA_1, 0x01, OP_EQUAL}, nil, true},
+		{"OP_SHA256", append([]byte{OP_0, OP_SHA256, OP_DATA_32}, append(mustDecode("e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"), OP_EQUAL)...), nil, true},
+		{"negative zero", []byte{OP_DATA_1, 0x80}, nil, false},
+	}
+	for _, testCase := range testCases {
+		stack, err := Execute(testCase.script, testCase.stack, testChecker{})
+		if err != nil {
+			t.Errorf("Executing %s failed. %s", testCase.name, err)
+			continue
+		}
+		if len(stack) != 1 || IsTrue(stack[0]) != testCase.result {
+			t.Errorf("Executing %s left stack %s, expected %v.", testCase.name, FormatStack(stack), testCase.result)
+		}
+	}
+
+	failingCases := []struct {
+		name   string
+		script []byte
+		stack  [][]byte
+		offset int
+		opcode byte
+		reason string
+	}{
+		{"P2PKH of another key", p2pkhScript, [][]byte{testSignature(keys[0]), keys[0]}, 23, OP_EQUALVERIFY, "Top of stack is false."},
+		{"P2PKH with invalid signature", p2pkhScript, [][]byte{testSignature(keys[0]), publicKey}, 24, OP_CHECKSIG, "not valid for public key"},
+		{"P2PKH on empty stack", p2pkhScript, nil, 0, OP_DUP, "Stack is empty."},
+		{"multisig signatures out of order", multisigScript, [][]byte{{}, testSignature(keys[2]), testSignature(keys[0])}, 11, OP_CHECKMULTISIG, "Only 0 of 2 signatures"},
+		{"multisig with an empty signature", multisigScript, [][]byte{{}, {}, testSignature(keys[1])}, 11, OP_CHECKMULTISIG, "Only 0 of 2 signatures"},
+		{"multisig without dummy", multisigScript, [][]byte{testSignature(keys[0]), testSignature(keys[2])}, 11, OP_CHECKMULTISIG, "Stack is empty."},
+		{"multisig with dummy", multisigScript, [][]byte{{0x01}, testSignature(keys[0]), testSignature(keys[2])}, 11, OP_CHECKMULTISIG, "must be empty"},
+		{"lock time not reached", cltvScript[2:], [][]byte{{101}}, 0, OP_CHECKLOCKTIMEVERIFY, "Lock time not reached."},
+		{"non-minimal lock time", cltvScript[2:], [][]byte{{100, 0}}, 0, OP_CHECKLOCKTIMEVERIFY, "not minimally encoded"},
+		{"relative lock time not reached", []byte{OP_11, OP_CHECKSEQUENCEVERIFY}, nil, 1, OP_CHECKSEQUENCEVERIFY, "Relative lock time not reached."},
+		{"OP_RETURN", []byte{OP_1, OP_RETURN}, nil, 1, OP_RETURN, "unspendable"},
+		{"OP_VERIFY of false", []byte{OP_0, OP_VERIFY}, nil, 1, OP_VERIFY, "Top of stack is false."},
+		{"unsupported opcode", []byte{OP_1, OP_IF, OP_1, OP_ENDIF}, nil, 1, OP_IF, "not supported"},
+		{"truncated push", []byte{OP_1, OP_DATA_20, 0x01}, nil, 1, OP_DATA_20, "truncated"},
+	}
+	for _, testCase := range failingCases {
+		_, err := Execute(testCase.script, testCase.stack, testChecker{})
+		execError, ok := err.(*ExecError)
+		if !ok {
+			t.Errorf("Executing %s returned %v, expected an ExecError.", testCase.name, err)
+			continue
+		}
+		if execError.Offset != testCase.offset || execError.Opcode != testCase.opcode || !strings.Contains(execError.Error(), testCase.reason) {
+			t.Errorf("Executing %s failed with %s, expected %s at offset %d failing with %q.", testCase.name, execError, opcodeName(testCase.opcode), testCase.offset, testCase.reason)
+		}
+	}
+
+	//The stack of the failing opcode is reported, and the stack given is left unchanged
+	stack := [][]byte{testSignature(keys[0]), keys[0]}
+	_, err := Execute(p2pkhScript, stack, testChecker{})
+	if !strings.Contains(err.Error(), "Stack: [300201 0201 ") || !strings.HasSuffix(err.Error(), " 1d0f172a0ecb48aee1be1f2687d2963ae33f71a1]") {
+		t.Errorf("Stack of failing opcode not reported. %s", err)
+	}
+	if len(stack) != 2 || !bytes.Equal(stack[1], keys[0]) {
+		t.Errorf("Execute changed the stack given to %s.", FormatStack(stack))
+	}
+}
+
+func TestParseNumber(t *testing.T) {
+	for _, number := range []int64{0, 1, -1, 16, 127, 128, -128, 255, 256, 0x7fffffff, -0x7fffffff, 0xffffffff} {
+		parsed, err := ParseNumber(Number(number), 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parsed != number {
+			t.Errorf("Parsed number %d, expected %d.", parsed, number)
+		}
+	}
+	for _, invalidNumberHex := range []string{"00", "80", "0100", "ff0080", "0000000001"} {
+		invalidNumber, _ := hex.DecodeString(invalidNumberHex)
+		if _, err := ParseNumber(invalidNumber, 4); err == nil {
+			t.Errorf("ParseNumber accepting %s.", invalidNumberHex)
+		}
+	}
+}
+
+func mustDecode(data string) []byte {
+	decoded, _ := hex.DecodeString(data)
+	return decoded
+}
@@ -0,0 +1,71 @@
+// Package txscript builds Bitcoin scripts from named opcodes and data pushes, always choosing the minimal push
+// encoding as per BIP62 so the scripts are standard.
+// See https://en.bitcoin.it/wiki/Script for full specification.
+package txscript
+
+// Push opcodes. Data of 1 to 75 bytes is pushed with the opcode of its length, OP_DATA_1 to OP_DATA_75.
+const (
+	OP_0         = 0x00
+	OP_FALSE     = OP_0
+	OP_DATA_1    = 0x01
+	OP_DATA_20   = 0x14
+	OP_DATA_32   = 0x20
+	OP_DATA_33   = 0x21
+	OP_DATA_65   = 0x41
+	OP_DATA_75   = 0x4b
+	OP_PUSHDATA1 = 0x4c
+	OP_PUSHDATA2 = 0x4d
+	OP_PUSHDATA4 = 0x4e
+	OP_1NEGATE   = 0x4f
+)
+
+// OP_1 through OP_16 push the numbers 1 to 16.
+const (
+	OP_1 = 0x51 + iota
+	OP_2
+	OP_3
+	OP_4
+	OP_5
+	OP_6
+	OP_7
+	OP_8
+	OP_9
+	OP_10
+	OP_11
+	OP_12
+	OP_13
+	OP_14
+	OP_15
+	OP_16
+	OP_TRUE = OP_1
+)
+
+// Flow control, stack, comparison, crypto and locktime opcodes used in standard scripts.
+const (
+	OP_NOP                 = 0x61
+	OP_IF                  = 0x63
+	OP_NOTIF               = 0x64
+	OP_ELSE                = 0x67
+	OP_ENDIF               = 0x68
+	OP_VERIFY              = 0x69
+	OP_RETURN              = 0x6a
+	OP_DROP                = 0x75
+	OP_DUP                 = 0x76
+	OP_SIZE                = 0x82
+	OP_EQUAL               = 0x87
+	OP_EQUALVERIFY         = 0x88
+	OP_NUMEQUAL            = 0x9c
+	OP_NUMEQUALVERIFY      = 0x9d
+	OP_SHA256              = 0xa8
+	OP_HASH160             = 0xa9
+	OP_HASH256             = 0xaa
+	OP_CHECKSIG            = 0xac
+	OP_CHECKSIGVERIFY      = 0xad
+	OP_CHECKMULTISIG       = 0xae
+	OP_CHECKMULTISIGVERIFY = 0xaf
+	//OP_CHECKLOCKTIMEVERIFY and OP_CHECKSEQUENCEVERIFY redefine OP_NOP2 and OP_NOP3 as per BIP65 and BIP112
+	OP_CHECKLOCKTIMEVERIFY = 0xb1
+	OP_CHECKSEQUENCEVERIFY = 0xb2
+	//OP_CHECKSIGADD replaces OP_CHECKMULTISIG in tapscript as per BIP342
+	OP_CHECKSIGADD = 0xba
+)
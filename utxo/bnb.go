@@ -0,0 +1,138 @@
+package utxo
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// MaxBnBTries is the number of selections BranchAndBound tries before settling for the best it found, or falling
+// back to a single random draw. It bounds the search, which is exponential in the number of UTXOs.
+const MaxBnBTries = 100000
+
+// BranchAndBound selects UTXOs holding at least target satoshis once the fee to spend each of them, costPerInput,
+// is paid, as per the branch and bound algorithm of Murch's "An Evaluation of Coin Selection Strategies". target is
+// the amount of the payment plus the fee of the rest of the transaction, and costPerOutput the fee of adding a
+// change output, plus the fee to spend it later.
+// A depth first search looks for a selection whose value less input costs lands between target and target plus
+// costPerOutput, so the excess can go to the miners instead of to a change output costing more than it. The
+// selection with the least excess found in MaxBnBTries tries is returned with hasChange unset. If there is none, a
+// single random draw picks UTXOs in random order until they also pay for a change output, returned with hasChange
+// set. UTXOs worth less than costPerInput to spend are never selected.
+// Returns an error if target isn't positive, a cost is negative or the UTXOs can't pay for target.
+func BranchAndBound(utxos []UTXO, target int64, costPerInput, costPerOutput int64) (selected []UTXO, hasChange bool, err error) {
+	if target <= 0 {
+		return nil, false, fmt.Errorf("Target amount must be positive. Provided amount is %d satoshis.", target)
+	}
+	if costPerInput < 0 || costPerOutput < 0 {
+		return nil, false, errors.New("Cost per input and cost per output can't be negative.")
+	}
+	var candidates []UTXO
+	for _, u := range utxos {
+		if u.Amount-costPerInput > 0 {
+			candidates = append(candidates, u)
+		}
+	}
+	if selected := searchExactMatch(candidates, target, costPerInput, costPerOutput); selected != nil {
+		return selected, false, nil
+	}
+	selected, err = singleRandomDraw(candidates, target+costPerOutput, costPerInput)
+	if err != nil {
+		return nil, false, err
+	}
+	return selected, true, nil
+}
+
+// searchExactMatch is the depth first search of BranchAndBound. UTXOs are sorted by decreasing effective value, their
+// amount less costPerInput, and each is either included or excluded in turn. A branch is cut when it overshoots
+// target plus costPerOutput or can't reach target even with every UTXO left, or once it lands in between. Including
+// a UTXO is skipped when the previous UTXO of the same effective value was excluded, as the branch would repeat one
+// already searched.
+// Returns the selection with the least excess over target, or nil if none was found.
+func searchExactMatch(utxos []UTXO, target int64, costPerInput, costPerOutput int64) []UTXO {
+	sorted := make([]UTXO, len(utxos))
+	copy(sorted, utxos)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Amount > sorted[j].Amount })
+	values := make([]int64, len(sorted))
+	var available int64
+	for i, u := range sorted {
+		values[i] = u.Amount - costPerInput
+		available += values[i]
+	}
+	if available < target {
+		return nil
+	}
+
+	var included []bool //Whether each UTXO searched so far is in the current selection
+	var best []bool
+	var bestExcess int64
+	var value int64
+	for tries := 0; tries < MaxBnBTries; tries++ {
+		backtrack := false
+		if value+available < target || value > target+costPerOutput {
+			backtrack = true
+		} else if value >= target {
+			if excess := value - target; best == nil || excess < bestExcess {
+				best = append([]bool(nil), included...)
+				bestExcess = excess
+				if excess == 0 {
+					break
+				}
+			}
+			backtrack = true
+		}
+
+		if backtrack {
+			//Give back the UTXOs excluded at the end of the branch, then exclude the last one included
+			for len(included) > 0 && !included[len(included)-1] {
+				available += values[len(included)-1]
+				included = included[:len(included)-1]
+			}
+			if len(included) == 0 {
+				break
+			}
+			included[len(included)-1] = false
+			value -= values[len(included)-1]
+			continue
+		}
+		i := len(included)
+		available -= values[i]
+		if i > 0 && !included[i-1] && values[i] == values[i-1] {
+			included = append(included, false)
+			continue
+		}
+		included = append(included, true)
+		value += values[i]
+	}
+
+	if best == nil {
+		return nil
+	}
+	var selected []UTXO
+	for i, in := range best {
+		if in {
+			selected = append(selected, sorted[i])
+		}
+	}
+	return selected
+}
+
+// singleRandomDraw picks utxos in random order until their amounts less costPerInput each add up to target.
+// Returns an error if all of them together don't.
+func singleRandomDraw(utxos []UTXO, target int64, costPerInput int64) ([]UTXO, error) {
+	shuffled := make([]UTXO, len(utxos))
+	copy(shuffled, utxos)
+	if err := shuffle(shuffled); err != nil {
+		return nil, err
+	}
+	var selected []UTXO
+	var value int64
+	for _, u := range shuffled {
+		selected = append(selected, u)
+		value += u.Amount - costPerInput
+		if value >= target {
+			return selected, nil
+		}
+	}
+	return nil, fmt.Errorf("Insufficient funds. Target amount with change is %d satoshis, but the %d UTXOs hold %d once the cost of spending them is paid.", target, len(utxos), value)
+}
@@ -0,0 +1,133 @@
+package utxo
+
+import (
+	"fmt"
+	"testing"
+)
+
+// testUTXOs returns UTXOs holding amounts, each being spent for 100 satoshis.
+func testUTXOs(amounts ...int64) []UTXO {
+	utxos := make([]UTXO, len(amounts))
+	for i, amount := range amounts {
+		utxos[i] = UTXO{TxID: fmt.Sprintf("%064x", i+1), Vout: uint32(i), Amount: amount}
+	}
+	return utxos
+}
+
+// selectedValue returns the amounts of selected less costPerInput for each.
+func selectedValue(selected []UTXO, costPerInput int64) int64 {
+	var value int64
+	for _, u := range selected {
+		value += u.Amount - costPerInput
+	}
+	return value
+}
+
+func TestBranchAndBound(t *testing.T) {
+	//Effective values of 100000, 50000, 30000, 20000 and 5000 satoshis, and a UTXO not worth spending
+	utxos := testUTXOs(30100, 100100, 5100, 50100, 20100, 90)
+
+	//Exact matches, without change
+	exactCases := []struct {
+		target int64
+		excess int64
+		inputs int
+	}{
+		{70000, 0, 2},   //50000 + 20000, where LargestFirst would spend 100000 with change
+		{74500, 500, 3}, //50000 + 20000 + 5000, within the cost of a change output
+		{105000, 0, 2},  //100000 + 5000
+		{205000, 0, 5},  //Every UTXO worth spending
+	}
+	for _, testCase := range exactCases {
+		selected, hasChange, err := BranchAndBound(utxos, testCase.target, 100, 1000)
+		if err != nil {
+			t.Fatal(err)
+		}
+		value := selectedValue(selected, 100)
+		if hasChange || len(selected) != testCase.inputs || value-testCase.target != testCase.excess {
+			t.Errorf("BranchAndBound selected %d UTXOs worth %d for target %d, change %v, expected %d worth %d without change.", len(selected), value, testCase.target, hasChange, testCase.inputs, testCase.target+testCase.excess)
+		}
+	}
+
+	//No selection lands within 100 satoshis above 12000, so the single random draw pays for a change output too
+	for i := 0; i < 20; i++ {
+		selected, hasChange, err := BranchAndBound(utxos, 12000, 100, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !hasChange || selectedValue(selected, 100) < 12100 {
+			t.Errorf("BranchAndBound fallback selected %d UTXOs worth %d, change %v, expected at least 12100 with change.", len(selected), selectedValue(selected, 100), hasChange)
+		}
+		for _, u := range selected {
+			if u.Amount == 90 {
+				t.Error("BranchAndBound fallback selected UTXO worth less than the cost of spending it.")
+			}
+		}
+	}
+
+	//Odd target out of reach of even values: the search gives up after MaxBnBTries and falls back
+	var amounts []int64
+	for i := int64(1); i <= 100; i++ {
+		amounts = append(amounts, i*2000+100)
+	}
+	selected, hasChange, err := BranchAndBound(testUTXOs(amounts...), 1000001, 100, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hasChange || selectedValue(selected, 100) < 1000001 {
+		t.Errorf("BranchAndBound selected %d UTXOs worth %d for unreachable exact match, change %v.", len(selected), selectedValue(selected, 100), hasChange)
+	}
+
+	invalidCases := map[string]struct {
+		target        int64
+		costPerInput  int64
+		costPerOutput int64
+	}{
+		"zero target":              {0, 100, 1000},
+		"negative cost per input":  {70000, -1, 1000},
+		"negative cost per output": {70000, 100, -1},
+		"more than balance":        {205001, 100, 0},
+	}
+	for name, testCase := range invalidCases {
+		if _, _, err := BranchAndBound(utxos, testCase.target, testCase.costPerInput, testCase.costPerOutput); err == nil {
+			t.Errorf("BranchAndBound accepting %s.", name)
+		}
+	}
+}
+
+// benchmarkUTXOs returns 100 UTXOs of 1000 to about 1000000 satoshis.
+func benchmarkUTXOs() []UTXO {
+	var amounts []int64
+	for i := int64(0); i < 100; i++ {
+		amounts = append(amounts, 1000+(i*7919)%1000000)
+	}
+	return testUTXOs(amounts...)
+}
+
+var benchmarkTargets = []int64{10000, 100000, 1000000, 10000000}
+
+func BenchmarkBranchAndBound(b *testing.B) {
+	utxos := benchmarkUTXOs()
+	for _, target := range benchmarkTargets {
+		b.Run(fmt.Sprintf("target %d", target), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, _, err := BranchAndBound(utxos, target, 100, 1000); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkLargestFirst(b *testing.B) {
+	s := NewUTXOSet(benchmarkUTXOs()...)
+	for _, target := range benchmarkTargets {
+		b.Run(fmt.Sprintf("target %d", target), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, _, err := s.Select(target, LargestFirst); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
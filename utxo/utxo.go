@@ -0,0 +1,150 @@
+// Package utxo keeps a set of unspent transaction outputs available to a wallet, and selects which of them to spend
+// to fund a payment.
+package utxo
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+)
+
+// UTXO is an unspent transaction output, output Vout of transaction TxID, holding Amount satoshis.
+type UTXO struct {
+	TxID          string //Transaction ID in the big-endian hex form displayed by block explorers
+	Vout          uint32 //Index of the output in the transaction
+	ScriptPubKey  []byte
+	Amount        int64 //Satoshis held by the output
+	Confirmations int   //Number of blocks confirming the transaction, 0 while unconfirmed
+}
+
+// Outpoint returns the TXID:VOUT string identifying the output spent.
+func (u UTXO) Outpoint() string {
+	return outpoint(u.TxID, u.Vout)
+}
+
+func outpoint(txID string, vout uint32) string {
+	return fmt.Sprintf("%s:%d", txID, vout)
+}
+
+// CoinSelectionAlgorithm is the order in which Select picks UTXOs until they cover the target amount.
+type CoinSelectionAlgorithm string
+
+const (
+	//LargestFirst spends the largest UTXOs first, funding a payment with as few inputs, and as low a fee, as possible
+	LargestFirst CoinSelectionAlgorithm = "largest-first"
+	//SmallestFirst spends the smallest UTXOs first, consolidating small outputs so fewer are left in the set
+	SmallestFirst CoinSelectionAlgorithm = "smallest-first"
+	//Random spends UTXOs in a random order, so the inputs chosen reveal less about the wallet
+	Random CoinSelectionAlgorithm = "random"
+)
+
+// UTXOSet is an in-memory set of UTXOs, keyed by their outpoint. The zero value is an empty set ready to use.
+type UTXOSet struct {
+	utxos map[string]UTXO
+}
+
+// NewUTXOSet returns a set holding utxos.
+func NewUTXOSet(utxos ...UTXO) *UTXOSet {
+	s := &UTXOSet{}
+	for _, u := range utxos {
+		s.Add(u)
+	}
+	return s
+}
+
+// Add adds u to the set, replacing the UTXO with the same outpoint if there is one.
+func (s *UTXOSet) Add(u UTXO) {
+	if s.utxos == nil {
+		s.utxos = make(map[string]UTXO)
+	}
+	s.utxos[u.Outpoint()] = u
+}
+
+// Remove removes output vout of transaction txid from the set, once it is spent.
+// Returns an error if the set doesn't hold it.
+func (s *UTXOSet) Remove(txid string, vout uint32) error {
+	key := outpoint(txid, vout)
+	if _, ok := s.utxos[key]; !ok {
+		return fmt.Errorf("UTXO %s is not in the set.", key)
+	}
+	delete(s.utxos, key)
+	return nil
+}
+
+// Len returns the number of UTXOs in the set.
+func (s *UTXOSet) Len() int {
+	return len(s.utxos)
+}
+
+// TotalBalance returns the number of satoshis held by all UTXOs of the set.
+func (s *UTXOSet) TotalBalance() int64 {
+	var total int64
+	for _, u := range s.utxos {
+		total += u.Amount
+	}
+	return total
+}
+
+// Select picks UTXOs of the set in the order given by algorithm until they hold at least target satoshis. The set is
+// left unchanged, so the UTXOs selected should be removed once the transaction spending them is broadcast.
+// Returns the UTXOs selected, in the order they were picked, and the change, the satoshis they hold above target.
+// Returns an error if target isn't positive, algorithm is unknown or the set holds less than target.
+func (s *UTXOSet) Select(target int64, algorithm CoinSelectionAlgorithm) ([]UTXO, int64, error) {
+	if target <= 0 {
+		return nil, 0, fmt.Errorf("Target amount must be positive. Provided amount is %d satoshis.", target)
+	}
+	candidates := s.sorted()
+	switch algorithm {
+	case LargestFirst:
+		for i, j := 0, len(candidates)-1; i < j; i, j = i+1, j-1 {
+			candidates[i], candidates[j] = candidates[j], candidates[i]
+		}
+	case SmallestFirst:
+	case Random:
+		if err := shuffle(candidates); err != nil {
+			return nil, 0, err
+		}
+	default:
+		return nil, 0, fmt.Errorf("Unknown coin selection algorithm %q. Use %s, %s or %s.", algorithm, LargestFirst, SmallestFirst, Random)
+	}
+	var selected []UTXO
+	var total int64
+	for _, u := range candidates {
+		selected = append(selected, u)
+		total += u.Amount
+		if total >= target {
+			return selected, total - target, nil
+		}
+	}
+	return nil, 0, fmt.Errorf("Insufficient funds. Target amount is %d satoshis, but the %d UTXOs of the set hold %d.", target, len(candidates), total)
+}
+
+// sorted returns the UTXOs of the set from the smallest to the largest amount, with equal amounts ordered by
+// outpoint so the selection doesn't depend on map iteration order.
+func (s *UTXOSet) sorted() []UTXO {
+	utxos := make([]UTXO, 0, len(s.utxos))
+	for _, u := range s.utxos {
+		utxos = append(utxos, u)
+	}
+	sort.Slice(utxos, func(i, j int) bool {
+		if utxos[i].Amount != utxos[j].Amount {
+			return utxos[i].Amount < utxos[j].Amount
+		}
+		return utxos[i].Outpoint() < utxos[j].Outpoint()
+	})
+	return utxos
+}
+
+// shuffle puts utxos in a random order with a Fisher-Yates shuffle, drawing from crypto/rand.
+func shuffle(utxos []UTXO) error {
+	for i := len(utxos) - 1; i > 0; i-- {
+		j, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return errors.New("Failed to shuffle UTXOs. " + err.Error())
+		}
+		utxos[i], utxos[j.Int64()] = utxos[j.Int64()], utxos[i]
+	}
+	return nil
+}
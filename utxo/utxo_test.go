@@ -0,0 +1,106 @@
+package utxo
+
+import (
+	"fmt"
+	"testing"
+)
+
+// testUTXOSet returns a set of a 1,000,000 satoshi UTXO and ten small ones of 10,000 to 100,000 satoshis.
+func testUTXOSet() *UTXOSet {
+	s := NewUTXOSet(UTXO{TxID: "8ac60eb9575db5b2d987e29f301b5b819ea83a5c6579d282d189cc04b8e151ef", Vout: 0, Amount: 1000000, Confirmations: 6})
+	for i := 1; i <= 10; i++ {
+		s.Add(UTXO{TxID: fmt.Sprintf("%064x", i), Vout: uint32(i), Amount: int64(i) * 10000, Confirmations: 1})
+	}
+	return s
+}
+
+func TestUTXOSet(t *testing.T) {
+	s := testUTXOSet()
+	if s.Len() != 11 || s.TotalBalance() != 1550000 {
+		t.Errorf("Set holding %d UTXOs with %d satoshis, expected 11 with 1550000.", s.Len(), s.TotalBalance())
+	}
+	//Adding an outpoint again replaces it
+	s.Add(UTXO{TxID: "8ac60eb9575db5b2d987e29f301b5b819ea83a5c6579d282d189cc04b8e151ef", Vout: 0, Amount: 2000000})
+	if s.Len() != 11 || s.TotalBalance() != 2550000 {
+		t.Errorf("Set holding %d UTXOs with %d satoshis after replacing one, expected 11 with 2550000.", s.Len(), s.TotalBalance())
+	}
+	if err := s.Remove("8ac60eb9575db5b2d987e29f301b5b819ea83a5c6579d282d189cc04b8e151ef", 0); err != nil {
+		t.Fatal(err)
+	}
+	if s.Len() != 10 || s.TotalBalance() != 550000 {
+		t.Errorf("Set holding %d UTXOs with %d satoshis after removing one, expected 10 with 550000.", s.Len(), s.TotalBalance())
+	}
+	if err := s.Remove("8ac60eb9575db5b2d987e29f301b5b819ea83a5c6579d282d189cc04b8e151ef", 0); err == nil {
+		t.Error("Remove accepting UTXO not in the set.")
+	}
+	var empty UTXOSet
+	if err := empty.Remove("8ac60eb9575db5b2d987e29f301b5b819ea83a5c6579d282d189cc04b8e151ef", 0); err == nil || empty.TotalBalance() != 0 {
+		t.Error("Empty set holding a UTXO.")
+	}
+}
+
+func TestSelect(t *testing.T) {
+	s := testUTXOSet()
+	target := int64(120000)
+
+	//LargestFirst funds the payment with the single large UTXO
+	selected, change, err := s.Select(target, LargestFirst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(selected) != 1 || selected[0].Amount != 1000000 || change != 880000 {
+		t.Errorf("LargestFirst selected %d UTXOs with change %d, expected the 1000000 satoshi UTXO with change 880000.", len(selected), change)
+	}
+
+	//SmallestFirst spends 10,000 to 50,000 satoshi UTXOs instead, leaving fewer UTXOs in the set
+	selected, change, err = s.Select(target, SmallestFirst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(selected) != 5 || change != 30000 {
+		t.Errorf("SmallestFirst selected %d UTXOs with change %d, expected 5 with change 30000.", len(selected), change)
+	}
+	for i, u := range selected {
+		if u.Amount != int64(i+1)*10000 {
+			t.Errorf("SmallestFirst selected UTXO %d of %d satoshis, expected %d.", i, u.Amount, (i+1)*10000)
+		}
+	}
+
+	//Random covers the target, with every UTXO but the last one picked short of it
+	for i := 0; i < 20; i++ {
+		selected, change, err = s.Select(target, Random)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var total int64
+		for _, u := range selected {
+			total += u.Amount
+		}
+		if total-target != change || total-selected[len(selected)-1].Amount >= target {
+			t.Errorf("Random selected %d UTXOs holding %d satoshis with change %d for target %d.", len(selected), total, change, target)
+		}
+	}
+
+	//The whole balance can be selected, without change
+	if selected, change, err := s.Select(s.TotalBalance(), SmallestFirst); err != nil || len(selected) != 11 || change != 0 {
+		t.Errorf("Selecting the whole balance gave %d UTXOs with change %d. %v", len(selected), change, err)
+	}
+	if s.Len() != 11 {
+		t.Errorf("Select changed the set to %d UTXOs.", s.Len())
+	}
+
+	invalidCases := map[string]struct {
+		target    int64
+		algorithm CoinSelectionAlgorithm
+	}{
+		"zero target":          {0, LargestFirst},
+		"more than balance":    {s.TotalBalance() + 1, LargestFirst},
+		"unknown algorithm":    {target, CoinSelectionAlgorithm("branch-and-bound")},
+		"empty algorithm name": {target, ""},
+	}
+	for name, testCase := range invalidCases {
+		if _, _, err := s.Select(testCase.target, testCase.algorithm); err == nil {
+			t.Errorf("Select accepting %s.", name)
+		}
+	}
+}